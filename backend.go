@@ -0,0 +1,78 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"github.com/gorilla/mux"
+)
+
+// backend holds the services the handlers are wired with, either the Google Cloud ones or their local replacements.
+type backend struct {
+	envVariables *types.EnvVariables
+	logWriter    io.Writer
+
+	firebaseService       interfaces.IFirebaseService
+	authService           interfaces.IAuthService
+	accountService        interfaces.IAccountService
+	authorizationService  interfaces.IAuthorizationService
+	callbackAuthService   interfaces.ICallbackAuthService
+	sessionManagerService interfaces.ISessionManagerService
+	templateService       interfaces.ITemplateService
+	emailService          interfaces.IEmailService
+	emailTemplateService  interfaces.IEmailTemplateService
+	errorReporterService  interfaces.IErrorReporterService
+	schedulerService      interfaces.ISchedulerService
+	storageService        interfaces.IStorageService
+
+	holidayCalendarService interfaces.IHolidayCalendarService
+	submissionLinkService  interfaces.ISubmissionLinkService
+	scheduleService        interfaces.IScheduleService
+	timesheetParserService interfaces.ITimesheetParserService
+	timesheetReviewService interfaces.ITimesheetReviewService
+
+	timesheetIngestionService interfaces.ITimesheetIngestionService
+	inboxWatcherService       interfaces.IInboxWatcherService
+
+	ownersDB            interfaces.IOwnerDatabaseService
+	groupsDB            interfaces.IGroupsDatabaseService
+	contractorsDB       interfaces.IContractorsDatabaseService
+	timesheetsDB        interfaces.ITimesheetsDatabaseService
+	timesheetRequestsDB interfaces.ITimesheetRequestsDatabaseService
+	timesheetVersionsDB interfaces.ITimesheetVersionsDatabaseService
+	timesheetEntriesDB  interfaces.ITimesheetEntriesDatabaseService
+	timesheetAuditLogDB interfaces.ITimesheetAuditLogDatabaseService
+	unassignedEmailsDB  interfaces.IUnassignedEmailsDatabaseService
+
+	// registerRoutes, when set, adds the routes only this backend needs.
+	registerRoutes func(r *mux.Router)
+}
+
+// sessionLifetimes returns the absolute lifetimes of a login and of a remembered one, from the environment or the defaults.
+func sessionLifetimes() (time.Duration, time.Duration) {
+	sessionLifetime := durationFromEnv(constants.SessionLifetimeEnvKey, constants.SessionDefaultLifetimeHours*time.Hour)
+	rememberMeSessionLifetime := durationFromEnv(constants.SessionRememberMeLifetimeEnvKey, constants.SessionDefaultRememberMeDays*24*time.Hour)
+
+	return sessionLifetime, rememberMeSessionLifetime
+}
+
+// durationFromEnv parses a positive duration, like 12h, from an environment variable, exiting if it is invalid.
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Fatalf("%s must be a positive duration, e.g. 12h: %q", key, value)
+	}
+
+	return d
+}
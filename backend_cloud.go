@@ -0,0 +1,266 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"job_sender/core"
+	"job_sender/emails"
+	"job_sender/interfaces"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+)
+
+// newCloudBackend creates the services backed by Google Cloud, exiting if any of them cannot be reached.
+func newCloudBackend() *backend {
+	// Create new EnvVariablesService
+	envVariablesService := core.NewEnvVariablesService("PORT", "GOOGLE_CLOUD_PROJECT_ID", "GOOGLE_CLOUD_PROJECT_LOCATION_ID", "GOOGLE_CLOUD_PROJECT_NUMBER", "SERVICE_ACCOUNT_EMAIL", "SECRET_NAME_SERVICE_ACCOUNT_KEY", "SECRET_NAME_FIREBASE_WEB_API_KEY", "SECRET_NAME_EMAIL_SERVICE_EMAIL", "SECRET_NAME_EMAIL_SERVICE_APP_PASSWORD", "SECRET_NAME_SESSION_COOKIE_STORE", "SECRET_NAME_SUBMISSION_LINK_KEY", "SECRET_NAME_ACCOUNT_LINK_KEY", "TIMESHEETS_BUCKET_NAME")
+	envVariables := envVariablesService.GetEnvVariables()
+
+	// Create a new Secret Manager client
+	s, err := core.NewSecretManagerService()
+	if err != nil {
+		log.Fatalf("Failed to create secret manager client: %v", err)
+	}
+
+	// Get the sa-backend service account key from Secret Manager
+	secretServiceAccountKey, err := s.GetSecret(envVariables.ProjectNumber, envVariables.SecretNameServiceAccountKey)
+	if err != nil {
+		log.Fatalf("Failed to get secret: %v", err)
+	}
+
+	// Initialize Firebase service
+	firebaseService, err := core.NewFirebaseService(secretServiceAccountKey)
+	if err != nil {
+		log.Fatalf("NewFirebaseService: %v", err)
+	}
+
+	// Get the Firestore Web API key from Secret Manager
+	firebaseWebApiKey, err := s.GetSecret(envVariables.ProjectNumber, envVariables.SecretNameFirestoreWebApiKey)
+	if err != nil {
+		log.Fatalf("Failed to get secret: %v", err)
+	}
+
+	// Get the email and app password for the email service from Secret Manager
+	emailServiceEmail, err := s.GetSecret(envVariables.ProjectNumber, envVariables.SecretNameEmailServiceEmail)
+	if err != nil {
+		log.Fatalf("Failed to get secret: %v", err)
+	}
+	emailServiceAppPassword, err := s.GetSecret(envVariables.ProjectNumber, envVariables.SecretNameEmailServiceAppPassword)
+	if err != nil {
+		log.Fatalf("Failed to get secret: %v", err)
+	}
+
+	// Initialize Error Reporter Service
+	errorReporterService := core.NewErrorReporterService(envVariables.ProjectID)
+	if errorReporterService == nil {
+		log.Fatalf("NewErrorReporterService: %v", err)
+	}
+
+	// Mail transport of the Email Service, Gmail requires STARTTLS for SMTP on port 587 and TLS for IMAP on port 993
+	mailTransport := &types.MailTransport{
+		SmtpHost:    constants.SmtpGmailAddress,
+		SmtpPort:    constants.SmtpGmailPort,
+		SmtpTLSMode: constants.StartTLS,
+
+		ImapHost:    constants.ImapGmailAddress,
+		ImapPort:    constants.ImapGmailPort,
+		ImapTLSMode: constants.ImplicitTLS,
+
+		AuthMechanism: constants.PlainAuth,
+	}
+
+	// Initialize Template Service
+	templateService := core.NewTemplateService(constants.TemplatesDir)
+
+	// Get the session cookie store secret from Secret Manager
+	sessionCookieStore, err := s.GetSecret(envVariables.ProjectNumber, envVariables.SecretNameSessionCookieStore)
+	if err != nil {
+		log.Fatalf("Failed to get secret: %v", err)
+	}
+
+	// Create the session store, the sessions are kept in Firestore so every instance sees them
+	sessionStoreService, err := core.NewSessionStoreService(firebaseService)
+	if err != nil {
+		log.Fatalf("NewSessionStoreService: %v", err)
+	}
+
+	// Initialize Sesssion Manager Service
+	sessionManagerService := core.NewSessionManagerService(sessionCookieStore, sessionStoreService)
+
+	// Initialize Cloud Scheduler Service
+	schedulerService, err := core.NewSchedulerService(envVariables.ServiceAccountEmail, envVariables.ProjectID, envVariables.ProjectLocationID, secretServiceAccountKey)
+	if err != nil {
+		log.Fatalf("NewSchedulerService: %v", err)
+	}
+
+	// Initialize the Auth service
+	sessionLifetime, rememberMeSessionLifetime := sessionLifetimes()
+	authService := core.NewAuthService(firebaseService, string(firebaseWebApiKey), sessionManagerService, sessionLifetime, rememberMeSessionLifetime)
+
+	// Authenticate the scheduler jobs by the OIDC tokens of the service account, and the signed callbacks if there is a key for them
+	callbackJwksURL := os.Getenv(constants.CallbackJwksURLEnvKey)
+	if callbackJwksURL == "" {
+		callbackJwksURL = constants.GoogleJwksURL
+	}
+	callbackOidcIssuer := os.Getenv(constants.CallbackOidcIssuerEnvKey)
+	if callbackOidcIssuer == "" {
+		callbackOidcIssuer = constants.GoogleOidcIssuer
+	}
+	var callbackSigningKey []byte
+	if secretName := os.Getenv(constants.CallbackSigningKeySecretEnvKey); secretName != "" {
+		callbackSigningKey, err = s.GetSecret(envVariables.ProjectNumber, secretName)
+		if err != nil {
+			log.Fatalf("Failed to get secret: %v", err)
+		}
+	}
+	callbackAuthService := core.NewCallbackAuthService(constants.AppUrl, callbackJwksURL, callbackOidcIssuer, envVariables.ServiceAccountEmail, callbackSigningKey)
+
+	// Create Storage Service
+	storageService, err := core.NewStorageService(envVariables.TimesheetsBucketName)
+	if err != nil {
+		log.Fatalf("NewStorageService: %v", err)
+	}
+
+	// Create owners db service
+	ownersDB, err := core.NewOwnerDatabaseService(firebaseService)
+	if err != nil {
+		log.Fatalf("NewOwnerDatabaseService: %v", err)
+	}
+
+	// Create the groups db service
+	groupsDB, err := core.NewGroupsDatabaseService(firebaseService)
+	if err != nil {
+		log.Fatalf("NewGroupsDatabaseService: %v", err)
+	}
+
+	// Create contractors db service
+	contractorsDB, err := core.NewContractorsDatabaseService(firebaseService)
+	if err != nil {
+		log.Fatalf("NewContractorsDatabaseService: %v", err)
+	}
+
+	// Create timesheets db service
+	timesheetsDB, err := core.NewTimesheetsDatabaseService(firebaseService)
+	if err != nil {
+		log.Fatalf("NewTimesheetsDatabaseService: %v", err)
+	}
+
+	// Create timesheet requests db service
+	timesheetRequestsDB, err := core.NewTimesheetRequestsDatabaseService(firebaseService)
+	if err != nil {
+		log.Fatalf("NewTimesheetRequestsDatabaseService: %v", err)
+	}
+
+	// Create timesheet versions db service
+	timesheetVersionsDB, err := core.NewTimesheetVersionsDatabaseService(firebaseService)
+	if err != nil {
+		log.Fatalf("NewTimesheetVersionsDatabaseService: %v", err)
+	}
+
+	// Create timesheet ingestions db service
+	timesheetIngestionsDB, err := core.NewTimesheetIngestionsDatabaseService(firebaseService)
+	if err != nil {
+		log.Fatalf("NewTimesheetIngestionsDatabaseService: %v", err)
+	}
+
+	// Create timesheet entries db service
+	timesheetEntriesDB, err := core.NewTimesheetEntriesDatabaseService(firebaseService)
+	if err != nil {
+		log.Fatalf("NewTimesheetEntriesDatabaseService: %v", err)
+	}
+
+	// Create timesheet audit log db service
+	timesheetAuditLogDB, err := core.NewTimesheetAuditLogDatabaseService(firebaseService)
+	if err != nil {
+		log.Fatalf("NewTimesheetAuditLogDatabaseService: %v", err)
+	}
+
+	// Create unassigned emails db service
+	unassignedEmailsDB, err := core.NewUnassignedEmailsDatabaseService(firebaseService)
+	if err != nil {
+		log.Fatalf("NewUnassignedEmailsDatabaseService: %v", err)
+	}
+
+	// Create leases db service
+	leasesDB, err := core.NewLeasesDatabaseService(firebaseService)
+	if err != nil {
+		log.Fatalf("NewLeasesDatabaseService: %v", err)
+	}
+
+	// Get the key submission links are signed with from Secret Manager
+	submissionLinkKey, err := s.GetSecret(envVariables.ProjectNumber, envVariables.SecretNameSubmissionLinkKey)
+	if err != nil {
+		log.Fatalf("Failed to get secret: %v", err)
+	}
+
+	// Get the key the password reset and email change links are signed with from Secret Manager
+	accountLinkKey, err := s.GetSecret(envVariables.ProjectNumber, envVariables.SecretNameAccountLinkKey)
+	if err != nil {
+		log.Fatalf("Failed to get secret: %v", err)
+	}
+
+	// Create the holiday calendar service from the bundled calendars
+	holidayCalendarService, err := core.NewHolidayCalendarService()
+	if err != nil {
+		log.Fatalf("NewHolidayCalendarService: %v", err)
+	}
+	scheduleService := core.NewScheduleService(holidayCalendarService)
+
+	// Initialize Email Service
+	emailTemplateService := core.NewEmailTemplateService(emails.FS)
+	emailService := core.NewEmailService(string(emailServiceEmail), string(emailServiceAppPassword), mailTransport, emailTemplateService)
+
+	// Scan the timesheets with the ClamAV daemon if there is one, the other checks of the files are done either way
+	var malwareScannerService interfaces.IMalwareScannerService
+	if clamdAddress := os.Getenv(constants.ClamdAddressEnvKey); clamdAddress != "" {
+		malwareScannerService, err = core.NewClamdScannerService(clamdAddress)
+		if err != nil {
+			log.Fatalf("NewClamdScannerService: %v", err)
+		}
+	}
+	attachmentValidationService := core.NewAttachmentValidationService(malwareScannerService)
+
+	// Create the services that save the timesheets, from the inbox as the replies arrive and from the submission page
+	timesheetReviewService := core.NewTimesheetReviewService(timesheetsDB, timesheetAuditLogDB)
+	timesheetParserService := core.NewTimesheetParserService()
+	timesheetIngestionService := core.NewTimesheetIngestionService(storageService, emailService, attachmentValidationService, timesheetParserService, groupsDB, contractorsDB, timesheetsDB, timesheetRequestsDB, timesheetVersionsDB, timesheetIngestionsDB, unassignedEmailsDB)
+	inboxWatcherService := core.NewInboxWatcherService(string(emailServiceEmail), string(emailServiceAppPassword), mailTransport, timesheetIngestionService, leasesDB)
+
+	return &backend{
+		envVariables: envVariables,
+		logWriter:    errorReporterService.LogWriter,
+
+		firebaseService:       firebaseService,
+		authService:           authService,
+		accountService:        core.NewAccountService(accountLinkKey, constants.AppUrl, firebaseService, emailService, sessionManagerService, ownersDB),
+		authorizationService:  core.NewAuthorizationService(sessionManagerService, groupsDB, contractorsDB, timesheetsDB),
+		callbackAuthService:   callbackAuthService,
+		sessionManagerService: sessionManagerService,
+		templateService:       templateService,
+		emailService:          emailService,
+		emailTemplateService:  emailTemplateService,
+		errorReporterService:  errorReporterService,
+		schedulerService:      schedulerService,
+		storageService:        storageService,
+
+		holidayCalendarService: holidayCalendarService,
+		scheduleService:        scheduleService,
+		submissionLinkService:  core.NewSubmissionLinkService(submissionLinkKey, constants.AppUrl),
+		timesheetParserService: timesheetParserService,
+		timesheetReviewService: timesheetReviewService,
+
+		timesheetIngestionService: timesheetIngestionService,
+		inboxWatcherService:       inboxWatcherService,
+
+		ownersDB:            ownersDB,
+		groupsDB:            groupsDB,
+		contractorsDB:       contractorsDB,
+		timesheetsDB:        timesheetsDB,
+		timesheetRequestsDB: timesheetRequestsDB,
+		timesheetVersionsDB: timesheetVersionsDB,
+		timesheetEntriesDB:  timesheetEntriesDB,
+		timesheetAuditLogDB: timesheetAuditLogDB,
+		unassignedEmailsDB:  unassignedEmailsDB,
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"job_sender/core"
+	"job_sender/emails"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+	"job_sender/utils/fakeclamd"
+	"job_sender/utils/fakemail"
+
+	"github.com/gorilla/mux"
+)
+
+// Names of the secrets of the local backend, they can be overridden in the secrets file or the environment.
+const (
+	localSecretNameEmailServiceEmail       = "EMAIL_SERVICE_EMAIL"
+	localSecretNameEmailServiceAppPassword = "EMAIL_SERVICE_APP_PASSWORD"
+	localSecretNameSessionCookieStore      = "SESSION_COOKIE_STORE"
+	localSecretNameIDTokenKey              = "ID_TOKEN_KEY"
+	localSecretNameSubmissionLinkKey       = "SUBMISSION_LINK_KEY"
+	localSecretNameAccountLinkKey          = "ACCOUNT_LINK_KEY"
+	localSecretNameCallbackSigningKey      = "CALLBACK_SIGNING_KEY"
+)
+
+// newLocalBackend creates the local replacements of the Google Cloud services, so the app runs without a cloud project.
+// Everything is kept in the data directory, emails go through an in-process SMTP and IMAP server.
+func newLocalBackend() *backend {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	appURL := "http://localhost:" + port
+
+	dataDir := os.Getenv(constants.LocalDataDirEnvKey)
+	if dataDir == "" {
+		dataDir = constants.LocalDataDir
+	}
+
+	envVariables := &types.EnvVariables{
+		Port: port,
+
+		ProjectID:         constants.LocalBackend,
+		ProjectLocationID: constants.LocalBackend,
+		ProjectNumber:     constants.LocalBackend,
+
+		SecretNameEmailServiceEmail:       localSecretNameEmailServiceEmail,
+		SecretNameEmailServiceAppPassword: localSecretNameEmailServiceAppPassword,
+		SecretNameSessionCookieStore:      localSecretNameSessionCookieStore,
+		SecretNameSubmissionLinkKey:       localSecretNameSubmissionLinkKey,
+		SecretNameAccountLinkKey:          localSecretNameAccountLinkKey,
+
+		TimesheetsBucketName: constants.LocalBucketDir,
+	}
+
+	// Read the secrets from the secrets file, the defaults are only fit for local development
+	s, err := core.NewLocalSecretManagerService(filepath.Join(dataDir, constants.LocalSecretsFile), map[string]string{
+		localSecretNameEmailServiceEmail:       "job-sender@localhost",
+		localSecretNameEmailServiceAppPassword: "local",
+		localSecretNameSessionCookieStore:      "local-session-cookie-store-key",
+		localSecretNameIDTokenKey:              "local-id-token-key",
+		localSecretNameSubmissionLinkKey:       "local-submission-link-key",
+		localSecretNameAccountLinkKey:          "local-account-link-key",
+		localSecretNameCallbackSigningKey:      "local-callback-signing-key",
+	})
+	if err != nil {
+		log.Fatalf("NewLocalSecretManagerService: %v", err)
+	}
+
+	emailServiceEmail := mustGetLocalSecret(s, localSecretNameEmailServiceEmail)
+	emailServiceAppPassword := mustGetLocalSecret(s, localSecretNameEmailServiceAppPassword)
+	sessionCookieStore := mustGetLocalSecret(s, localSecretNameSessionCookieStore)
+	idTokenKey := mustGetLocalSecret(s, localSecretNameIDTokenKey)
+	submissionLinkKey := mustGetLocalSecret(s, localSecretNameSubmissionLinkKey)
+	accountLinkKey := mustGetLocalSecret(s, localSecretNameAccountLinkKey)
+	callbackSigningKey := mustGetLocalSecret(s, localSecretNameCallbackSigningKey)
+
+	// Open the store that replaces Firestore
+	store, err := core.NewLocalStore(filepath.Join(dataDir, constants.LocalStoreFile))
+	if err != nil {
+		log.Fatalf("NewLocalStore: %v", err)
+	}
+
+	// Start the mail server the email service sends to and reads from
+	mailServer := fakemail.NewServer()
+	mailServer.Logger = log.New(os.Stderr, "", log.LstdFlags)
+	mailServer.AddUser(string(emailServiceEmail), string(emailServiceAppPassword))
+	err = mailServer.Start(constants.LocalSmtpAddress, constants.LocalImapAddress)
+	if err != nil {
+		log.Fatalf("Failed to start the local mail server: %v", err)
+	}
+
+	mailTransport := mailServer.MailTransport()
+
+	// Start the ClamAV daemon the timesheets are scanned with, it reports the EICAR test file
+	clamdServer := fakeclamd.NewServer()
+	clamdServer.Logger = log.New(os.Stderr, "", log.LstdFlags)
+	err = clamdServer.Start(constants.LocalClamdAddress)
+	if err != nil {
+		log.Fatalf("Failed to start the local ClamAV daemon: %v", err)
+	}
+
+	malwareScannerService, err := core.NewClamdScannerService("tcp:" + clamdServer.Addr())
+	if err != nil {
+		log.Fatalf("NewClamdScannerService: %v", err)
+	}
+
+	// The sessions are kept in memory, restarting the app logs everyone out
+	sessionManagerService := core.NewSessionManagerService(sessionCookieStore, core.NewMemorySessionStoreService())
+	firebaseService := core.NewLocalFirebaseService(store, idTokenKey, appURL)
+
+	// Only signed callbacks are accepted, the local scheduler signs its jobs
+	callbackAuthService := core.NewCallbackAuthService(appURL, "", "", "", callbackSigningKey)
+
+	storageService, err := core.NewLocalStorageService(filepath.Join(dataDir, constants.LocalBucketDir), appURL+constants.LocalStoragePath)
+	if err != nil {
+		log.Fatalf("NewLocalStorageService: %v", err)
+	}
+
+	holidayCalendarService, err := core.NewHolidayCalendarService()
+	if err != nil {
+		log.Fatalf("NewHolidayCalendarService: %v", err)
+	}
+	scheduleService := core.NewScheduleService(holidayCalendarService)
+	emailTemplateService := core.NewEmailTemplateService(emails.FS)
+	emailService := core.NewEmailService(string(emailServiceEmail), string(emailServiceAppPassword), mailTransport, emailTemplateService)
+
+	ownersDB := core.NewLocalOwnerDatabaseService(store)
+	groupsDB := core.NewLocalGroupsDatabaseService(store)
+	contractorsDB := core.NewLocalContractorsDatabaseService(store)
+	timesheetsDB := core.NewLocalTimesheetsDatabaseService(store)
+	timesheetRequestsDB := core.NewLocalTimesheetRequestsDatabaseService(store)
+	timesheetVersionsDB := core.NewLocalTimesheetVersionsDatabaseService(store)
+	timesheetIngestionsDB := core.NewLocalTimesheetIngestionsDatabaseService(store)
+	timesheetEntriesDB := core.NewLocalTimesheetEntriesDatabaseService(store)
+	timesheetAuditLogDB := core.NewLocalTimesheetAuditLogDatabaseService(store)
+	unassignedEmailsDB := core.NewLocalUnassignedEmailsDatabaseService(store)
+	leasesDB := core.NewLocalLeasesDatabaseService(store)
+
+	sessionLifetime, rememberMeSessionLifetime := sessionLifetimes()
+
+	timesheetReviewService := core.NewTimesheetReviewService(timesheetsDB, timesheetAuditLogDB)
+	timesheetParserService := core.NewTimesheetParserService()
+	attachmentValidationService := core.NewAttachmentValidationService(malwareScannerService)
+	timesheetIngestionService := core.NewTimesheetIngestionService(storageService, emailService, attachmentValidationService, timesheetParserService, groupsDB, contractorsDB, timesheetsDB, timesheetRequestsDB, timesheetVersionsDB, timesheetIngestionsDB, unassignedEmailsDB)
+
+	log.Printf("Running with the local backend on %s, data in %s, SMTP on %s, IMAP on %s, ClamAV on %s", appURL, dataDir, mailServer.SmtpAddr(), mailServer.ImapAddr(), clamdServer.Addr())
+
+	return &backend{
+		envVariables: envVariables,
+		logWriter:    os.Stderr,
+
+		firebaseService:       firebaseService,
+		authService:           core.NewLocalAuthService(firebaseService, sessionManagerService, sessionLifetime, rememberMeSessionLifetime),
+		accountService:        core.NewAccountService(accountLinkKey, appURL, firebaseService, emailService, sessionManagerService, ownersDB),
+		authorizationService:  core.NewAuthorizationService(sessionManagerService, groupsDB, contractorsDB, timesheetsDB),
+		callbackAuthService:   callbackAuthService,
+		sessionManagerService: sessionManagerService,
+		templateService:       core.NewTemplateService(constants.LocalTemplatesDir),
+		emailService:          emailService,
+		emailTemplateService:  emailTemplateService,
+		errorReporterService:  core.NewLocalErrorReporterService(os.Stderr),
+		schedulerService:      core.NewLocalSchedulerService(store, appURL, callbackAuthService),
+		storageService:        storageService,
+
+		holidayCalendarService: holidayCalendarService,
+		scheduleService:        scheduleService,
+		submissionLinkService:  core.NewSubmissionLinkService(submissionLinkKey, appURL),
+		timesheetParserService: timesheetParserService,
+		timesheetReviewService: timesheetReviewService,
+
+		timesheetIngestionService: timesheetIngestionService,
+		inboxWatcherService:       core.NewInboxWatcherService(string(emailServiceEmail), string(emailServiceAppPassword), mailTransport, timesheetIngestionService, leasesDB),
+
+		ownersDB:            ownersDB,
+		groupsDB:            groupsDB,
+		contractorsDB:       contractorsDB,
+		timesheetsDB:        timesheetsDB,
+		timesheetRequestsDB: timesheetRequestsDB,
+		timesheetVersionsDB: timesheetVersionsDB,
+		timesheetEntriesDB:  timesheetEntriesDB,
+		timesheetAuditLogDB: timesheetAuditLogDB,
+		unassignedEmailsDB:  unassignedEmailsDB,
+
+		registerRoutes: func(r *mux.Router) {
+			// Serve the uploaded timesheets to the signed URLs, like the bucket does
+			r.PathPrefix(constants.LocalStoragePath).Handler(http.StripPrefix(constants.LocalStoragePath, storageService.Handler()))
+		},
+	}
+}
+
+// mustGetLocalSecret returns a secret of the local backend, exiting if it is not set.
+func mustGetLocalSecret(s *core.LocalSecretManagerService, name string) []byte {
+	secret, err := s.GetSecret(constants.LocalBackend, name)
+	if err != nil {
+		log.Fatalf("Failed to get secret: %v", err)
+	}
+
+	return secret
+}
@@ -0,0 +1,277 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AccountService resets passwords and changes emails through signed links, like the submission links.
+// A link carries the time the sessions of its account are valid since, so it stops working once it is used and the sessions are revoked.
+type AccountService struct {
+	signer *tokenSigner
+	appURL string
+
+	firebaseService       interfaces.IFirebaseService
+	emailService          interfaces.IEmailService
+	sessionManagerService interfaces.ISessionManagerService
+
+	ownersDB interfaces.IOwnerDatabaseService
+}
+
+// Ensure AccountService implements IAccountService.
+var _ interfaces.IAccountService = &AccountService{}
+
+// NewAccountService creates a new AccountService that signs with the key the links to the app at appURL.
+func NewAccountService(key []byte, appURL string, firebaseService interfaces.IFirebaseService, emailService interfaces.IEmailService, sessionManagerService interfaces.ISessionManagerService, ownersDB interfaces.IOwnerDatabaseService) *AccountService {
+	return &AccountService{
+		signer: newTokenSigner(key, tokenPurposeAccount),
+		appURL: appURL,
+
+		firebaseService:       firebaseService,
+		emailService:          emailService,
+		sessionManagerService: sessionManagerService,
+
+		ownersDB: ownersDB,
+	}
+}
+
+// SendPasswordResetLink emails a password reset link to the account of an email, nothing is sent if there is none.
+func (s *AccountService) SendPasswordResetLink(email string) error {
+	// Whether an account has the email is not told
+	user, err := s.firebaseService.GetUserByEmail(email)
+	if status.Code(err) == codes.NotFound {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("could not get user: %w", err)
+	}
+
+	token, err := s.signer.createToken(&types.AccountLink{
+		Purpose: constants.AccountLinkPasswordReset,
+		UserID:  user.ID,
+		Email:   user.Email,
+
+		ValidSince: user.TokensValidAfter,
+		ExpiresAt:  time.Now().Add(constants.PasswordResetLinkLifetimeMinutes * time.Minute).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.emailService.SendPasswordResetEmail(user.Email, s.appURL+constants.PasswordResetLinkPath+"?token="+token)
+	if err != nil {
+		return fmt.Errorf("could not send password reset email: %w", err)
+	}
+
+	return nil
+}
+
+// CheckPasswordResetToken verifies the token of a password reset link, it returns a PermissionDenied status if it is forged, expired or used.
+func (s *AccountService) CheckPasswordResetToken(token string) (*types.AccountLink, error) {
+	link, err := s.parseToken(token, constants.AccountLinkPasswordReset)
+	if err != nil {
+		return nil, err
+	}
+
+	// The link is sent to the email of the account, it no longer works once the email is changed
+	user, err := s.checkValidSince(link)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.EqualFold(user.Email, link.Email) {
+		return nil, status.Errorf(codes.PermissionDenied, "the email of the account has changed")
+	}
+
+	return link, nil
+}
+
+// ResetPassword sets the password of the account of a password reset link and revokes its sessions.
+func (s *AccountService) ResetPassword(token string, password string) error {
+	link, err := s.CheckPasswordResetToken(token)
+	if err != nil {
+		return err
+	}
+
+	err = s.firebaseService.UpdatePassword(link.UserID, password)
+	if err != nil {
+		return fmt.Errorf("could not update password: %w", err)
+	}
+
+	// Log out every session, including the one of whoever knew the old password, this also uses up the link
+	err = s.logOutEverywhere(link.UserID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SendEmailChangeLink emails the link confirming a new email of a user to the new address, it returns an AlreadyExists status if an account has it.
+func (s *AccountService) SendEmailChangeLink(userID string, email string) error {
+	_, err := s.firebaseService.GetUserByEmail(email)
+	if err == nil {
+		return status.Errorf(codes.AlreadyExists, "an account has the email %s", email)
+	} else if status.Code(err) != codes.NotFound {
+		return fmt.Errorf("could not get user by email: %w", err)
+	}
+
+	user, err := s.firebaseService.GetUser(userID)
+	if err != nil {
+		return fmt.Errorf("could not get user: %w", err)
+	}
+
+	token, err := s.signer.createToken(&types.AccountLink{
+		Purpose: constants.AccountLinkEmailChange,
+		UserID:  user.ID,
+		Email:   email,
+
+		ValidSince: user.TokensValidAfter,
+		ExpiresAt:  time.Now().Add(constants.EmailChangeLinkLifetimeHours * time.Hour).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	// The link goes to the new address, opening it verifies the address
+	err = s.emailService.SendEmailChangeEmail(email, s.appURL+constants.EmailChangeLinkPath+"?token="+token)
+	if err != nil {
+		return fmt.Errorf("could not send email change email: %w", err)
+	}
+
+	return nil
+}
+
+// ChangeEmail sets the email of the account of an email change link, on the account and its owner, and revokes its sessions.
+func (s *AccountService) ChangeEmail(token string) (*types.AccountLink, error) {
+	link, err := s.parseToken(token, constants.AccountLinkEmailChange)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.checkValidSince(link)
+	if err != nil {
+		return nil, err
+	}
+
+	// The owner of the account is changed first, if the account then fails to change the owner gets its email back
+	owner, err := s.ownersDB.GetOwnerByID(link.UserID)
+	if status.Code(err) == codes.NotFound {
+		owner = nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get owner: %w", err)
+	}
+
+	var oldEmail string
+	if owner != nil {
+		oldEmail = owner.Email
+		owner.Email = link.Email
+		err = s.ownersDB.UpdateOwner(owner)
+		if err != nil {
+			return nil, fmt.Errorf("could not update owner: %w", err)
+		}
+	}
+
+	err = s.firebaseService.UpdateEmail(link.UserID, link.Email)
+	if err != nil {
+		if owner != nil {
+			owner.Email = oldEmail
+			restoreErr := s.ownersDB.UpdateOwner(owner)
+			if restoreErr != nil {
+				return nil, fmt.Errorf("could not update email: %w, nor restore the email of the owner: %v", err, restoreErr)
+			}
+		}
+		return nil, fmt.Errorf("could not update email: %w", err)
+	}
+
+	// The sessions hold the old email, they log in again
+	err = s.logOutEverywhere(link.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// DeleteAccount logs a user out everywhere and deletes the account, then its owner. A failure leaves the owner, so the deletion can be retried.
+func (s *AccountService) DeleteAccount(userID string) error {
+	// The account may be gone already if a deletion failed after deleting it
+	err := s.firebaseService.RevokeSessions(userID)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return fmt.Errorf("could not revoke sessions: %w", err)
+	}
+
+	err = s.firebaseService.DeleteUser(userID)
+	if err != nil {
+		return fmt.Errorf("could not delete user: %w", err)
+	}
+
+	err = s.ownersDB.DeleteOwner(userID)
+	if err != nil {
+		return fmt.Errorf("could not delete owner: %w", err)
+	}
+
+	err = s.sessionManagerService.DeleteUserSessions(userID)
+	if err != nil {
+		return fmt.Errorf("could not delete sessions: %w", err)
+	}
+
+	return nil
+}
+
+// logOutEverywhere revokes the Firebase tokens of a user and deletes their sessions.
+func (s *AccountService) logOutEverywhere(userID string) error {
+	err := s.firebaseService.RevokeSessions(userID)
+	if err != nil {
+		return fmt.Errorf("could not revoke sessions: %w", err)
+	}
+
+	err = s.sessionManagerService.DeleteUserSessions(userID)
+	if err != nil {
+		return fmt.Errorf("could not delete sessions: %w", err)
+	}
+
+	return nil
+}
+
+// checkValidSince returns the account of a link, it returns a PermissionDenied status if the sessions of the account were revoked since the link was created.
+func (s *AccountService) checkValidSince(link *types.AccountLink) (*types.AuthUser, error) {
+	user, err := s.firebaseService.GetUser(link.UserID)
+	if status.Code(err) == codes.NotFound {
+		return nil, status.Errorf(codes.PermissionDenied, "the account of the link does not exist")
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get user: %w", err)
+	}
+
+	if user.TokensValidAfter != link.ValidSince {
+		return nil, status.Errorf(codes.PermissionDenied, "the link was used or the sessions of the account were revoked since")
+	}
+
+	return user, nil
+}
+
+// parseToken verifies the token of a link for a purpose, it returns a PermissionDenied status if it is forged, for another purpose or expired.
+func (s *AccountService) parseToken(token string, purpose string) (*types.AccountLink, error) {
+	var link types.AccountLink
+	err := s.signer.parseToken(token, &link)
+	if err != nil {
+		return nil, err
+	}
+
+	if link.Purpose != purpose {
+		return nil, status.Errorf(codes.PermissionDenied, "the account token is not for %s", purpose)
+	}
+
+	if time.Now().Unix() > link.ExpiresAt {
+		return nil, status.Errorf(codes.PermissionDenied, "account token expired")
+	}
+
+	return &link, nil
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+)
+
+// executableSignatures are the first bytes of programs and scripts: Windows, ELF and Mach-O binaries, and scripts with a shebang.
+var executableSignatures = [][]byte{
+	[]byte("MZ"),
+	[]byte("\x7fELF"),
+	[]byte("\xfe\xed\xfa\xce"), []byte("\xfe\xed\xfa\xcf"), []byte("\xce\xfa\xed\xfe"), []byte("\xcf\xfa\xed\xfe"), []byte("\xca\xfe\xba\xbe"),
+	[]byte("#!"),
+}
+
+// archiveSignatures are the first bytes of the archives other than ZIP: gzip, bzip2, xz, RAR, 7z and cabinet files.
+var archiveSignatures = [][]byte{
+	[]byte("\x1f\x8b"),
+	[]byte("BZh"),
+	[]byte("\xfd7zXZ\x00"),
+	[]byte("Rar!\x1a\x07"),
+	[]byte("7z\xbc\xaf\x27\x1c"),
+	[]byte("MSCF"),
+}
+
+// AttachmentValidationService checks the timesheet files contractors send against the attachment policy of their group, before they are stored.
+type AttachmentValidationService struct {
+	malwareScannerService interfaces.IMalwareScannerService // Nil if the files are not scanned
+}
+
+// Ensure AttachmentValidationService implements IAttachmentValidationService.
+var _ interfaces.IAttachmentValidationService = &AttachmentValidationService{}
+
+// NewAttachmentValidationService creates a new AttachmentValidationService, the files are scanned for malware only if malwareScannerService is not nil.
+func NewAttachmentValidationService(malwareScannerService interfaces.IMalwareScannerService) *AttachmentValidationService {
+	return &AttachmentValidationService{
+		malwareScannerService: malwareScannerService,
+	}
+}
+
+// ValidateAttachment checks a timesheet file against the attachment policy of a group, it returns the type of the file sniffed from its content.
+// A file the group does not accept returns a *types.FileRejection error.
+func (s *AttachmentValidationService) ValidateAttachment(policy *types.AttachmentPolicy, attachment types.Attachment) (constants.TimesheetFileTypes, error) {
+	rejection := &types.FileRejection{
+		Filename:     attachment.Filename,
+		AllowedTypes: policy.AllowedTypes,
+		MaxSizeMB:    policy.MaxSizeMB,
+	}
+	if len(rejection.AllowedTypes) == 0 {
+		rejection.AllowedTypes = constants.TimesheetFileTypesAll
+	}
+	if rejection.MaxSizeMB == 0 {
+		rejection.MaxSizeMB = constants.AttachmentDefaultMaxSizeMB
+	}
+
+	if len(attachment.Content) > rejection.MaxSizeMB<<20 {
+		rejection.Reason = constants.FileTooLarge
+		return 0, rejection
+	}
+
+	fileType, ok, reason := sniffTimesheetFileType(attachment.Content)
+	if !ok {
+		rejection.Reason = reason
+		return 0, rejection
+	}
+
+	if !policy.Allows(fileType) {
+		rejection.Reason = constants.FileTypeNotAllowed
+		return 0, rejection
+	}
+
+	// Only the files that passed the other checks are scanned
+	if s.malwareScannerService != nil {
+		malware, err := s.malwareScannerService.ScanFile(attachment.Content)
+		if err != nil {
+			return 0, fmt.Errorf("failed to scan %s for malware: %w", attachment.Filename, err)
+		}
+
+		if malware != "" {
+			rejection.Reason = constants.MalwareFound
+			rejection.Malware = malware
+			return 0, rejection
+		}
+	}
+
+	return fileType, nil
+}
+
+// sniffTimesheetFileType tells the type of a timesheet file by its content, whatever its name. It returns false with the reason
+// if the file is not of a type timesheets can be read from.
+func sniffTimesheetFileType(content []byte) (constants.TimesheetFileTypes, bool, constants.FileRejectionReasons) {
+	for _, signature := range executableSignatures {
+		if bytes.HasPrefix(content, signature) {
+			return 0, false, constants.ExecutableFile
+		}
+	}
+
+	for _, signature := range archiveSignatures {
+		if bytes.HasPrefix(content, signature) {
+			return 0, false, constants.ArchiveFile
+		}
+	}
+
+	// A tar archive has its magic after the name of the first file
+	if len(content) > 262 && bytes.HasPrefix(content[257:], []byte("ustar")) {
+		return 0, false, constants.ArchiveFile
+	}
+
+	switch {
+	case len(content) == 0:
+		return 0, false, constants.FileTypeNotAllowed
+	case bytes.HasPrefix(content, []byte("%PDF-")):
+		return constants.PdfFile, true, 0
+	case bytes.HasPrefix(content, []byte("PK\x03\x04")):
+		return sniffZipFileType(content)
+	}
+
+	// Text without NUL bytes, HTML and XML are not timesheets
+	contentType := http.DetectContentType(content)
+	if strings.HasPrefix(contentType, "text/plain") && bytes.IndexByte(content, 0) == -1 {
+		return constants.CsvFile, true, 0
+	}
+
+	return 0, false, constants.FileTypeNotAllowed
+}
+
+// sniffZipFileType tells an Excel workbook from the other ZIP files, a workbook with macros is rejected for its macros.
+func sniffZipFileType(content []byte) (constants.TimesheetFileTypes, bool, constants.FileRejectionReasons) {
+	r, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return 0, false, constants.ArchiveFile
+	}
+
+	var contentTypes, workbook, macros bool
+	for _, f := range r.File {
+		switch name := strings.ToLower(f.Name); {
+		case name == "[content_types].xml":
+			contentTypes = true
+		case name == "xl/workbook.xml":
+			workbook = true
+		case strings.HasSuffix(name, "vbaproject.bin"):
+			macros = true
+		}
+	}
+
+	switch {
+	case !contentTypes || !workbook:
+		return 0, false, constants.ArchiveFile
+	case macros:
+		return 0, false, constants.MacroFile
+	default:
+		return constants.XlsxFile, true, 0
+	}
+}
@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"job_sender/interfaces"
 	"job_sender/types"
@@ -14,20 +17,33 @@ import (
 
 type AuthService struct {
 	firebaseWebApiKey     string
-	firebaseService       *FirebaseService
-	sessionManagerService *SessionManagerService
+	firebaseService       interfaces.IFirebaseService
+	sessionManagerService interfaces.ISessionManagerService
+
+	sessionLifetime           time.Duration
+	rememberMeSessionLifetime time.Duration
+
+	// refreshIDToken exchanges a refresh token for a new ID token, the local backend replaces the call to the Firebase REST API.
+	refreshIDToken func(refreshToken string) (*types.RefreshTokenResponseBody, error)
 }
 
 // Ensure firestoreDB conforms to the HashtagDatabase interface.
 var _ interfaces.IAuthService = &AuthService{}
 
 // NewAuthService creates a new AuthService backed by Cloud Firestore.
-func NewAuthService(firebaseService *FirebaseService, webApiKey string, sessionManagerService *SessionManagerService) *AuthService {
-	return &AuthService{
+// A login lasts sessionLifetime, or rememberMeSessionLifetime if it is remembered, however often its ID token is refreshed.
+func NewAuthService(firebaseService interfaces.IFirebaseService, webApiKey string, sessionManagerService interfaces.ISessionManagerService, sessionLifetime time.Duration, rememberMeSessionLifetime time.Duration) *AuthService {
+	s := &AuthService{
 		firebaseWebApiKey:     webApiKey,
 		firebaseService:       firebaseService,
 		sessionManagerService: sessionManagerService,
+
+		sessionLifetime:           sessionLifetime,
+		rememberMeSessionLifetime: rememberMeSessionLifetime,
 	}
+	s.refreshIDToken = s.refreshFirebaseIDToken
+
+	return s
 }
 
 // Register registers a new user.
@@ -94,6 +110,42 @@ func (s *AuthService) Login(email string, password string) (*types.LoginResponse
 	return &responseBody, nil
 }
 
+// StartSession keeps the tokens of a login in a new server-side session and creates the session cookie holding its ID.
+// The cookie of a remembered login lasts as long as the login, the others are dropped when the browser closes.
+func (s *AuthService) StartSession(w http.ResponseWriter, r *http.Request, responseBody *types.LoginResponseBody, isVerified bool, rememberMe bool) error {
+	expiresIn, err := strconv.ParseInt(responseBody.ExpiresIn, 10, 64)
+	if err != nil {
+		return fmt.Errorf("could not parse expires in time: %w", err)
+	}
+
+	now := time.Now()
+	lifetime := s.sessionLifetime
+	if rememberMe {
+		lifetime = s.rememberMeSessionLifetime
+	}
+
+	data := map[string]interface{}{
+		constants.SessionIDTokenField:          responseBody.IdToken,
+		constants.SessionIDTokenExpiresAtField: now.Add(time.Second * time.Duration(expiresIn)).Unix(),
+		constants.SessionRefreshTokenField:     responseBody.RefreshToken,
+		constants.SessionEmailField:            responseBody.Email,
+		constants.SessionIsVerfiedField:        isVerified,
+		constants.SesstionOwnerIdField:         responseBody.LocalId,
+	}
+
+	_, err = s.sessionManagerService.CreateSession(w, r, constants.UserSessionName, now.Add(lifetime), rememberMe, data)
+	if err != nil {
+		return fmt.Errorf("could not create session: %w", err)
+	}
+
+	return nil
+}
+
+// EndSession deletes the session of the request and its cookie.
+func (s *AuthService) EndSession(w http.ResponseWriter, r *http.Request) error {
+	return s.sessionManagerService.DeleteSession(w, r, constants.UserSessionName)
+}
+
 // CheckUser returns the user info
 func (s *AuthService) CheckUser(r *http.Request) (*types.LoggedUserInfo, error) {
 	email, err := s.sessionManagerService.GetElement(r, constants.UserSessionName, constants.SessionEmailField)
@@ -121,6 +173,15 @@ func (s *AuthService) CheckUser(r *http.Request) (*types.LoggedUserInfo, error)
 		return nil, err
 	}
 
+	// The account of a revoked session may have another email by now
+	if !isLoggedIn {
+		return &types.LoggedUserInfo{
+			Email:      emailStr,
+			IsLoggedIn: false,
+			IsVerified: false,
+		}, nil
+	}
+
 	isVerified, err := s.firebaseService.CheckIsUserVerified(emailStr)
 	if err != nil {
 		return nil, err
@@ -133,28 +194,44 @@ func (s *AuthService) CheckUser(r *http.Request) (*types.LoggedUserInfo, error)
 	}, nil
 }
 
-// CheckIsLoggedIn checks if a user is logged in.
+// CheckIsLoggedIn checks if a user is logged in, refreshing the ID token of the session before it expires.
+// The session store ends the sessions past their lifetime.
 func (s *AuthService) checkIsLoggedIn(r *http.Request) (bool, error) {
-	idToken, err := s.sessionManagerService.GetElement(r, constants.UserSessionName, constants.SessionTokenField)
+	idToken, err := s.getSessionString(r, constants.SessionIDTokenField)
 	if err != nil {
 		return false, err
 	}
 
-	if idToken == nil {
+	if idToken == "" {
 		return false, nil
 	}
 
-	// Convert the interface to a string
-	idTokenStr, ok := idToken.(string)
+	idTokenExpiresAt, err := s.sessionManagerService.GetElement(r, constants.UserSessionName, constants.SessionIDTokenExpiresAtField)
+	if err != nil {
+		return false, err
+	}
+
+	idTokenExpiresAtUnix, ok := idTokenExpiresAt.(int64)
 	if !ok {
-		return false, fmt.Errorf("could not convert interface to string")
+		return false, fmt.Errorf("could not convert interface to int64")
+	}
+
+	if time.Now().Add(constants.IDTokenRefreshMarginSeconds * time.Second).After(time.Unix(idTokenExpiresAtUnix, 0)) {
+		idToken, err = s.refreshSession(r)
+		if err != nil {
+			return false, err
+		}
+
+		if idToken == "" {
+			return false, nil
+		}
 	}
 
 	// Get the custom claims
-	claims, err := s.firebaseService.GetCustomClaims(idTokenStr)
+	claims, err := s.firebaseService.GetCustomClaims(idToken)
 	if err != nil {
-		// Handle expired token error specifically
-		if err.Error() == "ID token has expired" {
+		// Handle expired and revoked token errors specifically, the user logs in again
+		if err.Error() == "ID token has expired" || err.Error() == "ID token has been revoked" {
 			return false, nil
 		}
 		return false, err
@@ -166,3 +243,97 @@ func (s *AuthService) checkIsLoggedIn(r *http.Request) (bool, error) {
 
 	return true, nil
 }
+
+// refreshSession exchanges the refresh token of the session for a new ID token and stores it, it returns the new ID token.
+// It returns an empty string if the refresh token is rejected, once the sessions of the user are revoked or the user is deleted or disabled.
+func (s *AuthService) refreshSession(r *http.Request) (string, error) {
+	refreshToken, err := s.getSessionString(r, constants.SessionRefreshTokenField)
+	if err != nil {
+		return "", err
+	}
+
+	if refreshToken == "" {
+		return "", nil
+	}
+
+	responseBody, err := s.refreshIDToken(refreshToken)
+	if err != nil {
+		return "", fmt.Errorf("could not refresh ID token: %w", err)
+	}
+
+	if responseBody.IdToken == "" {
+		return "", nil
+	}
+
+	expiresIn, err := strconv.ParseInt(responseBody.ExpiresIn, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("could not parse expires in time: %w", err)
+	}
+
+	data := map[string]interface{}{
+		constants.SessionIDTokenField:          responseBody.IdToken,
+		constants.SessionIDTokenExpiresAtField: time.Now().Add(time.Second * time.Duration(expiresIn)).Unix(),
+	}
+	if responseBody.RefreshToken != "" {
+		data[constants.SessionRefreshTokenField] = responseBody.RefreshToken
+	}
+
+	err = s.sessionManagerService.UpdateElements(r, constants.UserSessionName, data)
+	if err != nil {
+		return "", fmt.Errorf("could not update session: %w", err)
+	}
+
+	return responseBody.IdToken, nil
+}
+
+// refreshFirebaseIDToken exchanges a refresh token for a new ID token through the Firebase REST API.
+// Like the sign in, a rejected refresh token is not an error but a response without an ID token.
+func (s *AuthService) refreshFirebaseIDToken(refreshToken string) (*types.RefreshTokenResponseBody, error) {
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	}
+
+	resp, err := http.PostForm("https://securetoken.googleapis.com/v1/token?key="+s.firebaseWebApiKey, form)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var responseBody types.RefreshTokenResponseBody
+	err = json.Unmarshal(body, &responseBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("securetoken: unexpected status %s", resp.Status)
+	}
+
+	return &responseBody, nil
+}
+
+// getSessionString returns a string element of the user session, or an empty string if it is not set.
+func (s *AuthService) getSessionString(r *http.Request, key string) (string, error) {
+	value, err := s.sessionManagerService.GetElement(r, constants.UserSessionName, key)
+	if err != nil {
+		return "", err
+	}
+
+	if value == nil {
+		return "", nil
+	}
+
+	// Convert the interface to a string
+	str, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("could not convert interface to string")
+	}
+
+	return str, nil
+}
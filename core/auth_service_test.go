@@ -0,0 +1,167 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+)
+
+// testFirebaseService accepts the ID tokens it lists, the other methods of IFirebaseService are not used by the tests.
+type testFirebaseService struct {
+	interfaces.IFirebaseService
+
+	idTokens map[string]bool
+	checked  []string
+}
+
+func (f *testFirebaseService) GetCustomClaims(idToken string) (map[string]interface{}, error) {
+	f.checked = append(f.checked, idToken)
+	if !f.idTokens[idToken] {
+		return nil, errors.New("ID token has expired")
+	}
+
+	return map[string]interface{}{constants.SessionEmailField: "owner@example.com"}, nil
+}
+
+func (f *testFirebaseService) CheckIsUserVerified(email string) (bool, error) {
+	return true, nil
+}
+
+// newRefreshFixture returns an AuthService whose ID tokens are refreshed by refresh, and the cookie of a login whose ID token expires in expiresIn seconds.
+func newRefreshFixture(t *testing.T, expiresIn string, refresh func(refreshToken string) (*types.RefreshTokenResponseBody, error)) (*AuthService, *SessionManagerService, *testFirebaseService, *http.Cookie) {
+	t.Helper()
+
+	firebase := &testFirebaseService{idTokens: map[string]bool{"id1": true, "id2": true}}
+	sessionManager := NewSessionManagerService(testSessionKey, NewMemorySessionStoreService())
+	s := NewAuthService(firebase, "", sessionManager, time.Hour, 24*time.Hour)
+	s.refreshIDToken = refresh
+
+	w := httptest.NewRecorder()
+	err := s.StartSession(w, loginRequest(nil), &types.LoginResponseBody{IdToken: "id1", RefreshToken: "r1", ExpiresIn: expiresIn, Email: "owner@example.com", LocalId: "u1"}, true, false)
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+
+	return s, sessionManager, firebase, w.Result().Cookies()[0]
+}
+
+// sessionString returns a string element of the session the cookie holds.
+func sessionString(t *testing.T, m *SessionManagerService, cookie *http.Cookie, key string) string {
+	t.Helper()
+
+	value, err := m.GetElement(loginRequest(cookie), constants.UserSessionName, key)
+	if err != nil {
+		t.Fatalf("GetElement: %v", err)
+	}
+
+	str, _ := value.(string)
+	return str
+}
+
+func TestCheckUserRefreshesIDToken(t *testing.T) {
+	var refreshed []string
+	s, m, firebase, cookie := newRefreshFixture(t, "60", func(refreshToken string) (*types.RefreshTokenResponseBody, error) {
+		refreshed = append(refreshed, refreshToken)
+		return &types.RefreshTokenResponseBody{IdToken: "id2", RefreshToken: "r2", ExpiresIn: "3600"}, nil
+	})
+
+	user, err := s.CheckUser(loginRequest(cookie))
+	if err != nil {
+		t.Fatalf("CheckUser: %v", err)
+	}
+	if !user.IsLoggedIn {
+		t.Errorf("IsLoggedIn = false, want true with the refreshed ID token")
+	}
+	if len(refreshed) != 1 || refreshed[0] != "r1" {
+		t.Errorf("refreshed = %v, want the stored refresh token r1", refreshed)
+	}
+	if len(firebase.checked) != 1 || firebase.checked[0] != "id2" {
+		t.Errorf("checked ID tokens = %v, want the refreshed id2", firebase.checked)
+	}
+
+	// The refreshed tokens are saved back to the session, the next request uses them without refreshing
+	if got := sessionString(t, m, cookie, constants.SessionIDTokenField); got != "id2" {
+		t.Errorf("stored ID token = %q, want id2", got)
+	}
+	if got := sessionString(t, m, cookie, constants.SessionRefreshTokenField); got != "r2" {
+		t.Errorf("stored refresh token = %q, want r2", got)
+	}
+
+	_, err = s.CheckUser(loginRequest(cookie))
+	if err != nil {
+		t.Fatalf("CheckUser: %v", err)
+	}
+	if len(refreshed) != 1 {
+		t.Errorf("refreshed = %v, want no second refresh", refreshed)
+	}
+}
+
+func TestCheckUserRefreshFailed(t *testing.T) {
+	tests := []struct {
+		name    string
+		refresh func(refreshToken string) (*types.RefreshTokenResponseBody, error)
+		wantErr bool
+	}{
+		{
+			name: "refresh token rejected",
+			refresh: func(refreshToken string) (*types.RefreshTokenResponseBody, error) {
+				return &types.RefreshTokenResponseBody{}, nil
+			},
+		},
+		{
+			name: "refresh failed",
+			refresh: func(refreshToken string) (*types.RefreshTokenResponseBody, error) {
+				return nil, errors.New("securetoken: unexpected status 503 Service Unavailable")
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, m, firebase, cookie := newRefreshFixture(t, "60", tt.refresh)
+
+			user, err := s.CheckUser(loginRequest(cookie))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("CheckUser = %+v, want an error", user)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("CheckUser: %v", err)
+				}
+				if user.IsLoggedIn {
+					t.Errorf("IsLoggedIn = true, want false once the refresh token is rejected")
+				}
+			}
+
+			if len(firebase.checked) != 0 {
+				t.Errorf("checked ID tokens = %v, want none", firebase.checked)
+			}
+			if got := sessionString(t, m, cookie, constants.SessionIDTokenField); got != "id1" {
+				t.Errorf("stored ID token = %q, want id1 unchanged", got)
+			}
+		})
+	}
+}
+
+func TestCheckUserWithoutRefresh(t *testing.T) {
+	s, _, firebase, cookie := newRefreshFixture(t, "3600", func(refreshToken string) (*types.RefreshTokenResponseBody, error) {
+		t.Errorf("refreshed %q, want no refresh of an ID token far from expiring", refreshToken)
+		return nil, errors.New("unexpected refresh")
+	})
+
+	user, err := s.CheckUser(loginRequest(cookie))
+	if err != nil {
+		t.Fatalf("CheckUser: %v", err)
+	}
+	if !user.IsLoggedIn || len(firebase.checked) != 1 || firebase.checked[0] != "id1" {
+		t.Errorf("user = %+v after checking %v, want logged in with id1", user, firebase.checked)
+	}
+}
@@ -0,0 +1,118 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AuthorizationService resolves the logged owner from the session and keeps each owner to the groups, contractors and timesheets of their own.
+type AuthorizationService struct {
+	sessionManagerService interfaces.ISessionManagerService
+
+	groupsDB      interfaces.IGroupsDatabaseService
+	contractorsDB interfaces.IContractorsDatabaseService
+	timesheetsDB  interfaces.ITimesheetsDatabaseService
+}
+
+// Ensure AuthorizationService implements IAuthorizationService.
+var _ interfaces.IAuthorizationService = &AuthorizationService{}
+
+// NewAuthorizationService creates a new AuthorizationService.
+func NewAuthorizationService(sessionManagerService interfaces.ISessionManagerService, groupsDB interfaces.IGroupsDatabaseService, contractorsDB interfaces.IContractorsDatabaseService, timesheetsDB interfaces.ITimesheetsDatabaseService) *AuthorizationService {
+	return &AuthorizationService{
+		sessionManagerService: sessionManagerService,
+
+		groupsDB:      groupsDB,
+		contractorsDB: contractorsDB,
+		timesheetsDB:  timesheetsDB,
+	}
+}
+
+// GetLoggedOwnerID returns the ID of the owner logged in the session, it returns an Unauthenticated status if there is none.
+func (s *AuthorizationService) GetLoggedOwnerID(r *http.Request) (string, error) {
+	ownerID, err := s.sessionManagerService.GetElement(r, constants.UserSessionName, constants.SesstionOwnerIdField)
+	if err != nil {
+		return "", fmt.Errorf("could not get owner id from session: %w", err)
+	}
+
+	ownerIDString, ok := ownerID.(string)
+	if !ok || ownerIDString == "" {
+		return "", status.Error(codes.Unauthenticated, "no owner is logged in")
+	}
+
+	return ownerIDString, nil
+}
+
+// AuthorizeOwner checks that an owner ID is the logged owner's.
+func (s *AuthorizationService) AuthorizeOwner(r *http.Request, ownerID string) error {
+	loggedOwnerID, err := s.GetLoggedOwnerID(r)
+	if err != nil {
+		return err
+	}
+
+	if ownerID != loggedOwnerID {
+		return status.Errorf(codes.NotFound, "owner %s not found", ownerID)
+	}
+
+	return nil
+}
+
+// GetOwnedGroup gets a group of the logged owner.
+func (s *AuthorizationService) GetOwnedGroup(r *http.Request, groupID string) (*types.Group, error) {
+	ownerID, err := s.GetLoggedOwnerID(r)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := s.groupsDB.GetGroup(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	if group.OwnerID != ownerID {
+		return nil, status.Errorf(codes.NotFound, "group %s not found", groupID)
+	}
+
+	return group, nil
+}
+
+// GetOwnedContractor gets a contractor of a group of the logged owner.
+func (s *AuthorizationService) GetOwnedContractor(r *http.Request, contractorID string) (*types.Contractor, error) {
+	contractor, err := s.contractorsDB.GetContractor(contractorID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.GetOwnedGroup(r, contractor.GroupID)
+	if status.Code(err) == codes.NotFound {
+		return nil, status.Errorf(codes.NotFound, "contractor %s not found", contractorID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return contractor, nil
+}
+
+// GetOwnedTimesheet gets a timesheet of a group of the logged owner.
+func (s *AuthorizationService) GetOwnedTimesheet(r *http.Request, timesheetID string) (*types.Timesheet, error) {
+	timesheet, err := s.timesheetsDB.GetTimesheetByID(timesheetID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.GetOwnedGroup(r, timesheet.GroupID)
+	if status.Code(err) == codes.NotFound {
+		return nil, status.Errorf(codes.NotFound, "timesheet %s not found", timesheetID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return timesheet, nil
+}
@@ -0,0 +1,317 @@
+package core
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"job_sender/interfaces"
+	constants "job_sender/utils/constants"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxSignedCallbackBody is the largest body of a signed callback, the callbacks only carry small JSON documents.
+const maxSignedCallbackBody = 1 << 20
+
+// CallbackAuthService authenticates the scheduler jobs and the other machines calling the internal routes back,
+// by an OIDC token of the app's service account or by a request signed with a shared key.
+type CallbackAuthService struct {
+	appURL string
+
+	keySet              *jwksKeySet // Nil if OIDC tokens are not accepted
+	issuer              string
+	serviceAccountEmail string
+
+	signingKey []byte // Empty if signed requests are not accepted
+
+	// The nonces of the signed requests accepted, by when their timestamp expires, so a signed request is not accepted twice by an instance
+	mu         sync.Mutex
+	usedNonces map[string]int64
+}
+
+// oidcHeader is the header of a JWT.
+type oidcHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// oidcClaims are the claims of an OIDC token the callbacks are checked against.
+type oidcClaims struct {
+	Issuer        string       `json:"iss"`
+	Audience      oidcAudience `json:"aud"`
+	ExpiresAt     int64        `json:"exp"`
+	IssuedAt      int64        `json:"iat"`
+	NotBefore     int64        `json:"nbf"`
+	Email         string       `json:"email"`
+	EmailVerified bool         `json:"email_verified"`
+}
+
+// oidcAudience is the audience of a JWT, a single one or a list.
+type oidcAudience []string
+
+// UnmarshalJSON decodes an audience that is either a string or a list of strings.
+func (a *oidcAudience) UnmarshalJSON(data []byte) error {
+	var audience string
+	if json.Unmarshal(data, &audience) == nil {
+		*a = oidcAudience{audience}
+		return nil
+	}
+
+	var audiences []string
+	err := json.Unmarshal(data, &audiences)
+	if err != nil {
+		return fmt.Errorf("audience must be a string or a list of strings: %w", err)
+	}
+	*a = audiences
+
+	return nil
+}
+
+// Ensure CallbackAuthService implements ICallbackAuthService.
+var _ interfaces.ICallbackAuthService = &CallbackAuthService{}
+
+// NewCallbackAuthService creates a new CallbackAuthService for the routes of the app at appURL, the audience of a token is the URL of its route.
+// OIDC tokens of issuer for serviceAccountEmail are accepted if jwksURL is not empty, signed requests if signingKey is not empty.
+func NewCallbackAuthService(appURL string, jwksURL string, issuer string, serviceAccountEmail string, signingKey []byte) *CallbackAuthService {
+	s := &CallbackAuthService{
+		appURL: strings.TrimSuffix(appURL, "/"),
+
+		issuer:              issuer,
+		serviceAccountEmail: serviceAccountEmail,
+
+		signingKey: signingKey,
+		usedNonces: map[string]int64{},
+	}
+
+	if jwksURL != "" {
+		s.keySet = newJwksKeySet(jwksURL)
+	}
+
+	return s
+}
+
+// VerifyCallback checks the OIDC token or the signature of a request and returns who sent it.
+// It returns an Unauthenticated status if the request has neither or they are not valid, and a PermissionDenied status if its sender is not allowed.
+func (s *CallbackAuthService) VerifyCallback(r *http.Request) (string, error) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return s.verifyOidcToken(r, token)
+	}
+
+	if r.Header.Get(constants.CallbackSignatureHeader) != "" {
+		return s.verifySignature(r)
+	}
+
+	return "", status.Error(codes.Unauthenticated, "the request has no OIDC token nor signature")
+}
+
+// SignCallback signs a request with the key signed callbacks are verified with, it returns a FailedPrecondition status if there is none.
+// The body of the request is read and replaced.
+func (s *CallbackAuthService) SignCallback(r *http.Request) error {
+	if len(s.signingKey) == 0 {
+		return status.Error(codes.FailedPrecondition, "there is no key to sign callbacks with")
+	}
+
+	body, err := readCallbackBody(r)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, 16)
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return fmt.Errorf("could not generate callback nonce: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	r.Header.Set(constants.CallbackTimestampHeader, timestamp)
+	r.Header.Set(constants.CallbackNonceHeader, hex.EncodeToString(nonce))
+	r.Header.Set(constants.CallbackSignatureHeader, s.sign(r, timestamp, hex.EncodeToString(nonce), body))
+
+	return nil
+}
+
+// verifyOidcToken checks the signature of an OIDC token against the JWKS, then its issuer, audience, validity and service account.
+func (s *CallbackAuthService) verifyOidcToken(r *http.Request, token string) (string, error) {
+	if s.keySet == nil {
+		return "", status.Error(codes.Unauthenticated, "OIDC tokens are not accepted")
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", status.Error(codes.Unauthenticated, "the OIDC token is not a JWT")
+	}
+
+	var header oidcHeader
+	err := decodeJwtPart(parts[0], &header)
+	if err != nil {
+		return "", status.Errorf(codes.Unauthenticated, "invalid OIDC token header: %v", err)
+	}
+
+	if header.Alg != "RS256" {
+		return "", status.Errorf(codes.Unauthenticated, "OIDC tokens signed with %q are not accepted", header.Alg)
+	}
+
+	key, err := s.keySet.key(header.Kid)
+	if err != nil {
+		return "", fmt.Errorf("could not get the key of the OIDC token: %w", err)
+	}
+	if key == nil {
+		return "", status.Errorf(codes.Unauthenticated, "the OIDC token is signed with unknown key %q", header.Kid)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", status.Errorf(codes.Unauthenticated, "invalid OIDC token signature: %v", err)
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	err = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature)
+	if err != nil {
+		return "", status.Error(codes.Unauthenticated, "the OIDC token signature is not valid")
+	}
+
+	var claims oidcClaims
+	err = decodeJwtPart(parts[1], &claims)
+	if err != nil {
+		return "", status.Errorf(codes.Unauthenticated, "invalid OIDC token claims: %v", err)
+	}
+
+	now := time.Now().Unix()
+	switch {
+	case claims.Issuer != s.issuer:
+		return "", status.Errorf(codes.Unauthenticated, "the OIDC token is issued by %q", claims.Issuer)
+	case now > claims.ExpiresAt+constants.CallbackMaxClockSkewSeconds:
+		return "", status.Error(codes.Unauthenticated, "the OIDC token has expired")
+	case now < claims.IssuedAt-constants.CallbackMaxClockSkewSeconds || now < claims.NotBefore-constants.CallbackMaxClockSkewSeconds:
+		return "", status.Error(codes.Unauthenticated, "the OIDC token is not valid yet")
+	}
+
+	// The scheduler jobs have the URL of their route as audience, a token for another route is not accepted
+	audience := s.appURL + r.URL.Path
+	if !slices.Contains(claims.Audience, audience) {
+		return "", status.Errorf(codes.PermissionDenied, "the OIDC token is not for %s", audience)
+	}
+
+	if !claims.EmailVerified || claims.Email != s.serviceAccountEmail {
+		return "", status.Errorf(codes.PermissionDenied, "%s may not call back the app", claims.Email)
+	}
+
+	return claims.Email, nil
+}
+
+// verifySignature checks the signature of a request, that it was sent within the allowed clock skew and that it was not accepted before.
+func (s *CallbackAuthService) verifySignature(r *http.Request) (string, error) {
+	if len(s.signingKey) == 0 {
+		return "", status.Error(codes.Unauthenticated, "signed requests are not accepted")
+	}
+
+	timestamp := r.Header.Get(constants.CallbackTimestampHeader)
+	sentAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", status.Errorf(codes.Unauthenticated, "the %s header must be a Unix time", constants.CallbackTimestampHeader)
+	}
+
+	now := time.Now().Unix()
+	skew := now - sentAt
+	if skew > constants.CallbackMaxClockSkewSeconds || skew < -constants.CallbackMaxClockSkewSeconds {
+		return "", status.Error(codes.Unauthenticated, "the signed request is too old or from the future")
+	}
+
+	nonce := r.Header.Get(constants.CallbackNonceHeader)
+	if nonce == "" {
+		return "", status.Errorf(codes.Unauthenticated, "the signed request has no %s header", constants.CallbackNonceHeader)
+	}
+
+	body, err := readCallbackBody(r)
+	if err != nil {
+		return "", status.Errorf(codes.Unauthenticated, "could not read the signed request: %v", err)
+	}
+
+	signature := r.Header.Get(constants.CallbackSignatureHeader)
+	if !hmac.Equal([]byte(signature), []byte(s.sign(r, timestamp, nonce, body))) {
+		return "", status.Error(codes.Unauthenticated, "the request signature is not valid")
+	}
+
+	if !s.useNonce(nonce, sentAt+constants.CallbackMaxClockSkewSeconds, now) {
+		return "", status.Error(codes.Unauthenticated, "the signed request was already accepted")
+	}
+
+	return "signed request", nil
+}
+
+// useNonce records the nonce of a signed request until it expires, it reports false if the nonce is already recorded.
+// The nonces that expired are forgotten, a request with one is too old to be accepted anyway.
+func (s *CallbackAuthService) useNonce(nonce string, expiresAt int64, now int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for used, usedExpiresAt := range s.usedNonces {
+		if usedExpiresAt < now {
+			delete(s.usedNonces, used)
+		}
+	}
+
+	if _, ok := s.usedNonces[nonce]; ok {
+		return false
+	}
+	s.usedNonces[nonce] = expiresAt
+
+	return true
+}
+
+// sign returns the hex HMAC-SHA256 of the timestamp, nonce, method, path with the query and SHA-256 of the body of a request, one per line.
+func (s *CallbackAuthService) sign(r *http.Request, timestamp string, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+
+	mac := hmac.New(sha256.New, s.signingKey)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s", timestamp, nonce, r.Method, r.URL.RequestURI(), hex.EncodeToString(bodyHash[:]))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// readCallbackBody reads the body of a request and replaces it, so it can be read again.
+func readCallbackBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSignedCallbackBody+1))
+	r.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("could not read body: %w", err)
+	}
+
+	if len(body) > maxSignedCallbackBody {
+		return nil, fmt.Errorf("the body is larger than %d bytes", maxSignedCallbackBody)
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
+
+// decodeJwtPart decodes the base64url JSON of the header or the claims of a JWT.
+func decodeJwtPart(part string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
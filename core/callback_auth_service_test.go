@@ -0,0 +1,261 @@
+package core
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	constants "job_sender/utils/constants"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	testCallbackAppURL         = "https://app.example.com"
+	testCallbackIssuer         = "https://issuer.example.com"
+	testCallbackServiceAccount = "scheduler@project.iam.gserviceaccount.com"
+)
+
+// testJwks serves the public key of key as kid in a JSON Web Key Set.
+func testJwks(t *testing.T, kid string, key *rsa.PrivateKey) *httptest.Server {
+	t.Helper()
+
+	document := map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": kid,
+			"kty": "RSA",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		json.NewEncoder(w).Encode(document)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// signJwt returns a JWT of the claims signed by key as kid with alg in its header, the signature is RS256 whatever alg says.
+func signJwt(t *testing.T, key *rsa.PrivateKey, kid string, alg string, claims map[string]interface{}) string {
+	t.Helper()
+
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	if err != nil {
+		t.Fatalf("Marshal header: %v", err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("Marshal claims: %v", err)
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("SignPKCS1v15: %v", err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerifyCallbackOidcToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	jwks := testJwks(t, "k1", key)
+	s := NewCallbackAuthService(testCallbackAppURL, jwks.URL, testCallbackIssuer, testCallbackServiceAccount, nil)
+
+	now := time.Now().Unix()
+
+	// validClaims are the claims of a scheduler job token for /timesheets/request, changed by the case
+	validClaims := func(change func(claims map[string]interface{})) map[string]interface{} {
+		claims := map[string]interface{}{
+			"iss":            testCallbackIssuer,
+			"aud":            testCallbackAppURL + "/timesheets/request",
+			"exp":            now + 3600,
+			"iat":            now,
+			"email":          testCallbackServiceAccount,
+			"email_verified": true,
+		}
+		if change != nil {
+			change(claims)
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		wantCode codes.Code
+	}{
+		{"valid", signJwt(t, key, "k1", "RS256", validClaims(nil)), codes.OK},
+		{"audience list", signJwt(t, key, "k1", "RS256", validClaims(func(c map[string]interface{}) {
+			c["aud"] = []string{"other", testCallbackAppURL + "/timesheets/request"}
+		})), codes.OK},
+		{"expired within the clock skew", signJwt(t, key, "k1", "RS256", validClaims(func(c map[string]interface{}) { c["exp"] = now - 60 })), codes.OK},
+		{"other key", signJwt(t, otherKey, "k1", "RS256", validClaims(nil)), codes.Unauthenticated},
+		{"unknown key", signJwt(t, key, "k2", "RS256", validClaims(nil)), codes.Unauthenticated},
+		{"not RS256", signJwt(t, key, "k1", "HS256", validClaims(nil)), codes.Unauthenticated},
+		{"not a JWT", "token", codes.Unauthenticated},
+		{"tampered claims", func() string {
+			parts := strings.Split(signJwt(t, key, "k1", "RS256", validClaims(nil)), ".")
+			forged, _ := json.Marshal(validClaims(func(c map[string]interface{}) { c["email"] = "attacker@example.com" }))
+			return parts[0] + "." + base64.RawURLEncoding.EncodeToString(forged) + "." + parts[2]
+		}(), codes.Unauthenticated},
+		{"other issuer", signJwt(t, key, "k1", "RS256", validClaims(func(c map[string]interface{}) { c["iss"] = "https://other.example.com" })), codes.Unauthenticated},
+		{"expired", signJwt(t, key, "k1", "RS256", validClaims(func(c map[string]interface{}) {
+			c["exp"] = now - constants.CallbackMaxClockSkewSeconds - 60
+		})), codes.Unauthenticated},
+		{"not valid yet", signJwt(t, key, "k1", "RS256", validClaims(func(c map[string]interface{}) {
+			c["nbf"] = now + constants.CallbackMaxClockSkewSeconds + 60
+		})), codes.Unauthenticated},
+		{"other route", signJwt(t, key, "k1", "RS256", validClaims(func(c map[string]interface{}) { c["aud"] = testCallbackAppURL + "/timesheets/remind" })), codes.PermissionDenied},
+		{"other app", signJwt(t, key, "k1", "RS256", validClaims(func(c map[string]interface{}) { c["aud"] = "https://other.example.com/timesheets/request" })), codes.PermissionDenied},
+		{"other service account", signJwt(t, key, "k1", "RS256", validClaims(func(c map[string]interface{}) { c["email"] = "other@project.iam.gserviceaccount.com" })), codes.PermissionDenied},
+		{"email not verified", signJwt(t, key, "k1", "RS256", validClaims(func(c map[string]interface{}) { c["email_verified"] = false })), codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/timesheets/request?groupID=g1", nil)
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+
+			caller, err := s.VerifyCallback(r)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("VerifyCallback error = %v, want %v", err, tt.wantCode)
+			}
+			if tt.wantCode == codes.OK && caller != testCallbackServiceAccount {
+				t.Errorf("caller = %q, want %q", caller, testCallbackServiceAccount)
+			}
+		})
+	}
+}
+
+func TestVerifyCallbackSignature(t *testing.T) {
+	key := []byte("callback-key")
+
+	// signedRequest returns a request to /timesheets/request signed at sentAt with the nonce, by the service of key
+	signedRequest := func(key []byte, sentAt int64, nonce string) *http.Request {
+		r := httptest.NewRequest("POST", "/timesheets/request?groupID=g1", strings.NewReader(`{"a":1}`))
+		timestamp := strconv.FormatInt(sentAt, 10)
+		r.Header.Set(constants.CallbackTimestampHeader, timestamp)
+		r.Header.Set(constants.CallbackNonceHeader, nonce)
+		r.Header.Set(constants.CallbackSignatureHeader, NewCallbackAuthService(testCallbackAppURL, "", "", "", key).sign(r, timestamp, nonce, []byte(`{"a":1}`)))
+		return r
+	}
+
+	now := time.Now().Unix()
+
+	tests := []struct {
+		name     string
+		request  func() *http.Request
+		wantCode codes.Code
+	}{
+		{"valid", func() *http.Request { return signedRequest(key, now, "n1") }, codes.OK},
+		{"behind within the clock skew", func() *http.Request {
+			return signedRequest(key, now-constants.CallbackMaxClockSkewSeconds+10, "n2")
+		}, codes.OK},
+		{"ahead within the clock skew", func() *http.Request {
+			return signedRequest(key, now+constants.CallbackMaxClockSkewSeconds-10, "n3")
+		}, codes.OK},
+		{"wrong signature", func() *http.Request { return signedRequest([]byte("other-key"), now, "n4") }, codes.Unauthenticated},
+		{"tampered body", func() *http.Request {
+			r := signedRequest(key, now, "n5")
+			r.Body = http.NoBody
+			return r
+		}, codes.Unauthenticated},
+		{"other route", func() *http.Request {
+			r := signedRequest(key, now, "n6")
+			r.URL.RawQuery = "groupID=g2"
+			return r
+		}, codes.Unauthenticated},
+		{"stale timestamp", func() *http.Request {
+			return signedRequest(key, now-constants.CallbackMaxClockSkewSeconds-60, "n7")
+		}, codes.Unauthenticated},
+		{"timestamp from the future", func() *http.Request {
+			return signedRequest(key, now+constants.CallbackMaxClockSkewSeconds+60, "n8")
+		}, codes.Unauthenticated},
+		{"timestamp changed", func() *http.Request {
+			r := signedRequest(key, now, "n9")
+			r.Header.Set(constants.CallbackTimestampHeader, strconv.FormatInt(now-1, 10))
+			return r
+		}, codes.Unauthenticated},
+		{"no timestamp", func() *http.Request {
+			r := signedRequest(key, now, "n10")
+			r.Header.Del(constants.CallbackTimestampHeader)
+			return r
+		}, codes.Unauthenticated},
+		{"no nonce", func() *http.Request { return signedRequest(key, now, "") }, codes.Unauthenticated},
+		{"replayed", func() *http.Request { return signedRequest(key, now, "n1") }, codes.Unauthenticated},
+	}
+
+	// The cases run in order on the same service, so the replayed case finds the nonce of the valid one
+	s := NewCallbackAuthService(testCallbackAppURL, "", "", "", key)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.VerifyCallback(tt.request())
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("VerifyCallback error = %v, want %v", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestSignCallback(t *testing.T) {
+	s := NewCallbackAuthService(testCallbackAppURL, "", "", "", []byte("callback-key"))
+
+	sign := func() *http.Request {
+		r := httptest.NewRequest("POST", "/timesheets/remind?groupID=g1", strings.NewReader("body"))
+		err := s.SignCallback(r)
+		if err != nil {
+			t.Fatalf("SignCallback: %v", err)
+		}
+		return r
+	}
+
+	first, second := sign(), sign()
+	if first.Header.Get(constants.CallbackNonceHeader) == second.Header.Get(constants.CallbackNonceHeader) {
+		t.Errorf("nonce = %q twice, want a new one for every request", first.Header.Get(constants.CallbackNonceHeader))
+	}
+
+	for _, r := range []*http.Request{first, second} {
+		_, err := s.VerifyCallback(r)
+		if err != nil {
+			t.Fatalf("VerifyCallback: %v", err)
+		}
+	}
+
+	// The body is read again by the handler
+	body := make([]byte, 4)
+	n, _ := first.Body.Read(body)
+	if string(body[:n]) != "body" {
+		t.Errorf("body = %q, want %q", body[:n], "body")
+	}
+
+	unsigned := NewCallbackAuthService(testCallbackAppURL, "", "", "", nil)
+	err := unsigned.SignCallback(httptest.NewRequest("POST", "/timesheets/remind", nil))
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("SignCallback error = %v, want FailedPrecondition", err)
+	}
+}
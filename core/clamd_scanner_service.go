@@ -0,0 +1,109 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"job_sender/interfaces"
+)
+
+// clamdChunkSize is the size of the chunks a file is streamed to the ClamAV daemon in, below its default StreamMaxLength.
+const clamdChunkSize = 64 << 10
+
+// clamdTimeout is how long a scan may take, from connecting to the daemon to its reply.
+const clamdTimeout = 2 * time.Minute
+
+// ClamdScannerService scans the files with a ClamAV daemon, streaming them over its socket with the INSTREAM command.
+type ClamdScannerService struct {
+	network string
+	address string
+}
+
+// Ensure ClamdScannerService implements IMalwareScannerService.
+var _ interfaces.IMalwareScannerService = &ClamdScannerService{}
+
+// NewClamdScannerService creates a new ClamdScannerService for the daemon at address, a TCP address like tcp:clamav:3310 or clamav:3310,
+// or a Unix socket like unix:/run/clamav/clamd.ctl.
+func NewClamdScannerService(address string) (*ClamdScannerService, error) {
+	network, addr, ok := strings.Cut(address, ":")
+	if !ok || (network != "tcp" && network != "unix") {
+		network, addr = "tcp", address
+	}
+
+	if addr == "" {
+		return nil, fmt.Errorf("invalid ClamAV daemon address %q", address)
+	}
+
+	return &ClamdScannerService{
+		network: network,
+		address: addr,
+	}, nil
+}
+
+// ScanFile streams a file to the ClamAV daemon, it returns the name of the malware found, or an empty name if the file is clean.
+func (s *ClamdScannerService) ScanFile(content []byte) (string, error) {
+	conn, err := net.DialTimeout(s.network, s.address, clamdTimeout)
+	if err != nil {
+		return "", fmt.Errorf("could not connect to ClamAV daemon: %w", err)
+	}
+	defer conn.Close()
+
+	err = conn.SetDeadline(time.Now().Add(clamdTimeout))
+	if err != nil {
+		return "", fmt.Errorf("could not set ClamAV deadline: %w", err)
+	}
+
+	// The z prefix makes the daemon end its reply with a NUL byte
+	w := bufio.NewWriter(conn)
+	_, err = w.WriteString("zINSTREAM\x00")
+	if err != nil {
+		return "", fmt.Errorf("could not write to ClamAV daemon: %w", err)
+	}
+
+	// Each chunk is prefixed with its length, a zero length ends the stream
+	for start := 0; start < len(content); start += clamdChunkSize {
+		chunk := content[start:min(start+clamdChunkSize, len(content))]
+
+		err = binary.Write(w, binary.BigEndian, uint32(len(chunk)))
+		if err == nil {
+			_, err = w.Write(chunk)
+		}
+		if err != nil {
+			return "", fmt.Errorf("could not write to ClamAV daemon: %w", err)
+		}
+	}
+
+	err = binary.Write(w, binary.BigEndian, uint32(0))
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not write to ClamAV daemon: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil {
+		return "", fmt.Errorf("could not read ClamAV reply: %w", err)
+	}
+
+	return parseClamdReply(string(bytes.TrimSuffix(reply, []byte{0})))
+}
+
+// parseClamdReply reads the reply to a scanned stream, "stream: OK", "stream: <malware> FOUND" or "<error> ERROR".
+func parseClamdReply(reply string) (string, error) {
+	result := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+
+	switch {
+	case result == "OK":
+		return "", nil
+	case strings.HasSuffix(result, " FOUND"):
+		return strings.TrimSuffix(result, " FOUND"), nil
+	default:
+		return "", fmt.Errorf("ClamAV daemon could not scan the file: %s", reply)
+	}
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"job_sender/utils/fakeclamd"
+)
+
+func TestClamdScannerServiceScanFile(t *testing.T) {
+	server := fakeclamd.NewServer()
+	err := server.Start("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	// The signature straddles two chunks of the stream
+	straddling := append(bytes.Repeat([]byte{'a'}, clamdChunkSize-10), fakeclamd.EicarSignature...)
+
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+		wantErr bool
+	}{
+		{"clean", []byte("Date,Hours\n2025-01-06,8\n"), "", false},
+		{"empty", nil, "", false},
+		{"infected", []byte("Date,Hours\n" + fakeclamd.EicarSignature), fakeclamd.EicarMalwareName, false},
+		{"infected across chunks", straddling, fakeclamd.EicarMalwareName, false},
+		{"oversize", make([]byte, 25<<20+1), "", true},
+	}
+
+	scanner, err := NewClamdScannerService(server.Addr())
+	if err != nil {
+		t.Fatalf("NewClamdScannerService: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := scanner.ScanFile(tt.content)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ScanFile = %q, want an error", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ScanFile: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ScanFile = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClamdScannerServiceDaemonDown(t *testing.T) {
+	// A port that was free a moment ago, nothing listens on it
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	scanner, err := NewClamdScannerService("tcp:" + address)
+	if err != nil {
+		t.Fatalf("NewClamdScannerService: %v", err)
+	}
+
+	_, err = scanner.ScanFile([]byte("Date,Hours\n"))
+	if err == nil {
+		t.Fatal("ScanFile succeeded without a daemon")
+	}
+}
+
+func TestNewClamdScannerService(t *testing.T) {
+	tests := []struct {
+		address     string
+		wantNetwork string
+		wantAddress string
+		wantErr     bool
+	}{
+		{"clamav:3310", "tcp", "clamav:3310", false},
+		{"tcp:clamav:3310", "tcp", "clamav:3310", false},
+		{"unix:/run/clamav/clamd.ctl", "unix", "/run/clamav/clamd.ctl", false},
+		{"unix:", "", "", true},
+		{"", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			scanner, err := NewClamdScannerService(tt.address)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NewClamdScannerService = %+v, want an error", scanner)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("NewClamdScannerService: %v", err)
+			}
+			if scanner.network != tt.wantNetwork || scanner.address != tt.wantAddress {
+				t.Errorf("NewClamdScannerService = %s %s, want %s %s", scanner.network, scanner.address, tt.wantNetwork, tt.wantAddress)
+			}
+		})
+	}
+}
@@ -14,9 +14,11 @@ import (
 )
 
 type ContractorsDatabaseService struct {
-	contractorsCollectionName string
-	timesheetsCollectionName  string
-	client                    *firestore.Client
+	contractorsCollectionName       string
+	timesheetsCollectionName        string
+	timesheetRequestsCollectionName string
+	ingestionsCollectionName        string
+	client                          *firestore.Client
 }
 
 // Ensure ContractorsDatabaseService implements IContractorsDatabaseService.
@@ -39,9 +41,11 @@ func NewContractorsDatabaseService(firebaseService *FirebaseService) (*Contracto
 	}
 
 	return &ContractorsDatabaseService{
-		contractorsCollectionName: "contractors",
-		timesheetsCollectionName:  "timesheets",
-		client:                    client,
+		contractorsCollectionName:       "contractors",
+		timesheetsCollectionName:        "timesheets",
+		timesheetRequestsCollectionName: "timesheet_requests",
+		ingestionsCollectionName:        "timesheet_ingestions",
+		client:                          client,
 	}, nil
 }
 
@@ -76,6 +80,58 @@ func (db *ContractorsDatabaseService) GetContractors(groupID string) ([]*types.C
 	return contractors, nil
 }
 
+// GetAllContractors gets the contractors of every group.
+func (db *ContractorsDatabaseService) GetAllContractors() ([]*types.Contractor, error) {
+	ctx := context.Background()
+	iter := db.client.Collection(db.contractorsCollectionName).Documents(ctx)
+
+	var contractors []*types.Contractor
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("firestoredb: could not list contractors: %w", err)
+		}
+
+		contractor := &types.Contractor{}
+		if err := doc.DataTo(contractor); err != nil {
+			return nil, fmt.Errorf("firestoredb: could not convert data to contractor: %w", err)
+		}
+
+		contractors = append(contractors, contractor)
+	}
+
+	return contractors, nil
+}
+
+// GetContractorsByEmail gets the contractors with an email address, in every group.
+func (db *ContractorsDatabaseService) GetContractorsByEmail(email string) ([]*types.Contractor, error) {
+	ctx := context.Background()
+	iter := db.client.Collection(db.contractorsCollectionName).Where("email", "==", email).Documents(ctx)
+
+	var contractors []*types.Contractor
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("firestoredb: could not list contractors: %w", err)
+		}
+
+		contractor := &types.Contractor{}
+		if err := doc.DataTo(contractor); err != nil {
+			return nil, fmt.Errorf("firestoredb: could not convert data to contractor: %w", err)
+		}
+
+		contractors = append(contractors, contractor)
+	}
+
+	return contractors, nil
+}
+
 // GetContractor gets a contractor by ID.
 func (db *ContractorsDatabaseService) GetContractor(id string) (*types.Contractor, error) {
 	ctx := context.Background()
@@ -123,6 +179,7 @@ func (db *ContractorsDatabaseService) AddContractor(groupID string, contractor *
 		"email":     contractor.Email,
 		"phone":     contractor.Phone,
 		"photo_url": contractor.PhotoURL,
+		"language":  contractor.Language,
 	}
 
 	_, err := ref.Create(ctx, contractorMap)
@@ -145,9 +202,9 @@ func (db *ContractorsDatabaseService) UpdateContractor(contractor *types.Contrac
 		"email":     contractor.Email,
 		"phone":     contractor.Phone,
 		"photo_url": contractor.PhotoURL,
+		"language":  contractor.Language,
 
-		"last_requests":              contractor.LastRequests,
-		"last_aggregation_timestamp": contractor.LastAggregationTimestamp,
+		"last_requests": contractor.LastRequests,
 	})
 	if err != nil {
 		return fmt.Errorf("firestoredb: could not update contractor: %w", err)
@@ -156,10 +213,34 @@ func (db *ContractorsDatabaseService) UpdateContractor(contractor *types.Contrac
 	return nil
 }
 
-// DeleteContractor deletes a contractor.
+// DeleteContractor deletes a contractor with its timesheet requests and ingestions.
 func (db *ContractorsDatabaseService) DeleteContractor(id string) error {
 	ctx := context.Background()
-	_, err := db.client.Collection(db.contractorsCollectionName).Doc(id).Delete(ctx)
+	requests, err := db.client.Collection(db.timesheetRequestsCollectionName).Where("contractor_id", "==", id).Documents(ctx).GetAll()
+	if err != nil {
+		return fmt.Errorf("firestoredb: could not get timesheet requests: %w", err)
+	}
+
+	for _, request := range requests {
+		_, err = request.Ref.Delete(ctx)
+		if err != nil {
+			return fmt.Errorf("firestoredb: could not delete timesheet request: %w", err)
+		}
+	}
+
+	ingestions, err := db.client.Collection(db.ingestionsCollectionName).Where("contractor_id", "==", id).Documents(ctx).GetAll()
+	if err != nil {
+		return fmt.Errorf("firestoredb: could not get timesheet ingestions: %w", err)
+	}
+
+	for _, ingestion := range ingestions {
+		_, err = ingestion.Ref.Delete(ctx)
+		if err != nil {
+			return fmt.Errorf("firestoredb: could not delete timesheet ingestion: %w", err)
+		}
+	}
+
+	_, err = db.client.Collection(db.contractorsCollectionName).Doc(id).Delete(ctx)
 	if err != nil {
 		return fmt.Errorf("firestoredb: could not delete contractor: %w", err)
 	}
@@ -1,11 +1,19 @@
 package core
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
 	"fmt"
 	"io"
-	"mime"
+	"log"
+	"net"
 	"net/smtp"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"job_sender/interfaces"
 	"job_sender/types"
@@ -14,201 +22,642 @@ import (
 	"github.com/emersion/go-imap"
 	"github.com/emersion/go-imap/client"
 	"github.com/emersion/go-message/mail"
+	"github.com/emersion/go-sasl"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type EmailService struct {
 	email       string
 	appPassword string
+
+	transport *types.MailTransport
+
+	emailTemplateService interfaces.IEmailTemplateService
 }
 
 // Ensure EmailService implements the IEmailService interface.
 var _ interfaces.IEmailService = &EmailService{}
 
-func NewEmailService(email string, appPassword string) *EmailService {
+// NewEmailService creates a new EmailService that talks to the servers described by the transport and renders the emails with the template service.
+func NewEmailService(email string, appPassword string, transport *types.MailTransport, emailTemplateService interfaces.IEmailTemplateService) *EmailService {
 	return &EmailService{
 		email:       email,
 		appPassword: appPassword,
+
+		transport: transport,
+
+		emailTemplateService: emailTemplateService,
 	}
 }
 
 // SendVerificationEmail sends a verification email to the user.
 func (h *EmailService) SendVerificationEmail(to string, link string) error {
-	subject := "Job sender account verification"
-	body := fmt.Sprintf("Click the link to verify your Job sender account: %s", link)
-	msg := fmt.Sprintf("From: %s\nTo: %s\nSubject: %s\n\n%s", h.email, to, subject, body)
+	data := map[string]interface{}{
+		"Link": link,
+	}
+
+	return h.sendTemplateEmail(&mail.Address{Address: to}, constants.DefaultLanguage, nil, constants.EmailTemplateVerificationName, data, mail.Header{})
+}
+
+// SendPasswordResetEmail sends a password reset email to the user.
+func (h *EmailService) SendPasswordResetEmail(to string, link string) error {
+	data := map[string]interface{}{
+		"Link":            link,
+		"LifetimeMinutes": constants.PasswordResetLinkLifetimeMinutes,
+	}
 
-	// Use smtp.PlainAuth with the app password
-	auth := smtp.PlainAuth("", h.email, h.appPassword, constants.SmtpGmailAddress)
+	return h.sendTemplateEmail(&mail.Address{Address: to}, constants.DefaultLanguage, nil, constants.EmailTemplatePasswordResetName, data, mail.Header{})
+}
+
+// SendEmailChangeEmail sends the link confirming the new email of a user to the new address.
+func (h *EmailService) SendEmailChangeEmail(to string, link string) error {
+	data := map[string]interface{}{
+		"Email":         to,
+		"Link":          link,
+		"LifetimeHours": constants.EmailChangeLinkLifetimeHours,
+	}
+
+	return h.sendTemplateEmail(&mail.Address{Address: to}, constants.DefaultLanguage, nil, constants.EmailTemplateEmailChangeName, data, mail.Header{})
+}
 
-	// Gmail SMTP server requires TLS connection on port 587
-	err := smtp.SendMail(fmt.Sprintf("%s:%s", constants.SmtpGmailAddress, strconv.Itoa(constants.SmtpGmailPort)), auth, h.email, []string{to}, []byte(msg))
+// SendTimesheetRequestEmail sends a timesheet request email to the contractor, with the link to submit the timesheet on if there is one.
+// The group's email content replaces the default wording.
+func (h *EmailService) SendTimesheetRequestEmail(group *types.Group, contractor *types.Contractor, request *types.TimesheetRequest, submissionLink string) error {
+	header, err := h.replyHeader(request)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	data, err := h.timesheetRequestData(group, contractor, request, submissionLink, group.EmailContent.RequestBody)
+	if err != nil {
+		return err
+	}
+
+	return h.sendTemplateEmail(contractorAddress(contractor), contractor.Language, group, constants.EmailTemplateRequestName, data, header)
 }
 
-// SendTimesheetRequestEmail sends a timesheet request email to the contractor.
-func (h *EmailService) SendTimesheetRequestEmail(contractor *types.Contractor, requestID string) error {
-	subject := fmt.Sprintf("Timesheet %s [%s]", requestID, contractor.ID)
-	body := fmt.Sprintf("Hi %s %s. Please submit your timesheet. You can submit it by replying to this email with the timesheet attached.", contractor.Name, contractor.Surname)
-	msg := fmt.Sprintf("From: %s\nTo: %s\nSubject: %s\n\n%s", h.email, contractor.Email, subject, body)
+// PreviewTimesheetRequestEmail renders the timesheet request email the contractor would get, without sending it.
+func (h *EmailService) PreviewTimesheetRequestEmail(group *types.Group, contractor *types.Contractor, request *types.TimesheetRequest, submissionLink string) (*types.RenderedEmail, error) {
+	data, err := h.timesheetRequestData(group, contractor, request, submissionLink, group.EmailContent.RequestBody)
+	if err != nil {
+		return nil, err
+	}
 
-	// Use smtp.PlainAuth with the app password
-	auth := smtp.PlainAuth("", h.email, h.appPassword, constants.SmtpGmailAddress)
+	return h.emailTemplateService.RenderEmail(constants.EmailTemplateRequestName, contractor.Language, group, data)
+}
 
-	// Gmail SMTP server requires TLS connection on port 587
-	err := smtp.SendMail(fmt.Sprintf("%s:%s", constants.SmtpGmailAddress, strconv.Itoa(constants.SmtpGmailPort)), auth, h.email, []string{contractor.Email}, []byte(msg))
+// SendTimesheetRejectionEmail asks the contractor for a corrected timesheet, the reply is matched to the request the same way as the replies to the request.
+func (h *EmailService) SendTimesheetRejectionEmail(group *types.Group, contractor *types.Contractor, request *types.TimesheetRequest, reason string) error {
+	header, err := h.replyHeader(request)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	data := map[string]interface{}{
+		"Contractor": contractor,
+		"RequestID":  requestSubjectID(request.RequestID),
+		"Reason":     reason,
+	}
+
+	return h.sendTemplateEmail(contractorAddress(contractor), contractor.Language, group, constants.EmailTemplateRejectionName, data, header)
 }
 
-// GetEmailAttachments returns the attachments of an email.
-func (h *EmailService) GetEmailAttachments(subject string) ([]types.Attachment, error) {
-	// Create a new IMAP client instance
-	c, err := client.DialTLS(constants.ImapGmailAddress+":993", nil)
+// SendFileRejectionEmail tells the contractor why a timesheet file sent for a request was rejected, the reply is matched to the request
+// the same way as the replies to the request.
+func (h *EmailService) SendFileRejectionEmail(group *types.Group, contractor *types.Contractor, request *types.TimesheetRequest, rejection *types.FileRejection) error {
+	header, err := h.replyHeader(request)
+	if err != nil {
+		return err
+	}
+
+	allowedTypes := make([]string, len(rejection.AllowedTypes))
+	for i, fileType := range rejection.AllowedTypes {
+		allowedTypes[i] = fileType.String()
+	}
+
+	data := map[string]interface{}{
+		"Contractor":   contractor,
+		"RequestID":    requestSubjectID(request.RequestID),
+		"Filename":     rejection.Filename,
+		"Reason":       rejection.Reason.Code(),
+		"AllowedTypes": strings.Join(allowedTypes, ", "),
+		"MaxSizeMB":    rejection.MaxSizeMB,
+	}
+
+	return h.sendTemplateEmail(contractorAddress(contractor), contractor.Language, group, constants.EmailTemplateFileRejectionName, data, header)
+}
+
+// SendTimesheetReminderEmail reminds the contractor about a missing timesheet, the reply is matched to the request the same way as the replies to the request.
+// The group's email content replaces the default wording.
+func (h *EmailService) SendTimesheetReminderEmail(group *types.Group, contractor *types.Contractor, request *types.TimesheetRequest) error {
+	header, err := h.replyHeader(request)
+	if err != nil {
+		return err
+	}
+
+	data, err := h.timesheetRequestData(group, contractor, request, "", group.EmailContent.ReminderBody)
+	if err != nil {
+		return err
+	}
+
+	return h.sendTemplateEmail(contractorAddress(contractor), contractor.Language, group, constants.EmailTemplateReminderName, data, header)
+}
+
+// PreviewTimesheetReminderEmail renders the reminder email the contractor would get, without sending it.
+func (h *EmailService) PreviewTimesheetReminderEmail(group *types.Group, contractor *types.Contractor, request *types.TimesheetRequest) (*types.RenderedEmail, error) {
+	data, err := h.timesheetRequestData(group, contractor, request, "", group.EmailContent.ReminderBody)
 	if err != nil {
 		return nil, err
 	}
-	defer c.Logout()
 
-	// Login to the IMAP server
-	err = c.Login(h.email, h.appPassword)
+	return h.emailTemplateService.RenderEmail(constants.EmailTemplateReminderName, contractor.Language, group, data)
+}
+
+// SendMissingTimesheetsEmail sends the owner a digest of the timesheets still missing in a group.
+func (h *EmailService) SendMissingTimesheetsEmail(to string, group *types.Group, missing []types.MissingTimesheet) error {
+	// The requests are shown the way they are in the subjects of the emails the contractors got
+	shown := make([]types.MissingTimesheet, len(missing))
+	for i, m := range missing {
+		shown[i] = m
+		shown[i].RequestID = requestSubjectID(m.RequestID)
+	}
+
+	data := map[string]interface{}{
+		"GroupName": group.Name,
+		"Missing":   shown,
+	}
+
+	return h.sendTemplateEmail(&mail.Address{Address: to}, constants.DefaultLanguage, group, constants.EmailTemplateMissingName, data, mail.Header{})
+}
+
+// GetUnassignedEmails returns the emails of the unassigned mailbox, the ones the inbox watcher could not match to a request.
+func (h *EmailService) GetUnassignedEmails() ([]*types.InboundEmail, error) {
+	c, err := h.selectUnassignedMailbox()
 	if err != nil {
-		return nil, fmt.Errorf("failed to login: %w", err)
+		return nil, err
 	}
+	defer c.Logout()
+
+	criteria := imap.NewSearchCriteria()
+	criteria.WithoutFlags = []string{imap.DeletedFlag}
 
-	// Select the INBOX
-	_, err = c.Select("INBOX", false)
+	return fetchEmails(c, criteria)
+}
+
+// GetUnassignedEmail returns the email of the unassigned mailbox an unassigned email was triaged from. It is searched by its Message-ID,
+// or by its sender and subject if it has none, and told apart from the others found by the ID of its key.
+func (h *EmailService) GetUnassignedEmail(unassigned *types.UnassignedEmail) (*types.InboundEmail, error) {
+	c, err := h.selectUnassignedMailbox()
 	if err != nil {
-		return nil, fmt.Errorf("failed to select INBOX: %w", err)
+		return nil, err
 	}
+	defer c.Logout()
 
-	// Search for emails with the specified subject
 	criteria := imap.NewSearchCriteria()
-	criteria.Header.Add("Subject", mime.QEncoding.Encode("utf-8", subject))
-	criteria.WithoutFlags = []string{"\\Seen"} // Exclude emails that are marked as Seen
-	uids, err := c.Search(criteria)
+	criteria.WithoutFlags = []string{imap.DeletedFlag}
+	if unassigned.MessageID != "" {
+		criteria.Header.Add("Message-Id", unassigned.MessageID)
+	} else {
+		criteria.Header.Add("From", unassigned.From)
+		criteria.Header.Add("Subject", unassigned.Subject)
+	}
+
+	emails, err := fetchEmails(c, criteria)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search emails: %w", err)
+		return nil, err
 	}
 
-	// Nothing to process
-	if len(uids) == 0 {
-		return []types.Attachment{}, nil
+	for _, email := range emails {
+		if hashHex([]byte(emailIngestionKey(email))) == unassigned.ID {
+			return email, nil
+		}
 	}
 
-	// Fetch the emails
-	seqSet := new(imap.SeqSet)
-	seqSet.AddNum(uids...)
+	return nil, status.Errorf(codes.NotFound, "email %q not found in %s", unassigned.Subject, constants.UnassignedMailbox)
+}
+
+// ArchiveUnassignedEmail removes the email with the given UID from the unassigned mailbox, once it is assigned or discarded.
+func (h *EmailService) ArchiveUnassignedEmail(uid uint32) error {
+	c, err := h.selectUnassignedMailbox()
+	if err != nil {
+		return err
+	}
+	defer c.Logout()
 
-	// We're only interested in the email structure and attachments
-	section := &imap.BodySectionName{}
-	items := []imap.FetchItem{imap.FetchBodyStructure, section.FetchItem()}
-	messages := make(chan *imap.Message)
+	return archiveEmails(c, []uint32{uid})
+}
 
-	var attachments []types.Attachment
-	done := make(chan error, 1)
+// SendReplyEmail replies to an inbound email in its thread, on behalf of the group.
+func (h *EmailService) SendReplyEmail(group *types.Group, email *types.InboundEmail, body string) error {
+	subject := email.Subject
+	if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+		subject = "Re: " + subject
+	}
 
-	// Start a goroutine to fetch messages
-	go func() {
-		done <- c.Fetch(seqSet, items, messages)
-	}()
+	// Thread the reply under the email, as mail clients do
+	var header mail.Header
+	if email.MessageID != "" {
+		header.SetMsgIDList("In-Reply-To", []string{email.MessageID})
+		header.SetMsgIDList("References", append(slices.Clone(email.References), email.MessageID))
+	}
 
-	// Process messages as they come in
-	for msg := range messages {
-		attachs, err := h.extractAttachments(msg, c)
+	data := map[string]interface{}{
+		"Subject": subject,
+		"Message": body,
+	}
+
+	return h.sendTemplateEmail(&mail.Address{Address: email.From}, constants.DefaultLanguage, group, constants.EmailTemplateReplyName, data, header)
+}
+
+// SendUnknownSenderEmail replies to an email attributed to no group in its thread, asking the sender to answer the request email they got instead.
+// The reply is marked as automatic, so an automatic reply to it is never answered again.
+func (h *EmailService) SendUnknownSenderEmail(email *types.InboundEmail) error {
+	subject := email.Subject
+	if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+		subject = "Re: " + subject
+	}
+
+	var header mail.Header
+	header.Set("Auto-Submitted", "auto-replied")
+	if email.MessageID != "" {
+		header.SetMsgIDList("In-Reply-To", []string{email.MessageID})
+		header.SetMsgIDList("References", append(slices.Clone(email.References), email.MessageID))
+	}
+
+	data := map[string]interface{}{
+		"Subject": subject,
+	}
+
+	return h.sendTemplateEmail(&mail.Address{Address: email.From}, constants.DefaultLanguage, nil, constants.EmailTemplateUnknownSenderName, data, header)
+}
+
+// selectUnassignedMailbox connects to the IMAP server and selects the unassigned mailbox, creating it if the inbox watcher has not yet.
+func (h *EmailService) selectUnassignedMailbox() (*client.Client, error) {
+	c, err := dialImap(h.email, h.appPassword, h.transport)
+	if err != nil {
+		return nil, err
+	}
+
+	err = createMailbox(c, constants.UnassignedMailbox)
+	if err != nil {
+		c.Logout()
+		return nil, err
+	}
+
+	_, err = c.Select(constants.UnassignedMailbox, false)
+	if err != nil {
+		c.Logout()
+		return nil, fmt.Errorf("failed to select %s: %w", constants.UnassignedMailbox, err)
+	}
+
+	return c, nil
+}
+
+// timesheetRequestData returns the data of the request and reminder email templates, with the group's subject suffix and the body rendered
+// with the placeholders of the request. An empty body keeps the default wording of the template.
+func (h *EmailService) timesheetRequestData(group *types.Group, contractor *types.Contractor, request *types.TimesheetRequest, submissionLink string, body string) (map[string]interface{}, error) {
+	placeholders := emailPlaceholders(group, contractor, request, submissionLink)
+
+	subjectSuffix, err := h.emailTemplateService.RenderEmailContent(group.EmailContent.SubjectSuffix, placeholders)
+	if err != nil {
+		return nil, err
+	}
+
+	renderedBody, err := h.emailTemplateService.RenderEmailContent(body, placeholders)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"Contractor":     contractor,
+		"RequestID":      placeholders.RequestID,
+		"SubmissionLink": submissionLink,
+
+		"SubjectSuffix": subjectSuffix,
+		"Body":          renderedBody,
+	}, nil
+}
+
+// emailPlaceholders returns the placeholders of a group's email content for a contractor's request, the due date is the day in the group's timezone.
+func emailPlaceholders(group *types.Group, contractor *types.Contractor, request *types.TimesheetRequest, submissionLink string) *types.EmailPlaceholders {
+	placeholders := &types.EmailPlaceholders{
+		Contractor: contractor,
+
+		GroupName: group.Name,
+		RequestID: requestSubjectID(request.RequestID),
+
+		SubmissionLink: submissionLink,
+	}
+
+	// The requests made before schedules had periods have none to show
+	if !request.PeriodStart.IsZero() {
+		placeholders.PeriodStart = request.PeriodStart.UTC().Format("2006-01-02")
+		placeholders.PeriodEnd = request.PeriodEnd.UTC().Format("2006-01-02")
+	}
+
+	if !request.DueAt.IsZero() {
+		loc, err := time.LoadLocation(group.Schedule.Timezone)
 		if err != nil {
-			return nil, fmt.Errorf("failed to extract attachments: %w", err)
+			loc = time.UTC
 		}
-		attachments = append(attachments, attachs...)
-	}
 
-	// Check for any fetch errors
-	if err := <-done; err != nil {
-		return nil, fmt.Errorf("error during message fetch: %w", err)
+		placeholders.DueDate = request.DueAt.In(loc).Format("2006-01-02")
 	}
 
-	return attachments, nil
+	return placeholders
 }
 
-// ArchiveEmail archives emails matching the given subject by removing them from the INBOX.
-func (h *EmailService) ArchiveEmail(subject string) error {
-	// Create a new IMAP client instance
-	c, err := client.DialTLS(constants.ImapGmailAddress+":993", nil)
+// sendTemplateEmail renders an email template for the recipient and sends it as plain text with an HTML alternative.
+// The header holds the headers of the email besides the ones set here, e.g. its Message-ID or the emails it replies to.
+func (h *EmailService) sendTemplateEmail(to *mail.Address, language string, group *types.Group, name string, data interface{}, header mail.Header) error {
+	rendered, err := h.emailTemplateService.RenderEmail(name, language, group, data)
 	if err != nil {
 		return err
 	}
-	defer c.Logout()
 
-	// Login to the IMAP server
-	err = c.Login(h.email, h.appPassword)
+	// The emails to the contractors come from their group
+	from := &mail.Address{Name: constants.EmailSenderName, Address: h.email}
+	if group != nil && group.Name != "" {
+		from.Name = group.Name
+	}
+
+	// Names and the subject are encoded as needed, e.g. for Polish letters
+	header = header.Copy()
+	header.Set("MIME-Version", "1.0")
+	header.SetDate(time.Now())
+	header.SetAddressList("From", []*mail.Address{from})
+	header.SetAddressList("To", []*mail.Address{to})
+	header.SetSubject(rendered.Subject)
+	if !header.Has("Message-Id") {
+		_, domain, _ := strings.Cut(h.email, "@")
+		err = header.GenerateMessageIDWithHostname(domain)
+		if err != nil {
+			return fmt.Errorf("failed to generate message ID: %w", err)
+		}
+	}
+
+	var msg bytes.Buffer
+	w, err := mail.CreateInlineWriter(&msg, header)
+	if err != nil {
+		return fmt.Errorf("failed to create email: %w", err)
+	}
+
+	parts := []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain", rendered.Text},
+		{"text/html", rendered.HTML},
+	}
+	for _, part := range parts {
+		var partHeader mail.InlineHeader
+		partHeader.SetContentType(part.contentType, map[string]string{"charset": "utf-8"})
+
+		pw, err := w.CreatePart(partHeader)
+		if err != nil {
+			return fmt.Errorf("failed to create email part: %w", err)
+		}
+
+		_, err = io.WriteString(pw, part.body)
+		if err != nil {
+			return fmt.Errorf("failed to write email part: %w", err)
+		}
+
+		err = pw.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write email part: %w", err)
+		}
+	}
+
+	err = w.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+
+	return h.sendMail([]string{to.Address}, msg.Bytes())
+}
+
+// contractorAddress returns the address of a contractor with the name shown in mail clients.
+func contractorAddress(contractor *types.Contractor) *mail.Address {
+	return &mail.Address{Name: strings.TrimSpace(contractor.Name + " " + contractor.Surname), Address: contractor.Email}
+}
+
+// sendMail delivers a raw message to the recipients through the configured SMTP server.
+func (h *EmailService) sendMail(to []string, msg []byte) error {
+	addr := net.JoinHostPort(h.transport.SmtpHost, strconv.Itoa(h.transport.SmtpPort))
+	tlsConfig := &tls.Config{
+		ServerName:         h.transport.SmtpHost,
+		InsecureSkipVerify: h.transport.InsecureSkipVerify,
+	}
+
+	// Open the connection, with TLS from the start if required
+	var c *smtp.Client
+	if h.transport.SmtpTLSMode == constants.ImplicitTLS {
+		conn, err := tls.Dial("tcp", addr, tlsConfig)
+		if err != nil {
+			return fmt.Errorf("failed to connect to SMTP server: %w", err)
+		}
+
+		c, err = smtp.NewClient(conn, h.transport.SmtpHost)
+		if err != nil {
+			conn.Close()
+			return fmt.Errorf("failed to create SMTP client: %w", err)
+		}
+	} else {
+		var err error
+		c, err = smtp.Dial(addr)
+		if err != nil {
+			return fmt.Errorf("failed to connect to SMTP server: %w", err)
+		}
+	}
+	defer c.Close()
+
+	// Upgrade the connection, Gmail requires STARTTLS on port 587
+	if h.transport.SmtpTLSMode == constants.StartTLS {
+		err := c.StartTLS(tlsConfig)
+		if err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	// Authenticate with the app password
+	switch h.transport.AuthMechanism {
+	case constants.PlainAuth:
+		err := c.Auth(smtp.PlainAuth("", h.email, h.appPassword, h.transport.SmtpHost))
+		if err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	case constants.LoginAuth:
+		err := c.Auth(&smtpLoginAuth{client: sasl.NewLoginClient(h.email, h.appPassword)})
+		if err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	// Send the envelope and the message
+	err := c.Mail(h.email)
 	if err != nil {
 		return err
 	}
+	for _, rcpt := range to {
+		err = c.Rcpt(rcpt)
+		if err != nil {
+			return err
+		}
+	}
 
-	// Select the INBOX
-	_, err = c.Select("INBOX", false)
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(msg)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
 	if err != nil {
 		return err
 	}
 
-	// Search for emails with the specified subject
+	return c.Quit()
+}
+
+// dialImap connects and logs in to the IMAP server described by the transport.
+func dialImap(email string, appPassword string, transport *types.MailTransport) (*client.Client, error) {
+	addr := net.JoinHostPort(transport.ImapHost, strconv.Itoa(transport.ImapPort))
+	tlsConfig := &tls.Config{
+		ServerName:         transport.ImapHost,
+		InsecureSkipVerify: transport.InsecureSkipVerify,
+	}
+
+	// Create a new IMAP client instance
+	var c *client.Client
+	var err error
+	if transport.ImapTLSMode == constants.ImplicitTLS {
+		c, err = client.DialTLS(addr, tlsConfig)
+	} else {
+		c, err = client.Dial(addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if transport.ImapTLSMode == constants.StartTLS {
+		err = c.StartTLS(tlsConfig)
+		if err != nil {
+			c.Logout()
+			return nil, fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	// Login to the IMAP server
+	switch transport.AuthMechanism {
+	case constants.PlainAuth:
+		err = c.Authenticate(sasl.NewPlainClient("", email, appPassword))
+	case constants.LoginAuth:
+		err = c.Login(email, appPassword)
+	}
+	if err != nil {
+		c.Logout()
+		return nil, fmt.Errorf("failed to login: %w", err)
+	}
+
+	return c, nil
+}
+
+// fetchUnseenEmails returns the unseen emails of the selected mailbox, without marking them seen.
+func fetchUnseenEmails(c *client.Client) ([]*types.InboundEmail, error) {
 	criteria := imap.NewSearchCriteria()
-	criteria.Header.Add("Subject", subject)
-	uids, err := c.Search(criteria)
+	criteria.WithoutFlags = []string{imap.SeenFlag, imap.DeletedFlag}
+
+	return fetchEmails(c, criteria)
+}
+
+// fetchEmails returns the emails of the selected mailbox matching the criteria, without marking them seen.
+// An email that cannot be read is returned with only its UID, so it is still handled.
+func fetchEmails(c *client.Client, criteria *imap.SearchCriteria) ([]*types.InboundEmail, error) {
+	uids, err := c.UidSearch(criteria)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to search emails: %w", err)
 	}
 
-	// Nothing to archive
+	// Nothing to fetch
+	if len(uids) == 0 {
+		return nil, nil
+	}
+
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(uids...)
+
+	// The whole message is fetched at once with PEEK, so it stays unseen until it is processed
+	section := &imap.BodySectionName{Peek: true}
+	items := []imap.FetchItem{imap.FetchUid, section.FetchItem()}
+
+	// The channel is large enough to hold every message, so the fetch never blocks on it
+	messages := make(chan *imap.Message, len(uids))
+	err = c.UidFetch(seqSet, items, messages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch emails: %w", err)
+	}
+
+	var emails []*types.InboundEmail
+	for msg := range messages {
+		email, err := readEmail(msg.GetBody(section))
+		if err != nil {
+			log.Printf("failed to read email %d: %v", msg.Uid, err)
+			email = &types.InboundEmail{}
+		}
+		email.UID = msg.Uid
+
+		emails = append(emails, email)
+	}
+
+	return emails, nil
+}
+
+// archiveEmails removes the emails with the given UIDs from the selected mailbox.
+func archiveEmails(c *client.Client, uids []uint32) error {
 	if len(uids) == 0 {
 		return nil
 	}
 
-	// Prepare to move the emails by removing the "INBOX" label
+	// Mark the emails as Deleted, for Gmail this removes the "INBOX" label on expunge
 	seqSet := new(imap.SeqSet)
 	seqSet.AddNum(uids...)
 	flags := []interface{}{imap.DeletedFlag}
-	err = c.UidStore(seqSet, imap.RemoveFlags, flags, nil)
+	err := c.UidStore(seqSet, imap.FormatFlagsOp(imap.AddFlags, true), flags, nil)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to archive emails: %w", err)
 	}
 
 	// Expunge to permanently remove emails marked as Deleted
 	err = c.Expunge(nil)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to archive emails: %w", err)
 	}
 
 	return nil
 }
 
-func (h *EmailService) extractAttachments(msg *imap.Message, c *client.Client) ([]types.Attachment, error) {
-	var attachments []types.Attachment
-
-	section := &imap.BodySectionName{}
-	fetchItem := section.FetchItem()
-	seqSet := new(imap.SeqSet)
-	seqSet.AddNum(msg.SeqNum)
-	items := []imap.FetchItem{fetchItem}
+// smtpLoginAuth adapts the SASL LOGIN client to net/smtp, which only ships PLAIN and CRAM-MD5.
+type smtpLoginAuth struct {
+	client sasl.Client
+}
 
-	// Create a channel to receive the fetched message
-	fetchedMsg := make(chan *imap.Message, 1)
+func (a *smtpLoginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	return a.client.Start()
+}
 
-	// Fetch the entire message
-	err := c.Fetch(seqSet, items, fetchedMsg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch message: %w", err)
+func (a *smtpLoginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
 	}
+	return a.client.Next(fromServer)
+}
 
-	// Read the message from the channel
-	m := <-fetchedMsg
-	r := m.GetBody(section)
+// readEmail reads the headers the emails are routed by and the attachments out of a raw message body.
+func readEmail(r imap.Literal) (*types.InboundEmail, error) {
 	if r == nil {
 		return nil, fmt.Errorf("no body found for message")
 	}
@@ -220,6 +669,35 @@ func (h *EmailService) extractAttachments(msg *imap.Message, c *client.Client) (
 	}
 	defer mr.Close()
 
+	email := &types.InboundEmail{}
+
+	// Headers that cannot be decoded are left empty, the email is then matched by the others
+	email.Subject, _ = mr.Header.Subject()
+	email.Date, _ = mr.Header.Date()
+	email.MessageID, _ = mr.Header.MessageID()
+	email.InReplyTo, _ = mr.Header.MsgIDList("In-Reply-To")
+	email.References, _ = mr.Header.MsgIDList("References")
+	if from, err := mr.Header.AddressList("From"); err == nil && len(from) > 0 {
+		email.From = from[0].Address
+	}
+
+	// Out-of-office replies, bounces and mailing lists say a program sent them
+	autoSubmitted := strings.ToLower(mr.Header.Get("Auto-Submitted"))
+	precedence := strings.ToLower(mr.Header.Get("Precedence"))
+	email.AutoSubmitted = (autoSubmitted != "" && autoSubmitted != "no") || precedence == "bulk" || precedence == "junk" || precedence == "list"
+
+	// The plus address the email was sent to is in one of these, depending on the client and the server
+	for _, field := range []string{"To", "Cc", "Delivered-To", "X-Original-To"} {
+		addresses, err := mr.Header.AddressList(field)
+		if err != nil {
+			continue
+		}
+
+		for _, address := range addresses {
+			email.To = append(email.To, address.Address)
+		}
+	}
+
 	// Iterate through each part of the email
 	for {
 		p, err := mr.NextPart()
@@ -246,7 +724,7 @@ func (h *EmailService) extractAttachments(msg *imap.Message, c *client.Client) (
 				continue
 			}
 
-			attachments = append(attachments, types.Attachment{
+			email.Attachments = append(email.Attachments, types.Attachment{
 				Filename: filename,
 				Content:  content,
 			})
@@ -255,5 +733,76 @@ func (h *EmailService) extractAttachments(msg *imap.Message, c *client.Client) (
 		}
 	}
 
-	return attachments, nil
+	return email, nil
+}
+
+// messageIDPrefix starts the Message-IDs of the emails about a request, followed by the reply key of the request and a nonce.
+const messageIDPrefix = "timesheet."
+
+// replyHeader returns the Message-ID and Reply-To headers of an email about a contractor's request, the replies are matched to the request by them.
+// The Message-ID comes back in the In-Reply-To and References headers of a reply, and the Reply-To is the plus address of the service with the reply key.
+// The key is random, so a reply cannot be matched to a request by anyone who only knows the IDs of the contractor and the request. A request without
+// a key gets a new one, for the caller to store.
+func (h *EmailService) replyHeader(request *types.TimesheetRequest) (mail.Header, error) {
+	if request.ReplyKey == "" {
+		key := make([]byte, 16)
+		_, err := rand.Read(key)
+		if err != nil {
+			return mail.Header{}, fmt.Errorf("failed to generate reply key: %w", err)
+		}
+		request.ReplyKey = hex.EncodeToString(key)
+	}
+
+	nonce := make([]byte, 8)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return mail.Header{}, fmt.Errorf("failed to generate message ID: %w", err)
+	}
+
+	local, domain, _ := strings.Cut(h.email, "@")
+
+	var header mail.Header
+	header.SetMessageID(fmt.Sprintf("%s%s.%s@%s", messageIDPrefix, request.ReplyKey, hex.EncodeToString(nonce), domain))
+	header.SetAddressList("Reply-To", []*mail.Address{{Address: fmt.Sprintf("%s+%s@%s", local, request.ReplyKey, domain)}})
+
+	return header, nil
+}
+
+// replyKeyOfMessageID returns the reply key of the Message-ID of an email about a request.
+func replyKeyOfMessageID(messageID string) (string, bool) {
+	local, _, _ := strings.Cut(strings.Trim(strings.TrimSpace(messageID), "<>"), "@")
+
+	rest, ok := strings.CutPrefix(local, messageIDPrefix)
+	if !ok {
+		return "", false
+	}
+
+	// Drop the nonce that makes the Message-ID unique
+	i := strings.LastIndex(rest, ".")
+	if i < 0 {
+		return "", false
+	}
+
+	return rest[:i], true
+}
+
+// replyKeyOfAddress returns the reply key of a plus address, e.g. "jobsender+<key>@gmail.com".
+func replyKeyOfAddress(address string) (string, bool) {
+	local, _, ok := strings.Cut(address, "@")
+	if !ok {
+		return "", false
+	}
+
+	_, key, ok := strings.Cut(local, "+")
+	return key, ok && key != ""
+}
+
+// requestSubjectID turns a stored request ID into the form it has in the subject of the request email.
+// Period IDs like "2025-01-06_2025-01-19" are the same in both, only the old IDs like "9_10-2024" were "9/10 2024" in the subject.
+func requestSubjectID(requestID string) string {
+	if strings.Count(requestID, "-") != 1 {
+		return requestID
+	}
+
+	return strings.ReplaceAll(strings.ReplaceAll(requestID, "_", "/"), "-", " ")
 }
@@ -0,0 +1,163 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// EmailTemplateService renders the emails from the templates of a file system, the layouts at its root and the templates of each language in a directory.
+// Every email has a ".txt" template that also defines its "subject", and a ".html" template, both are rendered in the layout of their kind.
+type EmailTemplateService struct {
+	templates fs.FS
+}
+
+// Ensure EmailTemplateService implements the IEmailTemplateService interface.
+var _ interfaces.IEmailTemplateService = &EmailTemplateService{}
+
+// NewEmailTemplateService creates a new EmailTemplateService that reads the templates from the given file system.
+func NewEmailTemplateService(templates fs.FS) *EmailTemplateService {
+	return &EmailTemplateService{
+		templates: templates,
+	}
+}
+
+// emailLayoutData is what the layouts are executed with, the template of the email gets the data.
+type emailLayoutData struct {
+	Group *types.Group
+	Data  interface{}
+}
+
+// RenderEmail renders an email template in a language, falling back to English, with the branding of the group, nil for none.
+func (s *EmailTemplateService) RenderEmail(name string, language string, group *types.Group, data interface{}) (*types.RenderedEmail, error) {
+	language = s.templateLanguage(name, language)
+	layoutData := emailLayoutData{Group: group, Data: data}
+
+	// The plain text and the subject
+	textTmpl, err := texttemplate.ParseFS(s.templates, constants.EmailLayoutTextName)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse email layout: %w", err)
+	}
+
+	textContent, err := fs.ReadFile(s.templates, path.Join(language, name+".txt"))
+	if err != nil {
+		return nil, fmt.Errorf("could not read email template %s: %w", name, err)
+	}
+
+	_, err = textTmpl.New("content").Parse(string(textContent))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse email template %s: %w", name, err)
+	}
+
+	var subject, text bytes.Buffer
+	err = textTmpl.ExecuteTemplate(&subject, "subject", data)
+	if err != nil {
+		return nil, fmt.Errorf("could not render subject of email %s: %w", name, err)
+	}
+
+	err = textTmpl.ExecuteTemplate(&text, constants.EmailLayoutTextName, layoutData)
+	if err != nil {
+		return nil, fmt.Errorf("could not render email %s: %w", name, err)
+	}
+
+	// The HTML, escaped as it is rendered
+	htmlTmpl, err := htmltemplate.ParseFS(s.templates, constants.EmailLayoutHTMLName)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse email layout: %w", err)
+	}
+
+	htmlContent, err := fs.ReadFile(s.templates, path.Join(language, name+".html"))
+	if err != nil {
+		return nil, fmt.Errorf("could not read email template %s: %w", name, err)
+	}
+
+	_, err = htmlTmpl.New("content").Parse(string(htmlContent))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse email template %s: %w", name, err)
+	}
+
+	var html bytes.Buffer
+	err = htmlTmpl.ExecuteTemplate(&html, constants.EmailLayoutHTMLName, layoutData)
+	if err != nil {
+		return nil, fmt.Errorf("could not render email %s: %w", name, err)
+	}
+
+	return &types.RenderedEmail{
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+// RenderEmailContent renders a group's email content with the placeholders, it returns an InvalidArgument status if the content is not a valid template of them.
+func (s *EmailTemplateService) RenderEmailContent(content string, placeholders *types.EmailPlaceholders) (string, error) {
+	tmpl, err := texttemplate.New("content").Parse(content)
+	if err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "could not parse email content: %v", err)
+	}
+
+	var rendered bytes.Buffer
+	err = tmpl.Execute(&rendered, placeholders)
+	if err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "could not render email content: %v", err)
+	}
+
+	return strings.TrimSpace(rendered.String()), nil
+}
+
+// ValidateEmailContent renders each field of a group's email content with sample placeholders, it returns an InvalidArgument status
+// naming the field if one is not a valid template or uses a field the placeholders do not have.
+func (s *EmailTemplateService) ValidateEmailContent(content *types.EmailContent) error {
+	placeholders := &types.EmailPlaceholders{
+		Contractor: &types.Contractor{ID: "contractorID", Name: "Jan", Surname: "Kowalski", Email: "jan.kowalski@example.com"},
+
+		GroupName: "Group",
+		RequestID: "2025-01-06_2025-01-19",
+
+		PeriodStart: "2025-01-06",
+		PeriodEnd:   "2025-01-19",
+		DueDate:     "2025-01-22",
+
+		SubmissionLink: constants.AppUrl + constants.SubmissionLinkPath + "token",
+	}
+
+	fields := []struct {
+		name    string
+		content string
+	}{
+		{"subject suffix", content.SubjectSuffix},
+		{"request body", content.RequestBody},
+		{"reminder body", content.ReminderBody},
+	}
+	for _, field := range fields {
+		_, err := s.RenderEmailContent(field.content, placeholders)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid %s: %v", field.name, status.Convert(err).Message())
+		}
+	}
+
+	return nil
+}
+
+// templateLanguage returns the language to render an email template in, English unless the template has both parts in the language.
+func (s *EmailTemplateService) templateLanguage(name string, language string) string {
+	for _, ext := range []string{".txt", ".html"} {
+		_, err := fs.Stat(s.templates, path.Join(language, name+ext))
+		if err != nil {
+			return constants.DefaultLanguage
+		}
+	}
+
+	return language
+}
@@ -21,8 +21,8 @@ type EnvVariablesService struct {
 	secretNameEmailServiceEmailKey       string
 	secretNameEmailServiceAppPasswordKey string
 	secretNameSessionCookieStoreKey      string
-
-	emailAggregatorQueueNameKey string
+	secretNameSubmissionLinkKey          string
+	secretNameAccountLinkKey             string
 
 	timeSheetsBucketNameKey string
 }
@@ -33,8 +33,7 @@ var _ interfaces.IEnvVariablesService = &EnvVariablesService{}
 func NewEnvVariablesService(portKey string,
 	projectIDKey string, projectLocationIDKey string, projectNumberKey string,
 	serviceAccountEmailKey string,
-	secretNameServiceAccountKey string, secretNameFirestoreWebApiKey string, secretNameEmailServiceEmailKey string, secretNameEmailServiceAppPasswordKey string, secretNameSessionCookieStoreKey string,
-	emailAggregatorQueueNameKey string,
+	secretNameServiceAccountKey string, secretNameFirestoreWebApiKey string, secretNameEmailServiceEmailKey string, secretNameEmailServiceAppPasswordKey string, secretNameSessionCookieStoreKey string, secretNameSubmissionLinkKey string, secretNameAccountLinkKey string,
 	timesheetsBucketNameKey string) *EnvVariablesService {
 
 	return &EnvVariablesService{
@@ -51,8 +50,8 @@ func NewEnvVariablesService(portKey string,
 		secretNameEmailServiceEmailKey:       secretNameEmailServiceEmailKey,
 		secretNameEmailServiceAppPasswordKey: secretNameEmailServiceAppPasswordKey,
 		secretNameSessionCookieStoreKey:      secretNameSessionCookieStoreKey,
-
-		emailAggregatorQueueNameKey: emailAggregatorQueueNameKey,
+		secretNameSubmissionLinkKey:          secretNameSubmissionLinkKey,
+		secretNameAccountLinkKey:             secretNameAccountLinkKey,
 
 		timeSheetsBucketNameKey: timesheetsBucketNameKey,
 	}
@@ -110,9 +109,14 @@ func (e *EnvVariablesService) GetEnvVariables() *types.EnvVariables {
 		log.Fatal("SECRET_NAME_SESSION_COOKIE_STORE must be set")
 	}
 
-	emailAggregatorQueueName := os.Getenv(e.emailAggregatorQueueNameKey)
-	if emailAggregatorQueueName == "" {
-		log.Fatal("EMAIL_AGGREGATOR_QUEUE_NAME must be set")
+	secretNameSubmissionLinkKey := os.Getenv(e.secretNameSubmissionLinkKey)
+	if secretNameSubmissionLinkKey == "" {
+		log.Fatal("SECRET_NAME_SUBMISSION_LINK_KEY must be set")
+	}
+
+	secretNameAccountLinkKey := os.Getenv(e.secretNameAccountLinkKey)
+	if secretNameAccountLinkKey == "" {
+		log.Fatal("SECRET_NAME_ACCOUNT_LINK_KEY must be set")
 	}
 
 	timesheetsBucketName := os.Getenv(e.timeSheetsBucketNameKey)
@@ -134,8 +138,8 @@ func (e *EnvVariablesService) GetEnvVariables() *types.EnvVariables {
 		SecretNameEmailServiceEmail:       secretNameEmailServiceEmail,
 		SecretNameEmailServiceAppPassword: secretNameEmailServiceAppPassword,
 		SecretNameSessionCookieStore:      secretNameSessionCookieStore,
-
-		EmailAggregatorQueueName: emailAggregatorQueueName,
+		SecretNameSubmissionLinkKey:       secretNameSubmissionLinkKey,
+		SecretNameAccountLinkKey:          secretNameAccountLinkKey,
 
 		TimesheetsBucketName: timesheetsBucketName,
 	}
@@ -6,10 +6,13 @@ import (
 	"strings"
 
 	"job_sender/interfaces"
+	"job_sender/types"
 
 	firebase "firebase.google.com/go"
 	"firebase.google.com/go/auth"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type FirebaseService struct {
@@ -86,18 +89,156 @@ func (s *FirebaseService) GetCustomClaims(idToken string) (map[string]interface{
 		return nil, err
 	}
 
-	token, err := client.VerifyIDToken(ctx, idToken)
+	// The tokens issued before the sessions of the user were revoked, e.g. by a password change, are rejected
+	token, err := client.VerifyIDTokenAndCheckRevoked(ctx, idToken)
 	if err != nil {
 		// Check if the error is due to an expired token
 		if strings.Contains(err.Error(), "has expired at") {
 			return nil, fmt.Errorf("ID token has expired")
 		}
+		if auth.IsIDTokenRevoked(err) {
+			return nil, fmt.Errorf("ID token has been revoked")
+		}
 		return nil, err
 	}
 
 	return token.Claims, nil
 }
 
+// EmailVerificationLink generates the link that verifies the email of a user.
+func (s *FirebaseService) EmailVerificationLink(email string) (string, error) {
+	ctx := context.Background()
+
+	client, err := s.app.Auth(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return client.EmailVerificationLink(ctx, email)
+}
+
+// GetUser gets a user by ID, it returns a NotFound status if there is none.
+func (s *FirebaseService) GetUser(id string) (*types.AuthUser, error) {
+	ctx := context.Background()
+
+	client, err := s.app.Auth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := client.GetUser(ctx, id)
+	if err != nil {
+		if auth.IsUserNotFound(err) {
+			return nil, status.Errorf(codes.NotFound, "cannot find user %s", id)
+		}
+		return nil, err
+	}
+
+	return authUser(user), nil
+}
+
+// GetUserByEmail gets a user by email, it returns a NotFound status if there is none.
+func (s *FirebaseService) GetUserByEmail(email string) (*types.AuthUser, error) {
+	ctx := context.Background()
+
+	client, err := s.app.Auth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := client.GetUserByEmail(ctx, email)
+	if err != nil {
+		if auth.IsUserNotFound(err) {
+			return nil, status.Errorf(codes.NotFound, "cannot find user from email %s", email)
+		}
+		return nil, err
+	}
+
+	return authUser(user), nil
+}
+
+// UpdatePassword sets the password of a user.
+func (s *FirebaseService) UpdatePassword(id string, password string) error {
+	ctx := context.Background()
+
+	client, err := s.app.Auth(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.UpdateUser(ctx, id, (&auth.UserToUpdate{}).Password(password))
+	if err != nil {
+		return fmt.Errorf("could not update password: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateEmail sets the email of a user, the address is verified as the user proved to own it.
+func (s *FirebaseService) UpdateEmail(id string, email string) error {
+	ctx := context.Background()
+
+	client, err := s.app.Auth(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.UpdateUser(ctx, id, (&auth.UserToUpdate{}).Email(email).EmailVerified(true))
+	if err != nil {
+		if auth.IsEmailAlreadyExists(err) {
+			return status.Errorf(codes.AlreadyExists, "EMAIL_EXISTS")
+		}
+		return fmt.Errorf("could not update email: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeSessions revokes the ID tokens issued to a user until now, the sessions holding them are logged out.
+func (s *FirebaseService) RevokeSessions(id string) error {
+	ctx := context.Background()
+
+	client, err := s.app.Auth(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = client.RevokeRefreshTokens(ctx, id)
+	if auth.IsUserNotFound(err) {
+		return status.Errorf(codes.NotFound, "cannot find user %s", id)
+	}
+
+	return err
+}
+
+// DeleteUser deletes a user, a user that does not exist is already deleted.
+func (s *FirebaseService) DeleteUser(id string) error {
+	ctx := context.Background()
+
+	client, err := s.app.Auth(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = client.DeleteUser(ctx, id)
+	if err != nil && !auth.IsUserNotFound(err) {
+		return fmt.Errorf("could not delete user: %w", err)
+	}
+
+	return nil
+}
+
+// authUser returns the AuthUser of a Firebase user record.
+func authUser(user *auth.UserRecord) *types.AuthUser {
+	return &types.AuthUser{
+		ID:            user.UID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+
+		TokensValidAfter: user.TokensValidAfterMillis / 1000,
+	}
+}
+
 // Auth is a method that returns the Firebase Auth client.
 func (s *FirebaseService) Auth(ctx context.Context) (*auth.Client, error) {
 	return s.app.Auth(ctx)
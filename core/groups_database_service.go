@@ -8,16 +8,21 @@ import (
 	"job_sender/types"
 
 	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 type GroupsDatabaseService struct {
-	ownerCollectionName       string
-	groupCollectionName       string
-	contractorsCollectionName string
-	timesheetsCollectionName  string
-	client                    *firestore.Client
+	ownerCollectionName             string
+	groupCollectionName             string
+	contractorsCollectionName       string
+	timesheetsCollectionName        string
+	timesheetRequestsCollectionName string
+	ingestionsCollectionName        string
+	versionsCollectionName          string
+	entriesCollectionName           string
+	client                          *firestore.Client
 }
 
 // Ensure GroupsDatabaseService implements IGroupsDatabaseService.
@@ -40,11 +45,15 @@ func NewGroupsDatabaseService(firebaseService *FirebaseService) (*GroupsDatabase
 	}
 
 	return &GroupsDatabaseService{
-		ownerCollectionName:       "owners",
-		groupCollectionName:       "groups",
-		contractorsCollectionName: "contractors",
-		timesheetsCollectionName:  "timesheets",
-		client:                    client,
+		ownerCollectionName:             "owners",
+		groupCollectionName:             "groups",
+		contractorsCollectionName:       "contractors",
+		timesheetsCollectionName:        "timesheets",
+		timesheetRequestsCollectionName: "timesheet_requests",
+		ingestionsCollectionName:        "timesheet_ingestions",
+		versionsCollectionName:          "timesheet_versions",
+		entriesCollectionName:           "timesheet_entries",
+		client:                          client,
 	}, nil
 }
 
@@ -74,6 +83,32 @@ func (db *GroupsDatabaseService) GetGroup(id string) (*types.Group, error) {
 	return &group, nil
 }
 
+// GetAllGroups gets the groups of every owner.
+func (db *GroupsDatabaseService) GetAllGroups() ([]*types.Group, error) {
+	ctx := context.Background()
+	iter := db.client.Collection(db.groupCollectionName).Documents(ctx)
+
+	var groups []*types.Group
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not list groups: %w", err)
+		}
+
+		group := &types.Group{}
+		if err := doc.DataTo(group); err != nil {
+			return nil, fmt.Errorf("could not convert group data: %w", err)
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
 // AddGroup adds a group.
 func (db *GroupsDatabaseService) AddGroup(group *types.Group) (*types.Group, error) {
 	ctx := context.Background()
@@ -99,7 +134,14 @@ func (db *GroupsDatabaseService) AddGroup(group *types.Group) (*types.Group, err
 
 		"name": group.Name,
 
-		"schedule": group.Schedule,
+		"schedule":         group.Schedule,
+		"holiday_calendar": group.HolidayCalendar,
+
+		"reminder_policy":   group.ReminderPolicy,
+		"attachment_policy": group.AttachmentPolicy,
+
+		"branding":      group.Branding,
+		"email_content": group.EmailContent,
 	}
 
 	_, err := ref.Create(ctx, groupMap)
@@ -156,28 +198,78 @@ func (db *GroupsDatabaseService) DeleteGroup(id string) error {
 			return fmt.Errorf("could not convert contractor data: %w", err)
 		}
 
-		// Delete lastRequests
-		for _, lastRequest := range contractor.LastRequests {
-			if lastRequest.Timestamp == 0 {
-				continue
+		// Delete the timesheets of the contractor with their entries and versions
+		timesheets, err := db.client.Collection(db.timesheetsCollectionName).Where("contractor_id", "==", contractor.ID).Documents(ctx).GetAll()
+		if err != nil {
+			// Log the error but don't return, allowing other deletions to proceed
+			fmt.Printf("Could not get timesheets of contractor %s: %v\n", contractor.ID, err)
+		}
+
+		for _, timesheet := range timesheets {
+			entries, err := db.client.Collection(db.entriesCollectionName).Where("timesheet_id", "==", timesheet.Ref.ID).Documents(ctx).GetAll()
+			if err != nil {
+				// Log the error but don't return, allowing other deletions to proceed
+				fmt.Printf("Could not get entries of timesheet %s: %v\n", timesheet.Ref.ID, err)
 			}
 
-			// Directly delete the timesheet without fetching it first
-			timesheets, err := db.client.Collection(db.timesheetsCollectionName).Where("request_id", "==", lastRequest.ID).Documents(ctx).GetAll()
+			for _, entry := range entries {
+				_, err := entry.Ref.Delete(ctx)
+				if err != nil {
+					// Log the error but don't return, allowing other deletions to proceed
+					fmt.Printf("Could not delete timesheet entry %s: %v\n", entry.Ref.ID, err)
+				}
+			}
+
+			versions, err := db.client.Collection(db.versionsCollectionName).Where("timesheet_id", "==", timesheet.Ref.ID).Documents(ctx).GetAll()
 			if err != nil {
 				// Log the error but don't return, allowing other deletions to proceed
-				fmt.Printf("Could not get timesheets %s: %v\n", lastRequest.ID, err)
-				continue
+				fmt.Printf("Could not get versions of timesheet %s: %v\n", timesheet.Ref.ID, err)
 			}
 
-			for _, timesheet := range timesheets {
-				_, err := db.client.Collection(db.timesheetsCollectionName).Doc(timesheet.Ref.ID).Delete(ctx)
+			for _, version := range versions {
+				_, err := version.Ref.Delete(ctx)
 				if err != nil {
 					// Log the error but don't return, allowing other deletions to proceed
-					fmt.Printf("Could not delete timesheet %s: %v\n", timesheet.Ref.ID, err)
-					continue
+					fmt.Printf("Could not delete timesheet version %s: %v\n", version.Ref.ID, err)
 				}
 			}
+
+			_, err = db.client.Collection(db.timesheetsCollectionName).Doc(timesheet.Ref.ID).Delete(ctx)
+			if err != nil {
+				// Log the error but don't return, allowing other deletions to proceed
+				fmt.Printf("Could not delete timesheet %s: %v\n", timesheet.Ref.ID, err)
+				continue
+			}
+		}
+
+		// Delete the timesheet requests of the contractor
+		requests, err := db.client.Collection(db.timesheetRequestsCollectionName).Where("contractor_id", "==", contractor.ID).Documents(ctx).GetAll()
+		if err != nil {
+			// Log the error but don't return, allowing other deletions to proceed
+			fmt.Printf("Could not get timesheet requests of contractor %s: %v\n", contractor.ID, err)
+		}
+
+		for _, request := range requests {
+			_, err := request.Ref.Delete(ctx)
+			if err != nil {
+				// Log the error but don't return, allowing other deletions to proceed
+				fmt.Printf("Could not delete timesheet request %s: %v\n", request.Ref.ID, err)
+			}
+		}
+
+		// Delete the timesheet ingestions of the contractor
+		ingestions, err := db.client.Collection(db.ingestionsCollectionName).Where("contractor_id", "==", contractor.ID).Documents(ctx).GetAll()
+		if err != nil {
+			// Log the error but don't return, allowing other deletions to proceed
+			fmt.Printf("Could not get timesheet ingestions of contractor %s: %v\n", contractor.ID, err)
+		}
+
+		for _, ingestion := range ingestions {
+			_, err := ingestion.Ref.Delete(ctx)
+			if err != nil {
+				// Log the error but don't return, allowing other deletions to proceed
+				fmt.Printf("Could not delete timesheet ingestion %s: %v\n", ingestion.Ref.ID, err)
+			}
 		}
 
 		contractorID := contractorRaw.Ref.ID
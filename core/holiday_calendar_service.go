@@ -0,0 +1,246 @@
+package core
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// holidayCalendarFiles are the bundled holiday calendars, one file per country named by its code, e.g. "pl.json".
+//
+//go:embed holidays/*.json
+var holidayCalendarFiles embed.FS
+
+// HolidayCalendarService computes the holidays of the bundled calendars from their rules, so they do not run out after some years.
+type HolidayCalendarService struct {
+	calendars map[string]*holidayCalendar
+}
+
+// holidayCalendar is a bundled calendar as kept in its file.
+type holidayCalendar struct {
+	Code     string        `json:"-"`
+	Name     string        `json:"name"`
+	Holidays []holidayRule `json:"holidays"`
+}
+
+// holidayRule is a holiday on a fixed date, a number of days from Easter Sunday, or a weekday of a week of the month.
+type holidayRule struct {
+	Name string `json:"name"`
+
+	Month   time.Month `json:"month"`
+	Day     int        `json:"day"`
+	Easter  *int       `json:"easter"`  // Days from Easter Sunday
+	Weekday string     `json:"weekday"` // With the week, e.g. the first Monday
+	Week    int        `json:"week"`    // 1 to 4, or -1 for the last
+
+	// Observed moves holidays falling on a weekend, "substitute" to the next working day and "nearest" to Friday or Monday.
+	Observed string `json:"observed"`
+
+	FromYear int `json:"from_year"`
+	ToYear   int `json:"to_year"`
+}
+
+// Ensure HolidayCalendarService implements IHolidayCalendarService.
+var _ interfaces.IHolidayCalendarService = &HolidayCalendarService{}
+
+// NewHolidayCalendarService creates a new HolidayCalendarService with the bundled calendars.
+func NewHolidayCalendarService() (*HolidayCalendarService, error) {
+	files, err := holidayCalendarFiles.ReadDir("holidays")
+	if err != nil {
+		return nil, fmt.Errorf("could not read holiday calendars: %w", err)
+	}
+
+	calendars := make(map[string]*holidayCalendar)
+	for _, file := range files {
+		content, err := holidayCalendarFiles.ReadFile(path.Join("holidays", file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("could not read holiday calendar %s: %w", file.Name(), err)
+		}
+
+		var calendar holidayCalendar
+		err = json.Unmarshal(content, &calendar)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse holiday calendar %s: %w", file.Name(), err)
+		}
+
+		for _, rule := range calendar.Holidays {
+			if rule.Weekday != "" {
+				if _, ok := parseWeekday(rule.Weekday); !ok {
+					return nil, fmt.Errorf("holiday calendar %s: invalid weekday of %s", file.Name(), rule.Name)
+				}
+			}
+		}
+
+		calendar.Code = strings.ToUpper(strings.TrimSuffix(file.Name(), path.Ext(file.Name())))
+		calendars[calendar.Code] = &calendar
+	}
+
+	return &HolidayCalendarService{
+		calendars: calendars,
+	}, nil
+}
+
+// ListHolidayCalendars lists the bundled holiday calendars.
+func (s *HolidayCalendarService) ListHolidayCalendars() []*types.HolidayCalendar {
+	var calendars []*types.HolidayCalendar
+	for _, calendar := range s.calendars {
+		calendars = append(calendars, &types.HolidayCalendar{Code: calendar.Code, Name: calendar.Name})
+	}
+
+	slices.SortFunc(calendars, func(a, b *types.HolidayCalendar) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return calendars
+}
+
+// GetHolidays returns the holidays of a calendar in a year, it returns a NotFound status if there is no such calendar.
+func (s *HolidayCalendarService) GetHolidays(calendar string, year int) ([]*types.Holiday, error) {
+	c, ok := s.calendars[calendar]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "holiday calendar %s does not exist", calendar)
+	}
+
+	// Observed holidays can move into the year before or after
+	var holidays []*types.Holiday
+	for _, holiday := range c.holidays(year-1, year+1) {
+		if holiday.date.Year() == year {
+			holidays = append(holidays, &types.Holiday{Date: holiday.date.Format(scheduleDateLayout), Name: holiday.name})
+		}
+	}
+
+	return holidays, nil
+}
+
+// IsWorkingDay reports whether a date is a weekday that is not a holiday of the calendar, an empty calendar has no holidays.
+func (s *HolidayCalendarService) IsWorkingDay(calendar string, date time.Time) (bool, error) {
+	days, err := s.CountWorkingDays(calendar, date, date)
+	if err != nil {
+		return false, err
+	}
+
+	return days == 1, nil
+}
+
+// CountWorkingDays counts the working days of the calendar from start to end, both included.
+func (s *HolidayCalendarService) CountWorkingDays(calendar string, start time.Time, end time.Time) (int, error) {
+	start, end = civilDate(start), civilDate(end)
+
+	holidays := make(map[time.Time]bool)
+	if calendar != "" {
+		c, ok := s.calendars[calendar]
+		if !ok {
+			return 0, status.Errorf(codes.NotFound, "holiday calendar %s does not exist", calendar)
+		}
+
+		for _, holiday := range c.holidays(start.Year()-1, end.Year()+1) {
+			holidays[holiday.date] = true
+		}
+	}
+
+	days := 0
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() != time.Saturday && day.Weekday() != time.Sunday && !holidays[day] {
+			days++
+		}
+	}
+
+	return days, nil
+}
+
+// datedHoliday is a holiday on its date at midnight UTC.
+type datedHoliday struct {
+	date time.Time
+	name string
+}
+
+// holidays returns the holidays of the calendar in the years from and to, in the order of the rules of each year.
+func (c *holidayCalendar) holidays(from int, to int) []datedHoliday {
+	var holidays []datedHoliday
+
+	for year := from; year <= to; year++ {
+		var substitutes []datedHoliday
+		taken := make(map[time.Time]bool)
+
+		for _, rule := range c.Holidays {
+			if (rule.FromYear != 0 && year < rule.FromYear) || (rule.ToYear != 0 && year > rule.ToYear) {
+				continue
+			}
+
+			holiday := datedHoliday{date: rule.date(year), name: rule.Name}
+			weekend := holiday.date.Weekday() == time.Saturday || holiday.date.Weekday() == time.Sunday
+
+			switch {
+			case weekend && rule.Observed == "nearest":
+				if holiday.date.Weekday() == time.Saturday {
+					holiday.date = holiday.date.AddDate(0, 0, -1)
+				} else {
+					holiday.date = holiday.date.AddDate(0, 0, 1)
+				}
+				holiday.name += " (observed)"
+			case weekend && rule.Observed == "substitute":
+				// Substitutes go after the other holidays of the year are known
+				substitutes = append(substitutes, holiday)
+				continue
+			}
+
+			holidays = append(holidays, holiday)
+			taken[holiday.date] = true
+		}
+
+		for _, holiday := range substitutes {
+			for holiday.date.Weekday() == time.Saturday || holiday.date.Weekday() == time.Sunday || taken[holiday.date] {
+				holiday.date = holiday.date.AddDate(0, 0, 1)
+			}
+			holiday.name += " (substitute day)"
+
+			holidays = append(holidays, holiday)
+			taken[holiday.date] = true
+		}
+	}
+
+	return holidays
+}
+
+// date returns the date of the holiday in a year at midnight UTC.
+func (r *holidayRule) date(year int) time.Time {
+	switch {
+	case r.Easter != nil:
+		return easterSunday(year).AddDate(0, 0, *r.Easter)
+	case r.Weekday != "":
+		weekday, _ := parseWeekday(r.Weekday)
+		return nthWeekday(year, r.Month, weekday, r.Week)
+	default:
+		return time.Date(year, r.Month, r.Day, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// easterSunday returns the date of Easter Sunday in a year, with the anonymous Gregorian algorithm.
+func easterSunday(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
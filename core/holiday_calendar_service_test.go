@@ -0,0 +1,188 @@
+package core
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestHolidayCalendarService(t *testing.T) *HolidayCalendarService {
+	t.Helper()
+
+	s, err := NewHolidayCalendarService()
+	if err != nil {
+		t.Fatalf("NewHolidayCalendarService: %v", err)
+	}
+
+	return s
+}
+
+func TestEasterSunday(t *testing.T) {
+	tests := []struct {
+		year int
+		want string
+	}{
+		{2000, "2000-04-23"},
+		{2019, "2019-04-21"},
+		{2024, "2024-03-31"},
+		{2025, "2025-04-20"},
+		{2026, "2026-04-05"},
+		{2038, "2038-04-25"},
+	}
+
+	for _, tt := range tests {
+		got := easterSunday(tt.year).Format(scheduleDateLayout)
+		if got != tt.want {
+			t.Errorf("easterSunday(%d) = %s, want %s", tt.year, got, tt.want)
+		}
+	}
+}
+
+func TestHolidayCalendarServiceGetHolidays(t *testing.T) {
+	tests := []struct {
+		name     string
+		calendar string
+		year     int
+		date     string
+		want     string // The name of the holiday on the date, empty if there is none
+	}{
+		{"Easter Monday", "PL", 2025, "2025-04-21", "Easter Monday"},
+		{"days from Easter", "PL", 2025, "2025-06-19", "Corpus Christi"},
+		{"before the first year", "PL", 2024, "2024-12-24", ""},
+		{"from the first year", "PL", 2025, "2025-12-24", "Christmas Eve"},
+		{"no observed days", "PL", 2022, "2022-01-03", ""},
+		{"Good Friday", "UK", 2025, "2025-04-18", "Good Friday"},
+		{"last Monday", "UK", 2025, "2025-05-26", "Spring bank holiday"},
+		{"first Monday", "UK", 2025, "2025-05-05", "Early May bank holiday"},
+		{"substitute day", "UK", 2022, "2022-01-03", "New Year's Day (substitute day)"},
+		{"substitute for Saturday", "UK", 2021, "2021-12-27", "Christmas Day (substitute day)"},
+		{"substitute after another substitute", "UK", 2021, "2021-12-28", "Boxing Day (substitute day)"},
+		{"weekend day itself", "UK", 2021, "2021-12-25", ""},
+		{"nearest Friday", "US", 2026, "2026-07-03", "Independence Day (observed)"},
+		{"nearest Monday", "US", 2027, "2027-07-05", "Independence Day (observed)"},
+		{"observed in the year before", "US", 2021, "2021-12-31", "New Year's Day (observed)"},
+		{"fourth Thursday", "US", 2025, "2025-11-27", "Thanksgiving Day"},
+		{"before a holiday exists", "US", 2020, "2020-06-19", ""},
+		{"after a holiday exists", "US", 2025, "2025-06-19", "Juneteenth National Independence Day"},
+	}
+
+	s := newTestHolidayCalendarService(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			holidays, err := s.GetHolidays(tt.calendar, tt.year)
+			if err != nil {
+				t.Fatalf("GetHolidays: %v", err)
+			}
+
+			got := ""
+			for _, holiday := range holidays {
+				if !strings.HasPrefix(holiday.Date, strings.Split(tt.date, "-")[0]) {
+					t.Errorf("holiday %s %s is not in %d", holiday.Date, holiday.Name, tt.year)
+				}
+				if holiday.Date == tt.date {
+					got = holiday.Name
+				}
+			}
+			if got != tt.want {
+				t.Errorf("holiday on %s = %q, want %q", tt.date, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHolidayCalendarServiceCountWorkingDays(t *testing.T) {
+	tests := []struct {
+		name     string
+		calendar string
+		start    string
+		end      string
+		want     int
+	}{
+		{"weekdays only", "", "2025-01-01", "2025-01-31", 23},
+		{"with holidays", "PL", "2025-01-01", "2025-01-31", 21},
+		{"Christmas", "PL", "2025-12-01", "2025-12-31", 20},
+		{"substitute days", "UK", "2021-12-20", "2021-12-31", 8},
+		{"across the year", "US", "2021-12-27", "2022-01-07", 9},
+		{"one holiday", "PL", "2025-06-19", "2025-06-19", 0},
+		{"weekend", "", "2025-01-04", "2025-01-05", 0},
+		{"end before start", "", "2025-01-10", "2025-01-06", 0},
+	}
+
+	s := newTestHolidayCalendarService(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, _ := time.Parse(scheduleDateLayout, tt.start)
+			end, _ := time.Parse(scheduleDateLayout, tt.end)
+
+			got, err := s.CountWorkingDays(tt.calendar, start, end)
+			if err != nil {
+				t.Fatalf("CountWorkingDays: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("CountWorkingDays(%s, %s, %s) = %d, want %d", tt.calendar, tt.start, tt.end, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHolidayCalendarServiceIsWorkingDay(t *testing.T) {
+	tests := []struct {
+		calendar string
+		date     time.Time
+		want     bool
+	}{
+		{"PL", time.Date(2025, 6, 19, 0, 0, 0, 0, time.UTC), false},
+		{"", time.Date(2025, 6, 19, 0, 0, 0, 0, time.UTC), true},
+		{"US", time.Date(2026, 7, 3, 0, 0, 0, 0, time.UTC), false},
+		{"US", time.Date(2026, 7, 6, 0, 0, 0, 0, time.UTC), true},
+		// The date counts in its own timezone
+		{"PL", time.Date(2026, 1, 1, 23, 30, 0, 0, time.FixedZone("UTC-5", -5*60*60)), false},
+	}
+
+	s := newTestHolidayCalendarService(t)
+	for _, tt := range tests {
+		got, err := s.IsWorkingDay(tt.calendar, tt.date)
+		if err != nil {
+			t.Fatalf("IsWorkingDay: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("IsWorkingDay(%q, %s) = %v, want %v", tt.calendar, tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestHolidayCalendarServiceUnknownCalendar(t *testing.T) {
+	s := newTestHolidayCalendarService(t)
+
+	_, err := s.GetHolidays("XX", 2025)
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("GetHolidays error = %v, want NotFound", err)
+	}
+
+	_, err = s.CountWorkingDays("XX", time.Now(), time.Now())
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("CountWorkingDays error = %v, want NotFound", err)
+	}
+}
+
+func TestHolidayCalendarServiceListHolidayCalendars(t *testing.T) {
+	calendars := newTestHolidayCalendarService(t).ListHolidayCalendars()
+
+	var got []string
+	for i, calendar := range calendars {
+		got = append(got, calendar.Code)
+		if i > 0 && calendars[i-1].Name > calendar.Name {
+			t.Errorf("calendar %s comes before %s", calendars[i-1].Name, calendar.Name)
+		}
+	}
+
+	for _, code := range []string{"PL", "UK", "US"} {
+		if !slices.Contains(got, code) {
+			t.Errorf("calendars = %v, want %s among them", got, code)
+		}
+	}
+}
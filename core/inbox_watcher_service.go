@@ -0,0 +1,295 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// Waits before reconnecting to the inbox, doubled after each failed connection.
+	inboxWatcherMinBackoff = time.Second
+	inboxWatcherMaxBackoff = 5 * time.Minute
+
+	// inboxWatcherRecheckInterval ends the IDLE to check the inbox again, to retry the emails that failed and to notice a dead connection.
+	inboxWatcherRecheckInterval = 10 * time.Minute
+
+	// Only the instance holding the lease watches the inbox. It renews the lease well before it expires, the other instances try to take it
+	// every retry interval, so a stopped instance is replaced within a lease duration and a retry interval.
+	inboxWatcherLeaseName          = "inbox_watcher"
+	inboxWatcherLeaseDuration      = time.Minute
+	inboxWatcherLeaseRenewInterval = 20 * time.Second
+	inboxWatcherLeaseRetryInterval = 30 * time.Second
+)
+
+// InboxWatcherService keeps an IMAP IDLE connection to the inbox of the email service and ingests the replies to timesheet requests as they arrive.
+// The emails it cannot match to a request are moved to the unassigned mailbox, with their triage saved for the owner of their group.
+type InboxWatcherService struct {
+	email       string
+	appPassword string
+
+	transport *types.MailTransport
+
+	timesheetIngestionService interfaces.ITimesheetIngestionService
+
+	leasesDB interfaces.ILeasesDatabaseService
+	holder   string // Random ID of the instance in the lease
+}
+
+// Ensure InboxWatcherService implements IInboxWatcherService.
+var _ interfaces.IInboxWatcherService = &InboxWatcherService{}
+
+// NewInboxWatcherService creates a new InboxWatcherService that watches the inbox on the servers described by the transport.
+func NewInboxWatcherService(email string, appPassword string, transport *types.MailTransport, timesheetIngestionService interfaces.ITimesheetIngestionService, leasesDB interfaces.ILeasesDatabaseService) *InboxWatcherService {
+	holder := make([]byte, 16)
+	_, err := rand.Read(holder)
+	if err != nil {
+		panic(fmt.Sprintf("inbox watcher: could not generate holder ID: %v", err))
+	}
+
+	return &InboxWatcherService{
+		email:       email,
+		appPassword: appPassword,
+
+		transport: transport,
+
+		timesheetIngestionService: timesheetIngestionService,
+
+		leasesDB: leasesDB,
+		holder:   hex.EncodeToString(holder),
+	}
+}
+
+// Watch processes the unseen emails of the inbox and then each new one, until the context is done.
+// Every instance of the app runs it, but only the one holding the inbox watcher lease connects to the inbox, so the emails are moved by one watcher.
+func (s *InboxWatcherService) Watch(ctx context.Context) {
+	for {
+		_, err := s.leasesDB.AcquireLease(inboxWatcherLeaseName, s.holder, inboxWatcherLeaseDuration)
+		if err == nil {
+			s.watchWhileLeased(ctx)
+		} else if status.Code(err) != codes.FailedPrecondition {
+			log.Printf("inbox watcher: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(inboxWatcherLeaseRetryInterval):
+		}
+	}
+}
+
+// watchWhileLeased watches the inbox and renews the lease until the context is done or the lease cannot be renewed.
+func (s *InboxWatcherService) watchWhileLeased(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		ticker := time.NewTicker(inboxWatcherLeaseRenewInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			// Stop watching at once, another instance may take the lease when it expires
+			_, err := s.leasesDB.AcquireLease(inboxWatcherLeaseName, s.holder, inboxWatcherLeaseDuration)
+			if err != nil {
+				log.Printf("inbox watcher: could not renew lease, stopping: %v", err)
+				cancel()
+				return
+			}
+		}
+	}()
+
+	s.watchInbox(ctx)
+}
+
+// watchInbox processes the emails of the inbox until the context is done, a failed connection is retried with an exponential backoff.
+func (s *InboxWatcherService) watchInbox(ctx context.Context) {
+	backoff := inboxWatcherMinBackoff
+	for {
+		connected, err := s.watch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Start over with the shortest wait after a connection that worked
+		if connected {
+			backoff = inboxWatcherMinBackoff
+		}
+		log.Printf("inbox watcher: %v, reconnecting in %s", err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, inboxWatcherMaxBackoff)
+	}
+}
+
+// watch connects to the inbox and processes its emails until the connection fails or the context is done, it reports whether it connected.
+func (s *InboxWatcherService) watch(ctx context.Context) (bool, error) {
+	// Wake up on new emails, the other updates are only drained so the client never blocks on them
+	updates := make(chan client.Update, 16)
+	newEmails := make(chan struct{}, 1)
+	stopUpdates := make(chan struct{})
+	defer close(stopUpdates)
+
+	go func() {
+		for {
+			select {
+			case <-stopUpdates:
+				return
+			case update := <-updates:
+				if _, ok := update.(*client.MailboxUpdate); ok {
+					select {
+					case newEmails <- struct{}{}:
+					default:
+					}
+				}
+			}
+		}
+	}()
+
+	c, err := dialImap(s.email, s.appPassword, s.transport)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer c.Logout()
+	c.Updates = updates
+
+	err = createMailbox(c, constants.UnassignedMailbox)
+	if err != nil {
+		return true, err
+	}
+
+	_, err = c.Select("INBOX", false)
+	if err != nil {
+		return true, fmt.Errorf("failed to select INBOX: %w", err)
+	}
+
+	for {
+		err = s.processEmails(c)
+		if err != nil {
+			return true, err
+		}
+
+		err = s.idle(ctx, c, newEmails)
+		if err != nil {
+			return true, err
+		}
+	}
+}
+
+// idle waits for new emails, the recheck interval or the context to be done.
+func (s *InboxWatcherService) idle(ctx context.Context, c *client.Client, newEmails <-chan struct{}) error {
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Idle(stop, nil)
+	}()
+
+	recheck := time.NewTimer(inboxWatcherRecheckInterval)
+	defer recheck.Stop()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			err = errors.New("ended by the server")
+		}
+		return fmt.Errorf("failed to idle: %w", err)
+	case <-ctx.Done():
+	case <-newEmails:
+	case <-recheck.C:
+	}
+
+	close(stop)
+	err := <-done
+	if err != nil {
+		return fmt.Errorf("failed to idle: %w", err)
+	}
+
+	return ctx.Err()
+}
+
+// processEmails ingests the unseen emails of the selected mailbox. The ingested emails are archived, and the ones that match no request or
+// do not have exactly one attachment are moved to the unassigned mailbox for the owner of their group to triage, once their triage is saved.
+// The ones attributed to no group are archived, their senders are told to answer the request email instead. Emails that failed for another reason
+// stay unseen, to be retried.
+func (s *InboxWatcherService) processEmails(c *client.Client) error {
+	emails, err := fetchUnseenEmails(c)
+	if err != nil {
+		return err
+	}
+
+	var archived []uint32
+	unassigned := new(imap.SeqSet)
+	for _, email := range emails {
+		err = s.timesheetIngestionService.IngestEmail(email)
+		switch status.Code(err) {
+		case codes.OK:
+			archived = append(archived, email.UID)
+		case codes.NotFound, codes.InvalidArgument:
+			log.Printf("inbox watcher: unassigned email %q from %s: %v", email.Subject, email.From, err)
+
+			kept, err := s.timesheetIngestionService.UnassignEmail(email)
+			if err != nil {
+				log.Printf("inbox watcher: could not unassign email %q from %s: %v", email.Subject, email.From, err)
+			} else if kept {
+				unassigned.AddNum(email.UID)
+			} else {
+				archived = append(archived, email.UID)
+			}
+		default:
+			log.Printf("inbox watcher: could not ingest email %q from %s: %v", email.Subject, email.From, err)
+		}
+	}
+
+	if !unassigned.Empty() {
+		err = c.UidMove(unassigned, constants.UnassignedMailbox)
+		if err != nil {
+			return fmt.Errorf("failed to move emails to %s: %w", constants.UnassignedMailbox, err)
+		}
+	}
+
+	return archiveEmails(c, archived)
+}
+
+// createMailbox creates a mailbox unless it exists, Gmail shows it as a label.
+func createMailbox(c *client.Client, name string) error {
+	mailboxes := make(chan *imap.MailboxInfo, 1)
+	err := c.List("", name, mailboxes)
+	if err != nil {
+		return fmt.Errorf("failed to list mailboxes: %w", err)
+	}
+
+	for range mailboxes {
+		return nil
+	}
+
+	err = c.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create mailbox %s: %w", name, err)
+	}
+
+	return nil
+}
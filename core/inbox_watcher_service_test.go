@@ -0,0 +1,471 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/mail"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"job_sender/emails"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+	"job_sender/utils/fakemail"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	testServiceEmail       = "job-sender@localhost"
+	testServiceAppPassword = "local"
+	testContractorEmail    = "jan@example.com"
+	testRequestID          = "2025-01-06_2025-01-19"
+	testTimesheetCSV       = "Date,Hours,Project\n2025-01-06,8,ACME\n2025-01-07,7.5,ACME\n"
+)
+
+// ingestionFixture wires the email, ingestion and inbox watcher services of the local backend to a fake mail server,
+// with a group, a contractor and a pending request of the contractor.
+type ingestionFixture struct {
+	mailServer *fakemail.Server
+
+	emailService              *EmailService
+	timesheetIngestionService *TimesheetIngestionService
+	inboxWatcherService       *InboxWatcherService
+
+	groupsDB            *LocalGroupsDatabaseService
+	contractorsDB       *LocalContractorsDatabaseService
+	timesheetsDB        *LocalTimesheetsDatabaseService
+	timesheetRequestsDB *LocalTimesheetRequestsDatabaseService
+	unassignedEmailsDB  *LocalUnassignedEmailsDatabaseService
+	leasesDB            *LocalLeasesDatabaseService
+
+	group      *types.Group
+	contractor *types.Contractor
+	request    *types.TimesheetRequest
+}
+
+func newIngestionFixture(t *testing.T) *ingestionFixture {
+	t.Helper()
+
+	dir := t.TempDir()
+	store, err := NewLocalStore(filepath.Join(dir, "store.json"))
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+
+	mailServer := fakemail.NewServer()
+	mailServer.AddUser(testServiceEmail, testServiceAppPassword)
+	err = mailServer.Start("127.0.0.1:0", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { mailServer.Close() })
+
+	storageService, err := NewLocalStorageService(filepath.Join(dir, "bucket"), "http://localhost/storage")
+	if err != nil {
+		t.Fatalf("NewLocalStorageService: %v", err)
+	}
+
+	f := &ingestionFixture{
+		mailServer: mailServer,
+
+		groupsDB:            NewLocalGroupsDatabaseService(store),
+		contractorsDB:       NewLocalContractorsDatabaseService(store),
+		timesheetsDB:        NewLocalTimesheetsDatabaseService(store),
+		timesheetRequestsDB: NewLocalTimesheetRequestsDatabaseService(store),
+		unassignedEmailsDB:  NewLocalUnassignedEmailsDatabaseService(store),
+		leasesDB:            NewLocalLeasesDatabaseService(store),
+	}
+
+	transport := mailServer.MailTransport()
+	f.emailService = NewEmailService(testServiceEmail, testServiceAppPassword, transport, NewEmailTemplateService(emails.FS))
+	f.timesheetIngestionService = NewTimesheetIngestionService(
+		storageService,
+		f.emailService,
+		NewAttachmentValidationService(nil),
+		NewTimesheetParserService(),
+		f.groupsDB,
+		f.contractorsDB,
+		f.timesheetsDB,
+		f.timesheetRequestsDB,
+		NewLocalTimesheetVersionsDatabaseService(store),
+		NewLocalTimesheetIngestionsDatabaseService(store),
+		f.unassignedEmailsDB,
+	)
+	f.inboxWatcherService = NewInboxWatcherService(testServiceEmail, testServiceAppPassword, transport, f.timesheetIngestionService, f.leasesDB)
+
+	f.group, err = f.groupsDB.AddGroup(&types.Group{OwnerID: "owner", Name: "ACME"})
+	if err != nil {
+		t.Fatalf("AddGroup: %v", err)
+	}
+
+	f.contractor = &types.Contractor{Name: "Jan", Surname: "Kowalski", Email: testContractorEmail}
+	err = f.contractorsDB.AddContractor(f.group.ID, f.contractor)
+	if err != nil {
+		t.Fatalf("AddContractor: %v", err)
+	}
+
+	f.request = &types.TimesheetRequest{
+		GroupID:      f.group.ID,
+		ContractorID: f.contractor.ID,
+		RequestID:    testRequestID,
+		PeriodStart:  time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC),
+		PeriodEnd:    time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC),
+		SentAt:       time.Now(),
+		DueAt:        time.Now().Add(48 * time.Hour),
+		Status:       constants.Pending,
+		Attempts:     1,
+	}
+	err = f.timesheetRequestsDB.AddTimesheetRequest(f.request)
+	if err != nil {
+		t.Fatalf("AddTimesheetRequest: %v", err)
+	}
+
+	return f
+}
+
+// sentEmail returns the headers of the only email in the mailbox of an address.
+func (f *ingestionFixture) sentEmail(t *testing.T, address string) mail.Header {
+	t.Helper()
+
+	messages := f.mailServer.Messages(address)
+	if len(messages) != 1 {
+		t.Fatalf("got %d emails to %s, want 1", len(messages), address)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(messages[0].Body))
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+
+	return msg.Header
+}
+
+// deliverReply delivers an email with a CSV attachment from an address to the service, as a reply to the email with the given Message-ID.
+func (f *ingestionFixture) deliverReply(from string, to string, messageID string, subject string, csv string) {
+	raw := fmt.Sprintf("From: %s\r\n"+
+		"To: %s\r\n"+
+		"Subject: %s\r\n"+
+		"Date: %s\r\n"+
+		"Message-ID: <reply.%d@example.com>\r\n"+
+		"In-Reply-To: %s\r\n"+
+		"References: %s\r\n"+
+		"MIME-Version: 1.0\r\n"+
+		"Content-Type: multipart/mixed; boundary=b\r\n"+
+		"\r\n"+
+		"--b\r\n"+
+		"Content-Type: text/plain\r\n"+
+		"\r\n"+
+		"Here you go.\r\n"+
+		"--b\r\n"+
+		"Content-Type: text/csv\r\n"+
+		"Content-Disposition: attachment; filename=\"timesheet.csv\"\r\n"+
+		"\r\n"+
+		"%s\r\n"+
+		"--b--\r\n", from, to, subject, time.Now().Format(time.RFC1123Z), time.Now().UnixNano(), messageID, messageID, csv)
+
+	f.mailServer.Deliver(from, []string{to}, []byte(raw))
+}
+
+// waitForStatus waits until the request of the fixture has the status, failing the test after a few seconds.
+func (f *ingestionFixture) waitForStatus(t *testing.T, want constants.TimesheetRequestStatuses) *types.TimesheetRequest {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		request, err := f.timesheetRequestsDB.GetTimesheetRequest(f.contractor.ID, testRequestID)
+		if err != nil {
+			t.Fatalf("GetTimesheetRequest: %v", err)
+		}
+		if request.Status == want {
+			return request
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("request status is %q, want %q", request.Status, want)
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// sendRequest sends the request email of the fixture and stores the reply key it got, as the handlers do.
+// It returns the Message-ID, the Reply-To address and the subject of the email the contractor got.
+func (f *ingestionFixture) sendRequest(t *testing.T) (string, string, string) {
+	t.Helper()
+
+	err := f.emailService.SendTimesheetRequestEmail(f.group, f.contractor, f.request, "")
+	if err != nil {
+		t.Fatalf("SendTimesheetRequestEmail: %v", err)
+	}
+
+	if f.request.ReplyKey == "" {
+		t.Fatal("request has no reply key")
+	}
+	err = f.timesheetRequestsDB.UpdateTimesheetRequest(f.request)
+	if err != nil {
+		t.Fatalf("UpdateTimesheetRequest: %v", err)
+	}
+
+	header := f.sentEmail(t, testContractorEmail)
+	messageID := header.Get("Message-Id")
+	if messageID == "" {
+		t.Fatal("request email has no Message-ID")
+	}
+	replyTo, err := header.AddressList("Reply-To")
+	if err != nil || len(replyTo) != 1 {
+		t.Fatalf("request email has no Reply-To: %v", err)
+	}
+
+	return messageID, replyTo[0].Address, header.Get("Subject")
+}
+
+// waitForEmptyInbox waits until the inbox of the service is empty, failing the test after a few seconds.
+func (f *ingestionFixture) waitForEmptyInbox(t *testing.T) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(f.mailServer.Messages(testServiceEmail)) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("inbox was not emptied")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestInboxWatcherIngestsReplyToRequestEmail(t *testing.T) {
+	f := newIngestionFixture(t)
+
+	// The contractor gets the request, the reply goes to its Reply-To
+	messageID, replyTo, subject := f.sendRequest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go f.inboxWatcherService.Watch(ctx)
+
+	f.deliverReply(testContractorEmail, replyTo, messageID, "Re: "+subject, testTimesheetCSV)
+
+	request := f.waitForStatus(t, constants.Collected)
+	if request.CollectedAt.IsZero() {
+		t.Error("collected request has no CollectedAt")
+	}
+
+	timesheet, err := f.timesheetsDB.GetTimesheet(f.contractor.ID, testRequestID)
+	if err != nil {
+		t.Fatalf("GetTimesheet: %v", err)
+	}
+	if timesheet.TotalHours != 15.5 {
+		t.Errorf("TotalHours = %v, want 15.5", timesheet.TotalHours)
+	}
+	if timesheet.Status != constants.Received {
+		t.Errorf("Status = %q, want %q", timesheet.Status, constants.Received)
+	}
+
+	// The ingested reply is archived
+	f.waitForEmptyInbox(t)
+}
+
+func TestInboxWatcherWaitsForLeaseOfOtherInstance(t *testing.T) {
+	f := newIngestionFixture(t)
+	messageID, replyTo, subject := f.sendRequest(t)
+
+	// Another instance watches the inbox
+	_, err := f.leasesDB.AcquireLease(inboxWatcherLeaseName, "other", time.Minute)
+	if err != nil {
+		t.Fatalf("AcquireLease: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go f.inboxWatcherService.Watch(ctx)
+
+	f.deliverReply(testContractorEmail, replyTo, messageID, "Re: "+subject, testTimesheetCSV)
+	time.Sleep(300 * time.Millisecond)
+
+	request, err := f.timesheetRequestsDB.GetTimesheetRequest(f.contractor.ID, testRequestID)
+	if err != nil {
+		t.Fatalf("GetTimesheetRequest: %v", err)
+	}
+	if request.Status != constants.Pending {
+		t.Errorf("request status = %q, want %q", request.Status, constants.Pending)
+	}
+	if n := len(f.mailServer.Messages(testServiceEmail)); n != 1 {
+		t.Errorf("inbox has %d emails, want 1", n)
+	}
+}
+
+func TestInboxWatcherLeavesReplyFromOtherSenderForOwner(t *testing.T) {
+	f := newIngestionFixture(t)
+
+	// Someone the request was forwarded to replies with its headers
+	messageID, replyTo, subject := f.sendRequest(t)
+
+	email := &types.InboundEmail{
+		From:        "someone@example.com",
+		To:          []string{replyTo},
+		Subject:     "Re: " + subject,
+		InReplyTo:   []string{messageID},
+		Attachments: []types.Attachment{{Filename: "timesheet.csv", Content: []byte(testTimesheetCSV)}},
+	}
+
+	unassigned, err := f.timesheetIngestionService.TriageEmail(email)
+	if err != nil {
+		t.Fatalf("TriageEmail: %v", err)
+	}
+	if unassigned.Reason != constants.OtherSender {
+		t.Errorf("Reason = %v, want %v", unassigned.Reason, constants.OtherSender)
+	}
+	if unassigned.GroupID != f.group.ID || unassigned.ContractorID != f.contractor.ID || unassigned.RequestID != testRequestID {
+		t.Errorf("unassigned email is not attributed to the request of the contractor: %+v", unassigned)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go f.inboxWatcherService.Watch(ctx)
+
+	f.deliverReply(email.From, replyTo, messageID, email.Subject, testTimesheetCSV)
+
+	// The reply is moved to the unassigned emails with its triage saved for the owner, the request still waits for the contractor's timesheet
+	f.waitForEmptyInbox(t)
+
+	emails, err := f.unassignedEmailsDB.ListUnassignedEmails(f.group.ID)
+	if err != nil {
+		t.Fatalf("ListUnassignedEmails: %v", err)
+	}
+	if len(emails) != 1 || emails[0].Reason != constants.OtherSender || emails[0].From != email.From || len(emails[0].Attachments) != 1 {
+		t.Fatalf("unassigned emails = %+v, want the reply of %s", emails, email.From)
+	}
+
+	// The owner finds it again in the unassigned mailbox
+	moved, err := f.emailService.GetUnassignedEmail(emails[0])
+	if err != nil {
+		t.Fatalf("GetUnassignedEmail: %v", err)
+	}
+	if moved.From != email.From || len(moved.Attachments) != 1 {
+		t.Errorf("email = %+v, want the reply of %s", moved, email.From)
+	}
+
+	request, err := f.timesheetRequestsDB.GetTimesheetRequest(f.contractor.ID, testRequestID)
+	if err != nil {
+		t.Fatalf("GetTimesheetRequest: %v", err)
+	}
+	if request.Status != constants.Pending {
+		t.Errorf("Status = %q, want %q", request.Status, constants.Pending)
+	}
+
+	_, err = f.timesheetsDB.GetTimesheet(f.contractor.ID, testRequestID)
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("GetTimesheet error = %v, want NotFound", err)
+	}
+}
+
+func TestTriageEmailOfContractorWithoutRequest(t *testing.T) {
+	const sharedEmail = "anna@example.com"
+
+	tests := []struct {
+		name           string
+		from           string
+		otherGroup     bool // Whether the sender is also a contractor of another owner's group
+		wantReason     constants.UnassignedReasons
+		wantContractor bool
+	}{
+		{"contractor of the group", sharedEmail, false, constants.WrongPeriod, true},
+		{"contractor of several groups", sharedEmail, true, constants.UnknownSender, false},
+		{"unknown sender", "someone@example.com", false, constants.UnknownSender, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newIngestionFixture(t)
+
+			err := f.contractorsDB.AddContractor(f.group.ID, &types.Contractor{Name: "Anna", Email: sharedEmail})
+			if err != nil {
+				t.Fatalf("AddContractor: %v", err)
+			}
+
+			if tt.otherGroup {
+				other, err := f.groupsDB.AddGroup(&types.Group{OwnerID: "other owner", Name: "Globex"})
+				if err != nil {
+					t.Fatalf("AddGroup: %v", err)
+				}
+
+				err = f.contractorsDB.AddContractor(other.ID, &types.Contractor{Name: "Anna", Email: sharedEmail})
+				if err != nil {
+					t.Fatalf("AddContractor: %v", err)
+				}
+			}
+
+			unassigned, err := f.timesheetIngestionService.TriageEmail(&types.InboundEmail{From: tt.from, Subject: "My hours"})
+			if err != nil {
+				t.Fatalf("TriageEmail: %v", err)
+			}
+			if unassigned.Reason != tt.wantReason {
+				t.Errorf("Reason = %v, want %v", unassigned.Reason, tt.wantReason)
+			}
+			if (unassigned.ContractorID != "") != tt.wantContractor {
+				t.Errorf("ContractorID = %q, want attributed %v", unassigned.ContractorID, tt.wantContractor)
+			}
+			wantGroupID := ""
+			if tt.wantContractor {
+				wantGroupID = f.group.ID
+			}
+			if unassigned.GroupID != wantGroupID {
+				t.Errorf("GroupID = %q, want %q", unassigned.GroupID, wantGroupID)
+			}
+		})
+	}
+}
+
+func TestInboxWatcherRepliesToEmailOfNoGroup(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    string // Extra header of the email
+		wantReply bool
+	}{
+		{"unknown sender", "", true},
+		{"automatic reply", "Auto-Submitted: auto-replied\r\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newIngestionFixture(t)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go f.inboxWatcherService.Watch(ctx)
+
+			raw := "From: someone@example.com\r\n" +
+				"To: " + testServiceEmail + "\r\n" +
+				"Subject: My hours\r\n" +
+				"Message-ID: <hours@example.com>\r\n" +
+				tt.header +
+				"\r\n" +
+				"Hello.\r\n"
+			f.mailServer.Deliver("someone@example.com", []string{testServiceEmail}, []byte(raw))
+
+			// Nobody could triage it, it is archived instead of left in the unassigned mailbox
+			f.waitForEmptyInbox(t)
+
+			emails, err := f.emailService.GetUnassignedEmails()
+			if err != nil {
+				t.Fatalf("GetUnassignedEmails: %v", err)
+			}
+			if len(emails) != 0 {
+				t.Errorf("unassigned emails = %d, want none", len(emails))
+			}
+
+			replies := f.mailServer.Messages("someone@example.com")
+			if (len(replies) == 1) != tt.wantReply || len(replies) > 1 {
+				t.Fatalf("got %d replies, want a reply %v", len(replies), tt.wantReply)
+			}
+			if tt.wantReply {
+				header := f.sentEmail(t, "someone@example.com")
+				if header.Get("Auto-Submitted") != "auto-replied" || header.Get("In-Reply-To") != "<hours@example.com>" {
+					t.Errorf("reply headers = %v, want an automatic reply in the thread", header)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package core
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	constants "job_sender/utils/constants"
+)
+
+// jwksKeySet is the RSA keys of a JSON Web Key Set, fetched again when they expire or a token is signed with an unknown key.
+type jwksKeySet struct {
+	url    string
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey // Keys by their ID
+	expiresAt time.Time
+	fetchedAt time.Time
+}
+
+// jwksDocument is a JSON Web Key Set as served.
+type jwksDocument struct {
+	Keys []struct {
+		Kid string `json:"kid"`
+		Kty string `json:"kty"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// newJwksKeySet creates a new jwksKeySet of the JWKS at url, the keys are fetched when first needed.
+func newJwksKeySet(url string) *jwksKeySet {
+	return &jwksKeySet{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// key returns the key with the ID, nil if the JWKS does not have it.
+func (k *jwksKeySet) key(kid string) (*rsa.PublicKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	// A key that is not known may have been added since, the JWKS is fetched again at most once a minute for it
+	now := time.Now()
+	key, ok := k.keys[kid]
+	expired := now.After(k.expiresAt)
+	unknown := !ok && now.Sub(k.fetchedAt) > constants.CallbackJwksRefreshDelaySeconds*time.Second
+	if !expired && !unknown {
+		return key, nil
+	}
+
+	err := k.fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	return k.keys[kid], nil
+}
+
+// fetch gets the keys of the JWKS, for as long as its Cache-Control allows.
+func (k *jwksKeySet) fetch() error {
+	resp, err := k.client.Get(k.url)
+	if err != nil {
+		return fmt.Errorf("could not get JWKS %s: %w", k.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("could not get JWKS %s: unexpected status %s", k.url, resp.Status)
+	}
+
+	var document jwksDocument
+	err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&document)
+	if err != nil {
+		return fmt.Errorf("could not decode JWKS %s: %w", k.url, err)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range document.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+
+		n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			return fmt.Errorf("invalid key %s in JWKS %s", jwk.Kid, k.url)
+		}
+
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	now := time.Now()
+	k.keys = keys
+	k.fetchedAt = now
+	k.expiresAt = now.Add(cacheMaxAge(resp.Header.Get("Cache-Control")))
+
+	return nil
+}
+
+// cacheMaxAge returns the max-age of a Cache-Control header, or the default time the keys are kept for.
+func cacheMaxAge(cacheControl string) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		value, ok := strings.CutPrefix(strings.TrimSpace(directive), "max-age=")
+		if !ok {
+			continue
+		}
+
+		seconds, err := strconv.Atoi(value)
+		if err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+
+	return constants.CallbackJwksCacheSeconds * time.Second
+}
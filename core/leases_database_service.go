@@ -0,0 +1,86 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LeasesDatabaseService is a service for keeping the leases of the background jobs in a database.
+type LeasesDatabaseService struct {
+	collectionName string
+	client         *firestore.Client
+}
+
+// Ensure LeasesDatabaseService implements ILeasesDatabaseService.
+var _ interfaces.ILeasesDatabaseService = &LeasesDatabaseService{}
+
+// NewLeasesDatabaseService creates a new LeasesDatabaseService.
+func NewLeasesDatabaseService(firebaseService *FirebaseService) (*LeasesDatabaseService, error) {
+	ctx := context.Background()
+	client, err := firebaseService.app.Firestore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not get Firestore client: %w", err)
+	}
+
+	// Verify that we can communicate and authenticate with the Firestore service.
+	err = client.RunTransaction(ctx, func(ctx context.Context, t *firestore.Transaction) error {
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not connect: %w", err)
+	}
+
+	return &LeasesDatabaseService{
+		collectionName: "leases",
+		client:         client,
+	}, nil
+}
+
+// Close closes the database.
+func (db *LeasesDatabaseService) Close() error {
+	return db.client.Close()
+}
+
+// AcquireLease takes the lease with the given name for a holder until the given duration has passed, or renews it if the holder has it.
+// It returns a FailedPrecondition status if another holder has the lease and it has not expired.
+func (db *LeasesDatabaseService) AcquireLease(name string, holder string, duration time.Duration) (*types.Lease, error) {
+	ctx := context.Background()
+	ref := db.client.Collection(db.collectionName).Doc(name)
+
+	var lease *types.Lease
+	err := db.client.RunTransaction(ctx, func(ctx context.Context, t *firestore.Transaction) error {
+		doc, err := t.Get(ref)
+		if err == nil {
+			var current types.Lease
+			err = doc.DataTo(&current)
+			if err != nil {
+				return fmt.Errorf("could not convert data to lease: %w", err)
+			}
+
+			if current.Holder != holder && time.Now().Before(current.ExpiresAt) {
+				return status.Errorf(codes.FailedPrecondition, "lease %s is held by %s until %s", name, current.Holder, current.ExpiresAt.Format(time.RFC3339))
+			}
+		} else if status.Code(err) != codes.NotFound {
+			return fmt.Errorf("could not get lease: %w", err)
+		}
+
+		lease = &types.Lease{Name: name, Holder: holder, ExpiresAt: time.Now().Add(duration)}
+
+		return t.Set(ref, lease)
+	})
+	if status.Code(err) == codes.FailedPrecondition {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not acquire lease: %w", err)
+	}
+
+	return lease, nil
+}
@@ -0,0 +1,40 @@
+package core
+
+import (
+	"time"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+)
+
+// LocalAuthService registers and logs in users against the LocalFirebaseService.
+// Sessions are checked exactly like in AuthService, their ID tokens are refreshed by the LocalFirebaseService.
+type LocalAuthService struct {
+	*AuthService
+
+	localFirebaseService *LocalFirebaseService
+}
+
+// Ensure LocalAuthService implements IAuthService.
+var _ interfaces.IAuthService = &LocalAuthService{}
+
+// NewLocalAuthService creates a new LocalAuthService.
+func NewLocalAuthService(localFirebaseService *LocalFirebaseService, sessionManagerService interfaces.ISessionManagerService, sessionLifetime time.Duration, rememberMeSessionLifetime time.Duration) *LocalAuthService {
+	authService := NewAuthService(localFirebaseService, "", sessionManagerService, sessionLifetime, rememberMeSessionLifetime)
+	authService.refreshIDToken = localFirebaseService.refreshIDToken
+
+	return &LocalAuthService{
+		AuthService:          authService,
+		localFirebaseService: localFirebaseService,
+	}
+}
+
+// Register registers a new user.
+func (s *LocalAuthService) Register(email string, password string) (*types.LoginResponseBody, error) {
+	return s.localFirebaseService.signUp(email, password)
+}
+
+// Login logs in a user.
+func (s *LocalAuthService) Login(email string, password string) (*types.LoginResponseBody, error) {
+	return s.localFirebaseService.signIn(email, password)
+}
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type LocalContractorsDatabaseService struct {
+	contractorsCollectionName string
+	store                     *LocalStore
+}
+
+// Ensure LocalContractorsDatabaseService implements IContractorsDatabaseService.
+var _ interfaces.IContractorsDatabaseService = &LocalContractorsDatabaseService{}
+
+// NewLocalContractorsDatabaseService creates a new LocalContractorsDatabaseService.
+func NewLocalContractorsDatabaseService(store *LocalStore) *LocalContractorsDatabaseService {
+	return &LocalContractorsDatabaseService{
+		contractorsCollectionName: "contractors",
+		store:                     store,
+	}
+}
+
+// GetContractors gets all contractors for a group.
+func (db *LocalContractorsDatabaseService) GetContractors(groupID string) ([]*types.Contractor, error) {
+	contractors, err := localList(db.store, db.contractorsCollectionName, func(c *types.Contractor) bool { return c.GroupID == groupID })
+	if err != nil {
+		return nil, fmt.Errorf("localstore: could not list contractors: %w", err)
+	}
+
+	return contractors, nil
+}
+
+// GetAllContractors gets the contractors of every group.
+func (db *LocalContractorsDatabaseService) GetAllContractors() ([]*types.Contractor, error) {
+	contractors, err := localList(db.store, db.contractorsCollectionName, func(c *types.Contractor) bool { return true })
+	if err != nil {
+		return nil, fmt.Errorf("localstore: could not list contractors: %w", err)
+	}
+
+	return contractors, nil
+}
+
+// GetContractorsByEmail gets the contractors with an email address, in every group.
+func (db *LocalContractorsDatabaseService) GetContractorsByEmail(email string) ([]*types.Contractor, error) {
+	contractors, err := localList(db.store, db.contractorsCollectionName, func(c *types.Contractor) bool { return strings.EqualFold(c.Email, email) })
+	if err != nil {
+		return nil, fmt.Errorf("localstore: could not list contractors: %w", err)
+	}
+
+	return contractors, nil
+}
+
+// GetContractor gets a contractor by ID.
+func (db *LocalContractorsDatabaseService) GetContractor(id string) (*types.Contractor, error) {
+	var contractor types.Contractor
+	err := db.store.Get(db.contractorsCollectionName, id, &contractor)
+	if err != nil {
+		return nil, fmt.Errorf("localstore: could not get contractor: %w", err)
+	}
+
+	return &contractor, nil
+}
+
+// AddContractor adds a contractor to a group.
+func (db *LocalContractorsDatabaseService) AddContractor(groupID string, contractor *types.Contractor) error {
+	// Check if contractor already exists.
+	existing, err := localList(db.store, db.contractorsCollectionName, func(c *types.Contractor) bool {
+		return c.GroupID == groupID && c.Email == contractor.Email
+	})
+	if err != nil {
+		return fmt.Errorf("localstore: could not check if contractor exists: %w", err)
+	}
+	if len(existing) > 0 {
+		return status.Errorf(codes.AlreadyExists, "localstore: contractor already exists")
+	}
+
+	contractor.ID = db.store.NewID()
+	contractor.GroupID = groupID
+
+	err = db.store.Create(db.contractorsCollectionName, contractor.ID, contractor)
+	if err != nil {
+		return fmt.Errorf("localstore: could not add contractor: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateContractor updates a contractor.
+func (db *LocalContractorsDatabaseService) UpdateContractor(contractor *types.Contractor) error {
+	err := db.store.Set(db.contractorsCollectionName, contractor.ID, contractor)
+	if err != nil {
+		return fmt.Errorf("localstore: could not update contractor: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteContractor deletes a contractor with its timesheet requests and ingestions.
+func (db *LocalContractorsDatabaseService) DeleteContractor(id string) error {
+	err := deleteLocalContractorTimesheetRequests(db.store, id)
+	if err != nil {
+		return fmt.Errorf("localstore: %w", err)
+	}
+
+	err = deleteLocalContractorTimesheetIngestions(db.store, id)
+	if err != nil {
+		return fmt.Errorf("localstore: %w", err)
+	}
+
+	err = db.store.Delete(db.contractorsCollectionName, id)
+	if err != nil {
+		return fmt.Errorf("localstore: could not delete contractor: %w", err)
+	}
+
+	return nil
+}
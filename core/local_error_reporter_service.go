@@ -0,0 +1,30 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/debug"
+
+	"job_sender/interfaces"
+)
+
+// LocalErrorReporterService writes error reports to a log writer instead of sending them to Error Reporting.
+type LocalErrorReporterService struct {
+	LogWriter io.Writer
+}
+
+// Ensure LocalErrorReporterService implements IErrorReporterService.
+var _ interfaces.IErrorReporterService = &LocalErrorReporterService{}
+
+// NewLocalErrorReporterService creates a new LocalErrorReporterService.
+func NewLocalErrorReporterService(logWriter io.Writer) *LocalErrorReporterService {
+	return &LocalErrorReporterService{
+		LogWriter: logWriter,
+	}
+}
+
+// ReportError logs the error together with the request and the stack trace.
+func (h *LocalErrorReporterService) ReportError(w http.ResponseWriter, r *http.Request, err error) {
+	fmt.Fprintf(h.LogWriter, "Error reported: %s %s: message: %s, underlying err: %+v\n%s\n", r.Method, r.URL, err.Error(), err, debug.Stack())
+}
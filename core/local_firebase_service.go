@@ -0,0 +1,400 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// localIDTokenLifetime is the lifetime of the ID tokens, the same as the Firebase ones.
+const localIDTokenLifetime = time.Hour
+
+// LocalFirebaseService keeps the user accounts in the local store and issues HMAC signed ID tokens.
+// Accounts are verified as soon as they are created, as there is no inbox to click the link in.
+type LocalFirebaseService struct {
+	collectionName string
+	store          *LocalStore
+	tokenKey       []byte
+	appURL         string
+}
+
+// localUser is a user account as kept in the local store.
+type localUser struct {
+	ID            string
+	Email         string
+	PasswordHash  []byte
+	EmailVerified bool
+
+	TokensValidAfter int64 // Unix time the ID tokens issued before are revoked at
+}
+
+// localIDTokenClaims are the claims of a local ID token, or of a refresh token, which never expires.
+type localIDTokenClaims struct {
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	Refresh   bool   `json:"refresh,omitempty"`
+}
+
+// Ensure LocalFirebaseService implements IFirebaseService.
+var _ interfaces.IFirebaseService = &LocalFirebaseService{}
+
+// NewLocalFirebaseService creates a new LocalFirebaseService signing the ID tokens with tokenKey.
+func NewLocalFirebaseService(store *LocalStore, tokenKey []byte, appURL string) *LocalFirebaseService {
+	return &LocalFirebaseService{
+		collectionName: "users",
+		store:          store,
+		tokenKey:       tokenKey,
+		appURL:         appURL,
+	}
+}
+
+// CheckIfUserExists checks if the user exists.
+func (s *LocalFirebaseService) CheckIfUserExists(email string) (bool, error) {
+	user, err := s.getUserByEmail(email)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return user != nil, nil
+}
+
+// CheckIsUserVerified checks if the user is verified.
+func (s *LocalFirebaseService) CheckIsUserVerified(email string) (bool, error) {
+	user, err := s.getUserByEmail(email)
+	if err != nil {
+		return false, err
+	}
+
+	return user.EmailVerified, nil
+}
+
+// GetCustomClaims verifies a local ID token and returns its claims.
+func (s *LocalFirebaseService) GetCustomClaims(idToken string) (map[string]interface{}, error) {
+	claims, err := s.verifyToken(idToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Refresh {
+		return nil, fmt.Errorf("malformed ID token: it is a refresh token")
+	}
+
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, fmt.Errorf("ID token has expired")
+	}
+
+	// The tokens issued before the sessions of the user were revoked are rejected, like Firebase does
+	var user localUser
+	err = s.store.Get(s.collectionName, claims.UserID, &user)
+	if err != nil {
+		return nil, fmt.Errorf("could not get user of ID token: %w", err)
+	}
+
+	if claims.IssuedAt < user.TokensValidAfter {
+		return nil, fmt.Errorf("ID token has been revoked")
+	}
+
+	return map[string]interface{}{
+		"user_id": claims.UserID,
+		"email":   claims.Email,
+		"exp":     claims.ExpiresAt,
+	}, nil
+}
+
+// EmailVerificationLink returns the login page, local accounts are verified on creation.
+func (s *LocalFirebaseService) EmailVerificationLink(email string) (string, error) {
+	if _, err := s.getUserByEmail(email); err != nil {
+		return "", err
+	}
+
+	return s.appURL + "/login", nil
+}
+
+// GetUser gets a user by ID, it returns a NotFound status if there is none.
+func (s *LocalFirebaseService) GetUser(id string) (*types.AuthUser, error) {
+	var user localUser
+	err := s.store.Get(s.collectionName, id, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	return user.authUser(), nil
+}
+
+// GetUserByEmail gets a user by email, it returns a NotFound status if there is none.
+func (s *LocalFirebaseService) GetUserByEmail(email string) (*types.AuthUser, error) {
+	user, err := s.getUserByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	return user.authUser(), nil
+}
+
+// UpdatePassword sets the password of a user.
+func (s *LocalFirebaseService) UpdatePassword(id string, password string) error {
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("could not hash password: %w", err)
+	}
+
+	return s.updateUser(id, func(user *localUser) error {
+		user.PasswordHash = passwordHash
+		return nil
+	})
+}
+
+// UpdateEmail sets the email of a user, the address is verified as the user proved to own it.
+func (s *LocalFirebaseService) UpdateEmail(id string, email string) error {
+	existing, err := s.getUserByEmail(email)
+	if err == nil && existing.ID != id {
+		return status.Errorf(codes.AlreadyExists, "EMAIL_EXISTS")
+	} else if err != nil && status.Code(err) != codes.NotFound {
+		return err
+	}
+
+	return s.updateUser(id, func(user *localUser) error {
+		user.Email = email
+		user.EmailVerified = true
+		return nil
+	})
+}
+
+// RevokeSessions revokes the ID tokens issued to a user until now, the sessions holding them are logged out.
+func (s *LocalFirebaseService) RevokeSessions(id string) error {
+	return s.updateUser(id, func(user *localUser) error {
+		user.TokensValidAfter = time.Now().Unix()
+		return nil
+	})
+}
+
+// DeleteUser deletes a user, a user that does not exist is already deleted.
+func (s *LocalFirebaseService) DeleteUser(id string) error {
+	err := s.store.Delete(s.collectionName, id)
+	if err != nil {
+		return fmt.Errorf("could not delete user: %w", err)
+	}
+
+	return nil
+}
+
+// updateUser applies a change to the account of a user.
+func (s *LocalFirebaseService) updateUser(id string, update func(user *localUser) error) error {
+	var user localUser
+	err := s.store.Get(s.collectionName, id, &user)
+	if err != nil {
+		return err
+	}
+
+	err = update(&user)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.Set(s.collectionName, id, &user)
+	if err != nil {
+		return fmt.Errorf("could not update user: %w", err)
+	}
+
+	return nil
+}
+
+// signUp creates a new account and signs it in.
+func (s *LocalFirebaseService) signUp(email string, password string) (*types.LoginResponseBody, error) {
+	exists, err := s.CheckIfUserExists(email)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, status.Errorf(codes.AlreadyExists, "EMAIL_EXISTS")
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("could not hash password: %w", err)
+	}
+
+	user := &localUser{
+		ID:            s.store.NewID(),
+		Email:         email,
+		PasswordHash:  passwordHash,
+		EmailVerified: true,
+	}
+
+	if err := s.store.Create(s.collectionName, user.ID, user); err != nil {
+		return nil, fmt.Errorf("could not create user: %w", err)
+	}
+
+	return s.issueToken(user)
+}
+
+// signIn checks the password of an account. Like the Firebase REST API,
+// wrong credentials are not an error but a response without an ID token.
+func (s *LocalFirebaseService) signIn(email string, password string) (*types.LoginResponseBody, error) {
+	user, err := s.getUserByEmail(email)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return &types.LoginResponseBody{}, nil
+		}
+		return nil, err
+	}
+
+	if bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(password)) != nil {
+		return &types.LoginResponseBody{}, nil
+	}
+
+	return s.issueToken(user)
+}
+
+// refreshIDToken exchanges a refresh token for a new ID token. Like the Firebase REST API, a refresh token of a deleted user,
+// or issued before the sessions of the user were revoked, is not an error but a response without an ID token.
+func (s *LocalFirebaseService) refreshIDToken(refreshToken string) (*types.RefreshTokenResponseBody, error) {
+	claims, err := s.verifyToken(refreshToken)
+	if err != nil || !claims.Refresh {
+		return &types.RefreshTokenResponseBody{}, nil
+	}
+
+	var user localUser
+	err = s.store.Get(s.collectionName, claims.UserID, &user)
+	if status.Code(err) == codes.NotFound {
+		return &types.RefreshTokenResponseBody{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get user of refresh token: %w", err)
+	}
+
+	if claims.IssuedAt < user.TokensValidAfter {
+		return &types.RefreshTokenResponseBody{}, nil
+	}
+
+	responseBody, err := s.issueToken(&user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.RefreshTokenResponseBody{
+		IdToken:      responseBody.IdToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    responseBody.ExpiresIn,
+		UserId:       user.ID,
+	}, nil
+}
+
+// issueToken creates the sign in response of a user.
+func (s *LocalFirebaseService) issueToken(user *localUser) (*types.LoginResponseBody, error) {
+	now := time.Now()
+	idToken, err := s.signToken(&localIDTokenClaims{
+		UserID:    user.ID,
+		Email:     user.Email,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(localIDTokenLifetime).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := s.signToken(&localIDTokenClaims{
+		UserID:   user.ID,
+		Email:    user.Email,
+		IssuedAt: now.Unix(),
+		Refresh:  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.LoginResponseBody{
+		IdToken:      idToken,
+		RefreshToken: refreshToken,
+		Email:        user.Email,
+		ExpiresIn:    strconv.Itoa(int(localIDTokenLifetime.Seconds())),
+		LocalId:      user.ID,
+		Registered:   true,
+	}, nil
+}
+
+// signToken encodes and signs the claims of a token.
+func (s *LocalFirebaseService) signToken(claims *localIDTokenClaims) (string, error) {
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(b)
+
+	return payload + "." + s.sign(payload), nil
+}
+
+// verifyToken checks the signature of a token and returns its claims.
+func (s *LocalFirebaseService) verifyToken(token string) (*localIDTokenClaims, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, fmt.Errorf("malformed ID token")
+	}
+
+	expectedSignature := s.sign(payload)
+	if !hmac.Equal([]byte(signature), []byte(expectedSignature)) {
+		return nil, fmt.Errorf("invalid ID token signature")
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("malformed ID token: %w", err)
+	}
+
+	var claims localIDTokenClaims
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return nil, fmt.Errorf("malformed ID token: %w", err)
+	}
+
+	return &claims, nil
+}
+
+// sign returns the HMAC of a token payload.
+func (s *LocalFirebaseService) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.tokenKey)
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// getUserByEmail returns the account of an email, or a NotFound status.
+func (s *LocalFirebaseService) getUserByEmail(email string) (*localUser, error) {
+	users, err := localList(s.store, s.collectionName, func(u *localUser) bool { return strings.EqualFold(u.Email, email) })
+	if err != nil {
+		return nil, err
+	}
+
+	if len(users) == 0 {
+		return nil, status.Errorf(codes.NotFound, "cannot find user from email %s", email)
+	}
+
+	return users[0], nil
+}
+
+// authUser returns the AuthUser of a local account.
+func (u *localUser) authUser() *types.AuthUser {
+	return &types.AuthUser{
+		ID:            u.ID,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+
+		TokensValidAfter: u.TokensValidAfter,
+	}
+}
@@ -0,0 +1,160 @@
+package core
+
+import (
+	"fmt"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type LocalGroupsDatabaseService struct {
+	ownerCollectionName string
+	groupCollectionName string
+	store               *LocalStore
+}
+
+// Ensure LocalGroupsDatabaseService implements IGroupsDatabaseService.
+var _ interfaces.IGroupsDatabaseService = &LocalGroupsDatabaseService{}
+
+// NewLocalGroupsDatabaseService creates a new LocalGroupsDatabaseService.
+func NewLocalGroupsDatabaseService(store *LocalStore) *LocalGroupsDatabaseService {
+	return &LocalGroupsDatabaseService{
+		ownerCollectionName: "owners",
+		groupCollectionName: "groups",
+		store:               store,
+	}
+}
+
+// GetGroup gets a group by ID.
+func (db *LocalGroupsDatabaseService) GetGroup(id string) (*types.Group, error) {
+	var group types.Group
+	err := db.store.Get(db.groupCollectionName, id, &group)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, status.Errorf(codes.NotFound, "group with ID %s does not exist", id)
+		}
+		return nil, fmt.Errorf("could not get group: %w", err)
+	}
+
+	return &group, nil
+}
+
+// GetAllGroups gets the groups of every owner.
+func (db *LocalGroupsDatabaseService) GetAllGroups() ([]*types.Group, error) {
+	groups, err := localList(db.store, db.groupCollectionName, func(g *types.Group) bool { return true })
+	if err != nil {
+		return nil, fmt.Errorf("could not list groups: %w", err)
+	}
+
+	return groups, nil
+}
+
+// AddGroup adds a group.
+func (db *LocalGroupsDatabaseService) AddGroup(group *types.Group) (*types.Group, error) {
+	group.ID = db.store.NewID()
+
+	err := db.store.Create(db.groupCollectionName, group.ID, group)
+	if err != nil {
+		return nil, fmt.Errorf("could not add group: %w", err)
+	}
+
+	return group, nil
+}
+
+// UpdateGroup updates a group.
+func (db *LocalGroupsDatabaseService) UpdateGroup(group *types.Group) error {
+	err := db.store.Set(db.groupCollectionName, group.ID, group)
+	if err != nil {
+		return fmt.Errorf("could not update group: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteGroup deletes a group and all contractors.
+func (db *LocalGroupsDatabaseService) DeleteGroup(id string) error {
+	group, err := db.GetGroup(id)
+	if err != nil {
+		return err
+	}
+
+	err = deleteLocalGroupContractors(db.store, group.ID)
+	if err != nil {
+		return err
+	}
+
+	err = db.store.Delete(db.groupCollectionName, id)
+	if err != nil {
+		return fmt.Errorf("could not delete group: %w", err)
+	}
+
+	// Delete the groupID from the owner.
+	if group.OwnerID != "" {
+		var owner types.Owner
+		err = db.store.Get(db.ownerCollectionName, group.OwnerID, &owner)
+		if err != nil {
+			return fmt.Errorf("could not get owner: %w", err)
+		}
+
+		owner.GroupID = ""
+
+		err = db.store.Set(db.ownerCollectionName, owner.ID, &owner)
+		if err != nil {
+			return fmt.Errorf("could not update owner: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// deleteLocalGroupContractors deletes the contractors of a group together with their timesheet requests and ingestions, timesheets, timesheet entries and versions.
+func deleteLocalGroupContractors(store *LocalStore, groupID string) error {
+	contractors, err := localList(store, "contractors", func(c *types.Contractor) bool { return c.GroupID == groupID })
+	if err != nil {
+		return fmt.Errorf("could not get contractors: %w", err)
+	}
+
+	for _, contractor := range contractors {
+		timesheets, err := localList(store, "timesheets", func(t *types.Timesheet) bool { return t.ContractorID == contractor.ID })
+		if err != nil {
+			return fmt.Errorf("could not get timesheets: %w", err)
+		}
+
+		for _, timesheet := range timesheets {
+			err = deleteLocalTimesheetEntries(store, timesheet.ID)
+			if err != nil {
+				return err
+			}
+
+			err = deleteLocalTimesheetVersions(store, timesheet.ID)
+			if err != nil {
+				return err
+			}
+
+			err = store.Delete("timesheets", timesheet.ID)
+			if err != nil {
+				return fmt.Errorf("could not delete timesheet: %w", err)
+			}
+		}
+
+		err = deleteLocalContractorTimesheetRequests(store, contractor.ID)
+		if err != nil {
+			return err
+		}
+
+		err = deleteLocalContractorTimesheetIngestions(store, contractor.ID)
+		if err != nil {
+			return err
+		}
+
+		err = store.Delete("contractors", contractor.ID)
+		if err != nil {
+			return fmt.Errorf("could not delete contractor: %w", err)
+		}
+	}
+
+	return nil
+}
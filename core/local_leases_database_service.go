@@ -0,0 +1,57 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LocalLeasesDatabaseService is a service for keeping the leases of the background jobs in the local store.
+type LocalLeasesDatabaseService struct {
+	collectionName string
+	store          *LocalStore
+}
+
+// Ensure LocalLeasesDatabaseService implements ILeasesDatabaseService.
+var _ interfaces.ILeasesDatabaseService = &LocalLeasesDatabaseService{}
+
+// NewLocalLeasesDatabaseService creates a new LocalLeasesDatabaseService.
+func NewLocalLeasesDatabaseService(store *LocalStore) *LocalLeasesDatabaseService {
+	return &LocalLeasesDatabaseService{
+		collectionName: "leases",
+		store:          store,
+	}
+}
+
+// AcquireLease takes the lease with the given name for a holder until the given duration has passed, or renews it if the holder has it.
+// It returns a FailedPrecondition status if another holder has the lease and it has not expired.
+func (db *LocalLeasesDatabaseService) AcquireLease(name string, holder string, duration time.Duration) (*types.Lease, error) {
+	var lease *types.Lease
+	err := db.store.RunTransaction(func(t *LocalTransaction) error {
+		var current types.Lease
+		err := t.Get(db.collectionName, name, &current)
+		if err == nil {
+			if current.Holder != holder && time.Now().Before(current.ExpiresAt) {
+				return status.Errorf(codes.FailedPrecondition, "lease %s is held by %s until %s", name, current.Holder, current.ExpiresAt.Format(time.RFC3339))
+			}
+		} else if status.Code(err) != codes.NotFound {
+			return fmt.Errorf("could not get lease: %w", err)
+		}
+
+		lease = &types.Lease{Name: name, Holder: holder, ExpiresAt: time.Now().Add(duration)}
+
+		return t.Set(db.collectionName, name, lease)
+	})
+	if status.Code(err) == codes.FailedPrecondition {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("localstore: could not acquire lease: %w", err)
+	}
+
+	return lease, nil
+}
@@ -0,0 +1,48 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAcquireLease(t *testing.T) {
+	store, err := NewLocalStore(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	db := NewLocalLeasesDatabaseService(store)
+
+	tests := []struct {
+		name     string
+		holder   string
+		duration time.Duration
+		wait     time.Duration
+		wantCode codes.Code
+	}{
+		{name: "free lease", holder: "a", duration: 50 * time.Millisecond},
+		{name: "lease of another holder", holder: "b", duration: time.Minute, wantCode: codes.FailedPrecondition},
+		{name: "renewed by its holder", holder: "a", duration: 50 * time.Millisecond},
+		{name: "expired lease", holder: "b", duration: time.Minute, wait: 100 * time.Millisecond},
+		{name: "lease taken over", holder: "a", duration: time.Minute, wantCode: codes.FailedPrecondition},
+	}
+
+	for _, tt := range tests {
+		time.Sleep(tt.wait)
+
+		lease, err := db.AcquireLease("job", tt.holder, tt.duration)
+		if code := status.Code(err); code != tt.wantCode {
+			t.Fatalf("%s: AcquireLease error = %v, want %v", tt.name, err, tt.wantCode)
+		}
+		if err != nil {
+			continue
+		}
+
+		if lease.Name != "job" || lease.Holder != tt.holder || time.Until(lease.ExpiresAt) > tt.duration {
+			t.Errorf("%s: lease = %+v, want job held by %s for %s", tt.name, lease, tt.holder, tt.duration)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"fmt"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type LocalOwnerDatabaseService struct {
+	collectionName string
+	store          *LocalStore
+}
+
+// Ensure LocalOwnerDatabaseService implements IOwnerDatabaseService.
+var _ interfaces.IOwnerDatabaseService = &LocalOwnerDatabaseService{}
+
+// NewLocalOwnerDatabaseService creates a new LocalOwnerDatabaseService.
+func NewLocalOwnerDatabaseService(store *LocalStore) *LocalOwnerDatabaseService {
+	return &LocalOwnerDatabaseService{
+		collectionName: "owners",
+		store:          store,
+	}
+}
+
+// GetOwnerByEmail gets an owner by email.
+func (db *LocalOwnerDatabaseService) GetOwnerByEmail(email string) (*types.Owner, error) {
+	owners, err := localList(db.store, db.collectionName, func(o *types.Owner) bool { return o.Email == email })
+	if err != nil {
+		return nil, fmt.Errorf("localstore: could not get owner: %w", err)
+	}
+
+	if len(owners) == 0 {
+		return nil, status.Errorf(codes.NotFound, "owner with email %s does not exist", email)
+	}
+
+	return owners[0], nil
+}
+
+// GetOwnerByID gets an owner by ID.
+func (db *LocalOwnerDatabaseService) GetOwnerByID(id string) (*types.Owner, error) {
+	var owner types.Owner
+	err := db.store.Get(db.collectionName, id, &owner)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, status.Errorf(codes.NotFound, "owner with ID %s does not exist", id)
+		}
+		return nil, fmt.Errorf("localstore: could not get owner: %w", err)
+	}
+
+	return &owner, nil
+}
+
+// AddOwner adds an owner.
+func (db *LocalOwnerDatabaseService) AddOwner(owner *types.Owner) error {
+	err := db.store.Create(db.collectionName, owner.ID, owner)
+	if err != nil {
+		if status.Code(err) == codes.AlreadyExists {
+			return status.Errorf(codes.AlreadyExists, "owner with ID %s already exists", owner.ID)
+		}
+		return fmt.Errorf("localstore: could not add owner: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateOwner updates an owner.
+func (db *LocalOwnerDatabaseService) UpdateOwner(owner *types.Owner) error {
+	err := db.store.Set(db.collectionName, owner.ID, owner)
+	if err != nil {
+		return fmt.Errorf("localstore: could not update owner: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteOwner deletes an owner, its groups, their contractors and timesheets.
+func (db *LocalOwnerDatabaseService) DeleteOwner(id string) error {
+	groups, err := localList(db.store, "groups", func(g *types.Group) bool { return g.OwnerID == id })
+	if err != nil {
+		return fmt.Errorf("localstore: could not get groups: %w", err)
+	}
+
+	for _, group := range groups {
+		err = deleteLocalGroupContractors(db.store, group.ID)
+		if err != nil {
+			return err
+		}
+
+		err = db.store.Delete("groups", group.ID)
+		if err != nil {
+			return fmt.Errorf("localstore: could not delete group: %w", err)
+		}
+	}
+
+	err = db.store.Delete(db.collectionName, id)
+	if err != nil {
+		return fmt.Errorf("localstore: could not delete owner: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,248 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+
+	"github.com/robfig/cron/v3"
+)
+
+// LocalSchedulerService fires the timesheet request jobs in-process, checking them once a minute.
+// The jobs are kept in the local store so they survive restarts.
+type LocalSchedulerService struct {
+	collectionName         string
+	reminderCollectionName string
+	store                  *LocalStore
+	appURL                 string
+	client                 *http.Client
+	callbackAuthService    interfaces.ICallbackAuthService
+
+	stop chan struct{}
+}
+
+// localSchedulerJob is a timesheet request job as kept in the local store.
+type localSchedulerJob struct {
+	GroupID  string
+	Schedule types.Schedule
+}
+
+// Ensure LocalSchedulerService implements ISchedulerService
+var _ interfaces.ISchedulerService = &LocalSchedulerService{}
+
+// NewLocalSchedulerService creates a new LocalSchedulerService and starts running its jobs against the app at appURL, signed by callbackAuthService.
+func NewLocalSchedulerService(store *LocalStore, appURL string, callbackAuthService interfaces.ICallbackAuthService) *LocalSchedulerService {
+	s := &LocalSchedulerService{
+		collectionName:         "scheduler_jobs",
+		reminderCollectionName: "scheduler_reminder_jobs",
+		store:                  store,
+		appURL:                 appURL,
+		client:                 &http.Client{Timeout: time.Minute},
+		callbackAuthService:    callbackAuthService,
+
+		stop: make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s
+}
+
+// Close stops running the jobs.
+func (s *LocalSchedulerService) Close() {
+	close(s.stop)
+}
+
+// CreateTimesheetRequestJob creates a new job for requesting timesheets.
+func (s *LocalSchedulerService) CreateTimesheetRequestJob(groupID string, schedule *types.Schedule) error {
+	if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+		return fmt.Errorf("LoadLocation: %v", err)
+	}
+
+	err := s.store.Create(s.collectionName, groupID, &localSchedulerJob{GroupID: groupID, Schedule: *schedule})
+	if err != nil {
+		return fmt.Errorf("CreateJob: %v", err)
+	}
+
+	return nil
+}
+
+// EditTimesheetRequestJob updates a job for requesting timesheets.
+func (s *LocalSchedulerService) EditTimesheetRequestJob(groupID string, schedule *types.Schedule) error {
+	var job localSchedulerJob
+	if err := s.store.Get(s.collectionName, groupID, &job); err != nil {
+		return fmt.Errorf("GetJob: %v", err)
+	}
+
+	if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+		return fmt.Errorf("LoadLocation: %v", err)
+	}
+
+	job.Schedule = *schedule
+
+	if err := s.store.Set(s.collectionName, groupID, &job); err != nil {
+		return fmt.Errorf("UpdateJob: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteTimesheetRequestJob deletes a job for requesting timesheets.
+func (s *LocalSchedulerService) DeleteTimesheetRequestJob(groupID string) error {
+	var job localSchedulerJob
+	if err := s.store.Get(s.collectionName, groupID, &job); err != nil {
+		return fmt.Errorf("DeleteJob: %v", err)
+	}
+
+	if err := s.store.Delete(s.collectionName, groupID); err != nil {
+		return fmt.Errorf("DeleteJob: %v", err)
+	}
+
+	return nil
+}
+
+// CreateTimesheetReminderJob creates a new job that sends the due reminders daily at the schedule's time.
+func (s *LocalSchedulerService) CreateTimesheetReminderJob(groupID string, schedule *types.Schedule) error {
+	if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+		return fmt.Errorf("LoadLocation: %v", err)
+	}
+
+	err := s.store.Create(s.reminderCollectionName, groupID, &localSchedulerJob{GroupID: groupID, Schedule: *schedule})
+	if err != nil {
+		return fmt.Errorf("CreateJob: %v", err)
+	}
+
+	return nil
+}
+
+// EditTimesheetReminderJob updates a job for sending reminders, creating it for groups made before reminders existed.
+func (s *LocalSchedulerService) EditTimesheetReminderJob(groupID string, schedule *types.Schedule) error {
+	if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+		return fmt.Errorf("LoadLocation: %v", err)
+	}
+
+	if err := s.store.Set(s.reminderCollectionName, groupID, &localSchedulerJob{GroupID: groupID, Schedule: *schedule}); err != nil {
+		return fmt.Errorf("UpdateJob: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteTimesheetReminderJob deletes a job for sending reminders, if there is one.
+func (s *LocalSchedulerService) DeleteTimesheetReminderJob(groupID string) error {
+	if err := s.store.Delete(s.reminderCollectionName, groupID); err != nil {
+		return fmt.Errorf("DeleteJob: %v", err)
+	}
+
+	return nil
+}
+
+// run checks the jobs at the start of every minute until Close is called.
+func (s *LocalSchedulerService) run() {
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+
+		select {
+		case <-s.stop:
+			return
+		case <-time.After(next.Sub(now)):
+			s.runDueJobs(next)
+		}
+	}
+}
+
+// runDueJobs posts the timesheet requests and reminders of every job whose cron expression fires at the given minute, like Cloud Scheduler does.
+func (s *LocalSchedulerService) runDueJobs(t time.Time) {
+	jobs, err := localList[localSchedulerJob](s.store, s.collectionName, nil)
+	if err != nil {
+		log.Printf("local scheduler: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		due, err := isCronDue(&job.Schedule, convertScheduleToCron, t)
+		if err != nil {
+			log.Printf("local scheduler: job for group %s: %v", job.GroupID, err)
+			continue
+		}
+		if !due {
+			continue
+		}
+
+		go s.dispatch(s.appURL + "/timesheets/request?groupID=" + job.GroupID)
+	}
+
+	reminderJobs, err := localList[localSchedulerJob](s.store, s.reminderCollectionName, nil)
+	if err != nil {
+		log.Printf("local scheduler: %v", err)
+		return
+	}
+
+	for _, job := range reminderJobs {
+		due, err := isCronDue(&job.Schedule, convertScheduleToDailyCron, t)
+		if err != nil {
+			log.Printf("local scheduler: reminder job for group %s: %v", job.GroupID, err)
+			continue
+		}
+		if !due {
+			continue
+		}
+
+		go s.dispatch(s.appURL + "/timesheets/remind?groupID=" + job.GroupID)
+	}
+}
+
+// isCronDue reports whether the cron expression of a schedule fires at the given minute in the schedule's timezone.
+func isCronDue(schedule *types.Schedule, convert func(s *types.Schedule) (string, error), t time.Time) (bool, error) {
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return false, fmt.Errorf("LoadLocation: %v", err)
+	}
+
+	expression, err := convert(schedule)
+	if err != nil {
+		return false, err
+	}
+
+	cronSchedule, err := cron.ParseStandard(expression)
+	if err != nil {
+		return false, fmt.Errorf("ParseStandard: %v", err)
+	}
+
+	t = t.In(loc)
+	return cronSchedule.Next(t.Add(-time.Second)).Equal(t), nil
+}
+
+// dispatch posts the signed timesheet request, the way Cloud Scheduler posts it with an OIDC token. Failed requests are logged and not retried.
+func (s *LocalSchedulerService) dispatch(url string) {
+	req, err := http.NewRequest(http.MethodPost, url, nil)
+	if err != nil {
+		log.Printf("local scheduler %s: %v", url, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	err = s.callbackAuthService.SignCallback(req)
+	if err != nil {
+		log.Printf("local scheduler %s: %v", url, err)
+		return
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		log.Printf("local scheduler %s: %v", url, err)
+		return
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		log.Printf("local scheduler %s: unexpected status %s", url, resp.Status)
+	}
+}
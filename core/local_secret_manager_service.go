@@ -0,0 +1,75 @@
+package core
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"job_sender/interfaces"
+)
+
+// LocalSecretManagerService reads secrets from an env file, falling back to the process environment and the defaults.
+type LocalSecretManagerService struct {
+	secrets  map[string]string
+	defaults map[string]string
+}
+
+// Ensure LocalSecretManagerService conforms to the ISecretManagerService interface.
+var _ interfaces.ISecretManagerService = &LocalSecretManagerService{}
+
+// NewLocalSecretManagerService creates a new LocalSecretManagerService.
+// The env file holds one NAME=value pair per line, a missing file is treated as an empty one.
+func NewLocalSecretManagerService(envFilePath string, defaults map[string]string) (*LocalSecretManagerService, error) {
+	s := &LocalSecretManagerService{
+		secrets:  make(map[string]string),
+		defaults: defaults,
+	}
+
+	f, err := os.Open(envFilePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("could not open secrets file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected NAME=value", envFilePath, lineNumber)
+		}
+
+		s.secrets[strings.TrimSpace(name)] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read secrets file: %w", err)
+	}
+
+	return s, nil
+}
+
+// GetSecret retrieves a secret by name, the project number is ignored.
+func (s *LocalSecretManagerService) GetSecret(projectNumber string, kmsSecretName string) ([]byte, error) {
+	if value, ok := s.secrets[kmsSecretName]; ok {
+		return []byte(value), nil
+	}
+
+	if value := os.Getenv(kmsSecretName); value != "" {
+		return []byte(value), nil
+	}
+
+	if value, ok := s.defaults[kmsSecretName]; ok {
+		return []byte(value), nil
+	}
+
+	return nil, fmt.Errorf("secret %s is not set", kmsSecretName)
+}
@@ -0,0 +1,225 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"job_sender/interfaces"
+)
+
+// localContentTypeKey is the metadata key the content type of an object is kept under.
+const localContentTypeKey = "Content-Type"
+
+// LocalStorageService keeps the bucket objects as files on disk, next to their metadata.
+type LocalStorageService struct {
+	objectsDir  string
+	metadataDir string
+	baseURL     string
+	signingKey  []byte // Signs the download URLs, a new one every run
+}
+
+// Ensure LocalStorageService implements the IStorageService interface.
+var _ interfaces.IStorageService = &LocalStorageService{}
+
+// NewLocalStorageService creates a new LocalStorageService rooted at dir.
+// The returned URLs point at baseURL, where Handler is expected to be mounted.
+func NewLocalStorageService(dir string, baseURL string) (*LocalStorageService, error) {
+	s := &LocalStorageService{
+		objectsDir:  filepath.Join(dir, "objects"),
+		metadataDir: filepath.Join(dir, "metadata"),
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		signingKey:  make([]byte, 32),
+	}
+
+	if _, err := rand.Read(s.signingKey); err != nil {
+		return nil, fmt.Errorf("could not generate signing key: %v", err)
+	}
+
+	for _, d := range []string{s.objectsDir, s.metadataDir} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			return nil, fmt.Errorf("could not create bucket directory %s: %v", d, err)
+		}
+	}
+
+	return s, nil
+}
+
+// UploadFile uploads a private file to the local bucket and returns its URL, the content type is kept with the metadata.
+func (s *LocalStorageService) UploadFile(objectName string, data []byte, contentType string, metadata map[string]string) (string, error) {
+	objectPath, err := s.objectPath(s.objectsDir, objectName)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(objectPath), 0o755); err != nil {
+		return "", fmt.Errorf("could not create object directory: %v", err)
+	}
+
+	if err := os.WriteFile(objectPath, data, 0o644); err != nil {
+		return "", fmt.Errorf("could not write data to object: %v", err)
+	}
+
+	// The content type is kept with the metadata, the way Cloud Storage keeps it with the object
+	metadata = maps.Clone(metadata)
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	metadata[localContentTypeKey] = contentType
+
+	metadataPath, err := s.objectPath(s.metadataDir, objectName+".json")
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal(metadata)
+	if err != nil {
+		return "", fmt.Errorf("could not encode metadata: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(metadataPath), 0o755); err != nil {
+		return "", fmt.Errorf("could not create metadata directory: %v", err)
+	}
+
+	if err := os.WriteFile(metadataPath, b, 0o644); err != nil {
+		return "", fmt.Errorf("could not write metadata: %v", err)
+	}
+
+	return s.baseURL + "/" + (&url.URL{Path: objectName}).EscapedPath(), nil
+}
+
+// SignedURL returns a URL that downloads the file at fileURL, as returned by UploadFile, until it expires.
+func (s *LocalStorageService) SignedURL(fileURL string, expiry time.Duration) (string, error) {
+	objectPath, ok := strings.CutPrefix(fileURL, s.baseURL)
+	if !ok || objectPath == "" {
+		return "", fmt.Errorf("%s is not a file of the local bucket", fileURL)
+	}
+
+	objectName, err := url.PathUnescape(strings.TrimPrefix(objectPath, "/"))
+	if err != nil {
+		return "", fmt.Errorf("invalid file URL %s: %v", fileURL, err)
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(expiry).Unix(), 10)
+	query := url.Values{
+		"expires":   {expires},
+		"signature": {s.sign(objectName, expires)},
+	}
+
+	return fileURL + "?" + query.Encode(), nil
+}
+
+// MakeFilesPrivate does nothing, the local files are served only through signed URLs.
+func (s *LocalStorageService) MakeFilesPrivate(prefixName string) (int, error) {
+	return 0, nil
+}
+
+// DeleteFiles deletes files with the given prefix name.
+func (s *LocalStorageService) DeleteFiles(prefixName string) error {
+	for _, root := range []string{s.objectsDir, s.metadataDir} {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+
+			if !strings.HasPrefix(filepath.ToSlash(rel), prefixName) {
+				return nil
+			}
+
+			return os.Remove(path)
+		})
+		if err != nil {
+			return fmt.Errorf("could not delete object: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Handler serves the objects of the bucket as downloads with their content type, the way the signed Cloud Storage URLs do.
+// A request without a valid signature or after the URL expired is forbidden.
+func (s *LocalStorageService) Handler() http.Handler {
+	files := http.FileServer(http.Dir(s.objectsDir))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.checkSignature(r) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		if contentType := s.contentType(r.URL.Path); contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		w.Header().Set("Content-Disposition", "attachment")
+
+		files.ServeHTTP(w, r)
+	})
+}
+
+// checkSignature reports whether the request has the signature of its object and has not expired.
+func (s *LocalStorageService) checkSignature(r *http.Request) bool {
+	query := r.URL.Query()
+	expires := query.Get("expires")
+
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return false
+	}
+
+	signature := s.sign(strings.TrimPrefix(r.URL.Path, "/"), expires)
+	return hmac.Equal([]byte(query.Get("signature")), []byte(signature))
+}
+
+// sign returns the hex HMAC-SHA256 of an object name and the time its URL expires.
+func (s *LocalStorageService) sign(objectName string, expires string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(objectName + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// contentType returns the content type an object was uploaded with, empty if it is not known.
+func (s *LocalStorageService) contentType(objectName string) string {
+	metadataPath, err := s.objectPath(s.metadataDir, strings.TrimPrefix(objectName, "/")+".json")
+	if err != nil {
+		return ""
+	}
+
+	b, err := os.ReadFile(metadataPath)
+	if err != nil {
+		return ""
+	}
+
+	var metadata map[string]string
+	if json.Unmarshal(b, &metadata) != nil {
+		return ""
+	}
+
+	return metadata[localContentTypeKey]
+}
+
+// objectPath maps an object name to a path inside dir, rejecting names that would escape it.
+func (s *LocalStorageService) objectPath(dir string, objectName string) (string, error) {
+	path := filepath.Join(dir, filepath.FromSlash(objectName))
+	if !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid object name %q", objectName)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,272 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LocalStore is a document store kept in a single JSON file, it stands in for Cloud Firestore in the local backend.
+// Documents are grouped in collections and keyed by ID, every write is flushed to disk.
+type LocalStore struct {
+	mu          sync.Mutex
+	path        string
+	collections map[string]map[string]json.RawMessage
+}
+
+// NewLocalStore opens the store at the given path, creating an empty one if the file does not exist.
+func NewLocalStore(path string) (*LocalStore, error) {
+	s := &LocalStore{
+		path:        path,
+		collections: make(map[string]map[string]json.RawMessage),
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("localstore: could not read %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(b, &s.collections); err != nil {
+		return nil, fmt.Errorf("localstore: could not parse %s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// NewID returns a random document ID in the same shape as the Firestore auto IDs.
+func (s *LocalStore) NewID() string {
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("localstore: could not generate ID: %v", err))
+	}
+
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+
+	return string(b)
+}
+
+// Get decodes the document with the given ID into v, it returns a NotFound status if there is no such document.
+func (s *LocalStore) Get(collection string, id string, v interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, ok := s.collections[collection][id]
+	if !ok {
+		return status.Errorf(codes.NotFound, "%s/%s does not exist", collection, id)
+	}
+
+	return json.Unmarshal(raw, v)
+}
+
+// Create stores a new document, it returns an AlreadyExists status if the ID is taken.
+func (s *LocalStore) Create(collection string, id string, v interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.collections[collection][id]; ok {
+		return status.Errorf(codes.AlreadyExists, "%s/%s already exists", collection, id)
+	}
+
+	return s.set(collection, id, v)
+}
+
+// Set creates or overwrites a document.
+func (s *LocalStore) Set(collection string, id string, v interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.set(collection, id, v)
+}
+
+// Delete removes a document, deleting a missing document is not an error.
+func (s *LocalStore) Delete(collection string, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.collections[collection][id]; !ok {
+		return nil
+	}
+
+	delete(s.collections[collection], id)
+	return s.flush()
+}
+
+// RunTransaction runs fn with the store locked and applies the writes it staged together, only if it succeeds.
+// No other write interleaves with the transaction, and a failed one writes nothing.
+func (s *LocalStore) RunTransaction(fn func(t *LocalTransaction) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := &LocalTransaction{s: s}
+	if err := fn(t); err != nil {
+		return err
+	}
+
+	for _, w := range t.writes {
+		if w.raw == nil {
+			delete(s.collections[w.collection], w.id)
+			continue
+		}
+
+		if s.collections[w.collection] == nil {
+			s.collections[w.collection] = make(map[string]json.RawMessage)
+		}
+		s.collections[w.collection][w.id] = w.raw
+	}
+
+	return s.flush()
+}
+
+// LocalTransaction reads the store as it was before the transaction and stages writes, which RunTransaction applies together.
+type LocalTransaction struct {
+	s      *LocalStore
+	writes []localWrite
+}
+
+// localWrite is a write staged by a transaction, a nil raw deletes the document.
+type localWrite struct {
+	collection string
+	id         string
+	raw        json.RawMessage
+}
+
+// Get decodes the document with the given ID into v, it returns a NotFound status if there is no such document.
+func (t *LocalTransaction) Get(collection string, id string, v interface{}) error {
+	raw, ok := t.s.collections[collection][id]
+	if !ok {
+		return status.Errorf(codes.NotFound, "%s/%s does not exist", collection, id)
+	}
+
+	return json.Unmarshal(raw, v)
+}
+
+// Create stages a new document, it returns an AlreadyExists status if the ID is taken.
+func (t *LocalTransaction) Create(collection string, id string, v interface{}) error {
+	if _, ok := t.s.collections[collection][id]; ok {
+		return status.Errorf(codes.AlreadyExists, "%s/%s already exists", collection, id)
+	}
+
+	return t.Set(collection, id, v)
+}
+
+// Set stages creating or overwriting a document.
+func (t *LocalTransaction) Set(collection string, id string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("localstore: could not encode %s/%s: %w", collection, id, err)
+	}
+
+	t.writes = append(t.writes, localWrite{collection: collection, id: id, raw: raw})
+	return nil
+}
+
+// Delete stages removing a document.
+func (t *LocalTransaction) Delete(collection string, id string) {
+	t.writes = append(t.writes, localWrite{collection: collection, id: id})
+}
+
+// documents returns the raw documents of a collection ordered by ID.
+func (s *LocalStore) documents(collection string) []json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.documentsLocked(collection)
+}
+
+// documentsLocked returns the raw documents of a collection ordered by ID. The caller must hold s.mu.
+func (s *LocalStore) documentsLocked(collection string) []json.RawMessage {
+	ids := make([]string, 0, len(s.collections[collection]))
+	for id := range s.collections[collection] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	docs := make([]json.RawMessage, 0, len(ids))
+	for _, id := range ids {
+		docs = append(docs, s.collections[collection][id])
+	}
+
+	return docs
+}
+
+// set stores a document. The caller must hold s.mu.
+func (s *LocalStore) set(collection string, id string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("localstore: could not encode %s/%s: %w", collection, id, err)
+	}
+
+	if s.collections[collection] == nil {
+		s.collections[collection] = make(map[string]json.RawMessage)
+	}
+	s.collections[collection][id] = raw
+
+	return s.flush()
+}
+
+// flush writes the whole store to disk, going through a temporary file so a crash never leaves half a file behind.
+// The caller must hold s.mu.
+func (s *LocalStore) flush() error {
+	b, err := json.MarshalIndent(s.collections, "", "  ")
+	if err != nil {
+		return fmt.Errorf("localstore: could not encode store: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("localstore: could not create directory: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return fmt.Errorf("localstore: could not write store: %w", err)
+	}
+
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("localstore: could not replace store: %w", err)
+	}
+
+	return nil
+}
+
+// localList decodes every document of a collection and returns the ones accepted by match, ordered by ID.
+// A nil match accepts every document.
+func localList[T any](s *LocalStore, collection string, match func(*T) bool) ([]*T, error) {
+	return decodeLocalDocuments(s.documents(collection), collection, match)
+}
+
+// localTransactionList is localList inside a transaction, it lists the documents as they were before the transaction.
+func localTransactionList[T any](t *LocalTransaction, collection string, match func(*T) bool) ([]*T, error) {
+	return decodeLocalDocuments(t.s.documentsLocked(collection), collection, match)
+}
+
+// decodeLocalDocuments decodes raw documents of a collection and returns the ones accepted by match.
+func decodeLocalDocuments[T any](docs []json.RawMessage, collection string, match func(*T) bool) ([]*T, error) {
+	var items []*T
+	for _, raw := range docs {
+		item := new(T)
+		if err := json.Unmarshal(raw, item); err != nil {
+			return nil, fmt.Errorf("localstore: could not decode %s document: %w", collection, err)
+		}
+
+		if match == nil || match(item) {
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"fmt"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+)
+
+// LocalTimesheetAuditLogDatabaseService is a service for recording the status changes of timesheets in the local store.
+type LocalTimesheetAuditLogDatabaseService struct {
+	collectionName string
+	store          *LocalStore
+}
+
+// Ensure LocalTimesheetAuditLogDatabaseService implements ITimesheetAuditLogDatabaseService.
+var _ interfaces.ITimesheetAuditLogDatabaseService = &LocalTimesheetAuditLogDatabaseService{}
+
+// NewLocalTimesheetAuditLogDatabaseService creates a new LocalTimesheetAuditLogDatabaseService.
+func NewLocalTimesheetAuditLogDatabaseService(store *LocalStore) *LocalTimesheetAuditLogDatabaseService {
+	return &LocalTimesheetAuditLogDatabaseService{
+		collectionName: "timesheet_audit_log",
+		store:          store,
+	}
+}
+
+// ListAuditEntries lists the audit entries of a timesheet from the oldest.
+func (db *LocalTimesheetAuditLogDatabaseService) ListAuditEntries(timesheetID string) ([]*types.TimesheetAuditEntry, error) {
+	entries, err := localList(db.store, db.collectionName, func(e *types.TimesheetAuditEntry) bool { return e.TimesheetID == timesheetID })
+	if err != nil {
+		return nil, fmt.Errorf("could not list audit entries: %w", err)
+	}
+
+	sortAuditEntries(entries)
+
+	return entries, nil
+}
+
+// AddAuditEntry adds an audit entry.
+func (db *LocalTimesheetAuditLogDatabaseService) AddAuditEntry(entry *types.TimesheetAuditEntry) error {
+	entry.ID = db.store.NewID()
+
+	err := db.store.Create(db.collectionName, entry.ID, entry)
+	if err != nil {
+		return fmt.Errorf("could not add audit entry: %w", err)
+	}
+
+	return nil
+}
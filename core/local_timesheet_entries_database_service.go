@@ -0,0 +1,73 @@
+package core
+
+import (
+	"fmt"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+)
+
+// LocalTimesheetEntriesDatabaseService is a service for managing timesheet entries in the local store.
+type LocalTimesheetEntriesDatabaseService struct {
+	collectionName string
+	store          *LocalStore
+}
+
+// Ensure LocalTimesheetEntriesDatabaseService implements ITimesheetEntriesDatabaseService.
+var _ interfaces.ITimesheetEntriesDatabaseService = &LocalTimesheetEntriesDatabaseService{}
+
+// NewLocalTimesheetEntriesDatabaseService creates a new LocalTimesheetEntriesDatabaseService.
+func NewLocalTimesheetEntriesDatabaseService(store *LocalStore) *LocalTimesheetEntriesDatabaseService {
+	return &LocalTimesheetEntriesDatabaseService{
+		collectionName: "timesheet_entries",
+		store:          store,
+	}
+}
+
+// ListTimesheetEntries lists all entries of a timesheet ordered by date.
+func (db *LocalTimesheetEntriesDatabaseService) ListTimesheetEntries(timesheetID string) ([]*types.TimesheetEntry, error) {
+	entries, err := localList(db.store, db.collectionName, func(e *types.TimesheetEntry) bool { return e.TimesheetID == timesheetID })
+	if err != nil {
+		return nil, fmt.Errorf("could not list timesheet entries: %w", err)
+	}
+
+	sortTimesheetEntries(entries)
+
+	return entries, nil
+}
+
+// AddTimesheetEntries adds the entries of a timesheet.
+func (db *LocalTimesheetEntriesDatabaseService) AddTimesheetEntries(entries []*types.TimesheetEntry) error {
+	for _, entry := range entries {
+		entry.ID = db.store.NewID()
+
+		err := db.store.Create(db.collectionName, entry.ID, entry)
+		if err != nil {
+			return fmt.Errorf("could not add timesheet entry: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteTimesheetEntries deletes all entries of a timesheet.
+func (db *LocalTimesheetEntriesDatabaseService) DeleteTimesheetEntries(timesheetID string) error {
+	return deleteLocalTimesheetEntries(db.store, timesheetID)
+}
+
+// deleteLocalTimesheetEntries deletes all entries of a timesheet from the store.
+func deleteLocalTimesheetEntries(store *LocalStore, timesheetID string) error {
+	entries, err := localList(store, "timesheet_entries", func(e *types.TimesheetEntry) bool { return e.TimesheetID == timesheetID })
+	if err != nil {
+		return fmt.Errorf("could not list timesheet entries: %w", err)
+	}
+
+	for _, entry := range entries {
+		err = store.Delete("timesheet_entries", entry.ID)
+		if err != nil {
+			return fmt.Errorf("could not delete timesheet entry: %w", err)
+		}
+	}
+
+	return nil
+}
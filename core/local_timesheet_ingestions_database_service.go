@@ -0,0 +1,210 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LocalTimesheetIngestionsDatabaseService is a service for saving the submitted timesheets in the local store, each submission once.
+type LocalTimesheetIngestionsDatabaseService struct {
+	collectionName string
+	store          *LocalStore
+}
+
+// Ensure LocalTimesheetIngestionsDatabaseService implements ITimesheetIngestionsDatabaseService.
+var _ interfaces.ITimesheetIngestionsDatabaseService = &LocalTimesheetIngestionsDatabaseService{}
+
+// NewLocalTimesheetIngestionsDatabaseService creates a new LocalTimesheetIngestionsDatabaseService.
+func NewLocalTimesheetIngestionsDatabaseService(store *LocalStore) *LocalTimesheetIngestionsDatabaseService {
+	return &LocalTimesheetIngestionsDatabaseService{
+		collectionName: "timesheet_ingestions",
+		store:          store,
+	}
+}
+
+// GetTimesheetIngestion gets the ingestion of a submission by the ID of its key, it returns a NotFound status if the submission was not saved.
+func (db *LocalTimesheetIngestionsDatabaseService) GetTimesheetIngestion(id string) (*types.TimesheetIngestion, error) {
+	var ingestion types.TimesheetIngestion
+	err := db.store.Get(db.collectionName, id, &ingestion)
+	if status.Code(err) == codes.NotFound {
+		return nil, status.Errorf(codes.NotFound, "timesheet ingestion %s does not exist", id)
+	} else if err != nil {
+		return nil, fmt.Errorf("localstore: could not get timesheet ingestion: %w", err)
+	}
+
+	return &ingestion, nil
+}
+
+// CommitTimesheetIngestion saves all a submission changes in one transaction: the timesheet, its new versions, its entries, the audit entry, the collected request and the ingestion.
+func (db *LocalTimesheetIngestionsDatabaseService) CommitTimesheetIngestion(ingestion *types.TimesheetIngestion, timesheet *types.Timesheet, versions []*types.TimesheetVersion, entries []*types.TimesheetEntry, auditEntry *types.TimesheetAuditEntry, timesheetRequestID string, collectedAt time.Time) error {
+	added := timesheet.ID == ""
+
+	err := db.store.RunTransaction(func(t *LocalTransaction) error {
+		err := t.Get(db.collectionName, ingestion.ID, &types.TimesheetIngestion{})
+		if err == nil {
+			return status.Errorf(codes.AlreadyExists, "submission %s was already saved", ingestion.ID)
+		} else if status.Code(err) != codes.NotFound {
+			return fmt.Errorf("could not get timesheet ingestion: %w", err)
+		}
+
+		var request types.TimesheetRequest
+		err = t.Get("timesheet_requests", timesheetRequestID, &request)
+		if err != nil {
+			return fmt.Errorf("could not get timesheet request: %w", err)
+		}
+
+		updated := timesheet
+		var oldEntries []*types.TimesheetEntry
+		if added {
+			existing, err := localTransactionList(t, "timesheets", func(s *types.Timesheet) bool {
+				return s.ContractorID == timesheet.ContractorID && s.RequestID == timesheet.RequestID
+			})
+			if err != nil {
+				return fmt.Errorf("could not get timesheets: %w", err)
+			}
+
+			if len(existing) > 0 {
+				return status.Errorf(codes.Aborted, "a timesheet of contractor %s for request %s was added meanwhile", timesheet.ContractorID, timesheet.RequestID)
+			}
+
+			timesheet.ID = db.store.NewID()
+		} else {
+			var stored types.Timesheet
+			err = t.Get("timesheets", timesheet.ID, &stored)
+			if err != nil {
+				return fmt.Errorf("could not get timesheet: %w", err)
+			}
+
+			// The versions are numbered from the one that was read
+			if len(versions) > 0 && stored.Version != versions[0].Version-1 {
+				return status.Errorf(codes.Aborted, "timesheet %s got another version meanwhile", timesheet.ID)
+			}
+
+			err = nextTimesheetVersion(&stored, timesheet, auditEntry)
+			if err != nil {
+				return err
+			}
+
+			oldEntries, err = localTransactionList(t, "timesheet_entries", func(e *types.TimesheetEntry) bool { return e.TimesheetID == timesheet.ID })
+			if err != nil {
+				return fmt.Errorf("could not get timesheet entries: %w", err)
+			}
+
+			// Only the file and the status change, the rest is kept as stored
+			stored.StorageURL = timesheet.StorageURL
+			stored.Version = timesheet.Version
+			stored.ContentHash = timesheet.ContentHash
+			stored.TotalHours = timesheet.TotalHours
+			stored.Status = timesheet.Status
+			stored.RejectionReason = timesheet.RejectionReason
+			updated = &stored
+		}
+
+		err = t.Set("timesheets", timesheet.ID, updated)
+		if err != nil {
+			return err
+		}
+
+		for _, version := range versions {
+			version.ID = db.store.NewID()
+			version.TimesheetID = timesheet.ID
+
+			err = t.Create("timesheet_versions", version.ID, version)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, entry := range oldEntries {
+			t.Delete("timesheet_entries", entry.ID)
+		}
+
+		for _, entry := range entries {
+			entry.ID = db.store.NewID()
+			entry.TimesheetID = timesheet.ID
+
+			err = t.Create("timesheet_entries", entry.ID, entry)
+			if err != nil {
+				return err
+			}
+		}
+
+		auditEntry.ID = db.store.NewID()
+		auditEntry.TimesheetID = timesheet.ID
+
+		err = t.Create("timesheet_audit_log", auditEntry.ID, auditEntry)
+		if err != nil {
+			return err
+		}
+
+		request.Status = constants.Collected
+		request.CollectedAt = collectedAt
+
+		err = t.Set("timesheet_requests", request.ID, &request)
+		if err != nil {
+			return err
+		}
+
+		ingestion.TimesheetID = timesheet.ID
+
+		return t.Create(db.collectionName, ingestion.ID, ingestion)
+	})
+	if err != nil && added {
+		timesheet.ID = ""
+	}
+
+	switch status.Code(err) {
+	case codes.OK:
+		return nil
+	case codes.AlreadyExists, codes.Aborted, codes.FailedPrecondition:
+		return err
+	default:
+		return fmt.Errorf("localstore: could not commit timesheet ingestion: %w", err)
+	}
+}
+
+// CommitDuplicateTimesheetIngestion saves a submission of the current file of a timesheet, only the ingestion and the audit entry noting it, in one transaction.
+func (db *LocalTimesheetIngestionsDatabaseService) CommitDuplicateTimesheetIngestion(ingestion *types.TimesheetIngestion, auditEntry *types.TimesheetAuditEntry) error {
+	err := db.store.RunTransaction(func(t *LocalTransaction) error {
+		err := t.Create(db.collectionName, ingestion.ID, ingestion)
+		if err != nil {
+			return err
+		}
+
+		auditEntry.ID = db.store.NewID()
+
+		return t.Create("timesheet_audit_log", auditEntry.ID, auditEntry)
+	})
+	switch status.Code(err) {
+	case codes.OK:
+		return nil
+	case codes.AlreadyExists:
+		return status.Errorf(codes.AlreadyExists, "submission %s was already saved", ingestion.ID)
+	default:
+		return fmt.Errorf("localstore: could not commit duplicate timesheet ingestion: %w", err)
+	}
+}
+
+// deleteLocalContractorTimesheetIngestions deletes all timesheet ingestions of a contractor from the store.
+func deleteLocalContractorTimesheetIngestions(store *LocalStore, contractorID string) error {
+	ingestions, err := localList(store, "timesheet_ingestions", func(i *types.TimesheetIngestion) bool { return i.ContractorID == contractorID })
+	if err != nil {
+		return fmt.Errorf("could not list timesheet ingestions: %w", err)
+	}
+
+	for _, ingestion := range ingestions {
+		err = store.Delete("timesheet_ingestions", ingestion.ID)
+		if err != nil {
+			return fmt.Errorf("could not delete timesheet ingestion: %w", err)
+		}
+	}
+
+	return nil
+}
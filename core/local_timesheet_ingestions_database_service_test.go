@@ -0,0 +1,89 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCommitTimesheetIngestionAfterReview(t *testing.T) {
+	tests := []struct {
+		name        string
+		reviewed    constants.TimesheetStatuses
+		wantErr     codes.Code
+		wantStatus  constants.TimesheetStatuses
+		wantVersion int
+	}{
+		{name: "rejected meanwhile", reviewed: constants.Rejected, wantStatus: constants.Resubmitted, wantVersion: 2},
+		{name: "approved meanwhile", reviewed: constants.Approved, wantErr: codes.FailedPrecondition, wantStatus: constants.Approved, wantVersion: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewLocalStore(filepath.Join(t.TempDir(), "store.json"))
+			if err != nil {
+				t.Fatalf("NewLocalStore: %v", err)
+			}
+			timesheetsDB := NewLocalTimesheetsDatabaseService(store)
+			requestsDB := NewLocalTimesheetRequestsDatabaseService(store)
+			auditLogDB := NewLocalTimesheetAuditLogDatabaseService(store)
+			db := NewLocalTimesheetIngestionsDatabaseService(store)
+
+			request := &types.TimesheetRequest{ContractorID: "c1", RequestID: testRequestID, Status: constants.Pending}
+			err = requestsDB.AddTimesheetRequest(request)
+			if err != nil {
+				t.Fatalf("AddTimesheetRequest: %v", err)
+			}
+
+			stored := &types.Timesheet{ContractorID: "c1", GroupID: "g1", RequestID: testRequestID, StorageURL: "v1.xlsx", Version: 1, ContentHash: "h1", Status: constants.UnderReview}
+			err = timesheetsDB.AddTimesheet(stored)
+			if err != nil {
+				t.Fatalf("AddTimesheet: %v", err)
+			}
+
+			// Version 2 was read while the timesheet was under review, then the owner reviewed it
+			timesheet := *stored
+			reviewed := *stored
+			reviewed.Status = tt.reviewed
+			reviewed.RejectionReason = "Missing a day"
+			err = timesheetsDB.UpdateTimesheetStatus(&reviewed, constants.UnderReview, 1)
+			if err != nil {
+				t.Fatalf("UpdateTimesheetStatus: %v", err)
+			}
+
+			timesheet.StorageURL = "v2.xlsx"
+			timesheet.Version = 2
+			timesheet.ContentHash = "h2"
+			versions := []*types.TimesheetVersion{{ContractorID: "c1", GroupID: "g1", RequestID: testRequestID, Version: 2, StorageURL: "v2.xlsx", ContentHash: "h2"}}
+			auditEntry := &types.TimesheetAuditEntry{GroupID: "g1", Actor: "c1@example.com", Status: constants.Received, Reason: "Version 2"}
+			ingestion := &types.TimesheetIngestion{ID: "ingestion", ContractorID: "c1", GroupID: "g1", RequestID: testRequestID, Version: 2}
+
+			err = db.CommitTimesheetIngestion(ingestion, &timesheet, versions, nil, auditEntry, request.ID, time.Now())
+			if status.Code(err) != tt.wantErr {
+				t.Fatalf("CommitTimesheetIngestion error = %v, want %v", err, tt.wantErr)
+			}
+
+			got, err := timesheetsDB.GetTimesheetByID(stored.ID)
+			if err != nil {
+				t.Fatalf("GetTimesheetByID: %v", err)
+			}
+			if got.Status != tt.wantStatus || got.Version != tt.wantVersion {
+				t.Errorf("timesheet = %+v, want %v at version %d", got, tt.wantStatus, tt.wantVersion)
+			}
+
+			auditLog, err := auditLogDB.ListAuditEntries(stored.ID)
+			if err != nil {
+				t.Fatalf("ListAuditEntries: %v", err)
+			}
+			if tt.wantErr == codes.OK && (len(auditLog) != 1 || auditLog[0].Status != tt.wantStatus) {
+				t.Errorf("audit log = %+v, want one %v entry", auditLog, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package core
+
+import (
+	"fmt"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LocalTimesheetRequestsDatabaseService is a service for managing timesheet requests in the local store.
+type LocalTimesheetRequestsDatabaseService struct {
+	collectionName string
+	store          *LocalStore
+}
+
+// Ensure LocalTimesheetRequestsDatabaseService implements ITimesheetRequestsDatabaseService.
+var _ interfaces.ITimesheetRequestsDatabaseService = &LocalTimesheetRequestsDatabaseService{}
+
+// NewLocalTimesheetRequestsDatabaseService creates a new LocalTimesheetRequestsDatabaseService.
+func NewLocalTimesheetRequestsDatabaseService(store *LocalStore) *LocalTimesheetRequestsDatabaseService {
+	return &LocalTimesheetRequestsDatabaseService{
+		collectionName: "timesheet_requests",
+		store:          store,
+	}
+}
+
+// ListTimesheetRequests lists the timesheet requests of a group.
+func (db *LocalTimesheetRequestsDatabaseService) ListTimesheetRequests(groupID string) ([]*types.TimesheetRequest, error) {
+	requests, err := localList(db.store, db.collectionName, func(r *types.TimesheetRequest) bool { return r.GroupID == groupID })
+	if err != nil {
+		return nil, fmt.Errorf("could not list timesheet requests: %w", err)
+	}
+
+	return requests, nil
+}
+
+// ListContractorTimesheetRequests lists the timesheet requests of a contractor.
+func (db *LocalTimesheetRequestsDatabaseService) ListContractorTimesheetRequests(contractorID string) ([]*types.TimesheetRequest, error) {
+	requests, err := localList(db.store, db.collectionName, func(r *types.TimesheetRequest) bool { return r.ContractorID == contractorID })
+	if err != nil {
+		return nil, fmt.Errorf("could not list timesheet requests: %w", err)
+	}
+
+	return requests, nil
+}
+
+// GetTimesheetRequest gets the request of a contractor for a period by ContractorID and RequestID, it returns a NotFound status if there is none.
+func (db *LocalTimesheetRequestsDatabaseService) GetTimesheetRequest(contractorID string, requestID string) (*types.TimesheetRequest, error) {
+	requests, err := localList(db.store, db.collectionName, func(r *types.TimesheetRequest) bool {
+		return r.ContractorID == contractorID && r.RequestID == requestID
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get timesheet request: %w", err)
+	}
+
+	if len(requests) == 0 {
+		return nil, status.Errorf(codes.NotFound, "timesheet request of contractor %s for request %s does not exist", contractorID, requestID)
+	}
+
+	return requests[0], nil
+}
+
+// GetTimesheetRequestByReplyKey gets the request with a reply key, it returns a NotFound status if there is none.
+func (db *LocalTimesheetRequestsDatabaseService) GetTimesheetRequestByReplyKey(replyKey string) (*types.TimesheetRequest, error) {
+	requests, err := localList(db.store, db.collectionName, func(r *types.TimesheetRequest) bool { return replyKey != "" && r.ReplyKey == replyKey })
+	if err != nil {
+		return nil, fmt.Errorf("could not get timesheet request: %w", err)
+	}
+
+	if len(requests) == 0 {
+		return nil, status.Errorf(codes.NotFound, "timesheet request with reply key %s does not exist", replyKey)
+	}
+
+	return requests[0], nil
+}
+
+// AddTimesheetRequest adds a timesheet request and sets its ID.
+func (db *LocalTimesheetRequestsDatabaseService) AddTimesheetRequest(request *types.TimesheetRequest) error {
+	request.ID = db.store.NewID()
+
+	err := db.store.Create(db.collectionName, request.ID, request)
+	if err != nil {
+		return fmt.Errorf("could not add timesheet request: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateTimesheetRequest updates a timesheet request.
+func (db *LocalTimesheetRequestsDatabaseService) UpdateTimesheetRequest(request *types.TimesheetRequest) error {
+	err := db.store.Set(db.collectionName, request.ID, request)
+	if err != nil {
+		return fmt.Errorf("could not update timesheet request: %w", err)
+	}
+
+	return nil
+}
+
+// UpdatePendingTimesheetRequest updates the attempts, the escalation, and the reply key and the sent and due dates if it has none yet, of a request
+// still waiting for its timesheet, the other fields are left as they are. It returns a FailedPrecondition status if the request is no longer
+// pending, e.g. its timesheet arrived meanwhile.
+func (db *LocalTimesheetRequestsDatabaseService) UpdatePendingTimesheetRequest(request *types.TimesheetRequest) error {
+	err := db.store.RunTransaction(func(t *LocalTransaction) error {
+		var stored types.TimesheetRequest
+		err := t.Get(db.collectionName, request.ID, &stored)
+		if err != nil {
+			return err
+		}
+
+		if stored.Status != constants.Pending {
+			return status.Errorf(codes.FailedPrecondition, "timesheet request %s is %s", request.ID, stored.Status)
+		}
+
+		stored.Attempts = request.Attempts
+		stored.Escalated = request.Escalated
+		if stored.ReplyKey == "" {
+			stored.ReplyKey = request.ReplyKey
+		}
+		if stored.SentAt.IsZero() {
+			stored.SentAt = request.SentAt
+			stored.DueAt = request.DueAt
+		}
+
+		return t.Set(db.collectionName, request.ID, &stored)
+	})
+	if status.Code(err) == codes.NotFound || status.Code(err) == codes.FailedPrecondition {
+		return err
+	} else if err != nil {
+		return fmt.Errorf("could not update timesheet request: %w", err)
+	}
+
+	return nil
+}
+
+// ReplaceSubmissionNonce updates the submission nonce of a request whose nonce is still oldNonce, the other fields are left as they are.
+// It returns a FailedPrecondition status if the nonce was replaced meanwhile, i.e. the submission link was used by another upload.
+func (db *LocalTimesheetRequestsDatabaseService) ReplaceSubmissionNonce(request *types.TimesheetRequest, oldNonce string) error {
+	err := db.store.RunTransaction(func(t *LocalTransaction) error {
+		var stored types.TimesheetRequest
+		err := t.Get(db.collectionName, request.ID, &stored)
+		if err != nil {
+			return err
+		}
+
+		if stored.SubmissionNonce != oldNonce {
+			return status.Errorf(codes.FailedPrecondition, "submission link of timesheet request %s was already used", request.ID)
+		}
+
+		stored.SubmissionNonce = request.SubmissionNonce
+
+		return t.Set(db.collectionName, request.ID, &stored)
+	})
+	if status.Code(err) == codes.NotFound || status.Code(err) == codes.FailedPrecondition {
+		return err
+	} else if err != nil {
+		return fmt.Errorf("could not update timesheet request: %w", err)
+	}
+
+	return nil
+}
+
+// deleteLocalContractorTimesheetRequests deletes all timesheet requests of a contractor from the store.
+func deleteLocalContractorTimesheetRequests(store *LocalStore, contractorID string) error {
+	requests, err := localList(store, "timesheet_requests", func(r *types.TimesheetRequest) bool { return r.ContractorID == contractorID })
+	if err != nil {
+		return fmt.Errorf("could not list timesheet requests: %w", err)
+	}
+
+	for _, request := range requests {
+		err = store.Delete("timesheet_requests", request.ID)
+		if err != nil {
+			return fmt.Errorf("could not delete timesheet request: %w", err)
+		}
+	}
+
+	return nil
+}
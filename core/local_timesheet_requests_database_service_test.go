@@ -0,0 +1,118 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUpdatePendingTimesheetRequest(t *testing.T) {
+	store, err := NewLocalStore(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	db := NewLocalTimesheetRequestsDatabaseService(store)
+
+	request := &types.TimesheetRequest{ContractorID: "c1", RequestID: testRequestID, Status: constants.Pending, Attempts: 1}
+	err = db.AddTimesheetRequest(request)
+	if err != nil {
+		t.Fatalf("AddTimesheetRequest: %v", err)
+	}
+
+	// A reminder read the request while it was pending, then the timesheet arrived
+	reminded := *request
+	reminded.Attempts = 2
+	reminded.ReplyKey = "key"
+
+	collected := *request
+	collected.Status = constants.Collected
+	collected.CollectedAt = time.Now()
+	err = db.UpdateTimesheetRequest(&collected)
+	if err != nil {
+		t.Fatalf("UpdateTimesheetRequest: %v", err)
+	}
+
+	err = db.UpdatePendingTimesheetRequest(&reminded)
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("UpdatePendingTimesheetRequest error = %v, want FailedPrecondition", err)
+	}
+
+	got, err := db.GetTimesheetRequest("c1", testRequestID)
+	if err != nil {
+		t.Fatalf("GetTimesheetRequest: %v", err)
+	}
+	if got.Status != constants.Collected || got.Attempts != 1 {
+		t.Errorf("request = %+v, want it collected after 1 attempt", got)
+	}
+
+	// A pending request gets only the reminder's fields
+	pending := &types.TimesheetRequest{ContractorID: "c2", RequestID: testRequestID, Status: constants.Pending, Attempts: 1, SubmissionNonce: "nonce"}
+	err = db.AddTimesheetRequest(pending)
+	if err != nil {
+		t.Fatalf("AddTimesheetRequest: %v", err)
+	}
+
+	reminded = *pending
+	reminded.Attempts = 3
+	reminded.Escalated = true
+	reminded.ReplyKey = "key"
+	reminded.SubmissionNonce = "stale"
+
+	err = db.UpdatePendingTimesheetRequest(&reminded)
+	if err != nil {
+		t.Fatalf("UpdatePendingTimesheetRequest: %v", err)
+	}
+
+	got, err = db.GetTimesheetRequest("c2", testRequestID)
+	if err != nil {
+		t.Fatalf("GetTimesheetRequest: %v", err)
+	}
+	if got.Attempts != 3 || !got.Escalated || got.ReplyKey != "key" || got.SubmissionNonce != "nonce" || got.Status != constants.Pending {
+		t.Errorf("request = %+v, want 3 attempts, escalated, the reply key and the stored nonce", got)
+	}
+}
+
+func TestReplaceSubmissionNonce(t *testing.T) {
+	store, err := NewLocalStore(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	db := NewLocalTimesheetRequestsDatabaseService(store)
+
+	request := &types.TimesheetRequest{ContractorID: "c1", RequestID: testRequestID, Status: constants.Pending, Attempts: 1, SubmissionNonce: "n1"}
+	err = db.AddTimesheetRequest(request)
+	if err != nil {
+		t.Fatalf("AddTimesheetRequest: %v", err)
+	}
+
+	// Two uploads read the request through the same link, the first one uses it up
+	first := *request
+	first.SubmissionNonce = "n2"
+	first.Attempts = 5
+	second := *request
+	second.SubmissionNonce = "n3"
+
+	err = db.ReplaceSubmissionNonce(&first, "n1")
+	if err != nil {
+		t.Fatalf("ReplaceSubmissionNonce: %v", err)
+	}
+
+	err = db.ReplaceSubmissionNonce(&second, "n1")
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("ReplaceSubmissionNonce error = %v, want FailedPrecondition", err)
+	}
+
+	got, err := db.GetTimesheetRequest("c1", testRequestID)
+	if err != nil {
+		t.Fatalf("GetTimesheetRequest: %v", err)
+	}
+	if got.SubmissionNonce != "n2" || got.Attempts != 1 {
+		t.Errorf("request = %+v, want the first upload's nonce and 1 attempt", got)
+	}
+}
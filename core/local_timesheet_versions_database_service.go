@@ -0,0 +1,54 @@
+package core
+
+import (
+	"fmt"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+)
+
+// LocalTimesheetVersionsDatabaseService is a service for managing the files submitted for the timesheets in the local store.
+type LocalTimesheetVersionsDatabaseService struct {
+	collectionName string
+	store          *LocalStore
+}
+
+// Ensure LocalTimesheetVersionsDatabaseService implements ITimesheetVersionsDatabaseService.
+var _ interfaces.ITimesheetVersionsDatabaseService = &LocalTimesheetVersionsDatabaseService{}
+
+// NewLocalTimesheetVersionsDatabaseService creates a new LocalTimesheetVersionsDatabaseService.
+func NewLocalTimesheetVersionsDatabaseService(store *LocalStore) *LocalTimesheetVersionsDatabaseService {
+	return &LocalTimesheetVersionsDatabaseService{
+		collectionName: "timesheet_versions",
+		store:          store,
+	}
+}
+
+// ListTimesheetVersions lists the versions of a timesheet from the first.
+func (db *LocalTimesheetVersionsDatabaseService) ListTimesheetVersions(timesheetID string) ([]*types.TimesheetVersion, error) {
+	versions, err := localList(db.store, db.collectionName, func(v *types.TimesheetVersion) bool { return v.TimesheetID == timesheetID })
+	if err != nil {
+		return nil, fmt.Errorf("could not list timesheet versions: %w", err)
+	}
+
+	sortTimesheetVersions(versions)
+
+	return versions, nil
+}
+
+// deleteLocalTimesheetVersions deletes all versions of a timesheet from the store.
+func deleteLocalTimesheetVersions(store *LocalStore, timesheetID string) error {
+	versions, err := localList(store, "timesheet_versions", func(v *types.TimesheetVersion) bool { return v.TimesheetID == timesheetID })
+	if err != nil {
+		return fmt.Errorf("could not list timesheet versions: %w", err)
+	}
+
+	for _, version := range versions {
+		err = store.Delete("timesheet_versions", version.ID)
+		if err != nil {
+			return fmt.Errorf("could not delete timesheet version: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"fmt"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LocalTimesheetsDatabaseService is a service for managing timesheets in the local store.
+type LocalTimesheetsDatabaseService struct {
+	collectionName string
+	store          *LocalStore
+}
+
+// Ensure LocalTimesheetsDatabaseService implements ITimesheetsDatabaseService.
+var _ interfaces.ITimesheetsDatabaseService = &LocalTimesheetsDatabaseService{}
+
+// NewLocalTimesheetsDatabaseService creates a new LocalTimesheetsDatabaseService.
+func NewLocalTimesheetsDatabaseService(store *LocalStore) *LocalTimesheetsDatabaseService {
+	return &LocalTimesheetsDatabaseService{
+		collectionName: "timesheets",
+		store:          store,
+	}
+}
+
+// ListTimesheets lists all timesheets for a group.
+func (db *LocalTimesheetsDatabaseService) ListTimesheets(groupID string) ([]*types.Timesheet, error) {
+	timesheets, err := localList(db.store, db.collectionName, func(t *types.Timesheet) bool { return t.GroupID == groupID })
+	if err != nil {
+		return nil, fmt.Errorf("could not list timesheets: %w", err)
+	}
+
+	return timesheets, nil
+}
+
+// GetTimesheet gets a timesheet by ContractorID and RequestID, it returns a NotFound status if there is none.
+func (db *LocalTimesheetsDatabaseService) GetTimesheet(contractorID string, requestID string) (*types.Timesheet, error) {
+	timesheets, err := localList(db.store, db.collectionName, func(t *types.Timesheet) bool {
+		return t.ContractorID == contractorID && t.RequestID == requestID
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get timesheet: %w", err)
+	}
+
+	if len(timesheets) == 0 {
+		return nil, status.Errorf(codes.NotFound, "timesheet of contractor %s for request %s does not exist", contractorID, requestID)
+	}
+
+	return timesheets[0], nil
+}
+
+// GetTimesheetByID gets a timesheet by ID.
+func (db *LocalTimesheetsDatabaseService) GetTimesheetByID(id string) (*types.Timesheet, error) {
+	var timesheet types.Timesheet
+	err := db.store.Get(db.collectionName, id, &timesheet)
+	if err != nil {
+		return nil, fmt.Errorf("could not get timesheet: %w", err)
+	}
+
+	return &timesheet, nil
+}
+
+// AddTimesheet adds a timesheet.
+func (db *LocalTimesheetsDatabaseService) AddTimesheet(timesheet *types.Timesheet) error {
+	timesheet.ID = db.store.NewID()
+
+	err := db.store.Create(db.collectionName, timesheet.ID, timesheet)
+	if err != nil {
+		return fmt.Errorf("could not add timesheet: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateTimesheet updates a timesheet.
+func (db *LocalTimesheetsDatabaseService) UpdateTimesheet(timesheet *types.Timesheet) error {
+	err := db.store.Set(db.collectionName, timesheet.ID, timesheet)
+	if err != nil {
+		return fmt.Errorf("could not update timesheet: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateTimesheetStatus writes the status and the rejection reason of a timesheet still in fromStatus at fromVersion, the other fields are left as they are.
+func (db *LocalTimesheetsDatabaseService) UpdateTimesheetStatus(timesheet *types.Timesheet, fromStatus constants.TimesheetStatuses, fromVersion int) error {
+	err := db.store.RunTransaction(func(t *LocalTransaction) error {
+		var stored types.Timesheet
+		err := t.Get(db.collectionName, timesheet.ID, &stored)
+		if err != nil {
+			return err
+		}
+
+		if stored.Status != fromStatus || stored.Version != fromVersion {
+			return status.Errorf(codes.FailedPrecondition, "timesheet %s is %s at version %d", timesheet.ID, stored.Status, stored.Version)
+		}
+
+		stored.Status = timesheet.Status
+		stored.RejectionReason = timesheet.RejectionReason
+
+		return t.Set(db.collectionName, timesheet.ID, &stored)
+	})
+	if status.Code(err) == codes.NotFound || status.Code(err) == codes.FailedPrecondition {
+		return err
+	} else if err != nil {
+		return fmt.Errorf("could not update timesheet status: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteTimesheet deletes a timesheet.
+func (db *LocalTimesheetsDatabaseService) DeleteTimesheet(id string) error {
+	err := db.store.Delete(db.collectionName, id)
+	if err != nil {
+		return fmt.Errorf("could not delete timesheet: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"fmt"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LocalUnassignedEmailsDatabaseService is a service for keeping the triage of the unassigned emails in the local store.
+type LocalUnassignedEmailsDatabaseService struct {
+	collectionName string
+	store          *LocalStore
+}
+
+// Ensure LocalUnassignedEmailsDatabaseService implements IUnassignedEmailsDatabaseService.
+var _ interfaces.IUnassignedEmailsDatabaseService = &LocalUnassignedEmailsDatabaseService{}
+
+// NewLocalUnassignedEmailsDatabaseService creates a new LocalUnassignedEmailsDatabaseService.
+func NewLocalUnassignedEmailsDatabaseService(store *LocalStore) *LocalUnassignedEmailsDatabaseService {
+	return &LocalUnassignedEmailsDatabaseService{
+		collectionName: "unassigned_emails",
+		store:          store,
+	}
+}
+
+// ListUnassignedEmails lists the unassigned emails attributed to a group from the newest.
+func (db *LocalUnassignedEmailsDatabaseService) ListUnassignedEmails(groupID string) ([]*types.UnassignedEmail, error) {
+	emails, err := localList(db.store, db.collectionName, func(e *types.UnassignedEmail) bool { return e.GroupID == groupID })
+	if err != nil {
+		return nil, fmt.Errorf("localstore: could not list unassigned emails: %w", err)
+	}
+
+	sortUnassignedEmails(emails)
+
+	return emails, nil
+}
+
+// GetUnassignedEmail gets an unassigned email by its ID.
+func (db *LocalUnassignedEmailsDatabaseService) GetUnassignedEmail(id string) (*types.UnassignedEmail, error) {
+	var email types.UnassignedEmail
+	err := db.store.Get(db.collectionName, id, &email)
+	if status.Code(err) == codes.NotFound {
+		return nil, status.Errorf(codes.NotFound, "unassigned email %s does not exist", id)
+	} else if err != nil {
+		return nil, fmt.Errorf("localstore: could not get unassigned email: %w", err)
+	}
+
+	return &email, nil
+}
+
+// SetUnassignedEmail saves an unassigned email, replacing the one with its ID.
+func (db *LocalUnassignedEmailsDatabaseService) SetUnassignedEmail(email *types.UnassignedEmail) error {
+	err := db.store.Set(db.collectionName, email.ID, email)
+	if err != nil {
+		return fmt.Errorf("localstore: could not set unassigned email: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteUnassignedEmail deletes an unassigned email.
+func (db *LocalUnassignedEmailsDatabaseService) DeleteUnassignedEmail(id string) error {
+	err := db.store.Delete(db.collectionName, id)
+	if err != nil {
+		return fmt.Errorf("localstore: could not delete unassigned email: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package core
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MemorySessionStoreService keeps the sessions in memory, they are lost when the app stops.
+// It is the session store of the local backend, and of a single instance that can afford logging everyone out on restart.
+type MemorySessionStoreService struct {
+	mu       sync.Mutex
+	sessions map[string]types.Session
+}
+
+// Ensure MemorySessionStoreService implements ISessionStoreService.
+var _ interfaces.ISessionStoreService = &MemorySessionStoreService{}
+
+// NewMemorySessionStoreService creates a new MemorySessionStoreService.
+func NewMemorySessionStoreService() *MemorySessionStoreService {
+	return &MemorySessionStoreService{
+		sessions: make(map[string]types.Session),
+	}
+}
+
+// GetSession gets a session by ID, it returns a NotFound status if there is none or it has expired.
+func (s *MemorySessionStoreService) GetSession(id string) (*types.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "session %s does not exist", id)
+	}
+
+	if !time.Now().Before(session.ExpiresAt) {
+		return nil, status.Errorf(codes.NotFound, "session %s has expired", id)
+	}
+
+	return copyMemorySession(session), nil
+}
+
+// SaveSession creates or overwrites a session.
+func (s *MemorySessionStoreService) SaveSession(session *types.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[session.ID] = *copyMemorySession(*session)
+
+	return nil
+}
+
+// UpdateSessionLastSeen records the time, user agent and IP address of the last request of a session, its values are left as they are.
+func (s *MemorySessionStoreService) UpdateSessionLastSeen(id string, lastSeenAt time.Time, userAgent string, ipAddress string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return status.Errorf(codes.NotFound, "session %s does not exist", id)
+	}
+
+	session.LastSeenAt = lastSeenAt
+	session.UserAgent = userAgent
+	session.IPAddress = ipAddress
+	s.sessions[id] = session
+
+	return nil
+}
+
+// DeleteSession deletes a session, deleting a missing one is not an error.
+func (s *MemorySessionStoreService) DeleteSession(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, id)
+
+	return nil
+}
+
+// GetUserSessions gets the sessions of a user that have not expired, the last seen first.
+func (s *MemorySessionStoreService) GetUserSessions(userID string) ([]*types.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var sessions []*types.Session
+	for _, session := range s.sessions {
+		if session.UserID == userID && now.Before(session.ExpiresAt) {
+			sessions = append(sessions, copyMemorySession(session))
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt) })
+
+	return sessions, nil
+}
+
+// DeleteUserSessions deletes every session of a user.
+func (s *MemorySessionStoreService) DeleteUserSessions(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, session := range s.sessions {
+		if session.UserID == userID {
+			delete(s.sessions, id)
+		}
+	}
+
+	return nil
+}
+
+// DeleteExpiredSessions deletes the sessions past their expiry.
+func (s *MemorySessionStoreService) DeleteExpiredSessions() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, session := range s.sessions {
+		if !now.Before(session.ExpiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+
+	return nil
+}
+
+// copyMemorySession copies a session, so the callers never share the values kept in the store.
+func copyMemorySession(session types.Session) *types.Session {
+	session.Values = append([]byte(nil), session.Values...)
+	return &session
+}
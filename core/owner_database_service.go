@@ -181,6 +181,45 @@ func (db *OwnerDatabaseService) DeleteOwner(id string) error {
 				}
 			}
 
+			// Delete timesheet requests
+			requests, err := db.client.Collection("timesheet_requests").Where("contractor_id", "==", contractor.Ref.ID).Documents(ctx).GetAll()
+			if err != nil {
+				return fmt.Errorf("could not get timesheet requests: %w", err)
+			}
+
+			for _, request := range requests {
+				_, err := request.Ref.Delete(ctx)
+				if err != nil {
+					return fmt.Errorf("could not delete timesheet request: %w", err)
+				}
+			}
+
+			// Delete timesheet versions
+			versions, err := db.client.Collection("timesheet_versions").Where("contractor_id", "==", contractor.Ref.ID).Documents(ctx).GetAll()
+			if err != nil {
+				return fmt.Errorf("could not get timesheet versions: %w", err)
+			}
+
+			for _, version := range versions {
+				_, err := version.Ref.Delete(ctx)
+				if err != nil {
+					return fmt.Errorf("could not delete timesheet version: %w", err)
+				}
+			}
+
+			// Delete timesheet ingestions
+			ingestions, err := db.client.Collection("timesheet_ingestions").Where("contractor_id", "==", contractor.Ref.ID).Documents(ctx).GetAll()
+			if err != nil {
+				return fmt.Errorf("could not get timesheet ingestions: %w", err)
+			}
+
+			for _, ingestion := range ingestions {
+				_, err := ingestion.Ref.Delete(ctx)
+				if err != nil {
+					return fmt.Errorf("could not delete timesheet ingestion: %w", err)
+				}
+			}
+
 			contractorID := contractor.Ref.ID
 
 			_, err = db.client.Collection("contractors").Doc(contractorID).Delete(ctx)
@@ -0,0 +1,436 @@
+package core
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"github.com/robfig/cron/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// scheduleDateLayout is the layout of the dates of a schedule and of the periods in request IDs.
+const scheduleDateLayout = "2006-01-02"
+
+// ScheduleService computes the occurrences of schedules, anchored at their start date.
+// The first occurrence is the first matching day whose period does not start before the start date, the next ones follow every interval,
+// and each covers the days from the previous one, or from the start date, until the day before it.
+// Payroll dates like the last day of month close their period, so it ends on the day of the request instead.
+// With a holiday calendar, requests falling on a weekend or a holiday are sent on the next working day, their periods stay the same.
+type ScheduleService struct {
+	holidayCalendarService interfaces.IHolidayCalendarService
+}
+
+// Ensure ScheduleService implements IScheduleService.
+var _ interfaces.IScheduleService = &ScheduleService{}
+
+// NewScheduleService creates a new ScheduleService.
+func NewScheduleService(holidayCalendarService interfaces.IHolidayCalendarService) *ScheduleService {
+	return &ScheduleService{
+		holidayCalendarService: holidayCalendarService,
+	}
+}
+
+// scheduleRule is a parsed schedule. Its dates are days at midnight UTC, so stepping over them is not affected by DST.
+type scheduleRule struct {
+	loc *time.Location
+
+	start time.Time
+	end   time.Time // Zero for schedules without an end date
+
+	// closesPeriod is set for payroll dates, whose period ends on the day of the request.
+	closesPeriod bool
+
+	// at returns when the k-th occurrence is due in the schedule's timezone, or false if there is none.
+	at func(k int) (time.Time, bool)
+
+	// isWorkingDay reports whether an occurrence can be sent on a day, nil if it is always sent when it is due.
+	isWorkingDay func(day time.Time) bool
+}
+
+// NextOccurrences returns up to n occurrences of the schedule after the given time.
+func (s *ScheduleService) NextOccurrences(schedule *types.Schedule, holidayCalendar string, after time.Time, n int) ([]*types.ScheduleOccurrence, error) {
+	rule, err := s.parseScheduleRule(schedule, holidayCalendar)
+	if err != nil {
+		return nil, err
+	}
+
+	var occurrences []*types.ScheduleOccurrence
+	for k := 0; len(occurrences) < n; k++ {
+		occurrence, ok := rule.occurrence(k)
+		if !ok {
+			break
+		}
+
+		if occurrence.Time.After(after) {
+			occurrences = append(occurrences, occurrence)
+		}
+	}
+
+	return occurrences, nil
+}
+
+// OccurrenceOn returns the occurrence of the schedule on the day of the given time in the schedule's timezone, it returns a NotFound status if there is none.
+func (s *ScheduleService) OccurrenceOn(schedule *types.Schedule, holidayCalendar string, t time.Time) (*types.ScheduleOccurrence, error) {
+	rule, err := s.parseScheduleRule(schedule, holidayCalendar)
+	if err != nil {
+		return nil, err
+	}
+
+	date := civilDate(t.In(rule.loc))
+
+	// Occurrences are never sent before they are due
+	for k := 0; ; k++ {
+		at, ok := rule.at(k)
+		if !ok || civilDate(at).After(date) {
+			break
+		}
+
+		occurrence, ok := rule.occurrence(k)
+		if !ok {
+			break
+		}
+
+		if civilDate(occurrence.Time).Equal(date) {
+			return occurrence, nil
+		}
+	}
+
+	return nil, status.Errorf(codes.NotFound, "schedule has no occurrence on %s", date.Format(scheduleDateLayout))
+}
+
+// occurrence returns the k-th occurrence, or false if there is none or its period starts after the end date.
+func (r *scheduleRule) occurrence(k int) (*types.ScheduleOccurrence, bool) {
+	at, ok := r.at(k)
+	if !ok {
+		return nil, false
+	}
+
+	periodStart := r.start
+	if k > 0 {
+		previous, _ := r.at(k - 1)
+		periodStart = civilDate(previous)
+		if r.closesPeriod {
+			periodStart = periodStart.AddDate(0, 0, 1)
+		}
+	}
+
+	periodEnd := civilDate(at)
+	if !r.closesPeriod {
+		periodEnd = periodEnd.AddDate(0, 0, -1)
+	}
+
+	if !r.end.IsZero() {
+		if periodStart.After(r.end) {
+			return nil, false
+		}
+
+		// The last occurrence collects the days until the end date
+		if periodEnd.After(r.end) {
+			periodEnd = r.end
+		}
+	}
+
+	// Sent on the next working day
+	if r.isWorkingDay != nil {
+		for !r.isWorkingDay(civilDate(at)) {
+			at = at.AddDate(0, 0, 1)
+		}
+	}
+
+	return &types.ScheduleOccurrence{
+		Time: at,
+
+		PeriodStart: time.Date(periodStart.Year(), periodStart.Month(), periodStart.Day(), 0, 0, 0, 0, r.loc),
+		PeriodEnd:   time.Date(periodEnd.Year(), periodEnd.Month(), periodEnd.Day(), 0, 0, 0, 0, r.loc),
+
+		RequestID: periodStart.Format(scheduleDateLayout) + "_" + periodEnd.Format(scheduleDateLayout),
+	}, true
+}
+
+// RequestPeriod returns the first and last day of the period of a request ID, it returns an InvalidArgument status for the request IDs made before schedules had periods.
+func (s *ScheduleService) RequestPeriod(requestID string) (time.Time, time.Time, error) {
+	startDate, endDate, ok := strings.Cut(requestID, "_")
+	if !ok {
+		return time.Time{}, time.Time{}, status.Errorf(codes.InvalidArgument, "request ID %q has no period", requestID)
+	}
+
+	start, err := time.Parse(scheduleDateLayout, startDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, status.Errorf(codes.InvalidArgument, "request ID %q has no period: %v", requestID, err)
+	}
+
+	end, err := time.Parse(scheduleDateLayout, endDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, status.Errorf(codes.InvalidArgument, "request ID %q has no period: %v", requestID, err)
+	}
+
+	return start, end, nil
+}
+
+// RequestDueAt returns when the timesheet of a request sent at the given time is due, on the first reminder of the policy,
+// or when the submission link expires if the policy sends no reminders.
+func (s *ScheduleService) RequestDueAt(policy *types.ReminderPolicy, sentAt time.Time) time.Time {
+	if policy.Enabled && len(policy.ReminderDays) > 0 {
+		return sentAt.AddDate(0, 0, policy.ReminderDays[0])
+	}
+
+	return sentAt.AddDate(0, 0, constants.SubmissionLinkLifetimeDays)
+}
+
+// parseScheduleRule validates a schedule and its holiday calendar and parses them into a rule, it returns an InvalidArgument status if either is invalid.
+func (s *ScheduleService) parseScheduleRule(schedule *types.Schedule, holidayCalendar string) (*scheduleRule, error) {
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid timezone %q: %v", schedule.Timezone, err)
+	}
+
+	start, err := time.Parse(scheduleDateLayout, schedule.StartDate)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid start date %q: %v", schedule.StartDate, err)
+	}
+
+	var end time.Time
+	if schedule.EndDate != "" {
+		end, err = time.Parse(scheduleDateLayout, schedule.EndDate)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid end date %q: %v", schedule.EndDate, err)
+		}
+
+		if end.Before(start) {
+			return nil, status.Errorf(codes.InvalidArgument, "end date %s is before start date %s", schedule.EndDate, schedule.StartDate)
+		}
+	}
+
+	interval := schedule.Interval
+	if schedule.IntervalType.HasInterval() && interval < 1 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid interval %d", interval)
+	}
+
+	rule := &scheduleRule{
+		loc: loc,
+
+		start: start,
+		end:   end,
+
+		closesPeriod: schedule.IntervalType == constants.SemiMonthly || schedule.IntervalType == constants.LastDayOfMonth || schedule.IntervalType == constants.LastBusinessDay,
+	}
+
+	// The first occurrence is the first one whose period does not start before the start date
+	from := start
+	if !rule.closesPeriod {
+		from = start.AddDate(0, 0, 1)
+	}
+
+	isWorkingDay := func(day time.Time) bool {
+		return day.Weekday() != time.Saturday && day.Weekday() != time.Sunday
+	}
+	if holidayCalendar != "" {
+		if _, err := s.holidayCalendarService.GetHolidays(holidayCalendar, start.Year()); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid holiday calendar %q: %v", holidayCalendar, err)
+		}
+
+		isWorkingDay = func(day time.Time) bool {
+			workingDay, _ := s.holidayCalendarService.IsWorkingDay(holidayCalendar, day)
+			return workingDay
+		}
+
+		rule.isWorkingDay = isWorkingDay
+	}
+
+	// The cron expression has its own times of day and is never moved, the other types are sent at the schedule's time
+	if schedule.IntervalType == constants.Cron {
+		rule.isWorkingDay = nil
+
+		rule.at, err = cronOccurrences(schedule.Cron, loc, from)
+		if err != nil {
+			return nil, err
+		}
+
+		return rule, nil
+	}
+
+	clock, err := time.Parse("15:04", schedule.Time)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid time %q: %v", schedule.Time, err)
+	}
+
+	var day func(k int) time.Time
+
+	switch schedule.IntervalType {
+	case constants.Weeks:
+		weekday, ok := parseWeekday(schedule.Weekday)
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid weekday %q", schedule.Weekday)
+		}
+
+		// The first matching weekday from the start
+		first := from.AddDate(0, 0, (int(weekday)-int(from.Weekday())+7)%7)
+
+		day = func(k int) time.Time {
+			return first.AddDate(0, 0, 7*interval*k)
+		}
+	case constants.Months:
+		monthday, err := strconv.Atoi(schedule.Monthday)
+		if err != nil || monthday < 1 || monthday > 31 {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid day of month %q", schedule.Monthday)
+		}
+
+		day = everyMonths(from, interval, func(year int, month time.Month) time.Time {
+			return monthDay(year, month, monthday)
+		})
+	case constants.SemiMonthly:
+		// The 15th and the last day of every month, two occurrences a month
+		first := 0
+		for semiMonthlyDay(from.Year(), from.Month(), first).Before(from) {
+			first++
+		}
+
+		day = func(k int) time.Time {
+			return semiMonthlyDay(from.Year(), from.Month(), first+k)
+		}
+	case constants.LastDayOfMonth:
+		day = everyMonths(from, interval, func(year int, month time.Month) time.Time {
+			return monthDay(year, month, 31)
+		})
+	case constants.NthWeekdayOfMonth:
+		weekday, ok := parseWeekday(schedule.Weekday)
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid weekday %q", schedule.Weekday)
+		}
+
+		week := schedule.Week
+		if week != -1 && (week < 1 || week > 4) {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid week of month %d", week)
+		}
+
+		day = everyMonths(from, interval, func(year int, month time.Month) time.Time {
+			return nthWeekday(year, month, weekday, week)
+		})
+	case constants.LastBusinessDay:
+		// The schedule's own holidays are added to the ones of the calendar
+		holidays := make(map[time.Time]bool)
+		for _, holiday := range schedule.Holidays {
+			date, err := time.Parse(scheduleDateLayout, holiday)
+			if err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "invalid holiday %q: %v", holiday, err)
+			}
+			holidays[date] = true
+		}
+
+		day = everyMonths(from, interval, func(year int, month time.Month) time.Time {
+			return lastBusinessDay(year, month, func(day time.Time) bool {
+				return isWorkingDay(day) && !holidays[day]
+			})
+		})
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "invalid interval type %d", schedule.IntervalType)
+	}
+
+	rule.at = func(k int) (time.Time, bool) {
+		d := day(k)
+		return time.Date(d.Year(), d.Month(), d.Day(), clock.Hour(), clock.Minute(), 0, 0, loc), true
+	}
+
+	return rule, nil
+}
+
+// everyMonths returns the days of every interval of months, from the month of the first day on or after from.
+func everyMonths(from time.Time, interval int, dayOf func(year int, month time.Month) time.Time) func(k int) time.Time {
+	first := dayOf(from.Year(), from.Month())
+	if first.Before(from) {
+		first = dayOf(from.Year(), from.Month()+1)
+	}
+
+	return func(k int) time.Time {
+		return dayOf(first.Year(), first.Month()+time.Month(interval*k))
+	}
+}
+
+// cronOccurrences parses a cron expression and returns its occurrences on the days from the given one, only the first time of a day counts.
+func cronOccurrences(expression string, loc *time.Location, from time.Time) (func(k int) (time.Time, bool), error) {
+	cronSchedule, err := cron.ParseStandard(expression)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid cron expression %q: %v", expression, err)
+	}
+
+	// The occurrences found so far, a cron expression can only be stepped through
+	var times []time.Time
+	next := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc).Add(-time.Nanosecond)
+
+	return func(k int) (time.Time, bool) {
+		for len(times) <= k {
+			t := cronSchedule.Next(next)
+			if t.IsZero() {
+				return time.Time{}, false
+			}
+			times = append(times, t)
+
+			// Skip to the end of the day
+			next = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc).Add(-time.Nanosecond)
+		}
+
+		return times[k], true
+	}, nil
+}
+
+// civilDate returns the date of a time in its location, at midnight UTC.
+func civilDate(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// monthDay returns the day of the month at midnight UTC, or the last day of the months that are shorter.
+func monthDay(year int, month time.Month, day int) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+
+	return first.AddDate(0, 0, min(day, last)-1)
+}
+
+// semiMonthlyDay returns the i-th of the 15th and last days of the months from the given month.
+func semiMonthlyDay(year int, month time.Month, i int) time.Time {
+	month += time.Month(i / 2)
+	if i%2 == 0 {
+		return monthDay(year, month, 15)
+	}
+
+	return monthDay(year, month, 31)
+}
+
+// nthWeekday returns the weekday of the week of the month, 1 to 4, or -1 for the last.
+func nthWeekday(year int, month time.Month, weekday time.Weekday, week int) time.Time {
+	if week == -1 {
+		last := monthDay(year, month, 31)
+		return last.AddDate(0, 0, -((int(last.Weekday()) - int(weekday) + 7) % 7))
+	}
+
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	return first.AddDate(0, 0, (int(weekday)-int(first.Weekday())+7)%7+7*(week-1))
+}
+
+// lastBusinessDay returns the last working day of the month.
+func lastBusinessDay(year int, month time.Month, isWorkingDay func(day time.Time) bool) time.Time {
+	day := monthDay(year, month, 31)
+	for !isWorkingDay(day) {
+		day = day.AddDate(0, 0, -1)
+	}
+
+	return day
+}
+
+// parseWeekday parses a weekday name like "Monday".
+func parseWeekday(name string) (time.Weekday, bool) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if weekday.String() == name {
+			return weekday, true
+		}
+	}
+
+	return time.Sunday, false
+}
@@ -0,0 +1,494 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testOccurrenceLayout is the layout the tests compare the times of the occurrences in, with their offset from UTC.
+const testOccurrenceLayout = "2006-01-02T15:04Z07:00"
+
+// testOccurrence is an occurrence as the tests compare it, its time and its request ID.
+type testOccurrence struct {
+	time      string
+	requestID string
+}
+
+func newTestScheduleService(t *testing.T) *ScheduleService {
+	t.Helper()
+
+	holidayCalendarService, err := NewHolidayCalendarService()
+	if err != nil {
+		t.Fatalf("NewHolidayCalendarService: %v", err)
+	}
+
+	return NewScheduleService(holidayCalendarService)
+}
+
+// checkOccurrences compares the occurrences of a schedule after the start of 2000 with the wanted ones.
+func checkOccurrences(t *testing.T, s *ScheduleService, schedule *types.Schedule, holidayCalendar string, want []testOccurrence) {
+	t.Helper()
+
+	occurrences, err := s.NextOccurrences(schedule, holidayCalendar, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), len(want)+1)
+	if err != nil {
+		t.Fatalf("NextOccurrences: %v", err)
+	}
+
+	for i, w := range want {
+		if i >= len(occurrences) {
+			t.Fatalf("got %d occurrences, want at least %d", len(occurrences), len(want))
+		}
+
+		got := testOccurrence{occurrences[i].Time.Format(testOccurrenceLayout), occurrences[i].RequestID}
+		if got != w {
+			t.Errorf("occurrence %d = %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+func TestScheduleServiceIntervals(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule *types.Schedule
+		want     []testOccurrence
+	}{
+		{
+			name:     "every two weeks",
+			schedule: &types.Schedule{IntervalType: constants.Weeks, Interval: 2, Weekday: "Monday", StartDate: "2025-01-01", Time: "09:00", Timezone: "UTC"},
+			want: []testOccurrence{
+				{"2025-01-06T09:00Z", "2025-01-01_2025-01-05"},
+				{"2025-01-20T09:00Z", "2025-01-06_2025-01-19"},
+				{"2025-02-03T09:00Z", "2025-01-20_2025-02-02"},
+			},
+		},
+		{
+			name:     "start on the weekday",
+			schedule: &types.Schedule{IntervalType: constants.Weeks, Interval: 1, Weekday: "Monday", StartDate: "2025-01-06", Time: "09:00", Timezone: "UTC"},
+			want: []testOccurrence{
+				{"2025-01-13T09:00Z", "2025-01-06_2025-01-12"},
+				{"2025-01-20T09:00Z", "2025-01-13_2025-01-19"},
+			},
+		},
+		{
+			name:     "across the DST change",
+			schedule: &types.Schedule{IntervalType: constants.Weeks, Interval: 1, Weekday: "Monday", StartDate: "2025-03-17", Time: "09:00", Timezone: "Europe/Warsaw"},
+			want: []testOccurrence{
+				{"2025-03-24T09:00+01:00", "2025-03-17_2025-03-23"},
+				{"2025-03-31T09:00+02:00", "2025-03-24_2025-03-30"},
+			},
+		},
+		{
+			name:     "back across the DST change",
+			schedule: &types.Schedule{IntervalType: constants.Weeks, Interval: 1, Weekday: "Sunday", StartDate: "2025-10-12", Time: "09:00", Timezone: "Europe/Warsaw"},
+			want: []testOccurrence{
+				{"2025-10-19T09:00+02:00", "2025-10-12_2025-10-18"},
+				{"2025-10-26T09:00+01:00", "2025-10-19_2025-10-25"},
+			},
+		},
+		{
+			name:     "month ends",
+			schedule: &types.Schedule{IntervalType: constants.Months, Interval: 1, Monthday: "31", StartDate: "2025-01-01", Time: "09:00", Timezone: "UTC"},
+			want: []testOccurrence{
+				{"2025-01-31T09:00Z", "2025-01-01_2025-01-30"},
+				{"2025-02-28T09:00Z", "2025-01-31_2025-02-27"},
+				{"2025-03-31T09:00Z", "2025-02-28_2025-03-30"},
+				{"2025-04-30T09:00Z", "2025-03-31_2025-04-29"},
+			},
+		},
+		{
+			name:     "leap year",
+			schedule: &types.Schedule{IntervalType: constants.Months, Interval: 1, Monthday: "30", StartDate: "2024-01-01", Time: "09:00", Timezone: "UTC"},
+			want: []testOccurrence{
+				{"2024-01-30T09:00Z", "2024-01-01_2024-01-29"},
+				{"2024-02-29T09:00Z", "2024-01-30_2024-02-28"},
+				{"2024-03-30T09:00Z", "2024-02-29_2024-03-29"},
+			},
+		},
+		{
+			name:     "every quarter across the year",
+			schedule: &types.Schedule{IntervalType: constants.Months, Interval: 3, Monthday: "1", StartDate: "2024-11-15", Time: "09:00", Timezone: "UTC"},
+			want: []testOccurrence{
+				{"2024-12-01T09:00Z", "2024-11-15_2024-11-30"},
+				{"2025-03-01T09:00Z", "2024-12-01_2025-02-28"},
+				{"2025-06-01T09:00Z", "2025-03-01_2025-05-31"},
+			},
+		},
+		{
+			name:     "in the schedule's timezone",
+			schedule: &types.Schedule{IntervalType: constants.Months, Interval: 1, Monthday: "1", StartDate: "2025-01-01", Time: "23:30", Timezone: "America/New_York"},
+			want: []testOccurrence{
+				{"2025-02-01T23:30-05:00", "2025-01-01_2025-01-31"},
+				{"2025-03-01T23:30-05:00", "2025-02-01_2025-02-28"},
+				{"2025-04-01T23:30-04:00", "2025-03-01_2025-03-31"},
+			},
+		},
+	}
+
+	s := newTestScheduleService(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkOccurrences(t, s, tt.schedule, "", tt.want)
+		})
+	}
+}
+
+func TestScheduleServiceEndDate(t *testing.T) {
+	s := newTestScheduleService(t)
+	schedule := &types.Schedule{IntervalType: constants.Weeks, Interval: 1, Weekday: "Monday", StartDate: "2025-01-01", EndDate: "2025-01-15", Time: "09:00", Timezone: "UTC"}
+
+	occurrences, err := s.NextOccurrences(schedule, "", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), 10)
+	if err != nil {
+		t.Fatalf("NextOccurrences: %v", err)
+	}
+
+	// The last occurrence collects the days until the end date
+	want := []string{"2025-01-01_2025-01-05", "2025-01-06_2025-01-12", "2025-01-13_2025-01-15"}
+	if len(occurrences) != len(want) {
+		t.Fatalf("got %d occurrences, want %d", len(occurrences), len(want))
+	}
+	for i, occurrence := range occurrences {
+		if occurrence.RequestID != want[i] {
+			t.Errorf("occurrence %d = %s, want %s", i, occurrence.RequestID, want[i])
+		}
+	}
+}
+
+func TestScheduleServiceOccurrenceOn(t *testing.T) {
+	s := newTestScheduleService(t)
+	schedule := &types.Schedule{IntervalType: constants.Weeks, Interval: 2, Weekday: "Monday", StartDate: "2025-01-01", Time: "09:00", Timezone: "Europe/Warsaw"}
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want string
+	}{
+		{"on the day", time.Date(2025, 1, 20, 9, 0, 0, 0, warsaw), "2025-01-06_2025-01-19"},
+		{"on the day in UTC", time.Date(2025, 1, 19, 23, 30, 0, 0, time.UTC), "2025-01-06_2025-01-19"},
+		{"between occurrences", time.Date(2025, 1, 13, 9, 0, 0, 0, warsaw), ""},
+		{"before the start", time.Date(2024, 12, 30, 9, 0, 0, 0, warsaw), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			occurrence, err := s.OccurrenceOn(schedule, "", tt.t)
+			if tt.want == "" {
+				if status.Code(err) != codes.NotFound {
+					t.Fatalf("OccurrenceOn = %+v, %v, want NotFound", occurrence, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("OccurrenceOn: %v", err)
+			}
+			if occurrence.RequestID != tt.want {
+				t.Errorf("RequestID = %s, want %s", occurrence.RequestID, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleServiceInvalidSchedules(t *testing.T) {
+	valid := types.Schedule{IntervalType: constants.Weeks, Interval: 1, Weekday: "Monday", Monthday: "1", StartDate: "2025-01-01", Time: "09:00", Timezone: "UTC"}
+
+	tests := []struct {
+		name   string
+		change func(s *types.Schedule)
+	}{
+		{"timezone", func(s *types.Schedule) { s.Timezone = "Mars/Olympus" }},
+		{"start date", func(s *types.Schedule) { s.StartDate = "2025-02-30" }},
+		{"end date", func(s *types.Schedule) { s.EndDate = "31.12.2025" }},
+		{"end before start", func(s *types.Schedule) { s.EndDate = "2024-12-31" }},
+		{"interval", func(s *types.Schedule) { s.Interval = 0 }},
+		{"weekday", func(s *types.Schedule) { s.Weekday = "Mon" }},
+		{"time", func(s *types.Schedule) { s.Time = "24:00" }},
+		{"day of month", func(s *types.Schedule) { s.IntervalType, s.Monthday = constants.Months, "32" }},
+		{"interval type", func(s *types.Schedule) { s.IntervalType = constants.IntervalTypes(100) }},
+	}
+
+	s := newTestScheduleService(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule := valid
+			tt.change(&schedule)
+
+			_, err := s.NextOccurrences(&schedule, "", time.Time{}, 1)
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("NextOccurrences error = %v, want InvalidArgument", err)
+			}
+		})
+	}
+}
+
+func TestScheduleServiceRequestPeriod(t *testing.T) {
+	s := newTestScheduleService(t)
+
+	start, end, err := s.RequestPeriod("2024-02-01_2024-02-29")
+	if err != nil {
+		t.Fatalf("RequestPeriod: %v", err)
+	}
+	if start != time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC) || end != time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC) {
+		t.Errorf("RequestPeriod = %s, %s, want 2024-02-01, 2024-02-29", start, end)
+	}
+
+	// The request IDs made before schedules had periods
+	for _, requestID := range []string{"9_10-2024", "2025-02-29_2025-03-01"} {
+		_, _, err = s.RequestPeriod(requestID)
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("RequestPeriod(%q) error = %v, want InvalidArgument", requestID, err)
+		}
+	}
+}
+
+func TestConvertScheduleToDailyCron(t *testing.T) {
+	tests := []struct {
+		time    string
+		want    string
+		wantErr bool
+	}{
+		{"09:00", "0 9 * * *", false},
+		{"00:00", "0 0 * * *", false},
+		{"23:59", "59 23 * * *", false},
+		{"7:05", "5 7 * * *", false},
+		{"24:00", "", true},
+		{"12:60", "", true},
+		{"0900", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.time, func(t *testing.T) {
+			got, err := convertScheduleToDailyCron(&types.Schedule{Time: tt.time})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("convertScheduleToDailyCron = %q, want an error", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("convertScheduleToDailyCron: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("convertScheduleToDailyCron = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleServicePayrollTypes(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule *types.Schedule
+		want     []testOccurrence
+	}{
+		{
+			name:     "semi-monthly",
+			schedule: &types.Schedule{IntervalType: constants.SemiMonthly, StartDate: "2025-01-01", Time: "09:00", Timezone: "UTC"},
+			want: []testOccurrence{
+				{"2025-01-15T09:00Z", "2025-01-01_2025-01-15"},
+				{"2025-01-31T09:00Z", "2025-01-16_2025-01-31"},
+				{"2025-02-15T09:00Z", "2025-02-01_2025-02-15"},
+				{"2025-02-28T09:00Z", "2025-02-16_2025-02-28"},
+			},
+		},
+		{
+			name:     "semi-monthly in a leap year",
+			schedule: &types.Schedule{IntervalType: constants.SemiMonthly, StartDate: "2024-02-01", Time: "09:00", Timezone: "UTC"},
+			want: []testOccurrence{
+				{"2024-02-15T09:00Z", "2024-02-01_2024-02-15"},
+				{"2024-02-29T09:00Z", "2024-02-16_2024-02-29"},
+				{"2024-03-15T09:00Z", "2024-03-01_2024-03-15"},
+			},
+		},
+		{
+			name:     "semi-monthly from the second half",
+			schedule: &types.Schedule{IntervalType: constants.SemiMonthly, StartDate: "2025-12-16", Time: "09:00", Timezone: "UTC"},
+			want: []testOccurrence{
+				{"2025-12-31T09:00Z", "2025-12-16_2025-12-31"},
+				{"2026-01-15T09:00Z", "2026-01-01_2026-01-15"},
+			},
+		},
+		{
+			name:     "last day of month",
+			schedule: &types.Schedule{IntervalType: constants.LastDayOfMonth, Interval: 1, StartDate: "2024-01-01", Time: "09:00", Timezone: "UTC"},
+			want: []testOccurrence{
+				{"2024-01-31T09:00Z", "2024-01-01_2024-01-31"},
+				{"2024-02-29T09:00Z", "2024-02-01_2024-02-29"},
+				{"2024-03-31T09:00Z", "2024-03-01_2024-03-31"},
+				{"2024-04-30T09:00Z", "2024-04-01_2024-04-30"},
+			},
+		},
+		{
+			name:     "last day of every other month",
+			schedule: &types.Schedule{IntervalType: constants.LastDayOfMonth, Interval: 2, StartDate: "2025-01-10", Time: "09:00", Timezone: "UTC"},
+			want: []testOccurrence{
+				{"2025-01-31T09:00Z", "2025-01-10_2025-01-31"},
+				{"2025-03-31T09:00Z", "2025-02-01_2025-03-31"},
+				{"2025-05-31T09:00Z", "2025-04-01_2025-05-31"},
+			},
+		},
+		{
+			name:     "first Monday",
+			schedule: &types.Schedule{IntervalType: constants.NthWeekdayOfMonth, Interval: 1, Weekday: "Monday", Week: 1, StartDate: "2025-01-01", Time: "09:00", Timezone: "UTC"},
+			want: []testOccurrence{
+				{"2025-01-06T09:00Z", "2025-01-01_2025-01-05"},
+				{"2025-02-03T09:00Z", "2025-01-06_2025-02-02"},
+				{"2025-03-03T09:00Z", "2025-02-03_2025-03-02"},
+			},
+		},
+		{
+			name:     "last Friday",
+			schedule: &types.Schedule{IntervalType: constants.NthWeekdayOfMonth, Interval: 1, Weekday: "Friday", Week: -1, StartDate: "2025-01-01", Time: "09:00", Timezone: "UTC"},
+			want: []testOccurrence{
+				{"2025-01-31T09:00Z", "2025-01-01_2025-01-30"},
+				{"2025-02-28T09:00Z", "2025-01-31_2025-02-27"},
+				{"2025-03-28T09:00Z", "2025-02-28_2025-03-27"},
+			},
+		},
+		{
+			name:     "last business day",
+			schedule: &types.Schedule{IntervalType: constants.LastBusinessDay, Interval: 1, StartDate: "2025-05-01", Time: "09:00", Timezone: "UTC"},
+			want: []testOccurrence{
+				{"2025-05-30T09:00Z", "2025-05-01_2025-05-30"},
+				{"2025-06-30T09:00Z", "2025-05-31_2025-06-30"},
+				{"2025-07-31T09:00Z", "2025-07-01_2025-07-31"},
+			},
+		},
+		{
+			name:     "last business day before the schedule's holiday",
+			schedule: &types.Schedule{IntervalType: constants.LastBusinessDay, Interval: 1, Holidays: []string{"2025-06-30"}, StartDate: "2025-05-01", Time: "09:00", Timezone: "UTC"},
+			want: []testOccurrence{
+				{"2025-05-30T09:00Z", "2025-05-01_2025-05-30"},
+				{"2025-06-27T09:00Z", "2025-05-31_2025-06-27"},
+				{"2025-07-31T09:00Z", "2025-06-28_2025-07-31"},
+			},
+		},
+		{
+			name:     "cron",
+			schedule: &types.Schedule{IntervalType: constants.Cron, Cron: "0 9 1,15 * *", StartDate: "2025-01-01", Timezone: "UTC"},
+			want: []testOccurrence{
+				{"2025-01-15T09:00Z", "2025-01-01_2025-01-14"},
+				{"2025-02-01T09:00Z", "2025-01-15_2025-01-31"},
+				{"2025-02-15T09:00Z", "2025-02-01_2025-02-14"},
+			},
+		},
+		{
+			name:     "cron firing twice a day",
+			schedule: &types.Schedule{IntervalType: constants.Cron, Cron: "0 9,17 * * 1", StartDate: "2025-01-01", Timezone: "Europe/Warsaw"},
+			want: []testOccurrence{
+				{"2025-01-06T09:00+01:00", "2025-01-01_2025-01-05"},
+				{"2025-01-13T09:00+01:00", "2025-01-06_2025-01-12"},
+			},
+		},
+	}
+
+	s := newTestScheduleService(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkOccurrences(t, s, tt.schedule, "", tt.want)
+		})
+	}
+}
+
+func TestScheduleServiceInvalidPayrollSchedules(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule *types.Schedule
+	}{
+		{"week of month", &types.Schedule{IntervalType: constants.NthWeekdayOfMonth, Interval: 1, Weekday: "Monday", Week: 5, StartDate: "2025-01-01", Time: "09:00", Timezone: "UTC"}},
+		{"weekday of month", &types.Schedule{IntervalType: constants.NthWeekdayOfMonth, Interval: 1, Weekday: "", Week: 1, StartDate: "2025-01-01", Time: "09:00", Timezone: "UTC"}},
+		{"holiday", &types.Schedule{IntervalType: constants.LastBusinessDay, Interval: 1, Holidays: []string{"30.06.2025"}, StartDate: "2025-01-01", Time: "09:00", Timezone: "UTC"}},
+		{"last day interval", &types.Schedule{IntervalType: constants.LastDayOfMonth, Interval: 0, StartDate: "2025-01-01", Time: "09:00", Timezone: "UTC"}},
+		{"cron expression", &types.Schedule{IntervalType: constants.Cron, Cron: "61 9 * * *", StartDate: "2025-01-01", Timezone: "UTC"}},
+		{"cron fields", &types.Schedule{IntervalType: constants.Cron, Cron: "0 9 * *", StartDate: "2025-01-01", Timezone: "UTC"}},
+	}
+
+	s := newTestScheduleService(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.NextOccurrences(tt.schedule, "", time.Time{}, 1)
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("NextOccurrences error = %v, want InvalidArgument", err)
+			}
+		})
+	}
+}
+
+func TestScheduleServiceHolidayCalendar(t *testing.T) {
+	tests := []struct {
+		name            string
+		schedule        *types.Schedule
+		holidayCalendar string
+		want            []testOccurrence
+	}{
+		{
+			name:            "moved past holidays and weekends",
+			schedule:        &types.Schedule{IntervalType: constants.Months, Interval: 1, Monthday: "1", StartDate: "2025-11-15", Time: "09:00", Timezone: "UTC"},
+			holidayCalendar: "PL",
+			want: []testOccurrence{
+				{"2025-12-01T09:00Z", "2025-11-15_2025-11-30"},
+				{"2026-01-02T09:00Z", "2025-12-01_2025-12-31"},
+				{"2026-02-02T09:00Z", "2026-01-01_2026-01-31"},
+			},
+		},
+		{
+			name:            "moved past substitute days",
+			schedule:        &types.Schedule{IntervalType: constants.Months, Interval: 1, Monthday: "27", StartDate: "2021-11-01", Time: "09:00", Timezone: "UTC"},
+			holidayCalendar: "UK",
+			want: []testOccurrence{
+				{"2021-11-29T09:00Z", "2021-11-01_2021-11-26"},
+				{"2021-12-29T09:00Z", "2021-11-27_2021-12-26"},
+			},
+		},
+		{
+			name:            "last business day before a bank holiday",
+			schedule:        &types.Schedule{IntervalType: constants.LastBusinessDay, Interval: 1, StartDate: "2026-08-01", Time: "09:00", Timezone: "UTC"},
+			holidayCalendar: "UK",
+			want: []testOccurrence{
+				{"2026-08-28T09:00Z", "2026-08-01_2026-08-28"},
+			},
+		},
+		{
+			name:     "last business day without a calendar",
+			schedule: &types.Schedule{IntervalType: constants.LastBusinessDay, Interval: 1, StartDate: "2026-08-01", Time: "09:00", Timezone: "UTC"},
+			want: []testOccurrence{
+				{"2026-08-31T09:00Z", "2026-08-01_2026-08-31"},
+			},
+		},
+		{
+			name:            "cron is not moved",
+			schedule:        &types.Schedule{IntervalType: constants.Cron, Cron: "0 9 1 * *", StartDate: "2025-12-15", Timezone: "UTC"},
+			holidayCalendar: "PL",
+			want: []testOccurrence{
+				{"2026-01-01T09:00Z", "2025-12-15_2025-12-31"},
+			},
+		},
+	}
+
+	s := newTestScheduleService(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkOccurrences(t, s, tt.schedule, tt.holidayCalendar, tt.want)
+		})
+	}
+}
+
+func TestScheduleServiceUnknownHolidayCalendar(t *testing.T) {
+	schedule := &types.Schedule{IntervalType: constants.Months, Interval: 1, Monthday: "1", StartDate: "2025-01-01", Time: "09:00", Timezone: "UTC"}
+
+	_, err := newTestScheduleService(t).NextOccurrences(schedule, "XX", time.Time{}, 1)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("NextOccurrences error = %v, want InvalidArgument", err)
+	}
+}
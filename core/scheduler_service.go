@@ -3,7 +3,7 @@ package core
 import (
 	"context"
 	"fmt"
-	"strings"
+	"time"
 
 	"job_sender/interfaces"
 	"job_sender/types"
@@ -12,6 +12,8 @@ import (
 	scheduler "cloud.google.com/go/scheduler/apiv1"
 	"cloud.google.com/go/scheduler/apiv1/schedulerpb"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type SchedulerService struct {
@@ -135,51 +137,112 @@ func (s *SchedulerService) DeleteTimesheetRequestJob(groupID string) error {
 	return nil
 }
 
-// convertScheduleToCron translates a Schedule instance to a Unix-cron format string.
-// Adjusted to handle intervals greater than 1 elsewhere.
-func convertScheduleToCron(s *types.Schedule) (string, error) {
-	// Split the Time into hour and minute
-	timeParts := strings.Split(s.Time, ":")
-	if len(timeParts) != 2 {
-		return "", fmt.Errorf("invalid time format")
+// CreateTimesheetReminderJob creates a new Cloud Scheduler job that sends the due reminders daily at the schedule's time.
+func (s *SchedulerService) CreateTimesheetReminderJob(groupID string, schedule *types.Schedule) error {
+	// Convert the schedule to a daily cron expression
+	cronExpression, err := convertScheduleToDailyCron(schedule)
+	if err != nil {
+		return fmt.Errorf("convertScheduleToDailyCron: %v", err)
+	}
+
+	// Define the job to be created
+	job := &schedulerpb.Job{
+		Name: fmt.Sprintf("projects/%s/locations/%s/jobs/%s", s.projectID, s.location, fmt.Sprintf("timesheet-reminder-scheduler-job-%s", groupID)),
+		Target: &schedulerpb.Job_HttpTarget{
+			HttpTarget: &schedulerpb.HttpTarget{
+				Uri:        constants.AppUrl + "/timesheets/remind?groupID=" + groupID,
+				HttpMethod: schedulerpb.HttpMethod_POST,
+				AuthorizationHeader: &schedulerpb.HttpTarget_OidcToken{
+					OidcToken: &schedulerpb.OidcToken{
+						ServiceAccountEmail: s.serviceAccountEmail,
+						Audience:            constants.AppUrl + "/timesheets/remind",
+					},
+				},
+			},
+		},
+		Schedule: cronExpression,
+		TimeZone: schedule.Timezone,
+	}
+
+	// Create the job
+	req := &schedulerpb.CreateJobRequest{
+		Parent: fmt.Sprintf("projects/%s/locations/%s", s.projectID, s.location),
+		Job:    job,
+	}
+
+	_, err = s.client.CreateJob(context.Background(), req)
+	if err != nil {
+		return fmt.Errorf("CreateJob: %v", err)
+	}
+
+	return nil
+}
+
+// EditTimesheetReminderJob updates a Cloud Scheduler job for sending reminders, creating it for groups made before reminders existed.
+func (s *SchedulerService) EditTimesheetReminderJob(groupID string, schedule *types.Schedule) error {
+	// Generate the job name
+	jobName := fmt.Sprintf("projects/%s/locations/%s/jobs/%s", s.projectID, s.location, fmt.Sprintf("timesheet-reminder-scheduler-job-%s", groupID))
+
+	// Retrieve the existing job
+	job, err := s.client.GetJob(context.Background(), &schedulerpb.GetJobRequest{Name: jobName})
+	if status.Code(err) == codes.NotFound {
+		return s.CreateTimesheetReminderJob(groupID, schedule)
+	} else if err != nil {
+		return fmt.Errorf("GetJob: %v", err)
 	}
-	hour, minute := timeParts[0], timeParts[1]
 
-	// Default values for cron fields
-	dayOfMonth, month, dayOfWeek := "*", "*", "*"
+	// Convert the new schedule to a daily cron expression
+	cronExpression, err := convertScheduleToDailyCron(schedule)
+	if err != nil {
+		return fmt.Errorf("convertScheduleToDailyCron: %v", err)
+	}
 
-	switch s.IntervalType {
-	case constants.Weeks:
-		// Assuming Weekday is already validated and converted elsewhere
-		dayOfWeek = weekdayToCronDay(s.Weekday)
-	case constants.Months:
-		// Assuming Monthday is already validated elsewhere
-		dayOfMonth = s.Monthday
+	// Update the job's schedule and timezone
+	job.Schedule = cronExpression
+	job.TimeZone = schedule.Timezone
+
+	// Update the job
+	_, err = s.client.UpdateJob(context.Background(), &schedulerpb.UpdateJobRequest{Job: job})
+	if err != nil {
+		return fmt.Errorf("UpdateJob: %v", err)
 	}
 
-	cronExpression := fmt.Sprintf("%s %s %s %s %s", minute, hour, dayOfMonth, month, dayOfWeek)
+	return nil
+}
 
-	return cronExpression, nil
+// DeleteTimesheetReminderJob deletes a Cloud Scheduler job for sending reminders, if there is one.
+func (s *SchedulerService) DeleteTimesheetReminderJob(groupID string) error {
+	// Delete the job
+	req := &schedulerpb.DeleteJobRequest{
+		Name: fmt.Sprintf("projects/%s/locations/%s/jobs/%s", s.projectID, s.location, fmt.Sprintf("timesheet-reminder-scheduler-job-%s", groupID)),
+	}
+
+	err := s.client.DeleteJob(context.Background(), req)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return fmt.Errorf("DeleteJob: %v", err)
+	}
+
+	return nil
+}
+
+// convertScheduleToDailyCron translates the time of day of a Schedule to a Unix-cron format string firing every day.
+func convertScheduleToDailyCron(s *types.Schedule) (string, error) {
+	// Parse the Time, so an hour or a minute out of range is not passed on to the job
+	clock, err := time.Parse("15:04", s.Time)
+	if err != nil {
+		return "", fmt.Errorf("invalid time format %q: %v", s.Time, err)
+	}
+
+	return fmt.Sprintf("%d %d * * *", clock.Minute(), clock.Hour()), nil
 }
 
-// weekdayToCronDay converts a weekday string to a cron-compatible day-of-week.
-func weekdayToCronDay(weekday string) string {
-	switch weekday {
-	case "Sunday":
-		return "0"
-	case "Monday":
-		return "1"
-	case "Tuesday":
-		return "2"
-	case "Wednesday":
-		return "3"
-	case "Thursday":
-		return "4"
-	case "Friday":
-		return "5"
-	case "Saturday":
-		return "6"
-	default:
-		return ""
+// convertScheduleToCron translates a Schedule instance to a Unix-cron format string firing on every day an occurrence can be sent on.
+// Cron cannot express intervals anchored at the start date or holidays, so the job fires daily and the schedule service picks the actual occurrences.
+func convertScheduleToCron(s *types.Schedule) (string, error) {
+	// A raw cron expression is used as is
+	if s.IntervalType == constants.Cron {
+		return s.Cron, nil
 	}
+
+	return convertScheduleToDailyCron(s)
 }
@@ -1,54 +1,88 @@
 package core
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"job_sender/interfaces"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
 
+	"github.com/gorilla/securecookie"
 	"github.com/gorilla/sessions"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type SessionManagerService struct {
-	store *sessions.CookieStore
+	store               *serverSideStore
+	sessionStoreService interfaces.ISessionStoreService
+}
+
+// serverSideStore is a gorilla sessions store keeping the sessions in an ISessionStoreService, their cookies only hold their signed IDs.
+type serverSideStore struct {
+	codec               *securecookie.SecureCookie
+	sessionStoreService interfaces.ISessionStoreService
 }
 
 // Ensure SessionManagerService implements ISessionManagerService.
 var _ interfaces.ISessionManagerService = &SessionManagerService{}
 
-// NewSessionManagerService creates a new SessionManagerService.
-func NewSessionManagerService(secretCookieStoreKey []byte) *SessionManagerService {
-	store := sessions.NewCookieStore(secretCookieStoreKey)
+// Ensure serverSideStore implements the gorilla sessions Store.
+var _ sessions.Store = &serverSideStore{}
+
+// NewSessionManagerService creates a new SessionManagerService keeping the sessions in sessionStoreService, their IDs are signed with secretCookieStoreKey.
+func NewSessionManagerService(secretCookieStoreKey []byte, sessionStoreService interfaces.ISessionStoreService) *SessionManagerService {
+	// The stored sessions expire, not the signatures of their IDs
+	codec := securecookie.New(secretCookieStoreKey, nil)
+	codec.MaxAge(0)
 
 	return &SessionManagerService{
-		store: store,
+		store: &serverSideStore{
+			codec:               codec,
+			sessionStoreService: sessionStoreService,
+		},
+		sessionStoreService: sessionStoreService,
 	}
 }
 
-// CreateSession creates a new session and stores key-value pairs.
-func (s *SessionManagerService) CreateSession(w http.ResponseWriter, r *http.Request, sessionID string, tokenExpirationTimestamp time.Time, data map[string]interface{}) (*sessions.Session, error) {
-	// Initialize a new session for the user
-	session, err := s.store.Get(r, sessionID)
+// CreateSession creates a new session and stores key-value pairs, a session of the request with the same name is replaced.
+// The session lasts until expiresAt, its cookie too if persistent, otherwise the cookie is dropped when the browser closes.
+func (s *SessionManagerService) CreateSession(w http.ResponseWriter, r *http.Request, sessionName string, expiresAt time.Time, persistent bool, data map[string]interface{}) (*sessions.Session, error) {
+	session, err := s.store.Get(r, sessionName)
 	if err != nil {
 		return nil, err
 	}
 
+	// A new ID is given on every login, so an ID known before it is of no use
+	if session.ID != "" {
+		err = s.sessionStoreService.DeleteSession(session.ID)
+		if err != nil {
+			return nil, fmt.Errorf("could not delete replaced session: %w", err)
+		}
+	}
+	session.ID = ""
+	session.IsNew = true
+
 	// Store the data in the session
+	session.Values = map[interface{}]interface{}{
+		constants.SessionExpiresAtField: expiresAt.Unix(),
+	}
 	for key, value := range data {
 		session.Values[key] = value
 	}
 
-	// Calculate MaxAge based on tokenExpirationTimestamp
-	currentTime := time.Now()
-	maxAge := int(tokenExpirationTimestamp.Sub(currentTime).Seconds())
-
-	// Set the session options
-	session.Options = &sessions.Options{
-		Path:     "/",
-		MaxAge:   maxAge, // TODO: set low maxAge and test, what would happen when session ends
-		HttpOnly: true,
-		Secure:   true,                 // Only send cookie over HTTPS
-		SameSite: http.SameSiteLaxMode, // Adjust according to your requirements
+	// Set the session options, a cookie without MaxAge is dropped when the browser closes
+	session.Options = newSessionOptions()
+	if persistent {
+		session.Options.MaxAge = int(time.Until(expiresAt).Seconds())
 	}
 
 	// Save the session
@@ -60,9 +94,9 @@ func (s *SessionManagerService) CreateSession(w http.ResponseWriter, r *http.Req
 	return session, nil
 }
 
-// DeleteSession deletes a session.
-func (s *SessionManagerService) DeleteSession(w http.ResponseWriter, r *http.Request, sessionID string) error {
-	session, err := s.store.Get(r, sessionID)
+// DeleteSession deletes a session and its cookie.
+func (s *SessionManagerService) DeleteSession(w http.ResponseWriter, r *http.Request, sessionName string) error {
+	session, err := s.store.Get(r, sessionName)
 	if err != nil {
 		return err
 	}
@@ -77,8 +111,8 @@ func (s *SessionManagerService) DeleteSession(w http.ResponseWriter, r *http.Req
 }
 
 // CheckSession checks if a session exists.
-func (s *SessionManagerService) CheckSession(r *http.Request, sessionID string) bool {
-	session, err := s.store.Get(r, sessionID)
+func (s *SessionManagerService) CheckSession(r *http.Request, sessionName string) bool {
+	session, err := s.store.Get(r, sessionName)
 	if err != nil {
 		return false
 	}
@@ -87,8 +121,8 @@ func (s *SessionManagerService) CheckSession(r *http.Request, sessionID string)
 }
 
 // GetElement retrieves an element from a session.
-func (s *SessionManagerService) GetElement(r *http.Request, sessionID string, key string) (interface{}, error) {
-	session, err := s.store.Get(r, sessionID)
+func (s *SessionManagerService) GetElement(r *http.Request, sessionName string, key string) (interface{}, error) {
+	session, err := s.store.Get(r, sessionName)
 	if err != nil {
 		return nil, err
 	}
@@ -97,8 +131,8 @@ func (s *SessionManagerService) GetElement(r *http.Request, sessionID string, ke
 }
 
 // SetElement sets an element in a session.
-func (s *SessionManagerService) SetElement(w http.ResponseWriter, r *http.Request, sessionID string, key string, value interface{}) error {
-	session, err := s.store.Get(r, sessionID)
+func (s *SessionManagerService) SetElement(w http.ResponseWriter, r *http.Request, sessionName string, key string, value interface{}) error {
+	session, err := s.store.Get(r, sessionName)
 	if err != nil {
 		return err
 	}
@@ -111,3 +145,297 @@ func (s *SessionManagerService) SetElement(w http.ResponseWriter, r *http.Reques
 
 	return nil
 }
+
+// UpdateElements sets elements of a stored session without writing its cookie, a session that is not stored is left as it is.
+func (s *SessionManagerService) UpdateElements(r *http.Request, sessionName string, data map[string]interface{}) error {
+	session, err := s.store.Get(r, sessionName)
+	if err != nil {
+		return err
+	}
+
+	if session.ID == "" {
+		return nil
+	}
+
+	for key, value := range data {
+		session.Values[key] = value
+	}
+
+	_, err = s.store.update(session)
+	return err
+}
+
+// GetSessionID returns the ID a session of the request is stored under, or an empty string if it is not stored.
+func (s *SessionManagerService) GetSessionID(r *http.Request, sessionName string) (string, error) {
+	session, err := s.store.Get(r, sessionName)
+	if err != nil {
+		return "", err
+	}
+
+	return session.ID, nil
+}
+
+// GetUserSessions gets the sessions of a user that have not expired, the last seen first.
+func (s *SessionManagerService) GetUserSessions(userID string) ([]*types.Session, error) {
+	return s.sessionStoreService.GetUserSessions(userID)
+}
+
+// DeleteUserSession deletes a session of a user, it returns a NotFound status if the user has no such session.
+func (s *SessionManagerService) DeleteUserSession(userID string, id string) error {
+	session, err := s.sessionStoreService.GetSession(id)
+	if err != nil {
+		return err
+	}
+
+	if session.UserID != userID {
+		return status.Errorf(codes.NotFound, "session %s does not exist", id)
+	}
+
+	return s.sessionStoreService.DeleteSession(id)
+}
+
+// DeleteUserSessions deletes every session of a user, they are all logged out.
+func (s *SessionManagerService) DeleteUserSessions(userID string) error {
+	return s.sessionStoreService.DeleteUserSessions(userID)
+}
+
+// ExpireSessions deletes the expired sessions every hour, until the context is done.
+// Expired sessions are never used, this only frees the store.
+func (s *SessionManagerService) ExpireSessions(ctx context.Context) {
+	ticker := time.NewTicker(constants.SessionExpiryIntervalMinutes * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := s.sessionStoreService.DeleteExpiredSessions()
+			if err != nil {
+				log.Printf("session expiry: %v", err)
+			}
+		}
+	}
+}
+
+// Get returns the session of the request with the given name, loaded once per request.
+func (s *serverSideStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New loads the session whose ID the cookie holds, or returns a new session if there is none, it has expired or was deleted.
+func (s *serverSideStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := s.newSession(name)
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	// A cookie that is not a signed ID, like the ones of the former cookie store, starts a new session
+	var id string
+	if s.codec.Decode(name, cookie.Value, &id) != nil {
+		return session, nil
+	}
+
+	stored, err := s.sessionStoreService.GetSession(id)
+	if status.Code(err) == codes.NotFound {
+		return session, nil
+	} else if err != nil {
+		return session, fmt.Errorf("could not get session: %w", err)
+	}
+
+	if stored.Name != name {
+		return session, nil
+	}
+
+	err = securecookie.GobEncoder{}.Deserialize(stored.Values, &session.Values)
+	if err != nil {
+		return session, fmt.Errorf("could not decode session values: %w", err)
+	}
+
+	session.ID = stored.ID
+	session.IsNew = false
+	if stored.Persistent {
+		session.Options.MaxAge = int(time.Until(stored.ExpiresAt).Seconds())
+	}
+
+	// The last request is recorded for the list of sessions, at most once a minute unless the device changes
+	now := time.Now()
+	userAgent := r.UserAgent()
+	ipAddress := clientIPAddress(r)
+	if now.Sub(stored.LastSeenAt) >= constants.SessionLastSeenIntervalSeconds*time.Second || userAgent != stored.UserAgent || ipAddress != stored.IPAddress {
+		err = s.sessionStoreService.UpdateSessionLastSeen(stored.ID, now, userAgent, ipAddress)
+		if status.Code(err) == codes.NotFound {
+			return s.newSession(name), nil
+		} else if err != nil {
+			return session, fmt.Errorf("could not update session: %w", err)
+		}
+	}
+
+	return session, nil
+}
+
+// newSession returns a session that is not stored yet.
+func (s *serverSideStore) newSession(name string) *sessions.Session {
+	session := sessions.NewSession(s, name)
+	session.Options = newSessionOptions()
+	session.IsNew = true
+
+	return session
+}
+
+// Save stores the session and writes its cookie, or deletes both if its MaxAge is negative.
+// A session deleted from the store meanwhile, e.g. signed out from another device, is not stored again.
+func (s *serverSideStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			err := s.sessionStoreService.DeleteSession(session.ID)
+			if err != nil {
+				return fmt.Errorf("could not delete session: %w", err)
+			}
+		}
+
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		err := s.create(r, session)
+		if err != nil {
+			return err
+		}
+	} else {
+		stored, err := s.update(session)
+		if err != nil {
+			return err
+		}
+
+		if !stored {
+			options := *session.Options
+			options.MaxAge = -1
+			http.SetCookie(w, sessions.NewCookie(session.Name(), "", &options))
+			return nil
+		}
+	}
+
+	encoded, err := s.codec.Encode(session.Name(), session.ID)
+	if err != nil {
+		return fmt.Errorf("could not encode session ID: %w", err)
+	}
+
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// create stores a new session under a new random ID.
+func (s *serverSideStore) create(r *http.Request, session *sessions.Session) error {
+	values, err := securecookie.GobEncoder{}.Serialize(session.Values)
+	if err != nil {
+		return fmt.Errorf("could not encode session values: %w", err)
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(constants.SessionDefaultLifetimeHours * time.Hour)
+	if expiresAtUnix, ok := session.Values[constants.SessionExpiresAtField].(int64); ok {
+		expiresAt = time.Unix(expiresAtUnix, 0)
+	}
+
+	userID, _ := session.Values[constants.SesstionOwnerIdField].(string)
+
+	err = s.sessionStoreService.SaveSession(&types.Session{
+		ID:     id,
+		Name:   session.Name(),
+		UserID: userID,
+		Values: values,
+
+		UserAgent:  r.UserAgent(),
+		IPAddress:  clientIPAddress(r),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  expiresAt,
+		Persistent: session.Options.MaxAge > 0,
+	})
+	if err != nil {
+		return fmt.Errorf("could not save session: %w", err)
+	}
+
+	session.ID = id
+	session.IsNew = false
+
+	return nil
+}
+
+// update stores the values of a stored session, it returns false if the session is not stored anymore.
+func (s *serverSideStore) update(session *sessions.Session) (bool, error) {
+	stored, err := s.sessionStoreService.GetSession(session.ID)
+	if status.Code(err) == codes.NotFound {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("could not get session: %w", err)
+	}
+
+	stored.Values, err = securecookie.GobEncoder{}.Serialize(session.Values)
+	if err != nil {
+		return false, fmt.Errorf("could not encode session values: %w", err)
+	}
+
+	if userID, ok := session.Values[constants.SesstionOwnerIdField].(string); ok {
+		stored.UserID = userID
+	}
+
+	err = s.sessionStoreService.SaveSession(stored)
+	if err != nil {
+		return false, fmt.Errorf("could not save session: %w", err)
+	}
+
+	return true, nil
+}
+
+// newSessionOptions returns the options of the session cookies, without MaxAge they are dropped when the browser closes.
+func newSessionOptions() *sessions.Options {
+	return &sessions.Options{
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,                 // Only send cookie over HTTPS
+		SameSite: http.SameSiteLaxMode, // Adjust according to your requirements
+	}
+}
+
+// newSessionID returns a random session ID, long enough not to be guessed.
+func newSessionID() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("could not generate session ID: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// clientIPAddress returns the IP address of the client of a request. Behind the Cloud Run front end it is the last
+// address of X-Forwarded-For, the one the front end appended, the ones before it are sent by the client.
+func clientIPAddress(r *http.Request) string {
+	if forwardedFor := r.Header.Get("X-Forwarded-For"); forwardedFor != "" {
+		addresses := strings.Split(forwardedFor, ",")
+		if ip := net.ParseIP(strings.TrimSpace(addresses[len(addresses)-1])); ip != nil {
+			return ip.String()
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String()
+	}
+
+	return ""
+}
@@ -0,0 +1,251 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	constants "job_sender/utils/constants"
+
+	"github.com/gorilla/securecookie"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testSessionKey signs the session IDs of the tests.
+var testSessionKey = []byte("0123456789abcdef0123456789abcdef")
+
+// loginRequest returns a request sending the cookie, without one if it is nil.
+func loginRequest(cookie *http.Cookie) *http.Request {
+	r := httptest.NewRequest("GET", "/auth/main", nil)
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	return r
+}
+
+// logIn creates a session of the user lasting until expiresAt on a request sending the cookie, and returns the cookie of the session.
+func logIn(t *testing.T, m *SessionManagerService, cookie *http.Cookie, userID string, expiresAt time.Time) *http.Cookie {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	_, err := m.CreateSession(w, loginRequest(cookie), constants.UserSessionName, expiresAt, false, map[string]interface{}{constants.SesstionOwnerIdField: userID})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("cookies = %v, want the session cookie", cookies)
+	}
+	return cookies[0]
+}
+
+// loggedUser returns the user of the session the cookie holds, empty if the session is not accepted.
+func loggedUser(t *testing.T, m *SessionManagerService, cookie *http.Cookie) string {
+	t.Helper()
+
+	userID, err := m.GetElement(loginRequest(cookie), constants.UserSessionName, constants.SesstionOwnerIdField)
+	if err != nil {
+		t.Fatalf("GetElement: %v", err)
+	}
+
+	id, _ := userID.(string)
+	return id
+}
+
+// sessionID returns the ID of the session the cookie holds, empty if the session is not accepted.
+func sessionID(t *testing.T, m *SessionManagerService, cookie *http.Cookie) string {
+	t.Helper()
+
+	id, err := m.GetSessionID(loginRequest(cookie), constants.UserSessionName)
+	if err != nil {
+		t.Fatalf("GetSessionID: %v", err)
+	}
+	return id
+}
+
+func TestSessionLoginIssuesNewID(t *testing.T) {
+	store := NewMemorySessionStoreService()
+	m := NewSessionManagerService(testSessionKey, store)
+
+	first := logIn(t, m, nil, "u1", time.Now().Add(time.Hour))
+	firstID := sessionID(t, m, first)
+	if firstID == "" || loggedUser(t, m, first) != "u1" {
+		t.Fatalf("session of the first login = %q of %q, want a session of u1", firstID, loggedUser(t, m, first))
+	}
+
+	// Logging in again on the same browser replaces the session, the ID known before the login is of no use
+	second := logIn(t, m, first, "u1", time.Now().Add(time.Hour))
+	secondID := sessionID(t, m, second)
+	if secondID == "" || secondID == firstID {
+		t.Errorf("session ID after the second login = %q, want a new one", secondID)
+	}
+
+	_, err := store.GetSession(firstID)
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("GetSession of the replaced session error = %v, want NotFound", err)
+	}
+	if got := loggedUser(t, m, first); got != "" {
+		t.Errorf("user of the replaced cookie = %q, want none", got)
+	}
+	if got := loggedUser(t, m, second); got != "u1" {
+		t.Errorf("user of the new cookie = %q, want u1", got)
+	}
+}
+
+func TestSessionRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		cookie func(t *testing.T, m *SessionManagerService) *http.Cookie
+	}{
+		{
+			name: "expired",
+			cookie: func(t *testing.T, m *SessionManagerService) *http.Cookie {
+				return logIn(t, m, nil, "u1", time.Now().Add(-time.Second))
+			},
+		},
+		{
+			name: "revoked",
+			cookie: func(t *testing.T, m *SessionManagerService) *http.Cookie {
+				cookie := logIn(t, m, nil, "u1", time.Now().Add(time.Hour))
+				if err := m.DeleteUserSession("u1", sessionID(t, m, cookie)); err != nil {
+					t.Fatalf("DeleteUserSession: %v", err)
+				}
+				return cookie
+			},
+		},
+		{
+			name: "signed out",
+			cookie: func(t *testing.T, m *SessionManagerService) *http.Cookie {
+				cookie := logIn(t, m, nil, "u1", time.Now().Add(time.Hour))
+				w := httptest.NewRecorder()
+				if err := m.DeleteSession(w, loginRequest(cookie), constants.UserSessionName); err != nil {
+					t.Fatalf("DeleteSession: %v", err)
+				}
+				return cookie
+			},
+		},
+		{
+			name: "tampered ID",
+			cookie: func(t *testing.T, m *SessionManagerService) *http.Cookie {
+				cookie := logIn(t, m, nil, "u1", time.Now().Add(time.Hour))
+				value := []byte(cookie.Value)
+				value[len(value)/2] ^= 1
+				cookie.Value = string(value)
+				return cookie
+			},
+		},
+		{
+			name: "ID signed with another key",
+			cookie: func(t *testing.T, m *SessionManagerService) *http.Cookie {
+				cookie := logIn(t, m, nil, "u1", time.Now().Add(time.Hour))
+				encoded, err := securecookie.New([]byte("another key of 32 bytes at least"), nil).Encode(constants.UserSessionName, sessionID(t, m, cookie))
+				if err != nil {
+					t.Fatalf("Encode: %v", err)
+				}
+				cookie.Value = encoded
+				return cookie
+			},
+		},
+		{
+			name: "unsigned ID",
+			cookie: func(t *testing.T, m *SessionManagerService) *http.Cookie {
+				cookie := logIn(t, m, nil, "u1", time.Now().Add(time.Hour))
+				cookie.Value = sessionID(t, m, cookie)
+				return cookie
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewSessionManagerService(testSessionKey, NewMemorySessionStoreService())
+			cookie := tt.cookie(t, m)
+
+			if id := sessionID(t, m, cookie); id != "" {
+				t.Errorf("session ID = %q, want none", id)
+			}
+			if got := loggedUser(t, m, cookie); got != "" {
+				t.Errorf("user = %q, want none", got)
+			}
+		})
+	}
+}
+
+func TestSessionRevokedNotStoredAgain(t *testing.T) {
+	store := NewMemorySessionStoreService()
+	m := NewSessionManagerService(testSessionKey, store)
+
+	cookie := logIn(t, m, nil, "u1", time.Now().Add(time.Hour))
+	r := loginRequest(cookie)
+	id := sessionID(t, m, cookie)
+
+	// The session was loaded by a request, then revoked from another device before the request saved it
+	_, err := m.GetElement(r, constants.UserSessionName, constants.SesstionOwnerIdField)
+	if err != nil {
+		t.Fatalf("GetElement: %v", err)
+	}
+	if err := m.DeleteUserSession("u1", id); err != nil {
+		t.Fatalf("DeleteUserSession: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	err = m.SetElement(w, r, constants.UserSessionName, constants.SessionEmailField, "u1@example.com")
+	if err != nil {
+		t.Fatalf("SetElement: %v", err)
+	}
+
+	_, err = store.GetSession(id)
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("GetSession error = %v, want NotFound", err)
+	}
+	if cookies := w.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("cookies = %v, want the session cookie deleted", cookies)
+	}
+}
+
+func TestSessionSignOutEverywhere(t *testing.T) {
+	m := NewSessionManagerService(testSessionKey, NewMemorySessionStoreService())
+
+	laptop := logIn(t, m, nil, "u1", time.Now().Add(time.Hour))
+	phone := logIn(t, m, nil, "u1", time.Now().Add(time.Hour))
+	other := logIn(t, m, nil, "u2", time.Now().Add(time.Hour))
+
+	sessions, err := m.GetUserSessions("u1")
+	if err != nil {
+		t.Fatalf("GetUserSessions: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("sessions of u1 = %d, want 2", len(sessions))
+	}
+
+	// Another user cannot revoke a session of u1
+	err = m.DeleteUserSession("u2", sessionID(t, m, laptop))
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("DeleteUserSession of another user error = %v, want NotFound", err)
+	}
+
+	err = m.DeleteUserSessions("u1")
+	if err != nil {
+		t.Fatalf("DeleteUserSessions: %v", err)
+	}
+
+	for name, cookie := range map[string]*http.Cookie{"laptop": laptop, "phone": phone} {
+		if got := loggedUser(t, m, cookie); got != "" {
+			t.Errorf("user of the %s = %q, want none", name, got)
+		}
+	}
+	if got := loggedUser(t, m, other); got != "u2" {
+		t.Errorf("user of the other user's session = %q, want u2", got)
+	}
+
+	sessions, err = m.GetUserSessions("u1")
+	if err != nil {
+		t.Fatalf("GetUserSessions: %v", err)
+	}
+	if len(sessions) != 0 {
+		t.Errorf("sessions of u1 = %d, want none", len(sessions))
+	}
+}
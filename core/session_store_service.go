@@ -0,0 +1,177 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SessionStoreService keeps the sessions in Firestore.
+type SessionStoreService struct {
+	collectionName string
+	client         *firestore.Client
+}
+
+// Ensure SessionStoreService implements ISessionStoreService.
+var _ interfaces.ISessionStoreService = &SessionStoreService{}
+
+// NewSessionStoreService creates a new SessionStoreService.
+func NewSessionStoreService(firebaseService *FirebaseService) (*SessionStoreService, error) {
+	ctx := context.Background()
+	client, err := firebaseService.app.Firestore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not get Firestore client: %w", err)
+	}
+
+	// Verify that we can communicate and authenticate with the Firestore service.
+	err = client.RunTransaction(ctx, func(ctx context.Context, t *firestore.Transaction) error {
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not connect: %w", err)
+	}
+
+	return &SessionStoreService{
+		collectionName: "sessions",
+		client:         client,
+	}, nil
+}
+
+// Close closes the database.
+func (db *SessionStoreService) Close() error {
+	return db.client.Close()
+}
+
+// GetSession gets a session by ID, it returns a NotFound status if there is none or it has expired.
+func (db *SessionStoreService) GetSession(id string) (*types.Session, error) {
+	ctx := context.Background()
+	doc, err := db.client.Collection(db.collectionName).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, status.Errorf(codes.NotFound, "session %s does not exist", id)
+	} else if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not get session: %w", err)
+	}
+
+	var session types.Session
+	err = doc.DataTo(&session)
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not convert data to session: %w", err)
+	}
+
+	if !time.Now().Before(session.ExpiresAt) {
+		return nil, status.Errorf(codes.NotFound, "session %s has expired", id)
+	}
+
+	return &session, nil
+}
+
+// SaveSession creates or overwrites a session.
+func (db *SessionStoreService) SaveSession(session *types.Session) error {
+	ctx := context.Background()
+	_, err := db.client.Collection(db.collectionName).Doc(session.ID).Set(ctx, session)
+	if err != nil {
+		return fmt.Errorf("firestoredb: could not save session: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateSessionLastSeen records the time, user agent and IP address of the last request of a session, its values are left as they are.
+func (db *SessionStoreService) UpdateSessionLastSeen(id string, lastSeenAt time.Time, userAgent string, ipAddress string) error {
+	ctx := context.Background()
+	_, err := db.client.Collection(db.collectionName).Doc(id).Update(ctx, []firestore.Update{
+		{Path: "last_seen_at", Value: lastSeenAt},
+		{Path: "user_agent", Value: userAgent},
+		{Path: "ip_address", Value: ipAddress},
+	})
+	if status.Code(err) == codes.NotFound {
+		return status.Errorf(codes.NotFound, "session %s does not exist", id)
+	} else if err != nil {
+		return fmt.Errorf("firestoredb: could not update session: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteSession deletes a session, deleting a missing one is not an error.
+func (db *SessionStoreService) DeleteSession(id string) error {
+	ctx := context.Background()
+	_, err := db.client.Collection(db.collectionName).Doc(id).Delete(ctx)
+	if err != nil {
+		return fmt.Errorf("firestoredb: could not delete session: %w", err)
+	}
+
+	return nil
+}
+
+// GetUserSessions gets the sessions of a user that have not expired, the last seen first.
+func (db *SessionStoreService) GetUserSessions(userID string) ([]*types.Session, error) {
+	ctx := context.Background()
+	docs, err := db.client.Collection(db.collectionName).Where("user_id", "==", userID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not get sessions: %w", err)
+	}
+
+	now := time.Now()
+	sessions := make([]*types.Session, 0, len(docs))
+	for _, doc := range docs {
+		var session types.Session
+		err = doc.DataTo(&session)
+		if err != nil {
+			return nil, fmt.Errorf("firestoredb: could not convert data to session: %w", err)
+		}
+
+		if now.Before(session.ExpiresAt) {
+			sessions = append(sessions, &session)
+		}
+	}
+
+	// Sorted here, ordering the query would need a composite index
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt) })
+
+	return sessions, nil
+}
+
+// DeleteUserSessions deletes every session of a user.
+func (db *SessionStoreService) DeleteUserSessions(userID string) error {
+	ctx := context.Background()
+	docs, err := db.client.Collection(db.collectionName).Where("user_id", "==", userID).Documents(ctx).GetAll()
+	if err != nil {
+		return fmt.Errorf("firestoredb: could not get sessions: %w", err)
+	}
+
+	for _, doc := range docs {
+		_, err = doc.Ref.Delete(ctx)
+		if err != nil {
+			return fmt.Errorf("firestoredb: could not delete session: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteExpiredSessions deletes the sessions past their expiry.
+func (db *SessionStoreService) DeleteExpiredSessions() error {
+	ctx := context.Background()
+	docs, err := db.client.Collection(db.collectionName).Where("expires_at", "<=", time.Now()).Documents(ctx).GetAll()
+	if err != nil {
+		return fmt.Errorf("firestoredb: could not get expired sessions: %w", err)
+	}
+
+	for _, doc := range docs {
+		_, err = doc.Ref.Delete(ctx)
+		if err != nil {
+			return fmt.Errorf("firestoredb: could not delete session: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -4,11 +4,21 @@ import (
 	"context"
 	"fmt"
 	"job_sender/interfaces"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
 
 	"cloud.google.com/go/storage"
 	"google.golang.org/api/iterator"
 )
 
+// storageURLFormat is the URL of an object in a bucket, it gives no access to a private object.
+const storageURLFormat = "https://storage.googleapis.com/%s/%s"
+
+// privateCacheControl lets only the browser cache a file, entries are immutable so for a day.
+const privateCacheControl = "private, max-age=86400"
+
 type StorageService struct {
 	storageBucketName string
 	storageBucket     *storage.BucketHandle
@@ -44,8 +54,8 @@ func NewStorageService(bucketName string) (*StorageService, error) {
 	}, nil
 }
 
-// UploadFile uploads a file to a storage bucket.
-func (s *StorageService) UploadFile(objectName string, data []byte, metadata map[string]string) (string, error) {
+// UploadFile uploads a private file to a storage bucket and returns its URL, it is served with the content type as a download through a signed URL.
+func (s *StorageService) UploadFile(objectName string, data []byte, contentType string, metadata map[string]string) (string, error) {
 	ctx := context.Background()
 
 	// Create a new object in the bucket
@@ -57,12 +67,15 @@ func (s *StorageService) UploadFile(objectName string, data []byte, metadata map
 		w.ObjectAttrs.Metadata = metadata
 	}
 
-	// TODO: storage.AllUsers gives public read access to anyone.
-	w.ACL = []storage.ACLRule{{Entity: storage.AllUsers, Role: storage.RoleReader}}
-	w.ContentType = "application/octet-stream"
+	// Only the project can read the object, whatever the default ACL of the bucket
+	w.PredefinedACL = "projectPrivate"
+	w.ContentType = contentType
+
+	// Browsers save the file instead of opening it
+	w.ContentDisposition = "attachment"
 
-	// Entries are immutable, be aggressive about caching (1 day).
-	w.CacheControl = "public, max-age=86400"
+	// Shared caches must not keep a private file
+	w.CacheControl = privateCacheControl
 
 	// Write the data to the object
 	_, err := w.Write(data)
@@ -76,8 +89,68 @@ func (s *StorageService) UploadFile(objectName string, data []byte, metadata map
 		return "", fmt.Errorf("could not close writer: %v", err)
 	}
 
-	const publicURL = "https://storage.googleapis.com/%s/%s"
-	return fmt.Sprintf(publicURL, s.storageBucketName, objectName), nil
+	return fmt.Sprintf(storageURLFormat, s.storageBucketName, objectName), nil
+}
+
+// SignedURL returns a URL that downloads the file at fileURL, as returned by UploadFile, until it expires.
+// Without a key file the URL is signed by the IAM credentials API as the service account of the app.
+func (s *StorageService) SignedURL(fileURL string, expiry time.Duration) (string, error) {
+	objectName, ok := strings.CutPrefix(fileURL, fmt.Sprintf(storageURLFormat, s.storageBucketName, ""))
+	if !ok || objectName == "" {
+		return "", fmt.Errorf("%s is not a file of bucket %s", fileURL, s.storageBucketName)
+	}
+
+	signedURL, err := s.storageBucket.SignedURL(objectName, &storage.SignedURLOptions{
+		Scheme:  storage.SigningSchemeV4,
+		Method:  "GET",
+		Expires: time.Now().Add(expiry),
+
+		// The files uploaded before they were served as downloads are downloaded too
+		QueryParameters: url.Values{"response-content-disposition": {"attachment"}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not sign URL of object %s: %v", objectName, err)
+	}
+
+	return signedURL, nil
+}
+
+// MakeFilesPrivate removes the public read access from the files with the given prefix name, it returns how many files were public.
+func (s *StorageService) MakeFilesPrivate(prefixName string) (int, error) {
+	ctx := context.Background()
+
+	// The ACLs are only listed with the full projection
+	query := &storage.Query{Prefix: prefixName, Projection: storage.ProjectionFull}
+	it := s.storageBucket.Objects(ctx, query)
+
+	public := 0
+	for {
+		objAttrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return public, fmt.Errorf("could not get next object: %v", err)
+		}
+
+		if !slices.ContainsFunc(objAttrs.ACL, func(rule storage.ACLRule) bool { return rule.Entity == storage.AllUsers }) {
+			continue
+		}
+
+		obj := s.storageBucket.Object(objAttrs.Name)
+		err = obj.ACL().Delete(ctx, storage.AllUsers)
+		if err != nil {
+			return public, fmt.Errorf("could not make object %s private: %v", objAttrs.Name, err)
+		}
+
+		_, err = obj.Update(ctx, storage.ObjectAttrsToUpdate{CacheControl: privateCacheControl})
+		if err != nil {
+			return public, fmt.Errorf("could not update cache control of object %s: %v", objAttrs.Name, err)
+		}
+		public++
+	}
+
+	return public, nil
 }
 
 // DeleteFiles deletes files with the given prefix name.
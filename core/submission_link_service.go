@@ -0,0 +1,72 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SubmissionLinkService signs submission links with HMAC-SHA256. A token is the base64 encoded link followed by its signature,
+// it is single-use as the nonce it carries is replaced on the contractor's request once a timesheet is submitted.
+type SubmissionLinkService struct {
+	signer *tokenSigner
+	appURL string
+}
+
+// Ensure SubmissionLinkService implements ISubmissionLinkService.
+var _ interfaces.ISubmissionLinkService = &SubmissionLinkService{}
+
+// NewSubmissionLinkService creates a new SubmissionLinkService that signs with the key the links to the app at appURL.
+func NewSubmissionLinkService(key []byte, appURL string) *SubmissionLinkService {
+	return &SubmissionLinkService{
+		signer: newTokenSigner(key, tokenPurposeSubmission),
+		appURL: appURL,
+	}
+}
+
+// CreateSubmissionLink returns a signed link to the submission page of a contractor's request, with the nonce that has to be stored on the request for the link to be valid.
+func (s *SubmissionLinkService) CreateSubmissionLink(contractorID string, requestID string) (string, string, error) {
+	nonce := make([]byte, 16)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", "", fmt.Errorf("could not create nonce: %w", err)
+	}
+
+	link := &types.SubmissionLink{
+		ContractorID: contractorID,
+		RequestID:    requestID,
+
+		Nonce:     hex.EncodeToString(nonce),
+		ExpiresAt: time.Now().AddDate(0, 0, constants.SubmissionLinkLifetimeDays).Unix(),
+	}
+
+	token, err := s.signer.createToken(link)
+	if err != nil {
+		return "", "", err
+	}
+
+	return s.appURL + constants.SubmissionLinkPath + token, link.Nonce, nil
+}
+
+// ParseSubmissionToken verifies the token of a submission link, it returns a PermissionDenied status if it is forged or expired.
+func (s *SubmissionLinkService) ParseSubmissionToken(token string) (*types.SubmissionLink, error) {
+	var link types.SubmissionLink
+	err := s.signer.parseToken(token, &link)
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Now().Unix() > link.ExpiresAt {
+		return nil, status.Errorf(codes.PermissionDenied, "submission token expired")
+	}
+
+	return &link, nil
+}
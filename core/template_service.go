@@ -12,22 +12,26 @@ import (
 	constants "job_sender/utils/constants"
 )
 
-type TemplateService struct{}
+type TemplateService struct {
+	templatesDir string
+}
 
 // Ensure TemplateService implements the ITemplateService interface.
 var _ interfaces.ITemplateService = &TemplateService{}
 
-// NewTemplateService creates a new TemplateService.
-func NewTemplateService() *TemplateService {
-	return &TemplateService{}
+// NewTemplateService creates a new TemplateService that reads the templates from the given directory.
+func NewTemplateService(templatesDir string) *TemplateService {
+	return &TemplateService{
+		templatesDir: templatesDir,
+	}
 }
 
 // ParseTemplate creates a template that applies a given file to the body of the base template.
 func (s *TemplateService) ParseTemplate(filename string) (*types.AppTemplate, error) {
-	tmpl := template.Must(template.ParseFiles(fmt.Sprintf("%s/%s", constants.TemplatesDir, constants.TemplatesBaseName)))
+	tmpl := template.Must(template.ParseFiles(filepath.Join(s.templatesDir, constants.TemplatesBaseName)))
 
 	// Put the named file into a template called "body"
-	path := filepath.Join(constants.TemplatesDir, filename)
+	path := filepath.Join(s.templatesDir, filename)
 	b, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("could not app template: %w", err)
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+
+	"cloud.google.com/go/firestore"
+)
+
+// TimesheetAuditLogDatabaseService is a service for recording the status changes of timesheets in a database.
+// It has no way to update or delete entries, so the log only grows.
+type TimesheetAuditLogDatabaseService struct {
+	collectionName string
+	client         *firestore.Client
+}
+
+// Ensure TimesheetAuditLogDatabaseService implements ITimesheetAuditLogDatabaseService.
+var _ interfaces.ITimesheetAuditLogDatabaseService = &TimesheetAuditLogDatabaseService{}
+
+// NewTimesheetAuditLogDatabaseService creates a new TimesheetAuditLogDatabaseService.
+func NewTimesheetAuditLogDatabaseService(firebaseService *FirebaseService) (*TimesheetAuditLogDatabaseService, error) {
+	ctx := context.Background()
+	client, err := firebaseService.app.Firestore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not get Firestore client: %w", err)
+	}
+
+	// Verify that we can communicate and authenticate with the Firestore service.
+	err = client.RunTransaction(ctx, func(ctx context.Context, t *firestore.Transaction) error {
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not connect: %w", err)
+	}
+
+	return &TimesheetAuditLogDatabaseService{
+		collectionName: "timesheet_audit_log",
+		client:         client,
+	}, nil
+}
+
+// Close closes the database.
+func (db *TimesheetAuditLogDatabaseService) Close() error {
+	return db.client.Close()
+}
+
+// ListAuditEntries lists the audit entries of a timesheet from the oldest.
+func (db *TimesheetAuditLogDatabaseService) ListAuditEntries(timesheetID string) ([]*types.TimesheetAuditEntry, error) {
+	ctx := context.Background()
+	docs, err := db.client.Collection(db.collectionName).Where("timesheet_id", "==", timesheetID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not list audit entries: %w", err)
+	}
+
+	entries := make([]*types.TimesheetAuditEntry, 0, len(docs))
+	for _, doc := range docs {
+		var entry types.TimesheetAuditEntry
+		err = doc.DataTo(&entry)
+		if err != nil {
+			return nil, fmt.Errorf("firestoredb: could not convert data to audit entry: %w", err)
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	sortAuditEntries(entries)
+
+	return entries, nil
+}
+
+// AddAuditEntry adds an audit entry.
+func (db *TimesheetAuditLogDatabaseService) AddAuditEntry(entry *types.TimesheetAuditEntry) error {
+	ctx := context.Background()
+	ref := db.client.Collection(db.collectionName).NewDoc()
+	entry.ID = ref.ID
+
+	_, err := ref.Create(ctx, entry)
+	if err != nil {
+		return fmt.Errorf("firestoredb: could not add audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// sortAuditEntries sorts the audit entries from the oldest.
+func sortAuditEntries(entries []*types.TimesheetAuditEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp < entries[j].Timestamp
+	})
+}
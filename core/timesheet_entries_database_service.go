@@ -0,0 +1,120 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+
+	"cloud.google.com/go/firestore"
+)
+
+// TimesheetEntriesDatabaseService is a service for managing timesheet entries in a database.
+type TimesheetEntriesDatabaseService struct {
+	collectionName string
+	client         *firestore.Client
+}
+
+// Ensure TimesheetEntriesDatabaseService implements ITimesheetEntriesDatabaseService.
+var _ interfaces.ITimesheetEntriesDatabaseService = &TimesheetEntriesDatabaseService{}
+
+// NewTimesheetEntriesDatabaseService creates a new TimesheetEntriesDatabaseService.
+func NewTimesheetEntriesDatabaseService(firebaseService *FirebaseService) (*TimesheetEntriesDatabaseService, error) {
+	ctx := context.Background()
+	client, err := firebaseService.app.Firestore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not get Firestore client: %w", err)
+	}
+
+	// Verify that we can communicate and authenticate with the Firestore service.
+	err = client.RunTransaction(ctx, func(ctx context.Context, t *firestore.Transaction) error {
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not connect: %w", err)
+	}
+
+	return &TimesheetEntriesDatabaseService{
+		collectionName: "timesheet_entries",
+		client:         client,
+	}, nil
+}
+
+// Close closes the database.
+func (db *TimesheetEntriesDatabaseService) Close() error {
+	return db.client.Close()
+}
+
+// ListTimesheetEntries lists all entries of a timesheet ordered by date.
+func (db *TimesheetEntriesDatabaseService) ListTimesheetEntries(timesheetID string) ([]*types.TimesheetEntry, error) {
+	ctx := context.Background()
+	docs, err := db.client.Collection(db.collectionName).Where("timesheet_id", "==", timesheetID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not list timesheet entries: %w", err)
+	}
+
+	entries := make([]*types.TimesheetEntry, 0, len(docs))
+	for _, doc := range docs {
+		var entry types.TimesheetEntry
+		err = doc.DataTo(&entry)
+		if err != nil {
+			return nil, fmt.Errorf("firestoredb: could not convert data to timesheet entry: %w", err)
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	sortTimesheetEntries(entries)
+
+	return entries, nil
+}
+
+// AddTimesheetEntries adds the entries of a timesheet in a single transaction.
+func (db *TimesheetEntriesDatabaseService) AddTimesheetEntries(entries []*types.TimesheetEntry) error {
+	ctx := context.Background()
+	err := db.client.RunTransaction(ctx, func(ctx context.Context, t *firestore.Transaction) error {
+		for _, entry := range entries {
+			ref := db.client.Collection(db.collectionName).NewDoc()
+			entry.ID = ref.ID
+
+			err := t.Create(ref, entry)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("firestoredb: could not add timesheet entries: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteTimesheetEntries deletes all entries of a timesheet.
+func (db *TimesheetEntriesDatabaseService) DeleteTimesheetEntries(timesheetID string) error {
+	ctx := context.Background()
+	docs, err := db.client.Collection(db.collectionName).Where("timesheet_id", "==", timesheetID).Documents(ctx).GetAll()
+	if err != nil {
+		return fmt.Errorf("firestoredb: could not list timesheet entries: %w", err)
+	}
+
+	for _, doc := range docs {
+		_, err = doc.Ref.Delete(ctx)
+		if err != nil {
+			return fmt.Errorf("firestoredb: could not delete timesheet entry: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// sortTimesheetEntries sorts the entries by date, keeping the order of the file for entries of the same day.
+func sortTimesheetEntries(entries []*types.TimesheetEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Date < entries[j].Date
+	})
+}
@@ -0,0 +1,637 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// timesheetSubjectPattern matches the subject of a request email and of the replies to it in every language the emails are sent in,
+// e.g. "Re: Timesheet 2025-01-06_2025-01-19 [contractorID]" or "Re: Karta czasu pracy 2025-01-06_2025-01-19 [contractorID] ACME", the group's subject suffix may follow.
+var timesheetSubjectPattern = regexp.MustCompile(`(?:Timesheet|Karta czasu pracy) (.+?) \[([^\[\]]+)\]`)
+
+// timesheetIngestionAttempts is how many times saving a timesheet is tried when another submission of the request is saved at the same time.
+const timesheetIngestionAttempts = 3
+
+// TimesheetIngestionService is a service for saving the timesheets contractors send.
+type TimesheetIngestionService struct {
+	storageService              interfaces.IStorageService
+	emailService                interfaces.IEmailService
+	attachmentValidationService interfaces.IAttachmentValidationService
+	timesheetParserService      interfaces.ITimesheetParserService
+
+	groupsDB              interfaces.IGroupsDatabaseService
+	contractorsDB         interfaces.IContractorsDatabaseService
+	timesheetsDB          interfaces.ITimesheetsDatabaseService
+	timesheetRequestsDB   interfaces.ITimesheetRequestsDatabaseService
+	timesheetVersionsDB   interfaces.ITimesheetVersionsDatabaseService
+	timesheetIngestionsDB interfaces.ITimesheetIngestionsDatabaseService
+	unassignedEmailsDB    interfaces.IUnassignedEmailsDatabaseService
+}
+
+// Ensure TimesheetIngestionService implements ITimesheetIngestionService.
+var _ interfaces.ITimesheetIngestionService = &TimesheetIngestionService{}
+
+// NewTimesheetIngestionService creates a new TimesheetIngestionService.
+func NewTimesheetIngestionService(storageService interfaces.IStorageService, emailService interfaces.IEmailService, attachmentValidationService interfaces.IAttachmentValidationService, timesheetParserService interfaces.ITimesheetParserService, groupsDB interfaces.IGroupsDatabaseService, contractorsDB interfaces.IContractorsDatabaseService, timesheetsDB interfaces.ITimesheetsDatabaseService, timesheetRequestsDB interfaces.ITimesheetRequestsDatabaseService, timesheetVersionsDB interfaces.ITimesheetVersionsDatabaseService, timesheetIngestionsDB interfaces.ITimesheetIngestionsDatabaseService, unassignedEmailsDB interfaces.IUnassignedEmailsDatabaseService) *TimesheetIngestionService {
+	return &TimesheetIngestionService{
+		storageService:              storageService,
+		emailService:                emailService,
+		attachmentValidationService: attachmentValidationService,
+		timesheetParserService:      timesheetParserService,
+
+		groupsDB:              groupsDB,
+		contractorsDB:         contractorsDB,
+		timesheetsDB:          timesheetsDB,
+		timesheetRequestsDB:   timesheetRequestsDB,
+		timesheetVersionsDB:   timesheetVersionsDB,
+		timesheetIngestionsDB: timesheetIngestionsDB,
+		unassignedEmailsDB:    unassignedEmailsDB,
+	}
+}
+
+// IngestEmail saves the attachment of a reply to a timesheet request as the timesheet of the request, an email is saved only once.
+// An email without exactly one attachment is not ingested, it is not known which file is the timesheet, nor is one for an approved timesheet.
+// The contractor is told by a reply why a file the group does not accept is rejected.
+func (s *TimesheetIngestionService) IngestEmail(email *types.InboundEmail) error {
+	// An email saved before is done, even if its request changed since
+	_, err := s.timesheetIngestionsDB.GetTimesheetIngestion(hashHex([]byte(emailIngestionKey(email))))
+	if err == nil {
+		return nil
+	} else if status.Code(err) != codes.NotFound {
+		return fmt.Errorf("failed to get timesheet ingestion: %w", err)
+	}
+
+	contractor, requestID, err := s.MatchEmail(email)
+	if status.Code(err) == codes.PermissionDenied {
+		// Left for the owner, who can tell whether the contractor sent it from another address
+		return status.Error(codes.InvalidArgument, status.Convert(err).Message())
+	} else if err != nil {
+		return err
+	}
+
+	if len(email.Attachments) != 1 {
+		return status.Errorf(codes.InvalidArgument, "email %q has %d attachments", email.Subject, len(email.Attachments))
+	}
+
+	_, err = s.SaveEmailTimesheet(email, contractor, requestID, 0)
+
+	var rejection *types.FileRejection
+	if errors.As(err, &rejection) {
+		// The email is left for the owner either way, a failed reply is not retried
+		replyErr := s.replyFileRejected(contractor, requestID, rejection)
+		if replyErr != nil {
+			log.Printf("failed to tell contractor %s why file %s was rejected: %v", contractor.ID, rejection.Filename, replyErr)
+		}
+		return status.Errorf(codes.InvalidArgument, "email %q: %v", email.Subject, err)
+	} else if status.Code(err) == codes.FailedPrecondition {
+		return status.Errorf(codes.InvalidArgument, "email %q: %v", email.Subject, err)
+	}
+
+	return err
+}
+
+// replyFileRejected sends the contractor the email telling why a timesheet file was rejected, in the contractor's language.
+func (s *TimesheetIngestionService) replyFileRejected(contractor *types.Contractor, requestID string, rejection *types.FileRejection) error {
+	group, err := s.groupsDB.GetGroup(contractor.GroupID)
+	if err != nil {
+		return fmt.Errorf("failed to get group: %w", err)
+	}
+
+	request, err := s.timesheetRequestsDB.GetTimesheetRequest(contractor.ID, requestID)
+	if err != nil {
+		return fmt.Errorf("failed to get timesheet request: %w", err)
+	}
+
+	return s.emailService.SendFileRejectionEmail(group, contractor, request, rejection)
+}
+
+// UnassignEmail leaves an email that could not be ingested for the owner of the group it is attributed to, saving its triage, and reports whether
+// it did. Nobody could triage an email attributed to no group, its sender is told by a reply to answer the request email instead, unless a program sent it.
+func (s *TimesheetIngestionService) UnassignEmail(email *types.InboundEmail) (bool, error) {
+	// An email triaged before is left as it was, e.g. one saved but not moved
+	_, err := s.unassignedEmailsDB.GetUnassignedEmail(hashHex([]byte(emailIngestionKey(email))))
+	if err == nil {
+		return true, nil
+	} else if status.Code(err) != codes.NotFound {
+		return false, fmt.Errorf("failed to get unassigned email: %w", err)
+	}
+
+	unassigned, err := s.TriageEmail(email)
+	if err != nil {
+		return false, err
+	}
+
+	if unassigned.GroupID == "" {
+		if email.From == "" || email.AutoSubmitted {
+			return false, nil
+		}
+
+		err = s.emailService.SendUnknownSenderEmail(email)
+		if err != nil {
+			return false, fmt.Errorf("failed to reply to unknown sender: %w", err)
+		}
+		return false, nil
+	}
+
+	unassigned.UnassignedAt = time.Now()
+	err = s.unassignedEmailsDB.SetUnassignedEmail(unassigned)
+	if err != nil {
+		return false, fmt.Errorf("failed to save unassigned email: %w", err)
+	}
+
+	return true, nil
+}
+
+// TriageEmail tells why an email could not be ingested, with the group, the contractor who likely sent it and the request it matches, for the owner to assign it.
+// An email of a contractor of several groups could be for any of their owners, it is attributed to none of them like the emails of unknown senders.
+func (s *TimesheetIngestionService) TriageEmail(email *types.InboundEmail) (*types.UnassignedEmail, error) {
+	unassigned := &types.UnassignedEmail{
+		ID:        hashHex([]byte(emailIngestionKey(email))),
+		MessageID: email.MessageID,
+		From:      email.From,
+		Subject:   email.Subject,
+		Date:      email.Date,
+	}
+	for _, attachment := range email.Attachments {
+		unassigned.Attachments = append(unassigned.Attachments, attachment.Filename)
+	}
+
+	contractor, requestID, err := s.MatchEmail(email)
+	if status.Code(err) == codes.NotFound {
+		unassigned.Reason = constants.UnknownSender
+		if email.From == "" {
+			return unassigned, nil
+		}
+
+		contractors, err := s.contractorsDB.GetContractorsByEmail(email.From)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get contractors: %w", err)
+		}
+
+		if len(contractors) > 0 && !slices.ContainsFunc(contractors, func(c *types.Contractor) bool { return c.GroupID != contractors[0].GroupID }) {
+			unassigned.Reason = constants.WrongPeriod
+			unassigned.GroupID = contractors[0].GroupID
+			unassigned.ContractorID = contractors[0].ID
+		}
+
+		return unassigned, nil
+	} else if status.Code(err) == codes.PermissionDenied {
+		unassigned.Reason = constants.OtherSender
+		unassigned.GroupID = contractor.GroupID
+		unassigned.ContractorID = contractor.ID
+		unassigned.RequestID = requestID
+		return unassigned, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	unassigned.GroupID = contractor.GroupID
+	unassigned.ContractorID = contractor.ID
+	unassigned.RequestID = requestID
+
+	switch len(email.Attachments) {
+	case 0:
+		unassigned.Reason = constants.NoAttachment
+	case 1:
+		unassigned.Reason = constants.Matched
+
+		// Tell the owner if the file is one the group does not accept, a scanner that fails leaves it matched
+		group, err := s.groupsDB.GetGroup(contractor.GroupID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get group: %w", err)
+		}
+
+		policy := group.AttachmentPolicy
+		_, err = s.attachmentValidationService.ValidateAttachment(&policy, email.Attachments[0])
+
+		var rejection *types.FileRejection
+		if errors.As(err, &rejection) {
+			unassigned.Reason = constants.RejectedFile
+			unassigned.Rejection = rejection
+		}
+	default:
+		unassigned.Reason = constants.MultipleAttachments
+	}
+
+	return unassigned, nil
+}
+
+// MatchEmail finds the contractor's request an email replies to. It tries the reply keys in the Message-IDs of the emails it replies to and in the
+// plus address it was sent to, the request in the subject, and at last the request waiting for a timesheet of the contractor who sent it.
+// A contractor who edits the subject or forwards the email is still matched by one of them. An email matched by its headers, its address or
+// its subject but sent from another address than the contractor's returns the contractor and the request with a PermissionDenied status,
+// the headers of a reply are known to anyone the email was forwarded to.
+func (s *TimesheetIngestionService) MatchEmail(email *types.InboundEmail) (*types.Contractor, string, error) {
+	var replyKeys []string
+
+	// The email it replies to comes first, then the thread from the newest email
+	for _, messageID := range email.InReplyTo {
+		if replyKey, ok := replyKeyOfMessageID(messageID); ok {
+			replyKeys = append(replyKeys, replyKey)
+		}
+	}
+	for i := len(email.References) - 1; i >= 0; i-- {
+		if replyKey, ok := replyKeyOfMessageID(email.References[i]); ok {
+			replyKeys = append(replyKeys, replyKey)
+		}
+	}
+
+	for _, address := range email.To {
+		if replyKey, ok := replyKeyOfAddress(address); ok {
+			replyKeys = append(replyKeys, replyKey)
+		}
+	}
+
+	for _, replyKey := range replyKeys {
+		request, err := s.timesheetRequestsDB.GetTimesheetRequestByReplyKey(replyKey)
+		if status.Code(err) == codes.NotFound {
+			continue
+		} else if err != nil {
+			return nil, "", fmt.Errorf("failed to get timesheet request: %w", err)
+		}
+
+		contractor, ok, err := s.matchContractor(email, request.ContractorID)
+		if err != nil {
+			return contractor, request.RequestID, err
+		} else if ok {
+			return contractor, request.RequestID, nil
+		}
+	}
+
+	// The replies to the emails sent before the requests had reply keys are matched by their subject
+	if match := timesheetSubjectPattern.FindStringSubmatch(email.Subject); match != nil {
+		requestID := requestIDFromSubject(match[1])
+
+		_, err := s.timesheetRequestsDB.GetTimesheetRequest(match[2], requestID)
+		if err == nil {
+			contractor, ok, err := s.matchContractor(email, match[2])
+			if err != nil {
+				return contractor, requestID, err
+			} else if ok {
+				return contractor, requestID, nil
+			}
+		} else if status.Code(err) != codes.NotFound {
+			return nil, "", fmt.Errorf("failed to get timesheet request: %w", err)
+		}
+	}
+
+	if email.From == "" {
+		return nil, "", status.Errorf(codes.NotFound, "email %q matches no request", email.Subject)
+	}
+
+	// Fall back to the sender, if only one request of the contractors with the address is waiting for a timesheet
+	contractors, err := s.contractorsDB.GetContractorsByEmail(email.From)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get contractors: %w", err)
+	}
+
+	var openContractor *types.Contractor
+	var openRequestID string
+	openRequests := 0
+	for _, contractor := range contractors {
+		requests, err := s.timesheetRequestsDB.ListContractorTimesheetRequests(contractor.ID)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to list timesheet requests: %w", err)
+		}
+
+		for _, request := range requests {
+			if request.Status == constants.Pending {
+				openContractor, openRequestID = contractor, request.RequestID
+				openRequests++
+			}
+		}
+	}
+
+	if openRequests != 1 {
+		return nil, "", status.Errorf(codes.NotFound, "email %q from %s matches no request, the sender has %d open requests", email.Subject, email.From, openRequests)
+	}
+
+	return openContractor, openRequestID, nil
+}
+
+// matchContractor gets the contractor of a request an email matches, it reports false if the contractor no longer exists.
+// It returns the contractor with a PermissionDenied status if the email was sent from another address than the contractor's.
+func (s *TimesheetIngestionService) matchContractor(email *types.InboundEmail, contractorID string) (*types.Contractor, bool, error) {
+	contractor, err := s.contractorsDB.GetContractor(contractorID)
+	if status.Code(err) == codes.NotFound {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, fmt.Errorf("failed to get contractor: %w", err)
+	}
+
+	if !strings.EqualFold(email.From, contractor.Email) {
+		return contractor, false, status.Errorf(codes.PermissionDenied, "email %q from %s matches a request of contractor %s, who has another address", email.Subject, email.From, contractor.ID)
+	}
+
+	return contractor, true, nil
+}
+
+// SaveEmailTimesheet saves an attachment of an email as the timesheet of a contractor's request, an email is saved only once.
+func (s *TimesheetIngestionService) SaveEmailTimesheet(email *types.InboundEmail, contractor *types.Contractor, requestID string, attachment int) (*types.TimesheetIngestion, error) {
+	if attachment < 0 || attachment >= len(email.Attachments) {
+		return nil, status.Errorf(codes.InvalidArgument, "email %q has no attachment %d", email.Subject, attachment)
+	}
+
+	return s.SaveTimesheet(emailIngestionKey(email), contractor, requestID, email.Attachments[attachment])
+}
+
+// SaveTimesheet uploads a timesheet file of a contractor's request to the storage and saves it with its entries, marking the request collected, in one transaction.
+// A file sent after a rejection resubmits the rejected timesheet, and any other timesheet not approved yet is replaced, each file becoming the next version.
+// The current file sent again is a duplicate, it is only noted in the audit log. The key identifies the submission, a submission already saved is not saved
+// again, and the file is stored under a name derived from its content so a retry after a failure reuses it. A file the group does not accept
+// is not stored, it returns a *types.FileRejection error.
+func (s *TimesheetIngestionService) SaveTimesheet(key string, contractor *types.Contractor, requestID string, attachment types.Attachment) (*types.TimesheetIngestion, error) {
+	ingestionID := hashHex([]byte(key))
+
+	// Skip the submissions already saved, e.g. an email saved but not archived
+	ingestion, err := s.timesheetIngestionsDB.GetTimesheetIngestion(ingestionID)
+	if err == nil {
+		return ingestion, nil
+	} else if status.Code(err) != codes.NotFound {
+		return nil, fmt.Errorf("failed to get timesheet ingestion: %w", err)
+	}
+
+	// Only the files the group accepts are stored, under the type sniffed from their content
+	group, err := s.groupsDB.GetGroup(contractor.GroupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+
+	fileType, err := s.attachmentValidationService.ValidateAttachment(&group.AttachmentPolicy, attachment)
+	if err != nil {
+		return nil, err
+	}
+
+	// The current file sent again is not uploaded nor parsed
+	contentHash := hashHex(attachment.Content)
+	existing, err := s.timesheetsDB.GetTimesheet(contractor.ID, requestID)
+	if err == nil && existing.ContentHash == contentHash {
+		return s.commitDuplicateTimesheet(ingestionID, key, contractor, existing)
+	} else if err != nil && status.Code(err) != codes.NotFound {
+		return nil, fmt.Errorf("failed to get timesheet: %w", err)
+	}
+
+	// Save the timesheet to the storage
+	objectDir := fmt.Sprintf("%s/%s/%s/%s/", contractor.GroupID, contractor.ID, requestID, contentHash)
+	objectName := fmt.Sprintf("%s%s-%s_%s%s", objectDir, contractor.Name, contractor.Surname, requestID, fileType.Extension())
+
+	metadata := map[string]string{
+		"RequestID":    requestID,
+		"ContractorID": contractor.ID,
+		"ContentHash":  contentHash,
+	}
+
+	timesheetUrl, err := s.storageService.UploadFile(objectName, attachment.Content, fileType.ContentType(), metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload timesheet to storage: %w", err)
+	}
+
+	// Parse the worked days, a timesheet that cannot be parsed is still kept for the owner to download
+	entries, err := s.timesheetParserService.ParseTimesheet(attachment.Filename, attachment.Content)
+	if err != nil {
+		log.Printf("failed to parse timesheet %s of contractor %s: %v", attachment.Filename, contractor.ID, err)
+	}
+
+	version := &types.TimesheetVersion{
+		ContractorID: contractor.ID,
+		GroupID:      contractor.GroupID,
+		RequestID:    requestID,
+
+		StorageURL:  timesheetUrl,
+		Filename:    attachment.Filename,
+		ContentHash: contentHash,
+
+		DailyHours: map[string]float64{},
+	}
+
+	for _, entry := range entries {
+		entry.ContractorID = contractor.ID
+		entry.GroupID = contractor.GroupID
+		entry.RequestID = requestID
+
+		version.TotalHours += entry.Hours
+		version.DailyHours[entry.Date] += entry.Hours
+	}
+	version.TotalHours = math.Round(version.TotalHours*100) / 100
+
+	// A timesheet added or revised by another submission meanwhile aborts the commit, it is then retried with that timesheet
+	for attempt := 1; ; attempt++ {
+		ingestion, err = s.commitTimesheet(ingestionID, key, contractor, objectName, version, entries)
+		if status.Code(err) != codes.Aborted || attempt == timesheetIngestionAttempts {
+			break
+		}
+	}
+
+	switch status.Code(err) {
+	case codes.OK:
+		return ingestion, nil
+	case codes.AlreadyExists:
+		return s.timesheetIngestionsDB.GetTimesheetIngestion(ingestionID)
+	case codes.FailedPrecondition:
+		// The file of an approved timesheet is never saved, unless one of its versions is the same file
+		versions, listErr := s.listTimesheetVersions(contractor.ID, requestID)
+		if listErr == nil && !slices.ContainsFunc(versions, func(v *types.TimesheetVersion) bool { return v.ContentHash == contentHash }) {
+			deleteErr := s.storageService.DeleteFiles(objectDir)
+			if deleteErr != nil {
+				log.Printf("failed to delete timesheet %s from storage: %v", objectName, deleteErr)
+			}
+		}
+		return nil, err
+	default:
+		return nil, err
+	}
+}
+
+// commitTimesheet saves a file uploaded to the storage as the next version of the timesheet, with its entries, the audit entry, the collected request and the ingestion in one transaction.
+func (s *TimesheetIngestionService) commitTimesheet(ingestionID string, key string, contractor *types.Contractor, objectName string, version *types.TimesheetVersion, entries []*types.TimesheetEntry) (*types.TimesheetIngestion, error) {
+	request, err := s.timesheetRequestsDB.GetTimesheetRequest(contractor.ID, version.RequestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get timesheet request: %w", err)
+	}
+
+	timesheet, err := s.timesheetsDB.GetTimesheet(contractor.ID, version.RequestID)
+	if status.Code(err) == codes.NotFound {
+		timesheet = &types.Timesheet{
+			ContractorID: contractor.ID,
+			GroupID:      contractor.GroupID,
+			RequestID:    version.RequestID,
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get timesheet: %w", err)
+	}
+
+	// The same file may have been saved by another submission meanwhile
+	if timesheet.ID != "" && timesheet.ContentHash == version.ContentHash {
+		return s.commitDuplicateTimesheet(ingestionID, key, contractor, timesheet)
+	}
+
+	var versions []*types.TimesheetVersion
+	if timesheet.ID != "" && timesheet.Version == 0 {
+		// The file saved before versions existed becomes the first version
+		versions = append(versions, &types.TimesheetVersion{
+			ContractorID: contractor.ID,
+			GroupID:      contractor.GroupID,
+			RequestID:    version.RequestID,
+
+			Version:    1,
+			StorageURL: timesheet.StorageURL,
+			Filename:   path.Base(timesheet.StorageURL),
+			TotalHours: timesheet.TotalHours,
+		})
+		timesheet.Version = 1
+	}
+
+	version.Version = timesheet.Version + 1
+	version.SubmittedAt = time.Now()
+	versions = append(versions, version)
+
+	auditEntry := &types.TimesheetAuditEntry{
+		GroupID: contractor.GroupID,
+
+		Actor:     contractor.Email,
+		Status:    constants.Received,
+		Reason:    fmt.Sprintf("Version %d", version.Version),
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	// The status is worked out from the timesheet as stored when the submission is committed, a review meanwhile is not lost
+	if timesheet.ID != "" && timesheet.Status == constants.Approved {
+		return nil, status.Errorf(codes.FailedPrecondition, "timesheet %s is already approved", timesheet.ID)
+	}
+
+	timesheet.StorageURL = version.StorageURL
+	timesheet.Version = version.Version
+	timesheet.ContentHash = version.ContentHash
+	timesheet.TotalHours = version.TotalHours
+
+	ingestion := &types.TimesheetIngestion{
+		ID:           ingestionID,
+		Key:          key,
+		ContractorID: contractor.ID,
+		GroupID:      contractor.GroupID,
+		RequestID:    version.RequestID,
+
+		ObjectName:  objectName,
+		ContentHash: version.ContentHash,
+		Version:     version.Version,
+
+		IngestedAt: time.Now(),
+	}
+
+	err = s.timesheetIngestionsDB.CommitTimesheetIngestion(ingestion, timesheet, versions, entries, auditEntry, request.ID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to save timesheet: %w", err)
+	}
+
+	return ingestion, nil
+}
+
+// nextTimesheetVersion works out the status of a stored timesheet getting a new version, and the audit entry noting it.
+// A rejected timesheet is resubmitted, other statuses are kept, and an approved timesheet is never replaced.
+func nextTimesheetVersion(stored *types.Timesheet, timesheet *types.Timesheet, auditEntry *types.TimesheetAuditEntry) error {
+	if stored.Status == constants.Approved {
+		return status.Errorf(codes.FailedPrecondition, "timesheet %s is already approved", stored.ID)
+	}
+
+	if slices.Contains(timesheetTransitions[stored.Status], constants.Resubmitted) {
+		timesheet.Status = constants.Resubmitted
+		timesheet.RejectionReason = ""
+		auditEntry.Status = constants.Resubmitted
+		return nil
+	}
+
+	timesheet.Status = stored.Status
+	timesheet.RejectionReason = stored.RejectionReason
+	auditEntry.Status = stored.Status
+	auditEntry.Reason = fmt.Sprintf("Version %d replaced the file", timesheet.Version)
+	return nil
+}
+
+// commitDuplicateTimesheet saves a submission of the current file of a timesheet, it is ignored but for a note in the audit log, even if the timesheet is approved.
+func (s *TimesheetIngestionService) commitDuplicateTimesheet(ingestionID string, key string, contractor *types.Contractor, timesheet *types.Timesheet) (*types.TimesheetIngestion, error) {
+	ingestion := &types.TimesheetIngestion{
+		ID:           ingestionID,
+		Key:          key,
+		ContractorID: contractor.ID,
+		GroupID:      contractor.GroupID,
+		RequestID:    timesheet.RequestID,
+		TimesheetID:  timesheet.ID,
+
+		ContentHash: timesheet.ContentHash,
+		Version:     timesheet.Version,
+		Duplicate:   true,
+
+		IngestedAt: time.Now(),
+	}
+
+	auditEntry := &types.TimesheetAuditEntry{
+		TimesheetID: timesheet.ID,
+		GroupID:     contractor.GroupID,
+
+		Actor:     contractor.Email,
+		Status:    timesheet.Status,
+		Reason:    fmt.Sprintf("Same file as version %d sent again, ignored", timesheet.Version),
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	err := s.timesheetIngestionsDB.CommitDuplicateTimesheetIngestion(ingestion, auditEntry)
+	if status.Code(err) == codes.AlreadyExists {
+		return s.timesheetIngestionsDB.GetTimesheetIngestion(ingestionID)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to save duplicate timesheet: %w", err)
+	}
+
+	return ingestion, nil
+}
+
+// listTimesheetVersions lists the versions of the timesheet of a contractor's request.
+func (s *TimesheetIngestionService) listTimesheetVersions(contractorID string, requestID string) ([]*types.TimesheetVersion, error) {
+	timesheet, err := s.timesheetsDB.GetTimesheet(contractorID, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get timesheet: %w", err)
+	}
+
+	return s.timesheetVersionsDB.ListTimesheetVersions(timesheet.ID)
+}
+
+// emailIngestionKey returns what identifies an email, its Message-ID, or a hash of its sender, date, subject and attachments if it has none.
+func emailIngestionKey(email *types.InboundEmail) string {
+	if email.MessageID != "" {
+		return "message:" + email.MessageID
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n", email.From, email.Date.UTC().Format(time.RFC3339), email.Subject)
+	for _, attachment := range email.Attachments {
+		fmt.Fprintf(h, "%s\n%s\n", attachment.Filename, hashHex(attachment.Content))
+	}
+
+	return "email:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// hashHex returns the hex SHA-256 of data.
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// requestIDFromSubject turns the request ID of an email subject into the stored one, the reverse of requestSubjectID.
+func requestIDFromSubject(subjectID string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(subjectID, "/", "_"), " ", "-")
+}
@@ -0,0 +1,254 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TimesheetIngestionsDatabaseService is a service for saving the submitted timesheets in a database, each submission once.
+type TimesheetIngestionsDatabaseService struct {
+	ingestionsCollectionName string
+	timesheetsCollectionName string
+	versionsCollectionName   string
+	entriesCollectionName    string
+	auditLogCollectionName   string
+	requestsCollectionName   string
+	client                   *firestore.Client
+}
+
+// Ensure TimesheetIngestionsDatabaseService implements ITimesheetIngestionsDatabaseService.
+var _ interfaces.ITimesheetIngestionsDatabaseService = &TimesheetIngestionsDatabaseService{}
+
+// NewTimesheetIngestionsDatabaseService creates a new TimesheetIngestionsDatabaseService.
+func NewTimesheetIngestionsDatabaseService(firebaseService *FirebaseService) (*TimesheetIngestionsDatabaseService, error) {
+	ctx := context.Background()
+	client, err := firebaseService.app.Firestore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not get Firestore client: %w", err)
+	}
+
+	// Verify that we can communicate and authenticate with the Firestore service.
+	err = client.RunTransaction(ctx, func(ctx context.Context, t *firestore.Transaction) error {
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not connect: %w", err)
+	}
+
+	return &TimesheetIngestionsDatabaseService{
+		ingestionsCollectionName: "timesheet_ingestions",
+		timesheetsCollectionName: "timesheets",
+		versionsCollectionName:   "timesheet_versions",
+		entriesCollectionName:    "timesheet_entries",
+		auditLogCollectionName:   "timesheet_audit_log",
+		requestsCollectionName:   "timesheet_requests",
+		client:                   client,
+	}, nil
+}
+
+// Close closes the database.
+func (db *TimesheetIngestionsDatabaseService) Close() error {
+	return db.client.Close()
+}
+
+// GetTimesheetIngestion gets the ingestion of a submission by the ID of its key, it returns a NotFound status if the submission was not saved.
+func (db *TimesheetIngestionsDatabaseService) GetTimesheetIngestion(id string) (*types.TimesheetIngestion, error) {
+	ctx := context.Background()
+	doc, err := db.client.Collection(db.ingestionsCollectionName).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, status.Errorf(codes.NotFound, "timesheet ingestion %s does not exist", id)
+	} else if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not get timesheet ingestion: %w", err)
+	}
+
+	var ingestion types.TimesheetIngestion
+	err = doc.DataTo(&ingestion)
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not convert data to timesheet ingestion: %w", err)
+	}
+
+	return &ingestion, nil
+}
+
+// CommitTimesheetIngestion saves all a submission changes in one transaction: the timesheet, its new versions, its entries, the audit entry, the collected request and the ingestion.
+func (db *TimesheetIngestionsDatabaseService) CommitTimesheetIngestion(ingestion *types.TimesheetIngestion, timesheet *types.Timesheet, versions []*types.TimesheetVersion, entries []*types.TimesheetEntry, auditEntry *types.TimesheetAuditEntry, timesheetRequestID string, collectedAt time.Time) error {
+	ctx := context.Background()
+	ingestionRef := db.client.Collection(db.ingestionsCollectionName).Doc(ingestion.ID)
+	requestRef := db.client.Collection(db.requestsCollectionName).Doc(timesheetRequestID)
+	added := timesheet.ID == ""
+
+	err := db.client.RunTransaction(ctx, func(ctx context.Context, t *firestore.Transaction) error {
+		// Every read of a transaction comes before its writes
+		_, err := t.Get(ingestionRef)
+		if err == nil {
+			return status.Errorf(codes.AlreadyExists, "submission %s was already saved", ingestion.ID)
+		} else if status.Code(err) != codes.NotFound {
+			return fmt.Errorf("could not get timesheet ingestion: %w", err)
+		}
+
+		// The request is read so a change of it meanwhile retries the transaction
+		_, err = t.Get(requestRef)
+		if err != nil {
+			return fmt.Errorf("could not get timesheet request: %w", err)
+		}
+
+		var timesheetRef *firestore.DocumentRef
+		var oldEntries []*firestore.DocumentSnapshot
+		if added {
+			existing, err := t.Documents(db.client.Collection(db.timesheetsCollectionName).Where("contractor_id", "==", timesheet.ContractorID).Where("request_id", "==", timesheet.RequestID)).GetAll()
+			if err != nil {
+				return fmt.Errorf("could not get timesheets: %w", err)
+			}
+
+			if len(existing) > 0 {
+				return status.Errorf(codes.Aborted, "a timesheet of contractor %s for request %s was added meanwhile", timesheet.ContractorID, timesheet.RequestID)
+			}
+
+			timesheetRef = db.client.Collection(db.timesheetsCollectionName).NewDoc()
+			timesheet.ID = timesheetRef.ID
+		} else {
+			timesheetRef = db.client.Collection(db.timesheetsCollectionName).Doc(timesheet.ID)
+
+			doc, err := t.Get(timesheetRef)
+			if err != nil {
+				return fmt.Errorf("could not get timesheet: %w", err)
+			}
+
+			var stored types.Timesheet
+			err = doc.DataTo(&stored)
+			if err != nil {
+				return fmt.Errorf("could not convert data to timesheet: %w", err)
+			}
+
+			// The versions are numbered from the one that was read
+			if len(versions) > 0 && stored.Version != versions[0].Version-1 {
+				return status.Errorf(codes.Aborted, "timesheet %s got another version meanwhile", timesheet.ID)
+			}
+
+			err = nextTimesheetVersion(&stored, timesheet, auditEntry)
+			if err != nil {
+				return err
+			}
+
+			oldEntries, err = t.Documents(db.client.Collection(db.entriesCollectionName).Where("timesheet_id", "==", timesheet.ID)).GetAll()
+			if err != nil {
+				return fmt.Errorf("could not get timesheet entries: %w", err)
+			}
+		}
+
+		if added {
+			err = t.Create(timesheetRef, timesheet)
+		} else {
+			// Only the file and the status change, the rest is kept as stored
+			err = t.Update(timesheetRef, []firestore.Update{
+				{Path: "storage_url", Value: timesheet.StorageURL},
+				{Path: "version", Value: timesheet.Version},
+				{Path: "content_hash", Value: timesheet.ContentHash},
+				{Path: "total_hours", Value: timesheet.TotalHours},
+				{Path: "status", Value: timesheet.Status},
+				{Path: "rejection_reason", Value: timesheet.RejectionReason},
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, version := range versions {
+			ref := db.client.Collection(db.versionsCollectionName).NewDoc()
+			version.ID = ref.ID
+			version.TimesheetID = timesheet.ID
+
+			err = t.Create(ref, version)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, doc := range oldEntries {
+			err = t.Delete(doc.Ref)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, entry := range entries {
+			ref := db.client.Collection(db.entriesCollectionName).NewDoc()
+			entry.ID = ref.ID
+			entry.TimesheetID = timesheet.ID
+
+			err = t.Create(ref, entry)
+			if err != nil {
+				return err
+			}
+		}
+
+		auditRef := db.client.Collection(db.auditLogCollectionName).NewDoc()
+		auditEntry.ID = auditRef.ID
+		auditEntry.TimesheetID = timesheet.ID
+
+		err = t.Create(auditRef, auditEntry)
+		if err != nil {
+			return err
+		}
+
+		err = t.Update(requestRef, []firestore.Update{
+			{Path: "status", Value: constants.Collected},
+			{Path: "collected_at", Value: collectedAt},
+		})
+		if err != nil {
+			return err
+		}
+
+		ingestion.TimesheetID = timesheet.ID
+
+		return t.Create(ingestionRef, ingestion)
+	})
+	if err != nil && added {
+		timesheet.ID = ""
+	}
+
+	switch status.Code(err) {
+	case codes.OK:
+		return nil
+	case codes.AlreadyExists, codes.Aborted, codes.FailedPrecondition:
+		return err
+	default:
+		return fmt.Errorf("firestoredb: could not commit timesheet ingestion: %w", err)
+	}
+}
+
+// CommitDuplicateTimesheetIngestion saves a submission of the current file of a timesheet, only the ingestion and the audit entry noting it, in one transaction.
+func (db *TimesheetIngestionsDatabaseService) CommitDuplicateTimesheetIngestion(ingestion *types.TimesheetIngestion, auditEntry *types.TimesheetAuditEntry) error {
+	ctx := context.Background()
+	ingestionRef := db.client.Collection(db.ingestionsCollectionName).Doc(ingestion.ID)
+
+	err := db.client.RunTransaction(ctx, func(ctx context.Context, t *firestore.Transaction) error {
+		auditRef := db.client.Collection(db.auditLogCollectionName).NewDoc()
+		auditEntry.ID = auditRef.ID
+
+		err := t.Create(auditRef, auditEntry)
+		if err != nil {
+			return err
+		}
+
+		// Creating the ingestion fails the commit if the submission was saved meanwhile
+		return t.Create(ingestionRef, ingestion)
+	})
+	switch status.Code(err) {
+	case codes.OK:
+		return nil
+	case codes.AlreadyExists:
+		return status.Errorf(codes.AlreadyExists, "submission %s was already saved", ingestion.ID)
+	default:
+		return fmt.Errorf("firestoredb: could not commit duplicate timesheet ingestion: %w", err)
+	}
+}
@@ -0,0 +1,482 @@
+package core
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+
+	"github.com/ledongthuc/pdf"
+	"github.com/xuri/excelize/v2"
+)
+
+// timesheetHeaderScanRows is how many rows of a table are searched for the header.
+const timesheetHeaderScanRows = 20
+
+// Words the timesheet column headers are recognised by, in English and Polish.
+var (
+	timesheetDateHeaders    = []string{"date", "day", "data", "dzień", "dzien", "dzień pracy"}
+	timesheetHoursHeaders   = []string{"hours", "hour", "hrs", "h", "time", "duration", "godziny", "godzin", "godz", "czas", "liczba godzin", "ilość godzin"}
+	timesheetProjectHeaders = []string{"project", "task", "client", "projekt", "zadanie", "klient"}
+	timesheetNoteHeaders    = []string{"note", "notes", "comment", "comments", "description", "notatka", "komentarz", "opis", "uwagi"}
+)
+
+// Layouts of the dates in the timesheets, the day comes before the month unless the year does.
+var timesheetDateLayouts = []string{
+	"2006-01-02",
+	"2006/01/02",
+	"2006.01.02",
+	"2.1.2006",
+	"2/1/2006",
+	"2-1-2006",
+	"1/2/2006",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05Z07:00",
+}
+
+// TimesheetParserService is a service for extracting the worked days from CSV, XLSX and PDF timesheets.
+type TimesheetParserService struct{}
+
+// Ensure TimesheetParserService implements ITimesheetParserService.
+var _ interfaces.ITimesheetParserService = &TimesheetParserService{}
+
+// timesheetTable is a table read from a timesheet, raw holds the unformatted spreadsheet values if the format has any.
+type timesheetTable struct {
+	rows [][]string
+	raw  [][]string
+}
+
+// timesheetColumns are the indexes of the timesheet columns in a table, -1 if the table has no such column.
+type timesheetColumns struct {
+	date    int
+	hours   int
+	project int
+	note    int
+}
+
+// pdfCell is a piece of text of a PDF page with the horizontal span it is drawn at.
+type pdfCell struct {
+	text       string
+	start, end float64
+}
+
+// NewTimesheetParserService creates a new TimesheetParserService.
+func NewTimesheetParserService() *TimesheetParserService {
+	return &TimesheetParserService{}
+}
+
+// ParseTimesheet parses a CSV, XLSX or PDF timesheet into its entries, the format is detected from the filename and the content.
+// Rows without a date, like titles and totals, are skipped. The entries are returned in the order of the file.
+func (s *TimesheetParserService) ParseTimesheet(filename string, content []byte) ([]*types.TimesheetEntry, error) {
+	var tables []timesheetTable
+	var err error
+
+	switch format := detectTimesheetFormat(filename, content); format {
+	case "csv":
+		tables, err = readCsvTimesheet(content)
+	case "xlsx":
+		tables, err = readXlsxTimesheet(content)
+	case "pdf":
+		tables, err = readPdfTimesheet(content)
+	default:
+		return nil, fmt.Errorf("unsupported timesheet format of %s", filename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", filename, err)
+	}
+
+	// Use the first table that has any entries, spreadsheets often have empty or summary sheets first
+	for _, table := range tables {
+		entries, err := parseTimesheetTable(table)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %s: %w", filename, err)
+		}
+
+		if len(entries) > 0 {
+			return entries, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no timesheet entries found in %s", filename)
+}
+
+// detectTimesheetFormat returns csv, xlsx or pdf based on the extension, sniffing the content if the extension is unknown.
+func detectTimesheetFormat(filename string, content []byte) string {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv", ".tsv", ".txt":
+		return "csv"
+	case ".xlsx", ".xlsm":
+		return "xlsx"
+	case ".pdf":
+		return "pdf"
+	}
+
+	switch {
+	case bytes.HasPrefix(content, []byte("%PDF-")):
+		return "pdf"
+	case bytes.HasPrefix(content, []byte("PK\x03\x04")):
+		return "xlsx"
+	case utf8.Valid(content):
+		return "csv"
+	default:
+		return ""
+	}
+}
+
+// readCsvTimesheet reads a CSV timesheet separated by commas, semicolons or tabs.
+func readCsvTimesheet(content []byte) ([]timesheetTable, error) {
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+
+	// Excel uses semicolons where the comma is the decimal separator, pick whichever separates the first line the most
+	firstLine, _, _ := bytes.Cut(content, []byte("\n"))
+	delimiter := ','
+	for _, d := range []rune{';', '\t'} {
+		if bytes.Count(firstLine, []byte(string(d))) > bytes.Count(firstLine, []byte(string(delimiter))) {
+			delimiter = d
+		}
+	}
+
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	return []timesheetTable{{rows: rows}}, nil
+}
+
+// readXlsxTimesheet reads every sheet of an XLSX timesheet.
+func readXlsxTimesheet(content []byte) ([]timesheetTable, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var tables []timesheetTable
+	for _, sheet := range f.GetSheetList() {
+		rows, err := f.GetRows(sheet)
+		if err != nil {
+			return nil, fmt.Errorf("could not read sheet %s: %w", sheet, err)
+		}
+
+		// The raw values keep the dates as serial numbers, whatever format the cells are displayed in
+		raw, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+		if err != nil {
+			return nil, fmt.Errorf("could not read sheet %s: %w", sheet, err)
+		}
+
+		tables = append(tables, timesheetTable{rows: rows, raw: raw})
+	}
+
+	return tables, nil
+}
+
+// readPdfTimesheet reads the table of a PDF timesheet, the text of all pages is joined into one table.
+// The cells are aligned to the columns of the header, so empty cells do not shift the following ones.
+func readPdfTimesheet(content []byte) (tables []timesheetTable, err error) {
+	// The PDF reader panics on some malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			tables, err = nil, fmt.Errorf("malformed pdf: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, err
+	}
+
+	var lines [][]pdfCell
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+
+		lines = append(lines, pdfLines(page.Content().Text)...)
+	}
+
+	rows := make([][]string, len(lines))
+	for i, line := range lines {
+		for _, cell := range line {
+			rows[i] = append(rows[i], cell.text)
+		}
+	}
+
+	headerRow, _, ok := findTimesheetHeader(rows)
+	if !ok {
+		return []timesheetTable{{rows: rows}}, nil
+	}
+
+	header := lines[headerRow]
+	for i := headerRow + 1; i < len(lines); i++ {
+		aligned := make([]string, len(header))
+		for _, cell := range lines[i] {
+			column := nearestPdfColumn(header, cell)
+			aligned[column] = strings.TrimSpace(aligned[column] + " " + cell.text)
+		}
+		rows[i] = aligned
+	}
+
+	return []timesheetTable{{rows: rows}}, nil
+}
+
+// pdfLines groups the glyphs of a page into lines from top to bottom, splitting each line into cells at wide gaps.
+func pdfLines(glyphs []pdf.Text) [][]pdfCell {
+	sort.SliceStable(glyphs, func(i, j int) bool {
+		if glyphs[i].Y != glyphs[j].Y {
+			return glyphs[i].Y > glyphs[j].Y
+		}
+		return glyphs[i].X < glyphs[j].X
+	})
+
+	var lines [][]pdfCell
+	var line []pdfCell
+	var cell *pdfCell
+	var lineY float64
+
+	for _, glyph := range glyphs {
+		fontSize := glyph.FontSize
+		if fontSize <= 0 {
+			fontSize = 10
+		}
+
+		// Standard fonts do not always carry their widths, assume an average glyph
+		width := glyph.W
+		if width <= 0 {
+			width = fontSize * 0.6
+		}
+
+		if (cell != nil || len(line) > 0) && math.Abs(glyph.Y-lineY) > 2 {
+			if cell != nil {
+				line = append(line, *cell)
+			}
+			lines = append(lines, line)
+			line, cell = nil, nil
+		}
+
+		if cell != nil {
+			gap := glyph.X - cell.end
+			switch {
+			case gap > fontSize:
+				line = append(line, *cell)
+				cell = nil
+			case gap > fontSize*0.15 && !strings.HasSuffix(cell.text, " "):
+				cell.text += " "
+			}
+		}
+
+		if cell == nil {
+			if strings.TrimSpace(glyph.S) == "" {
+				continue
+			}
+			cell = &pdfCell{start: glyph.X}
+			lineY = glyph.Y
+		}
+
+		cell.text += glyph.S
+		cell.end = glyph.X + width
+	}
+
+	if cell != nil {
+		line = append(line, *cell)
+	}
+	if len(line) > 0 {
+		lines = append(lines, line)
+	}
+
+	for _, line := range lines {
+		for i := range line {
+			line[i].text = strings.TrimSpace(line[i].text)
+		}
+	}
+
+	return lines
+}
+
+// nearestPdfColumn returns the index of the header cell whose center is the closest to the center of a cell.
+func nearestPdfColumn(header []pdfCell, cell pdfCell) int {
+	center := (cell.start + cell.end) / 2
+	nearest := 0
+	for i, h := range header {
+		if math.Abs((h.start+h.end)/2-center) < math.Abs((header[nearest].start+header[nearest].end)/2-center) {
+			nearest = i
+		}
+	}
+
+	return nearest
+}
+
+// parseTimesheetTable extracts the entries of a table. Without a header the columns are taken as date, hours, project and note.
+func parseTimesheetTable(table timesheetTable) ([]*types.TimesheetEntry, error) {
+	headerRow, columns, ok := findTimesheetHeader(table.rows)
+	if !ok {
+		headerRow, columns = -1, timesheetColumns{date: 0, hours: 1, project: 2, note: 3}
+	}
+
+	var entries []*types.TimesheetEntry
+	for i := headerRow + 1; i < len(table.rows); i++ {
+		row := table.rows[i]
+
+		date, ok := parseTimesheetDate(cellAt(row, columns.date))
+		if !ok && i < len(table.raw) {
+			date, ok = parseExcelDate(cellAt(table.raw[i], columns.date))
+		}
+		if !ok {
+			continue
+		}
+
+		hoursValue := cellAt(row, columns.hours)
+		if hoursValue == "" {
+			continue
+		}
+
+		hours, err := parseTimesheetHours(hoursValue)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+
+		entries = append(entries, &types.TimesheetEntry{
+			Date:    date.Format("2006-01-02"),
+			Hours:   hours,
+			Project: cellAt(row, columns.project),
+			Note:    cellAt(row, columns.note),
+		})
+	}
+
+	return entries, nil
+}
+
+// findTimesheetHeader searches the first rows for one naming at least the date and hours columns.
+func findTimesheetHeader(rows [][]string) (int, timesheetColumns, bool) {
+	for i := 0; i < len(rows) && i < timesheetHeaderScanRows; i++ {
+		columns := timesheetColumns{date: -1, hours: -1, project: -1, note: -1}
+		for j, cell := range rows[i] {
+			switch {
+			case columns.date == -1 && matchesTimesheetHeader(cell, timesheetDateHeaders):
+				columns.date = j
+			case columns.hours == -1 && matchesTimesheetHeader(cell, timesheetHoursHeaders):
+				columns.hours = j
+			case columns.project == -1 && matchesTimesheetHeader(cell, timesheetProjectHeaders):
+				columns.project = j
+			case columns.note == -1 && matchesTimesheetHeader(cell, timesheetNoteHeaders):
+				columns.note = j
+			}
+		}
+
+		if columns.date != -1 && columns.hours != -1 {
+			return i, columns, true
+		}
+	}
+
+	return 0, timesheetColumns{}, false
+}
+
+// matchesTimesheetHeader reports whether a header cell is one of the names, or starts with one, like "Hours worked" or "Data (dd.mm.rrrr)".
+func matchesTimesheetHeader(cell string, names []string) bool {
+	words := strings.FieldsFunc(strings.ToLower(cell), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	header := strings.Join(words, " ")
+	if header == "" {
+		return false
+	}
+
+	for _, name := range names {
+		if header == name || strings.HasPrefix(header, name+" ") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseTimesheetDate parses a date written in one of the timesheet date layouts.
+func parseTimesheetDate(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	for _, layout := range timesheetDateLayouts {
+		date, err := time.Parse(layout, value)
+		if err == nil {
+			return date, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// parseExcelDate parses a spreadsheet date serial number.
+func parseExcelDate(value string) (time.Time, bool) {
+	serial, err := strconv.ParseFloat(value, 64)
+	if err != nil || serial < 1 {
+		return time.Time{}, false
+	}
+
+	date, err := excelize.ExcelDateToTime(serial, false)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return date, true
+}
+
+// parseTimesheetHours parses hours written as 8, 7.5, 7,5, 7:30 or 7.5h.
+func parseTimesheetHours(value string) (float64, error) {
+	value = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(value)), "h")
+	value = strings.TrimSpace(value)
+
+	var hours float64
+	if h, m, ok := strings.Cut(value, ":"); ok {
+		wholeHours, err := strconv.Atoi(h)
+		if err != nil {
+			return 0, fmt.Errorf("invalid hours %q", value)
+		}
+
+		// Spreadsheets display durations as 7:30:00
+		m, _, _ = strings.Cut(m, ":")
+		minutes, err := strconv.Atoi(m)
+		if err != nil || minutes >= 60 {
+			return 0, fmt.Errorf("invalid hours %q", value)
+		}
+
+		hours = float64(wholeHours) + float64(minutes)/60
+	} else {
+		var err error
+		hours, err = strconv.ParseFloat(strings.ReplaceAll(value, ",", "."), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid hours %q", value)
+		}
+	}
+
+	if hours < 0 || hours > 24 {
+		return 0, fmt.Errorf("hours %q must be between 0 and 24", value)
+	}
+
+	return math.Round(hours*100) / 100, nil
+}
+
+// cellAt returns the trimmed cell of a row, or an empty string if the row is shorter or the column is missing.
+func cellAt(row []string, column int) string {
+	if column < 0 || column >= len(row) {
+		return ""
+	}
+
+	return strings.TrimSpace(row[column])
+}
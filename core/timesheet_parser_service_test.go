@@ -0,0 +1,271 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"job_sender/types"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// testEntry is the part of a parsed entry the tests compare.
+type testEntry struct {
+	date    string
+	hours   float64
+	project string
+	note    string
+}
+
+func TestParseTimesheet(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		want     []testEntry
+		wantErr  bool
+	}{
+		{
+			name:     "comma separated",
+			filename: "timesheet.csv",
+			content:  "Date,Hours,Project,Note\n2025-01-06,8,ACME,Backend\n2025-01-07,7.5,ACME,\n",
+			want:     []testEntry{{"2025-01-06", 8, "ACME", "Backend"}, {"2025-01-07", 7.5, "ACME", ""}},
+		},
+		{
+			name:     "semicolons with decimal commas",
+			filename: "timesheet.csv",
+			content:  "Data;Liczba godzin;Projekt\n06.01.2025;7,5;ACME\n07.01.2025;8;ACME\n",
+			want:     []testEntry{{"2025-01-06", 7.5, "ACME", ""}, {"2025-01-07", 8, "ACME", ""}},
+		},
+		{
+			name:     "tabs with a byte order mark",
+			filename: "timesheet.tsv",
+			content:  "\xef\xbb\xbfDay\tHrs\n2025-01-06\t8\n",
+			want:     []testEntry{{"2025-01-06", 8, "", ""}},
+		},
+		{
+			name:     "title and total rows",
+			filename: "timesheet.csv",
+			content:  "Timesheet January 2025\n\nDate,Hours worked\n2025-01-06,8\n2025-01-07,8\nTotal,16\n",
+			want:     []testEntry{{"2025-01-06", 8, "", ""}, {"2025-01-07", 8, "", ""}},
+		},
+		{
+			name:     "no header",
+			filename: "timesheet.csv",
+			content:  "2025-01-06,8,ACME,Backend\n",
+			want:     []testEntry{{"2025-01-06", 8, "ACME", "Backend"}},
+		},
+		{
+			name:     "days without hours",
+			filename: "timesheet.csv",
+			content:  "Date,Hours\n2025-01-04,\n2025-01-06,8\n",
+			want:     []testEntry{{"2025-01-06", 8, "", ""}},
+		},
+		{
+			name:     "durations",
+			filename: "timesheet.csv",
+			content:  "Date,Hours\n2025-01-06,7:30\n2025-01-07,7:45:00\n2025-01-08,6.5h\n",
+			want:     []testEntry{{"2025-01-06", 7.5, "", ""}, {"2025-01-07", 7.75, "", ""}, {"2025-01-08", 6.5, "", ""}},
+		},
+		{
+			name:     "month ends",
+			filename: "timesheet.csv",
+			content:  "Date,Hours\n31.01.2025,8\n30/04/2025,8\n2025-12-31,4\n",
+			want:     []testEntry{{"2025-01-31", 8, "", ""}, {"2025-04-30", 8, "", ""}, {"2025-12-31", 4, "", ""}},
+		},
+		{
+			name:     "leap day",
+			filename: "timesheet.csv",
+			content:  "Date,Hours\n29.02.2024,8\n",
+			want:     []testEntry{{"2024-02-29", 8, "", ""}},
+		},
+		{
+			name:     "leap day of a common year",
+			filename: "timesheet.csv",
+			content:  "Date,Hours\n29.02.2025,8\n",
+			wantErr:  true,
+		},
+		{
+			name:     "day before the month",
+			filename: "timesheet.csv",
+			content:  "Date,Hours\n01/02/2025,8\n",
+			want:     []testEntry{{"2025-02-01", 8, "", ""}},
+		},
+		{
+			name:     "sniffed without an extension",
+			filename: "timesheet",
+			content:  "Date,Hours\n2025-01-06,8\n",
+			want:     []testEntry{{"2025-01-06", 8, "", ""}},
+		},
+		{
+			name:     "invalid hours",
+			filename: "timesheet.csv",
+			content:  "Date,Hours\n2025-01-06,eight\n",
+			wantErr:  true,
+		},
+		{
+			name:     "too many hours",
+			filename: "timesheet.csv",
+			content:  "Date,Hours\n2025-01-06,25\n",
+			wantErr:  true,
+		},
+		{
+			name:     "invalid minutes",
+			filename: "timesheet.csv",
+			content:  "Date,Hours\n2025-01-06,7:60\n",
+			wantErr:  true,
+		},
+		{
+			name:     "no entries",
+			filename: "timesheet.csv",
+			content:  "Date,Hours\n",
+			wantErr:  true,
+		},
+		{
+			name:     "unsupported format",
+			filename: "timesheet",
+			content:  "\xff\xfe\x00",
+			wantErr:  true,
+		},
+		{
+			name:     "malformed spreadsheet",
+			filename: "timesheet.xlsx",
+			content:  "PK\x03\x04 not a spreadsheet",
+			wantErr:  true,
+		},
+		{
+			name:     "malformed PDF",
+			filename: "timesheet.pdf",
+			content:  "%PDF-1.4 not a document",
+			wantErr:  true,
+		},
+	}
+
+	parser := NewTimesheetParserService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := parser.ParseTimesheet(tt.filename, []byte(tt.content))
+			checkEntries(t, entries, err, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func TestParseXlsxTimesheet(t *testing.T) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	// An empty summary sheet comes first, the entries are on the second one
+	_, err := f.NewSheet("Entries")
+	if err != nil {
+		t.Fatalf("NewSheet: %v", err)
+	}
+
+	rows := [][]interface{}{
+		{"Date", "Hours", "Project"},
+		{time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), 8, "ACME"},
+		{time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC), 7.5, "ACME"},
+		{"Total", 15.5},
+	}
+	for i, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			t.Fatalf("CoordinatesToCellName: %v", err)
+		}
+
+		err = f.SetSheetRow("Entries", cell, &row)
+		if err != nil {
+			t.Fatalf("SetSheetRow: %v", err)
+		}
+	}
+
+	// The dates are shown in a format the parser does not know, their serial numbers are read instead
+	style, err := f.NewStyle(&excelize.Style{CustomNumFmt: stringPtr("d mmmm yyyy")})
+	if err != nil {
+		t.Fatalf("NewStyle: %v", err)
+	}
+	err = f.SetCellStyle("Entries", "A2", "A3", style)
+	if err != nil {
+		t.Fatalf("SetCellStyle: %v", err)
+	}
+
+	var content bytes.Buffer
+	err = f.Write(&content)
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	entries, err := NewTimesheetParserService().ParseTimesheet("timesheet.xlsx", content.Bytes())
+	checkEntries(t, entries, err, []testEntry{{"2024-02-29", 8, "ACME", ""}, {"2024-03-31", 7.5, "ACME", ""}}, false)
+}
+
+func TestParseTimesheetHours(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{"8", 8, false},
+		{"7.5", 7.5, false},
+		{"7,5", 7.5, false},
+		{"7:30", 7.5, false},
+		{"7:20", 7.33, false},
+		{"0", 0, false},
+		{"24", 24, false},
+		{" 6 h ", 6, false},
+		{"-1", 0, true},
+		{"24.5", 0, true},
+		{"7:75", 0, true},
+		{"x:30", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseTimesheetHours(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseTimesheetHours(%q) = %v, want an error", tt.value, got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("parseTimesheetHours(%q): %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseTimesheetHours(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+// checkEntries compares parsed entries with the wanted ones.
+func checkEntries(t *testing.T, entries []*types.TimesheetEntry, err error, want []testEntry, wantErr bool) {
+	t.Helper()
+
+	if wantErr {
+		if err == nil {
+			t.Fatalf("got %d entries, want an error", len(entries))
+		}
+		return
+	}
+
+	if err != nil {
+		t.Fatalf("ParseTimesheet: %v", err)
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+
+	for i, entry := range entries {
+		got := testEntry{entry.Date, entry.Hours, entry.Project, entry.Note}
+		if got != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,224 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TimesheetRequestsDatabaseService is a service for managing timesheet requests in a database.
+type TimesheetRequestsDatabaseService struct {
+	collectionName string
+	client         *firestore.Client
+}
+
+// Ensure TimesheetRequestsDatabaseService implements ITimesheetRequestsDatabaseService.
+var _ interfaces.ITimesheetRequestsDatabaseService = &TimesheetRequestsDatabaseService{}
+
+// NewTimesheetRequestsDatabaseService creates a new TimesheetRequestsDatabaseService.
+func NewTimesheetRequestsDatabaseService(firebaseService *FirebaseService) (*TimesheetRequestsDatabaseService, error) {
+	ctx := context.Background()
+	client, err := firebaseService.app.Firestore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not get Firestore client: %w", err)
+	}
+
+	// Verify that we can communicate and authenticate with the Firestore service.
+	err = client.RunTransaction(ctx, func(ctx context.Context, t *firestore.Transaction) error {
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not connect: %w", err)
+	}
+
+	return &TimesheetRequestsDatabaseService{
+		collectionName: "timesheet_requests",
+		client:         client,
+	}, nil
+}
+
+// Close closes the database.
+func (db *TimesheetRequestsDatabaseService) Close() error {
+	return db.client.Close()
+}
+
+// ListTimesheetRequests lists the timesheet requests of a group.
+func (db *TimesheetRequestsDatabaseService) ListTimesheetRequests(groupID string) ([]*types.TimesheetRequest, error) {
+	return db.listTimesheetRequests(db.client.Collection(db.collectionName).Where("group_id", "==", groupID))
+}
+
+// ListContractorTimesheetRequests lists the timesheet requests of a contractor.
+func (db *TimesheetRequestsDatabaseService) ListContractorTimesheetRequests(contractorID string) ([]*types.TimesheetRequest, error) {
+	return db.listTimesheetRequests(db.client.Collection(db.collectionName).Where("contractor_id", "==", contractorID))
+}
+
+// GetTimesheetRequest gets the request of a contractor for a period by ContractorID and RequestID, it returns a NotFound status if there is none.
+func (db *TimesheetRequestsDatabaseService) GetTimesheetRequest(contractorID string, requestID string) (*types.TimesheetRequest, error) {
+	requests, err := db.listTimesheetRequests(db.client.Collection(db.collectionName).Where("contractor_id", "==", contractorID).Where("request_id", "==", requestID).Limit(1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(requests) == 0 {
+		return nil, status.Errorf(codes.NotFound, "timesheet request of contractor %s for request %s does not exist", contractorID, requestID)
+	}
+
+	return requests[0], nil
+}
+
+// GetTimesheetRequestByReplyKey gets the request with a reply key, it returns a NotFound status if there is none.
+func (db *TimesheetRequestsDatabaseService) GetTimesheetRequestByReplyKey(replyKey string) (*types.TimesheetRequest, error) {
+	requests, err := db.listTimesheetRequests(db.client.Collection(db.collectionName).Where("reply_key", "==", replyKey).Limit(1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(requests) == 0 {
+		return nil, status.Errorf(codes.NotFound, "timesheet request with reply key %s does not exist", replyKey)
+	}
+
+	return requests[0], nil
+}
+
+// AddTimesheetRequest adds a timesheet request and sets its ID.
+func (db *TimesheetRequestsDatabaseService) AddTimesheetRequest(request *types.TimesheetRequest) error {
+	ctx := context.Background()
+	ref := db.client.Collection(db.collectionName).NewDoc()
+	request.ID = ref.ID
+
+	_, err := ref.Create(ctx, request)
+	if err != nil {
+		return fmt.Errorf("could not add timesheet request: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateTimesheetRequest updates a timesheet request.
+func (db *TimesheetRequestsDatabaseService) UpdateTimesheetRequest(request *types.TimesheetRequest) error {
+	ctx := context.Background()
+	_, err := db.client.Collection(db.collectionName).Doc(request.ID).Set(ctx, request)
+	if err != nil {
+		return fmt.Errorf("could not update timesheet request: %w", err)
+	}
+
+	return nil
+}
+
+// UpdatePendingTimesheetRequest updates the attempts, the escalation, and the reply key and the sent and due dates if it has none yet, of a request
+// still waiting for its timesheet, the other fields are left as they are. It returns a FailedPrecondition status if the request is no longer
+// pending, e.g. its timesheet arrived meanwhile.
+func (db *TimesheetRequestsDatabaseService) UpdatePendingTimesheetRequest(request *types.TimesheetRequest) error {
+	ctx := context.Background()
+	ref := db.client.Collection(db.collectionName).Doc(request.ID)
+
+	err := db.client.RunTransaction(ctx, func(ctx context.Context, t *firestore.Transaction) error {
+		doc, err := t.Get(ref)
+		if err != nil {
+			return err
+		}
+
+		var stored types.TimesheetRequest
+		err = doc.DataTo(&stored)
+		if err != nil {
+			return fmt.Errorf("could not convert data to timesheet request: %w", err)
+		}
+
+		if stored.Status != constants.Pending {
+			return status.Errorf(codes.FailedPrecondition, "timesheet request %s is %s", request.ID, stored.Status)
+		}
+
+		updates := []firestore.Update{
+			{Path: "attempts", Value: request.Attempts},
+			{Path: "escalated", Value: request.Escalated},
+		}
+		if stored.ReplyKey == "" && request.ReplyKey != "" {
+			updates = append(updates, firestore.Update{Path: "reply_key", Value: request.ReplyKey})
+		}
+		if stored.SentAt.IsZero() && !request.SentAt.IsZero() {
+			updates = append(updates,
+				firestore.Update{Path: "sent_at", Value: request.SentAt},
+				firestore.Update{Path: "due_at", Value: request.DueAt},
+			)
+		}
+
+		return t.Update(ref, updates)
+	})
+	if status.Code(err) == codes.NotFound || status.Code(err) == codes.FailedPrecondition {
+		return err
+	} else if err != nil {
+		return fmt.Errorf("could not update timesheet request: %w", err)
+	}
+
+	return nil
+}
+
+// ReplaceSubmissionNonce updates the submission nonce of a request whose nonce is still oldNonce, the other fields are left as they are.
+// It returns a FailedPrecondition status if the nonce was replaced meanwhile, i.e. the submission link was used by another upload.
+func (db *TimesheetRequestsDatabaseService) ReplaceSubmissionNonce(request *types.TimesheetRequest, oldNonce string) error {
+	ctx := context.Background()
+	ref := db.client.Collection(db.collectionName).Doc(request.ID)
+
+	err := db.client.RunTransaction(ctx, func(ctx context.Context, t *firestore.Transaction) error {
+		doc, err := t.Get(ref)
+		if err != nil {
+			return err
+		}
+
+		var stored types.TimesheetRequest
+		err = doc.DataTo(&stored)
+		if err != nil {
+			return fmt.Errorf("could not convert data to timesheet request: %w", err)
+		}
+
+		if stored.SubmissionNonce != oldNonce {
+			return status.Errorf(codes.FailedPrecondition, "submission link of timesheet request %s was already used", request.ID)
+		}
+
+		return t.Update(ref, []firestore.Update{
+			{Path: "submission_nonce", Value: request.SubmissionNonce},
+		})
+	})
+	if status.Code(err) == codes.NotFound || status.Code(err) == codes.FailedPrecondition {
+		return err
+	} else if err != nil {
+		return fmt.Errorf("could not update timesheet request: %w", err)
+	}
+
+	return nil
+}
+
+// listTimesheetRequests lists the timesheet requests of a query.
+func (db *TimesheetRequestsDatabaseService) listTimesheetRequests(query firestore.Query) ([]*types.TimesheetRequest, error) {
+	ctx := context.Background()
+	iter := query.Documents(ctx)
+
+	var requests []*types.TimesheetRequest
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not list timesheet requests: %w", err)
+		}
+
+		var request types.TimesheetRequest
+		err = doc.DataTo(&request)
+		if err != nil {
+			return nil, fmt.Errorf("could not convert data to timesheet request: %w", err)
+		}
+
+		requests = append(requests, &request)
+	}
+
+	return requests, nil
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// timesheetTransitions lists the statuses a timesheet can move to from each status.
+var timesheetTransitions = map[constants.TimesheetStatuses][]constants.TimesheetStatuses{
+	constants.Received:    {constants.UnderReview},
+	constants.UnderReview: {constants.Approved, constants.Rejected},
+	constants.Rejected:    {constants.Resubmitted},
+	constants.Resubmitted: {constants.UnderReview},
+}
+
+// TimesheetReviewService is a service for moving timesheets through their review statuses.
+type TimesheetReviewService struct {
+	timesheetsDB interfaces.ITimesheetsDatabaseService
+	auditLogDB   interfaces.ITimesheetAuditLogDatabaseService
+}
+
+// Ensure TimesheetReviewService implements ITimesheetReviewService.
+var _ interfaces.ITimesheetReviewService = &TimesheetReviewService{}
+
+// NewTimesheetReviewService creates a new TimesheetReviewService.
+func NewTimesheetReviewService(timesheetsDB interfaces.ITimesheetsDatabaseService, auditLogDB interfaces.ITimesheetAuditLogDatabaseService) *TimesheetReviewService {
+	return &TimesheetReviewService{
+		timesheetsDB: timesheetsDB,
+		auditLogDB:   auditLogDB,
+	}
+}
+
+// TransitionTimesheet moves a timesheet to a status, saves it and records the change in the audit log.
+func (s *TimesheetReviewService) TransitionTimesheet(timesheet *types.Timesheet, to constants.TimesheetStatuses, actor string, reason string) error {
+	if !s.CanTransitionTimesheet(timesheet.Status, to) {
+		return status.Errorf(codes.FailedPrecondition, "timesheet %s cannot go from %s to %s", timesheet.ID, timesheet.Status, to)
+	}
+
+	if to == constants.Rejected && reason == "" {
+		return status.Errorf(codes.InvalidArgument, "a reason is required to reject timesheet %s", timesheet.ID)
+	}
+
+	// Only the status is written, and only while the timesheet is still the one that was read, a version that arrived meanwhile is reviewed first
+	updated := *timesheet
+	updated.Status = to
+	updated.RejectionReason = ""
+	if to == constants.Rejected {
+		updated.RejectionReason = reason
+	}
+
+	err := s.timesheetsDB.UpdateTimesheetStatus(&updated, timesheet.Status, timesheet.Version)
+	if status.Code(err) == codes.FailedPrecondition {
+		return err
+	} else if err != nil {
+		return fmt.Errorf("could not update timesheet: %w", err)
+	}
+
+	timesheet.Status = updated.Status
+	timesheet.RejectionReason = updated.RejectionReason
+
+	err = s.auditLogDB.AddAuditEntry(&types.TimesheetAuditEntry{
+		TimesheetID: timesheet.ID,
+		GroupID:     timesheet.GroupID,
+
+		Actor:     actor,
+		Status:    to,
+		Reason:    timesheet.RejectionReason,
+		Timestamp: time.Now().UnixMilli(),
+	})
+	if err != nil {
+		return fmt.Errorf("could not record timesheet status change: %w", err)
+	}
+
+	return nil
+}
+
+// CanTransitionTimesheet reports whether a timesheet can move from one status to another.
+func (s *TimesheetReviewService) CanTransitionTimesheet(from constants.TimesheetStatuses, to constants.TimesheetStatuses) bool {
+	return slices.Contains(timesheetTransitions[from], to)
+}
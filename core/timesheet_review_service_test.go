@@ -0,0 +1,75 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTransitionTimesheet(t *testing.T) {
+	store, err := NewLocalStore(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	timesheetsDB := NewLocalTimesheetsDatabaseService(store)
+	auditLogDB := NewLocalTimesheetAuditLogDatabaseService(store)
+	service := NewTimesheetReviewService(timesheetsDB, auditLogDB)
+
+	timesheet := &types.Timesheet{ContractorID: "c1", GroupID: "g1", RequestID: testRequestID, StorageURL: "v1.xlsx", Version: 1, ContentHash: "h1", Status: constants.UnderReview}
+	err = timesheetsDB.AddTimesheet(timesheet)
+	if err != nil {
+		t.Fatalf("AddTimesheet: %v", err)
+	}
+
+	// The owner opened version 1, then version 2 arrived
+	read, err := timesheetsDB.GetTimesheetByID(timesheet.ID)
+	if err != nil {
+		t.Fatalf("GetTimesheetByID: %v", err)
+	}
+
+	err = timesheetsDB.UpdateTimesheet(&types.Timesheet{ID: timesheet.ID, ContractorID: "c1", GroupID: "g1", RequestID: testRequestID, StorageURL: "v2.xlsx", Version: 2, ContentHash: "h2", Status: constants.UnderReview})
+	if err != nil {
+		t.Fatalf("UpdateTimesheet: %v", err)
+	}
+
+	err = service.TransitionTimesheet(read, constants.Approved, "owner@example.com", "")
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("TransitionTimesheet error = %v, want FailedPrecondition", err)
+	}
+	if read.Status != constants.UnderReview {
+		t.Errorf("status of the read timesheet = %v, want %v", read.Status, constants.UnderReview)
+	}
+
+	// The version that was read again moves, and only its status and reason are written
+	read, err = timesheetsDB.GetTimesheetByID(timesheet.ID)
+	if err != nil {
+		t.Fatalf("GetTimesheetByID: %v", err)
+	}
+	read.StorageURL = "stale.xlsx"
+
+	err = service.TransitionTimesheet(read, constants.Rejected, "owner@example.com", "Missing a day")
+	if err != nil {
+		t.Fatalf("TransitionTimesheet: %v", err)
+	}
+
+	got, err := timesheetsDB.GetTimesheetByID(timesheet.ID)
+	if err != nil {
+		t.Fatalf("GetTimesheetByID: %v", err)
+	}
+	if got.Status != constants.Rejected || got.RejectionReason != "Missing a day" || got.StorageURL != "v2.xlsx" || got.Version != 2 || got.ContentHash != "h2" {
+		t.Errorf("timesheet = %+v, want version 2 rejected with its reason", got)
+	}
+
+	auditLog, err := auditLogDB.ListAuditEntries(timesheet.ID)
+	if err != nil {
+		t.Fatalf("ListAuditEntries: %v", err)
+	}
+	if len(auditLog) != 1 || auditLog[0].Status != constants.Rejected {
+		t.Errorf("audit log = %+v, want one rejection", auditLog)
+	}
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+
+	"cloud.google.com/go/firestore"
+)
+
+// TimesheetVersionsDatabaseService is a service for managing the files submitted for the timesheets in a database.
+type TimesheetVersionsDatabaseService struct {
+	collectionName string
+	client         *firestore.Client
+}
+
+// Ensure TimesheetVersionsDatabaseService implements ITimesheetVersionsDatabaseService.
+var _ interfaces.ITimesheetVersionsDatabaseService = &TimesheetVersionsDatabaseService{}
+
+// NewTimesheetVersionsDatabaseService creates a new TimesheetVersionsDatabaseService.
+func NewTimesheetVersionsDatabaseService(firebaseService *FirebaseService) (*TimesheetVersionsDatabaseService, error) {
+	ctx := context.Background()
+	client, err := firebaseService.app.Firestore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not get Firestore client: %w", err)
+	}
+
+	// Verify that we can communicate and authenticate with the Firestore service.
+	err = client.RunTransaction(ctx, func(ctx context.Context, t *firestore.Transaction) error {
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not connect: %w", err)
+	}
+
+	return &TimesheetVersionsDatabaseService{
+		collectionName: "timesheet_versions",
+		client:         client,
+	}, nil
+}
+
+// Close closes the database.
+func (db *TimesheetVersionsDatabaseService) Close() error {
+	return db.client.Close()
+}
+
+// ListTimesheetVersions lists the versions of a timesheet from the first.
+func (db *TimesheetVersionsDatabaseService) ListTimesheetVersions(timesheetID string) ([]*types.TimesheetVersion, error) {
+	ctx := context.Background()
+	docs, err := db.client.Collection(db.collectionName).Where("timesheet_id", "==", timesheetID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not list timesheet versions: %w", err)
+	}
+
+	versions := make([]*types.TimesheetVersion, 0, len(docs))
+	for _, doc := range docs {
+		var version types.TimesheetVersion
+		err = doc.DataTo(&version)
+		if err != nil {
+			return nil, fmt.Errorf("firestoredb: could not convert data to timesheet version: %w", err)
+		}
+
+		versions = append(versions, &version)
+	}
+
+	sortTimesheetVersions(versions)
+
+	return versions, nil
+}
+
+// sortTimesheetVersions sorts the versions from the first.
+func sortTimesheetVersions(versions []*types.TimesheetVersion) {
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version < versions[j].Version
+	})
+}
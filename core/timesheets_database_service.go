@@ -6,9 +6,12 @@ import (
 
 	"job_sender/interfaces"
 	"job_sender/types"
+	constants "job_sender/utils/constants"
 
 	"cloud.google.com/go/firestore"
 	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // TimesheetsDatabaseService is a service for managing timesheets in a database.
@@ -77,8 +80,7 @@ func (db *TimesheetsDatabaseService) GetTimesheet(contractorID string, requestID
 	iter := db.client.Collection(db.collectionName).Where("contractor_id", "==", contractorID).Where("request_id", "==", requestID).Documents(ctx)
 	doc, err := iter.Next()
 	if err == iterator.Done {
-		// Directly return iterator.Done to indicate no timesheet found
-		return nil, iterator.Done
+		return nil, status.Errorf(codes.NotFound, "timesheet of contractor %s for request %s does not exist", contractorID, requestID)
 	} else if err != nil {
 		return nil, fmt.Errorf("could not get timesheet: %w", err)
 	}
@@ -116,9 +118,17 @@ func (db *TimesheetsDatabaseService) AddTimesheet(timesheet *types.Timesheet) er
 	timesheetMap := map[string]interface{}{
 		"id":            ref.ID,
 		"contractor_id": timesheet.ContractorID,
+		"group_id":      timesheet.GroupID,
 		"request_id":    timesheet.RequestID,
 
-		"storage_url": timesheet.StorageURL,
+		"storage_url":  timesheet.StorageURL,
+		"version":      timesheet.Version,
+		"content_hash": timesheet.ContentHash,
+
+		"total_hours": timesheet.TotalHours,
+
+		"status":           timesheet.Status,
+		"rejection_reason": timesheet.RejectionReason,
 	}
 
 	_, err := ref.Create(ctx, timesheetMap)
@@ -126,6 +136,8 @@ func (db *TimesheetsDatabaseService) AddTimesheet(timesheet *types.Timesheet) er
 		return fmt.Errorf("could not add timesheet: %w", err)
 	}
 
+	timesheet.ID = ref.ID
+
 	return nil
 }
 
@@ -140,6 +152,41 @@ func (db *TimesheetsDatabaseService) UpdateTimesheet(timesheet *types.Timesheet)
 	return nil
 }
 
+// UpdateTimesheetStatus writes the status and the rejection reason of a timesheet still in fromStatus at fromVersion, the other fields are left as they are.
+func (db *TimesheetsDatabaseService) UpdateTimesheetStatus(timesheet *types.Timesheet, fromStatus constants.TimesheetStatuses, fromVersion int) error {
+	ctx := context.Background()
+	ref := db.client.Collection(db.collectionName).Doc(timesheet.ID)
+
+	err := db.client.RunTransaction(ctx, func(ctx context.Context, t *firestore.Transaction) error {
+		doc, err := t.Get(ref)
+		if err != nil {
+			return err
+		}
+
+		var stored types.Timesheet
+		err = doc.DataTo(&stored)
+		if err != nil {
+			return fmt.Errorf("could not convert data to timesheet: %w", err)
+		}
+
+		if stored.Status != fromStatus || stored.Version != fromVersion {
+			return status.Errorf(codes.FailedPrecondition, "timesheet %s is %s at version %d", timesheet.ID, stored.Status, stored.Version)
+		}
+
+		return t.Update(ref, []firestore.Update{
+			{Path: "status", Value: timesheet.Status},
+			{Path: "rejection_reason", Value: timesheet.RejectionReason},
+		})
+	})
+	if status.Code(err) == codes.NotFound || status.Code(err) == codes.FailedPrecondition {
+		return err
+	} else if err != nil {
+		return fmt.Errorf("could not update timesheet status: %w", err)
+	}
+
+	return nil
+}
+
 // DeleteTimesheet deletes a timesheet.
 func (db *TimesheetsDatabaseService) DeleteTimesheet(id string) error {
 	ctx := context.Background()
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type testTokenPayload struct {
+	UserID string `json:"user_id"`
+}
+
+func TestTokenSigner(t *testing.T) {
+	key := []byte("key")
+	signer := newTokenSigner(key, tokenPurposeAccount)
+
+	token, err := signer.createToken(&testTokenPayload{UserID: "u1"})
+	if err != nil {
+		t.Fatalf("createToken: %v", err)
+	}
+
+	payload, signature, _ := strings.Cut(token, ".")
+
+	tests := []struct {
+		name    string
+		signer  *tokenSigner
+		token   string
+		wantErr bool
+	}{
+		{"valid", signer, token, false},
+		{"other purpose", newTokenSigner(key, tokenPurposeSubmission), token, true},
+		{"other key", newTokenSigner([]byte("other"), tokenPurposeAccount), token, true},
+		{"forged payload", signer, base64.RawURLEncoding.EncodeToString([]byte(`{"user_id":"u2"}`)) + "." + signature, true},
+		{"truncated signature", signer, payload + "." + signature[:len(signature)-4], true},
+		{"no signature", signer, payload, true},
+		{"not base64", signer, "!." + signature, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got testTokenPayload
+			err := tt.signer.parseToken(tt.token, &got)
+			if tt.wantErr {
+				if status.Code(err) != codes.PermissionDenied {
+					t.Fatalf("parseToken error = %v, want PermissionDenied", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("parseToken: %v", err)
+			}
+			if got.UserID != "u1" {
+				t.Errorf("UserID = %q, want u1", got.UserID)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnassignedEmailsDatabaseService is a service for keeping the triage of the unassigned emails in a database.
+type UnassignedEmailsDatabaseService struct {
+	collectionName string
+	client         *firestore.Client
+}
+
+// Ensure UnassignedEmailsDatabaseService implements IUnassignedEmailsDatabaseService.
+var _ interfaces.IUnassignedEmailsDatabaseService = &UnassignedEmailsDatabaseService{}
+
+// NewUnassignedEmailsDatabaseService creates a new UnassignedEmailsDatabaseService.
+func NewUnassignedEmailsDatabaseService(firebaseService *FirebaseService) (*UnassignedEmailsDatabaseService, error) {
+	ctx := context.Background()
+	client, err := firebaseService.app.Firestore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not get Firestore client: %w", err)
+	}
+
+	// Verify that we can communicate and authenticate with the Firestore service.
+	err = client.RunTransaction(ctx, func(ctx context.Context, t *firestore.Transaction) error {
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not connect: %w", err)
+	}
+
+	return &UnassignedEmailsDatabaseService{
+		collectionName: "unassigned_emails",
+		client:         client,
+	}, nil
+}
+
+// Close closes the database.
+func (db *UnassignedEmailsDatabaseService) Close() error {
+	return db.client.Close()
+}
+
+// ListUnassignedEmails lists the unassigned emails attributed to a group from the newest.
+func (db *UnassignedEmailsDatabaseService) ListUnassignedEmails(groupID string) ([]*types.UnassignedEmail, error) {
+	ctx := context.Background()
+	docs, err := db.client.Collection(db.collectionName).Where("group_id", "==", groupID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not list unassigned emails: %w", err)
+	}
+
+	emails := make([]*types.UnassignedEmail, 0, len(docs))
+	for _, doc := range docs {
+		var email types.UnassignedEmail
+		err = doc.DataTo(&email)
+		if err != nil {
+			return nil, fmt.Errorf("firestoredb: could not convert data to unassigned email: %w", err)
+		}
+
+		emails = append(emails, &email)
+	}
+
+	sortUnassignedEmails(emails)
+
+	return emails, nil
+}
+
+// GetUnassignedEmail gets an unassigned email by its ID.
+func (db *UnassignedEmailsDatabaseService) GetUnassignedEmail(id string) (*types.UnassignedEmail, error) {
+	ctx := context.Background()
+	doc, err := db.client.Collection(db.collectionName).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, status.Errorf(codes.NotFound, "unassigned email %s does not exist", id)
+	} else if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not get unassigned email: %w", err)
+	}
+
+	var email types.UnassignedEmail
+	err = doc.DataTo(&email)
+	if err != nil {
+		return nil, fmt.Errorf("firestoredb: could not convert data to unassigned email: %w", err)
+	}
+
+	return &email, nil
+}
+
+// SetUnassignedEmail saves an unassigned email, replacing the one with its ID.
+func (db *UnassignedEmailsDatabaseService) SetUnassignedEmail(email *types.UnassignedEmail) error {
+	ctx := context.Background()
+	_, err := db.client.Collection(db.collectionName).Doc(email.ID).Set(ctx, email)
+	if err != nil {
+		return fmt.Errorf("firestoredb: could not set unassigned email: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteUnassignedEmail deletes an unassigned email.
+func (db *UnassignedEmailsDatabaseService) DeleteUnassignedEmail(id string) error {
+	ctx := context.Background()
+	_, err := db.client.Collection(db.collectionName).Doc(id).Delete(ctx)
+	if err != nil {
+		return fmt.Errorf("firestoredb: could not delete unassigned email: %w", err)
+	}
+
+	return nil
+}
+
+// sortUnassignedEmails sorts the unassigned emails from the newest.
+func sortUnassignedEmails(emails []*types.UnassignedEmail) {
+	sort.SliceStable(emails, func(i, j int) bool {
+		return emails[i].UnassignedAt.After(emails[j].UnassignedAt)
+	})
+}
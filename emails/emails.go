@@ -0,0 +1,11 @@
+// Package emails holds the templates of the emails the app sends, embedded in the binary.
+package emails
+
+import (
+	"embed"
+)
+
+// FS holds the layouts of the emails and, in a directory per language, the templates of each email.
+//
+//go:embed layout.html layout.txt en pl
+var FS embed.FS
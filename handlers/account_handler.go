@@ -0,0 +1,372 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type AccountHandler struct {
+	authService           interfaces.IAuthService
+	authorizationService  interfaces.IAuthorizationService
+	accountService        interfaces.IAccountService
+	sessionManagerService interfaces.ISessionManagerService
+	templateService       interfaces.ITemplateService
+	errorReporterService  interfaces.IErrorReporterService
+}
+
+// NewAccountHandler creates a new AccountHandler.
+func NewAccountHandler(authService interfaces.IAuthService, authorizationService interfaces.IAuthorizationService, accountService interfaces.IAccountService, sessionManagerService interfaces.ISessionManagerService, templateService interfaces.ITemplateService, errorReporterService interfaces.IErrorReporterService) *AccountHandler {
+	return &AccountHandler{
+		authService:           authService,
+		authorizationService:  authorizationService,
+		accountService:        accountService,
+		sessionManagerService: sessionManagerService,
+		templateService:       templateService,
+		errorReporterService:  errorReporterService,
+	}
+}
+
+// RegisterAccountHandlers registers the password reset handlers and the handler confirming a new email, the links of the emails are opened without logging in.
+func (h *AccountHandler) RegisterAccountHandlers(r *mux.Router) {
+	r.Methods("GET").Path("/password/forgot").HandlerFunc(h.showForgotPassword)
+	r.Methods("GET").Path(constants.PasswordResetLinkPath).HandlerFunc(h.showResetPassword)
+	r.Methods("GET").Path(constants.EmailChangeLinkPath).HandlerFunc(h.confirmEmailChange)
+
+	r.Methods("POST").Path("/password/forgot").HandlerFunc(h.forgotPassword)
+	r.Methods("POST").Path(constants.PasswordResetLinkPath).HandlerFunc(h.resetPassword)
+}
+
+// RegisterAccountAuthHandlers registers the handlers of the logged user's account.
+func (h *AccountHandler) RegisterAccountAuthHandlers(r *mux.Router) {
+	r.Methods("GET").Path("/account/email").HandlerFunc(h.showChangeEmail)
+	r.Methods("GET").Path("/account/sessions").HandlerFunc(h.showSessions)
+
+	r.Methods("POST").Path("/account/email").HandlerFunc(h.changeEmail)
+	r.Methods("POST").Path("/account/sessions/{ID}/signout").HandlerFunc(h.signOutSession)
+}
+
+// showForgotPassword displays the page asking for the email to send a password reset link to.
+func (h *AccountHandler) showForgotPassword(w http.ResponseWriter, r *http.Request) {
+	h.render(w, r, constants.TemplateForgotPasswordName, nil)
+}
+
+// forgotPassword sends a password reset link to the email of the form, the page does not tell whether an account has it.
+func (h *AccountHandler) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	email := strings.TrimSpace(r.FormValue("email"))
+	if email == "" {
+		h.render(w, r, constants.TemplateForgotPasswordName, map[string]interface{}{"ErrorMessage": "Email missing"})
+		return
+	}
+
+	err := h.accountService.SendPasswordResetLink(email)
+	if err != nil {
+		h.render(w, r, constants.TemplateForgotPasswordName, map[string]interface{}{"ErrorMessage": "Could not send the password reset link"})
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not send password reset link: %w", err))
+		return
+	}
+
+	h.render(w, r, constants.TemplateForgotPasswordName, map[string]interface{}{
+		"Message": fmt.Sprintf("If an account has this email, a link to reset its password was sent to it. The link is valid for %d minutes.", constants.PasswordResetLinkLifetimeMinutes),
+	})
+}
+
+// showResetPassword displays the form choosing a new password, if the link is valid.
+func (h *AccountHandler) showResetPassword(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+
+	_, err := h.accountService.CheckPasswordResetToken(token)
+	if status.Code(err) == codes.PermissionDenied {
+		h.render(w, r, constants.TemplateResetPasswordName, map[string]interface{}{"ErrorMessage": "The link is invalid, expired or already used. Ask for a new one."})
+		return
+	} else if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not check password reset token: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	h.render(w, r, constants.TemplateResetPasswordName, map[string]interface{}{"Token": token})
+}
+
+// resetPassword sets the new password of the form, every session of the account is logged out.
+func (h *AccountHandler) resetPassword(w http.ResponseWriter, r *http.Request) {
+	token := r.FormValue("token")
+	password := r.FormValue("password")
+	confirmPassword := r.FormValue("confirm_password")
+
+	showError := func(errorMessage string) {
+		h.render(w, r, constants.TemplateResetPasswordName, map[string]interface{}{"ErrorMessage": errorMessage, "Token": token})
+	}
+
+	if password == "" || confirmPassword == "" {
+		showError("Password or confirm password missing")
+		return
+	}
+
+	if password != confirmPassword {
+		showError("Password and confirm password do not match")
+		return
+	}
+
+	if len(password) < constants.PasswordMinLength {
+		showError(fmt.Sprintf("Password must be at least %d characters long", constants.PasswordMinLength))
+		return
+	}
+
+	err := h.accountService.ResetPassword(token, password)
+	if status.Code(err) == codes.PermissionDenied {
+		h.render(w, r, constants.TemplateResetPasswordName, map[string]interface{}{"ErrorMessage": "The link is invalid, expired or already used. Ask for a new one."})
+		return
+	} else if err != nil {
+		showError("Could not reset the password")
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not reset password: %w", err))
+		return
+	}
+
+	h.showLogin(w, r, "Your password was changed, log in with the new password.")
+}
+
+// showChangeEmail displays the form changing the email of the logged user.
+func (h *AccountHandler) showChangeEmail(w http.ResponseWriter, r *http.Request) {
+	h.renderChangeEmail(w, r, nil)
+}
+
+// changeEmail sends the link confirming the new email of the form to the new address, once the user's password is checked.
+// The email changes only when the link is opened.
+func (h *AccountHandler) changeEmail(w http.ResponseWriter, r *http.Request) {
+	userInfo, err := h.authService.CheckUser(r)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not check user: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	email := strings.TrimSpace(r.FormValue("new_email"))
+	password := r.FormValue("password")
+
+	if email == "" || password == "" {
+		h.renderChangeEmail(w, r, map[string]interface{}{"ErrorMessage": "New email or password missing"})
+		return
+	}
+
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		h.renderChangeEmail(w, r, map[string]interface{}{"ErrorMessage": "The new email is not a valid email address"})
+		return
+	}
+
+	if strings.EqualFold(email, userInfo.Email) {
+		h.renderChangeEmail(w, r, map[string]interface{}{"ErrorMessage": "The new email is the current one"})
+		return
+	}
+
+	// The password is asked again, a session left open is not enough to take over the account
+	responseBody, err := h.authService.Login(userInfo.Email, password)
+	if err != nil {
+		h.renderChangeEmail(w, r, map[string]interface{}{"ErrorMessage": "Could not check the password"})
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not login user: %w", err))
+		return
+	}
+
+	if responseBody.IdToken == "" {
+		h.renderChangeEmail(w, r, map[string]interface{}{"ErrorMessage": "Invalid password"})
+		return
+	}
+
+	err = h.accountService.SendEmailChangeLink(responseBody.LocalId, email)
+	if status.Code(err) == codes.AlreadyExists {
+		h.renderChangeEmail(w, r, map[string]interface{}{"ErrorMessage": "An account already has this email"})
+		return
+	} else if err != nil {
+		h.renderChangeEmail(w, r, map[string]interface{}{"ErrorMessage": "Could not send the confirmation link"})
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not send email change link: %w", err))
+		return
+	}
+
+	h.renderChangeEmail(w, r, map[string]interface{}{
+		"Message": fmt.Sprintf("A confirmation link was sent to %s. Your email changes once you open it, within %d hours.", email, constants.EmailChangeLinkLifetimeHours),
+	})
+}
+
+// confirmEmailChange changes the email of the account of the link, every session of the account is logged out.
+func (h *AccountHandler) confirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	link, err := h.accountService.ChangeEmail(r.URL.Query().Get("token"))
+	if status.Code(err) == codes.PermissionDenied {
+		h.showLoginError(w, r, "The link is invalid, expired or already used.")
+		return
+	} else if status.Code(err) == codes.AlreadyExists {
+		h.showLoginError(w, r, "An account already has this email.")
+		return
+	} else if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not change email: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	h.showLogin(w, r, fmt.Sprintf("Your email was changed to %s, log in with it.", link.Email))
+}
+
+// showSessions lists the devices the logged user is logged in on, the current one first.
+func (h *AccountHandler) showSessions(w http.ResponseWriter, r *http.Request) {
+	userInfo, err := h.authService.CheckUser(r)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not check user: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	userID, err := h.authorizationService.GetLoggedOwnerID(r)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get logged owner: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	currentID, err := h.sessionManagerService.GetSessionID(r, constants.UserSessionName)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get session ID: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	userSessions, err := h.sessionManagerService.GetUserSessions(userID)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get sessions: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	var sessions []*types.Session
+	for _, session := range userSessions {
+		if session.Name != constants.UserSessionName {
+			continue
+		}
+
+		if session.ID == currentID {
+			sessions = append([]*types.Session{session}, sessions...)
+		} else {
+			sessions = append(sessions, session)
+		}
+	}
+
+	sessionsTmpl, err := h.templateService.ParseTemplate(constants.TemplateSessionsName)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not parse sessions template: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Sessions":  sessions,
+		"CurrentID": currentID,
+		"SignedOut": r.URL.Query().Get("signedOut") != "",
+	}
+
+	err = h.templateService.ExecuteTemplate(sessionsTmpl, w, r, data, userInfo)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, err)
+	}
+}
+
+// signOutSession logs out a session of the logged user, signing out the current one logs out.
+func (h *AccountHandler) signOutSession(w http.ResponseWriter, r *http.Request) {
+	sessionID := mux.Vars(r)["ID"]
+
+	currentID, err := h.sessionManagerService.GetSessionID(r, constants.UserSessionName)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get session ID: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	if sessionID == currentID {
+		h.showLogin(w, r, "You were logged out.")
+		return
+	}
+
+	userID, err := h.authorizationService.GetLoggedOwnerID(r)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get logged owner: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	// The sessions of other users answer 404 as if they did not exist
+	err = h.sessionManagerService.DeleteUserSession(userID, sessionID)
+	if status.Code(err) == codes.NotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not delete session: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, "/auth/account/sessions?signedOut=1", http.StatusSeeOther)
+}
+
+// renderChangeEmail renders the change email page with the logged user's email.
+func (h *AccountHandler) renderChangeEmail(w http.ResponseWriter, r *http.Request, data map[string]interface{}) {
+	userInfo, err := h.authService.CheckUser(r)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not check user: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	data["Email"] = userInfo.Email
+
+	changeEmailTmpl, err := h.templateService.ParseTemplate(constants.TemplateChangeEmailName)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not parse change email template: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	err = h.templateService.ExecuteTemplate(changeEmailTmpl, w, r, data, userInfo)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, err)
+	}
+}
+
+// showLogin logs out the session of the request, its tokens are revoked, and renders the login page with a message.
+func (h *AccountHandler) showLogin(w http.ResponseWriter, r *http.Request, message string) {
+	err := h.authService.EndSession(w, r)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not end session: %w", err))
+	}
+
+	h.render(w, r, constants.TemplateLoginName, map[string]interface{}{"Message": message})
+}
+
+// showLoginError renders the login page with an error message.
+func (h *AccountHandler) showLoginError(w http.ResponseWriter, r *http.Request, errorMessage string) {
+	h.render(w, r, constants.TemplateLoginName, map[string]interface{}{"ErrorMessage": errorMessage})
+}
+
+// render renders a page of the account flows, they are shown to users not logged in.
+func (h *AccountHandler) render(w http.ResponseWriter, r *http.Request, name string, data map[string]interface{}) {
+	tmpl, err := h.templateService.ParseTemplate(name)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not parse %s template: %w", name, err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	err = h.templateService.ExecuteTemplate(tmpl, w, r, data, nil)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, err)
+	}
+}
@@ -0,0 +1,503 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// addAccountLinks adds a password reset link "reset" and an email change link "change" of owner1.
+func addAccountLinks(ts *testServer) {
+	ts.account.links["reset"] = &types.AccountLink{Purpose: constants.AccountLinkPasswordReset, UserID: "owner1", Email: "owner1@example.com"}
+	ts.account.links["change"] = &types.AccountLink{Purpose: constants.AccountLinkEmailChange, UserID: "owner1", Email: "new@example.com"}
+}
+
+// addUserSessions adds the sessions of owner1, the current one among them, and one of owner2.
+func addUserSessions(ts *testServer) {
+	ts.sessions.sessions = []*types.Session{
+		{ID: "session0", Name: constants.UserSessionName, UserID: "owner1"},
+		{ID: "aggregation", Name: constants.TimesheetAggegationSessionName, UserID: "owner1"},
+		{ID: "session1", Name: constants.UserSessionName, UserID: "owner1"},
+		{ID: "session2", Name: constants.UserSessionName, UserID: "owner2"},
+	}
+}
+
+func TestAccountHandlerPasswordReset(t *testing.T) {
+	reset := url.Values{"token": {"reset"}, "password": {"secret1"}, "confirm_password": {"secret1"}}
+
+	runRouteTests(t, []routeTest{
+		{
+			name:         "forgot password page",
+			method:       "GET",
+			target:       "/password/forgot",
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateForgotPasswordName,
+		},
+		{
+			name:         "forgot password page not parsed",
+			method:       "GET",
+			target:       "/password/forgot",
+			setup:        func(ts *testServer) { ts.templates.fail("ParseTemplate", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "forgot password",
+			method:       "POST",
+			target:       "/password/forgot",
+			form:         url.Values{"email": {" owner1@example.com "}},
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateForgotPasswordName,
+			wantMessage:  "If an account has this email",
+			check: func(t *testing.T, ts *testServer) {
+				if !slices.Equal(ts.account.resetLinksSent, []string{"owner1@example.com"}) {
+					t.Errorf("reset links sent = %v, want owner1@example.com", ts.account.resetLinksSent)
+				}
+			},
+		},
+		{
+			name:         "forgot password without email",
+			method:       "POST",
+			target:       "/password/forgot",
+			form:         url.Values{"email": {" "}},
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateForgotPasswordName,
+			wantMessage:  "Email missing",
+		},
+		{
+			name:         "reset link not sent",
+			method:       "POST",
+			target:       "/password/forgot",
+			form:         url.Values{"email": {"owner1@example.com"}},
+			setup:        func(ts *testServer) { ts.account.fail("SendPasswordResetLink", errFake) },
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateForgotPasswordName,
+			wantMessage:  "Could not send the password reset link",
+			wantReported: true,
+		},
+		{
+			name:         "reset password page",
+			method:       "GET",
+			target:       "/password/reset?token=reset",
+			setup:        addAccountLinks,
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateResetPasswordName,
+			check: func(t *testing.T, ts *testServer) {
+				if token := ts.pageData(t)["Token"]; token != "reset" {
+					t.Errorf("Token = %v, want reset", token)
+				}
+			},
+		},
+		{
+			name:         "reset password page of an invalid link",
+			method:       "GET",
+			target:       "/password/reset?token=change",
+			setup:        addAccountLinks,
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateResetPasswordName,
+			wantMessage:  "The link is invalid, expired or already used",
+		},
+		{
+			name:         "reset password link not checked",
+			method:       "GET",
+			target:       "/password/reset?token=reset",
+			setup:        func(ts *testServer) { ts.account.fail("CheckPasswordResetToken", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "reset password",
+			method:       "POST",
+			target:       "/password/reset",
+			form:         reset,
+			setup:        addAccountLinks,
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateLoginName,
+			wantMessage:  "Your password was changed",
+			check: func(t *testing.T, ts *testServer) {
+				if ts.account.passwords["owner1"] != "secret1" {
+					t.Errorf("password of owner1 = %q, want secret1", ts.account.passwords["owner1"])
+				}
+				if ts.auth.ended != 1 {
+					t.Errorf("sessions ended = %d, want 1", ts.auth.ended)
+				}
+			},
+		},
+		{
+			name:         "reset password without confirmation",
+			method:       "POST",
+			target:       "/password/reset",
+			form:         url.Values{"token": {"reset"}, "password": {"secret1"}},
+			setup:        addAccountLinks,
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateResetPasswordName,
+			wantMessage:  "Password or confirm password missing",
+		},
+		{
+			name:         "reset password with different passwords",
+			method:       "POST",
+			target:       "/password/reset",
+			form:         url.Values{"token": {"reset"}, "password": {"secret1"}, "confirm_password": {"secret2"}},
+			setup:        addAccountLinks,
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateResetPasswordName,
+			wantMessage:  "Password and confirm password do not match",
+		},
+		{
+			name:         "reset password too short",
+			method:       "POST",
+			target:       "/password/reset",
+			form:         url.Values{"token": {"reset"}, "password": {"abc"}, "confirm_password": {"abc"}},
+			setup:        addAccountLinks,
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateResetPasswordName,
+			wantMessage:  "Password must be at least 6 characters long",
+		},
+		{
+			name:         "reset password of an invalid link",
+			method:       "POST",
+			target:       "/password/reset",
+			form:         reset,
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateResetPasswordName,
+			wantMessage:  "The link is invalid, expired or already used",
+		},
+		{
+			name:   "password not reset",
+			method: "POST",
+			target: "/password/reset",
+			form:   reset,
+			setup: func(ts *testServer) {
+				addAccountLinks(ts)
+				ts.account.fail("ResetPassword", errFake)
+			},
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateResetPasswordName,
+			wantMessage:  "Could not reset the password",
+			wantReported: true,
+		},
+	})
+}
+
+func TestAccountHandlerEmailChange(t *testing.T) {
+	change := url.Values{"new_email": {"new@example.com"}, "password": {"password1"}}
+
+	runRouteTests(t, []routeTest{
+		{
+			name:         "change email page",
+			method:       "GET",
+			target:       "/auth/account/email",
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateChangeEmailName,
+			check: func(t *testing.T, ts *testServer) {
+				if email := ts.pageData(t)["Email"]; email != "owner1@example.com" {
+					t.Errorf("Email = %v, want owner1@example.com", email)
+				}
+			},
+		},
+		{
+			name:         "change email page without the user",
+			method:       "GET",
+			target:       "/auth/account/email",
+			setup:        func(ts *testServer) { ts.auth.fail("CheckUser", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "change email page not parsed",
+			method:       "GET",
+			target:       "/auth/account/email",
+			setup:        func(ts *testServer) { ts.templates.fail("ParseTemplate", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "change email",
+			method:       "POST",
+			target:       "/auth/account/email",
+			form:         change,
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateChangeEmailName,
+			wantMessage:  "A confirmation link was sent to new@example.com",
+			check: func(t *testing.T, ts *testServer) {
+				if !slices.Equal(ts.account.emailLinksSent, []string{"new@example.com"}) {
+					t.Errorf("email change links sent = %v, want new@example.com", ts.account.emailLinksSent)
+				}
+			},
+		},
+		{
+			name:         "change email without the user",
+			method:       "POST",
+			target:       "/auth/account/email",
+			form:         change,
+			setup:        func(ts *testServer) { ts.auth.fail("CheckUser", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "change email without password",
+			method:       "POST",
+			target:       "/auth/account/email",
+			form:         url.Values{"new_email": {"new@example.com"}},
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateChangeEmailName,
+			wantMessage:  "New email or password missing",
+		},
+		{
+			name:         "change to an invalid email",
+			method:       "POST",
+			target:       "/auth/account/email",
+			form:         url.Values{"new_email": {"New <new@example.com>"}, "password": {"password1"}},
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateChangeEmailName,
+			wantMessage:  "The new email is not a valid email address",
+		},
+		{
+			name:         "change to the current email",
+			method:       "POST",
+			target:       "/auth/account/email",
+			form:         url.Values{"new_email": {"Owner1@example.com"}, "password": {"password1"}},
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateChangeEmailName,
+			wantMessage:  "The new email is the current one",
+		},
+		{
+			name:         "change email with a wrong password",
+			method:       "POST",
+			target:       "/auth/account/email",
+			form:         url.Values{"new_email": {"new@example.com"}, "password": {"wrong"}},
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateChangeEmailName,
+			wantMessage:  "Invalid password",
+		},
+		{
+			name:         "password not checked",
+			method:       "POST",
+			target:       "/auth/account/email",
+			form:         change,
+			setup:        func(ts *testServer) { ts.auth.fail("Login", errFake) },
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateChangeEmailName,
+			wantMessage:  "Could not check the password",
+			wantReported: true,
+		},
+		{
+			name:   "change to the email of another account",
+			method: "POST",
+			target: "/auth/account/email",
+			form:   change,
+			setup: func(ts *testServer) {
+				ts.account.fail("SendEmailChangeLink", status.Error(codes.AlreadyExists, "email taken"))
+			},
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateChangeEmailName,
+			wantMessage:  "An account already has this email",
+		},
+		{
+			name:         "confirmation link not sent",
+			method:       "POST",
+			target:       "/auth/account/email",
+			form:         change,
+			setup:        func(ts *testServer) { ts.account.fail("SendEmailChangeLink", errFake) },
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateChangeEmailName,
+			wantMessage:  "Could not send the confirmation link",
+			wantReported: true,
+		},
+		{
+			name:         "confirm email change",
+			method:       "GET",
+			target:       "/account/email/confirm?token=change",
+			setup:        addAccountLinks,
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateLoginName,
+			wantMessage:  "Your email was changed to new@example.com",
+			check: func(t *testing.T, ts *testServer) {
+				if ts.auth.ended != 1 {
+					t.Errorf("sessions ended = %d, want 1", ts.auth.ended)
+				}
+			},
+		},
+		{
+			name:         "confirm email change of an invalid link",
+			method:       "GET",
+			target:       "/account/email/confirm?token=reset",
+			setup:        addAccountLinks,
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateLoginName,
+			wantMessage:  "The link is invalid, expired or already used",
+		},
+		{
+			name:   "confirm email change to a taken email",
+			method: "GET",
+			target: "/account/email/confirm?token=change",
+			setup: func(ts *testServer) {
+				ts.account.fail("ChangeEmail", status.Error(codes.AlreadyExists, "email taken"))
+			},
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateLoginName,
+			wantMessage:  "An account already has this email",
+		},
+		{
+			name:         "email not changed",
+			method:       "GET",
+			target:       "/account/email/confirm?token=change",
+			setup:        func(ts *testServer) { ts.account.fail("ChangeEmail", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+	})
+}
+
+func TestAccountHandlerSessions(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name:         "sessions page",
+			method:       "GET",
+			target:       "/auth/account/sessions?signedOut=1",
+			setup:        addUserSessions,
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateSessionsName,
+			check: func(t *testing.T, ts *testServer) {
+				data := ts.pageData(t)
+
+				// The current session first, the other users' sessions and the other cookies are not listed
+				var ids []string
+				for _, session := range data["Sessions"].([]*types.Session) {
+					ids = append(ids, session.ID)
+				}
+				if !slices.Equal(ids, []string{"session1", "session0"}) {
+					t.Errorf("sessions = %v, want [session1 session0]", ids)
+				}
+				if data["SignedOut"] != true {
+					t.Errorf("SignedOut = %v, want true", data["SignedOut"])
+				}
+			},
+		},
+		{
+			name:         "sessions page without the user",
+			method:       "GET",
+			target:       "/auth/account/sessions",
+			setup:        func(ts *testServer) { ts.auth.fail("CheckUser", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "sessions page without the owner",
+			method:       "GET",
+			target:       "/auth/account/sessions",
+			setup:        func(ts *testServer) { delete(ts.sessions.values, constants.SesstionOwnerIdField) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "sessions page without the session ID",
+			method:       "GET",
+			target:       "/auth/account/sessions",
+			setup:        func(ts *testServer) { ts.sessions.fail("GetSessionID", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "sessions not listed",
+			method:       "GET",
+			target:       "/auth/account/sessions",
+			setup:        func(ts *testServer) { ts.sessions.fail("GetUserSessions", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "sessions page not parsed",
+			method:       "GET",
+			target:       "/auth/account/sessions",
+			setup:        func(ts *testServer) { ts.templates.fail("ParseTemplate", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "sign out another session",
+			method:       "POST",
+			target:       "/auth/account/sessions/session0/signout",
+			setup:        addUserSessions,
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/account/sessions?signedOut=1",
+			check: func(t *testing.T, ts *testServer) {
+				if len(ts.sessions.sessions) != 3 {
+					t.Errorf("sessions = %d, want 3", len(ts.sessions.sessions))
+				}
+			},
+		},
+		{
+			name:         "sign out the current session",
+			method:       "POST",
+			target:       "/auth/account/sessions/session1/signout",
+			setup:        addUserSessions,
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateLoginName,
+			wantMessage:  "You were logged out",
+			check: func(t *testing.T, ts *testServer) {
+				if ts.auth.ended != 1 {
+					t.Errorf("sessions ended = %d, want 1", ts.auth.ended)
+				}
+			},
+		},
+		{
+			name:        "sign out the session of another owner",
+			method:      "POST",
+			target:      "/auth/account/sessions/session2/signout",
+			setup:       addUserSessions,
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+			check: func(t *testing.T, ts *testServer) {
+				if len(ts.sessions.sessions) != 4 {
+					t.Errorf("sessions = %d, want 4", len(ts.sessions.sessions))
+				}
+			},
+		},
+		{
+			name:         "sign out without the session ID",
+			method:       "POST",
+			target:       "/auth/account/sessions/session0/signout",
+			setup:        func(ts *testServer) { ts.sessions.fail("GetSessionID", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "sign out without the owner",
+			method:       "POST",
+			target:       "/auth/account/sessions/session0/signout",
+			setup:        func(ts *testServer) { delete(ts.sessions.values, constants.SesstionOwnerIdField) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "session not signed out",
+			method:       "POST",
+			target:       "/auth/account/sessions/session0/signout",
+			setup:        func(ts *testServer) { ts.sessions.fail("DeleteUserSession", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+	})
+}
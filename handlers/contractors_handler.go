@@ -3,28 +3,27 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 
-	"job_sender/core"
+	"job_sender/interfaces"
 	"job_sender/types"
 	constants "job_sender/utils/constants"
 
 	"github.com/gorilla/mux"
-	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 type ContractorsHandler struct {
-	authService          *core.AuthService
-	cloudTaskService     *core.CloudTasksService
-	templateService      *core.TemplateService
-	errorReporterService *core.ErrorReporterService
-
-	groupsDB      *core.GroupsDatabaseService
-	contractorsDB *core.ContractorsDatabaseService
-	timesheetsDB  *core.TimesheetsDatabaseService
-
-	envVariables *types.EnvVariables
+	authService          interfaces.IAuthService
+	authorizationService interfaces.IAuthorizationService
+	templateService      interfaces.ITemplateService
+	errorReporterService interfaces.IErrorReporterService
+
+	groupsDB      interfaces.IGroupsDatabaseService
+	contractorsDB interfaces.IContractorsDatabaseService
+	timesheetsDB  interfaces.ITimesheetsDatabaseService
 }
 
 type contractorWithTimesheets struct {
@@ -33,18 +32,16 @@ type contractorWithTimesheets struct {
 }
 
 // NewContractorsHandler creates a new ContractorsHandler.
-func NewContractorsHandler(authService *core.AuthService, cloudTaskService *core.CloudTasksService, templateService *core.TemplateService, errorReporterService *core.ErrorReporterService, groupsDB *core.GroupsDatabaseService, contractorsDB *core.ContractorsDatabaseService, timesheetsDB *core.TimesheetsDatabaseService, envVariables *types.EnvVariables) *ContractorsHandler {
+func NewContractorsHandler(authService interfaces.IAuthService, authorizationService interfaces.IAuthorizationService, templateService interfaces.ITemplateService, errorReporterService interfaces.IErrorReporterService, groupsDB interfaces.IGroupsDatabaseService, contractorsDB interfaces.IContractorsDatabaseService, timesheetsDB interfaces.ITimesheetsDatabaseService) *ContractorsHandler {
 	return &ContractorsHandler{
 		authService:          authService,
-		cloudTaskService:     cloudTaskService,
+		authorizationService: authorizationService,
 		templateService:      templateService,
 		errorReporterService: errorReporterService,
 
 		groupsDB:      groupsDB,
 		contractorsDB: contractorsDB,
 		timesheetsDB:  timesheetsDB,
-
-		envVariables: envVariables,
 	}
 }
 
@@ -76,29 +73,15 @@ func (h *ContractorsHandler) GetContractors(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Get the group.
-	group, err := h.groupsDB.GetGroup(groupID)
-	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			// Display the add group page.
-			groupTmpl, err := h.templateService.ParseTemplate(constants.TemplateGroupEditName)
-			if err != nil {
-				h.errorReporterService.ReportError(w, r, fmt.Errorf("could not parse group template: %w", err))
-				http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
-				return
-			}
-
-			err = h.templateService.ExecuteTemplate(groupTmpl, w, r, nil, userInfo)
-			if err != nil {
-				h.errorReporterService.ReportError(w, r, err)
-				http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
-				return
-			}
-		} else {
-			h.errorReporterService.ReportError(w, r, err)
-			http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
-			return
-		}
+	// Get the group, the groups of other owners are not found.
+	group, err := h.authorizationService.GetOwnedGroup(r, groupID)
+	if status.Code(err) == codes.NotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		h.errorReporterService.ReportError(w, r, err)
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
 	}
 
 	// Add the groupInfo to the userInfo
@@ -113,45 +96,17 @@ func (h *ContractorsHandler) GetContractors(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	var timesheets []*types.Timesheet
-
-	// Start timeheet aggregation for each contractor
-	for _, contractor := range contractors {
-		for _, request := range contractor.LastRequests {
-			if request.Timestamp == 0 {
-				_, err := h.cloudTaskService.CreateTimesheetAggregatorTask(h.envVariables.ProjectID, h.envVariables.ProjectLocationID, h.envVariables.EmailAggregatorQueueName, contractor, types.TimesheetAggregation{
-					RequestID: request.ID,
-
-					Contractor: contractor,
-				})
-				if err != nil {
-					h.errorReporterService.ReportError(w, r, fmt.Errorf("could not create timesheet aggregator task: %w", err))
-					continue
-				}
-			}
-
-			// Get the timesheet
-			timesheet, err := h.timesheetsDB.GetTimesheet(contractor.ID, request.ID)
-			if err != nil {
-				if err == iterator.Done {
-					// No timesheet found
-					continue
-				}
-				h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get timesheet: %w", err))
-				continue
-			}
-
-			timesheets = append(timesheets, timesheet)
-		}
-
-		// Update the contractors last aggregation timestamp
-		err = h.contractorsDB.UpdateContractor(contractor)
-		if err != nil {
-			h.errorReporterService.ReportError(w, r, fmt.Errorf("could not update contractor: %w", err))
-			continue
-		}
+	// Get the timesheets of the group, the inbox watcher collects them as the replies arrive
+	timesheets, err := h.timesheetsDB.ListTimesheets(groupID)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not list timesheets: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
 	}
 
+	// Oldest first, the periods of the request IDs sort by their first day
+	slices.SortFunc(timesheets, func(a, b *types.Timesheet) int { return strings.Compare(a.RequestID, b.RequestID) })
+
 	var contractorsWithTimesheets []contractorWithTimesheets
 
 	for _, contractor := range contractors {
@@ -196,8 +151,11 @@ func (h *ContractorsHandler) ShowAddContractor(w http.ResponseWriter, r *http.Re
 	}
 
 	// Get the group.
-	group, err := h.groupsDB.GetGroup(groupID)
-	if err != nil {
+	group, err := h.authorizationService.GetOwnedGroup(r, groupID)
+	if status.Code(err) == codes.NotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
 		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get group: %w", err))
 		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
 		return
@@ -246,9 +204,12 @@ func (h *ContractorsHandler) ShowEditContractor(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// Get the contractor.
-	contractor, err := h.contractorsDB.GetContractor(id)
-	if err != nil {
+	// Get the contractor, the contractors of other owners are not found.
+	contractor, err := h.authorizationService.GetOwnedContractor(r, id)
+	if status.Code(err) == codes.NotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
 		h.errorReporterService.ReportError(w, r, err)
 		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
 		return
@@ -297,6 +258,17 @@ func (h *ContractorsHandler) AddContractor(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// Contractors are only added to the groups of the owner.
+	_, err := h.authorizationService.GetOwnedGroup(r, groupID)
+	if status.Code(err) == codes.NotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get group: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
 	// Get the contractor from the form.
 	contractor, err := h.contractorFromForm(r)
 	if err != nil {
@@ -332,6 +304,22 @@ func (h *ContractorsHandler) EditContractor(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	// The contractor must be of a group of the owner and stay in it.
+	storedContractor, err := h.authorizationService.GetOwnedContractor(r, id)
+	if status.Code(err) == codes.NotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get contractor: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	if storedContractor.GroupID != groupID {
+		http.NotFound(w, r)
+		return
+	}
+
 	// Get the contractor from the form.
 	contractor, err := h.contractorFromForm(r)
 	if err != nil {
@@ -362,15 +350,26 @@ func (h *ContractorsHandler) DeleteContractor(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// The contractors of other owners are not found.
+	contractor, err := h.authorizationService.GetOwnedContractor(r, id)
+	if status.Code(err) == codes.NotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get contractor: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
 	// Delete the contractor.
-	err := h.contractorsDB.DeleteContractor(id)
+	err = h.contractorsDB.DeleteContractor(id)
 	if err != nil {
 		h.errorReporterService.ReportError(w, r, err)
 		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
 		return
 	}
 
-	http.Redirect(w, r, "/auth/contractors", http.StatusSeeOther) // TODO: group id is needed
+	http.Redirect(w, r, "/auth/contractors?groupID="+contractor.GroupID, http.StatusSeeOther)
 }
 
 // contractorFromForm creates a contractor from a form.
@@ -391,6 +390,11 @@ func (h *ContractorsHandler) contractorFromForm(r *http.Request) (*types.Contrac
 		Email:    r.FormValue("email"),
 		Phone:    r.FormValue("phone"),
 		PhotoURL: r.FormValue("photoURL"),
+		Language: r.FormValue("language"),
+	}
+
+	if contractor.Language != constants.EnglishLanguage && contractor.Language != constants.PolishLanguage {
+		contractor.Language = constants.DefaultLanguage
 	}
 
 	return contractor, nil
@@ -0,0 +1,372 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+)
+
+func TestContractorsHandler(t *testing.T) {
+	contractor := url.Values{"name": {"Jan"}, "surname": {"Kowalski"}, "email": {"jan@example.com"}, "language": {constants.PolishLanguage}}
+
+	runRouteTests(t, []routeTest{
+		{
+			name:         "contractors",
+			method:       "GET",
+			target:       "/auth/contractors?groupID=group1",
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateContractorsGetName,
+			check: func(t *testing.T, ts *testServer) {
+				contractors := ts.pageData(t)["ContractorsWithTimesheets"].([]contractorWithTimesheets)
+				if len(contractors) != 1 || contractors[0].Contractor.ID != "contractor1" || len(contractors[0].Timesheets) != 1 {
+					t.Errorf("contractors = %+v, want contractor1 with its timesheet", contractors)
+				}
+				if ts.templates.userInfo.GroupName != "Group 1" {
+					t.Errorf("GroupName of the user = %q, want Group 1", ts.templates.userInfo.GroupName)
+				}
+			},
+		},
+		{
+			name:        "contractors without a group",
+			method:      "GET",
+			target:      "/auth/contractors",
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "groupID is required",
+		},
+		{
+			name:        "contractors of another owner's group",
+			method:      "GET",
+			target:      "/auth/contractors?groupID=group2",
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+		},
+		{
+			name:         "contractors without the user",
+			method:       "GET",
+			target:       "/auth/contractors?groupID=group1",
+			setup:        func(ts *testServer) { ts.auth.fail("CheckUser", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "contractors without the group",
+			method:       "GET",
+			target:       "/auth/contractors?groupID=group1",
+			setup:        func(ts *testServer) { ts.db.fail("GetGroup", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "contractors not read",
+			method:       "GET",
+			target:       "/auth/contractors?groupID=group1",
+			setup:        func(ts *testServer) { ts.db.fail("GetContractors", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "timesheets not listed",
+			method:       "GET",
+			target:       "/auth/contractors?groupID=group1",
+			setup:        func(ts *testServer) { ts.db.fail("ListTimesheets", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "contractors page not parsed",
+			method:       "GET",
+			target:       "/auth/contractors?groupID=group1",
+			setup:        func(ts *testServer) { ts.templates.fail("ParseTemplate", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "contractors page not executed",
+			method:       "GET",
+			target:       "/auth/contractors?groupID=group1",
+			setup:        func(ts *testServer) { ts.templates.fail("ExecuteTemplate", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "add contractor page",
+			method:       "GET",
+			target:       "/auth/contractors/add?groupID=group1",
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateContractorsAddName,
+		},
+		{
+			name:        "add contractor page without a group",
+			method:      "GET",
+			target:      "/auth/contractors/add",
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "groupID is required",
+		},
+		{
+			name:        "add contractor page of another owner's group",
+			method:      "GET",
+			target:      "/auth/contractors/add?groupID=group2",
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+		},
+		{
+			name:         "add contractor page without the group",
+			method:       "GET",
+			target:       "/auth/contractors/add?groupID=group1",
+			setup:        func(ts *testServer) { ts.db.fail("GetGroup", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "add contractor page without the user",
+			method:       "GET",
+			target:       "/auth/contractors/add?groupID=group1",
+			setup:        func(ts *testServer) { ts.auth.fail("CheckUser", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "add contractor page not parsed",
+			method:       "GET",
+			target:       "/auth/contractors/add?groupID=group1",
+			setup:        func(ts *testServer) { ts.templates.fail("ParseTemplate", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "edit contractor page",
+			method:       "GET",
+			target:       "/auth/contractors/contractor1/edit",
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateContractorsEditName,
+			check: func(t *testing.T, ts *testServer) {
+				if contractor, ok := ts.templates.data.(*types.Contractor); !ok || contractor.ID != "contractor1" {
+					t.Errorf("page data = %+v, want contractor1", ts.templates.data)
+				}
+			},
+		},
+		{
+			name:        "edit page of another owner's contractor",
+			method:      "GET",
+			target:      "/auth/contractors/contractor2/edit",
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+		},
+		{
+			name:         "edit contractor page without the contractor",
+			method:       "GET",
+			target:       "/auth/contractors/contractor1/edit",
+			setup:        func(ts *testServer) { ts.db.fail("GetContractor", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "edit contractor page without the user",
+			method:       "GET",
+			target:       "/auth/contractors/contractor1/edit",
+			setup:        func(ts *testServer) { ts.auth.fail("CheckUser", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "edit contractor page not parsed",
+			method:       "GET",
+			target:       "/auth/contractors/contractor1/edit",
+			setup:        func(ts *testServer) { ts.templates.fail("ParseTemplate", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "add contractor",
+			method:       "POST",
+			target:       "/auth/contractors?groupID=group1",
+			form:         contractor,
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/contractors?groupID=group1",
+			check: func(t *testing.T, ts *testServer) {
+				added := ts.db.contractors["new-contractor1"]
+				if added == nil || added.GroupID != "group1" || added.Email != "jan@example.com" || added.Language != constants.PolishLanguage {
+					t.Errorf("contractor = %+v, want jan@example.com in group1 writing Polish", added)
+				}
+			},
+		},
+		{
+			name:         "add contractor with an unknown language",
+			method:       "POST",
+			target:       "/auth/contractors?groupID=group1",
+			form:         url.Values{"name": {"Jan"}, "email": {"jan@example.com"}, "language": {"xx"}},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/contractors?groupID=group1",
+			check: func(t *testing.T, ts *testServer) {
+				if language := ts.db.contractors["new-contractor1"].Language; language != constants.DefaultLanguage {
+					t.Errorf("language = %q, want %q", language, constants.DefaultLanguage)
+				}
+			},
+		},
+		{
+			name:        "add contractor without a group",
+			method:      "POST",
+			target:      "/auth/contractors",
+			form:        contractor,
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "groupID is required",
+		},
+		{
+			name:        "add contractor to another owner's group",
+			method:      "POST",
+			target:      "/auth/contractors?groupID=group2",
+			form:        contractor,
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+			check: func(t *testing.T, ts *testServer) {
+				if len(ts.db.contractors) != 2 {
+					t.Errorf("contractors = %d, want 2", len(ts.db.contractors))
+				}
+			},
+		},
+		{
+			name:         "add contractor without the group",
+			method:       "POST",
+			target:       "/auth/contractors?groupID=group1",
+			form:         contractor,
+			setup:        func(ts *testServer) { ts.db.fail("GetGroup", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "contractor not added",
+			method:       "POST",
+			target:       "/auth/contractors?groupID=group1",
+			form:         contractor,
+			setup:        func(ts *testServer) { ts.db.fail("AddContractor", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "edit contractor",
+			method:       "POST",
+			target:       "/auth/contractors/contractor1?groupID=group1",
+			form:         contractor,
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/contractors?groupID=group1",
+			check: func(t *testing.T, ts *testServer) {
+				if edited := ts.db.contractors["contractor1"]; edited.Name != "Jan" || edited.GroupID != "group1" {
+					t.Errorf("contractor1 = %+v, want Jan in group1", edited)
+				}
+			},
+		},
+		{
+			name:        "edit contractor without a group",
+			method:      "POST",
+			target:      "/auth/contractors/contractor1",
+			form:        contractor,
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "groupID is required",
+		},
+		{
+			name:        "edit another owner's contractor",
+			method:      "POST",
+			target:      "/auth/contractors/contractor2?groupID=group1",
+			form:        contractor,
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+			check: func(t *testing.T, ts *testServer) {
+				if edited := ts.db.contractors["contractor2"]; edited.Name != "Contractor" || edited.GroupID != "group2" {
+					t.Errorf("contractor2 = %+v, want it unchanged", edited)
+				}
+			},
+		},
+		{
+			name:        "move contractor to another group",
+			method:      "POST",
+			target:      "/auth/contractors/contractor1?groupID=group2",
+			form:        contractor,
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+			check: func(t *testing.T, ts *testServer) {
+				if groupID := ts.db.contractors["contractor1"].GroupID; groupID != "group1" {
+					t.Errorf("group of contractor1 = %q, want group1", groupID)
+				}
+			},
+		},
+		{
+			name:         "edit contractor without the contractor",
+			method:       "POST",
+			target:       "/auth/contractors/contractor1?groupID=group1",
+			form:         contractor,
+			setup:        func(ts *testServer) { ts.db.fail("GetContractor", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "contractor not updated",
+			method:       "POST",
+			target:       "/auth/contractors/contractor1?groupID=group1",
+			form:         contractor,
+			setup:        func(ts *testServer) { ts.db.fail("UpdateContractor", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "delete contractor",
+			method:       "DELETE",
+			target:       "/auth/contractors/contractor1",
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/contractors?groupID=group1",
+			check: func(t *testing.T, ts *testServer) {
+				if _, ok := ts.db.contractors["contractor1"]; ok {
+					t.Error("contractor1 was not deleted")
+				}
+			},
+		},
+		{
+			name:        "delete another owner's contractor",
+			method:      "DELETE",
+			target:      "/auth/contractors/contractor2",
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+			check: func(t *testing.T, ts *testServer) {
+				if _, ok := ts.db.contractors["contractor2"]; !ok {
+					t.Error("contractor2 was deleted")
+				}
+			},
+		},
+		{
+			name:         "delete contractor without the contractor",
+			method:       "DELETE",
+			target:       "/auth/contractors/contractor1",
+			setup:        func(ts *testServer) { ts.db.fail("GetContractor", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "contractor not deleted",
+			method:       "DELETE",
+			target:       "/auth/contractors/contractor1",
+			setup:        func(ts *testServer) { ts.db.fail("DeleteContractor", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+	})
+}
@@ -0,0 +1,1503 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"github.com/gorilla/sessions"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errFake is the error the fakes return from the methods made to fail.
+var errFake = errors.New("fake failure")
+
+// fakeErrors are the errors the methods of a fake return, by method name, the other methods succeed.
+type fakeErrors struct {
+	errs map[string]error
+}
+
+// fail makes a method of the fake return an error.
+func (f *fakeErrors) fail(method string, err error) {
+	if f.errs == nil {
+		f.errs = make(map[string]error)
+	}
+	f.errs[method] = err
+}
+
+// err returns the error a method of the fake returns, nil if it succeeds.
+func (f *fakeErrors) err(method string) error {
+	return f.errs[method]
+}
+
+// fakeDatabase keeps the owners, groups, contractors, requests and timesheets in memory, it implements every database service the handlers use.
+// The getters return copies, as the databases do.
+type fakeDatabase struct {
+	fakeErrors
+
+	owners      map[string]*types.Owner
+	groups      map[string]*types.Group
+	contractors map[string]*types.Contractor
+	requests    []*types.TimesheetRequest
+	timesheets  map[string]*types.Timesheet
+	versions    map[string][]*types.TimesheetVersion
+	entries     map[string][]*types.TimesheetEntry
+	auditLog    map[string][]*types.TimesheetAuditEntry
+
+	unassignedEmails map[string]*types.UnassignedEmail
+
+	nextID int
+}
+
+// Ensure fakeDatabase implements the database services.
+var _ interfaces.IOwnerDatabaseService = &fakeDatabase{}
+var _ interfaces.IGroupsDatabaseService = &fakeDatabase{}
+var _ interfaces.IContractorsDatabaseService = &fakeDatabase{}
+var _ interfaces.ITimesheetsDatabaseService = &fakeDatabase{}
+var _ interfaces.ITimesheetRequestsDatabaseService = &fakeDatabase{}
+var _ interfaces.ITimesheetVersionsDatabaseService = &fakeDatabase{}
+var _ interfaces.ITimesheetEntriesDatabaseService = &fakeDatabase{}
+var _ interfaces.ITimesheetAuditLogDatabaseService = &fakeDatabase{}
+var _ interfaces.IUnassignedEmailsDatabaseService = &fakeDatabase{}
+
+func newFakeDatabase() *fakeDatabase {
+	return &fakeDatabase{
+		owners:      make(map[string]*types.Owner),
+		groups:      make(map[string]*types.Group),
+		contractors: make(map[string]*types.Contractor),
+		timesheets:  make(map[string]*types.Timesheet),
+		versions:    make(map[string][]*types.TimesheetVersion),
+		entries:     make(map[string][]*types.TimesheetEntry),
+		auditLog:    make(map[string][]*types.TimesheetAuditEntry),
+
+		unassignedEmails: make(map[string]*types.UnassignedEmail),
+	}
+}
+
+func (db *fakeDatabase) newID(prefix string) string {
+	db.nextID++
+	return fmt.Sprintf("new-%s%d", prefix, db.nextID)
+}
+
+func (db *fakeDatabase) GetOwnerByEmail(email string) (*types.Owner, error) {
+	if err := db.err("GetOwnerByEmail"); err != nil {
+		return nil, err
+	}
+
+	for _, owner := range db.owners {
+		if owner.Email == email {
+			o := *owner
+			return &o, nil
+		}
+	}
+
+	return nil, status.Errorf(codes.NotFound, "owner %s not found", email)
+}
+
+func (db *fakeDatabase) GetOwnerByID(id string) (*types.Owner, error) {
+	if err := db.err("GetOwnerByID"); err != nil {
+		return nil, err
+	}
+
+	owner, ok := db.owners[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "owner %s not found", id)
+	}
+
+	o := *owner
+	return &o, nil
+}
+
+func (db *fakeDatabase) AddOwner(owner *types.Owner) error {
+	if err := db.err("AddOwner"); err != nil {
+		return err
+	}
+
+	o := *owner
+	db.owners[owner.ID] = &o
+	return nil
+}
+
+func (db *fakeDatabase) UpdateOwner(owner *types.Owner) error {
+	if err := db.err("UpdateOwner"); err != nil {
+		return err
+	}
+
+	o := *owner
+	db.owners[owner.ID] = &o
+	return nil
+}
+
+func (db *fakeDatabase) DeleteOwner(id string) error {
+	if err := db.err("DeleteOwner"); err != nil {
+		return err
+	}
+
+	delete(db.owners, id)
+	return nil
+}
+
+func (db *fakeDatabase) GetGroup(id string) (*types.Group, error) {
+	if err := db.err("GetGroup"); err != nil {
+		return nil, err
+	}
+
+	group, ok := db.groups[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "group %s not found", id)
+	}
+
+	g := *group
+	return &g, nil
+}
+
+func (db *fakeDatabase) GetAllGroups() ([]*types.Group, error) {
+	if err := db.err("GetAllGroups"); err != nil {
+		return nil, err
+	}
+
+	var groups []*types.Group
+	for _, group := range db.groups {
+		g := *group
+		groups = append(groups, &g)
+	}
+	return groups, nil
+}
+
+func (db *fakeDatabase) AddGroup(group *types.Group) (*types.Group, error) {
+	if err := db.err("AddGroup"); err != nil {
+		return nil, err
+	}
+
+	group.ID = db.newID("group")
+	g := *group
+	db.groups[group.ID] = &g
+	return group, nil
+}
+
+func (db *fakeDatabase) UpdateGroup(group *types.Group) error {
+	if err := db.err("UpdateGroup"); err != nil {
+		return err
+	}
+
+	g := *group
+	db.groups[group.ID] = &g
+	return nil
+}
+
+func (db *fakeDatabase) DeleteGroup(id string) error {
+	if err := db.err("DeleteGroup"); err != nil {
+		return err
+	}
+
+	delete(db.groups, id)
+	return nil
+}
+
+func (db *fakeDatabase) GetContractors(groupID string) ([]*types.Contractor, error) {
+	if err := db.err("GetContractors"); err != nil {
+		return nil, err
+	}
+
+	var contractors []*types.Contractor
+	for _, contractor := range db.contractors {
+		if contractor.GroupID == groupID {
+			c := *contractor
+			contractors = append(contractors, &c)
+		}
+	}
+	slices.SortFunc(contractors, func(a, b *types.Contractor) int { return strings.Compare(a.ID, b.ID) })
+
+	return contractors, nil
+}
+
+func (db *fakeDatabase) GetAllContractors() ([]*types.Contractor, error) {
+	if err := db.err("GetAllContractors"); err != nil {
+		return nil, err
+	}
+
+	var contractors []*types.Contractor
+	for _, contractor := range db.contractors {
+		c := *contractor
+		contractors = append(contractors, &c)
+	}
+
+	return contractors, nil
+}
+
+func (db *fakeDatabase) GetContractorsByEmail(email string) ([]*types.Contractor, error) {
+	if err := db.err("GetContractorsByEmail"); err != nil {
+		return nil, err
+	}
+
+	var contractors []*types.Contractor
+	for _, contractor := range db.contractors {
+		if strings.EqualFold(contractor.Email, email) {
+			c := *contractor
+			contractors = append(contractors, &c)
+		}
+	}
+
+	return contractors, nil
+}
+
+func (db *fakeDatabase) GetContractor(id string) (*types.Contractor, error) {
+	if err := db.err("GetContractor"); err != nil {
+		return nil, err
+	}
+
+	contractor, ok := db.contractors[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "contractor %s not found", id)
+	}
+
+	c := *contractor
+	return &c, nil
+}
+
+func (db *fakeDatabase) AddContractor(groupID string, contractor *types.Contractor) error {
+	if err := db.err("AddContractor"); err != nil {
+		return err
+	}
+
+	contractor.ID = db.newID("contractor")
+	contractor.GroupID = groupID
+	c := *contractor
+	db.contractors[contractor.ID] = &c
+	return nil
+}
+
+func (db *fakeDatabase) UpdateContractor(contractor *types.Contractor) error {
+	if err := db.err("UpdateContractor"); err != nil {
+		return err
+	}
+
+	c := *contractor
+	db.contractors[contractor.ID] = &c
+	return nil
+}
+
+func (db *fakeDatabase) DeleteContractor(id string) error {
+	if err := db.err("DeleteContractor"); err != nil {
+		return err
+	}
+
+	delete(db.contractors, id)
+	return nil
+}
+
+func (db *fakeDatabase) ListTimesheets(groupID string) ([]*types.Timesheet, error) {
+	if err := db.err("ListTimesheets"); err != nil {
+		return nil, err
+	}
+
+	var timesheets []*types.Timesheet
+	for _, timesheet := range db.timesheets {
+		if timesheet.GroupID == groupID {
+			t := *timesheet
+			timesheets = append(timesheets, &t)
+		}
+	}
+
+	return timesheets, nil
+}
+
+func (db *fakeDatabase) GetTimesheet(contractorID string, requestID string) (*types.Timesheet, error) {
+	if err := db.err("GetTimesheet"); err != nil {
+		return nil, err
+	}
+
+	for _, timesheet := range db.timesheets {
+		if timesheet.ContractorID == contractorID && timesheet.RequestID == requestID {
+			t := *timesheet
+			return &t, nil
+		}
+	}
+
+	return nil, status.Errorf(codes.NotFound, "timesheet of %s for %s not found", contractorID, requestID)
+}
+
+func (db *fakeDatabase) GetTimesheetByID(id string) (*types.Timesheet, error) {
+	if err := db.err("GetTimesheetByID"); err != nil {
+		return nil, err
+	}
+
+	timesheet, ok := db.timesheets[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "timesheet %s not found", id)
+	}
+
+	t := *timesheet
+	return &t, nil
+}
+
+func (db *fakeDatabase) AddTimesheet(timesheet *types.Timesheet) error {
+	if err := db.err("AddTimesheet"); err != nil {
+		return err
+	}
+
+	t := *timesheet
+	db.timesheets[timesheet.ID] = &t
+	return nil
+}
+
+func (db *fakeDatabase) UpdateTimesheet(timesheet *types.Timesheet) error {
+	if err := db.err("UpdateTimesheet"); err != nil {
+		return err
+	}
+
+	t := *timesheet
+	db.timesheets[timesheet.ID] = &t
+	return nil
+}
+
+func (db *fakeDatabase) UpdateTimesheetStatus(timesheet *types.Timesheet, fromStatus constants.TimesheetStatuses, fromVersion int) error {
+	if err := db.err("UpdateTimesheetStatus"); err != nil {
+		return err
+	}
+
+	stored, ok := db.timesheets[timesheet.ID]
+	if !ok {
+		return status.Errorf(codes.NotFound, "timesheet %s not found", timesheet.ID)
+	}
+
+	if stored.Status != fromStatus || stored.Version != fromVersion {
+		return status.Errorf(codes.FailedPrecondition, "timesheet %s is %s at version %d", timesheet.ID, stored.Status, stored.Version)
+	}
+
+	stored.Status = timesheet.Status
+	stored.RejectionReason = timesheet.RejectionReason
+	return nil
+}
+
+func (db *fakeDatabase) DeleteTimesheet(id string) error {
+	if err := db.err("DeleteTimesheet"); err != nil {
+		return err
+	}
+
+	delete(db.timesheets, id)
+	return nil
+}
+
+func (db *fakeDatabase) ListTimesheetRequests(groupID string) ([]*types.TimesheetRequest, error) {
+	if err := db.err("ListTimesheetRequests"); err != nil {
+		return nil, err
+	}
+
+	var requests []*types.TimesheetRequest
+	for _, request := range db.requests {
+		if request.GroupID == groupID {
+			r := *request
+			requests = append(requests, &r)
+		}
+	}
+
+	return requests, nil
+}
+
+func (db *fakeDatabase) ListContractorTimesheetRequests(contractorID string) ([]*types.TimesheetRequest, error) {
+	if err := db.err("ListContractorTimesheetRequests"); err != nil {
+		return nil, err
+	}
+
+	var requests []*types.TimesheetRequest
+	for _, request := range db.requests {
+		if request.ContractorID == contractorID {
+			r := *request
+			requests = append(requests, &r)
+		}
+	}
+
+	return requests, nil
+}
+
+func (db *fakeDatabase) GetTimesheetRequest(contractorID string, requestID string) (*types.TimesheetRequest, error) {
+	if err := db.err("GetTimesheetRequest"); err != nil {
+		return nil, err
+	}
+
+	request := db.findTimesheetRequest(contractorID, requestID)
+	if request == nil {
+		return nil, status.Errorf(codes.NotFound, "timesheet request %s of %s not found", requestID, contractorID)
+	}
+
+	r := *request
+	return &r, nil
+}
+
+func (db *fakeDatabase) GetTimesheetRequestByReplyKey(replyKey string) (*types.TimesheetRequest, error) {
+	if err := db.err("GetTimesheetRequestByReplyKey"); err != nil {
+		return nil, err
+	}
+
+	for _, request := range db.requests {
+		if replyKey != "" && request.ReplyKey == replyKey {
+			r := *request
+			return &r, nil
+		}
+	}
+
+	return nil, status.Errorf(codes.NotFound, "no timesheet request has the reply key %s", replyKey)
+}
+
+func (db *fakeDatabase) AddTimesheetRequest(request *types.TimesheetRequest) error {
+	if err := db.err("AddTimesheetRequest"); err != nil {
+		return err
+	}
+
+	request.ID = db.newID("request")
+	r := *request
+	db.requests = append(db.requests, &r)
+	return nil
+}
+
+func (db *fakeDatabase) UpdateTimesheetRequest(request *types.TimesheetRequest) error {
+	if err := db.err("UpdateTimesheetRequest"); err != nil {
+		return err
+	}
+
+	stored := db.findTimesheetRequest(request.ContractorID, request.RequestID)
+	if stored == nil {
+		return status.Errorf(codes.NotFound, "timesheet request %s of %s not found", request.RequestID, request.ContractorID)
+	}
+
+	*stored = *request
+	return nil
+}
+
+func (db *fakeDatabase) UpdatePendingTimesheetRequest(request *types.TimesheetRequest) error {
+	if err := db.err("UpdatePendingTimesheetRequest"); err != nil {
+		return err
+	}
+
+	stored := db.findTimesheetRequest(request.ContractorID, request.RequestID)
+	if stored == nil {
+		return status.Errorf(codes.NotFound, "timesheet request %s of %s not found", request.RequestID, request.ContractorID)
+	}
+
+	if stored.Status != constants.Pending {
+		return status.Errorf(codes.FailedPrecondition, "timesheet request %s of %s is no longer pending", request.RequestID, request.ContractorID)
+	}
+
+	stored.Attempts = request.Attempts
+	stored.Escalated = request.Escalated
+	if stored.ReplyKey == "" {
+		stored.ReplyKey = request.ReplyKey
+	}
+	if stored.SentAt.IsZero() {
+		stored.SentAt = request.SentAt
+		stored.DueAt = request.DueAt
+	}
+	return nil
+}
+
+func (db *fakeDatabase) ReplaceSubmissionNonce(request *types.TimesheetRequest, oldNonce string) error {
+	if err := db.err("ReplaceSubmissionNonce"); err != nil {
+		return err
+	}
+
+	stored := db.findTimesheetRequest(request.ContractorID, request.RequestID)
+	if stored == nil {
+		return status.Errorf(codes.NotFound, "timesheet request %s of %s not found", request.RequestID, request.ContractorID)
+	}
+
+	if stored.SubmissionNonce != oldNonce {
+		return status.Errorf(codes.FailedPrecondition, "submission link of timesheet request %s of %s was already used", request.RequestID, request.ContractorID)
+	}
+
+	stored.SubmissionNonce = request.SubmissionNonce
+	return nil
+}
+
+// findTimesheetRequest returns the stored request of a contractor, nil if there is none.
+func (db *fakeDatabase) findTimesheetRequest(contractorID string, requestID string) *types.TimesheetRequest {
+	for _, request := range db.requests {
+		if request.ContractorID == contractorID && request.RequestID == requestID {
+			return request
+		}
+	}
+
+	return nil
+}
+
+func (db *fakeDatabase) ListTimesheetVersions(timesheetID string) ([]*types.TimesheetVersion, error) {
+	if err := db.err("ListTimesheetVersions"); err != nil {
+		return nil, err
+	}
+
+	return db.versions[timesheetID], nil
+}
+
+func (db *fakeDatabase) ListTimesheetEntries(timesheetID string) ([]*types.TimesheetEntry, error) {
+	if err := db.err("ListTimesheetEntries"); err != nil {
+		return nil, err
+	}
+
+	return db.entries[timesheetID], nil
+}
+
+func (db *fakeDatabase) AddTimesheetEntries(entries []*types.TimesheetEntry) error {
+	if err := db.err("AddTimesheetEntries"); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		db.entries[entry.TimesheetID] = append(db.entries[entry.TimesheetID], entry)
+	}
+	return nil
+}
+
+func (db *fakeDatabase) DeleteTimesheetEntries(timesheetID string) error {
+	if err := db.err("DeleteTimesheetEntries"); err != nil {
+		return err
+	}
+
+	delete(db.entries, timesheetID)
+	return nil
+}
+
+func (db *fakeDatabase) ListAuditEntries(timesheetID string) ([]*types.TimesheetAuditEntry, error) {
+	if err := db.err("ListAuditEntries"); err != nil {
+		return nil, err
+	}
+
+	return db.auditLog[timesheetID], nil
+}
+
+func (db *fakeDatabase) AddAuditEntry(entry *types.TimesheetAuditEntry) error {
+	if err := db.err("AddAuditEntry"); err != nil {
+		return err
+	}
+
+	db.auditLog[entry.TimesheetID] = append(db.auditLog[entry.TimesheetID], entry)
+	return nil
+}
+
+func (db *fakeDatabase) ListUnassignedEmails(groupID string) ([]*types.UnassignedEmail, error) {
+	if err := db.err("ListUnassignedEmails"); err != nil {
+		return nil, err
+	}
+
+	var emails []*types.UnassignedEmail
+	for _, email := range db.unassignedEmails {
+		if email.GroupID == groupID {
+			emails = append(emails, email)
+		}
+	}
+	slices.SortFunc(emails, func(a, b *types.UnassignedEmail) int { return b.UnassignedAt.Compare(a.UnassignedAt) })
+
+	return emails, nil
+}
+
+func (db *fakeDatabase) GetUnassignedEmail(id string) (*types.UnassignedEmail, error) {
+	if err := db.err("GetUnassignedEmail"); err != nil {
+		return nil, err
+	}
+
+	email, ok := db.unassignedEmails[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unassigned email %s not found", id)
+	}
+
+	return email, nil
+}
+
+func (db *fakeDatabase) SetUnassignedEmail(email *types.UnassignedEmail) error {
+	if err := db.err("SetUnassignedEmail"); err != nil {
+		return err
+	}
+
+	db.unassignedEmails[email.ID] = email
+	return nil
+}
+
+func (db *fakeDatabase) DeleteUnassignedEmail(id string) error {
+	if err := db.err("DeleteUnassignedEmail"); err != nil {
+		return err
+	}
+
+	delete(db.unassignedEmails, id)
+	return nil
+}
+
+// fakeAuthService has one logged user, with the password the tests log in with.
+type fakeAuthService struct {
+	fakeErrors
+
+	userInfo types.LoggedUserInfo
+	userID   string
+	password string
+
+	registered []string
+	sessions   int // The sessions started
+	ended      int // The sessions ended
+}
+
+// Ensure fakeAuthService implements IAuthService.
+var _ interfaces.IAuthService = &fakeAuthService{}
+
+func (s *fakeAuthService) Register(email string, password string) (*types.LoginResponseBody, error) {
+	if err := s.err("Register"); err != nil {
+		return nil, err
+	}
+
+	s.registered = append(s.registered, email)
+	return &types.LoginResponseBody{IdToken: "token", Email: email, LocalId: "registered"}, nil
+}
+
+// Login logs in the logged user with the password, any other one gets a response without tokens.
+func (s *fakeAuthService) Login(email string, password string) (*types.LoginResponseBody, error) {
+	if err := s.err("Login"); err != nil {
+		return nil, err
+	}
+
+	if email != s.userInfo.Email || password != s.password {
+		return &types.LoginResponseBody{}, nil
+	}
+
+	return &types.LoginResponseBody{IdToken: "token", Email: email, LocalId: s.userID}, nil
+}
+
+func (s *fakeAuthService) StartSession(w http.ResponseWriter, r *http.Request, responseBody *types.LoginResponseBody, isVerified bool, rememberMe bool) error {
+	if err := s.err("StartSession"); err != nil {
+		return err
+	}
+
+	s.sessions++
+	return nil
+}
+
+func (s *fakeAuthService) EndSession(w http.ResponseWriter, r *http.Request) error {
+	if err := s.err("EndSession"); err != nil {
+		return err
+	}
+
+	s.ended++
+	return nil
+}
+
+// CheckUser returns a copy of the logged user, the handlers add the group to it.
+func (s *fakeAuthService) CheckUser(r *http.Request) (*types.LoggedUserInfo, error) {
+	if err := s.err("CheckUser"); err != nil {
+		return nil, err
+	}
+
+	userInfo := s.userInfo
+	return &userInfo, nil
+}
+
+// fakeAccountService accepts the tokens it knows, by the links they are of.
+type fakeAccountService struct {
+	fakeErrors
+
+	links map[string]*types.AccountLink // The valid tokens
+
+	resetLinksSent  []string
+	emailLinksSent  []string
+	passwords       map[string]string // The passwords set, by user ID
+	deletedAccounts []string
+}
+
+// Ensure fakeAccountService implements IAccountService.
+var _ interfaces.IAccountService = &fakeAccountService{}
+
+func (s *fakeAccountService) SendPasswordResetLink(email string) error {
+	if err := s.err("SendPasswordResetLink"); err != nil {
+		return err
+	}
+
+	s.resetLinksSent = append(s.resetLinksSent, email)
+	return nil
+}
+
+func (s *fakeAccountService) CheckPasswordResetToken(token string) (*types.AccountLink, error) {
+	if err := s.err("CheckPasswordResetToken"); err != nil {
+		return nil, err
+	}
+
+	link, ok := s.links[token]
+	if !ok || link.Purpose != constants.AccountLinkPasswordReset {
+		return nil, status.Error(codes.PermissionDenied, "invalid password reset link")
+	}
+
+	return link, nil
+}
+
+func (s *fakeAccountService) ResetPassword(token string, password string) error {
+	link, err := s.CheckPasswordResetToken(token)
+	if err != nil {
+		return err
+	}
+
+	if err := s.err("ResetPassword"); err != nil {
+		return err
+	}
+
+	s.passwords[link.UserID] = password
+	delete(s.links, token)
+	return nil
+}
+
+func (s *fakeAccountService) SendEmailChangeLink(userID string, email string) error {
+	if err := s.err("SendEmailChangeLink"); err != nil {
+		return err
+	}
+
+	s.emailLinksSent = append(s.emailLinksSent, email)
+	return nil
+}
+
+func (s *fakeAccountService) ChangeEmail(token string) (*types.AccountLink, error) {
+	if err := s.err("ChangeEmail"); err != nil {
+		return nil, err
+	}
+
+	link, ok := s.links[token]
+	if !ok || link.Purpose != constants.AccountLinkEmailChange {
+		return nil, status.Error(codes.PermissionDenied, "invalid email change link")
+	}
+
+	delete(s.links, token)
+	return link, nil
+}
+
+func (s *fakeAccountService) DeleteAccount(userID string) error {
+	if err := s.err("DeleteAccount"); err != nil {
+		return err
+	}
+
+	s.deletedAccounts = append(s.deletedAccounts, userID)
+	return nil
+}
+
+// fakeFirebaseService knows the users by their email.
+type fakeFirebaseService struct {
+	fakeErrors
+
+	users map[string]*types.AuthUser
+}
+
+// Ensure fakeFirebaseService implements IFirebaseService.
+var _ interfaces.IFirebaseService = &fakeFirebaseService{}
+
+func (s *fakeFirebaseService) CheckIfUserExists(email string) (bool, error) {
+	if err := s.err("CheckIfUserExists"); err != nil {
+		return false, err
+	}
+
+	_, ok := s.users[email]
+	return ok, nil
+}
+
+func (s *fakeFirebaseService) CheckIsUserVerified(email string) (bool, error) {
+	if err := s.err("CheckIsUserVerified"); err != nil {
+		return false, err
+	}
+
+	user, ok := s.users[email]
+	return ok && user.EmailVerified, nil
+}
+
+func (s *fakeFirebaseService) GetCustomClaims(idToken string) (map[string]interface{}, error) {
+	if err := s.err("GetCustomClaims"); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{}, nil
+}
+
+func (s *fakeFirebaseService) EmailVerificationLink(email string) (string, error) {
+	if err := s.err("EmailVerificationLink"); err != nil {
+		return "", err
+	}
+
+	return "https://example.com/verify?email=" + email, nil
+}
+
+func (s *fakeFirebaseService) GetUser(id string) (*types.AuthUser, error) {
+	if err := s.err("GetUser"); err != nil {
+		return nil, err
+	}
+
+	for _, user := range s.users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+
+	return nil, status.Errorf(codes.NotFound, "user %s not found", id)
+}
+
+func (s *fakeFirebaseService) GetUserByEmail(email string) (*types.AuthUser, error) {
+	if err := s.err("GetUserByEmail"); err != nil {
+		return nil, err
+	}
+
+	user, ok := s.users[email]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "user %s not found", email)
+	}
+
+	return user, nil
+}
+
+func (s *fakeFirebaseService) UpdatePassword(id string, password string) error {
+	return s.err("UpdatePassword")
+}
+
+func (s *fakeFirebaseService) UpdateEmail(id string, email string) error {
+	return s.err("UpdateEmail")
+}
+
+func (s *fakeFirebaseService) RevokeSessions(id string) error {
+	return s.err("RevokeSessions")
+}
+
+func (s *fakeFirebaseService) DeleteUser(id string) error {
+	return s.err("DeleteUser")
+}
+
+// fakeSessionManagerService has the values of the request's sessions, and the sessions of the users in its store.
+type fakeSessionManagerService struct {
+	fakeErrors
+
+	sessionID string                 // ID of the request's user session
+	values    map[string]interface{} // Values of the request's user session
+	sessions  []*types.Session       // The stored sessions of every user
+
+	deleted []string // Names of the request's sessions deleted
+}
+
+// Ensure fakeSessionManagerService implements ISessionManagerService.
+var _ interfaces.ISessionManagerService = &fakeSessionManagerService{}
+
+func (s *fakeSessionManagerService) CreateSession(w http.ResponseWriter, r *http.Request, sessionName string, expiresAt time.Time, persistent bool, data map[string]interface{}) (*sessions.Session, error) {
+	if err := s.err("CreateSession"); err != nil {
+		return nil, err
+	}
+
+	for key, value := range data {
+		s.values[key] = value
+	}
+	return sessions.NewSession(nil, sessionName), nil
+}
+
+func (s *fakeSessionManagerService) DeleteSession(w http.ResponseWriter, r *http.Request, sessionName string) error {
+	if err := s.err("DeleteSession"); err != nil {
+		return err
+	}
+
+	s.deleted = append(s.deleted, sessionName)
+	return nil
+}
+
+func (s *fakeSessionManagerService) CheckSession(r *http.Request, sessionName string) bool {
+	return sessionName == constants.UserSessionName
+}
+
+func (s *fakeSessionManagerService) GetElement(r *http.Request, sessionName string, key string) (interface{}, error) {
+	if err := s.err("GetElement"); err != nil {
+		return nil, err
+	}
+
+	return s.values[key], nil
+}
+
+func (s *fakeSessionManagerService) SetElement(w http.ResponseWriter, r *http.Request, sessionName string, key string, value interface{}) error {
+	if err := s.err("SetElement"); err != nil {
+		return err
+	}
+
+	s.values[key] = value
+	return nil
+}
+
+func (s *fakeSessionManagerService) UpdateElements(r *http.Request, sessionName string, data map[string]interface{}) error {
+	if err := s.err("UpdateElements"); err != nil {
+		return err
+	}
+
+	for key, value := range data {
+		s.values[key] = value
+	}
+	return nil
+}
+
+func (s *fakeSessionManagerService) GetSessionID(r *http.Request, sessionName string) (string, error) {
+	if err := s.err("GetSessionID"); err != nil {
+		return "", err
+	}
+
+	return s.sessionID, nil
+}
+
+func (s *fakeSessionManagerService) GetUserSessions(userID string) ([]*types.Session, error) {
+	if err := s.err("GetUserSessions"); err != nil {
+		return nil, err
+	}
+
+	var userSessions []*types.Session
+	for _, session := range s.sessions {
+		if session.UserID == userID {
+			userSessions = append(userSessions, session)
+		}
+	}
+
+	return userSessions, nil
+}
+
+func (s *fakeSessionManagerService) DeleteUserSession(userID string, id string) error {
+	if err := s.err("DeleteUserSession"); err != nil {
+		return err
+	}
+
+	i := slices.IndexFunc(s.sessions, func(session *types.Session) bool { return session.ID == id && session.UserID == userID })
+	if i < 0 {
+		return status.Errorf(codes.NotFound, "session %s not found", id)
+	}
+
+	s.sessions = slices.Delete(s.sessions, i, i+1)
+	return nil
+}
+
+func (s *fakeSessionManagerService) DeleteUserSessions(userID string) error {
+	if err := s.err("DeleteUserSessions"); err != nil {
+		return err
+	}
+
+	s.sessions = slices.DeleteFunc(s.sessions, func(session *types.Session) bool { return session.UserID == userID })
+	return nil
+}
+
+func (s *fakeSessionManagerService) ExpireSessions(ctx context.Context) {}
+
+// fakeTemplateService renders nothing, it keeps what the last page was rendered with for the tests to check.
+type fakeTemplateService struct {
+	fakeErrors
+
+	name         string // The file of the last page rendered
+	data         interface{}
+	userInfo     *types.LoggedUserInfo
+	errorMessage string
+}
+
+// Ensure fakeTemplateService implements ITemplateService.
+var _ interfaces.ITemplateService = &fakeTemplateService{}
+
+func (s *fakeTemplateService) ParseTemplate(filename string) (*types.AppTemplate, error) {
+	if err := s.err("ParseTemplate"); err != nil {
+		return nil, err
+	}
+
+	return &types.AppTemplate{Tmpl: template.New(filename)}, nil
+}
+
+func (s *fakeTemplateService) ExecuteTemplate(tmpl *types.AppTemplate, w http.ResponseWriter, r *http.Request, data interface{}, userInfo *types.LoggedUserInfo) error {
+	if err := s.err("ExecuteTemplate"); err != nil {
+		return err
+	}
+
+	s.name = tmpl.Tmpl.Name()
+	s.data = data
+	s.userInfo = userInfo
+	return nil
+}
+
+func (s *fakeTemplateService) ShowError(tmpl *types.AppTemplate, w http.ResponseWriter, r *http.Request, errorMessage string) error {
+	if err := s.err("ShowError"); err != nil {
+		return err
+	}
+
+	// The handlers show the error even if the page could not be parsed
+	if tmpl != nil {
+		s.name = tmpl.Tmpl.Name()
+	}
+	s.errorMessage = errorMessage
+	return nil
+}
+
+// fakeErrorReporterService keeps the reported errors.
+type fakeErrorReporterService struct {
+	errs []error
+}
+
+// Ensure fakeErrorReporterService implements IErrorReporterService.
+var _ interfaces.IErrorReporterService = &fakeErrorReporterService{}
+
+func (s *fakeErrorReporterService) ReportError(w http.ResponseWriter, r *http.Request, err error) {
+	s.errs = append(s.errs, err)
+}
+
+// fakeEmailService keeps the emails sent, as "<email name> <recipient>", and has the unassigned emails in memory.
+type fakeEmailService struct {
+	fakeErrors
+
+	sent []string
+
+	unassigned map[uint32]*types.InboundEmail
+}
+
+// Ensure fakeEmailService implements IEmailService.
+var _ interfaces.IEmailService = &fakeEmailService{}
+
+// send records an email unless the method is made to fail.
+func (s *fakeEmailService) send(method string, name string, to string) error {
+	if err := s.err(method); err != nil {
+		return err
+	}
+
+	s.sent = append(s.sent, name+" "+to)
+	return nil
+}
+
+func (s *fakeEmailService) SendVerificationEmail(email string, link string) error {
+	return s.send("SendVerificationEmail", constants.EmailTemplateVerificationName, email)
+}
+
+func (s *fakeEmailService) SendTimesheetRequestEmail(group *types.Group, contractor *types.Contractor, request *types.TimesheetRequest, submissionLink string) error {
+	return s.send("SendTimesheetRequestEmail", constants.EmailTemplateRequestName, contractor.Email)
+}
+
+func (s *fakeEmailService) PreviewTimesheetRequestEmail(group *types.Group, contractor *types.Contractor, request *types.TimesheetRequest, submissionLink string) (*types.RenderedEmail, error) {
+	if err := s.err("PreviewTimesheetRequestEmail"); err != nil {
+		return nil, err
+	}
+
+	return &types.RenderedEmail{Subject: "Timesheet " + request.RequestID, Text: submissionLink}, nil
+}
+
+func (s *fakeEmailService) SendTimesheetRejectionEmail(group *types.Group, contractor *types.Contractor, request *types.TimesheetRequest, reason string) error {
+	return s.send("SendTimesheetRejectionEmail", constants.EmailTemplateRejectionName, contractor.Email)
+}
+
+func (s *fakeEmailService) SendFileRejectionEmail(group *types.Group, contractor *types.Contractor, request *types.TimesheetRequest, rejection *types.FileRejection) error {
+	return s.send("SendFileRejectionEmail", constants.EmailTemplateFileRejectionName, contractor.Email)
+}
+
+func (s *fakeEmailService) SendTimesheetReminderEmail(group *types.Group, contractor *types.Contractor, request *types.TimesheetRequest) error {
+	return s.send("SendTimesheetReminderEmail", constants.EmailTemplateReminderName, contractor.Email)
+}
+
+func (s *fakeEmailService) PreviewTimesheetReminderEmail(group *types.Group, contractor *types.Contractor, request *types.TimesheetRequest) (*types.RenderedEmail, error) {
+	if err := s.err("PreviewTimesheetReminderEmail"); err != nil {
+		return nil, err
+	}
+
+	return &types.RenderedEmail{Subject: "Reminder " + request.RequestID}, nil
+}
+
+func (s *fakeEmailService) SendMissingTimesheetsEmail(to string, group *types.Group, missing []types.MissingTimesheet) error {
+	return s.send("SendMissingTimesheetsEmail", constants.EmailTemplateMissingName, to)
+}
+
+func (s *fakeEmailService) SendPasswordResetEmail(email string, link string) error {
+	return s.send("SendPasswordResetEmail", constants.EmailTemplatePasswordResetName, email)
+}
+
+func (s *fakeEmailService) SendEmailChangeEmail(email string, link string) error {
+	return s.send("SendEmailChangeEmail", constants.EmailTemplateEmailChangeName, email)
+}
+
+func (s *fakeEmailService) GetUnassignedEmails() ([]*types.InboundEmail, error) {
+	if err := s.err("GetUnassignedEmails"); err != nil {
+		return nil, err
+	}
+
+	var emails []*types.InboundEmail
+	for _, email := range s.unassigned {
+		emails = append(emails, email)
+	}
+	slices.SortFunc(emails, func(a, b *types.InboundEmail) int { return int(a.UID) - int(b.UID) })
+
+	return emails, nil
+}
+
+func (s *fakeEmailService) GetUnassignedEmail(unassigned *types.UnassignedEmail) (*types.InboundEmail, error) {
+	if err := s.err("GetUnassignedEmail"); err != nil {
+		return nil, err
+	}
+
+	for _, email := range s.unassigned {
+		if email.MessageID == unassigned.MessageID {
+			return email, nil
+		}
+	}
+
+	return nil, status.Errorf(codes.NotFound, "email %s not found", unassigned.MessageID)
+}
+
+func (s *fakeEmailService) ArchiveUnassignedEmail(uid uint32) error {
+	if err := s.err("ArchiveUnassignedEmail"); err != nil {
+		return err
+	}
+
+	delete(s.unassigned, uid)
+	return nil
+}
+
+func (s *fakeEmailService) SendReplyEmail(group *types.Group, email *types.InboundEmail, body string) error {
+	return s.send("SendReplyEmail", constants.EmailTemplateReplyName, email.From)
+}
+
+func (s *fakeEmailService) SendUnknownSenderEmail(email *types.InboundEmail) error {
+	return s.send("SendUnknownSenderEmail", constants.EmailTemplateUnknownSenderName, email.From)
+}
+
+// fakeEmailTemplateService accepts any email content.
+type fakeEmailTemplateService struct {
+	fakeErrors
+}
+
+// Ensure fakeEmailTemplateService implements IEmailTemplateService.
+var _ interfaces.IEmailTemplateService = &fakeEmailTemplateService{}
+
+func (s *fakeEmailTemplateService) RenderEmail(name string, language string, group *types.Group, data interface{}) (*types.RenderedEmail, error) {
+	if err := s.err("RenderEmail"); err != nil {
+		return nil, err
+	}
+
+	return &types.RenderedEmail{Subject: name}, nil
+}
+
+func (s *fakeEmailTemplateService) RenderEmailContent(content string, placeholders *types.EmailPlaceholders) (string, error) {
+	if err := s.err("RenderEmailContent"); err != nil {
+		return "", err
+	}
+
+	return content, nil
+}
+
+func (s *fakeEmailTemplateService) ValidateEmailContent(content *types.EmailContent) error {
+	return s.err("ValidateEmailContent")
+}
+
+// fakeScheduleService has one occurrence, sent today, whatever the schedule.
+type fakeScheduleService struct {
+	fakeErrors
+
+	occurrence *types.ScheduleOccurrence
+}
+
+// Ensure fakeScheduleService implements IScheduleService.
+var _ interfaces.IScheduleService = &fakeScheduleService{}
+
+func (s *fakeScheduleService) NextOccurrences(schedule *types.Schedule, holidayCalendar string, after time.Time, n int) ([]*types.ScheduleOccurrence, error) {
+	if err := s.err("NextOccurrences"); err != nil {
+		return nil, err
+	}
+
+	return []*types.ScheduleOccurrence{s.occurrence}, nil
+}
+
+func (s *fakeScheduleService) OccurrenceOn(schedule *types.Schedule, holidayCalendar string, t time.Time) (*types.ScheduleOccurrence, error) {
+	if err := s.err("OccurrenceOn"); err != nil {
+		return nil, err
+	}
+
+	return s.occurrence, nil
+}
+
+// RequestPeriod parses the period of a request ID like "2025-01-01_2025-01-31".
+func (s *fakeScheduleService) RequestPeriod(requestID string) (time.Time, time.Time, error) {
+	if err := s.err("RequestPeriod"); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	startStr, endStr, _ := strings.Cut(requestID, "_")
+	start, err := time.Parse("2006-01-02", startStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, status.Errorf(codes.InvalidArgument, "request ID %s has no period", requestID)
+	}
+	end, err := time.Parse("2006-01-02", endStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, status.Errorf(codes.InvalidArgument, "request ID %s has no period", requestID)
+	}
+
+	return start, end, nil
+}
+
+func (s *fakeScheduleService) RequestDueAt(policy *types.ReminderPolicy, sentAt time.Time) time.Time {
+	return sentAt.AddDate(0, 0, 7)
+}
+
+// fakeSchedulerService keeps the jobs of the groups, as "<request or reminder> <group ID>".
+type fakeSchedulerService struct {
+	fakeErrors
+
+	jobs []string
+}
+
+// Ensure fakeSchedulerService implements ISchedulerService.
+var _ interfaces.ISchedulerService = &fakeSchedulerService{}
+
+func (s *fakeSchedulerService) addJob(method string, job string) error {
+	if err := s.err(method); err != nil {
+		return err
+	}
+
+	if !slices.Contains(s.jobs, job) {
+		s.jobs = append(s.jobs, job)
+	}
+	return nil
+}
+
+func (s *fakeSchedulerService) deleteJob(method string, job string) error {
+	if err := s.err(method); err != nil {
+		return err
+	}
+
+	s.jobs = slices.DeleteFunc(s.jobs, func(j string) bool { return j == job })
+	return nil
+}
+
+func (s *fakeSchedulerService) CreateTimesheetRequestJob(groupID string, schedule *types.Schedule) error {
+	return s.addJob("CreateTimesheetRequestJob", "request "+groupID)
+}
+
+func (s *fakeSchedulerService) EditTimesheetRequestJob(groupID string, schedule *types.Schedule) error {
+	return s.addJob("EditTimesheetRequestJob", "request "+groupID)
+}
+
+func (s *fakeSchedulerService) DeleteTimesheetRequestJob(groupID string) error {
+	return s.deleteJob("DeleteTimesheetRequestJob", "request "+groupID)
+}
+
+func (s *fakeSchedulerService) CreateTimesheetReminderJob(groupID string, schedule *types.Schedule) error {
+	return s.addJob("CreateTimesheetReminderJob", "reminder "+groupID)
+}
+
+func (s *fakeSchedulerService) EditTimesheetReminderJob(groupID string, schedule *types.Schedule) error {
+	return s.addJob("EditTimesheetReminderJob", "reminder "+groupID)
+}
+
+func (s *fakeSchedulerService) DeleteTimesheetReminderJob(groupID string) error {
+	return s.deleteJob("DeleteTimesheetReminderJob", "reminder "+groupID)
+}
+
+// fakeHolidayCalendarService has one calendar without holidays, only the weekends are not working days.
+type fakeHolidayCalendarService struct {
+	fakeErrors
+}
+
+// Ensure fakeHolidayCalendarService implements IHolidayCalendarService.
+var _ interfaces.IHolidayCalendarService = &fakeHolidayCalendarService{}
+
+func (s *fakeHolidayCalendarService) ListHolidayCalendars() []*types.HolidayCalendar {
+	return []*types.HolidayCalendar{{Code: "XX", Name: "Weekends only"}}
+}
+
+func (s *fakeHolidayCalendarService) GetHolidays(calendar string, year int) ([]*types.Holiday, error) {
+	if err := s.err("GetHolidays"); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (s *fakeHolidayCalendarService) IsWorkingDay(calendar string, date time.Time) (bool, error) {
+	if err := s.err("IsWorkingDay"); err != nil {
+		return false, err
+	}
+
+	return date.Weekday() != time.Saturday && date.Weekday() != time.Sunday, nil
+}
+
+func (s *fakeHolidayCalendarService) CountWorkingDays(calendar string, start time.Time, end time.Time) (int, error) {
+	if err := s.err("CountWorkingDays"); err != nil {
+		return 0, err
+	}
+
+	days := 0
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() != time.Saturday && day.Weekday() != time.Sunday {
+			days++
+		}
+	}
+
+	return days, nil
+}
+
+// fakeStorageService signs the URLs of the files it is asked for, it has no files.
+type fakeStorageService struct {
+	fakeErrors
+
+	deletedPrefixes []string
+}
+
+// Ensure fakeStorageService implements IStorageService.
+var _ interfaces.IStorageService = &fakeStorageService{}
+
+func (s *fakeStorageService) UploadFile(objectName string, data []byte, contentType string, metadata map[string]string) (string, error) {
+	if err := s.err("UploadFile"); err != nil {
+		return "", err
+	}
+
+	return "gs://bucket/" + objectName, nil
+}
+
+func (s *fakeStorageService) SignedURL(fileURL string, expiry time.Duration) (string, error) {
+	if err := s.err("SignedURL"); err != nil {
+		return "", err
+	}
+
+	return "https://storage.example.com/" + strings.TrimPrefix(fileURL, "gs://") + "?signed", nil
+}
+
+func (s *fakeStorageService) MakeFilesPrivate(prefixName string) (int, error) {
+	if err := s.err("MakeFilesPrivate"); err != nil {
+		return 0, err
+	}
+
+	return 0, nil
+}
+
+func (s *fakeStorageService) DeleteFiles(prefixName string) error {
+	if err := s.err("DeleteFiles"); err != nil {
+		return err
+	}
+
+	s.deletedPrefixes = append(s.deletedPrefixes, prefixName)
+	return nil
+}
+
+// fakeSubmissionLinkService signs nothing, the token of a link is its contractor, request and nonce.
+type fakeSubmissionLinkService struct {
+	fakeErrors
+
+	nonces int
+}
+
+// Ensure fakeSubmissionLinkService implements ISubmissionLinkService.
+var _ interfaces.ISubmissionLinkService = &fakeSubmissionLinkService{}
+
+func (s *fakeSubmissionLinkService) CreateSubmissionLink(contractorID string, requestID string) (string, string, error) {
+	if err := s.err("CreateSubmissionLink"); err != nil {
+		return "", "", err
+	}
+
+	s.nonces++
+	nonce := fmt.Sprintf("nonce%d", s.nonces)
+	return constants.SubmissionLinkPath + submissionToken(contractorID, requestID, nonce), nonce, nil
+}
+
+func (s *fakeSubmissionLinkService) ParseSubmissionToken(token string) (*types.SubmissionLink, error) {
+	if err := s.err("ParseSubmissionToken"); err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, status.Error(codes.PermissionDenied, "invalid submission token")
+	}
+
+	return &types.SubmissionLink{ContractorID: parts[0], RequestID: parts[1], Nonce: parts[2]}, nil
+}
+
+// submissionToken is the token of fakeSubmissionLinkService for a link.
+func submissionToken(contractorID string, requestID string, nonce string) string {
+	return contractorID + "." + requestID + "." + nonce
+}
+
+// fakeTimesheetIngestionService matches no email, and keeps the timesheets saved.
+type fakeTimesheetIngestionService struct {
+	fakeErrors
+
+	saved      []string // The timesheets saved, as "<contractor ID> <request ID> <filename>"
+	duplicates bool     // Whether the timesheets saved were the current ones already
+}
+
+// Ensure fakeTimesheetIngestionService implements ITimesheetIngestionService.
+var _ interfaces.ITimesheetIngestionService = &fakeTimesheetIngestionService{}
+
+func (s *fakeTimesheetIngestionService) IngestEmail(email *types.InboundEmail) error {
+	return s.err("IngestEmail")
+}
+
+func (s *fakeTimesheetIngestionService) UnassignEmail(email *types.InboundEmail) (bool, error) {
+	if err := s.err("UnassignEmail"); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *fakeTimesheetIngestionService) MatchEmail(email *types.InboundEmail) (*types.Contractor, string, error) {
+	if err := s.err("MatchEmail"); err != nil {
+		return nil, "", err
+	}
+
+	return nil, "", status.Errorf(codes.NotFound, "email %d matches no request", email.UID)
+}
+
+func (s *fakeTimesheetIngestionService) SaveEmailTimesheet(email *types.InboundEmail, contractor *types.Contractor, requestID string, attachment int) (*types.TimesheetIngestion, error) {
+	return s.SaveTimesheet(email.MessageID, contractor, requestID, email.Attachments[attachment])
+}
+
+func (s *fakeTimesheetIngestionService) SaveTimesheet(key string, contractor *types.Contractor, requestID string, attachment types.Attachment) (*types.TimesheetIngestion, error) {
+	if err := s.err("SaveTimesheet"); err != nil {
+		return nil, err
+	}
+
+	s.saved = append(s.saved, contractor.ID+" "+requestID+" "+attachment.Filename)
+	return &types.TimesheetIngestion{Key: key, ContractorID: contractor.ID, RequestID: requestID, Duplicate: s.duplicates}, nil
+}
+
+// fakeTimesheetReviewService moves the timesheets the way TimesheetReviewService does and stores them in the database.
+type fakeTimesheetReviewService struct {
+	fakeErrors
+
+	db *fakeDatabase
+}
+
+// Ensure fakeTimesheetReviewService implements ITimesheetReviewService.
+var _ interfaces.ITimesheetReviewService = &fakeTimesheetReviewService{}
+
+// fakeTimesheetTransitions are the statuses a timesheet can move to, by its status.
+var fakeTimesheetTransitions = map[constants.TimesheetStatuses][]constants.TimesheetStatuses{
+	constants.Received:    {constants.UnderReview},
+	constants.UnderReview: {constants.Approved, constants.Rejected},
+	constants.Rejected:    {constants.Resubmitted},
+	constants.Resubmitted: {constants.UnderReview},
+}
+
+func (s *fakeTimesheetReviewService) TransitionTimesheet(timesheet *types.Timesheet, to constants.TimesheetStatuses, actor string, reason string) error {
+	if err := s.err("TransitionTimesheet"); err != nil {
+		return err
+	}
+
+	if !s.CanTransitionTimesheet(timesheet.Status, to) {
+		return status.Errorf(codes.FailedPrecondition, "timesheet %s cannot move from %s to %s", timesheet.ID, timesheet.Status, to)
+	}
+
+	if to == constants.Rejected && reason == "" {
+		return status.Error(codes.InvalidArgument, "a reason is required to reject a timesheet")
+	}
+
+	updated := *timesheet
+	updated.Status = to
+	updated.RejectionReason = reason
+
+	err := s.db.UpdateTimesheetStatus(&updated, timesheet.Status, timesheet.Version)
+	if err != nil {
+		return err
+	}
+
+	timesheet.Status = to
+	timesheet.RejectionReason = reason
+
+	return s.db.AddAuditEntry(&types.TimesheetAuditEntry{TimesheetID: timesheet.ID, GroupID: timesheet.GroupID, Actor: actor, Status: to, Reason: reason, Timestamp: time.Now().UnixMilli()})
+}
+
+func (s *fakeTimesheetReviewService) CanTransitionTimesheet(from constants.TimesheetStatuses, to constants.TimesheetStatuses) bool {
+	return slices.Contains(fakeTimesheetTransitions[from], to)
+}
@@ -1,14 +1,17 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	_ "time/tzdata"
 
-	"job_sender/core"
+	"job_sender/interfaces"
 	"job_sender/types"
 	constants "job_sender/utils/constants"
 
@@ -18,26 +21,65 @@ import (
 )
 
 type GroupsHandler struct {
-	authService           *core.AuthService
-	schedulerService      *core.SchedulerService
-	sessionManagerService *core.SessionManagerService
-	storageService        *core.StorageService
-	templateService       *core.TemplateService
-	errorReporterService  *core.ErrorReporterService
-
-	ownersDB *core.OwnerDatabaseService
-	groupsDB *core.GroupsDatabaseService
+	authService            interfaces.IAuthService
+	authorizationService   interfaces.IAuthorizationService
+	schedulerService       interfaces.ISchedulerService
+	scheduleService        interfaces.IScheduleService
+	holidayCalendarService interfaces.IHolidayCalendarService
+	sessionManagerService  interfaces.ISessionManagerService
+	storageService         interfaces.IStorageService
+	templateService        interfaces.ITemplateService
+	emailService           interfaces.IEmailService
+	emailTemplateService   interfaces.IEmailTemplateService
+	errorReporterService   interfaces.IErrorReporterService
+
+	ownersDB interfaces.IOwnerDatabaseService
+	groupsDB interfaces.IGroupsDatabaseService
 }
 
+// scheduleOccurrencesPreviewCount is the number of upcoming timesheet requests shown on the edit group page.
+const scheduleOccurrencesPreviewCount = 5
+
+// groupEditView is a group with the upcoming timesheet requests of its schedule and the holiday calendars to choose from.
+type groupEditView struct {
+	*types.Group
+
+	Occurrences      []*types.ScheduleOccurrence
+	OccurrencesError string
+
+	HolidayCalendars []*types.HolidayCalendar
+	FileTypes        []constants.TimesheetFileTypes // The timesheet file types a group can accept
+	MaxSizeMB        int                            // The largest file of the group, the default if it sets none
+	MaxSizeMBLimit   int                            // The largest file a group may allow
+
+	ErrorMessage string // Shown above the form, showError renders the page with one when the form is not saved
+}
+
+// emailPreview is the request and reminder emails of a group as a sample contractor would get them, or why the group's email content cannot be rendered.
+type emailPreview struct {
+	Error string
+
+	Request  *types.RenderedEmail
+	Reminder *types.RenderedEmail
+}
+
+// emailPreviewSubmissionLink is the upload link shown in the previews of the request email.
+const emailPreviewSubmissionLink = constants.AppUrl + constants.SubmissionLinkPath + "preview"
+
 // NewGroupsHandler creates a new GroupsHandler.
-func NewGroupsHandler(authService *core.AuthService, schedulerService *core.SchedulerService, sessionManagerService *core.SessionManagerService, storageService *core.StorageService, templateService *core.TemplateService, errorReporterService *core.ErrorReporterService, ownersDB *core.OwnerDatabaseService, groupsDB *core.GroupsDatabaseService) *GroupsHandler {
+func NewGroupsHandler(authService interfaces.IAuthService, authorizationService interfaces.IAuthorizationService, schedulerService interfaces.ISchedulerService, scheduleService interfaces.IScheduleService, holidayCalendarService interfaces.IHolidayCalendarService, sessionManagerService interfaces.ISessionManagerService, storageService interfaces.IStorageService, templateService interfaces.ITemplateService, emailService interfaces.IEmailService, emailTemplateService interfaces.IEmailTemplateService, errorReporterService interfaces.IErrorReporterService, ownersDB interfaces.IOwnerDatabaseService, groupsDB interfaces.IGroupsDatabaseService) *GroupsHandler {
 	return &GroupsHandler{
-		authService:           authService,
-		schedulerService:      schedulerService,
-		sessionManagerService: sessionManagerService,
-		storageService:        storageService,
-		templateService:       templateService,
-		errorReporterService:  errorReporterService,
+		authService:            authService,
+		authorizationService:   authorizationService,
+		schedulerService:       schedulerService,
+		scheduleService:        scheduleService,
+		holidayCalendarService: holidayCalendarService,
+		sessionManagerService:  sessionManagerService,
+		storageService:         storageService,
+		templateService:        templateService,
+		emailService:           emailService,
+		emailTemplateService:   emailTemplateService,
+		errorReporterService:   errorReporterService,
 
 		ownersDB: ownersDB,
 		groupsDB: groupsDB,
@@ -53,6 +95,7 @@ func (h *GroupsHandler) RegisterGroupsHandlers(r *mux.Router) {
 
 	r.Methods("POST").Path("/groups").HandlerFunc(h.AddGroup)
 	r.Methods("POST").Path("/groups/{ID}").HandlerFunc(h.EditGroup)
+	r.Methods("POST").Path("/groups/emails/preview").HandlerFunc(h.PreviewEmails)
 }
 
 // GetGroup gets a group.
@@ -63,10 +106,11 @@ func (h *GroupsHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	group, err := h.groupsDB.GetGroup(groupID)
+	// The groups of other owners are not found
+	group, err := h.authorizationService.GetOwnedGroup(r, groupID)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
-			http.Redirect(w, r, "/auth/groups/add", http.StatusSeeOther)
+			http.NotFound(w, r)
 			return
 		} else {
 			h.errorReporterService.ReportError(w, r, err)
@@ -94,7 +138,14 @@ func (h *GroupsHandler) ShowAddGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.templateService.ExecuteTemplate(groupTmpl, w, r, nil, userInfo)
+	data := map[string]interface{}{
+		"HolidayCalendars": h.holidayCalendarService.ListHolidayCalendars(),
+		"FileTypes":        constants.TimesheetFileTypesAll,
+		"MaxSizeMB":        constants.AttachmentDefaultMaxSizeMB,
+		"MaxSizeMBLimit":   constants.AttachmentMaxSizeMB,
+	}
+
+	err = h.templateService.ExecuteTemplate(groupTmpl, w, r, data, userInfo)
 	if err != nil {
 		h.errorReporterService.ReportError(w, r, err)
 		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
@@ -110,8 +161,11 @@ func (h *GroupsHandler) ShowEditGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	group, err := h.groupsDB.GetGroup(groupID)
-	if err != nil {
+	group, err := h.authorizationService.GetOwnedGroup(r, groupID)
+	if status.Code(err) == codes.NotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
 		h.errorReporterService.ReportError(w, r, err)
 		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
 		return
@@ -128,6 +182,22 @@ func (h *GroupsHandler) ShowEditGroup(w http.ResponseWriter, r *http.Request) {
 	userInfo.GroupID = group.ID
 	userInfo.GroupName = group.Name
 
+	// Preview the next timesheet requests, an invalid schedule is shown instead of failing the page
+	view := &groupEditView{
+		Group:            group,
+		HolidayCalendars: h.holidayCalendarService.ListHolidayCalendars(),
+		FileTypes:        constants.TimesheetFileTypesAll,
+		MaxSizeMB:        group.AttachmentPolicy.MaxSizeMB,
+		MaxSizeMBLimit:   constants.AttachmentMaxSizeMB,
+	}
+	if view.MaxSizeMB == 0 {
+		view.MaxSizeMB = constants.AttachmentDefaultMaxSizeMB
+	}
+	view.Occurrences, err = h.scheduleService.NextOccurrences(&group.Schedule, group.HolidayCalendar, time.Now(), scheduleOccurrencesPreviewCount)
+	if err != nil {
+		view.OccurrencesError = err.Error()
+	}
+
 	groupTmpl, err := h.templateService.ParseTemplate(constants.TemplateGroupEditName)
 	if err != nil {
 		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not parse group template: %w", err))
@@ -135,7 +205,7 @@ func (h *GroupsHandler) ShowEditGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.templateService.ExecuteTemplate(groupTmpl, w, r, group, userInfo)
+	err = h.templateService.ExecuteTemplate(groupTmpl, w, r, view, userInfo)
 	if err != nil {
 		h.errorReporterService.ReportError(w, r, err)
 		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
@@ -203,6 +273,14 @@ func (h *GroupsHandler) AddGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Create the reminder job for the group.
+	err = h.schedulerService.CreateTimesheetReminderJob(group.ID, &group.Schedule)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not create timesheet reminder job: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
 	http.Redirect(w, r, "/auth/contractors?groupID="+group.ID, http.StatusSeeOther)
 }
 
@@ -214,6 +292,17 @@ func (h *GroupsHandler) EditGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Only the owner of the group may change it, the group of another owner is not taken over
+	storedGroup, err := h.authorizationService.GetOwnedGroup(r, groupID)
+	if status.Code(err) == codes.NotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		h.errorReporterService.ReportError(w, r, err)
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
 	// Get the group from the form.
 	group, err := h.groupFromForm(r)
 	if err != nil {
@@ -222,23 +311,8 @@ func (h *GroupsHandler) EditGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	group.ID = groupID
-
-	// Get the owner id from session.
-	ownerID, err := h.sessionManagerService.GetElement(r, constants.UserSessionName, constants.SesstionOwnerIdField)
-	if err != nil {
-		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get owner id from session: %w", err))
-		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
-		return
-	}
-
-	// Append the owner id to the group.
-	ownerIDString, ok := ownerID.(string)
-	if !ok {
-		http.Error(w, "ownerID is required", http.StatusBadRequest)
-		return
-	}
-	group.OwnerID = ownerIDString
+	group.ID = storedGroup.ID
+	group.OwnerID = storedGroup.OwnerID
 
 	// Update the group.
 	err = h.groupsDB.UpdateGroup(group)
@@ -256,9 +330,89 @@ func (h *GroupsHandler) EditGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Update the reminder job for the group.
+	err = h.schedulerService.EditTimesheetReminderJob(group.ID, &group.Schedule)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not edit timesheet reminder job: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
 	http.Redirect(w, r, "/auth/contractors?groupID="+group.ID, http.StatusSeeOther)
 }
 
+// PreviewEmails renders the request and reminder emails of the group in the add or edit group form for a sample contractor, in the language of the form.
+// The period of the request comes from the schedule of the form, a schedule that is not filled in yet previews a sample period instead.
+func (h *GroupsHandler) PreviewEmails(w http.ResponseWriter, r *http.Request) {
+	var preview emailPreview
+
+	group, err := h.groupFromForm(r)
+	if err != nil {
+		group = &types.Group{
+			Name: strings.TrimSpace(r.FormValue("name")),
+
+			Branding:     brandingFromForm(r),
+			EmailContent: emailContentFromForm(r),
+		}
+
+		group.Schedule.Timezone = r.FormValue("timezone")
+		if reminderPolicy, err := reminderPolicyFromForm(r); err == nil {
+			group.ReminderPolicy = *reminderPolicy
+		}
+
+		err = h.emailTemplateService.ValidateEmailContent(&group.EmailContent)
+		if err != nil {
+			preview.Error = status.Convert(err).Message()
+		}
+	}
+
+	requestID := time.Now().AddDate(0, 0, -13).Format("2006-01-02") + "_" + time.Now().Format("2006-01-02")
+	occurrences, err := h.scheduleService.NextOccurrences(&group.Schedule, group.HolidayCalendar, time.Now(), 1)
+	if err == nil && len(occurrences) > 0 {
+		requestID = occurrences[0].RequestID
+	}
+
+	// The request as it would be sent now
+	sentAt := time.Now()
+	request := &types.TimesheetRequest{
+		RequestID: requestID,
+
+		SentAt: sentAt,
+		DueAt:  h.scheduleService.RequestDueAt(&group.ReminderPolicy, sentAt),
+	}
+	request.PeriodStart, request.PeriodEnd, _ = h.scheduleService.RequestPeriod(requestID)
+
+	contractor := &types.Contractor{
+		ID: "contractorID",
+
+		Name:     "Jan",
+		Surname:  "Kowalski",
+		Email:    "jan.kowalski@example.com",
+		Language: r.FormValue("preview_language"),
+	}
+
+	if preview.Error == "" {
+		preview.Request, err = h.emailService.PreviewTimesheetRequestEmail(group, contractor, request, emailPreviewSubmissionLink)
+		if err == nil {
+			preview.Reminder, err = h.emailService.PreviewTimesheetReminderEmail(group, contractor, request)
+		}
+
+		if status.Code(err) == codes.InvalidArgument {
+			preview.Error = status.Convert(err).Message()
+		} else if err != nil {
+			h.errorReporterService.ReportError(w, r, fmt.Errorf("could not preview emails: %w", err))
+			http.Error(w, "could not preview emails", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(&preview)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not write email preview: %w", err))
+	}
+}
+
 // DeleteGroup deletes a group.
 func (h *GroupsHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
 	groupID := mux.Vars(r)["ID"]
@@ -267,7 +421,17 @@ func (h *GroupsHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.groupsDB.DeleteGroup(groupID)
+	_, err := h.authorizationService.GetOwnedGroup(r, groupID)
+	if status.Code(err) == codes.NotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		h.errorReporterService.ReportError(w, r, err)
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	err = h.groupsDB.DeleteGroup(groupID)
 	if err != nil {
 		h.errorReporterService.ReportError(w, r, err)
 		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
@@ -282,6 +446,14 @@ func (h *GroupsHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Delete the reminder job for the group.
+	err = h.schedulerService.DeleteTimesheetReminderJob(groupID)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not delete timesheet reminder job: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
 	// Delete the timesheets from the storage.
 	err = h.storageService.DeleteFiles(groupID)
 	if err != nil {
@@ -304,6 +476,10 @@ func (h *GroupsHandler) groupFromForm(r *http.Request) (*types.Group, error) {
 	endDateStr := r.FormValue("end_date")
 	intervalTypeStr := r.FormValue("interval_type")
 	intervalStr := r.FormValue("interval")
+	weekStr := r.FormValue("week")
+	holidaysStr := r.FormValue("holidays")
+	cronStr := strings.TrimSpace(r.FormValue("cron"))
+	holidayCalendar := r.FormValue("holiday_calendar")
 
 	if intervalTypeStr == "" {
 		return nil, fmt.Errorf("missing required fields")
@@ -315,6 +491,16 @@ func (h *GroupsHandler) groupFromForm(r *http.Request) (*types.Group, error) {
 		intervalType = constants.Weeks
 	case "months":
 		intervalType = constants.Months
+	case "semi_monthly":
+		intervalType = constants.SemiMonthly
+	case "last_day_of_month":
+		intervalType = constants.LastDayOfMonth
+	case "nth_weekday_of_month":
+		intervalType = constants.NthWeekdayOfMonth
+	case "last_business_day":
+		intervalType = constants.LastBusinessDay
+	case "cron":
+		intervalType = constants.Cron
 	default:
 		return nil, fmt.Errorf("invalid interval type: %s", intervalTypeStr)
 	}
@@ -323,11 +509,23 @@ func (h *GroupsHandler) groupFromForm(r *http.Request) (*types.Group, error) {
 		return nil, fmt.Errorf("missing required fields")
 	}
 
-	if intervalType == constants.Weeks && weekday == "" {
+	if (intervalType == constants.Weeks || intervalType == constants.NthWeekdayOfMonth) && weekday == "" {
+		return nil, fmt.Errorf("missing required fields")
+	}
+
+	if intervalType == constants.NthWeekdayOfMonth && weekStr == "" {
+		return nil, fmt.Errorf("missing required fields")
+	}
+
+	if intervalType == constants.Cron && cronStr == "" {
 		return nil, fmt.Errorf("missing required fields")
 	}
 
-	if name == "" || timezoneStr == "" || timeStr == "" || intervalStr == "" || startDateStr == "" || endDateStr == "" {
+	if intervalType.HasInterval() && intervalStr == "" {
+		return nil, fmt.Errorf("missing required fields")
+	}
+
+	if name == "" || timezoneStr == "" || timeStr == "" || startDateStr == "" || endDateStr == "" {
 		return nil, fmt.Errorf("missing required fields")
 	}
 
@@ -346,53 +544,212 @@ func (h *GroupsHandler) groupFromForm(r *http.Request) (*types.Group, error) {
 		return nil, fmt.Errorf("could not parse end date: %w", err)
 	}
 
+	if endDate.Before(startDate) {
+		return nil, fmt.Errorf("end date is before start date")
+	}
+
 	timeParsed, err := time.Parse("15:04", timeStr)
 	if err != nil {
 		return nil, fmt.Errorf("could not parse time: %w", err)
 	}
 
-	interval, err := strconv.Atoi(intervalStr)
-	if err != nil {
-		return nil, fmt.Errorf("could not convert interval to number: %w", err)
+	// Semi-monthly and cron schedules have no interval of their own
+	interval := 1
+	if intervalType.HasInterval() {
+		interval, err = strconv.Atoi(intervalStr)
+		if err != nil {
+			return nil, fmt.Errorf("could not convert interval to number: %w", err)
+		}
+
+		if interval < 1 {
+			return nil, fmt.Errorf("interval must be at least 1")
+		}
 	}
 
-	// Round the monthday to the nearest valid day of the month.
+	var week int
+	if intervalType == constants.NthWeekdayOfMonth {
+		week, err = strconv.Atoi(weekStr)
+		if err != nil || (week != -1 && (week < 1 || week > 4)) {
+			return nil, fmt.Errorf("invalid week of month: %s", weekStr)
+		}
+	}
+
+	var holidays []string
+	if intervalType == constants.LastBusinessDay {
+		holidays, err = holidaysFromForm(holidaysStr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if intervalType != constants.Cron {
+		cronStr = ""
+	}
+
+	// Days missing in shorter months fall on their last day, so any day of month is kept as is.
 	if intervalType == constants.Months {
 		monthdayInt, err := strconv.Atoi(monthday)
 		if err != nil {
 			return nil, fmt.Errorf("could not convert monthday to number: %w", err)
 		}
 
-		// Get current month and check if the monthday is valid.
-		now := time.Now()
-		year, month, _ := now.Date()
+		if monthdayInt < 1 || monthdayInt > 31 {
+			return nil, fmt.Errorf("invalid monthday: %d", monthdayInt)
+		}
+	}
 
-		// Get the last day of current month.
-		lastDay := time.Date(year, month, 0, 0, 0, 0, 0, time.UTC).Day()
+	reminderPolicy, err := reminderPolicyFromForm(r)
+	if err != nil {
+		return nil, err
+	}
 
-		// Round the monthday to the nearest valid day of the month.
-		if monthdayInt > lastDay {
-			monthday = fmt.Sprintf("%d", lastDay)
-		}
+	attachmentPolicy, err := attachmentPolicyFromForm(r)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := types.Schedule{
+		Weekday:      weekday,
+		Monthday:     monthday,
+		Week:         week,
+		Timezone:     timezoneParsed.String(),
+		Time:         timeParsed.Format("15:04"),
+		StartDate:    startDate.Format("2006-01-02"),
+		EndDate:      endDate.Format("2006-01-02"),
+		IntervalType: intervalType,
+		Interval:     interval,
+		Holidays:     holidays,
+		Cron:         cronStr,
+	}
+
+	// Check the schedule has occurrences the way they are computed, e.g. that the cron expression is valid
+	_, err = h.scheduleService.NextOccurrences(&schedule, holidayCalendar, time.Now(), 1)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule: %w", err)
+	}
+
+	// Check the email content only uses the placeholders there are
+	emailContent := emailContentFromForm(r)
+	err = h.emailTemplateService.ValidateEmailContent(&emailContent)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email content: %w", err)
 	}
 
 	return &types.Group{
 		OwnerID: r.FormValue("ownerID"),
 		Name:    name,
 
-		Schedule: types.Schedule{
-			Weekday:      weekday,
-			Monthday:     monthday,
-			Timezone:     timezoneParsed.String(),
-			Time:         timeParsed.Format("15:04"),
-			StartDate:    startDate.Format("2006-01-02"),
-			EndDate:      endDate.Format("2006-01-02"),
-			IntervalType: intervalType,
-			Interval:     interval,
-		},
+		ReminderPolicy:   *reminderPolicy,
+		AttachmentPolicy: *attachmentPolicy,
+
+		Schedule:        schedule,
+		HolidayCalendar: holidayCalendar,
+
+		Branding:     brandingFromForm(r),
+		EmailContent: emailContent,
 	}, nil
 }
 
+// brandingFromForm returns the email branding of a form.
+func brandingFromForm(r *http.Request) types.EmailBranding {
+	return types.EmailBranding{
+		LogoURL:   strings.TrimSpace(r.FormValue("logo_url")),
+		Signature: strings.TrimSpace(r.FormValue("signature")),
+	}
+}
+
+// emailContentFromForm returns the email content of a form, the empty fields keep the default wording.
+func emailContentFromForm(r *http.Request) types.EmailContent {
+	return types.EmailContent{
+		SubjectSuffix: strings.TrimSpace(r.FormValue("subject_suffix")),
+		RequestBody:   strings.TrimSpace(r.FormValue("request_body")),
+		ReminderBody:  strings.TrimSpace(r.FormValue("reminder_body")),
+	}
+}
+
+// holidaysFromForm parses the holidays of a form, dates separated by commas or new lines like "2025-12-24, 2025-12-31".
+func holidaysFromForm(holidaysStr string) ([]string, error) {
+	var holidays []string
+	for _, holidayStr := range strings.FieldsFunc(holidaysStr, func(c rune) bool { return c == ',' || c == '\n' || c == '\r' }) {
+		holidayStr = strings.TrimSpace(holidayStr)
+		if holidayStr == "" {
+			continue
+		}
+
+		holiday, err := time.Parse("2006-01-02", holidayStr)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse holiday: %w", err)
+		}
+
+		holidays = append(holidays, holiday.Format("2006-01-02"))
+	}
+	slices.Sort(holidays)
+
+	return slices.Compact(holidays), nil
+}
+
+// reminderPolicyFromForm creates a reminder policy from a form, the reminder days are a comma separated list like "2, 5".
+func reminderPolicyFromForm(r *http.Request) (*types.ReminderPolicy, error) {
+	policy := &types.ReminderPolicy{
+		Enabled: r.FormValue("reminders_enabled") == "on",
+	}
+
+	for _, daysStr := range strings.Split(r.FormValue("reminder_days"), ",") {
+		daysStr = strings.TrimSpace(daysStr)
+		if daysStr == "" {
+			continue
+		}
+
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days < 1 {
+			return nil, fmt.Errorf("invalid reminder days: %s", daysStr)
+		}
+
+		policy.ReminderDays = append(policy.ReminderDays, days)
+	}
+	slices.Sort(policy.ReminderDays)
+	policy.ReminderDays = slices.Compact(policy.ReminderDays)
+
+	if escalationDaysStr := r.FormValue("escalation_days"); escalationDaysStr != "" {
+		escalationDays, err := strconv.Atoi(escalationDaysStr)
+		if err != nil || escalationDays < 0 {
+			return nil, fmt.Errorf("invalid escalation days: %s", escalationDaysStr)
+		}
+
+		policy.EscalationDays = escalationDays
+	}
+
+	return policy, nil
+}
+
+// attachmentPolicyFromForm creates an attachment policy from a form, at least one file type is required.
+func attachmentPolicyFromForm(r *http.Request) (*types.AttachmentPolicy, error) {
+	maxSizeMB, err := strconv.Atoi(r.FormValue("max_size_mb"))
+	if err != nil || maxSizeMB < 1 || maxSizeMB > constants.AttachmentMaxSizeMB {
+		return nil, fmt.Errorf("invalid largest file size: %s, at most %d MB", r.FormValue("max_size_mb"), constants.AttachmentMaxSizeMB)
+	}
+
+	policy := &types.AttachmentPolicy{MaxSizeMB: maxSizeMB}
+
+	// FormValue parsed the form
+	for _, typeStr := range r.Form["allowed_types"] {
+		fileType, err := strconv.Atoi(typeStr)
+		if err != nil || !slices.Contains(constants.TimesheetFileTypesAll, constants.TimesheetFileTypes(fileType)) {
+			return nil, fmt.Errorf("invalid file type: %s", typeStr)
+		}
+
+		policy.AllowedTypes = append(policy.AllowedTypes, constants.TimesheetFileTypes(fileType))
+	}
+	slices.Sort(policy.AllowedTypes)
+	policy.AllowedTypes = slices.Compact(policy.AllowedTypes)
+
+	if len(policy.AllowedTypes) == 0 {
+		return nil, fmt.Errorf("at least one file type must be allowed")
+	}
+
+	return policy, nil
+}
+
 // showError renders the login page with an error message.
 func (h *GroupsHandler) showError(w http.ResponseWriter, r *http.Request, errorMessage string) {
 	groupTmpl, err := h.templateService.ParseTemplate(constants.TemplateGroupEditName)
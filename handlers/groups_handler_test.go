@@ -0,0 +1,569 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+
+	constants "job_sender/utils/constants"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testGroupForm returns the form of a weekly group, with a field changed for each pair of keys and values.
+func testGroupForm(keysAndValues ...string) url.Values {
+	form := url.Values{
+		"name":          {"Backend"},
+		"interval_type": {"weeks"},
+		"weekday":       {"Monday"},
+		"interval":      {"2"},
+		"timezone":      {"Europe/Warsaw"},
+		"time":          {"09:00"},
+		"start_date":    {"2025-01-06"},
+		"end_date":      {"2025-12-31"},
+		"reminder_days": {"5, 2"},
+		"max_size_mb":   {"5"},
+		"allowed_types": {"0", "1"},
+	}
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		form.Set(keysAndValues[i], keysAndValues[i+1])
+	}
+
+	return form
+}
+
+func TestGroupsHandler(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name:         "group",
+			method:       "GET",
+			target:       "/auth/groups/group1",
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/contractors?groupID=group1",
+		},
+		{
+			name:        "group of another owner",
+			method:      "GET",
+			target:      "/auth/groups/group2",
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+		},
+		{
+			name:         "group not read",
+			method:       "GET",
+			target:       "/auth/groups/group1",
+			setup:        func(ts *testServer) { ts.db.fail("GetGroup", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "add group page",
+			method:       "GET",
+			target:       "/auth/groups/add",
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateGroupAddName,
+			check: func(t *testing.T, ts *testServer) {
+				if maxSizeMB := ts.pageData(t)["MaxSizeMB"]; maxSizeMB != constants.AttachmentDefaultMaxSizeMB {
+					t.Errorf("MaxSizeMB = %v, want %d", maxSizeMB, constants.AttachmentDefaultMaxSizeMB)
+				}
+			},
+		},
+		{
+			name:         "add group page without the user",
+			method:       "GET",
+			target:       "/auth/groups/add",
+			setup:        func(ts *testServer) { ts.auth.fail("CheckUser", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "add group page not parsed",
+			method:       "GET",
+			target:       "/auth/groups/add",
+			setup:        func(ts *testServer) { ts.templates.fail("ParseTemplate", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "edit group page",
+			method:       "GET",
+			target:       "/auth/groups/group1/edit",
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateGroupEditName,
+			check: func(t *testing.T, ts *testServer) {
+				view, ok := ts.templates.data.(*groupEditView)
+				if !ok {
+					t.Fatalf("page data = %T, want a groupEditView", ts.templates.data)
+				}
+				if view.ID != "group1" || view.MaxSizeMB != 5 || len(view.Occurrences) != 1 {
+					t.Errorf("view = %+v, want group1 with 5 MB files and its occurrence", view)
+				}
+				if ts.templates.userInfo.GroupID != "group1" {
+					t.Errorf("GroupID of the user = %q, want group1", ts.templates.userInfo.GroupID)
+				}
+			},
+		},
+		{
+			name:         "edit group page with an invalid schedule",
+			method:       "GET",
+			target:       "/auth/groups/group1/edit",
+			setup:        func(ts *testServer) { ts.schedule.fail("NextOccurrences", errFake) },
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateGroupEditName,
+			check: func(t *testing.T, ts *testServer) {
+				if view := ts.templates.data.(*groupEditView); view.OccurrencesError != errFake.Error() {
+					t.Errorf("OccurrencesError = %q, want %q", view.OccurrencesError, errFake.Error())
+				}
+			},
+		},
+		{
+			name:        "edit page of a group of another owner",
+			method:      "GET",
+			target:      "/auth/groups/group2/edit",
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+		},
+		{
+			name:         "edit group page without the group",
+			method:       "GET",
+			target:       "/auth/groups/group1/edit",
+			setup:        func(ts *testServer) { ts.db.fail("GetGroup", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "edit group page without the user",
+			method:       "GET",
+			target:       "/auth/groups/group1/edit",
+			setup:        func(ts *testServer) { ts.auth.fail("CheckUser", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "edit group page not executed",
+			method:       "GET",
+			target:       "/auth/groups/group1/edit",
+			setup:        func(ts *testServer) { ts.templates.fail("ExecuteTemplate", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "add group",
+			method:       "POST",
+			target:       "/auth/groups",
+			form:         testGroupForm(),
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/contractors?groupID=new-group1",
+			check: func(t *testing.T, ts *testServer) {
+				group := ts.db.groups["new-group1"]
+				if group == nil || group.OwnerID != "owner1" || group.Schedule.Interval != 2 || !slices.Equal(group.ReminderPolicy.ReminderDays, []int{2, 5}) {
+					t.Fatalf("group = %+v, want the group of owner1 every 2 weeks, reminded after 2 and 5 days", group)
+				}
+				if ts.db.owners["owner1"].GroupID != "new-group1" {
+					t.Errorf("group of owner1 = %q, want new-group1", ts.db.owners["owner1"].GroupID)
+				}
+				if !slices.Contains(ts.scheduler.jobs, "request new-group1") || !slices.Contains(ts.scheduler.jobs, "reminder new-group1") {
+					t.Errorf("jobs = %v, want the jobs of new-group1", ts.scheduler.jobs)
+				}
+			},
+		},
+		{
+			name:         "add group without a name",
+			method:       "POST",
+			target:       "/auth/groups",
+			form:         testGroupForm("name", ""),
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateGroupEditName,
+			wantMessage:  "missing required fields",
+			wantReported: true,
+		},
+		{
+			name:         "add group with an invalid timezone",
+			method:       "POST",
+			target:       "/auth/groups",
+			form:         testGroupForm("timezone", "Mars/Olympus"),
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateGroupEditName,
+			wantMessage:  "could not parse timezone",
+			wantReported: true,
+		},
+		{
+			name:         "add group ending before it starts",
+			method:       "POST",
+			target:       "/auth/groups",
+			form:         testGroupForm("end_date", "2024-12-31"),
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateGroupEditName,
+			wantMessage:  "end date is before start date",
+			wantReported: true,
+		},
+		{
+			name:         "add group with an invalid interval",
+			method:       "POST",
+			target:       "/auth/groups",
+			form:         testGroupForm("interval", "0"),
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateGroupEditName,
+			wantMessage:  "interval must be at least 1",
+			wantReported: true,
+		},
+		{
+			name:         "add group with invalid reminder days",
+			method:       "POST",
+			target:       "/auth/groups",
+			form:         testGroupForm("reminder_days", "2, 0"),
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateGroupEditName,
+			wantMessage:  "invalid reminder days: 0",
+			wantReported: true,
+		},
+		{
+			name:         "add group with too large files",
+			method:       "POST",
+			target:       "/auth/groups",
+			form:         testGroupForm("max_size_mb", "26"),
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateGroupEditName,
+			wantMessage:  "invalid largest file size: 26",
+			wantReported: true,
+		},
+		{
+			name:         "add group with an invalid schedule",
+			method:       "POST",
+			target:       "/auth/groups",
+			form:         testGroupForm(),
+			setup:        func(ts *testServer) { ts.schedule.fail("NextOccurrences", errFake) },
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateGroupEditName,
+			wantMessage:  "invalid schedule",
+			wantReported: true,
+		},
+		{
+			name:         "add group with invalid email content",
+			method:       "POST",
+			target:       "/auth/groups",
+			form:         testGroupForm(),
+			setup:        func(ts *testServer) { ts.emailTemplate.fail("ValidateEmailContent", errFake) },
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateGroupEditName,
+			wantMessage:  "invalid email content",
+			wantReported: true,
+		},
+		{
+			name:         "add group without the session",
+			method:       "POST",
+			target:       "/auth/groups",
+			form:         testGroupForm(),
+			setup:        func(ts *testServer) { ts.sessions.fail("GetElement", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:        "add group without the owner ID",
+			method:      "POST",
+			target:      "/auth/groups",
+			form:        testGroupForm(),
+			setup:       func(ts *testServer) { delete(ts.sessions.values, constants.SesstionOwnerIdField) },
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "ownerID is required",
+		},
+		{
+			name:         "group not added",
+			method:       "POST",
+			target:       "/auth/groups",
+			form:         testGroupForm(),
+			setup:        func(ts *testServer) { ts.db.fail("AddGroup", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "owner of the added group not read",
+			method:       "POST",
+			target:       "/auth/groups",
+			form:         testGroupForm(),
+			setup:        func(ts *testServer) { ts.db.fail("GetOwnerByID", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "owner of the added group not updated",
+			method:       "POST",
+			target:       "/auth/groups",
+			form:         testGroupForm(),
+			setup:        func(ts *testServer) { ts.db.fail("UpdateOwner", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "request job not created",
+			method:       "POST",
+			target:       "/auth/groups",
+			form:         testGroupForm(),
+			setup:        func(ts *testServer) { ts.scheduler.fail("CreateTimesheetRequestJob", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "reminder job not created",
+			method:       "POST",
+			target:       "/auth/groups",
+			form:         testGroupForm(),
+			setup:        func(ts *testServer) { ts.scheduler.fail("CreateTimesheetReminderJob", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "edit group",
+			method:       "POST",
+			target:       "/auth/groups/group1",
+			form:         testGroupForm("name", "Frontend", "ownerID", "owner2"),
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/contractors?groupID=group1",
+			check: func(t *testing.T, ts *testServer) {
+				// The owner of the form is ignored, the group stays the logged owner's
+				if group := ts.db.groups["group1"]; group.Name != "Frontend" || group.OwnerID != "owner1" {
+					t.Errorf("group1 = %+v, want Frontend of owner1", group)
+				}
+			},
+		},
+		{
+			name:        "edit group of another owner",
+			method:      "POST",
+			target:      "/auth/groups/group2",
+			form:        testGroupForm("name", "Taken over"),
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+			check: func(t *testing.T, ts *testServer) {
+				if name := ts.db.groups["group2"].Name; name != "Group 2" {
+					t.Errorf("name of group2 = %q, want it unchanged", name)
+				}
+			},
+		},
+		{
+			name:         "edit group without the group",
+			method:       "POST",
+			target:       "/auth/groups/group1",
+			form:         testGroupForm(),
+			setup:        func(ts *testServer) { ts.db.fail("GetGroup", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "edit group with an invalid form",
+			method:       "POST",
+			target:       "/auth/groups/group1",
+			form:         testGroupForm("interval_type", "years"),
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateGroupEditName,
+			wantMessage:  "invalid interval type: years",
+			wantReported: true,
+		},
+		{
+			name:         "group not updated",
+			method:       "POST",
+			target:       "/auth/groups/group1",
+			form:         testGroupForm(),
+			setup:        func(ts *testServer) { ts.db.fail("UpdateGroup", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "request job not edited",
+			method:       "POST",
+			target:       "/auth/groups/group1",
+			form:         testGroupForm(),
+			setup:        func(ts *testServer) { ts.scheduler.fail("EditTimesheetRequestJob", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "reminder job not edited",
+			method:       "POST",
+			target:       "/auth/groups/group1",
+			form:         testGroupForm(),
+			setup:        func(ts *testServer) { ts.scheduler.fail("EditTimesheetReminderJob", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "delete group",
+			method:       "GET",
+			target:       "/auth/groups/group1/delete",
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/groups/add",
+			check: func(t *testing.T, ts *testServer) {
+				if _, ok := ts.db.groups["group1"]; ok {
+					t.Error("group1 was not deleted")
+				}
+				if !slices.Equal(ts.scheduler.jobs, []string{"request group2", "reminder group2"}) {
+					t.Errorf("jobs = %v, want only the jobs of group2", ts.scheduler.jobs)
+				}
+				if !slices.Equal(ts.storage.deletedPrefixes, []string{"group1"}) {
+					t.Errorf("deleted files = %v, want the files of group1", ts.storage.deletedPrefixes)
+				}
+			},
+		},
+		{
+			name:        "delete group of another owner",
+			method:      "GET",
+			target:      "/auth/groups/group2/delete",
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+			check: func(t *testing.T, ts *testServer) {
+				if _, ok := ts.db.groups["group2"]; !ok {
+					t.Error("group2 was deleted")
+				}
+			},
+		},
+		{
+			name:         "delete group without the group",
+			method:       "GET",
+			target:       "/auth/groups/group1/delete",
+			setup:        func(ts *testServer) { ts.db.fail("GetGroup", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "group not deleted",
+			method:       "GET",
+			target:       "/auth/groups/group1/delete",
+			setup:        func(ts *testServer) { ts.db.fail("DeleteGroup", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "request job not deleted",
+			method:       "GET",
+			target:       "/auth/groups/group1/delete",
+			setup:        func(ts *testServer) { ts.scheduler.fail("DeleteTimesheetRequestJob", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "reminder job not deleted",
+			method:       "GET",
+			target:       "/auth/groups/group1/delete",
+			setup:        func(ts *testServer) { ts.scheduler.fail("DeleteTimesheetReminderJob", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "files not deleted",
+			method:       "GET",
+			target:       "/auth/groups/group1/delete",
+			setup:        func(ts *testServer) { ts.storage.fail("DeleteFiles", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+	})
+}
+
+func TestGroupsHandlerPreviewEmails(t *testing.T) {
+	invalidContent := status.Error(codes.InvalidArgument, "unknown placeholder {{.Salary}}")
+
+	tests := []struct {
+		name         string
+		form         url.Values
+		setup        func(*testServer)
+		wantStatus   int
+		wantSubject  string // Subject of the request email, empty if there is no preview
+		wantError    string
+		wantReported bool
+	}{
+		{
+			name:        "complete form",
+			form:        testGroupForm(),
+			wantStatus:  http.StatusOK,
+			wantSubject: "Timesheet " + testRequestID,
+		},
+		{
+			name:        "schedule not filled in",
+			form:        url.Values{"name": {"Backend"}, "timezone": {"Europe/Warsaw"}},
+			wantStatus:  http.StatusOK,
+			wantSubject: "Timesheet " + testRequestID,
+		},
+		{
+			name:       "invalid email content",
+			form:       url.Values{"name": {"Backend"}, "request_body": {"{{.Salary}}"}},
+			setup:      func(ts *testServer) { ts.emailTemplate.fail("ValidateEmailContent", invalidContent) },
+			wantStatus: http.StatusOK,
+			wantError:  "unknown placeholder {{.Salary}}",
+		},
+		{
+			name:       "email not rendered",
+			form:       testGroupForm(),
+			setup:      func(ts *testServer) { ts.email.fail("PreviewTimesheetReminderEmail", invalidContent) },
+			wantStatus: http.StatusOK,
+			wantError:  "unknown placeholder {{.Salary}}",
+		},
+		{
+			name:         "preview fails",
+			form:         testGroupForm(),
+			setup:        func(ts *testServer) { ts.email.fail("PreviewTimesheetRequestEmail", errFake) },
+			wantStatus:   http.StatusInternalServerError,
+			wantReported: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t)
+			if tt.setup != nil {
+				tt.setup(ts)
+			}
+
+			w := ts.do(t, "POST", "/auth/groups/emails/preview", tt.form, "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %q)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if reported := len(ts.errorReporter.errs) > 0; reported != tt.wantReported {
+				t.Errorf("reported errors = %v, want reported %v", ts.errorReporter.errs, tt.wantReported)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var preview emailPreview
+			err := json.Unmarshal(w.Body.Bytes(), &preview)
+			if err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+
+			if preview.Error != tt.wantError {
+				t.Errorf("Error = %q, want %q", preview.Error, tt.wantError)
+			}
+
+			var subject string
+			if preview.Error == "" && preview.Request != nil {
+				subject = preview.Request.Subject
+			}
+			if subject != tt.wantSubject {
+				t.Errorf("subject = %q, want %q", subject, tt.wantSubject)
+			}
+		})
+	}
+}
@@ -0,0 +1,356 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type InboxHandler struct {
+	authService               interfaces.IAuthService
+	authorizationService      interfaces.IAuthorizationService
+	emailService              interfaces.IEmailService
+	templateService           interfaces.ITemplateService
+	timesheetIngestionService interfaces.ITimesheetIngestionService
+	errorReporterService      interfaces.IErrorReporterService
+
+	ownersDB            interfaces.IOwnerDatabaseService
+	groupsDB            interfaces.IGroupsDatabaseService
+	contractorsDB       interfaces.IContractorsDatabaseService
+	timesheetRequestsDB interfaces.ITimesheetRequestsDatabaseService
+	unassignedEmailsDB  interfaces.IUnassignedEmailsDatabaseService
+}
+
+// inboxView is the owner's inbox, the unassigned emails with the requests of the group's contractors to assign them to.
+type inboxView struct {
+	Emails      []*inboxEmail
+	Contractors []*types.Contractor
+
+	// Requests are the requests of the group by contractor ID
+	Requests map[string][]*types.TimesheetRequest
+}
+
+// inboxEmail is an unassigned email with the contractor it is attributed to, nil if none or if the contractor was deleted since.
+type inboxEmail struct {
+	*types.UnassignedEmail
+
+	Contractor *types.Contractor
+}
+
+// NewInboxHandler creates a new InboxHandler.
+func NewInboxHandler(authService interfaces.IAuthService, authorizationService interfaces.IAuthorizationService, emailService interfaces.IEmailService, templateService interfaces.ITemplateService, timesheetIngestionService interfaces.ITimesheetIngestionService, errorReporterService interfaces.IErrorReporterService, ownersDB interfaces.IOwnerDatabaseService, groupsDB interfaces.IGroupsDatabaseService, contractorsDB interfaces.IContractorsDatabaseService, timesheetRequestsDB interfaces.ITimesheetRequestsDatabaseService, unassignedEmailsDB interfaces.IUnassignedEmailsDatabaseService) *InboxHandler {
+	return &InboxHandler{
+		authService:               authService,
+		authorizationService:      authorizationService,
+		emailService:              emailService,
+		templateService:           templateService,
+		timesheetIngestionService: timesheetIngestionService,
+		errorReporterService:      errorReporterService,
+
+		ownersDB:            ownersDB,
+		groupsDB:            groupsDB,
+		contractorsDB:       contractorsDB,
+		timesheetRequestsDB: timesheetRequestsDB,
+		unassignedEmailsDB:  unassignedEmailsDB,
+	}
+}
+
+// RegisterInboxHandlers registers the Inbox handlers.
+func (h *InboxHandler) RegisterInboxHandlers(r *mux.Router) {
+	r.Methods("GET").Path("/inbox").HandlerFunc(h.GetInbox)
+
+	r.Methods("POST").Path("/inbox/{ID}/assign").HandlerFunc(h.AssignEmail)
+	r.Methods("POST").Path("/inbox/{ID}/discard").HandlerFunc(h.DiscardEmail)
+	r.Methods("POST").Path("/inbox/{ID}/reply").HandlerFunc(h.ReplyToEmail)
+}
+
+// GetInbox lists the emails that could not be matched to a timesheet request, with why, for the owner to triage. The owner sees the emails
+// attributed to the group, as the inbox watcher triaged them, the mailbox is not read.
+func (h *InboxHandler) GetInbox(w http.ResponseWriter, r *http.Request) {
+	userInfo, err := h.authService.CheckUser(r)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not check user: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	group, contractors, ok := h.getContractors(w, r, userInfo)
+	if !ok {
+		return
+	}
+
+	view := &inboxView{Contractors: contractors, Requests: map[string][]*types.TimesheetRequest{}}
+	if group != nil {
+		requests, err := h.timesheetRequestsDB.ListTimesheetRequests(group.ID)
+		if err != nil {
+			h.errorReporterService.ReportError(w, r, fmt.Errorf("could not list timesheet requests: %w", err))
+			http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+			return
+		}
+
+		// Oldest first, the periods of the request IDs sort by their first day
+		slices.SortFunc(requests, func(a, b *types.TimesheetRequest) int { return strings.Compare(a.RequestID, b.RequestID) })
+		for _, request := range requests {
+			view.Requests[request.ContractorID] = append(view.Requests[request.ContractorID], request)
+		}
+
+		emails, err := h.unassignedEmailsDB.ListUnassignedEmails(group.ID)
+		if err != nil {
+			h.errorReporterService.ReportError(w, r, fmt.Errorf("could not list unassigned emails: %w", err))
+			http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+			return
+		}
+
+		for _, email := range emails {
+			view.Emails = append(view.Emails, &inboxEmail{UnassignedEmail: email, Contractor: findContractor(contractors, email.ContractorID)})
+		}
+	}
+
+	inboxTmpl, err := h.templateService.ParseTemplate(constants.TemplateInboxName)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not parse inbox template: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	err = h.templateService.ExecuteTemplate(inboxTmpl, w, r, view, userInfo)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not execute template: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+}
+
+// AssignEmail saves an attachment of an email as the timesheet of a request of the group's contractors, and removes the email from the inbox.
+func (h *InboxHandler) AssignEmail(w http.ResponseWriter, r *http.Request) {
+	_, unassigned, email, contractors, ok := h.getEmail(w, r)
+	if !ok {
+		return
+	}
+
+	// The request is chosen as "<contractor ID>/<request ID>"
+	contractorID, requestID, _ := strings.Cut(r.FormValue("request"), "/")
+	contractor := findContractor(contractors, contractorID)
+	if contractor == nil {
+		http.Error(w, "A contractor of the group is required", http.StatusBadRequest)
+		return
+	}
+
+	_, err := h.timesheetRequestsDB.GetTimesheetRequest(contractor.ID, requestID)
+	if status.Code(err) == codes.NotFound {
+		http.Error(w, "A request of the contractor is required", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get timesheet request: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	attachment, err := strconv.Atoi(r.FormValue("attachment"))
+	if err != nil || attachment < 0 || attachment >= len(email.Attachments) {
+		http.Error(w, "An attachment of the email is required", http.StatusBadRequest)
+		return
+	}
+
+	_, err = h.timesheetIngestionService.SaveEmailTimesheet(email, contractor, requestID, attachment)
+
+	var rejection *types.FileRejection
+	if errors.As(err, &rejection) {
+		http.Error(w, fmt.Sprintf("The file is not accepted by the group: %s", rejection.Reason), http.StatusBadRequest)
+		return
+	} else if status.Code(err) == codes.FailedPrecondition {
+		http.Error(w, "The timesheet of the request is already approved", http.StatusConflict)
+		return
+	} else if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not save timesheet: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	if !h.archiveEmail(w, r, unassigned, email) {
+		return
+	}
+
+	http.Redirect(w, r, "/auth/inbox", http.StatusSeeOther)
+}
+
+// DiscardEmail removes an email from the inbox without saving anything.
+func (h *InboxHandler) DiscardEmail(w http.ResponseWriter, r *http.Request) {
+	_, unassigned, email, _, ok := h.getEmail(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.archiveEmail(w, r, unassigned, email) {
+		return
+	}
+
+	http.Redirect(w, r, "/auth/inbox", http.StatusSeeOther)
+}
+
+// ReplyToEmail replies to the sender of an email, e.g. to ask for the missing timesheet. The email stays in the inbox.
+func (h *InboxHandler) ReplyToEmail(w http.ResponseWriter, r *http.Request) {
+	group, _, email, _, ok := h.getEmail(w, r)
+	if !ok {
+		return
+	}
+
+	message := strings.TrimSpace(r.FormValue("message"))
+	if message == "" {
+		http.Error(w, "A message is required", http.StatusBadRequest)
+		return
+	}
+
+	if email.From == "" {
+		http.Error(w, "The email has no sender to reply to", http.StatusBadRequest)
+		return
+	}
+
+	err := h.emailService.SendReplyEmail(group, email, message)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not send reply email: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, "/auth/inbox", http.StatusSeeOther)
+}
+
+// getContractors gets the logged owner's group with its contractors and adds the group info to the user info, the group is nil if the owner has none.
+func (h *InboxHandler) getContractors(w http.ResponseWriter, r *http.Request, userInfo *types.LoggedUserInfo) (*types.Group, []*types.Contractor, bool) {
+	ownerID, err := h.authorizationService.GetLoggedOwnerID(r)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get logged owner: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return nil, nil, false
+	}
+
+	owner, err := h.ownersDB.GetOwnerByID(ownerID)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			http.Redirect(w, r, "/auth/owners/add", http.StatusSeeOther)
+			return nil, nil, false
+		}
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get owner: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return nil, nil, false
+	}
+
+	// An owner without a group has no contractors, so sees no emails
+	if owner.GroupID == "" {
+		return nil, nil, true
+	}
+
+	group, err := h.authorizationService.GetOwnedGroup(r, owner.GroupID)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get group: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return nil, nil, false
+	}
+
+	// Add the groupInfo to the userInfo
+	userInfo.GroupID = group.ID
+	userInfo.GroupName = group.Name
+
+	contractors, err := h.contractorsDB.GetContractors(group.ID)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get contractors: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return nil, nil, false
+	}
+
+	return group, contractors, true
+}
+
+// getEmail gets the unassigned email of the ID in the path and the email it was triaged from, with the owner's group and its contractors,
+// responding with an error if the owner cannot see it. An email no longer in the mailbox is not found, and its triage is deleted.
+func (h *InboxHandler) getEmail(w http.ResponseWriter, r *http.Request) (*types.Group, *types.UnassignedEmail, *types.InboundEmail, []*types.Contractor, bool) {
+	id := mux.Vars(r)["ID"]
+	if id == "" {
+		http.Error(w, "ID is required", http.StatusBadRequest)
+		return nil, nil, nil, nil, false
+	}
+
+	userInfo, err := h.authService.CheckUser(r)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not check user: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return nil, nil, nil, nil, false
+	}
+
+	group, contractors, ok := h.getContractors(w, r, userInfo)
+	if !ok {
+		return nil, nil, nil, nil, false
+	}
+
+	// The emails of other groups are not found
+	unassigned, err := h.unassignedEmailsDB.GetUnassignedEmail(id)
+	if status.Code(err) == codes.NotFound || (err == nil && (group == nil || unassigned.GroupID != group.ID)) {
+		http.NotFound(w, r)
+		return nil, nil, nil, nil, false
+	} else if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get unassigned email: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return nil, nil, nil, nil, false
+	}
+
+	email, err := h.emailService.GetUnassignedEmail(unassigned)
+	if status.Code(err) == codes.NotFound {
+		err = h.unassignedEmailsDB.DeleteUnassignedEmail(unassigned.ID)
+		if err != nil {
+			h.errorReporterService.ReportError(w, r, fmt.Errorf("could not delete unassigned email: %w", err))
+			http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+			return nil, nil, nil, nil, false
+		}
+		http.NotFound(w, r)
+		return nil, nil, nil, nil, false
+	} else if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get email of unassigned email: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return nil, nil, nil, nil, false
+	}
+
+	return group, unassigned, email, contractors, true
+}
+
+// archiveEmail removes an email from the unassigned mailbox and then its triage, responding with an error if either fails.
+func (h *InboxHandler) archiveEmail(w http.ResponseWriter, r *http.Request, unassigned *types.UnassignedEmail, email *types.InboundEmail) bool {
+	err := h.emailService.ArchiveUnassignedEmail(email.UID)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not archive email: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return false
+	}
+
+	err = h.unassignedEmailsDB.DeleteUnassignedEmail(unassigned.ID)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not delete unassigned email: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return false
+	}
+
+	return true
+}
+
+// findContractor returns the contractor with the given ID, nil if none.
+func findContractor(contractors []*types.Contractor, id string) *types.Contractor {
+	i := slices.IndexFunc(contractors, func(contractor *types.Contractor) bool {
+		return contractor.ID == id
+	})
+	if i < 0 {
+		return nil
+	}
+
+	return contractors[i]
+}
@@ -0,0 +1,486 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// withInbox puts emails in the unassigned mailbox: 1 of contractor1 with a timesheet, 2 of contractor2 of owner2, and 3 of an unknown sender.
+// The inbox watcher saved the triage of the first two as email1 and email2, the third is attributed to no group.
+func withInbox(ts *testServer) {
+	attachment := types.Attachment{Filename: "january.csv", Content: []byte("Date,Hours\n2025-01-06,8\n")}
+	for uid, from := range map[uint32]string{1: "contractor1@example.com", 2: "contractor2@example.com", 3: "someone@example.com"} {
+		messageID := fmt.Sprintf("%d@example.com", uid)
+		ts.email.unassigned[uid] = &types.InboundEmail{UID: uid, MessageID: messageID, From: from, Subject: "Timesheet", Attachments: []types.Attachment{attachment}}
+	}
+
+	for _, n := range []int{1, 2} {
+		ts.db.unassignedEmails[fmt.Sprintf("email%d", n)] = &types.UnassignedEmail{
+			ID:           fmt.Sprintf("email%d", n),
+			MessageID:    fmt.Sprintf("%d@example.com", n),
+			From:         fmt.Sprintf("contractor%d@example.com", n),
+			Subject:      "Timesheet",
+			Attachments:  []string{attachment.Filename},
+			Reason:       constants.WrongPeriod,
+			GroupID:      fmt.Sprintf("group%d", n),
+			ContractorID: fmt.Sprintf("contractor%d", n),
+		}
+	}
+}
+
+// checkInbox checks the UIDs of the emails left in the unassigned mailbox, and that the triage of the first two is left with them.
+func checkInbox(want ...uint32) func(*testing.T, *testServer) {
+	return func(t *testing.T, ts *testServer) {
+		var uids []uint32
+		for uid := range ts.email.unassigned {
+			uids = append(uids, uid)
+		}
+		slices.Sort(uids)
+
+		if !slices.Equal(uids, want) {
+			t.Errorf("unassigned emails = %v, want %v", uids, want)
+		}
+
+		for _, uid := range []uint32{1, 2} {
+			_, triaged := ts.db.unassignedEmails[fmt.Sprintf("email%d", uid)]
+			if triaged != slices.Contains(want, uid) {
+				t.Errorf("triage of email %d kept = %v, want %v", uid, triaged, slices.Contains(want, uid))
+			}
+		}
+	}
+}
+
+func TestInboxHandlerGetInbox(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name:         "inbox",
+			method:       "GET",
+			target:       "/auth/inbox",
+			setup:        withInbox,
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateInboxName,
+			check: func(t *testing.T, ts *testServer) {
+				view := ts.templates.data.(*inboxView)
+
+				// The emails of owner2's contractor and of the unknown sender are not shown
+				if len(view.Emails) != 1 || view.Emails[0].ID != "email1" || view.Emails[0].Contractor == nil || view.Emails[0].Contractor.ID != "contractor1" {
+					t.Errorf("emails = %+v, want only email1 of contractor1", view.Emails)
+				}
+				if len(view.Contractors) != 1 || view.Contractors[0].ID != "contractor1" || len(view.Requests["contractor1"]) != 1 {
+					t.Errorf("contractors = %+v, requests = %+v, want contractor1 with its request", view.Contractors, view.Requests)
+				}
+				if ts.templates.userInfo.GroupID != "group1" {
+					t.Errorf("GroupID = %q, want group1", ts.templates.userInfo.GroupID)
+				}
+			},
+		},
+		{
+			name:         "inbox of an owner without a group",
+			method:       "GET",
+			target:       "/auth/inbox",
+			setup:        func(ts *testServer) { withInbox(ts); ts.db.owners["owner1"].GroupID = "" },
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateInboxName,
+			check: func(t *testing.T, ts *testServer) {
+				if view := ts.templates.data.(*inboxView); len(view.Emails) != 0 {
+					t.Errorf("emails = %+v, want none", view.Emails)
+				}
+			},
+		},
+		{
+			name:         "inbox of a user without an owner",
+			method:       "GET",
+			target:       "/auth/inbox",
+			setup:        func(ts *testServer) { delete(ts.db.owners, "owner1") },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/owners/add",
+		},
+		{
+			name:         "inbox without the user",
+			method:       "GET",
+			target:       "/auth/inbox",
+			setup:        func(ts *testServer) { ts.auth.fail("CheckUser", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:   "inbox of the owner of the session",
+			method: "GET",
+			target: "/auth/inbox",
+			setup: func(ts *testServer) {
+				withInbox(ts)
+				// The email of the user changed to the one of another owner, the session still tells who is logged
+				ts.auth.userInfo.Email = "owner2@example.com"
+			},
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateInboxName,
+			check: func(t *testing.T, ts *testServer) {
+				if ts.templates.userInfo.GroupID != "group1" {
+					t.Errorf("GroupID = %q, want group1", ts.templates.userInfo.GroupID)
+				}
+			},
+		},
+		{
+			name:         "inbox without a logged owner",
+			method:       "GET",
+			target:       "/auth/inbox",
+			setup:        func(ts *testServer) { delete(ts.sessions.values, constants.SesstionOwnerIdField) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "owner not read",
+			method:       "GET",
+			target:       "/auth/inbox",
+			setup:        func(ts *testServer) { ts.db.fail("GetOwnerByID", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "contractors not read",
+			method:       "GET",
+			target:       "/auth/inbox",
+			setup:        func(ts *testServer) { ts.db.fail("GetContractors", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "requests not listed",
+			method:       "GET",
+			target:       "/auth/inbox",
+			setup:        func(ts *testServer) { ts.db.fail("ListTimesheetRequests", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:   "inbox without reading the mailbox",
+			method: "GET",
+			target: "/auth/inbox",
+			setup: func(ts *testServer) {
+				withInbox(ts)
+				ts.email.fail("GetUnassignedEmails", errFake)
+				ts.email.fail("GetUnassignedEmail", errFake)
+			},
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateInboxName,
+		},
+		{
+			name:         "unassigned emails not listed",
+			method:       "GET",
+			target:       "/auth/inbox",
+			setup:        func(ts *testServer) { withInbox(ts); ts.db.fail("ListUnassignedEmails", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "inbox page not parsed",
+			method:       "GET",
+			target:       "/auth/inbox",
+			setup:        func(ts *testServer) { ts.templates.fail("ParseTemplate", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+	})
+}
+
+func TestInboxHandlerAssignEmail(t *testing.T) {
+	assign := url.Values{"request": {"contractor1/" + testRequestID}, "attachment": {"0"}}
+
+	runRouteTests(t, []routeTest{
+		{
+			name:         "assign",
+			method:       "POST",
+			target:       "/auth/inbox/email1/assign",
+			form:         assign,
+			setup:        withInbox,
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/inbox",
+			check: func(t *testing.T, ts *testServer) {
+				if want := []string{"contractor1 " + testRequestID + " january.csv"}; !slices.Equal(ts.ingestion.saved, want) {
+					t.Errorf("saved = %v, want %v", ts.ingestion.saved, want)
+				}
+				checkInbox(2, 3)(t, ts)
+			},
+		},
+		{
+			name:        "assign to another owner's contractor",
+			method:      "POST",
+			target:      "/auth/inbox/email1/assign",
+			form:        url.Values{"request": {"contractor2/" + testRequestID}, "attachment": {"0"}},
+			setup:       withInbox,
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "A contractor of the group is required",
+			check:       checkInbox(1, 2, 3),
+		},
+		{
+			name:        "assign to an unknown request",
+			method:      "POST",
+			target:      "/auth/inbox/email1/assign",
+			form:        url.Values{"request": {"contractor1/2025-02-01_2025-02-28"}, "attachment": {"0"}},
+			setup:       withInbox,
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "A request of the contractor is required",
+		},
+		{
+			name:        "assign an unknown attachment",
+			method:      "POST",
+			target:      "/auth/inbox/email1/assign",
+			form:        url.Values{"request": {"contractor1/" + testRequestID}, "attachment": {"1"}},
+			setup:       withInbox,
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "An attachment of the email is required",
+		},
+		{
+			name:   "assign a rejected file",
+			method: "POST",
+			target: "/auth/inbox/email1/assign",
+			form:   assign,
+			setup: func(ts *testServer) {
+				withInbox(ts)
+				ts.ingestion.fail("SaveTimesheet", &types.FileRejection{Reason: constants.ArchiveFile})
+			},
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "The file is not accepted by the group",
+			check:       checkInbox(1, 2, 3),
+		},
+		{
+			name:   "assign over an approved timesheet",
+			method: "POST",
+			target: "/auth/inbox/email1/assign",
+			form:   assign,
+			setup: func(ts *testServer) {
+				withInbox(ts)
+				ts.ingestion.fail("SaveTimesheet", status.Error(codes.FailedPrecondition, "timesheet approved"))
+			},
+			wantStatus:  http.StatusConflict,
+			wantMessage: "The timesheet of the request is already approved",
+		},
+		{
+			name:        "assign an email of another owner's contractor",
+			method:      "POST",
+			target:      "/auth/inbox/email2/assign",
+			form:        assign,
+			setup:       withInbox,
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+			check:       checkInbox(1, 2, 3),
+		},
+		{
+			name:        "assign an email of an unknown sender",
+			method:      "POST",
+			target:      "/auth/inbox/email3/assign",
+			form:        assign,
+			setup:       withInbox,
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+			check:       checkInbox(1, 2, 3),
+		},
+		{
+			name:        "assign an unknown email",
+			method:      "POST",
+			target:      "/auth/inbox/email4/assign",
+			form:        assign,
+			setup:       withInbox,
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+		},
+		{
+			name:         "assign without the user",
+			method:       "POST",
+			target:       "/auth/inbox/email1/assign",
+			form:         assign,
+			setup:        func(ts *testServer) { withInbox(ts); ts.auth.fail("CheckUser", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "email not read",
+			method:       "POST",
+			target:       "/auth/inbox/email1/assign",
+			form:         assign,
+			setup:        func(ts *testServer) { withInbox(ts); ts.email.fail("GetUnassignedEmail", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:        "assign an email no longer in the mailbox",
+			method:      "POST",
+			target:      "/auth/inbox/email1/assign",
+			form:        assign,
+			setup:       func(ts *testServer) { withInbox(ts); delete(ts.email.unassigned, 1) },
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+			check:       checkInbox(2, 3),
+		},
+		{
+			name:         "unassigned email not read",
+			method:       "POST",
+			target:       "/auth/inbox/email1/assign",
+			form:         assign,
+			setup:        func(ts *testServer) { withInbox(ts); ts.db.fail("GetUnassignedEmail", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "request not read",
+			method:       "POST",
+			target:       "/auth/inbox/email1/assign",
+			form:         assign,
+			setup:        func(ts *testServer) { withInbox(ts); ts.db.fail("GetTimesheetRequest", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "timesheet not saved",
+			method:       "POST",
+			target:       "/auth/inbox/email1/assign",
+			form:         assign,
+			setup:        func(ts *testServer) { withInbox(ts); ts.ingestion.fail("SaveTimesheet", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+			check:        checkInbox(1, 2, 3),
+		},
+		{
+			name:         "assigned email not archived",
+			method:       "POST",
+			target:       "/auth/inbox/email1/assign",
+			form:         assign,
+			setup:        func(ts *testServer) { withInbox(ts); ts.email.fail("ArchiveUnassignedEmail", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+			check:        checkInbox(1, 2, 3),
+		},
+		{
+			name:         "triage of the assigned email not deleted",
+			method:       "POST",
+			target:       "/auth/inbox/email1/assign",
+			form:         assign,
+			setup:        func(ts *testServer) { withInbox(ts); ts.db.fail("DeleteUnassignedEmail", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+	})
+}
+
+func TestInboxHandlerDiscardAndReply(t *testing.T) {
+	reply := url.Values{"message": {" Please send the timesheet of January. "}}
+
+	runRouteTests(t, []routeTest{
+		{
+			name:         "discard",
+			method:       "POST",
+			target:       "/auth/inbox/email1/discard",
+			setup:        withInbox,
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/inbox",
+			check: func(t *testing.T, ts *testServer) {
+				checkInbox(2, 3)(t, ts)
+				if len(ts.ingestion.saved) != 0 {
+					t.Errorf("saved = %v, want nothing", ts.ingestion.saved)
+				}
+			},
+		},
+		{
+			name:        "discard an email of another owner's contractor",
+			method:      "POST",
+			target:      "/auth/inbox/email2/discard",
+			setup:       withInbox,
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+			check:       checkInbox(1, 2, 3),
+		},
+		{
+			name:        "discard an email of an unknown sender",
+			method:      "POST",
+			target:      "/auth/inbox/email3/discard",
+			setup:       withInbox,
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+			check:       checkInbox(1, 2, 3),
+		},
+		{
+			name:         "discarded email not archived",
+			method:       "POST",
+			target:       "/auth/inbox/email1/discard",
+			setup:        func(ts *testServer) { withInbox(ts); ts.email.fail("ArchiveUnassignedEmail", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "reply",
+			method:       "POST",
+			target:       "/auth/inbox/email1/reply",
+			form:         reply,
+			setup:        withInbox,
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/inbox",
+			check: func(t *testing.T, ts *testServer) {
+				checkSent(constants.EmailTemplateReplyName+" contractor1@example.com")(t, ts)
+				checkInbox(1, 2, 3)(t, ts)
+			},
+		},
+		{
+			name:        "reply without a message",
+			method:      "POST",
+			target:      "/auth/inbox/email1/reply",
+			form:        url.Values{"message": {" "}},
+			setup:       withInbox,
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "A message is required",
+			check:       checkSent(),
+		},
+		{
+			name:        "reply to an email without a sender",
+			method:      "POST",
+			target:      "/auth/inbox/email1/reply",
+			form:        reply,
+			setup:       func(ts *testServer) { withInbox(ts); ts.email.unassigned[1].From = "" },
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "The email has no sender to reply to",
+		},
+		{
+			name:        "reply to an email of another owner's contractor",
+			method:      "POST",
+			target:      "/auth/inbox/email2/reply",
+			form:        reply,
+			setup:       withInbox,
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+			check:       checkSent(),
+		},
+		{
+			name:         "reply not sent",
+			method:       "POST",
+			target:       "/auth/inbox/email1/reply",
+			form:         reply,
+			setup:        func(ts *testServer) { withInbox(ts); ts.email.fail("SendReplyEmail", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+	})
+}
@@ -3,24 +3,22 @@ package handlers
 import (
 	"fmt"
 	"net/http"
-	"strconv"
-	"time"
 
-	"job_sender/core"
+	"job_sender/interfaces"
 	constants "job_sender/utils/constants"
 
 	"github.com/gorilla/mux"
 )
 
 type LoginHandler struct {
-	authService           *core.AuthService
-	firebaseService       *core.FirebaseService
-	templateService       *core.TemplateService
-	sessionManagerService *core.SessionManagerService
-	errorReporterService  *core.ErrorReporterService
+	authService           interfaces.IAuthService
+	firebaseService       interfaces.IFirebaseService
+	templateService       interfaces.ITemplateService
+	sessionManagerService interfaces.ISessionManagerService
+	errorReporterService  interfaces.IErrorReporterService
 }
 
-func NewLoginHandler(authService *core.AuthService, firebaseService *core.FirebaseService, templateService *core.TemplateService, sessionManagerService *core.SessionManagerService, errorReporterService *core.ErrorReporterService) *LoginHandler {
+func NewLoginHandler(authService interfaces.IAuthService, firebaseService interfaces.IFirebaseService, templateService interfaces.ITemplateService, sessionManagerService interfaces.ISessionManagerService, errorReporterService interfaces.IErrorReporterService) *LoginHandler {
 	return &LoginHandler{
 		authService:           authService,
 		firebaseService:       firebaseService,
@@ -58,6 +56,7 @@ func (h *LoginHandler) showLogin(w http.ResponseWriter, r *http.Request) {
 func (h *LoginHandler) login(w http.ResponseWriter, r *http.Request) {
 	email := r.FormValue("email")
 	password := r.FormValue("password")
+	rememberMe := r.FormValue("remember_me") == "on"
 
 	if email == "" || password == "" {
 		h.showError(w, r, "Email or password missing")
@@ -77,17 +76,6 @@ func (h *LoginHandler) login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Extract the expires in time
-	expiresIn, err := strconv.ParseInt(responseBody.ExpiresIn, 10, 64)
-	if err != nil {
-		h.showError(w, r, "Could not parse expires in time")
-		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not parse expires in time: %w", err))
-		return
-	}
-
-	// Convert expiresIn to a timestamp
-	expirationTimestamp := time.Now().Add(time.Second * time.Duration(expiresIn))
-
 	// Check if the user is verified
 	isVerified, err := h.firebaseService.CheckIsUserVerified(email)
 	if err != nil {
@@ -96,19 +84,11 @@ func (h *LoginHandler) login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create the data
-	data := map[string]interface{}{
-		constants.SessionTokenField:     responseBody.IdToken,
-		constants.SessionEmailField:     responseBody.Email,
-		constants.SessionIsVerfiedField: isVerified,
-		constants.SesstionOwnerIdField:  responseBody.LocalId,
-	}
-
-	// Create the session
-	_, err = h.sessionManagerService.CreateSession(w, r, constants.UserSessionName, expirationTimestamp, data)
+	// Create the session, a remembered one outlives the browser session
+	err = h.authService.StartSession(w, r, responseBody, isVerified, rememberMe)
 	if err != nil {
 		h.showError(w, r, "Could not create session")
-		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not create session: %w", err))
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not start session: %w", err))
 		return
 	}
 
@@ -117,8 +97,8 @@ func (h *LoginHandler) login(w http.ResponseWriter, r *http.Request) {
 
 // logout logs out the user.
 func (h *LoginHandler) logout(w http.ResponseWriter, r *http.Request) {
-	// Delete the user session
-	err := h.sessionManagerService.DeleteSession(w, r, constants.UserSessionName)
+	// Delete the user session and its login
+	err := h.authService.EndSession(w, r)
 	if err != nil {
 		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not delete session: %w", err))
 		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+
+	constants "job_sender/utils/constants"
+)
+
+func TestLoginHandler(t *testing.T) {
+	login := url.Values{"email": {"owner1@example.com"}, "password": {"password1"}, "remember_me": {"on"}}
+
+	runRouteTests(t, []routeTest{
+		{
+			name:         "login page",
+			method:       "GET",
+			target:       "/login",
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateLoginName,
+		},
+		{
+			name:         "login page not parsed",
+			method:       "GET",
+			target:       "/login",
+			setup:        func(ts *testServer) { ts.templates.fail("ParseTemplate", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "login page not executed",
+			method:       "GET",
+			target:       "/login",
+			setup:        func(ts *testServer) { ts.templates.fail("ExecuteTemplate", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "login",
+			method:       "POST",
+			target:       "/login",
+			form:         login,
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/owners/owner1",
+			check: func(t *testing.T, ts *testServer) {
+				if ts.auth.sessions != 1 {
+					t.Errorf("sessions started = %d, want 1", ts.auth.sessions)
+				}
+			},
+		},
+		{
+			name:         "missing password",
+			method:       "POST",
+			target:       "/login",
+			form:         url.Values{"email": {"owner1@example.com"}},
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateLoginName,
+			wantMessage:  "Email or password missing",
+		},
+		{
+			name:         "wrong password",
+			method:       "POST",
+			target:       "/login",
+			form:         url.Values{"email": {"owner1@example.com"}, "password": {"wrong"}},
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateLoginName,
+			wantMessage:  "Invalid email or password",
+		},
+		{
+			name:         "login fails",
+			method:       "POST",
+			target:       "/login",
+			form:         login,
+			setup:        func(ts *testServer) { ts.auth.fail("Login", errFake) },
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateLoginName,
+			wantMessage:  "Invalid email or password",
+			wantReported: true,
+		},
+		{
+			name:         "verification not checked",
+			method:       "POST",
+			target:       "/login",
+			form:         login,
+			setup:        func(ts *testServer) { ts.firebase.fail("CheckIsUserVerified", errFake) },
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateLoginName,
+			wantMessage:  "Could not check if user is verified",
+			wantReported: true,
+		},
+		{
+			name:         "session not started",
+			method:       "POST",
+			target:       "/login",
+			form:         login,
+			setup:        func(ts *testServer) { ts.auth.fail("StartSession", errFake) },
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateLoginName,
+			wantMessage:  "Could not create session",
+			wantReported: true,
+		},
+		{
+			name:         "logout",
+			method:       "POST",
+			target:       "/logout",
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/main",
+			check: func(t *testing.T, ts *testServer) {
+				if ts.auth.ended != 1 {
+					t.Errorf("sessions ended = %d, want 1", ts.auth.ended)
+				}
+				if !slices.Contains(ts.sessions.deleted, constants.TimesheetAggegationSessionName) {
+					t.Errorf("deleted sessions = %v, want the timesheet aggregation session", ts.sessions.deleted)
+				}
+			},
+		},
+		{
+			name:         "session not ended",
+			method:       "POST",
+			target:       "/logout",
+			setup:        func(ts *testServer) { ts.auth.fail("EndSession", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "aggregation session not deleted",
+			method:       "POST",
+			target:       "/logout",
+			setup:        func(ts *testServer) { ts.sessions.fail("DeleteSession", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+	})
+}
@@ -2,9 +2,10 @@ package handlers
 
 import (
 	"fmt"
+	"io"
 	"net/http"
 
-	"job_sender/core"
+	"job_sender/interfaces"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
@@ -13,18 +14,22 @@ import (
 )
 
 type MainHandler struct {
-	authService          *core.AuthService
-	errorReporterService *core.ErrorReporterService
+	authService          interfaces.IAuthService
+	errorReporterService interfaces.IErrorReporterService
 
-	ownersDB *core.OwnerDatabaseService
+	ownersDB interfaces.IOwnerDatabaseService
+
+	logWriter io.Writer
 }
 
-func NewMainHandler(authService *core.AuthService, errorReporterService *core.ErrorReporterService, ownersDB *core.OwnerDatabaseService) *MainHandler {
+func NewMainHandler(authService interfaces.IAuthService, errorReporterService interfaces.IErrorReporterService, ownersDB interfaces.IOwnerDatabaseService, logWriter io.Writer) *MainHandler {
 	return &MainHandler{
 		authService:          authService,
 		errorReporterService: errorReporterService,
 
 		ownersDB: ownersDB,
+
+		logWriter: logWriter,
 	}
 }
 
@@ -37,7 +42,7 @@ func (h *MainHandler) CreateRouter() *mux.Router {
 
 	// Delegate all of the HTTP routing and serving to the gorilla/mux router.
 	// Log all requests using the standard Apache format.
-	http.Handle("/", handlers.CombinedLoggingHandler(h.errorReporterService.LogWriter, r))
+	http.Handle("/", handlers.CombinedLoggingHandler(h.logWriter, r))
 	return r
 }
 
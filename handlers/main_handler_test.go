@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMainHandler(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name:         "owner",
+			method:       "GET",
+			target:       "/main",
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/owners/owner1",
+		},
+		{
+			name:         "user without an owner",
+			method:       "GET",
+			target:       "/main",
+			setup:        func(ts *testServer) { delete(ts.db.owners, "owner1") },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/owners/add",
+		},
+		{
+			name:         "logged out",
+			method:       "GET",
+			target:       "/main",
+			setup:        func(ts *testServer) { ts.auth.userInfo.IsLoggedIn = false },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/login",
+		},
+		{
+			name:         "user not checked",
+			method:       "GET",
+			target:       "/main",
+			setup:        func(ts *testServer) { ts.auth.fail("CheckUser", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "owner not read",
+			method:       "GET",
+			target:       "/main",
+			setup:        func(ts *testServer) { ts.db.fail("GetOwnerByEmail", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+	})
+}
@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"net/http"
 
-	"job_sender/core"
+	"job_sender/interfaces"
 	"job_sender/types"
 	constants "job_sender/utils/constants"
 
@@ -14,18 +14,22 @@ import (
 )
 
 type OwnersHandler struct {
-	authService           *core.AuthService
-	sessionManagerService *core.SessionManagerService
-	templateService       *core.TemplateService
-	errorReporterService  *core.ErrorReporterService
-
-	ownersDB *core.OwnerDatabaseService
+	authService           interfaces.IAuthService
+	authorizationService  interfaces.IAuthorizationService
+	accountService        interfaces.IAccountService
+	sessionManagerService interfaces.ISessionManagerService
+	templateService       interfaces.ITemplateService
+	errorReporterService  interfaces.IErrorReporterService
+
+	ownersDB interfaces.IOwnerDatabaseService
 }
 
 // NewOwnersHandler creates a new OwnersHandler.
-func NewOwnersHandler(authService *core.AuthService, sessionManagerService *core.SessionManagerService, templateService *core.TemplateService, errorReporterService *core.ErrorReporterService, ownersDB *core.OwnerDatabaseService) *OwnersHandler {
+func NewOwnersHandler(authService interfaces.IAuthService, authorizationService interfaces.IAuthorizationService, accountService interfaces.IAccountService, sessionManagerService interfaces.ISessionManagerService, templateService interfaces.ITemplateService, errorReporterService interfaces.IErrorReporterService, ownersDB interfaces.IOwnerDatabaseService) *OwnersHandler {
 	return &OwnersHandler{
 		authService:           authService,
+		authorizationService:  authorizationService,
+		accountService:        accountService,
 		sessionManagerService: sessionManagerService,
 		templateService:       templateService,
 		errorReporterService:  errorReporterService,
@@ -83,6 +87,17 @@ func (h *OwnersHandler) GetOwner(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Owners may only see and change themselves
+	err := h.authorizationService.AuthorizeOwner(r, ownerID)
+	if status.Code(err) == codes.NotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not authorize owner: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
 	owner, err := h.ownersDB.GetOwnerByID(ownerID)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
@@ -110,6 +125,17 @@ func (h *OwnersHandler) EditOwner(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Owners may only see and change themselves
+	err := h.authorizationService.AuthorizeOwner(r, ownerID)
+	if status.Code(err) == codes.NotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not authorize owner: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
 	owner, err := h.ownersDB.GetOwnerByID(ownerID)
 	if err != nil {
 		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get owner: %w", err))
@@ -189,6 +215,17 @@ func (h *OwnersHandler) UpdateOwner(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Owners may only see and change themselves
+	err := h.authorizationService.AuthorizeOwner(r, ownerID)
+	if status.Code(err) == codes.NotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not authorize owner: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
 	// Get the owner from the form.
 	owner, err := ownerFromForm(r)
 	if err != nil {
@@ -209,7 +246,7 @@ func (h *OwnersHandler) UpdateOwner(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/auth/contractors", http.StatusSeeOther) // TODO: group id is needed
 }
 
-// DeleteOwner deletes an owner.
+// DeleteOwner deletes an owner with its account, logs it out everywhere and sends it to the login page.
 func (h *OwnersHandler) DeleteOwner(w http.ResponseWriter, r *http.Request) {
 	ownerID := mux.Vars(r)["ID"]
 	if ownerID == "" {
@@ -217,12 +254,26 @@ func (h *OwnersHandler) DeleteOwner(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.ownersDB.DeleteOwner(ownerID)
+	// Owners may only see and change themselves
+	err := h.authorizationService.AuthorizeOwner(r, ownerID)
+	if status.Code(err) == codes.NotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not authorize owner: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	// The account is deleted with the owner, and every session of it is logged out, this one included
+	err = h.accountService.DeleteAccount(ownerID)
 	if err != nil {
-		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not delete owner: %w", err))
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not delete account: %w", err))
 		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
 		return
 	}
+
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
 }
 
 func ownerFromForm(r *http.Request) (*types.Owner, error) {
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+
+	constants "job_sender/utils/constants"
+)
+
+func TestOwnersHandler(t *testing.T) {
+	owner := url.Values{"name": {"Anna"}, "surname": {"Nowak"}, "email": {"owner1@example.com"}}
+
+	runRouteTests(t, []routeTest{
+		{
+			name:         "add owner page",
+			method:       "GET",
+			target:       "/auth/owners/add",
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateOwnerAddName,
+			check: func(t *testing.T, ts *testServer) {
+				if email := ts.pageData(t)["Email"]; email != "owner1@example.com" {
+					t.Errorf("Email = %v, want owner1@example.com", email)
+				}
+			},
+		},
+		{
+			name:         "add owner page without the user",
+			method:       "GET",
+			target:       "/auth/owners/add",
+			setup:        func(ts *testServer) { ts.auth.fail("CheckUser", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "add owner page not parsed",
+			method:       "GET",
+			target:       "/auth/owners/add",
+			setup:        func(ts *testServer) { ts.templates.fail("ParseTemplate", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "owner",
+			method:       "GET",
+			target:       "/auth/owners/owner1",
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/groups/group1",
+		},
+		{
+			name:         "owner without a group",
+			method:       "GET",
+			target:       "/auth/owners/owner1",
+			setup:        func(ts *testServer) { ts.db.owners["owner1"].GroupID = "" },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/groups/add",
+		},
+		{
+			name:         "owner not added yet",
+			method:       "GET",
+			target:       "/auth/owners/owner1",
+			setup:        func(ts *testServer) { delete(ts.db.owners, "owner1") },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/owners/add",
+		},
+		{
+			name:        "another owner",
+			method:      "GET",
+			target:      "/auth/owners/owner2",
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+		},
+		{
+			name:         "owner not authorized",
+			method:       "GET",
+			target:       "/auth/owners/owner1",
+			setup:        func(ts *testServer) { delete(ts.sessions.values, constants.SesstionOwnerIdField) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "owner not read",
+			method:       "GET",
+			target:       "/auth/owners/owner1",
+			setup:        func(ts *testServer) { ts.db.fail("GetOwnerByID", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "edit owner page",
+			method:       "GET",
+			target:       "/auth/owners/owner1/edit",
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateOwnerEditName,
+		},
+		{
+			name:        "edit page of another owner",
+			method:      "GET",
+			target:      "/auth/owners/owner2/edit",
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+		},
+		{
+			name:         "edit owner page not authorized",
+			method:       "GET",
+			target:       "/auth/owners/owner1/edit",
+			setup:        func(ts *testServer) { delete(ts.sessions.values, constants.SesstionOwnerIdField) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "edit owner page without the owner",
+			method:       "GET",
+			target:       "/auth/owners/owner1/edit",
+			setup:        func(ts *testServer) { ts.db.fail("GetOwnerByID", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "edit owner page without the user",
+			method:       "GET",
+			target:       "/auth/owners/owner1/edit",
+			setup:        func(ts *testServer) { ts.auth.fail("CheckUser", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "edit owner page not parsed",
+			method:       "GET",
+			target:       "/auth/owners/owner1/edit",
+			setup:        func(ts *testServer) { ts.templates.fail("ParseTemplate", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "add owner",
+			method:       "POST",
+			target:       "/auth/owners",
+			form:         owner,
+			setup:        func(ts *testServer) { delete(ts.db.owners, "owner1") },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/groups/add",
+			check: func(t *testing.T, ts *testServer) {
+				added := ts.db.owners["owner1"]
+				if added == nil || added.Name != "Anna" || added.Surname != "Nowak" {
+					t.Errorf("owner1 = %+v, want Anna Nowak", added)
+				}
+			},
+		},
+		{
+			name:         "add owner without the session",
+			method:       "POST",
+			target:       "/auth/owners",
+			form:         owner,
+			setup:        func(ts *testServer) { ts.sessions.fail("GetElement", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:        "add owner without the owner ID",
+			method:      "POST",
+			target:      "/auth/owners",
+			form:        owner,
+			setup:       func(ts *testServer) { delete(ts.sessions.values, constants.SesstionOwnerIdField) },
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "ownerID is required",
+		},
+		{
+			name:         "add owner with an invalid owner ID",
+			method:       "POST",
+			target:       "/auth/owners",
+			form:         owner,
+			setup:        func(ts *testServer) { ts.sessions.values[constants.SesstionOwnerIdField] = 1 },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "owner not added",
+			method:       "POST",
+			target:       "/auth/owners",
+			form:         owner,
+			setup:        func(ts *testServer) { ts.db.fail("AddOwner", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "update owner",
+			method:       "PUT",
+			target:       "/auth/owners/owner1",
+			form:         owner,
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/contractors",
+			check: func(t *testing.T, ts *testServer) {
+				if name := ts.db.owners["owner1"].Name; name != "Anna" {
+					t.Errorf("name of owner1 = %q, want Anna", name)
+				}
+			},
+		},
+		{
+			name:        "update another owner",
+			method:      "PUT",
+			target:      "/auth/owners/owner2",
+			form:        owner,
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+			check: func(t *testing.T, ts *testServer) {
+				if name := ts.db.owners["owner2"].Name; name != "Owner" {
+					t.Errorf("name of owner2 = %q, want it unchanged", name)
+				}
+			},
+		},
+		{
+			name:         "update owner not authorized",
+			method:       "PUT",
+			target:       "/auth/owners/owner1",
+			form:         owner,
+			setup:        func(ts *testServer) { delete(ts.sessions.values, constants.SesstionOwnerIdField) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "owner not updated",
+			method:       "PUT",
+			target:       "/auth/owners/owner1",
+			form:         owner,
+			setup:        func(ts *testServer) { ts.db.fail("UpdateOwner", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "delete owner",
+			method:       "DELETE",
+			target:       "/auth/owners/owner1",
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/login",
+			check: func(t *testing.T, ts *testServer) {
+				if !slices.Equal(ts.account.deletedAccounts, []string{"owner1"}) {
+					t.Errorf("deleted accounts = %v, want owner1", ts.account.deletedAccounts)
+				}
+			},
+		},
+		{
+			name:        "delete another owner",
+			method:      "DELETE",
+			target:      "/auth/owners/owner2",
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+			check: func(t *testing.T, ts *testServer) {
+				if len(ts.account.deletedAccounts) != 0 {
+					t.Errorf("deleted accounts = %v, want none", ts.account.deletedAccounts)
+				}
+			},
+		},
+		{
+			name:         "delete owner not authorized",
+			method:       "DELETE",
+			target:       "/auth/owners/owner1",
+			setup:        func(ts *testServer) { delete(ts.sessions.values, constants.SesstionOwnerIdField) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "account not deleted",
+			method:       "DELETE",
+			target:       "/auth/owners/owner1",
+			setup:        func(ts *testServer) { ts.account.fail("DeleteAccount", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+	})
+}
@@ -5,21 +5,21 @@ import (
 	"net/http"
 	"net/url"
 
-	"job_sender/core"
+	"job_sender/interfaces"
 	constants "job_sender/utils/constants"
 
 	"github.com/gorilla/mux"
 )
 
 type RegisterHandler struct {
-	firebaseService      *core.FirebaseService
-	authService          *core.AuthService
-	templateService      *core.TemplateService
-	emailService         *core.EmailService
-	errorReporterService *core.ErrorReporterService
+	firebaseService      interfaces.IFirebaseService
+	authService          interfaces.IAuthService
+	templateService      interfaces.ITemplateService
+	emailService         interfaces.IEmailService
+	errorReporterService interfaces.IErrorReporterService
 }
 
-func NewRegisterHandler(firebaseService *core.FirebaseService, authService *core.AuthService, templateService *core.TemplateService, emailService *core.EmailService, errorReporterService *core.ErrorReporterService) *RegisterHandler {
+func NewRegisterHandler(firebaseService interfaces.IFirebaseService, authService interfaces.IAuthService, templateService interfaces.ITemplateService, emailService interfaces.IEmailService, errorReporterService interfaces.IErrorReporterService) *RegisterHandler {
 	return &RegisterHandler{
 		firebaseService:      firebaseService,
 		authService:          authService,
@@ -148,15 +148,7 @@ func (h *RegisterHandler) register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Send verification email
-	ctx := r.Context()
-	client, err := h.firebaseService.Auth(ctx)
-	if err != nil {
-		h.showError(w, r, "Could not send verification email")
-		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get firebase auth client: %w", err))
-		return
-	}
-
-	link, err := client.EmailVerificationLink(ctx, email)
+	link, err := h.firebaseService.EmailVerificationLink(email)
 	if err != nil {
 		h.showError(w, r, "Could not send verification email")
 		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get email verification link: %w", err))
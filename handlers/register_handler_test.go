@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+
+	constants "job_sender/utils/constants"
+)
+
+func TestRegisterHandler(t *testing.T) {
+	register := url.Values{"email": {"new@example.com"}, "password": {"password1"}, "confirm_password": {"password1"}}
+
+	runRouteTests(t, []routeTest{
+		{
+			name:         "register page",
+			method:       "GET",
+			target:       "/register",
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateRegisterName,
+		},
+		{
+			name:         "register page without the user",
+			method:       "GET",
+			target:       "/register",
+			setup:        func(ts *testServer) { ts.auth.fail("CheckUser", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "register page not parsed",
+			method:       "GET",
+			target:       "/register",
+			setup:        func(ts *testServer) { ts.templates.fail("ParseTemplate", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "register page not executed",
+			method:       "GET",
+			target:       "/register",
+			setup:        func(ts *testServer) { ts.templates.fail("ExecuteTemplate", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "register",
+			method:       "POST",
+			target:       "/register",
+			form:         register,
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/register/confirm",
+			check: func(t *testing.T, ts *testServer) {
+				if !slices.Equal(ts.auth.registered, []string{"new@example.com"}) {
+					t.Errorf("registered = %v, want new@example.com", ts.auth.registered)
+				}
+				if !slices.Equal(ts.email.sent, []string{constants.EmailTemplateVerificationName + " new@example.com"}) {
+					t.Errorf("sent = %v, want the verification email", ts.email.sent)
+				}
+			},
+		},
+		{
+			name:         "missing email",
+			method:       "POST",
+			target:       "/register",
+			form:         url.Values{"password": {"password1"}, "confirm_password": {"password1"}},
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateRegisterName,
+			wantMessage:  "Email missing",
+		},
+		{
+			name:         "registered email",
+			method:       "POST",
+			target:       "/register",
+			form:         url.Values{"email": {"owner1@example.com"}, "password": {"password1"}, "confirm_password": {"password1"}},
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateRegisterName,
+			wantMessage:  "Email already registered",
+		},
+		{
+			name:         "user not checked",
+			method:       "POST",
+			target:       "/register",
+			form:         register,
+			setup:        func(ts *testServer) { ts.firebase.fail("CheckIfUserExists", errFake) },
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateRegisterName,
+			wantMessage:  "Could not register",
+			wantReported: true,
+		},
+		{
+			name:         "missing password",
+			method:       "POST",
+			target:       "/register",
+			form:         url.Values{"email": {"new@example.com"}, "password": {"password1"}},
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateRegisterName,
+			wantMessage:  "Password or confirm password missing",
+		},
+		{
+			name:         "passwords differ",
+			method:       "POST",
+			target:       "/register",
+			form:         url.Values{"email": {"new@example.com"}, "password": {"password1"}, "confirm_password": {"password2"}},
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateRegisterName,
+			wantMessage:  "Password and confirm password do not match",
+		},
+		{
+			name:         "registration fails",
+			method:       "POST",
+			target:       "/register",
+			form:         register,
+			setup:        func(ts *testServer) { ts.auth.fail("Register", errFake) },
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateRegisterName,
+			wantMessage:  "Could not register",
+			wantReported: true,
+		},
+		{
+			name:         "verification link not created",
+			method:       "POST",
+			target:       "/register",
+			form:         register,
+			setup:        func(ts *testServer) { ts.firebase.fail("EmailVerificationLink", errFake) },
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateRegisterName,
+			wantMessage:  "Could not send verification email",
+			wantReported: true,
+		},
+		{
+			name:         "verification email not sent",
+			method:       "POST",
+			target:       "/register",
+			form:         register,
+			setup:        func(ts *testServer) { ts.email.fail("SendVerificationEmail", errFake) },
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateRegisterName,
+			wantMessage:  "Could not send verification email",
+			wantReported: true,
+		},
+		{
+			name:         "confirmation without the cookie",
+			method:       "GET",
+			target:       "/register/confirm",
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+	})
+}
+
+func TestRegisterConfirmPage(t *testing.T) {
+	tests := []struct {
+		name         string
+		cookie       string
+		setup        func(*testServer)
+		wantStatus   int
+		wantTemplate string
+		wantEmail    string
+		wantReported bool
+	}{
+		{"confirmation", url.QueryEscape("new+1@example.com"), nil, http.StatusOK, constants.TemplateConfirmRegisterName, "new+1@example.com", false},
+		{"undecodable cookie", "%zz", nil, http.StatusSeeOther, "", "", true},
+		{"template not parsed", "new@example.com", func(ts *testServer) { ts.templates.fail("ParseTemplate", errFake) }, http.StatusSeeOther, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t)
+			if tt.setup != nil {
+				tt.setup(ts)
+			}
+
+			r, err := http.NewRequest("GET", "/register/confirm", nil)
+			if err != nil {
+				t.Fatalf("NewRequest: %v", err)
+			}
+			r.AddCookie(&http.Cookie{Name: "emailConfirmationAddress", Value: tt.cookie})
+
+			w := ts.serve(r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if ts.templates.name != tt.wantTemplate {
+				t.Errorf("template = %q, want %q", ts.templates.name, tt.wantTemplate)
+			}
+			if reported := len(ts.errorReporter.errs) > 0; reported != tt.wantReported {
+				t.Errorf("reported errors = %v, want reported %v", ts.errorReporter.errs, tt.wantReported)
+			}
+
+			if tt.wantEmail != "" {
+				data, ok := ts.templates.data.(map[string]string)
+				if !ok || data["Email"] != tt.wantEmail {
+					t.Errorf("page data = %v, want the email %s", ts.templates.data, tt.wantEmail)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,392 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"job_sender/core"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"github.com/gorilla/mux"
+)
+
+// testRequestID is the period of the requests of the test data.
+const testRequestID = "2025-01-01_2025-01-31"
+
+// testServer is the router of every handler, with fakes of the services but the authorization, logged in as owner1.
+// owner1 has group1 with contractor1, owner2 has group2 with contractor2. Each contractor has a pending request for testRequestID with the
+// nonce "nonce", and a received timesheet for it with two versions.
+type testServer struct {
+	router *mux.Router
+
+	db             *fakeDatabase
+	auth           *fakeAuthService
+	authorization  *core.AuthorizationService
+	account        *fakeAccountService
+	firebase       *fakeFirebaseService
+	sessions       *fakeSessionManagerService
+	templates      *fakeTemplateService
+	errorReporter  *fakeErrorReporterService
+	email          *fakeEmailService
+	emailTemplate  *fakeEmailTemplateService
+	schedule       *fakeScheduleService
+	scheduler      *fakeSchedulerService
+	holidays       *fakeHolidayCalendarService
+	storage        *fakeStorageService
+	submissionLink *fakeSubmissionLinkService
+	ingestion      *fakeTimesheetIngestionService
+	review         *fakeTimesheetReviewService
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	db := newFakeDatabase()
+	for _, n := range []string{"1", "2"} {
+		db.owners["owner"+n] = &types.Owner{ID: "owner" + n, GroupID: "group" + n, Name: "Owner", Email: "owner" + n + "@example.com"}
+		db.groups["group"+n] = &types.Group{
+			ID:      "group" + n,
+			OwnerID: "owner" + n,
+			Name:    "Group " + n,
+
+			ReminderPolicy:   types.ReminderPolicy{Enabled: true, ReminderDays: []int{2}, EscalationDays: 5},
+			AttachmentPolicy: types.AttachmentPolicy{MaxSizeMB: 5, AllowedTypes: []constants.TimesheetFileTypes{constants.CsvFile}},
+		}
+		db.contractors["contractor"+n] = &types.Contractor{ID: "contractor" + n, GroupID: "group" + n, Name: "Contractor", Email: "contractor" + n + "@example.com"}
+		db.requests = append(db.requests, &types.TimesheetRequest{
+			ID:           "request" + n,
+			GroupID:      "group" + n,
+			ContractorID: "contractor" + n,
+			RequestID:    testRequestID,
+
+			SentAt: time.Now().AddDate(0, 0, -3),
+			Status: constants.Pending,
+
+			Attempts:        1,
+			SubmissionNonce: "nonce",
+		})
+		db.timesheets["timesheet"+n] = &types.Timesheet{
+			ID:           "timesheet" + n,
+			ContractorID: "contractor" + n,
+			GroupID:      "group" + n,
+			RequestID:    testRequestID,
+
+			StorageURL: "gs://bucket/group" + n + "/v2.csv",
+			Version:    2,
+			TotalHours: 16,
+			Status:     constants.Received,
+		}
+		db.versions["timesheet"+n] = []*types.TimesheetVersion{
+			{TimesheetID: "timesheet" + n, Version: 1, StorageURL: "gs://bucket/group" + n + "/v1.csv", TotalHours: 8, DailyHours: map[string]float64{"2025-01-06": 8}},
+			{TimesheetID: "timesheet" + n, Version: 2, StorageURL: "gs://bucket/group" + n + "/v2.csv", TotalHours: 16, DailyHours: map[string]float64{"2025-01-06": 8, "2025-01-11": 8}},
+		}
+		db.entries["timesheet"+n] = []*types.TimesheetEntry{
+			{TimesheetID: "timesheet" + n, Date: "2025-01-06", Hours: 8},
+			{TimesheetID: "timesheet" + n, Date: "2025-01-11", Hours: 8},
+		}
+	}
+
+	ts := &testServer{
+		db:      db,
+		auth:    &fakeAuthService{userInfo: types.LoggedUserInfo{Email: "owner1@example.com", IsLoggedIn: true, IsVerified: true}, userID: "owner1", password: "password1"},
+		account: &fakeAccountService{links: map[string]*types.AccountLink{}, passwords: map[string]string{}},
+		firebase: &fakeFirebaseService{users: map[string]*types.AuthUser{
+			"owner1@example.com": {ID: "owner1", Email: "owner1@example.com", EmailVerified: true},
+		}},
+		sessions:      &fakeSessionManagerService{sessionID: "session1", values: map[string]interface{}{constants.SesstionOwnerIdField: "owner1"}},
+		templates:     &fakeTemplateService{},
+		errorReporter: &fakeErrorReporterService{},
+		email:         &fakeEmailService{unassigned: map[uint32]*types.InboundEmail{}},
+		emailTemplate: &fakeEmailTemplateService{},
+		schedule: &fakeScheduleService{occurrence: &types.ScheduleOccurrence{
+			Time:      time.Now(),
+			RequestID: testRequestID,
+		}},
+		scheduler:      &fakeSchedulerService{jobs: []string{"request group1", "reminder group1", "request group2", "reminder group2"}},
+		holidays:       &fakeHolidayCalendarService{},
+		storage:        &fakeStorageService{},
+		submissionLink: &fakeSubmissionLinkService{},
+		ingestion:      &fakeTimesheetIngestionService{},
+		review:         &fakeTimesheetReviewService{db: db},
+	}
+
+	// The owners are authorized by the real service, on the fake session and database
+	ts.authorization = core.NewAuthorizationService(ts.sessions, db, db, db)
+
+	// The routes of main.go, without its middlewares
+	r := mux.NewRouter()
+	authRouter := r.PathPrefix("/auth").Subrouter()
+	callbackRouter := r.PathPrefix("/timesheets").Subrouter()
+
+	mainHandler := NewMainHandler(ts.auth, ts.errorReporter, db, io.Discard)
+	r.Methods("GET").Path("/main").HandlerFunc(mainHandler.showMain)
+
+	NewRegisterHandler(ts.firebase, ts.auth, ts.templates, ts.email, ts.errorReporter).RegisterRegisterHandlers(r)
+	NewLoginHandler(ts.auth, ts.firebase, ts.templates, ts.sessions, ts.errorReporter).RegisterLoginHandlers(r)
+
+	accountHandler := NewAccountHandler(ts.auth, ts.authorization, ts.account, ts.sessions, ts.templates, ts.errorReporter)
+	accountHandler.RegisterAccountHandlers(r)
+	accountHandler.RegisterAccountAuthHandlers(authRouter)
+
+	NewSomethingWentWrongHandler(ts.templates).RegisterSomethingWentWrongHandlers(r)
+	NewOwnersHandler(ts.auth, ts.authorization, ts.account, ts.sessions, ts.templates, ts.errorReporter, db).RegisterOwnersHandlers(authRouter)
+	NewGroupsHandler(ts.auth, ts.authorization, ts.scheduler, ts.schedule, ts.holidays, ts.sessions, ts.storage, ts.templates, ts.email, ts.emailTemplate, ts.errorReporter, db, db).RegisterGroupsHandlers(authRouter)
+	NewContractorsHandler(ts.auth, ts.authorization, ts.templates, ts.errorReporter, db, db, db).RegisterContractorsHandler(authRouter)
+
+	timesheetsHandler := NewTimesheetsHandler(ts.email, ts.schedule, ts.storage, ts.submissionLink, ts.templates, ts.ingestion, ts.errorReporter, db, db, db, db, db, db)
+	timesheetsHandler.RegisterTimesheetsHandlers(r)
+	timesheetsHandler.RegisterTimesheetsCallbackHandlers(callbackRouter)
+
+	NewTimesheetReviewsHandler(ts.auth, ts.authorization, ts.email, ts.templates, ts.review, ts.schedule, ts.holidays, ts.storage, ts.errorReporter, db, db, db, db, db, db, db).RegisterTimesheetReviewsHandlers(authRouter)
+	NewInboxHandler(ts.auth, ts.authorization, ts.email, ts.templates, ts.ingestion, ts.errorReporter, db, db, db, db, db).RegisterInboxHandlers(authRouter)
+
+	ts.router = r
+	return ts
+}
+
+// routeTest is a request to a route of the test server and the response it gets.
+type routeTest struct {
+	name   string
+	method string
+	target string
+	form   url.Values        // Sent URL encoded, or as a multipart form with the file
+	file   string            // Content of the timesheet file uploaded, none if empty
+	setup  func(*testServer) // Changes the test server before the request, e.g. makes a service fail
+	check  func(*testing.T, *testServer)
+
+	wantStatus   int
+	wantLocation string // The redirect, empty if none
+	wantTemplate string // The page rendered, empty if none
+	wantMessage  string // Part of the message of the page, or of the body if no page is rendered
+	wantReported bool   // Whether an error is reported
+}
+
+// runRouteTests runs each test on a new test server.
+func runRouteTests(t *testing.T, tests []routeTest) {
+	t.Helper()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t)
+			if tt.setup != nil {
+				tt.setup(ts)
+			}
+
+			w := ts.do(t, tt.method, tt.target, tt.form, tt.file)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %q)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if location := w.Header().Get("Location"); location != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", location, tt.wantLocation)
+			}
+			if ts.templates.name != tt.wantTemplate {
+				t.Errorf("template = %q, want %q", ts.templates.name, tt.wantTemplate)
+			}
+			if message := ts.message(w); !strings.Contains(message, tt.wantMessage) {
+				t.Errorf("message = %q, want it to contain %q", message, tt.wantMessage)
+			}
+			if reported := len(ts.errorReporter.errs) > 0; reported != tt.wantReported {
+				t.Errorf("reported errors = %v, want reported %v", ts.errorReporter.errs, tt.wantReported)
+			}
+
+			if tt.check != nil {
+				tt.check(t, ts)
+			}
+		})
+	}
+}
+
+// do sends a request to the test server, the file is uploaded as the timesheet field of a multipart form.
+func (ts *testServer) do(t *testing.T, method string, target string, form url.Values, file string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var body io.Reader
+	contentType := ""
+	if file != "" {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		for key, values := range form {
+			for _, value := range values {
+				mw.WriteField(key, value)
+			}
+		}
+
+		part, err := mw.CreateFormFile("timesheet", "timesheet.csv")
+		if err != nil {
+			t.Fatalf("CreateFormFile: %v", err)
+		}
+		part.Write([]byte(file))
+		mw.Close()
+
+		body = &buf
+		contentType = mw.FormDataContentType()
+	} else if form != nil {
+		body = strings.NewReader(form.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	}
+
+	r := httptest.NewRequest(method, target, body)
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+
+	return ts.serve(r)
+}
+
+// serve serves a request with the test server.
+func (ts *testServer) serve(r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	ts.router.ServeHTTP(w, r)
+	return w
+}
+
+// message returns the message the page was rendered with, or the body of the response if no page was.
+func (ts *testServer) message(w *httptest.ResponseRecorder) string {
+	if ts.templates.errorMessage != "" {
+		return ts.templates.errorMessage
+	}
+
+	switch data := ts.templates.data.(type) {
+	case map[string]interface{}:
+		if message, ok := data["ErrorMessage"].(string); ok {
+			return message
+		}
+		if message, ok := data["Message"].(string); ok {
+			return message
+		}
+	case *submissionView:
+		return data.ErrorMessage
+	}
+
+	return w.Body.String()
+}
+
+// pageData returns the map a page was rendered with.
+func (ts *testServer) pageData(t *testing.T) map[string]interface{} {
+	t.Helper()
+
+	data, ok := ts.templates.data.(map[string]interface{})
+	if !ok {
+		t.Fatalf("page data = %T, want a map", ts.templates.data)
+	}
+
+	return data
+}
+
+// request returns the stored request of a contractor for testRequestID.
+func (ts *testServer) request(t *testing.T, contractorID string) *types.TimesheetRequest {
+	t.Helper()
+
+	request := ts.db.findTimesheetRequest(contractorID, testRequestID)
+	if request == nil {
+		t.Fatalf("no request of %s for %s", contractorID, testRequestID)
+	}
+
+	return request
+}
+
+// ownerSnapshot is what owner2 has, to check that owner1 cannot change it.
+type ownerSnapshot struct {
+	owner      types.Owner
+	group      types.Group
+	contractor types.Contractor
+	timesheet  types.Timesheet
+	request    types.TimesheetRequest
+	jobs       []string
+	email      bool // Whether the unassigned email of contractor2 is still in the inbox, with its triage
+}
+
+func (ts *testServer) owner2Snapshot() ownerSnapshot {
+	snapshot := ownerSnapshot{jobs: slices.Clone(ts.scheduler.jobs)}
+	if owner, ok := ts.db.owners["owner2"]; ok {
+		snapshot.owner = *owner
+	}
+	if group, ok := ts.db.groups["group2"]; ok {
+		snapshot.group = *group
+	}
+	if contractor, ok := ts.db.contractors["contractor2"]; ok {
+		snapshot.contractor = *contractor
+	}
+	if timesheet, ok := ts.db.timesheets["timesheet2"]; ok {
+		snapshot.timesheet = *timesheet
+	}
+	if request := ts.db.findTimesheetRequest("contractor2", testRequestID); request != nil {
+		snapshot.request = *request
+	}
+	_, inMailbox := ts.email.unassigned[2]
+	_, triaged := ts.db.unassignedEmails["email2"]
+	snapshot.email = inMailbox && triaged
+
+	return snapshot
+}
+
+// TestOwnerRoutesScopedToLoggedOwner sends every owner route the IDs of owner2's records while logged in as owner1.
+// The records of another owner are answered as if they did not exist, and are left unchanged.
+func TestOwnerRoutesScopedToLoggedOwner(t *testing.T) {
+	tests := []struct {
+		method string
+		target string
+		form   url.Values
+	}{
+		{"GET", "/auth/owners/owner2", nil},
+		{"GET", "/auth/owners/owner2/edit", nil},
+		{"PUT", "/auth/owners/owner2", url.Values{"name": {"Mallory"}, "email": {"owner2@example.com"}}},
+		{"DELETE", "/auth/owners/owner2", nil},
+
+		{"GET", "/auth/groups/group2", nil},
+		{"GET", "/auth/groups/group2/edit", nil},
+		{"GET", "/auth/groups/group2/delete", nil},
+		{"POST", "/auth/groups/group2", testGroupForm("name", "Mallory")},
+
+		{"GET", "/auth/contractors?groupID=group2", nil},
+		{"GET", "/auth/contractors/add?groupID=group2", nil},
+		{"GET", "/auth/contractors/contractor2/edit", nil},
+		{"POST", "/auth/contractors?groupID=group2", url.Values{"name": {"Mallory"}, "email": {"mallory@example.com"}, "language": {constants.EnglishLanguage}}},
+		{"POST", "/auth/contractors/contractor2?groupID=group1", url.Values{"name": {"Mallory"}, "email": {"mallory@example.com"}, "language": {constants.EnglishLanguage}}},
+		{"DELETE", "/auth/contractors/contractor2", nil},
+
+		{"GET", "/auth/timesheets/timesheet2", nil},
+		{"GET", "/auth/timesheets/timesheet2/download", nil},
+		{"POST", "/auth/timesheets/timesheet2/review", nil},
+		{"POST", "/auth/timesheets/timesheet2/approve", nil},
+		{"POST", "/auth/timesheets/timesheet2/reject", url.Values{"reason": {"Wrong hours"}}},
+
+		{"POST", "/auth/inbox/email2/assign", url.Values{"request": {"contractor2/" + testRequestID}, "attachment": {"0"}}},
+		{"POST", "/auth/inbox/email2/discard", nil},
+		{"POST", "/auth/inbox/email2/reply", url.Values{"message": {"Send it again"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			ts := newTestServer(t)
+			withInbox(ts)
+			before := ts.owner2Snapshot()
+
+			w := ts.do(t, tt.method, tt.target, tt.form, "")
+
+			if w.Code != http.StatusNotFound {
+				t.Errorf("status = %d, want %d (Location %q)", w.Code, http.StatusNotFound, w.Header().Get("Location"))
+			}
+			if after := ts.owner2Snapshot(); !reflect.DeepEqual(after, before) {
+				t.Errorf("owner2 = %+v, want it unchanged %+v", after, before)
+			}
+			if len(ts.email.sent) != 0 || len(ts.ingestion.saved) != 0 || len(ts.storage.deletedPrefixes) != 0 {
+				t.Errorf("sent = %v, saved = %v, deleted = %v, want nothing", ts.email.sent, ts.ingestion.saved, ts.storage.deletedPrefixes)
+			}
+			if len(ts.errorReporter.errs) != 0 {
+				t.Errorf("reported errors = %v, want none", ts.errorReporter.errs)
+			}
+		})
+	}
+}
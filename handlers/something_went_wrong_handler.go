@@ -3,17 +3,17 @@ package handlers
 import (
 	"net/http"
 
-	"job_sender/core"
+	"job_sender/interfaces"
 	constants "job_sender/utils/constants"
 
 	"github.com/gorilla/mux"
 )
 
 type SomethingWentWrongHandler struct {
-	templateService *core.TemplateService
+	templateService interfaces.ITemplateService
 }
 
-func NewSomethingWentWrongHandler(templateService *core.TemplateService) *SomethingWentWrongHandler {
+func NewSomethingWentWrongHandler(templateService interfaces.ITemplateService) *SomethingWentWrongHandler {
 	return &SomethingWentWrongHandler{
 		templateService: templateService,
 	}
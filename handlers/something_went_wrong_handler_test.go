@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	constants "job_sender/utils/constants"
+)
+
+func TestSomethingWentWrongHandler(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name:         "page",
+			method:       "GET",
+			target:       "/somethingWentWrong",
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateSomethingWentWrong,
+		},
+		{
+			name:        "template not parsed",
+			method:      "GET",
+			target:      "/somethingWentWrong",
+			setup:       func(ts *testServer) { ts.templates.fail("ParseTemplate", errFake) },
+			wantStatus:  http.StatusInternalServerError,
+			wantMessage: "could not parse something went wrong template",
+		},
+		{
+			name:        "template not executed",
+			method:      "GET",
+			target:      "/somethingWentWrong",
+			setup:       func(ts *testServer) { ts.templates.fail("ExecuteTemplate", errFake) },
+			wantStatus:  http.StatusInternalServerError,
+			wantMessage: "could not execute something went wrong template",
+		},
+	})
+}
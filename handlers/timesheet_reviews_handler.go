@@ -0,0 +1,473 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"job_sender/interfaces"
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type TimesheetReviewsHandler struct {
+	authService            interfaces.IAuthService
+	authorizationService   interfaces.IAuthorizationService
+	emailService           interfaces.IEmailService
+	templateService        interfaces.ITemplateService
+	timesheetReviewService interfaces.ITimesheetReviewService
+	scheduleService        interfaces.IScheduleService
+	holidayCalendarService interfaces.IHolidayCalendarService
+	storageService         interfaces.IStorageService
+	errorReporterService   interfaces.IErrorReporterService
+
+	groupsDB            interfaces.IGroupsDatabaseService
+	contractorsDB       interfaces.IContractorsDatabaseService
+	timesheetsDB        interfaces.ITimesheetsDatabaseService
+	timesheetRequestsDB interfaces.ITimesheetRequestsDatabaseService
+	timesheetVersionsDB interfaces.ITimesheetVersionsDatabaseService
+	timesheetEntriesDB  interfaces.ITimesheetEntriesDatabaseService
+	auditLogDB          interfaces.ITimesheetAuditLogDatabaseService
+}
+
+type timesheetAuditEntryView struct {
+	Time   string
+	Actor  string
+	Status constants.TimesheetStatuses
+	Reason string
+}
+
+type timesheetVersionView struct {
+	Version     int
+	SubmittedAt string // Empty for the file saved before versions existed
+	Filename    string
+	TotalHours  float64
+
+	HasPrevious bool // Whether there is a version to compare with
+	HoursChange float64
+	ChangedDays []timesheetDayChangeView // Nil if either version has no parsed days
+}
+
+type timesheetDayChangeView struct {
+	Date   string
+	Before float64
+	After  float64
+}
+
+// NewTimesheetReviewsHandler creates a new TimesheetReviewsHandler.
+func NewTimesheetReviewsHandler(authService interfaces.IAuthService, authorizationService interfaces.IAuthorizationService, emailService interfaces.IEmailService, templateService interfaces.ITemplateService, timesheetReviewService interfaces.ITimesheetReviewService, scheduleService interfaces.IScheduleService, holidayCalendarService interfaces.IHolidayCalendarService, storageService interfaces.IStorageService, errorReporterService interfaces.IErrorReporterService, groupsDB interfaces.IGroupsDatabaseService, contractorsDB interfaces.IContractorsDatabaseService, timesheetsDB interfaces.ITimesheetsDatabaseService, timesheetRequestsDB interfaces.ITimesheetRequestsDatabaseService, timesheetVersionsDB interfaces.ITimesheetVersionsDatabaseService, timesheetEntriesDB interfaces.ITimesheetEntriesDatabaseService, auditLogDB interfaces.ITimesheetAuditLogDatabaseService) *TimesheetReviewsHandler {
+	return &TimesheetReviewsHandler{
+		authService:            authService,
+		authorizationService:   authorizationService,
+		emailService:           emailService,
+		templateService:        templateService,
+		timesheetReviewService: timesheetReviewService,
+		scheduleService:        scheduleService,
+		holidayCalendarService: holidayCalendarService,
+		storageService:         storageService,
+		errorReporterService:   errorReporterService,
+
+		groupsDB:            groupsDB,
+		contractorsDB:       contractorsDB,
+		timesheetsDB:        timesheetsDB,
+		timesheetRequestsDB: timesheetRequestsDB,
+		timesheetVersionsDB: timesheetVersionsDB,
+		timesheetEntriesDB:  timesheetEntriesDB,
+		auditLogDB:          auditLogDB,
+	}
+}
+
+// RegisterTimesheetReviewsHandlers registers the Timesheet reviews handlers.
+func (h *TimesheetReviewsHandler) RegisterTimesheetReviewsHandlers(r *mux.Router) {
+	r.Methods("GET").Path("/timesheets/{ID}").HandlerFunc(h.GetTimesheet)
+	r.Methods("GET").Path("/timesheets/{ID}/download").HandlerFunc(h.DownloadTimesheet)
+
+	r.Methods("POST").Path("/timesheets/{ID}/review").HandlerFunc(h.StartTimesheetReview)
+	r.Methods("POST").Path("/timesheets/{ID}/approve").HandlerFunc(h.ApproveTimesheet)
+	r.Methods("POST").Path("/timesheets/{ID}/reject").HandlerFunc(h.RejectTimesheet)
+}
+
+// GetTimesheet shows a timesheet with its entries and audit log, it changes nothing.
+func (h *TimesheetReviewsHandler) GetTimesheet(w http.ResponseWriter, r *http.Request) {
+	timesheet, ok := h.getTimesheet(w, r)
+	if !ok {
+		return
+	}
+
+	userInfo, err := h.authService.CheckUser(r)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not check user: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	// Get the group.
+	group, err := h.groupsDB.GetGroup(timesheet.GroupID)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get group: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	// Add the groupInfo to the userInfo
+	userInfo.GroupID = group.ID
+	userInfo.GroupName = group.Name
+
+	// Get the contractor.
+	contractor, err := h.contractorsDB.GetContractor(timesheet.ContractorID)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get contractor: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	// Get the entries.
+	entries, err := h.timesheetEntriesDB.ListTimesheetEntries(timesheet.ID)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not list timesheet entries: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	// Get the audit log.
+	auditEntries, err := h.auditLogDB.ListAuditEntries(timesheet.ID)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not list audit entries: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	// Get the versions.
+	versions, err := h.timesheetVersionsDB.ListTimesheetVersions(timesheet.ID)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not list timesheet versions: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	var auditLog []timesheetAuditEntryView
+	for _, entry := range auditEntries {
+		auditLog = append(auditLog, timesheetAuditEntryView{
+			Time:   time.UnixMilli(entry.Timestamp).UTC().Format("2006-01-02 15:04:05 UTC"),
+			Actor:  entry.Actor,
+			Status: entry.Status,
+			Reason: entry.Reason,
+		})
+	}
+
+	data := map[string]interface{}{
+		"Timesheet":  timesheet,
+		"Contractor": contractor,
+		"Entries":    entries,
+		"AuditLog":   auditLog,
+		"Versions":   versionViews(versions),
+
+		"CanStartReview": h.timesheetReviewService.CanTransitionTimesheet(timesheet.Status, constants.UnderReview),
+		"CanApprove":     h.timesheetReviewService.CanTransitionTimesheet(timesheet.Status, constants.Approved),
+		"CanReject":      h.timesheetReviewService.CanTransitionTimesheet(timesheet.Status, constants.Rejected),
+	}
+
+	// Compare the hours with the working days of the period, timesheets requested before schedules had periods are not checked
+	start, end, err := h.scheduleService.RequestPeriod(timesheet.RequestID)
+	if err == nil {
+		workingDays, err := h.holidayCalendarService.CountWorkingDays(group.HolidayCalendar, start, end)
+		if err != nil {
+			h.errorReporterService.ReportError(w, r, fmt.Errorf("could not count working days: %w", err))
+			http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+			return
+		}
+
+		// Hours booked on weekends and holidays
+		nonWorkingDays := make(map[string]bool)
+		for _, entry := range entries {
+			date, err := time.Parse("2006-01-02", entry.Date)
+			if err != nil || entry.Hours == 0 {
+				continue
+			}
+
+			isWorkingDay, err := h.holidayCalendarService.IsWorkingDay(group.HolidayCalendar, date)
+			if err != nil {
+				h.errorReporterService.ReportError(w, r, fmt.Errorf("could not check working day: %w", err))
+				http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+				return
+			}
+			nonWorkingDays[entry.Date] = !isWorkingDay
+		}
+
+		expectedHours := float64(workingDays * constants.WorkingDayHours)
+
+		data["WorkingDays"] = workingDays
+		data["ExpectedHours"] = expectedHours
+		data["HoursMismatch"] = timesheet.TotalHours != expectedHours
+		data["NonWorkingDays"] = nonWorkingDays
+	} else if status.Code(err) != codes.InvalidArgument {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get request period: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	// Execute the template
+	getTmpl, err := h.templateService.ParseTemplate(constants.TemplateTimesheetGetName)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not parse timesheet template: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	err = h.templateService.ExecuteTemplate(getTmpl, w, r, data, userInfo)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not execute template: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+}
+
+// StartTimesheetReview puts a received or resubmitted timesheet under review.
+func (h *TimesheetReviewsHandler) StartTimesheetReview(w http.ResponseWriter, r *http.Request) {
+	timesheet, ok := h.getTimesheet(w, r)
+	if !ok {
+		return
+	}
+
+	userInfo, err := h.authService.CheckUser(r)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not check user: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	if !h.transitionTimesheet(w, r, timesheet, constants.UnderReview, userInfo.Email, "") {
+		return
+	}
+
+	http.Redirect(w, r, "/auth/timesheets/"+timesheet.ID, http.StatusSeeOther)
+}
+
+// ApproveTimesheet approves a timesheet under review.
+func (h *TimesheetReviewsHandler) ApproveTimesheet(w http.ResponseWriter, r *http.Request) {
+	timesheet, ok := h.getTimesheet(w, r)
+	if !ok {
+		return
+	}
+
+	userInfo, err := h.authService.CheckUser(r)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not check user: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	if !h.transitionTimesheet(w, r, timesheet, constants.Approved, userInfo.Email, "") {
+		return
+	}
+
+	http.Redirect(w, r, "/auth/timesheets/"+timesheet.ID, http.StatusSeeOther)
+}
+
+// RejectTimesheet rejects a timesheet under review and asks the contractor for a corrected one.
+func (h *TimesheetReviewsHandler) RejectTimesheet(w http.ResponseWriter, r *http.Request) {
+	timesheet, ok := h.getTimesheet(w, r)
+	if !ok {
+		return
+	}
+
+	userInfo, err := h.authService.CheckUser(r)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not check user: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	reason := strings.TrimSpace(r.FormValue("reason"))
+	if !h.transitionTimesheet(w, r, timesheet, constants.Rejected, userInfo.Email, reason) {
+		return
+	}
+
+	// Get the contractor.
+	contractor, err := h.contractorsDB.GetContractor(timesheet.ContractorID)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get contractor: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	// Get the group, the email is sent with its branding.
+	group, err := h.groupsDB.GetGroup(timesheet.GroupID)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get group: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	// Get the request, the reply to the email is matched to it by its reply key.
+	request, err := h.timesheetRequestsDB.GetTimesheetRequest(contractor.ID, timesheet.RequestID)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get timesheet request: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	err = h.emailService.SendTimesheetRejectionEmail(group, contractor, request, reason)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not send timesheet rejection email: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	// Collect the request again, so the corrected timesheet is picked up, and remind about it as about a new request
+	request.SentAt = time.Now()
+	request.DueAt = h.scheduleService.RequestDueAt(&group.ReminderPolicy, request.SentAt)
+	request.CollectedAt = time.Time{}
+	request.Status = constants.Pending
+	request.Attempts = 1
+	request.Escalated = false
+
+	err = h.timesheetRequestsDB.UpdateTimesheetRequest(request)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not update timesheet request: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, "/auth/timesheets/"+timesheet.ID, http.StatusSeeOther)
+}
+
+// DownloadTimesheet sends the owner of the timesheet's group to a short-lived signed URL of its file, or of the version in the query.
+func (h *TimesheetReviewsHandler) DownloadTimesheet(w http.ResponseWriter, r *http.Request) {
+	timesheet, ok := h.getTimesheet(w, r)
+	if !ok {
+		return
+	}
+
+	fileURL := timesheet.StorageURL
+	if query := r.URL.Query().Get("version"); query != "" {
+		number, err := strconv.Atoi(query)
+		if err != nil {
+			http.Error(w, "version must be a number", http.StatusBadRequest)
+			return
+		}
+
+		versions, err := h.timesheetVersionsDB.ListTimesheetVersions(timesheet.ID)
+		if err != nil {
+			h.errorReporterService.ReportError(w, r, fmt.Errorf("could not list timesheet versions: %w", err))
+			http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+			return
+		}
+
+		i := slices.IndexFunc(versions, func(v *types.TimesheetVersion) bool { return v.Version == number })
+		if i == -1 {
+			http.NotFound(w, r)
+			return
+		}
+		fileURL = versions[i].StorageURL
+	}
+
+	signedURL, err := h.storageService.SignedURL(fileURL, constants.DownloadURLLifetimeMinutes*time.Minute)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not sign timesheet download URL: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	// The signed URL expires, the redirect must not be cached
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, signedURL, http.StatusFound)
+}
+
+// getTimesheet gets the timesheet of the ID in the path, responding with an error if there is none or it is of another owner's group.
+func (h *TimesheetReviewsHandler) getTimesheet(w http.ResponseWriter, r *http.Request) (*types.Timesheet, bool) {
+	id := mux.Vars(r)["ID"]
+	if id == "" {
+		http.Error(w, "ID is required", http.StatusBadRequest)
+		return nil, false
+	}
+
+	timesheet, err := h.authorizationService.GetOwnedTimesheet(r, id)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			http.NotFound(w, r)
+			return nil, false
+		}
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get timesheet: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return nil, false
+	}
+
+	return timesheet, true
+}
+
+// transitionTimesheet moves a timesheet to a status, responding with an error if it cannot move there.
+func (h *TimesheetReviewsHandler) transitionTimesheet(w http.ResponseWriter, r *http.Request, timesheet *types.Timesheet, to constants.TimesheetStatuses, actor string, reason string) bool {
+	err := h.timesheetReviewService.TransitionTimesheet(timesheet, to, actor, reason)
+	switch status.Code(err) {
+	case codes.OK:
+		return true
+	case codes.FailedPrecondition:
+		http.Error(w, fmt.Sprintf("A timesheet that is %s cannot be %s", strings.ToLower(timesheet.Status.String()), strings.ToLower(to.String())), http.StatusConflict)
+	case codes.InvalidArgument:
+		http.Error(w, "A reason is required to reject a timesheet", http.StatusBadRequest)
+	default:
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not change timesheet status: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+	}
+
+	return false
+}
+
+// versionViews shows the versions of a timesheet from the newest, each compared with the version before it.
+func versionViews(versions []*types.TimesheetVersion) []timesheetVersionView {
+	views := make([]timesheetVersionView, 0, len(versions))
+	for i := len(versions) - 1; i >= 0; i-- {
+		version := versions[i]
+		view := timesheetVersionView{
+			Version:    version.Version,
+			Filename:   version.Filename,
+			TotalHours: version.TotalHours,
+		}
+
+		if !version.SubmittedAt.IsZero() {
+			view.SubmittedAt = version.SubmittedAt.UTC().Format("2006-01-02 15:04:05 UTC")
+		}
+
+		if i > 0 {
+			previous := versions[i-1]
+			view.HasPrevious = true
+			view.HoursChange = math.Round((version.TotalHours-previous.TotalHours)*100) / 100
+
+			if version.DailyHours != nil && previous.DailyHours != nil {
+				view.ChangedDays = dayChanges(previous.DailyHours, version.DailyHours)
+			}
+		}
+
+		views = append(views, view)
+	}
+
+	return views
+}
+
+// dayChanges lists the days whose hours differ between two versions, by date.
+func dayChanges(before map[string]float64, after map[string]float64) []timesheetDayChangeView {
+	var changes []timesheetDayChangeView
+	for date, hours := range after {
+		if before[date] != hours {
+			changes = append(changes, timesheetDayChangeView{Date: date, Before: before[date], After: hours})
+		}
+	}
+	for date, hours := range before {
+		if _, ok := after[date]; !ok {
+			changes = append(changes, timesheetDayChangeView{Date: date, Before: hours})
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b timesheetDayChangeView) int { return strings.Compare(a.Date, b.Date) })
+
+	return changes
+}
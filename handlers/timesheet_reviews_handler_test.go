@@ -0,0 +1,454 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+
+	constants "job_sender/utils/constants"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// underReview puts timesheet1 under review, its request collected after a reminder.
+func underReview(ts *testServer) {
+	ts.db.timesheets["timesheet1"].Status = constants.UnderReview
+
+	request := ts.db.findTimesheetRequest("contractor1", testRequestID)
+	request.Status = constants.Collected
+	request.Attempts = 2
+	request.Escalated = true
+}
+
+func TestTimesheetReviewsHandlerGetTimesheet(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name:         "timesheet",
+			method:       "GET",
+			target:       "/auth/timesheets/timesheet1",
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateTimesheetGetName,
+			check: func(t *testing.T, ts *testServer) {
+				data := ts.pageData(t)
+
+				// January 2025 has 23 weekdays, the hours of Saturday the 11th are flagged
+				if data["WorkingDays"] != 23 || data["ExpectedHours"] != float64(23*constants.WorkingDayHours) || data["HoursMismatch"] != true {
+					t.Errorf("working days = %v, expected hours = %v, mismatch = %v, want 23, 184, true", data["WorkingDays"], data["ExpectedHours"], data["HoursMismatch"])
+				}
+				if nonWorkingDays := data["NonWorkingDays"].(map[string]bool); !nonWorkingDays["2025-01-11"] || nonWorkingDays["2025-01-06"] {
+					t.Errorf("NonWorkingDays = %v, want only 2025-01-11", nonWorkingDays)
+				}
+				if data["CanStartReview"] != true || data["CanApprove"] != false || data["CanReject"] != false {
+					t.Errorf("CanStartReview, CanApprove, CanReject = %v, %v, %v, want true, false, false", data["CanStartReview"], data["CanApprove"], data["CanReject"])
+				}
+
+				// The newest version first, compared with the one before it
+				versions := data["Versions"].([]timesheetVersionView)
+				if len(versions) != 2 || versions[0].Version != 2 || versions[0].HoursChange != 8 || len(versions[0].ChangedDays) != 1 {
+					t.Errorf("versions = %+v, want version 2 adding 8 hours on a day, then version 1", versions)
+				}
+			},
+		},
+		{
+			name:         "timesheet requested before periods",
+			method:       "GET",
+			target:       "/auth/timesheets/timesheet1",
+			setup:        func(ts *testServer) { ts.db.timesheets["timesheet1"].RequestID = "9_10-2024" },
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateTimesheetGetName,
+			check: func(t *testing.T, ts *testServer) {
+				if _, ok := ts.pageData(t)["WorkingDays"]; ok {
+					t.Error("WorkingDays is set, want the hours not checked")
+				}
+			},
+		},
+		{
+			name:        "timesheet of another owner",
+			method:      "GET",
+			target:      "/auth/timesheets/timesheet2",
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+		},
+		{
+			name:        "unknown timesheet",
+			method:      "GET",
+			target:      "/auth/timesheets/timesheet3",
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+		},
+		{
+			name:         "timesheet not read",
+			method:       "GET",
+			target:       "/auth/timesheets/timesheet1",
+			setup:        func(ts *testServer) { ts.db.fail("GetTimesheetByID", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "timesheet without the user",
+			method:       "GET",
+			target:       "/auth/timesheets/timesheet1",
+			setup:        func(ts *testServer) { ts.auth.fail("CheckUser", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "timesheet without the contractor",
+			method:       "GET",
+			target:       "/auth/timesheets/timesheet1",
+			setup:        func(ts *testServer) { ts.db.fail("GetContractor", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "entries not listed",
+			method:       "GET",
+			target:       "/auth/timesheets/timesheet1",
+			setup:        func(ts *testServer) { ts.db.fail("ListTimesheetEntries", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "audit log not listed",
+			method:       "GET",
+			target:       "/auth/timesheets/timesheet1",
+			setup:        func(ts *testServer) { ts.db.fail("ListAuditEntries", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "versions not listed",
+			method:       "GET",
+			target:       "/auth/timesheets/timesheet1",
+			setup:        func(ts *testServer) { ts.db.fail("ListTimesheetVersions", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "period not read",
+			method:       "GET",
+			target:       "/auth/timesheets/timesheet1",
+			setup:        func(ts *testServer) { ts.schedule.fail("RequestPeriod", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "working days not counted",
+			method:       "GET",
+			target:       "/auth/timesheets/timesheet1",
+			setup:        func(ts *testServer) { ts.holidays.fail("CountWorkingDays", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "working day not checked",
+			method:       "GET",
+			target:       "/auth/timesheets/timesheet1",
+			setup:        func(ts *testServer) { ts.holidays.fail("IsWorkingDay", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "timesheet page not parsed",
+			method:       "GET",
+			target:       "/auth/timesheets/timesheet1",
+			setup:        func(ts *testServer) { ts.templates.fail("ParseTemplate", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+	})
+}
+
+func TestTimesheetReviewsHandlerReview(t *testing.T) {
+	reject := url.Values{"reason": {" Missing the 2nd of January "}}
+
+	runRouteTests(t, []routeTest{
+		{
+			name:         "start review",
+			method:       "POST",
+			target:       "/auth/timesheets/timesheet1/review",
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/timesheets/timesheet1",
+			check: func(t *testing.T, ts *testServer) {
+				if got := ts.db.timesheets["timesheet1"].Status; got != constants.UnderReview {
+					t.Errorf("status = %v, want %v", got, constants.UnderReview)
+				}
+				if auditLog := ts.db.auditLog["timesheet1"]; len(auditLog) != 1 || auditLog[0].Actor != "owner1@example.com" {
+					t.Errorf("audit log = %+v, want one entry by owner1@example.com", auditLog)
+				}
+			},
+		},
+		{
+			name:        "start review of another owner's timesheet",
+			method:      "POST",
+			target:      "/auth/timesheets/timesheet2/review",
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+			check: func(t *testing.T, ts *testServer) {
+				if got := ts.db.timesheets["timesheet2"].Status; got != constants.Received {
+					t.Errorf("status of timesheet2 = %v, want it unchanged", got)
+				}
+			},
+		},
+		{
+			name:         "start review without the user",
+			method:       "POST",
+			target:       "/auth/timesheets/timesheet1/review",
+			setup:        func(ts *testServer) { ts.auth.fail("CheckUser", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:        "start review of a timesheet under review",
+			method:      "POST",
+			target:      "/auth/timesheets/timesheet1/review",
+			setup:       underReview,
+			wantStatus:  http.StatusConflict,
+			wantMessage: "A timesheet that is under review cannot be under review",
+		},
+		{
+			name:         "review not started",
+			method:       "POST",
+			target:       "/auth/timesheets/timesheet1/review",
+			setup:        func(ts *testServer) { ts.db.fail("UpdateTimesheetStatus", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "approve",
+			method:       "POST",
+			target:       "/auth/timesheets/timesheet1/approve",
+			setup:        underReview,
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/timesheets/timesheet1",
+			check: func(t *testing.T, ts *testServer) {
+				if got := ts.db.timesheets["timesheet1"].Status; got != constants.Approved {
+					t.Errorf("status = %v, want %v", got, constants.Approved)
+				}
+			},
+		},
+		{
+			name:        "approve a received timesheet",
+			method:      "POST",
+			target:      "/auth/timesheets/timesheet1/approve",
+			wantStatus:  http.StatusConflict,
+			wantMessage: "A timesheet that is received cannot be approved",
+		},
+		{
+			name:        "approve another owner's timesheet",
+			method:      "POST",
+			target:      "/auth/timesheets/timesheet2/approve",
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+		},
+		{
+			name:         "approve without the user",
+			method:       "POST",
+			target:       "/auth/timesheets/timesheet1/approve",
+			setup:        func(ts *testServer) { ts.auth.fail("CheckUser", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "reject",
+			method:       "POST",
+			target:       "/auth/timesheets/timesheet1/reject",
+			form:         reject,
+			setup:        underReview,
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/auth/timesheets/timesheet1",
+			check: func(t *testing.T, ts *testServer) {
+				timesheet := ts.db.timesheets["timesheet1"]
+				if timesheet.Status != constants.Rejected || timesheet.RejectionReason != "Missing the 2nd of January" {
+					t.Errorf("timesheet = %+v, want it rejected with the trimmed reason", timesheet)
+				}
+				if !slices.Equal(ts.email.sent, []string{constants.EmailTemplateRejectionName + " contractor1@example.com"}) {
+					t.Errorf("sent = %v, want the rejection email to contractor1", ts.email.sent)
+				}
+
+				// The request is collected again, reminded as a new one
+				request := ts.request(t, "contractor1")
+				if request.Status != constants.Pending || request.Attempts != 1 || request.Escalated || request.DueAt.Sub(request.SentAt).Hours() != 7*24 {
+					t.Errorf("request = %+v, want it pending after 1 attempt, due in 7 days", request)
+				}
+			},
+		},
+		{
+			name:        "reject without a reason",
+			method:      "POST",
+			target:      "/auth/timesheets/timesheet1/reject",
+			form:        url.Values{"reason": {" "}},
+			setup:       underReview,
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "A reason is required to reject a timesheet",
+		},
+		{
+			name:        "reject a received timesheet",
+			method:      "POST",
+			target:      "/auth/timesheets/timesheet1/reject",
+			form:        reject,
+			wantStatus:  http.StatusConflict,
+			wantMessage: "A timesheet that is received cannot be rejected",
+			check: func(t *testing.T, ts *testServer) {
+				if len(ts.email.sent) != 0 {
+					t.Errorf("sent = %v, want nothing", ts.email.sent)
+				}
+			},
+		},
+		{
+			name:        "reject another owner's timesheet",
+			method:      "POST",
+			target:      "/auth/timesheets/timesheet2/reject",
+			form:        reject,
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+		},
+		{
+			name:         "reject without the user",
+			method:       "POST",
+			target:       "/auth/timesheets/timesheet1/reject",
+			form:         reject,
+			setup:        func(ts *testServer) { ts.auth.fail("CheckUser", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:   "reject without the contractor",
+			method: "POST",
+			target: "/auth/timesheets/timesheet1/reject",
+			form:   reject,
+			setup: func(ts *testServer) {
+				underReview(ts)
+				ts.db.fail("GetContractor", errFake)
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:   "reject without the request",
+			method: "POST",
+			target: "/auth/timesheets/timesheet1/reject",
+			form:   reject,
+			setup: func(ts *testServer) {
+				underReview(ts)
+				ts.db.fail("GetTimesheetRequest", errFake)
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:   "rejection email not sent",
+			method: "POST",
+			target: "/auth/timesheets/timesheet1/reject",
+			form:   reject,
+			setup: func(ts *testServer) {
+				underReview(ts)
+				ts.email.fail("SendTimesheetRejectionEmail", errFake)
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:   "request not collected again",
+			method: "POST",
+			target: "/auth/timesheets/timesheet1/reject",
+			form:   reject,
+			setup: func(ts *testServer) {
+				underReview(ts)
+				ts.db.fail("UpdateTimesheetRequest", errFake)
+			},
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:   "timesheet moved meanwhile",
+			method: "POST",
+			target: "/auth/timesheets/timesheet1/approve",
+			setup: func(ts *testServer) {
+				ts.review.fail("TransitionTimesheet", status.Error(codes.FailedPrecondition, "timesheet changed"))
+			},
+			wantStatus:  http.StatusConflict,
+			wantMessage: "A timesheet that is received cannot be approved",
+		},
+	})
+}
+
+func TestTimesheetReviewsHandlerDownload(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name:         "current file",
+			method:       "GET",
+			target:       "/auth/timesheets/timesheet1/download",
+			wantStatus:   http.StatusFound,
+			wantLocation: "https://storage.example.com/bucket/group1/v2.csv?signed",
+			wantMessage:  "Found",
+		},
+		{
+			name:         "version",
+			method:       "GET",
+			target:       "/auth/timesheets/timesheet1/download?version=1",
+			wantStatus:   http.StatusFound,
+			wantLocation: "https://storage.example.com/bucket/group1/v1.csv?signed",
+			wantMessage:  "Found",
+		},
+		{
+			name:        "invalid version",
+			method:      "GET",
+			target:      "/auth/timesheets/timesheet1/download?version=last",
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "version must be a number",
+		},
+		{
+			name:        "unknown version",
+			method:      "GET",
+			target:      "/auth/timesheets/timesheet1/download?version=3",
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+		},
+		{
+			name:        "file of another owner's timesheet",
+			method:      "GET",
+			target:      "/auth/timesheets/timesheet2/download",
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+		},
+		{
+			name:         "versions not listed",
+			method:       "GET",
+			target:       "/auth/timesheets/timesheet1/download?version=1",
+			setup:        func(ts *testServer) { ts.db.fail("ListTimesheetVersions", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "URL not signed",
+			method:       "GET",
+			target:       "/auth/timesheets/timesheet1/download",
+			setup:        func(ts *testServer) { ts.storage.fail("SignedURL", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+	})
+}
@@ -1,48 +1,86 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"path"
 	"strings"
 	"time"
 
-	"job_sender/core"
+	"job_sender/interfaces"
 	"job_sender/types"
 	constants "job_sender/utils/constants"
 
 	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type TimesheetsHandler struct {
-	emailService         *core.EmailService
-	storageService       *core.StorageService
-	errorReporterService *core.ErrorReporterService
+	emailService              interfaces.IEmailService
+	scheduleService           interfaces.IScheduleService
+	storageService            interfaces.IStorageService
+	submissionLinkService     interfaces.ISubmissionLinkService
+	templateService           interfaces.ITemplateService
+	timesheetIngestionService interfaces.ITimesheetIngestionService
+	errorReporterService      interfaces.IErrorReporterService
+
+	ownersDB            interfaces.IOwnerDatabaseService
+	groupsDB            interfaces.IGroupsDatabaseService
+	contractorsDB       interfaces.IContractorsDatabaseService
+	timesheetsDB        interfaces.ITimesheetsDatabaseService
+	timesheetRequestsDB interfaces.ITimesheetRequestsDatabaseService
+	timesheetEntriesDB  interfaces.ITimesheetEntriesDatabaseService
+}
+
+// submissionView is the submission page of a contractor's request.
+type submissionView struct {
+	Token      string
+	nonce      string // Nonce of the link, it identifies the upload made through it
+	Contractor *types.Contractor
+	RequestID  string
+
+	Timesheet  *types.Timesheet // Nil until a timesheet is submitted
+	Entries    []*types.TimesheetEntry
+	CanReplace bool
+	Submitted  bool // Whether the page is shown right after an upload
+	Duplicate  bool // Whether the upload was the file already submitted, and was ignored
 
-	groupsDB      *core.GroupsDatabaseService
-	contractorsDB *core.ContractorsDatabaseService
-	timesheetsDB  *core.TimesheetsDatabaseService
+	ErrorMessage string
 }
 
 // NewTimesheetsHandler creates a new TimesheetsHandler.
-func NewTimesheetsHandler(emailService *core.EmailService, storageService *core.StorageService, errorReporterService *core.ErrorReporterService, groupsDB *core.GroupsDatabaseService, contractorsDB *core.ContractorsDatabaseService, timesheetsDB *core.TimesheetsDatabaseService) *TimesheetsHandler {
+func NewTimesheetsHandler(emailService interfaces.IEmailService, scheduleService interfaces.IScheduleService, storageService interfaces.IStorageService, submissionLinkService interfaces.ISubmissionLinkService, templateService interfaces.ITemplateService, timesheetIngestionService interfaces.ITimesheetIngestionService, errorReporterService interfaces.IErrorReporterService, ownersDB interfaces.IOwnerDatabaseService, groupsDB interfaces.IGroupsDatabaseService, contractorsDB interfaces.IContractorsDatabaseService, timesheetsDB interfaces.ITimesheetsDatabaseService, timesheetRequestsDB interfaces.ITimesheetRequestsDatabaseService, timesheetEntriesDB interfaces.ITimesheetEntriesDatabaseService) *TimesheetsHandler {
 	return &TimesheetsHandler{
-		emailService:         emailService,
-		storageService:       storageService,
-		errorReporterService: errorReporterService,
-
-		groupsDB:      groupsDB,
-		contractorsDB: contractorsDB,
-		timesheetsDB:  timesheetsDB,
+		emailService:              emailService,
+		scheduleService:           scheduleService,
+		storageService:            storageService,
+		submissionLinkService:     submissionLinkService,
+		templateService:           templateService,
+		timesheetIngestionService: timesheetIngestionService,
+		errorReporterService:      errorReporterService,
+
+		ownersDB:            ownersDB,
+		groupsDB:            groupsDB,
+		contractorsDB:       contractorsDB,
+		timesheetsDB:        timesheetsDB,
+		timesheetRequestsDB: timesheetRequestsDB,
+		timesheetEntriesDB:  timesheetEntriesDB,
 	}
 }
 
-// RegisterTimesheetsHandlers registers the Timesheets handlers.
+// RegisterTimesheetsHandlers registers the Timesheets handlers the contractors use.
 func (h *TimesheetsHandler) RegisterTimesheetsHandlers(r *mux.Router) {
-	r.Methods("POST").Path("/timesheets/request").HandlerFunc(h.RequestTimesheet)
-	r.Methods("POST").Path("/timesheets/aggregate").HandlerFunc(h.AggregateTimesheet)
+	r.Methods("GET").Path(constants.SubmissionLinkPath + "{token}").HandlerFunc(h.ShowSubmitTimesheet)
+	r.Methods("POST").Path(constants.SubmissionLinkPath + "{token}").HandlerFunc(h.SubmitTimesheet)
+	r.Methods("GET").Path(constants.SubmissionLinkPath + "{token}/download").HandlerFunc(h.DownloadSubmittedTimesheet)
+}
+
+// RegisterTimesheetsCallbackHandlers registers the Timesheets handlers the scheduler jobs and the other machines call, on a router under /timesheets.
+func (h *TimesheetsHandler) RegisterTimesheetsCallbackHandlers(r *mux.Router) {
+	r.Methods("POST").Path("/request").HandlerFunc(h.RequestTimesheet)
+	r.Methods("POST").Path("/remind").HandlerFunc(h.RemindTimesheets)
 }
 
 // RequestTimesheet sends a timesheet request email to the contractor.
@@ -61,18 +99,16 @@ func (h *TimesheetsHandler) RequestTimesheet(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Check if the current time is within the schedule's start and end dates
-	requestID, err := getRequestID(group)
+	// Check if the schedule sends a request today, moved to working days of the group's holiday calendar, the request ID is the period it covers
+	occurrence, err := h.scheduleService.OccurrenceOn(&group.Schedule, group.HolidayCalendar, time.Now())
 	if err != nil {
-		if err.Error() == "current time is not within the schedule's start and end dates" {
-			return
-		} else if err.Error() == "not the correct request time" {
-			return
-		} else {
-			h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to get requestID ID: %w", err))
+		if status.Code(err) == codes.NotFound {
 			return
 		}
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to get schedule occurrence: %w", err))
+		return
 	}
+	requestID := occurrence.RequestID
 
 	// Get contractors from the database
 	contractors, err := h.contractorsDB.GetContractors(groupID)
@@ -81,173 +117,438 @@ func (h *TimesheetsHandler) RequestTimesheet(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	periodStart, periodEnd, err := h.scheduleService.RequestPeriod(requestID)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to get request period: %w", err))
+		return
+	}
+
 	// Send timesheet request emails to the contractors
 	for _, contractor := range contractors {
-		var requestExists bool
-		parsedRequestID := strings.ReplaceAll(strings.ReplaceAll(requestID, "/", "_"), " ", "-")
-
-		if contractor.LastRequests != nil {
-			for _, lastRequest := range contractor.LastRequests {
-				requestExists = lastRequest.ID == parsedRequestID && lastRequest.Timestamp != 0
-			}
+		// Contractors who did not reply to the request are reminded by RemindTimesheets instead, a request saved without its email is sent again
+		request, err := h.timesheetRequestsDB.GetTimesheetRequest(contractor.ID, requestID)
+		if err == nil && (request.Attempts > 0 || request.Status != constants.Pending) {
+			continue
+		} else if err != nil && status.Code(err) != codes.NotFound {
+			h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to get timesheet request: %w", err))
+			continue
 		}
 
-		if requestExists {
+		submissionLink, nonce, err := h.submissionLinkService.CreateSubmissionLink(contractor.ID, requestID)
+		if err != nil {
+			h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to create submission link: %w", err))
 			continue
 		}
 
-		err = h.emailService.SendTimesheetRequestEmail(contractor, requestID)
+		// Save the request before the email, so an email sent is never without its request, it is marked sent once the email is
+		if request == nil {
+			request = &types.TimesheetRequest{
+				GroupID:      group.ID,
+				ContractorID: contractor.ID,
+				RequestID:    requestID,
+
+				PeriodStart: periodStart,
+				PeriodEnd:   periodEnd,
+
+				Status: constants.Pending,
+
+				SubmissionNonce: nonce,
+			}
+
+			err = h.timesheetRequestsDB.AddTimesheetRequest(request)
+			if err != nil {
+				h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to add timesheet request: %w", err))
+				continue
+			}
+		} else {
+			usedNonce := request.SubmissionNonce
+			request.SubmissionNonce = nonce
+
+			err = h.timesheetRequestsDB.ReplaceSubmissionNonce(request, usedNonce)
+			if status.Code(err) == codes.FailedPrecondition {
+				continue
+			} else if err != nil {
+				h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to update timesheet request: %w", err))
+				continue
+			}
+		}
+
+		request.SentAt = time.Now()
+		request.DueAt = h.scheduleService.RequestDueAt(&group.ReminderPolicy, request.SentAt)
+		request.Attempts = 1
+
+		err = h.emailService.SendTimesheetRequestEmail(group, contractor, request, submissionLink)
 		if err != nil {
 			h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to send timesheet request email: %w", err))
 			continue
 		}
 
-		// Update the contractor's last request
-		contractor.LastRequests = append(contractor.LastRequests, types.LastRequest{ID: parsedRequestID, Timestamp: 0}) // TODO: should old requests be deleted when schedule changes?
-
-		// Update the contractor in the database
-		err = h.contractorsDB.UpdateContractor(contractor)
-		if err != nil {
-			h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to update contractor: %w", err))
+		// Only the sending is written, a timesheet collected meanwhile stays collected
+		err = h.timesheetRequestsDB.UpdatePendingTimesheetRequest(request)
+		if status.Code(err) == codes.FailedPrecondition {
+			continue
+		} else if err != nil {
+			h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to update timesheet request: %w", err))
 			continue
 		}
 	}
 }
 
-// AggregateTimesheet aggregates the timesheets from the email attachments.
-func (h *TimesheetsHandler) AggregateTimesheet(w http.ResponseWriter, r *http.Request) {
-	// Get the timesheet aggregation model from the request body
-	body, err := io.ReadAll(r.Body)
+// RemindTimesheets sends the reminders of the group's policy that are due, and the owner a digest of the timesheets missing for too long.
+// What was sent is recorded on the contractors, so it is never sent twice.
+func (h *TimesheetsHandler) RemindTimesheets(w http.ResponseWriter, r *http.Request) {
+	// Get the group ID from the query.
+	groupID := r.URL.Query().Get("groupID")
+	if groupID == "" {
+		http.Error(w, "groupID is required", http.StatusBadRequest)
+		return
+	}
+
+	group, err := h.groupsDB.GetGroup(groupID)
 	if err != nil {
-		h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to read request body: %w", err))
-		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to get group: %w", err))
+		return
+	}
+
+	policy := group.ReminderPolicy
+	if !policy.Enabled {
 		return
 	}
 
-	// Unmarshal the timesheet aggregation model
-	var timesheetAggregation types.TimesheetAggregation
-	err = json.Unmarshal(body, &timesheetAggregation)
+	// Get contractors from the database
+	contractors, err := h.contractorsDB.GetContractors(groupID)
 	if err != nil {
-		h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to unmarshal timesheet aggregation model: %w", err))
-		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to get contractors: %w", err))
 		return
 	}
 
-	emailSubject := fmt.Sprintf("Timesheet %s [%s]", strings.ReplaceAll(strings.ReplaceAll(timesheetAggregation.RequestID, "_", "/"), "-", " "), timesheetAggregation.Contractor.ID)
+	// Index the contractors, the requests refer to them by ID
+	contractorsByID := make(map[string]*types.Contractor, len(contractors))
+	for _, contractor := range contractors {
+		contractorsByID[contractor.ID] = contractor
+	}
 
-	// Get the attachments of the email
-	attachments, err := h.emailService.GetEmailAttachments(emailSubject)
+	requests, err := h.timesheetRequestsDB.ListTimesheetRequests(groupID)
 	if err != nil {
-		h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to get email attachments: %w", err))
-		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to list timesheet requests: %w", err))
 		return
 	}
 
-	// Parse the attachments and process the timesheets
-	for _, attachment := range attachments {
-		// Get the timesheet extension
-		extension := path.Ext(attachment.Filename)
+	now := time.Now()
 
-		// Save the timesheet to the storage
-		metadata := map[string]string{
-			"RequestID":    timesheetAggregation.RequestID,
-			"ContractorID": timesheetAggregation.Contractor.ID,
+	var missing []types.MissingTimesheet
+	var escalations []*types.TimesheetRequest
+
+	for _, request := range requests {
+		// Skip collected timesheets, and requests sent before reminders existed as their age is unknown
+		if request.Status != constants.Pending || request.SentAt.IsZero() {
+			continue
 		}
 
-		objectName := fmt.Sprintf("%s-%s_%s%s", timesheetAggregation.Contractor.Name, timesheetAggregation.Contractor.Surname, timesheetAggregation.RequestID, extension)
-		timesheetUrl, err := h.storageService.UploadFile(timesheetAggregation.Contractor.GroupID+"/"+objectName, attachment.Content, metadata)
-		if err != nil {
-			h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to upload timesheet to storage: %w", err))
-			http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
-			return
+		contractor, ok := contractorsByID[request.ContractorID]
+		if !ok {
+			continue
 		}
 
-		timesheet := &types.Timesheet{
-			ContractorID: timesheetAggregation.Contractor.ID,
-			RequestID:    timesheetAggregation.RequestID,
+		daysSinceRequest := int(now.Sub(request.SentAt).Hours() / 24)
 
-			StorageURL: timesheetUrl,
+		// Count the reminders that are due by now, only one is sent if several are due at once
+		dueReminders := 0
+		for _, days := range policy.ReminderDays {
+			if daysSinceRequest >= days {
+				dueReminders++
+			}
 		}
 
-		// Add the timesheet to the database
-		h.timesheetsDB.AddTimesheet(timesheet)
+		// The request itself is the first attempt
+		if dueReminders > request.Attempts-1 {
+			err = h.emailService.SendTimesheetReminderEmail(group, contractor, request)
+			if err != nil {
+				h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to send timesheet reminder email: %w", err))
+				continue
+			}
 
-		// Get contractor
-		contractor, err := h.contractorsDB.GetContractor(timesheetAggregation.Contractor.ID)
-		if err != nil {
-			h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to get contractor: %w", err))
-			http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
-			return
-		}
+			request.Attempts = dueReminders + 1
 
-		// Update contractors last request
-		for i, lastRequest := range contractor.LastRequests {
-			if lastRequest.ID == timesheetAggregation.RequestID {
-				contractor.LastRequests[i].Timestamp = time.Now().Unix()
+			// Only the reminder's fields are written, a timesheet collected meanwhile stays collected
+			err = h.timesheetRequestsDB.UpdatePendingTimesheetRequest(request)
+			if status.Code(err) == codes.FailedPrecondition {
+				continue
+			} else if err != nil {
+				h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to update timesheet request: %w", err))
+				continue
 			}
 		}
 
-		// Update the contractor in the database
-		err = h.contractorsDB.UpdateContractor(contractor)
-		if err != nil {
-			h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to update contractor: %w", err))
-			http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
-			return
+		if policy.EscalationDays > 0 && daysSinceRequest >= policy.EscalationDays && !request.Escalated {
+			missing = append(missing, types.MissingTimesheet{
+				Contractor: contractor,
+				RequestID:  request.RequestID,
+
+				DaysSinceRequest: daysSinceRequest,
+			})
+			escalations = append(escalations, request)
 		}
+	}
 
-		// Archive the email
-		err = h.emailService.ArchiveEmail(emailSubject)
-		if err != nil {
-			h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to archive email: %w", err))
-			http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
-			return
+	if len(missing) == 0 {
+		return
+	}
+
+	// Tell the owner who is still missing
+	owner, err := h.ownersDB.GetOwnerByID(group.OwnerID)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to get owner: %w", err))
+		return
+	}
+
+	err = h.emailService.SendMissingTimesheetsEmail(owner.Email, group, missing)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to send missing timesheets email: %w", err))
+		return
+	}
+
+	// Record the escalations only once the owner has the digest
+	for _, request := range escalations {
+		request.Escalated = true
+
+		err = h.timesheetRequestsDB.UpdatePendingTimesheetRequest(request)
+		if status.Code(err) == codes.FailedPrecondition {
+			continue
+		} else if err != nil {
+			h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to update timesheet request: %w", err))
+			continue
 		}
 	}
 }
 
-// getRequestID returns the request ID for the group based on the schedule.
-func getRequestID(group *types.Group) (string, error) {
+// ShowSubmitTimesheet shows the contractor the submission page of a signed link, with the timesheet already submitted for the request.
+func (h *TimesheetsHandler) ShowSubmitTimesheet(w http.ResponseWriter, r *http.Request) {
+	view, ok := h.getSubmission(w, r)
+	if !ok {
+		return
+	}
+
+	view.Submitted = r.URL.Query().Get("submitted") != ""
+	view.Duplicate = r.URL.Query().Get("submitted") == "duplicate"
+
+	h.showSubmission(w, r, http.StatusOK, view)
+}
+
+// DownloadSubmittedTimesheet sends the contractor of a signed link to a short-lived signed URL of the timesheet file submitted for the request.
+func (h *TimesheetsHandler) DownloadSubmittedTimesheet(w http.ResponseWriter, r *http.Request) {
+	view, ok := h.getSubmission(w, r)
+	if !ok {
+		return
+	}
+
+	if view.Timesheet == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	signedURL, err := h.storageService.SignedURL(view.Timesheet.StorageURL, constants.DownloadURLLifetimeMinutes*time.Minute)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to sign timesheet download URL: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	// The signed URL expires, the redirect must not be cached
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, signedURL, http.StatusFound)
+}
+
+// SubmitTimesheet saves the timesheet uploaded on the submission page, replacing the one submitted before unless it is approved.
+// The link is used up, the contractor is sent to the page of a new link to see the timesheet and replace it again.
+func (h *TimesheetsHandler) SubmitTimesheet(w http.ResponseWriter, r *http.Request) {
+	view, ok := h.getSubmission(w, r)
+	if !ok {
+		return
+	}
+
+	if !view.CanReplace {
+		view.ErrorMessage = "Your timesheet is already approved and can no longer be replaced."
+		h.showSubmission(w, r, http.StatusConflict, view)
+		return
+	}
 
-	// Parse the start and end dates from the Schedule
-	layout := "2006-01-02" // the layout string used for parsing
-	startDate, err := time.Parse(layout, group.Schedule.StartDate)
+	// Read the uploaded file
+	// The group's limit is checked with its other rules, the body may only be a little larger than the largest file a group allows
+	r.Body = http.MaxBytesReader(w, r.Body, (constants.AttachmentMaxSizeMB+1)<<20)
+	file, header, err := r.FormFile("timesheet")
 	if err != nil {
-		return "", fmt.Errorf("failed to parse start date: %w", err)
+		view.ErrorMessage = fmt.Sprintf("Choose a timesheet file of at most %d MB to upload.", constants.AttachmentMaxSizeMB)
+		h.showSubmission(w, r, http.StatusBadRequest, view)
+		return
 	}
+	defer file.Close()
 
-	endDate, err := time.Parse(layout, group.Schedule.EndDate)
+	content, err := io.ReadAll(file)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse end date: %w", err)
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to read uploaded timesheet: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
 	}
 
-	// Get the current time in the schedule's timezone
-	loc, err := time.LoadLocation(group.Schedule.Timezone)
+	// Save it the same way as the timesheets sent by email, a link is used once so a repeated upload through it is saved once
+	key := fmt.Sprintf("submission:%s/%s/%s", view.Contractor.ID, view.RequestID, view.nonce)
+	ingestion, err := h.timesheetIngestionService.SaveTimesheet(key, view.Contractor, view.RequestID, types.Attachment{Filename: header.Filename, Content: content})
+
+	var rejection *types.FileRejection
+	if errors.As(err, &rejection) {
+		view.ErrorMessage = fileRejectionMessage(rejection)
+		h.showSubmission(w, r, http.StatusBadRequest, view)
+		return
+	} else if status.Code(err) == codes.FailedPrecondition {
+		view.ErrorMessage = "Your timesheet is already approved and can no longer be replaced."
+		h.showSubmission(w, r, http.StatusConflict, view)
+		return
+	} else if err != nil {
+		h.errorReporterService.ReportError(w, r, err)
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	// Use up the link by replacing the nonce of the request
+	submissionLink, nonce, err := h.submissionLinkService.CreateSubmissionLink(view.Contractor.ID, view.RequestID)
 	if err != nil {
-		return "", fmt.Errorf("failed to load location: %w", err)
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to create submission link: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
 	}
-	now := time.Now().In(loc)
 
-	// Check if the current time is within the schedule's start and end dates
-	if now.Before(startDate) || now.After(endDate) {
-		return "", fmt.Errorf("current time is not within the schedule's start and end dates") // TODO: inform owner about it
+	request, err := h.timesheetRequestsDB.GetTimesheetRequest(view.Contractor.ID, view.RequestID)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to get timesheet request: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
 	}
 
-	// Get the current week and month number
-	currentYear := now.Year()
-	_, currentWeek := now.ISOWeek()
-	currentMonth := int(now.Month())
+	// Only the nonce of the link that was used is replaced, another upload through the same link meanwhile already used it up
+	request.SubmissionNonce = nonce
 
-	if group.Schedule.IntervalType == constants.Weeks {
-		if currentWeek%group.Schedule.Interval != 0 {
-			return "", fmt.Errorf("not the correct request time")
-		}
-		return fmt.Sprintf("%d/%d %d", max(0, currentWeek-1), currentWeek, currentYear), nil
-	} else if group.Schedule.IntervalType == constants.Months {
-		if currentMonth%group.Schedule.Interval != 0 {
-			return "", fmt.Errorf("not the correct request time")
-		}
-		return fmt.Sprintf("%d/%d %d", max(0, currentMonth-1), currentMonth, currentYear), nil
-	} else {
-		return "", fmt.Errorf("invalid interval type")
+	err = h.timesheetRequestsDB.ReplaceSubmissionNonce(request, view.nonce)
+	if status.Code(err) == codes.FailedPrecondition {
+		h.showSubmission(w, r, http.StatusGone, &submissionView{ErrorMessage: "This link was already used. Please use the link shown after your last upload."})
+		return
+	} else if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to update timesheet request: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	submitted := "1"
+	if ingestion.Duplicate {
+		submitted = "duplicate"
+	}
+
+	http.Redirect(w, r, submissionLink+"?submitted="+submitted, http.StatusSeeOther)
+}
+
+// fileRejectionMessage tells the contractor why an uploaded file was rejected, the way the email sent for a rejected attachment does.
+func fileRejectionMessage(rejection *types.FileRejection) string {
+	allowedTypes := make([]string, len(rejection.AllowedTypes))
+	for i, fileType := range rejection.AllowedTypes {
+		allowedTypes[i] = fileType.String()
+	}
+	allowed := fmt.Sprintf("Upload your timesheet as a %s file of at most %d MB.", strings.Join(allowedTypes, ", "), rejection.MaxSizeMB)
+
+	switch rejection.Reason {
+	case constants.FileTooLarge:
+		return fmt.Sprintf("The file is larger than %d MB. %s", rejection.MaxSizeMB, allowed)
+	case constants.ArchiveFile:
+		return "The file is an archive, upload the timesheet itself. " + allowed
+	case constants.ExecutableFile:
+		return "The file is a program or a script. " + allowed
+	case constants.MacroFile:
+		return "The file is a workbook with macros, save it as a plain XLSX workbook. " + allowed
+	case constants.MalwareFound:
+		return "Our virus scanner found malware in the file. " + allowed
+	default:
+		return "The file is not of a type we accept. " + allowed
+	}
+}
+
+// getSubmission verifies the link of the submission page and returns what the page shows, it shows an error page and returns false if the link is not valid.
+func (h *TimesheetsHandler) getSubmission(w http.ResponseWriter, r *http.Request) (*submissionView, bool) {
+	token := mux.Vars(r)["token"]
+
+	link, err := h.submissionLinkService.ParseSubmissionToken(token)
+	if err != nil {
+		h.showSubmission(w, r, http.StatusForbidden, &submissionView{ErrorMessage: "This link is not valid or has expired."})
+		return nil, false
+	}
+
+	contractor, err := h.contractorsDB.GetContractor(link.ContractorID)
+	if status.Code(err) == codes.NotFound {
+		h.showSubmission(w, r, http.StatusForbidden, &submissionView{ErrorMessage: "This link is not valid or has expired."})
+		return nil, false
+	} else if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to get contractor: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return nil, false
+	}
+
+	// The link is valid while its nonce is the one of the request
+	request, err := h.timesheetRequestsDB.GetTimesheetRequest(contractor.ID, link.RequestID)
+	if status.Code(err) == codes.NotFound {
+		h.showSubmission(w, r, http.StatusForbidden, &submissionView{ErrorMessage: "This link is not valid or has expired."})
+		return nil, false
+	} else if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to get timesheet request: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return nil, false
+	}
+
+	if request.SubmissionNonce != link.Nonce {
+		h.showSubmission(w, r, http.StatusGone, &submissionView{ErrorMessage: "This link was already used. Please use the link shown after your last upload."})
+		return nil, false
+	}
+
+	view := &submissionView{
+		Token:      token,
+		nonce:      link.Nonce,
+		Contractor: contractor,
+		RequestID:  link.RequestID,
+		CanReplace: true,
+	}
+
+	timesheet, err := h.timesheetsDB.GetTimesheet(contractor.ID, link.RequestID)
+	if status.Code(err) == codes.NotFound {
+		return view, true
+	} else if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to get timesheet: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return nil, false
+	}
+
+	view.Timesheet = timesheet
+	view.CanReplace = timesheet.Status != constants.Approved
+
+	view.Entries, err = h.timesheetEntriesDB.ListTimesheetEntries(timesheet.ID)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("failed to list timesheet entries: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return nil, false
+	}
+
+	return view, true
+}
+
+// showSubmission renders the submission page with the status code.
+func (h *TimesheetsHandler) showSubmission(w http.ResponseWriter, r *http.Request, statusCode int, view *submissionView) {
+	submitTmpl, err := h.templateService.ParseTemplate(constants.TemplateTimesheetSubmitName)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not parse submission template: %w", err))
+		http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+		return
+	}
+
+	w.WriteHeader(statusCode)
+
+	err = h.templateService.ExecuteTemplate(submitTmpl, w, r, view, nil)
+	if err != nil {
+		h.errorReporterService.ReportError(w, r, fmt.Errorf("could not execute template: %w", err))
 	}
 }
@@ -0,0 +1,598 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testToken is the token of contractor1's link for testRequestID.
+var testToken = submissionToken("contractor1", testRequestID, "nonce")
+
+// withoutRequests removes the requests of group1, as if none was sent yet.
+func withoutRequests(ts *testServer) {
+	ts.db.requests = slices.DeleteFunc(ts.db.requests, func(request *types.TimesheetRequest) bool { return request.GroupID == "group1" })
+}
+
+// sentDaysAgo makes contractor1's request sent days ago.
+func sentDaysAgo(days int) func(*testServer) {
+	return func(ts *testServer) {
+		ts.db.findTimesheetRequest("contractor1", testRequestID).SentAt = time.Now().AddDate(0, 0, -days)
+	}
+}
+
+// checkSent checks the emails sent.
+func checkSent(want ...string) func(*testing.T, *testServer) {
+	return func(t *testing.T, ts *testServer) {
+		if !slices.Equal(ts.email.sent, want) {
+			t.Errorf("sent = %v, want %v", ts.email.sent, want)
+		}
+	}
+}
+
+func TestTimesheetsHandlerRequestTimesheet(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name:       "request",
+			method:     "POST",
+			target:     "/timesheets/request?groupID=group1",
+			setup:      withoutRequests,
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, ts *testServer) {
+				checkSent(constants.EmailTemplateRequestName+" contractor1@example.com")(t, ts)
+
+				request := ts.request(t, "contractor1")
+				if request.Status != constants.Pending || request.Attempts != 1 || request.SubmissionNonce != "nonce1" || request.DueAt.Sub(request.SentAt).Hours() != 7*24 {
+					t.Errorf("request = %+v, want it pending after 1 attempt with nonce1, due in 7 days", request)
+				}
+				if request.PeriodStart.Format("2006-01-02") != "2025-01-01" || request.PeriodEnd.Format("2006-01-02") != "2025-01-31" {
+					t.Errorf("period = %v - %v, want 2025-01-01 - 2025-01-31", request.PeriodStart, request.PeriodEnd)
+				}
+			},
+		},
+		{
+			name:       "request sent before",
+			method:     "POST",
+			target:     "/timesheets/request?groupID=group1",
+			wantStatus: http.StatusOK,
+			check:      checkSent(),
+		},
+		{
+			name:   "no request today",
+			method: "POST",
+			target: "/timesheets/request?groupID=group1",
+			setup: func(ts *testServer) {
+				withoutRequests(ts)
+				ts.schedule.fail("OccurrenceOn", status.Error(codes.NotFound, "no occurrence"))
+			},
+			wantStatus: http.StatusOK,
+			check:      checkSent(),
+		},
+		{
+			name:        "no group",
+			method:      "POST",
+			target:      "/timesheets/request",
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "groupID is required",
+		},
+		{
+			name:         "group not read",
+			method:       "POST",
+			target:       "/timesheets/request?groupID=group1",
+			setup:        func(ts *testServer) { ts.db.fail("GetGroup", errFake) },
+			wantStatus:   http.StatusOK,
+			wantReported: true,
+		},
+		{
+			name:         "occurrence not read",
+			method:       "POST",
+			target:       "/timesheets/request?groupID=group1",
+			setup:        func(ts *testServer) { ts.schedule.fail("OccurrenceOn", errFake) },
+			wantStatus:   http.StatusOK,
+			wantReported: true,
+		},
+		{
+			name:         "contractors not read",
+			method:       "POST",
+			target:       "/timesheets/request?groupID=group1",
+			setup:        func(ts *testServer) { ts.db.fail("GetContractors", errFake) },
+			wantStatus:   http.StatusOK,
+			wantReported: true,
+		},
+		{
+			name:         "link not created",
+			method:       "POST",
+			target:       "/timesheets/request?groupID=group1",
+			setup:        func(ts *testServer) { withoutRequests(ts); ts.submissionLink.fail("CreateSubmissionLink", errFake) },
+			wantStatus:   http.StatusOK,
+			wantReported: true,
+			check:        checkSent(),
+		},
+		{
+			name:         "request email not sent",
+			method:       "POST",
+			target:       "/timesheets/request?groupID=group1",
+			setup:        func(ts *testServer) { withoutRequests(ts); ts.email.fail("SendTimesheetRequestEmail", errFake) },
+			wantStatus:   http.StatusOK,
+			wantReported: true,
+			check: func(t *testing.T, ts *testServer) {
+				if request := ts.request(t, "contractor1"); request.Attempts != 0 || !request.SentAt.IsZero() {
+					t.Errorf("request = %+v, want it stored unsent", request)
+				}
+			},
+		},
+		{
+			name:   "request stored without its email",
+			method: "POST",
+			target: "/timesheets/request?groupID=group1",
+			setup: func(ts *testServer) {
+				request := ts.db.findTimesheetRequest("contractor1", testRequestID)
+				request.Attempts = 0
+				request.SentAt = time.Time{}
+				request.DueAt = time.Time{}
+			},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, ts *testServer) {
+				checkSent(constants.EmailTemplateRequestName+" contractor1@example.com")(t, ts)
+
+				if request := ts.request(t, "contractor1"); request.Attempts != 1 || request.SentAt.IsZero() || request.SubmissionNonce != "nonce1" {
+					t.Errorf("request = %+v, want it sent once with nonce1", request)
+				}
+			},
+		},
+		{
+			name:         "request not stored",
+			method:       "POST",
+			target:       "/timesheets/request?groupID=group1",
+			setup:        func(ts *testServer) { withoutRequests(ts); ts.db.fail("AddTimesheetRequest", errFake) },
+			wantStatus:   http.StatusOK,
+			wantReported: true,
+			check:        checkSent(),
+		},
+		{
+			name:         "request not marked sent",
+			method:       "POST",
+			target:       "/timesheets/request?groupID=group1",
+			setup:        func(ts *testServer) { withoutRequests(ts); ts.db.fail("UpdatePendingTimesheetRequest", errFake) },
+			wantStatus:   http.StatusOK,
+			wantReported: true,
+			check:        checkSent(constants.EmailTemplateRequestName + " contractor1@example.com"),
+		},
+	})
+}
+
+func TestTimesheetsHandlerRemindTimesheets(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name:       "reminder",
+			method:     "POST",
+			target:     "/timesheets/remind?groupID=group1",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, ts *testServer) {
+				checkSent(constants.EmailTemplateReminderName+" contractor1@example.com")(t, ts)
+
+				if request := ts.request(t, "contractor1"); request.Attempts != 2 || request.Escalated {
+					t.Errorf("request = %+v, want 2 attempts, not escalated", request)
+				}
+			},
+		},
+		{
+			name:       "reminder sent before",
+			method:     "POST",
+			target:     "/timesheets/remind?groupID=group1",
+			setup:      func(ts *testServer) { ts.db.findTimesheetRequest("contractor1", testRequestID).Attempts = 2 },
+			wantStatus: http.StatusOK,
+			check:      checkSent(),
+		},
+		{
+			name:       "no reminder due",
+			method:     "POST",
+			target:     "/timesheets/remind?groupID=group1",
+			setup:      sentDaysAgo(1),
+			wantStatus: http.StatusOK,
+			check:      checkSent(),
+		},
+		{
+			name:   "timesheet collected",
+			method: "POST",
+			target: "/timesheets/remind?groupID=group1",
+			setup: func(ts *testServer) {
+				ts.db.findTimesheetRequest("contractor1", testRequestID).Status = constants.Collected
+			},
+			wantStatus: http.StatusOK,
+			check:      checkSent(),
+		},
+		{
+			name:       "reminders disabled",
+			method:     "POST",
+			target:     "/timesheets/remind?groupID=group1",
+			setup:      func(ts *testServer) { ts.db.groups["group1"].ReminderPolicy.Enabled = false },
+			wantStatus: http.StatusOK,
+			check:      checkSent(),
+		},
+		{
+			name:       "escalation",
+			method:     "POST",
+			target:     "/timesheets/remind?groupID=group1",
+			setup:      sentDaysAgo(6),
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, ts *testServer) {
+				checkSent(constants.EmailTemplateReminderName+" contractor1@example.com", constants.EmailTemplateMissingName+" owner1@example.com")(t, ts)
+
+				if request := ts.request(t, "contractor1"); request.Attempts != 2 || !request.Escalated {
+					t.Errorf("request = %+v, want 2 attempts, escalated", request)
+				}
+			},
+		},
+		{
+			name:   "escalated before",
+			method: "POST",
+			target: "/timesheets/remind?groupID=group1",
+			setup: func(ts *testServer) {
+				sentDaysAgo(6)(ts)
+				request := ts.db.findTimesheetRequest("contractor1", testRequestID)
+				request.Attempts = 2
+				request.Escalated = true
+			},
+			wantStatus: http.StatusOK,
+			check:      checkSent(),
+		},
+		{
+			name:        "no group",
+			method:      "POST",
+			target:      "/timesheets/remind",
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "groupID is required",
+		},
+		{
+			name:         "group not read",
+			method:       "POST",
+			target:       "/timesheets/remind?groupID=group1",
+			setup:        func(ts *testServer) { ts.db.fail("GetGroup", errFake) },
+			wantStatus:   http.StatusOK,
+			wantReported: true,
+		},
+		{
+			name:         "requests not listed",
+			method:       "POST",
+			target:       "/timesheets/remind?groupID=group1",
+			setup:        func(ts *testServer) { ts.db.fail("ListTimesheetRequests", errFake) },
+			wantStatus:   http.StatusOK,
+			wantReported: true,
+		},
+		{
+			name:         "reminder not sent",
+			method:       "POST",
+			target:       "/timesheets/remind?groupID=group1",
+			setup:        func(ts *testServer) { ts.email.fail("SendTimesheetReminderEmail", errFake) },
+			wantStatus:   http.StatusOK,
+			wantReported: true,
+			check: func(t *testing.T, ts *testServer) {
+				if request := ts.request(t, "contractor1"); request.Attempts != 1 {
+					t.Errorf("attempts = %d, want 1", request.Attempts)
+				}
+			},
+		},
+		{
+			name:         "owner not read",
+			method:       "POST",
+			target:       "/timesheets/remind?groupID=group1",
+			setup:        func(ts *testServer) { sentDaysAgo(6)(ts); ts.db.fail("GetOwnerByID", errFake) },
+			wantStatus:   http.StatusOK,
+			wantReported: true,
+		},
+		{
+			name:         "digest not sent",
+			method:       "POST",
+			target:       "/timesheets/remind?groupID=group1",
+			setup:        func(ts *testServer) { sentDaysAgo(6)(ts); ts.email.fail("SendMissingTimesheetsEmail", errFake) },
+			wantStatus:   http.StatusOK,
+			wantReported: true,
+			check: func(t *testing.T, ts *testServer) {
+				if request := ts.request(t, "contractor1"); request.Escalated {
+					t.Error("request escalated without the digest")
+				}
+			},
+		},
+	})
+}
+
+func TestTimesheetsHandlerSubmission(t *testing.T) {
+	submitted := constants.SubmissionLinkPath + submissionToken("contractor1", testRequestID, "nonce1")
+	file := "Date,Hours\n2025-01-06,8\n"
+	approved := func(ts *testServer) { ts.db.timesheets["timesheet1"].Status = constants.Approved }
+
+	runRouteTests(t, []routeTest{
+		{
+			name:         "page",
+			method:       "GET",
+			target:       constants.SubmissionLinkPath + testToken,
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateTimesheetSubmitName,
+			check: func(t *testing.T, ts *testServer) {
+				view := ts.templates.data.(*submissionView)
+				if view.Timesheet == nil || view.Timesheet.ID != "timesheet1" || len(view.Entries) != 2 || !view.CanReplace || view.Submitted {
+					t.Errorf("view = %+v, want timesheet1 with 2 entries, replaceable", view)
+				}
+			},
+		},
+		{
+			name:         "page after a duplicate upload",
+			method:       "GET",
+			target:       constants.SubmissionLinkPath + testToken + "?submitted=duplicate",
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateTimesheetSubmitName,
+			check: func(t *testing.T, ts *testServer) {
+				if view := ts.templates.data.(*submissionView); !view.Submitted || !view.Duplicate {
+					t.Errorf("Submitted, Duplicate = %v, %v, want true, true", view.Submitted, view.Duplicate)
+				}
+			},
+		},
+		{
+			name:         "page before a timesheet",
+			method:       "GET",
+			target:       constants.SubmissionLinkPath + testToken,
+			setup:        func(ts *testServer) { delete(ts.db.timesheets, "timesheet1") },
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateTimesheetSubmitName,
+			check: func(t *testing.T, ts *testServer) {
+				if view := ts.templates.data.(*submissionView); view.Timesheet != nil || !view.CanReplace {
+					t.Errorf("view = %+v, want no timesheet, replaceable", view)
+				}
+			},
+		},
+		{
+			name:         "page of an approved timesheet",
+			method:       "GET",
+			target:       constants.SubmissionLinkPath + testToken,
+			setup:        approved,
+			wantStatus:   http.StatusOK,
+			wantTemplate: constants.TemplateTimesheetSubmitName,
+			check: func(t *testing.T, ts *testServer) {
+				if view := ts.templates.data.(*submissionView); view.CanReplace {
+					t.Error("CanReplace = true, want false")
+				}
+			},
+		},
+		{
+			name:         "invalid link",
+			method:       "GET",
+			target:       constants.SubmissionLinkPath + "forged",
+			wantStatus:   http.StatusForbidden,
+			wantTemplate: constants.TemplateTimesheetSubmitName,
+			wantMessage:  "This link is not valid or has expired.",
+		},
+		{
+			name:         "link of a removed contractor",
+			method:       "GET",
+			target:       constants.SubmissionLinkPath + submissionToken("contractor3", testRequestID, "nonce"),
+			wantStatus:   http.StatusForbidden,
+			wantTemplate: constants.TemplateTimesheetSubmitName,
+			wantMessage:  "This link is not valid or has expired.",
+		},
+		{
+			name:         "link of an unknown request",
+			method:       "GET",
+			target:       constants.SubmissionLinkPath + submissionToken("contractor1", "2025-02-01_2025-02-28", "nonce"),
+			wantStatus:   http.StatusForbidden,
+			wantTemplate: constants.TemplateTimesheetSubmitName,
+			wantMessage:  "This link is not valid or has expired.",
+		},
+		{
+			name:         "used link",
+			method:       "GET",
+			target:       constants.SubmissionLinkPath + submissionToken("contractor1", testRequestID, "old"),
+			wantStatus:   http.StatusGone,
+			wantTemplate: constants.TemplateTimesheetSubmitName,
+			wantMessage:  "This link was already used.",
+		},
+		{
+			name:         "contractor not read",
+			method:       "GET",
+			target:       constants.SubmissionLinkPath + testToken,
+			setup:        func(ts *testServer) { ts.db.fail("GetContractor", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "request not read",
+			method:       "GET",
+			target:       constants.SubmissionLinkPath + testToken,
+			setup:        func(ts *testServer) { ts.db.fail("GetTimesheetRequest", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "timesheet not read",
+			method:       "GET",
+			target:       constants.SubmissionLinkPath + testToken,
+			setup:        func(ts *testServer) { ts.db.fail("GetTimesheet", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "entries not listed",
+			method:       "GET",
+			target:       constants.SubmissionLinkPath + testToken,
+			setup:        func(ts *testServer) { ts.db.fail("ListTimesheetEntries", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "page not parsed",
+			method:       "GET",
+			target:       constants.SubmissionLinkPath + testToken,
+			setup:        func(ts *testServer) { ts.templates.fail("ParseTemplate", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "upload",
+			method:       "POST",
+			target:       constants.SubmissionLinkPath + testToken,
+			file:         file,
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: submitted + "?submitted=1",
+			check: func(t *testing.T, ts *testServer) {
+				if want := []string{"contractor1 " + testRequestID + " timesheet.csv"}; !slices.Equal(ts.ingestion.saved, want) {
+					t.Errorf("saved = %v, want %v", ts.ingestion.saved, want)
+				}
+				if nonce := ts.request(t, "contractor1").SubmissionNonce; nonce != "nonce1" {
+					t.Errorf("nonce = %q, want the link used up for nonce1", nonce)
+				}
+			},
+		},
+		{
+			name:         "upload of the current file",
+			method:       "POST",
+			target:       constants.SubmissionLinkPath + testToken,
+			file:         file,
+			setup:        func(ts *testServer) { ts.ingestion.duplicates = true },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: submitted + "?submitted=duplicate",
+		},
+		{
+			name:         "upload through a used link",
+			method:       "POST",
+			target:       constants.SubmissionLinkPath + submissionToken("contractor1", testRequestID, "old"),
+			file:         file,
+			wantStatus:   http.StatusGone,
+			wantTemplate: constants.TemplateTimesheetSubmitName,
+			wantMessage:  "This link was already used.",
+			check: func(t *testing.T, ts *testServer) {
+				if len(ts.ingestion.saved) != 0 {
+					t.Errorf("saved = %v, want nothing", ts.ingestion.saved)
+				}
+			},
+		},
+		{
+			name:         "upload over an approved timesheet",
+			method:       "POST",
+			target:       constants.SubmissionLinkPath + testToken,
+			file:         file,
+			setup:        approved,
+			wantStatus:   http.StatusConflict,
+			wantTemplate: constants.TemplateTimesheetSubmitName,
+			wantMessage:  "already approved",
+		},
+		{
+			name:   "timesheet approved meanwhile",
+			method: "POST",
+			target: constants.SubmissionLinkPath + testToken,
+			file:   file,
+			setup: func(ts *testServer) {
+				ts.ingestion.fail("SaveTimesheet", status.Error(codes.FailedPrecondition, "timesheet approved"))
+			},
+			wantStatus:   http.StatusConflict,
+			wantTemplate: constants.TemplateTimesheetSubmitName,
+			wantMessage:  "already approved",
+		},
+		{
+			name:         "upload without a file",
+			method:       "POST",
+			target:       constants.SubmissionLinkPath + testToken,
+			wantStatus:   http.StatusBadRequest,
+			wantTemplate: constants.TemplateTimesheetSubmitName,
+			wantMessage:  "Choose a timesheet file of at most",
+		},
+		{
+			name:   "rejected file",
+			method: "POST",
+			target: constants.SubmissionLinkPath + testToken,
+			file:   file,
+			setup: func(ts *testServer) {
+				ts.ingestion.fail("SaveTimesheet", &types.FileRejection{Reason: constants.FileTooLarge, MaxSizeMB: 5, AllowedTypes: []constants.TimesheetFileTypes{constants.CsvFile}})
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantTemplate: constants.TemplateTimesheetSubmitName,
+			wantMessage:  "The file is larger than 5 MB. Upload your timesheet as a CSV file of at most 5 MB.",
+		},
+		{
+			name:         "timesheet not saved",
+			method:       "POST",
+			target:       constants.SubmissionLinkPath + testToken,
+			file:         file,
+			setup:        func(ts *testServer) { ts.ingestion.fail("SaveTimesheet", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "link not created",
+			method:       "POST",
+			target:       constants.SubmissionLinkPath + testToken,
+			file:         file,
+			setup:        func(ts *testServer) { ts.submissionLink.fail("CreateSubmissionLink", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:         "link not used up",
+			method:       "POST",
+			target:       constants.SubmissionLinkPath + testToken,
+			file:         file,
+			setup:        func(ts *testServer) { ts.db.fail("ReplaceSubmissionNonce", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+		{
+			name:   "link used by another upload meanwhile",
+			method: "POST",
+			target: constants.SubmissionLinkPath + testToken,
+			file:   file,
+			setup: func(ts *testServer) {
+				ts.db.fail("ReplaceSubmissionNonce", status.Error(codes.FailedPrecondition, "link already used"))
+			},
+			wantStatus:   http.StatusGone,
+			wantTemplate: constants.TemplateTimesheetSubmitName,
+			wantMessage:  "This link was already used.",
+		},
+		{
+			name:         "download",
+			method:       "GET",
+			target:       constants.SubmissionLinkPath + testToken + "/download",
+			wantStatus:   http.StatusFound,
+			wantLocation: "https://storage.example.com/bucket/group1/v2.csv?signed",
+			wantMessage:  "Found",
+		},
+		{
+			name:        "download before a timesheet",
+			method:      "GET",
+			target:      constants.SubmissionLinkPath + testToken + "/download",
+			setup:       func(ts *testServer) { delete(ts.db.timesheets, "timesheet1") },
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "404 page not found",
+		},
+		{
+			name:         "download through an invalid link",
+			method:       "GET",
+			target:       constants.SubmissionLinkPath + "forged/download",
+			wantStatus:   http.StatusForbidden,
+			wantTemplate: constants.TemplateTimesheetSubmitName,
+			wantMessage:  "This link is not valid or has expired.",
+		},
+		{
+			name:         "download URL not signed",
+			method:       "GET",
+			target:       constants.SubmissionLinkPath + testToken + "/download",
+			setup:        func(ts *testServer) { ts.storage.fail("SignedURL", errFake) },
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/somethingWentWrong",
+			wantReported: true,
+		},
+	})
+}
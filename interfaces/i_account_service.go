@@ -0,0 +1,27 @@
+package interfaces
+
+import (
+	"job_sender/types"
+)
+
+// IAccountService is an interface for a service that resets the passwords and changes the emails of the accounts through signed links sent by email.
+// A link expires and works once, and the sessions of the account are revoked once it is used.
+type IAccountService interface {
+	// SendPasswordResetLink emails a password reset link to the account of an email, nothing is sent if there is none.
+	SendPasswordResetLink(email string) error
+
+	// CheckPasswordResetToken verifies the token of a password reset link, it returns a PermissionDenied status if it is forged, expired or used.
+	CheckPasswordResetToken(token string) (*types.AccountLink, error)
+
+	// ResetPassword sets the password of the account of a password reset link and revokes its sessions.
+	ResetPassword(token string, password string) error
+
+	// SendEmailChangeLink emails the link confirming a new email of a user to the new address, it returns an AlreadyExists status if an account has it.
+	SendEmailChangeLink(userID string, email string) error
+
+	// ChangeEmail sets the email of the account of an email change link, on the account and its owner, and revokes its sessions.
+	ChangeEmail(token string) (*types.AccountLink, error)
+
+	// DeleteAccount logs a user out everywhere and deletes the account, then its owner. A failure leaves the owner, so the deletion can be retried.
+	DeleteAccount(userID string) error
+}
@@ -0,0 +1,14 @@
+package interfaces
+
+import (
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+)
+
+// IAttachmentValidationService is an interface for a service that checks the timesheet files contractors send before they are stored.
+type IAttachmentValidationService interface {
+	// ValidateAttachment checks a timesheet file against the attachment policy of a group: its size, its type sniffed from the content, archives,
+	// executables and workbooks with macros, and the malware scanner if there is one. It returns the type of the file, and a *types.FileRejection
+	// error, with the InvalidArgument status, if the group does not accept it.
+	ValidateAttachment(policy *types.AttachmentPolicy, attachment types.Attachment) (constants.TimesheetFileTypes, error)
+}
@@ -13,6 +13,13 @@ type IAuthService interface {
 	// Login logs in a user.
 	Login(email string, password string) (*types.LoginResponseBody, error)
 
-	// CheckUser returns the user info
+	// StartSession keeps the tokens of a login server-side and creates the session cookie of the user.
+	// The cookie of a remembered login lasts as long as the login, the others are dropped when the browser closes.
+	StartSession(w http.ResponseWriter, r *http.Request, responseBody *types.LoginResponseBody, isVerified bool, rememberMe bool) error
+
+	// EndSession deletes the session of the request and its cookie.
+	EndSession(w http.ResponseWriter, r *http.Request) error
+
+	// CheckUser returns the user info, the ID token of the login is refreshed before it expires.
 	CheckUser(r *http.Request) (*types.LoggedUserInfo, error)
 }
@@ -0,0 +1,26 @@
+package interfaces
+
+import (
+	"net/http"
+
+	"job_sender/types"
+)
+
+// IAuthorizationService is an interface for a service that resolves the logged owner of a request and gets only the entities the owner owns.
+// An entity of another owner is reported with a NotFound status, the same as one that does not exist.
+type IAuthorizationService interface {
+	// GetLoggedOwnerID returns the ID of the owner logged in the session, it returns an Unauthenticated status if there is none.
+	GetLoggedOwnerID(r *http.Request) (string, error)
+
+	// AuthorizeOwner checks that an owner ID is the logged owner's.
+	AuthorizeOwner(r *http.Request, ownerID string) error
+
+	// GetOwnedGroup gets a group of the logged owner.
+	GetOwnedGroup(r *http.Request, groupID string) (*types.Group, error)
+
+	// GetOwnedContractor gets a contractor of a group of the logged owner.
+	GetOwnedContractor(r *http.Request, contractorID string) (*types.Contractor, error)
+
+	// GetOwnedTimesheet gets a timesheet of a group of the logged owner.
+	GetOwnedTimesheet(r *http.Request, timesheetID string) (*types.Timesheet, error)
+}
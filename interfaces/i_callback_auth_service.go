@@ -0,0 +1,15 @@
+package interfaces
+
+import (
+	"net/http"
+)
+
+// ICallbackAuthService is an interface for a service that authenticates the machines calling the internal routes back, like the scheduler jobs.
+type ICallbackAuthService interface {
+	// VerifyCallback checks the OIDC token or the signature of a request and returns who sent it.
+	// It returns an Unauthenticated status if the request has neither or they are not valid, and a PermissionDenied status if its sender is not allowed.
+	VerifyCallback(r *http.Request) (string, error)
+
+	// SignCallback signs a request with the key signed callbacks are verified with, it returns a FailedPrecondition status if there is none.
+	SignCallback(r *http.Request) error
+}
@@ -9,6 +9,12 @@ type IContractorsDatabaseService interface {
 	// GetContractors lists all for a group.
 	GetContractors(groupID string) ([]*types.Contractor, error)
 
+	// GetAllContractors lists the contractors of every group.
+	GetAllContractors() ([]*types.Contractor, error)
+
+	// GetContractorsByEmail lists the contractors with an email address, in every group.
+	GetContractorsByEmail(email string) ([]*types.Contractor, error)
+
 	// GetContractor gets a contractor by ID.
 	GetContractor(id string) (*types.Contractor, error)
 
@@ -18,6 +24,6 @@ type IContractorsDatabaseService interface {
 	// UpdateContractor updates a contractor.
 	UpdateContractor(contractor *types.Contractor) error
 
-	// DeleteContractor deletes a contractor.
+	// DeleteContractor deletes a contractor with its timesheet requests.
 	DeleteContractor(id string) error
 }
@@ -8,15 +8,49 @@ type IEmailService interface {
 	// SendVerificationEmail sends a verification email to the user.
 	SendVerificationEmail(email string, link string) error
 
-	// SendTimsheetRequestEmail sends a timesheet request email to the contractor.
-	SendTimesheetRequestEmail(contractor *types.Contractor, weekID string) error
+	// SendTimsheetRequestEmail sends a timesheet request email to the contractor, with the link to submit the timesheet on if there is one.
+	// The emails to a contractor are in the contractor's language, with the branding of the group.
+	// The group's email content replaces the default wording of the request and reminder emails.
+	// The emails about a request carry its reply key, a request without one gets a new key the caller stores with it.
+	SendTimesheetRequestEmail(group *types.Group, contractor *types.Contractor, request *types.TimesheetRequest, submissionLink string) error
+
+	// PreviewTimesheetRequestEmail renders the timesheet request email the contractor would get, without sending it.
+	PreviewTimesheetRequestEmail(group *types.Group, contractor *types.Contractor, request *types.TimesheetRequest, submissionLink string) (*types.RenderedEmail, error)
+
+	// SendTimesheetRejectionEmail asks the contractor for a corrected timesheet, the reply is matched to the request the same way as the replies to the request.
+	SendTimesheetRejectionEmail(group *types.Group, contractor *types.Contractor, request *types.TimesheetRequest, reason string) error
+
+	// SendFileRejectionEmail tells the contractor why a timesheet file sent for a request was rejected, the reply is matched to the request
+	// the same way as the replies to the request.
+	SendFileRejectionEmail(group *types.Group, contractor *types.Contractor, request *types.TimesheetRequest, rejection *types.FileRejection) error
+
+	// SendTimesheetReminderEmail reminds the contractor about a missing timesheet, the reply is matched to the request the same way as the replies to the request.
+	SendTimesheetReminderEmail(group *types.Group, contractor *types.Contractor, request *types.TimesheetRequest) error
+
+	// PreviewTimesheetReminderEmail renders the reminder email the contractor would get, without sending it.
+	PreviewTimesheetReminderEmail(group *types.Group, contractor *types.Contractor, request *types.TimesheetRequest) (*types.RenderedEmail, error)
+
+	// SendMissingTimesheetsEmail sends the owner a digest of the timesheets still missing in a group.
+	SendMissingTimesheetsEmail(to string, group *types.Group, missing []types.MissingTimesheet) error
 
 	// SendPasswordResetEmail sends a password reset email to the user.
-	// TODO: Implement this method.
+	SendPasswordResetEmail(email string, link string) error
+
+	// SendEmailChangeEmail sends the link confirming the new email of a user to the new address.
+	SendEmailChangeEmail(email string, link string) error
+
+	// GetUnassignedEmails returns the emails of the unassigned mailbox, the ones the inbox watcher could not match to a request.
+	GetUnassignedEmails() ([]*types.InboundEmail, error)
+
+	// GetUnassignedEmail returns the email of the unassigned mailbox an unassigned email was triaged from, it returns a NotFound status if it is not there.
+	GetUnassignedEmail(unassigned *types.UnassignedEmail) (*types.InboundEmail, error)
+
+	// ArchiveUnassignedEmail removes the email with the given UID from the unassigned mailbox, once it is assigned or discarded.
+	ArchiveUnassignedEmail(uid uint32) error
 
-	// GetEmailAttachments returns the attachments of an email.
-	GetEmailAttachments(subject string) ([]types.Attachment, error)
+	// SendReplyEmail replies to an inbound email in its thread, on behalf of the group.
+	SendReplyEmail(group *types.Group, email *types.InboundEmail, body string) error
 
-	// ArchiveEmails archives emails with the specified subject.
-	ArchiveEmail(subject string) error
+	// SendUnknownSenderEmail replies to an inbound email attributed to no group, asking the sender to answer the request email they got instead.
+	SendUnknownSenderEmail(email *types.InboundEmail) error
 }
@@ -0,0 +1,17 @@
+package interfaces
+
+import (
+	"job_sender/types"
+)
+
+// IEmailTemplateService is an interface for a service that renders the emails the app sends.
+type IEmailTemplateService interface {
+	// RenderEmail renders an email template in a language, falling back to English, with the branding of the group, nil for none.
+	RenderEmail(name string, language string, group *types.Group, data interface{}) (*types.RenderedEmail, error)
+
+	// RenderEmailContent renders a group's email content with the placeholders, it returns an InvalidArgument status if the content is not a valid template of them.
+	RenderEmailContent(content string, placeholders *types.EmailPlaceholders) (string, error)
+
+	// ValidateEmailContent checks each field of a group's email content renders with the placeholders, it returns an InvalidArgument status naming the field that does not.
+	ValidateEmailContent(content *types.EmailContent) error
+}
@@ -1,9 +1,7 @@
 package interfaces
 
 import (
-	"context"
-
-	"firebase.google.com/go/auth"
+	"job_sender/types"
 )
 
 type IFirebaseService interface {
@@ -16,6 +14,25 @@ type IFirebaseService interface {
 	// GetCustomClaims retrieves custom claims from Firebase.
 	GetCustomClaims(idToken string) (map[string]interface{}, error)
 
-	// Auth is a method that returns the Firebase Auth client.
-	Auth(ctx context.Context) (*auth.Client, error)
+	// EmailVerificationLink generates the link that verifies the email of a user.
+	EmailVerificationLink(email string) (string, error)
+
+	// GetUser gets a user by ID, it returns a NotFound status if there is none.
+	GetUser(id string) (*types.AuthUser, error)
+
+	// GetUserByEmail gets a user by email, it returns a NotFound status if there is none.
+	GetUserByEmail(email string) (*types.AuthUser, error)
+
+	// UpdatePassword sets the password of a user.
+	UpdatePassword(id string, password string) error
+
+	// UpdateEmail sets the email of a user, the address is verified as the user proved to own it.
+	UpdateEmail(id string, email string) error
+
+	// RevokeSessions revokes the ID tokens issued to a user until now, the sessions holding them are logged out.
+	// It returns a NotFound status if there is no such user.
+	RevokeSessions(id string) error
+
+	// DeleteUser deletes a user, a user that does not exist is already deleted.
+	DeleteUser(id string) error
 }
@@ -9,6 +9,9 @@ type IGroupsDatabaseService interface {
 	// GetGroup gets a group by ID.
 	GetGroup(id string) (*types.Group, error)
 
+	// GetAllGroups lists the groups of every owner.
+	GetAllGroups() ([]*types.Group, error)
+
 	// AddGroup adds a group.
 	AddGroup(group *types.Group) (*types.Group, error)
 
@@ -0,0 +1,22 @@
+package interfaces
+
+import (
+	"time"
+
+	"job_sender/types"
+)
+
+// IHolidayCalendarService is an interface for a service that knows the holidays and working days of the bundled holiday calendars.
+type IHolidayCalendarService interface {
+	// ListHolidayCalendars lists the bundled holiday calendars.
+	ListHolidayCalendars() []*types.HolidayCalendar
+
+	// GetHolidays returns the holidays of a calendar in a year, it returns a NotFound status if there is no such calendar.
+	GetHolidays(calendar string, year int) ([]*types.Holiday, error)
+
+	// IsWorkingDay reports whether a date is a weekday that is not a holiday of the calendar, an empty calendar has no holidays.
+	IsWorkingDay(calendar string, date time.Time) (bool, error)
+
+	// CountWorkingDays counts the working days of the calendar from start to end, both included.
+	CountWorkingDays(calendar string, start time.Time, end time.Time) (int, error)
+}
@@ -0,0 +1,11 @@
+package interfaces
+
+import (
+	"context"
+)
+
+// IInboxWatcherService is an interface for a service that processes the emails of the inbox as they arrive.
+type IInboxWatcherService interface {
+	// Watch processes the unseen emails of the inbox and then each new one, until the context is done.
+	Watch(ctx context.Context)
+}
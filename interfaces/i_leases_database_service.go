@@ -0,0 +1,14 @@
+package interfaces
+
+import (
+	"time"
+
+	"job_sender/types"
+)
+
+// ILeasesDatabaseService is an interface for a database service that keeps the leases electing the instance that runs a background job.
+type ILeasesDatabaseService interface {
+	// AcquireLease takes the lease with the given name for a holder until the given duration has passed, or renews it if the holder has it.
+	// It returns a FailedPrecondition status if another holder has the lease and it has not expired.
+	AcquireLease(name string, holder string, duration time.Duration) (*types.Lease, error)
+}
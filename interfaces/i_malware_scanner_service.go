@@ -0,0 +1,7 @@
+package interfaces
+
+// IMalwareScannerService is an interface for a service that scans the files contractors send for malware.
+type IMalwareScannerService interface {
+	// ScanFile scans a file, it returns the name of the malware found, or an empty name if the file is clean.
+	ScanFile(content []byte) (string, error)
+}
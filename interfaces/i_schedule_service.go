@@ -0,0 +1,23 @@
+package interfaces
+
+import (
+	"time"
+
+	"job_sender/types"
+)
+
+// IScheduleService is an interface for a service that computes when a schedule sends timesheet requests and the periods they cover.
+type IScheduleService interface {
+	// NextOccurrences returns up to n occurrences of the schedule after the given time, moved to working days of the holiday calendar if one is given.
+	NextOccurrences(schedule *types.Schedule, holidayCalendar string, after time.Time, n int) ([]*types.ScheduleOccurrence, error)
+
+	// OccurrenceOn returns the occurrence of the schedule sent on the day of the given time in the schedule's timezone, it returns a NotFound status if there is none.
+	OccurrenceOn(schedule *types.Schedule, holidayCalendar string, t time.Time) (*types.ScheduleOccurrence, error)
+
+	// RequestPeriod returns the first and last day of the period of a request ID, it returns an InvalidArgument status for the request IDs made before schedules had periods.
+	RequestPeriod(requestID string) (time.Time, time.Time, error)
+
+	// RequestDueAt returns when the timesheet of a request sent at the given time is due, on the first reminder of the policy,
+	// or when the submission link expires if the policy sends no reminders.
+	RequestDueAt(policy *types.ReminderPolicy, sentAt time.Time) time.Time
+}
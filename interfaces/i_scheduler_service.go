@@ -11,4 +11,13 @@ type ISchedulerService interface {
 
 	// DeleteTimesheetRequestJob deletes a Cloud Scheduler job for requesting timesheets.
 	DeleteTimesheetRequestJob(groupID string) error
+
+	// CreateTimesheetReminderJob creates a new Cloud Scheduler job that sends the due reminders daily at the schedule's time.
+	CreateTimesheetReminderJob(groupID string, schedule *types.Schedule) error
+
+	// EditTimesheetReminderJob updates a Cloud Scheduler job for sending reminders, creating it for groups made before reminders existed.
+	EditTimesheetReminderJob(groupID string, schedule *types.Schedule) error
+
+	// DeleteTimesheetReminderJob deletes a Cloud Scheduler job for sending reminders, if there is one.
+	DeleteTimesheetReminderJob(groupID string) error
 }
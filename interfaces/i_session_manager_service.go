@@ -1,25 +1,47 @@
 package interfaces
 
 import (
+	"context"
 	"net/http"
 	"time"
 
+	"job_sender/types"
+
 	"github.com/gorilla/sessions"
 )
 
 type ISessionManagerService interface {
-	// CreateSession creates a new session and stores key-value pairs.
-	CreateSession(w http.ResponseWriter, r *http.Request, sessionID string, tokenExpirationTimestamp time.Time, data map[string]interface{}) (*sessions.Session, error)
+	// CreateSession creates a new session and stores key-value pairs, a session of the request with the same name is replaced.
+	// The session lasts until expiresAt, its cookie too if persistent, otherwise the cookie is dropped when the browser closes.
+	CreateSession(w http.ResponseWriter, r *http.Request, sessionName string, expiresAt time.Time, persistent bool, data map[string]interface{}) (*sessions.Session, error)
 
-	// DeleteSession deletes a session.
-	DeleteSession(w http.ResponseWriter, r *http.Request, sessionID string) error
+	// DeleteSession deletes a session and its cookie.
+	DeleteSession(w http.ResponseWriter, r *http.Request, sessionName string) error
 
 	// CheckSession checks if a session exists.
-	CheckSession(r *http.Request, sessionID string) bool
+	CheckSession(r *http.Request, sessionName string) bool
 
 	// GetElement retrieves an element from a session.
-	GetElement(r *http.Request, sessionID string, key string) (interface{}, error)
+	GetElement(r *http.Request, sessionName string, key string) (interface{}, error)
 
 	// SetElement sets an element in a session.
-	SetElement(w http.ResponseWriter, r *http.Request, sessionID string, key string, value interface{}) error
+	SetElement(w http.ResponseWriter, r *http.Request, sessionName string, key string, value interface{}) error
+
+	// UpdateElements sets elements of a stored session without writing its cookie, a session that is not stored is left as it is.
+	UpdateElements(r *http.Request, sessionName string, data map[string]interface{}) error
+
+	// GetSessionID returns the ID a session of the request is stored under, or an empty string if it is not stored.
+	GetSessionID(r *http.Request, sessionName string) (string, error)
+
+	// GetUserSessions gets the sessions of a user that have not expired, the last seen first.
+	GetUserSessions(userID string) ([]*types.Session, error)
+
+	// DeleteUserSession deletes a session of a user, it returns a NotFound status if the user has no such session.
+	DeleteUserSession(userID string, id string) error
+
+	// DeleteUserSessions deletes every session of a user, they are all logged out.
+	DeleteUserSessions(userID string) error
+
+	// ExpireSessions deletes the expired sessions every hour, until the context is done.
+	ExpireSessions(ctx context.Context)
 }
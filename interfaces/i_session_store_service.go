@@ -0,0 +1,31 @@
+package interfaces
+
+import (
+	"time"
+
+	"job_sender/types"
+)
+
+// ISessionStoreService is an interface for the store the sessions are kept in server-side.
+type ISessionStoreService interface {
+	// GetSession gets a session by ID, it returns a NotFound status if there is none or it has expired.
+	GetSession(id string) (*types.Session, error)
+
+	// SaveSession creates or overwrites a session.
+	SaveSession(session *types.Session) error
+
+	// UpdateSessionLastSeen records the time, user agent and IP address of the last request of a session, its values are left as they are.
+	UpdateSessionLastSeen(id string, lastSeenAt time.Time, userAgent string, ipAddress string) error
+
+	// DeleteSession deletes a session, deleting a missing one is not an error.
+	DeleteSession(id string) error
+
+	// GetUserSessions gets the sessions of a user that have not expired, the last seen first.
+	GetUserSessions(userID string) ([]*types.Session, error)
+
+	// DeleteUserSessions deletes every session of a user.
+	DeleteUserSessions(userID string) error
+
+	// DeleteExpiredSessions deletes the sessions past their expiry.
+	DeleteExpiredSessions() error
+}
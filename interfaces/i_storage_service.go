@@ -1,8 +1,18 @@
 package interfaces
 
+import (
+	"time"
+)
+
 type IStorageService interface {
-	// UploadFile uploads a file to a storage bucket.
-	UploadFile(objectName string, data []byte, metadata map[string]string) (string, error)
+	// UploadFile uploads a private file to a storage bucket and returns its URL, it is served with the content type as a download through a signed URL.
+	UploadFile(objectName string, data []byte, contentType string, metadata map[string]string) (string, error)
+
+	// SignedURL returns a URL that downloads the file at fileURL, as returned by UploadFile, until it expires.
+	SignedURL(fileURL string, expiry time.Duration) (string, error)
+
+	// MakeFilesPrivate removes the public read access from the files with the given prefix name, it returns how many files were public.
+	MakeFilesPrivate(prefixName string) (int, error)
 
 	// DeleteFiles deletes files with the given prefix name.
 	DeleteFiles(prefixName string) error
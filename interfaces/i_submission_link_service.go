@@ -0,0 +1,14 @@
+package interfaces
+
+import (
+	"job_sender/types"
+)
+
+// ISubmissionLinkService is an interface for a service that signs and verifies the links contractors submit their timesheets with.
+type ISubmissionLinkService interface {
+	// CreateSubmissionLink returns a signed link to the submission page of a contractor's request, with the nonce that has to be stored on the request for the link to be valid.
+	CreateSubmissionLink(contractorID string, requestID string) (string, string, error)
+
+	// ParseSubmissionToken verifies the token of a submission link, it returns a PermissionDenied status if it is forged or expired.
+	ParseSubmissionToken(token string) (*types.SubmissionLink, error)
+}
@@ -0,0 +1,14 @@
+package interfaces
+
+import (
+	"job_sender/types"
+)
+
+// ITimesheetAuditLogDatabaseService is an interface for an append-only database service that records the status changes of timesheets.
+type ITimesheetAuditLogDatabaseService interface {
+	// ListAuditEntries lists the audit entries of a timesheet from the oldest.
+	ListAuditEntries(timesheetID string) ([]*types.TimesheetAuditEntry, error)
+
+	// AddAuditEntry adds an audit entry.
+	AddAuditEntry(entry *types.TimesheetAuditEntry) error
+}
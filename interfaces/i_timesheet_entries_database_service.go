@@ -0,0 +1,17 @@
+package interfaces
+
+import (
+	"job_sender/types"
+)
+
+// ITimesheetEntriesDatabaseService is an interface for a database service that manages timesheet entries.
+type ITimesheetEntriesDatabaseService interface {
+	// ListTimesheetEntries lists all entries of a timesheet ordered by date.
+	ListTimesheetEntries(timesheetID string) ([]*types.TimesheetEntry, error)
+
+	// AddTimesheetEntries adds the entries of a timesheet.
+	AddTimesheetEntries(entries []*types.TimesheetEntry) error
+
+	// DeleteTimesheetEntries deletes all entries of a timesheet.
+	DeleteTimesheetEntries(timesheetID string) error
+}
@@ -0,0 +1,36 @@
+package interfaces
+
+import (
+	"job_sender/types"
+)
+
+// ITimesheetIngestionService is an interface for a service that saves the timesheets contractors send, by email or on the submission page.
+type ITimesheetIngestionService interface {
+	// IngestEmail saves the attachment of a reply to a timesheet request as the timesheet of the request, an email is saved only once.
+	// It returns a NotFound status if the email is not a reply to a request, and an InvalidArgument status unless it has exactly one attachment
+	// or if it was sent from another address than the contractor's, the timesheet of the request is already approved, or the file is one the group
+	// does not accept. The contractor is told by a reply why such a file is rejected.
+	IngestEmail(email *types.InboundEmail) error
+
+	// UnassignEmail saves why an email could not be ingested, with the group, the contractor who likely sent it and the request it matches, for the owner
+	// of the group to assign it, and reports whether the email is left for the owner. The sender of an email attributed to no group is told by a reply
+	// to answer the request email instead, unless a program sent it.
+	UnassignEmail(email *types.InboundEmail) (bool, error)
+
+	// MatchEmail finds the contractor and the ID of the request an email replies to, by its headers, the address it was sent to, its subject or its sender.
+	// It returns a NotFound status if the email matches no request, and the contractor and the request with a PermissionDenied status
+	// if the email matches a request by its headers, its address or its subject but was sent from another address than the contractor's.
+	MatchEmail(email *types.InboundEmail) (*types.Contractor, string, error)
+
+	// SaveEmailTimesheet saves an attachment of an email as the timesheet of a contractor's request, an email is saved only once.
+	// It returns the ingestion of the email, a FailedPrecondition status if the timesheet of the request is already approved, and a *types.FileRejection
+	// error if the file is one the group does not accept.
+	SaveEmailTimesheet(email *types.InboundEmail, contractor *types.Contractor, requestID string, attachment int) (*types.TimesheetIngestion, error)
+
+	// SaveTimesheet uploads a timesheet file of a contractor's request and saves it with its entries, marking the request collected, in one transaction.
+	// A file sent after a rejection resubmits the rejected timesheet, and any other timesheet not approved yet is replaced, each file becoming the next version.
+	// The current file sent again is a duplicate, only noted in the audit log. The key identifies the submission, e.g. the Message-ID of an email, a submission
+	// already saved is not saved again. It returns the ingestion of the submission, a FailedPrecondition status if the timesheet is already approved,
+	// and a *types.FileRejection error, with the InvalidArgument status, if the file is one the group does not accept.
+	SaveTimesheet(key string, contractor *types.Contractor, requestID string, attachment types.Attachment) (*types.TimesheetIngestion, error)
+}
@@ -0,0 +1,23 @@
+package interfaces
+
+import (
+	"time"
+
+	"job_sender/types"
+)
+
+// ITimesheetIngestionsDatabaseService is an interface for a database service that saves the submitted timesheets, each submission once.
+type ITimesheetIngestionsDatabaseService interface {
+	// GetTimesheetIngestion gets the ingestion of a submission by the ID of its key, it returns a NotFound status if the submission was not saved.
+	GetTimesheetIngestion(id string) (*types.TimesheetIngestion, error)
+
+	// CommitTimesheetIngestion saves all a submission changes in one transaction: the timesheet, added if it has no ID, its new versions, its entries
+	// replacing the old ones, the audit entry, the request of the timesheetRequestID marked collected and the ingestion. Only the status and the collection time
+	// of the request are written, the other fields are left as they are. It returns an AlreadyExists status if the submission was saved meanwhile,
+	// an Aborted status if a timesheet of the request was added or got another version meanwhile, and a FailedPrecondition status if it was approved meanwhile.
+	CommitTimesheetIngestion(ingestion *types.TimesheetIngestion, timesheet *types.Timesheet, versions []*types.TimesheetVersion, entries []*types.TimesheetEntry, auditEntry *types.TimesheetAuditEntry, timesheetRequestID string, collectedAt time.Time) error
+
+	// CommitDuplicateTimesheetIngestion saves a submission of the current file of a timesheet, only the ingestion and the audit entry noting it, in one transaction.
+	// It returns an AlreadyExists status if the submission was saved meanwhile.
+	CommitDuplicateTimesheetIngestion(ingestion *types.TimesheetIngestion, auditEntry *types.TimesheetAuditEntry) error
+}
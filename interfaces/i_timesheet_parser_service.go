@@ -0,0 +1,11 @@
+package interfaces
+
+import (
+	"job_sender/types"
+)
+
+// ITimesheetParserService is an interface for a service that extracts the worked days from timesheet files.
+type ITimesheetParserService interface {
+	// ParseTimesheet parses a CSV, XLSX or PDF timesheet into its entries, the format is detected from the filename and the content.
+	ParseTimesheet(filename string, content []byte) ([]*types.TimesheetEntry, error)
+}
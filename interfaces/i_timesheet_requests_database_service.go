@@ -0,0 +1,35 @@
+package interfaces
+
+import (
+	"job_sender/types"
+)
+
+// ITimesheetRequestsDatabaseService is an interface for a database service that manages the timesheet requests sent to the contractors.
+type ITimesheetRequestsDatabaseService interface {
+	// ListTimesheetRequests lists the timesheet requests of a group.
+	ListTimesheetRequests(groupID string) ([]*types.TimesheetRequest, error)
+
+	// ListContractorTimesheetRequests lists the timesheet requests of a contractor.
+	ListContractorTimesheetRequests(contractorID string) ([]*types.TimesheetRequest, error)
+
+	// GetTimesheetRequest gets the request of a contractor for a period by ContractorID and RequestID, it returns a NotFound status if there is none.
+	GetTimesheetRequest(contractorID string, requestID string) (*types.TimesheetRequest, error)
+
+	// GetTimesheetRequestByReplyKey gets the request with a reply key, it returns a NotFound status if there is none.
+	GetTimesheetRequestByReplyKey(replyKey string) (*types.TimesheetRequest, error)
+
+	// AddTimesheetRequest adds a timesheet request and sets its ID.
+	AddTimesheetRequest(request *types.TimesheetRequest) error
+
+	// UpdateTimesheetRequest updates a timesheet request.
+	UpdateTimesheetRequest(request *types.TimesheetRequest) error
+
+	// UpdatePendingTimesheetRequest updates the attempts, the escalation, and the reply key and the sent and due dates if it has none yet, of a request
+	// still waiting for its timesheet, the other fields are left as they are. It returns a FailedPrecondition status if the request is no longer
+	// pending, e.g. its timesheet arrived meanwhile.
+	UpdatePendingTimesheetRequest(request *types.TimesheetRequest) error
+
+	// ReplaceSubmissionNonce updates the submission nonce of a request whose nonce is still oldNonce, the other fields are left as they are.
+	// It returns a FailedPrecondition status if the nonce was replaced meanwhile, i.e. the submission link was used by another upload.
+	ReplaceSubmissionNonce(request *types.TimesheetRequest, oldNonce string) error
+}
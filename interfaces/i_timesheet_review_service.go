@@ -0,0 +1,17 @@
+package interfaces
+
+import (
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+)
+
+// ITimesheetReviewService is an interface for a service that moves timesheets through their review statuses.
+type ITimesheetReviewService interface {
+	// TransitionTimesheet moves a timesheet to a status, saves it and records the change in the audit log.
+	// It returns a FailedPrecondition status if the timesheet cannot move to the status, or if it changed since it was read, and an InvalidArgument status
+	// if a rejection has no reason.
+	TransitionTimesheet(timesheet *types.Timesheet, to constants.TimesheetStatuses, actor string, reason string) error
+
+	// CanTransitionTimesheet reports whether a timesheet can move from one status to another.
+	CanTransitionTimesheet(from constants.TimesheetStatuses, to constants.TimesheetStatuses) bool
+}
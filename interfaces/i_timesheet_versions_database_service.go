@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	"job_sender/types"
+)
+
+// ITimesheetVersionsDatabaseService is an interface for a database service that manages the files submitted for the timesheets.
+// The versions are added when a submission is saved, see ITimesheetIngestionsDatabaseService.
+type ITimesheetVersionsDatabaseService interface {
+	// ListTimesheetVersions lists the versions of a timesheet from the first.
+	ListTimesheetVersions(timesheetID string) ([]*types.TimesheetVersion, error)
+}
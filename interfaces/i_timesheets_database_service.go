@@ -2,6 +2,7 @@ package interfaces
 
 import (
 	"job_sender/types"
+	constants "job_sender/utils/constants"
 )
 
 // ITimesheetsDatabaseService is an interface for a database service that manages timesheets.
@@ -9,18 +10,22 @@ type ITimesheetsDatabaseService interface {
 	// ListTimesheets lists all timesheets for a group.
 	ListTimesheets(groupID string) ([]*types.Timesheet, error)
 
-	// GetTimesheet gets a timesheet by ContractorID and RequestID.
+	// GetTimesheet gets a timesheet by ContractorID and RequestID, it returns a NotFound status if there is none.
 	GetTimesheet(contractorID string, requestID string) (*types.Timesheet, error)
 
 	// GetTimesheetByID gets a timesheet by ID.
 	GetTimesheetByID(id string) (*types.Timesheet, error)
 
-	// AddTimesheet adds a timesheet to a group.
+	// AddTimesheet adds a timesheet to a group and sets its ID.
 	AddTimesheet(timesheet *types.Timesheet) error
 
 	// UpdateTimesheet updates a timesheet.
 	UpdateTimesheet(timesheet *types.Timesheet) error
 
+	// UpdateTimesheetStatus writes the status and the rejection reason of a timesheet in a transaction, the other fields are left as they are.
+	// It returns a FailedPrecondition status if the stored timesheet is no longer in fromStatus at fromVersion, e.g. a new file arrived meanwhile.
+	UpdateTimesheetStatus(timesheet *types.Timesheet, fromStatus constants.TimesheetStatuses, fromVersion int) error
+
 	// DeleteTimesheet deletes a timesheet.
 	DeleteTimesheet(id string) error
 }
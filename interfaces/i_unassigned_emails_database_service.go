@@ -0,0 +1,20 @@
+package interfaces
+
+import (
+	"job_sender/types"
+)
+
+// IUnassignedEmailsDatabaseService is an interface for a database service that keeps the triage of the emails in the unassigned mailbox.
+type IUnassignedEmailsDatabaseService interface {
+	// ListUnassignedEmails lists the unassigned emails attributed to a group from the newest.
+	ListUnassignedEmails(groupID string) ([]*types.UnassignedEmail, error)
+
+	// GetUnassignedEmail gets an unassigned email by its ID, it returns a NotFound status if there is none.
+	GetUnassignedEmail(id string) (*types.UnassignedEmail, error)
+
+	// SetUnassignedEmail saves an unassigned email, replacing the one with its ID.
+	SetUnassignedEmail(email *types.UnassignedEmail) error
+
+	// DeleteUnassignedEmail deletes an unassigned email once it is assigned or discarded, deleting a missing one is not an error.
+	DeleteUnassignedEmail(id string) error
+}
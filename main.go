@@ -1,163 +1,122 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"net/http"
+	"os"
 	"time"
 
-	"job_sender/core"
 	"job_sender/handlers"
 	"job_sender/middlewares"
+	constants "job_sender/utils/constants"
 )
 
 func main() {
-	// Create new EnvVariablesService
-	envVariablesService := core.NewEnvVariablesService("PORT", "GOOGLE_CLOUD_PROJECT_ID", "GOOGLE_CLOUD_PROJECT_LOCATION_ID", "GOOGLE_CLOUD_PROJECT_NUMBER", "SERVICE_ACCOUNT_EMAIL", "SECRET_NAME_SERVICE_ACCOUNT_KEY", "SECRET_NAME_FIREBASE_WEB_API_KEY", "SECRET_NAME_EMAIL_SERVICE_EMAIL", "SECRET_NAME_EMAIL_SERVICE_APP_PASSWORD", "SECRET_NAME_SESSION_COOKIE_STORE", "EMAIL_AGGREGATOR_QUEUE_NAME", "TIMESHEETS_BUCKET_NAME")
-	envVariables := envVariablesService.GetEnvVariables()
-
-	// Create a new Secret Manager client
-	s, err := core.NewSecretManagerService()
-	if err != nil {
-		log.Fatalf("Failed to create secret manager client: %v", err)
-	}
-
-	// Get the sa-backend service account key from Secret Manager
-	secretServiceAccountKey, err := s.GetSecret(envVariables.ProjectNumber, envVariables.SecretNameServiceAccountKey)
-	if err != nil {
-		log.Fatalf("Failed to get secret: %v", err)
-	}
-
-	// Initialize Firebase service
-	firebaseService, err := core.NewFirebaseService(secretServiceAccountKey)
-	if err != nil {
-		log.Fatalf("NewFirebaseService: %v", err)
-	}
+	migrate := flag.Bool("migrate", false, "migrate the stored data to the current data model and exit")
+	flag.Parse()
 
-	// Get the Firestore Web API key from Secret Manager
-	firebaseWebApiKey, err := s.GetSecret(envVariables.ProjectNumber, envVariables.SecretNameFirestoreWebApiKey)
-	if err != nil {
-		log.Fatalf("Failed to get secret: %v", err)
+	// Create the services, on Google Cloud unless the local backend is requested
+	var b *backend
+	switch backendName := os.Getenv(constants.BackendEnvKey); backendName {
+	case "", constants.CloudBackend:
+		b = newCloudBackend()
+	case constants.LocalBackend:
+		b = newLocalBackend()
+	default:
+		log.Fatalf("%s must be %q or %q, got %q", constants.BackendEnvKey, constants.CloudBackend, constants.LocalBackend, backendName)
 	}
 
-	// Get the email and app password for the email service from Secret Manager
-	emailServiceEmail, err := s.GetSecret(envVariables.ProjectNumber, envVariables.SecretNameEmailServiceEmail)
-	if err != nil {
-		log.Fatalf("Failed to get secret: %v", err)
+	// Migrate the data instead of serving
+	if *migrate {
+		err := runMigrations(b)
+		if err != nil {
+			log.Fatalf("failed to migrate: %v", err)
+		}
+		return
 	}
-	emailServiceAppPassword, err := s.GetSecret(envVariables.ProjectNumber, envVariables.SecretNameEmailServiceAppPassword)
-	if err != nil {
-		log.Fatalf("Failed to get secret: %v", err)
-	}
-
-	// Initialize Error Reporter Service
-	errorReporterService := core.NewErrorReporterService(envVariables.ProjectID)
-	if errorReporterService == nil {
-		log.Fatalf("NewErrorReporterService: %v", err)
-	}
-
-	// Initialize Email Service
-	emailService := core.NewEmailService(string(emailServiceEmail), string(emailServiceAppPassword))
 
 	// Initialize Panic Recover Middleware
-	panicRecoverMiddleware := middlewares.NewPanicRecoverMiddleware(errorReporterService)
+	panicRecoverMiddleware := middlewares.NewPanicRecoverMiddleware(b.errorReporterService)
 
-	// Initialize Template Service
-	templateService := core.NewTemplateService()
+	// Initialize the Auth middleware
+	authMiddleware := middlewares.NewAuthMiddleware(b.authService, b.authorizationService, b.errorReporterService)
 
-	// Get the session cookie store secret from Secret Manager
-	sessionCookieStore, err := s.GetSecret(envVariables.ProjectNumber, envVariables.SecretNameSessionCookieStore)
-	if err != nil {
-		log.Fatalf("Failed to get secret: %v", err)
-	}
-
-	// Initialize Sesssion Manager Service
-	sessionManagerService := core.NewSessionManagerService(sessionCookieStore)
-
-	// Initialize Cloud Tasks service
-	cloudTasksService := core.NewCloudTasksService(envVariablesService)
-
-	// Initialize Cloud Scheduler Service
-	schedulerService, err := core.NewSchedulerService(envVariables.ServiceAccountEmail, envVariables.ProjectID, envVariables.ProjectLocationID, secretServiceAccountKey)
-	if err != nil {
-		log.Fatalf("NewSchedulerService: %v", err)
-	}
-
-	// Initialize the Auth service
-	authService := core.NewAuthService(firebaseService, string(firebaseWebApiKey), sessionManagerService)
-	authMiddleware := middlewares.NewAuthMiddleware(authService, errorReporterService)
-
-	// Create Storage Service
-	storageService, err := core.NewStorageService(envVariables.TimesheetsBucketName)
-	if err != nil {
-		log.Fatalf("NewStorageService: %v", err)
-	}
-
-	// Create owners db service
-	ownersDB, err := core.NewOwnerDatabaseService(firebaseService)
-	if err != nil {
-		log.Fatalf("NewOwnerDatabaseService: %v", err)
-	}
+	// Initialize the Callback auth middleware
+	callbackAuthMiddleware := middlewares.NewCallbackAuthMiddleware(b.callbackAuthService, b.errorReporterService)
 
 	// Create new Main handler and router
-	mainHandler := handlers.NewMainHandler(authService, errorReporterService, ownersDB)
+	mainHandler := handlers.NewMainHandler(b.authService, b.errorReporterService, b.ownersDB, b.logWriter)
 
 	// Create the router
 	router := mainHandler.CreateRouter()
 	router.Use(panicRecoverMiddleware.PanicRecoverMiddleware)
 
+	// Register the routes only the backend needs
+	if b.registerRoutes != nil {
+		b.registerRoutes(router)
+	}
+
 	// Create a subrouter for routes that require authentication
 	authRouter := router.PathPrefix("/auth").Subrouter()
 	authRouter.Use(authMiddleware.AuthMiddleware)
 
+	// Create a subrouter for the routes machines call back, with an OIDC token or a signature
+	callbackRouter := router.PathPrefix("/timesheets").Subrouter()
+	callbackRouter.Use(callbackAuthMiddleware.CallbackAuthMiddleware)
+
 	// Create register handler
-	registerHandler := handlers.NewRegisterHandler(firebaseService, authService, templateService, emailService, errorReporterService)
+	registerHandler := handlers.NewRegisterHandler(b.firebaseService, b.authService, b.templateService, b.emailService, b.errorReporterService)
 	registerHandler.RegisterRegisterHandlers(router)
 
 	// Create login handler
-	loginHandler := handlers.NewLoginHandler(authService, firebaseService, templateService, sessionManagerService, errorReporterService)
+	loginHandler := handlers.NewLoginHandler(b.authService, b.firebaseService, b.templateService, b.sessionManagerService, b.errorReporterService)
 	loginHandler.RegisterLoginHandlers(router)
 
+	// Create account handler
+	accountHandler := handlers.NewAccountHandler(b.authService, b.authorizationService, b.accountService, b.sessionManagerService, b.templateService, b.errorReporterService)
+	accountHandler.RegisterAccountHandlers(router)
+	accountHandler.RegisterAccountAuthHandlers(authRouter)
+
 	// Create Something went wrong handler
-	somethingWentWrongHandler := handlers.NewSomethingWentWrongHandler(templateService)
+	somethingWentWrongHandler := handlers.NewSomethingWentWrongHandler(b.templateService)
 	somethingWentWrongHandler.RegisterSomethingWentWrongHandlers(router)
 
-	// Create the groups db service
-	groupsDB, err := core.NewGroupsDatabaseService(firebaseService)
-	if err != nil {
-		log.Fatalf("NewGroupsDatabaseService: %v", err)
-	}
-
-	// Create contractors db service
-	contractorsDB, err := core.NewContractorsDatabaseService(firebaseService)
-	if err != nil {
-		log.Fatalf("NewContractorsDatabaseService: %v", err)
-	}
-
-	// Create timesheets db service
-	timesheetsDB, err := core.NewTimesheetsDatabaseService(firebaseService)
-	if err != nil {
-		log.Fatalf("NewTimesheetsDatabaseService: %v", err)
-	}
-
 	// Create owners handler
-	ownersHandler := handlers.NewOwnersHandler(authService, sessionManagerService, templateService, errorReporterService, ownersDB)
+	ownersHandler := handlers.NewOwnersHandler(b.authService, b.authorizationService, b.accountService, b.sessionManagerService, b.templateService, b.errorReporterService, b.ownersDB)
 	ownersHandler.RegisterOwnersHandlers(authRouter)
 
 	// Create groups handler
-	groupsHandler := handlers.NewGroupsHandler(authService, schedulerService, sessionManagerService, storageService, templateService, errorReporterService, ownersDB, groupsDB)
+	groupsHandler := handlers.NewGroupsHandler(b.authService, b.authorizationService, b.schedulerService, b.scheduleService, b.holidayCalendarService, b.sessionManagerService, b.storageService, b.templateService, b.emailService, b.emailTemplateService, b.errorReporterService, b.ownersDB, b.groupsDB)
 	groupsHandler.RegisterGroupsHandlers(authRouter)
 
 	// Create contractor handler
-	contractorsHandler := handlers.NewContractorsHandler(authService, cloudTasksService, templateService, errorReporterService, groupsDB, contractorsDB, timesheetsDB, envVariables)
+	contractorsHandler := handlers.NewContractorsHandler(b.authService, b.authorizationService, b.templateService, b.errorReporterService, b.groupsDB, b.contractorsDB, b.timesheetsDB)
 	contractorsHandler.RegisterContractorsHandler(authRouter)
 
 	// Create timesheets handler
-	timesheetsHandler := handlers.NewTimesheetsHandler(emailService, storageService, errorReporterService, groupsDB, contractorsDB, timesheetsDB)
+	timesheetsHandler := handlers.NewTimesheetsHandler(b.emailService, b.scheduleService, b.storageService, b.submissionLinkService, b.templateService, b.timesheetIngestionService, b.errorReporterService, b.ownersDB, b.groupsDB, b.contractorsDB, b.timesheetsDB, b.timesheetRequestsDB, b.timesheetEntriesDB)
 	timesheetsHandler.RegisterTimesheetsHandlers(router)
+	timesheetsHandler.RegisterTimesheetsCallbackHandlers(callbackRouter)
+
+	// Create timesheet reviews handler
+	timesheetReviewsHandler := handlers.NewTimesheetReviewsHandler(b.authService, b.authorizationService, b.emailService, b.templateService, b.timesheetReviewService, b.scheduleService, b.holidayCalendarService, b.storageService, b.errorReporterService, b.groupsDB, b.contractorsDB, b.timesheetsDB, b.timesheetRequestsDB, b.timesheetVersionsDB, b.timesheetEntriesDB, b.timesheetAuditLogDB)
+	timesheetReviewsHandler.RegisterTimesheetReviewsHandlers(authRouter)
+
+	// Create inbox handler
+	inboxHandler := handlers.NewInboxHandler(b.authService, b.authorizationService, b.emailService, b.templateService, b.timesheetIngestionService, b.errorReporterService, b.ownersDB, b.groupsDB, b.contractorsDB, b.timesheetRequestsDB, b.unassignedEmailsDB)
+	inboxHandler.RegisterInboxHandlers(authRouter)
+
+	// Ingest the replies to timesheet requests as they arrive in the inbox
+	go b.inboxWatcherService.Watch(context.Background())
+
+	// Delete the expired sessions from the store
+	go b.sessionManagerService.ExpireSessions(context.Background())
 
 	// Configure the server
 	server := &http.Server{
-		Addr:         ":" + envVariables.Port,
+		Addr:         ":" + b.envVariables.Port,
 		WriteTimeout: 15 * time.Second,
 		ReadTimeout:  15 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -4,17 +4,22 @@ import (
 	"fmt"
 	"net/http"
 
-	"job_sender/core"
+	"job_sender/interfaces"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type authMiddleware struct {
-	authService          *core.AuthService
-	errorReporterService *core.ErrorReporterService
+	authService          interfaces.IAuthService
+	authorizationService interfaces.IAuthorizationService
+	errorReporterService interfaces.IErrorReporterService
 }
 
-func NewAuthMiddleware(authService *core.AuthService, errorReporterService *core.ErrorReporterService) *authMiddleware {
+func NewAuthMiddleware(authService interfaces.IAuthService, authorizationService interfaces.IAuthorizationService, errorReporterService interfaces.IErrorReporterService) *authMiddleware {
 	return &authMiddleware{
 		authService:          authService,
+		authorizationService: authorizationService,
 		errorReporterService: errorReporterService,
 	}
 }
@@ -34,6 +39,17 @@ func (h *authMiddleware) AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// The routes are scoped to the logged owner, a session without one is not let through
+		_, err = h.authorizationService.GetLoggedOwnerID(r)
+		if status.Code(err) == codes.Unauthenticated {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		} else if err != nil {
+			h.errorReporterService.ReportError(w, r, fmt.Errorf("could not get logged owner: %w", err))
+			http.Redirect(w, r, "/somethingWentWrong", http.StatusSeeOther)
+			return
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
@@ -0,0 +1,46 @@
+package middlewares
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"job_sender/interfaces"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type callbackAuthMiddleware struct {
+	callbackAuthService  interfaces.ICallbackAuthService
+	errorReporterService interfaces.IErrorReporterService
+}
+
+func NewCallbackAuthMiddleware(callbackAuthService interfaces.ICallbackAuthService, errorReporterService interfaces.IErrorReporterService) *callbackAuthMiddleware {
+	return &callbackAuthMiddleware{
+		callbackAuthService:  callbackAuthService,
+		errorReporterService: errorReporterService,
+	}
+}
+
+// CallbackAuthMiddleware lets through only the requests with a valid OIDC token or signature, the routes behind it are called by machines.
+func (h *callbackAuthMiddleware) CallbackAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := h.callbackAuthService.VerifyCallback(r)
+		switch status.Code(err) {
+		case codes.OK:
+			next.ServeHTTP(w, r)
+		case codes.Unauthenticated:
+			log.Printf("rejected callback %s %s: %v", r.Method, r.URL.Path, err)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		case codes.PermissionDenied:
+			log.Printf("rejected callback %s %s: %v", r.Method, r.URL.Path, err)
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		default:
+			// The caller retries, the keys may be fetched by then
+			h.errorReporterService.ReportError(w, r, fmt.Errorf("could not verify callback: %w", err))
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		}
+	})
+}
@@ -4,14 +4,14 @@ import (
 	"fmt"
 	"net/http"
 
-	"job_sender/core"
+	"job_sender/interfaces"
 )
 
 type panicRecoverMiddleware struct {
-	errorReporterService *core.ErrorReporterService
+	errorReporterService interfaces.IErrorReporterService
 }
 
-func NewPanicRecoverMiddleware(errorReporterService *core.ErrorReporterService) *panicRecoverMiddleware {
+func NewPanicRecoverMiddleware(errorReporterService interfaces.IErrorReporterService) *panicRecoverMiddleware {
 	return &panicRecoverMiddleware{
 		errorReporterService: errorReporterService,
 	}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// runMigrations moves the stored data to the current data model, each migration skips the data already migrated so it can be run again.
+func runMigrations(b *backend) error {
+	err := migrateLastRequests(b)
+	if err != nil {
+		return err
+	}
+
+	err = migrateSchedulerJobs(b)
+	if err != nil {
+		return err
+	}
+
+	err = migrateUnassignedEmails(b)
+	if err != nil {
+		return err
+	}
+
+	return migratePublicFiles(b)
+}
+
+// migrateLastRequests moves the requests stored on the contractors to the timesheet requests collection.
+func migrateLastRequests(b *backend) error {
+	contractors, err := b.contractorsDB.GetAllContractors()
+	if err != nil {
+		return fmt.Errorf("failed to get contractors: %w", err)
+	}
+
+	groups := map[string]*types.Group{}
+	migrated, skipped := 0, 0
+	for _, contractor := range contractors {
+		if len(contractor.LastRequests) == 0 {
+			continue
+		}
+
+		group, ok := groups[contractor.GroupID]
+		if !ok {
+			group, err = b.groupsDB.GetGroup(contractor.GroupID)
+			if err != nil {
+				return fmt.Errorf("failed to get group of contractor %s: %w", contractor.ID, err)
+			}
+			groups[contractor.GroupID] = group
+		}
+
+		for _, lastRequest := range contractor.LastRequests {
+			_, err := b.timesheetRequestsDB.GetTimesheetRequest(contractor.ID, lastRequest.ID)
+			if err == nil {
+				skipped++
+				continue
+			} else if status.Code(err) != codes.NotFound {
+				return fmt.Errorf("failed to get timesheet request: %w", err)
+			}
+
+			request := &types.TimesheetRequest{
+				GroupID:      contractor.GroupID,
+				ContractorID: contractor.ID,
+				RequestID:    lastRequest.ID,
+
+				Status:   constants.Pending,
+				Attempts: 1 + lastRequest.RemindersSent,
+
+				Escalated:       lastRequest.Escalated,
+				SubmissionNonce: lastRequest.SubmissionNonce,
+			}
+
+			// The request IDs made before schedules had periods keep a zero period
+			periodStart, periodEnd, err := b.scheduleService.RequestPeriod(lastRequest.ID)
+			if err == nil {
+				request.PeriodStart, request.PeriodEnd = periodStart, periodEnd
+			}
+
+			if lastRequest.RequestedAt != 0 {
+				request.SentAt = time.Unix(lastRequest.RequestedAt, 0)
+				request.DueAt = b.scheduleService.RequestDueAt(&group.ReminderPolicy, request.SentAt)
+			}
+
+			if lastRequest.Timestamp != 0 {
+				request.Status = constants.Collected
+				request.CollectedAt = time.Unix(lastRequest.Timestamp, 0)
+			}
+
+			err = b.timesheetRequestsDB.AddTimesheetRequest(request)
+			if err != nil {
+				return fmt.Errorf("failed to add timesheet request: %w", err)
+			}
+			migrated++
+		}
+
+		contractor.LastRequests = nil
+		err = b.contractorsDB.UpdateContractor(contractor)
+		if err != nil {
+			return fmt.Errorf("failed to update contractor %s: %w", contractor.ID, err)
+		}
+	}
+
+	log.Printf("migrated %d requests of %d contractors to timesheet requests, %d were already migrated", migrated, len(contractors), skipped)
+
+	return nil
+}
+
+// migrateSchedulerJobs moves the request jobs of the groups to the daily trigger the schedule service filters, and creates the reminder jobs of the
+// groups made before reminders existed. The jobs are rewritten from the groups' schedules, so migrated jobs are left as they are.
+func migrateSchedulerJobs(b *backend) error {
+	groups, err := b.groupsDB.GetAllGroups()
+	if err != nil {
+		return fmt.Errorf("failed to get groups: %w", err)
+	}
+
+	for _, group := range groups {
+		err = b.schedulerService.EditTimesheetRequestJob(group.ID, &group.Schedule)
+		if err != nil {
+			return fmt.Errorf("failed to update the timesheet request job of group %s: %w", group.ID, err)
+		}
+
+		// Editing creates the reminder job of a group that has none
+		err = b.schedulerService.EditTimesheetReminderJob(group.ID, &group.Schedule)
+		if err != nil {
+			return fmt.Errorf("failed to update the timesheet reminder job of group %s: %w", group.ID, err)
+		}
+	}
+
+	log.Printf("updated the scheduler jobs of %d groups", len(groups))
+
+	return nil
+}
+
+// migrateUnassignedEmails saves the triage of the emails moved to the unassigned mailbox before the inbox was shown from the saved triage.
+// The emails attributed to no group are archived, their senders are told to answer the request email instead.
+func migrateUnassignedEmails(b *backend) error {
+	emails, err := b.emailService.GetUnassignedEmails()
+	if err != nil {
+		return fmt.Errorf("failed to get unassigned emails: %w", err)
+	}
+
+	archived := 0
+	for _, email := range emails {
+		kept, err := b.timesheetIngestionService.UnassignEmail(email)
+		if err != nil {
+			return fmt.Errorf("failed to unassign email %q from %s: %w", email.Subject, email.From, err)
+		}
+		if kept {
+			continue
+		}
+
+		err = b.emailService.ArchiveUnassignedEmail(email.UID)
+		if err != nil {
+			return fmt.Errorf("failed to archive email %q from %s: %w", email.Subject, email.From, err)
+		}
+		archived++
+	}
+
+	log.Printf("triaged %d unassigned emails, archived %d attributed to no group", len(emails), archived)
+
+	return nil
+}
+
+// migratePublicFiles makes the timesheet files uploaded with public read access private, they are then only downloaded through signed URLs.
+func migratePublicFiles(b *backend) error {
+	public, err := b.storageService.MakeFilesPrivate("")
+	if err != nil {
+		return fmt.Errorf("failed to make files private after %d files: %w", public, err)
+	}
+
+	log.Printf("made %d public timesheet files private", public)
+
+	return nil
+}
@@ -0,0 +1,11 @@
+package types
+
+// AccountLink is what a signed account link lets its holder do: reset the password of a user, or change the email of a user to the address the link is sent to.
+type AccountLink struct {
+	Purpose string `json:"purpose"` // constants.AccountLinkPasswordReset or constants.AccountLinkEmailChange
+	UserID  string `json:"user_id"`
+	Email   string `json:"email"` // The address the link is sent to
+
+	ValidSince int64 `json:"valid_since"` // The user's TokensValidAfter when the link was created, the link is rejected once the sessions are revoked
+	ExpiresAt  int64 `json:"expires_at"`  // Unix time after which the link is rejected
+}
@@ -0,0 +1,18 @@
+package types
+
+import (
+	"slices"
+
+	constants "job_sender/utils/constants"
+)
+
+// AttachmentPolicy holds which timesheet files the contractors of a group may send, the files are told apart by their content, not their names.
+type AttachmentPolicy struct {
+	AllowedTypes []constants.TimesheetFileTypes `firestore:"allowed_types"` // All the types timesheets can be read from if empty
+	MaxSizeMB    int                            `firestore:"max_size_mb"`   // Largest file in MB, 0 for the default
+}
+
+// Allows tells whether the group accepts the files of a type.
+func (p *AttachmentPolicy) Allows(fileType constants.TimesheetFileTypes) bool {
+	return len(p.AllowedTypes) == 0 || slices.Contains(p.AllowedTypes, fileType)
+}
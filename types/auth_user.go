@@ -0,0 +1,10 @@
+package types
+
+// AuthUser is a user account of Firebase Authentication.
+type AuthUser struct {
+	ID            string
+	Email         string
+	EmailVerified bool
+
+	TokensValidAfter int64 // Unix time the ID tokens issued before are revoked at
+}
@@ -10,12 +10,19 @@ type Contractor struct {
 	Email    string `firestore:"email"`
 	Phone    string `firestore:"phone"`
 	PhotoURL string `firestore:"photo_url"`
+	Language string `firestore:"language"` // Language of the emails sent to the contractor, e.g. "pl", English if empty
 
-	LastRequests             []LastRequest `firestore:"last_requests"`
-	LastAggregationTimestamp int64         `firestore:"last_aggregation_timestamp"`
+	LastRequests []LastRequest `firestore:"last_requests"` // Deprecated: the requests are TimesheetRequests, running the app with -migrate moves these to them
 }
 
+// LastRequest is a request as it was stored on the contractor before requests were TimesheetRequests.
 type LastRequest struct {
 	ID        string `firestore:"id"`
-	Timestamp int64  `firestore:"timestamp"`
+	Timestamp int64  `firestore:"timestamp"` // When the timesheet was collected, 0 while it is missing
+
+	RequestedAt   int64 `firestore:"requested_at"`   // When the request email was sent, 0 for requests sent before reminders existed
+	RemindersSent int   `firestore:"reminders_sent"` // How many reminders of the group's policy were sent
+	Escalated     bool  `firestore:"escalated"`      // Whether the owner was told the timesheet is missing
+
+	SubmissionNonce string `firestore:"submission_nonce"` // Nonce of the valid submission link, replaced whenever a timesheet is submitted through it
 }
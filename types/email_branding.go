@@ -0,0 +1,7 @@
+package types
+
+// EmailBranding is how a group signs the emails sent to its contractors.
+type EmailBranding struct {
+	LogoURL   string `firestore:"logo_url"`  // Logo shown at the top of the HTML emails, none if empty
+	Signature string `firestore:"signature"` // Closing lines of the emails, the group name if empty
+}
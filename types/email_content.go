@@ -0,0 +1,23 @@
+package types
+
+// EmailContent is the wording a group uses in the emails to its contractors instead of the default one, empty fields keep the default.
+// Each field is a template executed with the EmailPlaceholders, e.g. "Hi {{.Contractor.Name}}, please send the timesheet by {{.DueDate}}."
+type EmailContent struct {
+	SubjectSuffix string `firestore:"subject_suffix"` // Appended to the subject of the request and reminder emails
+	RequestBody   string `firestore:"request_body"`   // Body of the request email
+	ReminderBody  string `firestore:"reminder_body"`  // Body of the reminder email
+}
+
+// EmailPlaceholders are the fields the email content of a group can use.
+type EmailPlaceholders struct {
+	Contractor *Contractor
+
+	GroupName string
+	RequestID string // As in the subject, e.g. "2025-01-06_2025-01-19"
+
+	PeriodStart string // First day of the requested period, empty for the requests made before schedules had periods
+	PeriodEnd   string // Last day of the requested period, empty for the requests made before schedules had periods
+	DueDate     string // Day the timesheet is expected by, the first reminder or the expiry of the upload link
+
+	SubmissionLink string // Link to upload the timesheet on, empty if there is none
+}
@@ -14,8 +14,8 @@ type EnvVariables struct {
 	SecretNameEmailServiceEmail       string
 	SecretNameEmailServiceAppPassword string
 	SecretNameSessionCookieStore      string
-
-	EmailAggregatorQueueName string
+	SecretNameSubmissionLinkKey       string
+	SecretNameAccountLinkKey          string
 
 	TimesheetsBucketName string
 }
@@ -0,0 +1,38 @@
+package types
+
+import (
+	"fmt"
+
+	constants "job_sender/utils/constants"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FileRejection is the error of a timesheet file a group does not accept, it has the InvalidArgument status.
+// It is saved with the triage of an unassigned email.
+type FileRejection struct {
+	Filename string                         `firestore:"filename"`
+	Reason   constants.FileRejectionReasons `firestore:"reason"`
+
+	AllowedTypes []constants.TimesheetFileTypes `firestore:"allowed_types"` // The types the group accepts
+	MaxSizeMB    int                            `firestore:"max_size_mb"`   // The largest file the group accepts
+	Malware      string                         `firestore:"malware"`       // Name of the malware found, empty unless MalwareFound
+}
+
+// Error tells the reason of the rejection.
+func (r *FileRejection) Error() string {
+	switch r.Reason {
+	case constants.FileTooLarge:
+		return fmt.Sprintf("file %s is larger than %d MB", r.Filename, r.MaxSizeMB)
+	case constants.MalwareFound:
+		return fmt.Sprintf("file %s contains malware %s", r.Filename, r.Malware)
+	default:
+		return fmt.Sprintf("file %s is rejected: %s", r.Filename, r.Reason)
+	}
+}
+
+// GRPCStatus returns the InvalidArgument status of the rejection.
+func (r *FileRejection) GRPCStatus() *status.Status {
+	return status.New(codes.InvalidArgument, r.Error())
+}
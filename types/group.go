@@ -7,5 +7,13 @@ type Group struct {
 
 	Name string `firestore:"name"`
 
-	Schedule Schedule `firestore:"schedule"`
+	Schedule        Schedule `firestore:"schedule"`
+	HolidayCalendar string   `firestore:"holiday_calendar"` // Code of the holiday calendar, e.g. "PL", empty for none
+
+	ReminderPolicy ReminderPolicy `firestore:"reminder_policy"`
+
+	AttachmentPolicy AttachmentPolicy `firestore:"attachment_policy"`
+
+	Branding     EmailBranding `firestore:"branding"`
+	EmailContent EmailContent  `firestore:"email_content"`
 }
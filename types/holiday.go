@@ -0,0 +1,13 @@
+package types
+
+// Holiday is a day off of a holiday calendar.
+type Holiday struct {
+	Date string // Date, e.g. "2025-12-25"
+	Name string // Name, e.g. "Christmas Day"
+}
+
+// HolidayCalendar is a bundled calendar of the holidays of a country.
+type HolidayCalendar struct {
+	Code string // Code, e.g. "PL"
+	Name string // Name, e.g. "Poland"
+}
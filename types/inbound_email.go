@@ -0,0 +1,22 @@
+package types
+
+import (
+	"time"
+)
+
+// InboundEmail is an email received in the inbox of the email service.
+type InboundEmail struct {
+	UID       uint32
+	MessageID string
+	From      string
+	To        []string // Addresses the email was sent to, including the copies
+	Subject   string
+	Date      time.Time // When the email was sent, zero if the header is missing
+
+	AutoSubmitted bool // Whether a program sent it, e.g. an out-of-office reply, it is never replied to automatically
+
+	InReplyTo  []string // Message IDs of the emails it replies to
+	References []string // Message IDs of the emails of the thread
+
+	Attachments []Attachment
+}
@@ -0,0 +1,12 @@
+package types
+
+import (
+	"time"
+)
+
+// Lease elects the one instance of the app that runs a background job, the instance holding it renews it before it expires.
+type Lease struct {
+	Name      string    `firestore:"name"`
+	Holder    string    `firestore:"holder"`     // Random ID of the instance holding the lease
+	ExpiresAt time.Time `firestore:"expires_at"` // Any instance may take the lease after, unless its holder renewed it
+}
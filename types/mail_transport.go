@@ -0,0 +1,19 @@
+package types
+
+import (
+	constants "job_sender/utils/constants"
+)
+
+// MailTransport holds the connection settings of the SMTP and IMAP servers used by the email service.
+type MailTransport struct {
+	SmtpHost    string             // SMTP server host, e.g. "smtp.gmail.com"
+	SmtpPort    int                // SMTP server port, e.g. 587
+	SmtpTLSMode constants.TLSModes // How the SMTP connection is secured
+
+	ImapHost    string             // IMAP server host, e.g. "imap.gmail.com"
+	ImapPort    int                // IMAP server port, e.g. 993
+	ImapTLSMode constants.TLSModes // How the IMAP connection is secured
+
+	AuthMechanism      constants.AuthMechanisms // How the email service authenticates against both servers
+	InsecureSkipVerify bool                     // Skip TLS certificate verification, only for self-signed local servers
+}
@@ -0,0 +1,9 @@
+package types
+
+// MissingTimesheet is a requested timesheet that the contractor has not sent yet.
+type MissingTimesheet struct {
+	Contractor *Contractor
+	RequestID  string
+
+	DaysSinceRequest int
+}
@@ -0,0 +1,12 @@
+package types
+
+type RefreshTokenResponseBody struct {
+	IdToken      string `json:"id_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    string `json:"expires_in"`
+	UserId       string `json:"user_id"`
+	Error        *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"` // e.g. TOKEN_EXPIRED once the sessions of the user are revoked, USER_NOT_FOUND, USER_DISABLED
+	} `json:"error,omitempty"`
+}
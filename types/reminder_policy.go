@@ -0,0 +1,9 @@
+package types
+
+// ReminderPolicy holds when the contractors of a group are reminded about missing timesheets and when the owner is told.
+type ReminderPolicy struct {
+	Enabled bool `firestore:"enabled"`
+
+	ReminderDays   []int `firestore:"reminder_days"`   // Days after the request to remind the contractor, e.g. [2, 5]
+	EscalationDays int   `firestore:"escalation_days"` // Days after the request to send the owner a digest of the missing timesheets, 0 to never escalate
+}
@@ -0,0 +1,8 @@
+package types
+
+// RenderedEmail is an email template rendered for a recipient, with the same text as plain text and as HTML.
+type RenderedEmail struct {
+	Subject string
+	Text    string
+	HTML    string
+}
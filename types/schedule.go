@@ -8,6 +8,7 @@ import (
 type Schedule struct {
 	Weekday  string `firestore:"weekday"`  // Day of week, e.g. "Monday"
 	Monthday string `firestore:"monthday"` // Day of month, e.g. "1"
+	Week     int    `firestore:"week"`     // Week of the month of the weekday, 1 to 4, or -1 for the last
 
 	Timezone string `firestore:"timezone"` // Timezone, e.g. "America/New_York"
 	Time     string `firestore:"time"`     // Time of day, e.g. "09:00" in 24-hour format
@@ -15,6 +16,9 @@ type Schedule struct {
 	StartDate string `firestore:"start_date"` // Start date, e.g. "2021-01-01"
 	EndDate   string `firestore:"end_date"`   // End date, e.g. "2021-12-31"
 
-	IntervalType intervalTypes.IntervalTypes `firestore:"interval_type"` // The type of interval, "weeks" or "months"
+	IntervalType intervalTypes.IntervalTypes `firestore:"interval_type"` // The type of interval, e.g. "weeks" or "months"
 	Interval     int                         `firestore:"interval"`      // The numeric interval value
+
+	Holidays []string `firestore:"holidays"` // Dates that are not business days, e.g. "2021-12-24"
+	Cron     string   `firestore:"cron"`     // Cron expression of the cron interval type, e.g. "0 9 1,15 * *"
 }
@@ -0,0 +1,13 @@
+package types
+
+import "time"
+
+// ScheduleOccurrence is a time a schedule sends the timesheet request, with the period the request covers.
+type ScheduleOccurrence struct {
+	Time time.Time // When the request is sent, in the schedule's timezone
+
+	PeriodStart time.Time // First day of the covered period, at midnight in the schedule's timezone
+	PeriodEnd   time.Time // Last day of the covered period, inclusive
+
+	RequestID string // The covered period, e.g. "2025-01-06_2025-01-19"
+}
@@ -0,0 +1,61 @@
+package types
+
+import (
+	"strings"
+	"time"
+)
+
+// Session is a session kept server-side, its cookie only holds its signed ID, so it can be listed, revoked and expired centrally.
+type Session struct {
+	ID     string `firestore:"id"`
+	Name   string `firestore:"name"`    // Name of the cookie, e.g. user-session
+	UserID string `firestore:"user_id"` // ID of the logged user, empty until one logs in
+	Values []byte `firestore:"values"`  // The gob encoded values, the Firebase tokens of a login among them
+
+	UserAgent  string    `firestore:"user_agent"` // User agent of the last request
+	IPAddress  string    `firestore:"ip_address"` // IP address of the last request
+	CreatedAt  time.Time `firestore:"created_at"`
+	LastSeenAt time.Time `firestore:"last_seen_at"`
+	ExpiresAt  time.Time `firestore:"expires_at"` // Absolute end of the session, it is deleted past it
+	Persistent bool      `firestore:"persistent"` // Whether the cookie lasts until ExpiresAt, or is dropped when the browser closes
+}
+
+// Device names the browser and the system of the session from its user agent, e.g. "Firefox on Linux".
+// Only known names are returned, the user agent is never echoed.
+func (s *Session) Device() string {
+	ua := s.UserAgent
+
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(ua, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "Safari/"):
+		browser = "Safari"
+	case strings.HasPrefix(ua, "curl/"):
+		browser = "curl"
+	}
+
+	system := "unknown system"
+	switch {
+	case strings.Contains(ua, "Windows"):
+		system = "Windows"
+	case strings.Contains(ua, "Android"):
+		system = "Android"
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"):
+		system = "iOS"
+	case strings.Contains(ua, "Mac OS X"):
+		system = "macOS"
+	case strings.Contains(ua, "CrOS"):
+		system = "ChromeOS"
+	case strings.Contains(ua, "Linux"):
+		system = "Linux"
+	}
+
+	return browser + " on " + system
+}
@@ -0,0 +1,10 @@
+package types
+
+// SubmissionLink is what a signed submission link lets its holder do: submit the timesheet of one contractor for one request.
+type SubmissionLink struct {
+	ContractorID string `json:"contractor_id"`
+	RequestID    string `json:"request_id"`
+
+	Nonce     string `json:"nonce"`      // Matches the nonce of the contractor's request until the link is used
+	ExpiresAt int64  `json:"expires_at"` // Unix time after which the link is rejected
+}
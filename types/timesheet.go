@@ -1,10 +1,22 @@
 package types
 
+import (
+	constants "job_sender/utils/constants"
+)
+
 // Timesheet represents a contractor's timesheet.
 type Timesheet struct {
 	ID           string `firestore:"id"`
 	ContractorID string `firestore:"contractor_id"`
+	GroupID      string `firestore:"group_id"`
 	RequestID    string `firestore:"request_id"`
 
-	StorageURL string `firestore:"storage_url"`
+	StorageURL  string `firestore:"storage_url"`
+	Version     int    `firestore:"version"`      // The current version of the file, 0 for timesheets saved before versions existed
+	ContentHash string `firestore:"content_hash"` // Hex SHA-256 of the current file
+
+	TotalHours float64 `firestore:"total_hours"`
+
+	Status          constants.TimesheetStatuses `firestore:"status"`           // The review status, timesheets stored before reviews existed are Received
+	RejectionReason string                      `firestore:"rejection_reason"` // Why the owner rejected the timesheet, empty unless Rejected
 }
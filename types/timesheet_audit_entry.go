@@ -0,0 +1,17 @@
+package types
+
+import (
+	constants "job_sender/utils/constants"
+)
+
+// TimesheetAuditEntry records a change of a timesheet's status, entries are only ever added.
+type TimesheetAuditEntry struct {
+	ID          string `firestore:"id"`
+	TimesheetID string `firestore:"timesheet_id"`
+	GroupID     string `firestore:"group_id"`
+
+	Actor     string                      `firestore:"actor"`     // Email of the owner or contractor who made the change
+	Status    constants.TimesheetStatuses `firestore:"status"`    // The status the timesheet moved to
+	Reason    string                      `firestore:"reason"`    // The rejection reason, or why the file was replaced
+	Timestamp int64                       `firestore:"timestamp"` // Unix time in milliseconds
+}
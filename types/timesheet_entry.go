@@ -0,0 +1,15 @@
+package types
+
+// TimesheetEntry represents a single day of work parsed from a timesheet.
+type TimesheetEntry struct {
+	ID           string `firestore:"id"`
+	TimesheetID  string `firestore:"timesheet_id"`
+	ContractorID string `firestore:"contractor_id"`
+	GroupID      string `firestore:"group_id"`
+	RequestID    string `firestore:"request_id"`
+
+	Date    string  `firestore:"date"`
+	Hours   float64 `firestore:"hours"`
+	Project string  `firestore:"project"`
+	Note    string  `firestore:"note"`
+}
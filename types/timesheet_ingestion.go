@@ -0,0 +1,22 @@
+package types
+
+import (
+	"time"
+)
+
+// TimesheetIngestion records that a submission of a timesheet, an email or an upload, was saved, so it is never saved twice.
+type TimesheetIngestion struct {
+	ID           string `firestore:"id"`  // Hex SHA-256 of the key, the keys do not fit in document IDs
+	Key          string `firestore:"key"` // The Message-ID of the email, or what else identifies the submission
+	ContractorID string `firestore:"contractor_id"`
+	GroupID      string `firestore:"group_id"`
+	RequestID    string `firestore:"request_id"`
+	TimesheetID  string `firestore:"timesheet_id"`
+
+	ObjectName  string `firestore:"object_name"`  // Name of the file in the storage, derived from its content
+	ContentHash string `firestore:"content_hash"` // Hex SHA-256 of the file
+	Version     int    `firestore:"version"`      // The version of the timesheet the file became, or duplicates
+	Duplicate   bool   `firestore:"duplicate"`    // Whether the file was the current one already, and was ignored
+
+	IngestedAt time.Time `firestore:"ingested_at"`
+}
@@ -0,0 +1,29 @@
+package types
+
+import (
+	"time"
+
+	constants "job_sender/utils/constants"
+)
+
+// TimesheetRequest is the request of a contractor's timesheet for a period of the group's schedule.
+type TimesheetRequest struct {
+	ID           string `firestore:"id"`
+	GroupID      string `firestore:"group_id"`
+	ContractorID string `firestore:"contractor_id"`
+	RequestID    string `firestore:"request_id"` // The period as the emails and the timesheets name it, e.g. "2025-01-06_2025-01-19", or "9_10-2024" for the requests made before schedules had periods
+
+	PeriodStart time.Time `firestore:"period_start"` // First day of the period at midnight UTC, zero for the requests made before schedules had periods
+	PeriodEnd   time.Time `firestore:"period_end"`   // Last day of the period at midnight UTC, zero for the requests made before schedules had periods
+
+	SentAt      time.Time `firestore:"sent_at"`      // When the contractor was last asked for the timesheet, by the request or a rejection, zero for requests sent before reminders existed
+	DueAt       time.Time `firestore:"due_at"`       // When the timesheet is expected, zero if SentAt is
+	CollectedAt time.Time `firestore:"collected_at"` // When the timesheet was received, zero while it is missing
+
+	Status    constants.TimesheetRequestStatuses `firestore:"status"`
+	Attempts  int                                `firestore:"attempts"`  // How many emails asked for the timesheet since it was sent, the request and the reminders of the group's policy
+	Escalated bool                               `firestore:"escalated"` // Whether the owner was told the timesheet is missing
+
+	SubmissionNonce string `firestore:"submission_nonce"` // Nonce of the valid submission link, replaced whenever a timesheet is submitted through it
+	ReplyKey        string `firestore:"reply_key"`        // Random key in the Message-IDs and Reply-To addresses of the emails about the request, the replies are matched to it by the key
+}
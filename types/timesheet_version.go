@@ -0,0 +1,24 @@
+package types
+
+import (
+	"time"
+)
+
+// TimesheetVersion is a file a contractor submitted for a timesheet, every revised file adds a version.
+type TimesheetVersion struct {
+	ID           string `firestore:"id"`
+	TimesheetID  string `firestore:"timesheet_id"`
+	ContractorID string `firestore:"contractor_id"`
+	GroupID      string `firestore:"group_id"`
+	RequestID    string `firestore:"request_id"`
+
+	Version     int    `firestore:"version"` // 1 for the first file
+	StorageURL  string `firestore:"storage_url"`
+	Filename    string `firestore:"filename"`     // Name of the file as the contractor sent it
+	ContentHash string `firestore:"content_hash"` // Hex SHA-256 of the file, empty for the file saved before versions existed
+
+	TotalHours float64            `firestore:"total_hours"`
+	DailyHours map[string]float64 `firestore:"daily_hours"` // Hours of the parsed entries by date, nil for the file saved before versions existed
+
+	SubmittedAt time.Time `firestore:"submitted_at"` // Zero for the file saved before versions existed
+}
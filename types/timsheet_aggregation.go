@@ -1,8 +0,0 @@
-package types
-
-// TimesheetAggregation represents a timesheet aggregation.
-type TimesheetAggregation struct {
-	RequestID string
-
-	Contractor *Contractor
-}
@@ -0,0 +1,26 @@
+package types
+
+import (
+	"time"
+
+	constants "job_sender/utils/constants"
+)
+
+// UnassignedEmail is the triage of an email the inbox watcher moved to the unassigned mailbox: why it was not ingested and who likely sent it.
+// It is saved as the email is moved, so the inbox is shown without reading the mailbox.
+type UnassignedEmail struct {
+	ID          string    `firestore:"id"`         // Hex SHA-256 of the key of the email, as the ID of its ingestion
+	MessageID   string    `firestore:"message_id"` // Finds the email in the unassigned mailbox, empty if it has none
+	From        string    `firestore:"from"`
+	Subject     string    `firestore:"subject"`
+	Date        time.Time `firestore:"date"`        // When the email was sent, zero if the header is missing
+	Attachments []string  `firestore:"attachments"` // Filenames of the attachments, in the order of the email
+
+	Reason       constants.UnassignedReasons `firestore:"reason"`
+	GroupID      string                      `firestore:"group_id"`      // The group whose owner triages it, empty if it is attributed to none
+	ContractorID string                      `firestore:"contractor_id"` // The contractor who sent it or whose request it matches, empty for an unknown sender
+	RequestID    string                      `firestore:"request_id"`    // The request it matches, empty if none
+	Rejection    *FileRejection              `firestore:"rejection"`     // Why the attachment is rejected, nil unless RejectedFile
+
+	UnassignedAt time.Time `firestore:"unassigned_at"`
+}
@@ -4,15 +4,52 @@ const (
 	AppName = "job-sender"
 	AppUrl  = "https://app.jobsender.pl"
 
+	BackendEnvKey = "JOB_SENDER_BACKEND" // Selects the set of services the app runs on
+	CloudBackend  = "cloud"              // Google Cloud services, the default
+	LocalBackend  = "local"              // Local replacements, no cloud project needed
+
+	LocalDataDirEnvKey = "JOB_SENDER_DATA_DIR" // Directory of the local store, bucket and secrets
+	LocalDataDir       = ".local"
+	LocalSecretsFile   = "secrets.env"
+	LocalStoreFile     = "store.json"
+	LocalBucketDir     = "bucket"
+	LocalStoragePath   = "/local/storage/" // Route serving the files of the local bucket
+	LocalSmtpAddress   = "127.0.0.1:2525"
+	LocalImapAddress   = "127.0.0.1:1143"
+	LocalClamdAddress  = "127.0.0.1:3310" // The fake ClamAV daemon the local backend scans the timesheets with
+
+	ClamdAddressEnvKey = "CLAMD_ADDRESS" // Address of the ClamAV daemon the timesheets are scanned with, e.g. tcp:clamav:3310 or unix:/run/clamav/clamd.ctl, none if empty
+
+	CallbackJwksURLEnvKey          = "CALLBACK_JWKS_URL"                // JWKS the OIDC tokens of the callbacks are verified with, Google's if empty
+	CallbackOidcIssuerEnvKey       = "CALLBACK_OIDC_ISSUER"             // Issuer of the OIDC tokens of the callbacks, Google if empty
+	CallbackSigningKeySecretEnvKey = "SECRET_NAME_CALLBACK_SIGNING_KEY" // Secret of the key signed callbacks are verified with, none are accepted if empty
+	GoogleJwksURL                  = "https://www.googleapis.com/oauth2/v3/certs"
+	GoogleOidcIssuer               = "https://accounts.google.com"
+
+	CallbackTimestampHeader         = "X-Callback-Timestamp" // Unix time a signed callback was sent at
+	CallbackSignatureHeader         = "X-Callback-Signature" // Hex HMAC-SHA256 of a signed callback
+	CallbackNonceHeader             = "X-Callback-Nonce"     // Random value a signed callback is accepted with once
+	CallbackMaxClockSkewSeconds     = 300                    // How old a signed callback or how far an OIDC token from its validity may be
+	CallbackJwksCacheSeconds        = 3600                   // How long the keys are kept when the JWKS does not say
+	CallbackJwksRefreshDelaySeconds = 60                     // Least time between two fetches of the JWKS for an unknown key
+
 	SmtpGmailAddress = "smtp.gmail.com"
 	ImapGmailAddress = "imap.gmail.com"
 	SmtpGmailPort    = 587
+	ImapGmailPort    = 993
+
+	UnassignedMailbox = "Unassigned" // Mailbox, or Gmail label, of the emails that match no timesheet request
 
 	TemplatesDir                = "/templates"
+	LocalTemplatesDir           = "templates"
 	TemplatesBaseName           = "base.html"
 	TemplateLoginName           = "login.html"
 	TemplateRegisterName        = "register.html"
 	TemplateConfirmRegisterName = "confirm_registration.html"
+	TemplateForgotPasswordName  = "forgot_password.html"
+	TemplateResetPasswordName   = "reset_password.html"
+	TemplateChangeEmailName     = "change_email.html"
+	TemplateSessionsName        = "sessions.html"
 	TemplateSomethingWentWrong  = "something_went_wrong.html"
 
 	TemplateOwnerAddName  = "add_owner.html"
@@ -25,14 +62,64 @@ const (
 	TemplateContractorsAddName  = "add_contractor.html"
 	TemplateContractorsEditName = "edit_contractor.html"
 
+	TemplateTimesheetGetName    = "get_timesheet.html"
+	TemplateTimesheetSubmitName = "submit_timesheet.html"
+
+	TemplateInboxName = "inbox.html"
+
+	EmailLayoutHTMLName            = "layout.html" // Layouts the email templates are rendered in, with the branding of the group
+	EmailLayoutTextName            = "layout.txt"
+	EmailTemplateVerificationName  = "verification"
+	EmailTemplatePasswordResetName = "password_reset"
+	EmailTemplateEmailChangeName   = "email_change"
+	EmailTemplateRequestName       = "timesheet_request"
+	EmailTemplateRejectionName     = "timesheet_rejection"
+	EmailTemplateFileRejectionName = "file_rejection"
+	EmailTemplateReminderName      = "timesheet_reminder"
+	EmailTemplateMissingName       = "missing_timesheets"
+	EmailTemplateReplyName         = "reply"
+	EmailTemplateUnknownSenderName = "unknown_sender"
+	EmailSenderName                = "Job sender" // Sender name of the emails not sent on behalf of a group
+
+	EnglishLanguage = "en"
+	PolishLanguage  = "pl"
+	DefaultLanguage = EnglishLanguage // Language of the emails with no template in the recipient's language
+
+	WorkingDayHours = 8 // Hours expected of a contractor on a working day
+
+	SubmissionLinkPath         = "/submit/" // Route of the page contractors upload their timesheets on
+	SubmissionLinkLifetimeDays = 30         // Days a submission link is valid for after the request
+
+	DownloadURLLifetimeMinutes = 5 // Minutes a signed URL downloading a timesheet file is valid for
+
+	AccountLinkPasswordReset         = "password_reset"         // Purpose of the links resetting the password of an account
+	AccountLinkEmailChange           = "email_change"           // Purpose of the links confirming the new email of an account
+	PasswordResetLinkPath            = "/password/reset"        // Route of the page a password is reset on
+	EmailChangeLinkPath              = "/account/email/confirm" // Route confirming the new email of an account
+	PasswordResetLinkLifetimeMinutes = 60                       // Minutes a password reset link is valid for
+	EmailChangeLinkLifetimeHours     = 24                       // Hours a link confirming a new email is valid for
+	PasswordMinLength                = 6                        // Shortest password Firebase accepts
+
+	AttachmentDefaultMaxSizeMB = 10 // Largest timesheet file of a group that sets no limit, in MB
+	AttachmentMaxSizeMB        = 25 // Largest timesheet file a group may allow, in MB, the most Gmail takes
+
 	UserSessionName                = "user-session"
 	TimesheetAggegationSessionName = "timesheet-aggregation-session"
 
-	SessionEmailField     = "email"
-	SessionTokenField     = "token"
-	SessionIsVerfiedField = "isVerified"
-	SesstionOwnerIdField  = "ownerID"
+	SessionEmailField            = "email"
+	SessionIDTokenField          = "idToken"
+	SessionIDTokenExpiresAtField = "idTokenExpiresAt" // Unix time the ID token expires at
+	SessionRefreshTokenField     = "refreshToken"     // Exchanged for a new ID token before the current one expires
+	SessionExpiresAtField        = "expiresAt"        // Unix time the session expires at, kept with the values when it is created
+	SessionIsVerfiedField        = "isVerified"
+	SesstionOwnerIdField         = "ownerID" // ID of the logged user, the sessions of a user are listed by it
+
+	SessionLastSeenIntervalSeconds = 60 // Least time between two records of the last request of a session
+	SessionExpiryIntervalMinutes   = 60 // How often the expired sessions are deleted from the store
 
-	SessionAggregatorIDField        = "aggregatorID"
-	SessionLastAggregationTimeField = "lastAggregationTime"
+	SessionLifetimeEnvKey           = "SESSION_LIFETIME"             // Absolute lifetime of a login, e.g. 12h, the default if empty
+	SessionRememberMeLifetimeEnvKey = "SESSION_REMEMBER_ME_LIFETIME" // Absolute lifetime of a login that is remembered, e.g. 720h, the default if empty
+	SessionDefaultLifetimeHours     = 12                             // Hours a login lasts, however active the user is
+	SessionDefaultRememberMeDays    = 30                             // Days a remembered login lasts, the cookie outlives the browser session
+	IDTokenRefreshMarginSeconds     = 300                            // How long before it expires the ID token of a login is refreshed
 )
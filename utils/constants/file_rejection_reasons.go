@@ -0,0 +1,52 @@
+package utils
+
+type FileRejectionReasons int
+
+const (
+	FileTooLarge       FileRejectionReasons = iota // the file is larger than the group allows
+	FileTypeNotAllowed                             // the content is not of a file type the group allows, whatever its extension
+	ArchiveFile                                    // the file is an archive, e.g. a ZIP or RAR
+	ExecutableFile                                 // the file is a program or a script
+	MacroFile                                      // the file is a workbook with macros
+	MalwareFound                                   // the scanner found malware in the file
+)
+
+// String returns the reason as shown to the owners.
+func (r FileRejectionReasons) String() string {
+	switch r {
+	case FileTooLarge:
+		return "File too large"
+	case FileTypeNotAllowed:
+		return "File type not allowed"
+	case ArchiveFile:
+		return "Archive"
+	case ExecutableFile:
+		return "Executable"
+	case MacroFile:
+		return "Workbook with macros"
+	case MalwareFound:
+		return "Malware found"
+	default:
+		return "Unknown"
+	}
+}
+
+// Code returns the reason as the email templates tell it apart, they explain it in the contractor's language.
+func (r FileRejectionReasons) Code() string {
+	switch r {
+	case FileTooLarge:
+		return "too_large"
+	case FileTypeNotAllowed:
+		return "type_not_allowed"
+	case ArchiveFile:
+		return "archive"
+	case ExecutableFile:
+		return "executable"
+	case MacroFile:
+		return "macros"
+	case MalwareFound:
+		return "malware"
+	default:
+		return "unknown"
+	}
+}
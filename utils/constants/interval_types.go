@@ -3,6 +3,16 @@ package utils
 type IntervalTypes int
 
 const (
-	Weeks  IntervalTypes = iota // iota starts at 0
-	Months                      // implicitly Weeks + 1
+	Weeks             IntervalTypes = iota // iota starts at 0
+	Months                                 // implicitly Weeks + 1
+	SemiMonthly                            // The 15th and the last day of every month
+	LastDayOfMonth                         // The last day of every interval of months
+	NthWeekdayOfMonth                      // A weekday of a week of the month, e.g. the first Monday
+	LastBusinessDay                        // The last weekday of the month that is not a holiday
+	Cron                                   // A raw cron expression
 )
+
+// HasInterval reports whether the schedules of the type repeat every Interval of weeks or months.
+func (t IntervalTypes) HasInterval() bool {
+	return t != SemiMonthly && t != Cron
+}
@@ -0,0 +1,17 @@
+package utils
+
+type TLSModes int
+
+const (
+	StartTLS    TLSModes = iota // plain connection upgraded with STARTTLS, e.g. port 587
+	ImplicitTLS                 // TLS from the first byte, e.g. port 465 or 993
+	NoTLS                       // plain connection, only meant for local servers
+)
+
+type AuthMechanisms int
+
+const (
+	PlainAuth AuthMechanisms = iota // SASL PLAIN for both SMTP and IMAP
+	LoginAuth                       // SASL LOGIN for SMTP, LOGIN command for IMAP
+	NoAuth                          // no authentication
+)
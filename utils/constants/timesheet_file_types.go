@@ -0,0 +1,54 @@
+package utils
+
+type TimesheetFileTypes int
+
+const (
+	CsvFile  TimesheetFileTypes = iota // a text file of comma, semicolon or tab separated values
+	XlsxFile                           // an Excel workbook without macros
+	PdfFile                            // a PDF document
+)
+
+// TimesheetFileTypesAll are the file types timesheets can be read from, the ones a group allows if it sets none.
+var TimesheetFileTypesAll = []TimesheetFileTypes{CsvFile, XlsxFile, PdfFile}
+
+// String returns the name of the file type as shown to the owners and the contractors.
+func (t TimesheetFileTypes) String() string {
+	switch t {
+	case CsvFile:
+		return "CSV"
+	case XlsxFile:
+		return "XLSX"
+	case PdfFile:
+		return "PDF"
+	default:
+		return "Unknown"
+	}
+}
+
+// Extension returns the extension the files of the type are stored with, whatever the name they were sent with.
+func (t TimesheetFileTypes) Extension() string {
+	switch t {
+	case CsvFile:
+		return ".csv"
+	case XlsxFile:
+		return ".xlsx"
+	case PdfFile:
+		return ".pdf"
+	default:
+		return ""
+	}
+}
+
+// ContentType returns the MIME type the files of the type are stored with.
+func (t TimesheetFileTypes) ContentType() string {
+	switch t {
+	case CsvFile:
+		return "text/csv; charset=utf-8"
+	case XlsxFile:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case PdfFile:
+		return "application/pdf"
+	default:
+		return "application/octet-stream"
+	}
+}
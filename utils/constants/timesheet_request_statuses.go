@@ -0,0 +1,20 @@
+package utils
+
+type TimesheetRequestStatuses int
+
+const (
+	Pending   TimesheetRequestStatuses = iota // the contractor was asked for the timesheet and has not sent it yet
+	Collected                                 // the timesheet of the request was received
+)
+
+// String returns the name of the status as shown to the owners.
+func (s TimesheetRequestStatuses) String() string {
+	switch s {
+	case Pending:
+		return "Pending"
+	case Collected:
+		return "Collected"
+	default:
+		return "Unknown"
+	}
+}
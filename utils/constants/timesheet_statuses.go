@@ -0,0 +1,29 @@
+package utils
+
+type TimesheetStatuses int
+
+const (
+	Received    TimesheetStatuses = iota // the timesheet arrived and nobody looked at it yet
+	UnderReview                          // the owner started reviewing the timesheet
+	Approved                             // the owner accepted the timesheet
+	Rejected                             // the owner asked the contractor for a corrected file
+	Resubmitted                          // the contractor sent a corrected file after a rejection
+)
+
+// String returns the name of the status as shown to the owners.
+func (s TimesheetStatuses) String() string {
+	switch s {
+	case Received:
+		return "Received"
+	case UnderReview:
+		return "Under review"
+	case Approved:
+		return "Approved"
+	case Rejected:
+		return "Rejected"
+	case Resubmitted:
+		return "Resubmitted"
+	default:
+		return "Unknown"
+	}
+}
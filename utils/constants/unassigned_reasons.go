@@ -0,0 +1,35 @@
+package utils
+
+type UnassignedReasons int
+
+const (
+	UnknownSender       UnassignedReasons = iota // no contractor has the address the email was sent from
+	WrongPeriod                                  // the sender is a contractor, but the email matches none of their requests or several of them
+	NoAttachment                                 // the email matches a request but has no timesheet attached
+	MultipleAttachments                          // the email matches a request but it is not known which attachment is the timesheet
+	Matched                                      // the email matches a request now, e.g. one requested after the email arrived
+	RejectedFile                                 // the email matches a request but its attachment is not a file the group accepts
+	OtherSender                                  // the email replies to a request but was sent from another address than the contractor's
+)
+
+// String returns the reason as shown to the owners.
+func (r UnassignedReasons) String() string {
+	switch r {
+	case UnknownSender:
+		return "Unknown sender"
+	case WrongPeriod:
+		return "Wrong period"
+	case NoAttachment:
+		return "No attachment"
+	case MultipleAttachments:
+		return "Multiple attachments"
+	case Matched:
+		return "Matches a request"
+	case RejectedFile:
+		return "File rejected"
+	case OtherSender:
+		return "Other sender"
+	default:
+		return "Unknown"
+	}
+}
@@ -0,0 +1,187 @@
+// Package fakeclamd is an in-process stand-in for the ClamAV daemon, it speaks enough of its protocol for the ClamdScannerService to scan files.
+package fakeclamd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EicarSignature is the EICAR test file, the one every virus scanner reports, the server reports any file containing it.
+const EicarSignature = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// EicarMalwareName is the name the server reports the EICAR test file with, the one ClamAV uses.
+const EicarMalwareName = "Win.Test.EICAR_HDB-1"
+
+// maxStreamLength is the largest stream the server scans, the default StreamMaxLength of ClamAV.
+const maxStreamLength = 25 << 20
+
+// Server is a TCP server answering PING and INSTREAM the way the ClamAV daemon does, reporting the files with the EICAR signature
+// or one of its added signatures.
+type Server struct {
+	mu         sync.Mutex
+	signatures map[string]string // Malware names by the bytes that identify them
+
+	listener net.Listener
+
+	// Logger, when set, receives a line for every scanned stream.
+	Logger *log.Logger
+}
+
+// NewServer creates a new Server that reports the EICAR test file.
+func NewServer() *Server {
+	return &Server{
+		signatures: map[string]string{EicarSignature: EicarMalwareName},
+	}
+}
+
+// AddSignature makes the server report the files containing data as the malware with the given name.
+func (s *Server) AddSignature(name string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.signatures[string(data)] = name
+}
+
+// Start listens on addr, use "127.0.0.1:0" for a free port.
+func (s *Server) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("could not listen for clamd: %w", err)
+	}
+
+	s.listener = listener
+	go s.serve()
+
+	return nil
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// serve accepts the connections until the server is closed.
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handle(conn)
+	}
+}
+
+// handle answers one command, the daemon closes the connection after it unless a session was started, which the server does not support.
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Minute))
+
+	r := bufio.NewReader(conn)
+	command, delimiter, err := readCommand(r)
+	if err != nil {
+		return
+	}
+
+	var reply string
+	switch command {
+	case "PING":
+		reply = "PONG"
+	case "INSTREAM":
+		reply = s.scanStream(r)
+	default:
+		reply = "UNKNOWN COMMAND"
+	}
+
+	conn.Write(append([]byte(reply), delimiter))
+}
+
+// scanStream reads the chunks of a stream and scans them, it returns the reply to the INSTREAM command.
+func (s *Server) scanStream(r io.Reader) string {
+	var content bytes.Buffer
+	for {
+		var size uint32
+		err := binary.Read(r, binary.BigEndian, &size)
+		if err != nil {
+			return "INSTREAM: could not read chunk size ERROR"
+		}
+
+		if size == 0 {
+			break
+		}
+
+		if content.Len()+int(size) > maxStreamLength {
+			return "INSTREAM size limit exceeded. ERROR"
+		}
+
+		_, err = io.CopyN(&content, r, int64(size))
+		if err != nil {
+			return "INSTREAM: could not read chunk ERROR"
+		}
+	}
+
+	reply := "stream: OK"
+	if name, found := s.scan(content.Bytes()); found {
+		reply = fmt.Sprintf("stream: %s FOUND", name)
+	}
+
+	if s.Logger != nil {
+		s.Logger.Printf("fakeclamd: scanned %d bytes: %s", content.Len(), reply)
+	}
+
+	return reply
+}
+
+// scan looks for the signatures in a file.
+func (s *Server) scan(content []byte) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for signature, name := range s.signatures {
+		if bytes.Contains(content, []byte(signature)) {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// readCommand reads a command prefixed with z, ended with a NUL byte, or with n, ended with a new line. It returns the command
+// and the byte its reply ends with.
+func readCommand(r *bufio.Reader) (string, byte, error) {
+	prefix, err := r.ReadByte()
+	if err != nil {
+		return "", 0, err
+	}
+
+	var delimiter byte
+	switch prefix {
+	case 'z':
+		delimiter = 0
+	case 'n':
+		delimiter = '\n'
+	default:
+		return "", 0, errors.New("commands without a z or n prefix are not supported")
+	}
+
+	command, err := r.ReadString(delimiter)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return strings.TrimSuffix(command, string(delimiter)), delimiter, nil
+}
@@ -0,0 +1,451 @@
+package fakemail
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/backend"
+	"github.com/emersion/go-imap/backend/backendutil"
+	"github.com/emersion/go-message"
+	"github.com/emersion/go-message/textproto"
+)
+
+const inboxName = "INBOX"
+
+// imapBackend exposes the in-memory mailboxes over IMAP.
+type imapBackend struct {
+	server *Server
+}
+
+// Login authenticates a user against the passwords registered with AddUser.
+func (b *imapBackend) Login(connInfo *imap.ConnInfo, username string, password string) (backend.User, error) {
+	u, err := b.server.authenticate(username, password)
+	if err != nil {
+		return nil, backend.ErrInvalidCredentials
+	}
+
+	return u, nil
+}
+
+// Updates returns the channel the IMAP server reads mailbox updates from, to push them to idling clients.
+func (b *imapBackend) Updates() <-chan backend.Update {
+	return b.server.updates
+}
+
+// user owns a set of mailboxes, the INBOX always exists.
+type user struct {
+	server    *Server
+	username  string
+	password  string
+	mailboxes map[string]*mailbox
+}
+
+func newUser(server *Server, username string) *user {
+	u := &user{
+		server:    server,
+		username:  username,
+		mailboxes: make(map[string]*mailbox),
+	}
+	u.mailboxes[inboxName] = &mailbox{user: u, name: inboxName, uidNext: 1}
+
+	return u
+}
+
+// inbox returns the INBOX of the user. The caller must hold the server lock.
+func (u *user) inbox() *mailbox {
+	return u.mailboxes[inboxName]
+}
+
+func (u *user) Username() string {
+	return u.username
+}
+
+func (u *user) ListMailboxes(subscribed bool) ([]backend.Mailbox, error) {
+	u.server.mu.Lock()
+	defer u.server.mu.Unlock()
+
+	var mailboxes []backend.Mailbox
+	for _, mbox := range u.mailboxes {
+		if subscribed && !mbox.subscribed {
+			continue
+		}
+		mailboxes = append(mailboxes, mbox)
+	}
+
+	return mailboxes, nil
+}
+
+func (u *user) GetMailbox(name string) (backend.Mailbox, error) {
+	u.server.mu.Lock()
+	defer u.server.mu.Unlock()
+
+	if strings.EqualFold(name, inboxName) {
+		name = inboxName
+	}
+
+	mbox, ok := u.mailboxes[name]
+	if !ok {
+		return nil, backend.ErrNoSuchMailbox
+	}
+
+	return mbox, nil
+}
+
+func (u *user) CreateMailbox(name string) error {
+	u.server.mu.Lock()
+	defer u.server.mu.Unlock()
+
+	if _, ok := u.mailboxes[name]; ok {
+		return backend.ErrMailboxAlreadyExists
+	}
+
+	u.mailboxes[name] = &mailbox{user: u, name: name, uidNext: 1}
+	return nil
+}
+
+func (u *user) DeleteMailbox(name string) error {
+	u.server.mu.Lock()
+	defer u.server.mu.Unlock()
+
+	if name == inboxName {
+		return backend.ErrNoSuchMailbox
+	}
+	if _, ok := u.mailboxes[name]; !ok {
+		return backend.ErrNoSuchMailbox
+	}
+
+	delete(u.mailboxes, name)
+	return nil
+}
+
+func (u *user) RenameMailbox(existingName string, newName string) error {
+	u.server.mu.Lock()
+	defer u.server.mu.Unlock()
+
+	mbox, ok := u.mailboxes[existingName]
+	if !ok {
+		return backend.ErrNoSuchMailbox
+	}
+	if _, ok := u.mailboxes[newName]; ok {
+		return backend.ErrMailboxAlreadyExists
+	}
+
+	mbox.name = newName
+	u.mailboxes[newName] = mbox
+	delete(u.mailboxes, existingName)
+
+	return nil
+}
+
+func (u *user) Logout() error {
+	return nil
+}
+
+// mailbox is a list of messages. Every method takes the server lock, since SMTP deliveries happen concurrently.
+type mailbox struct {
+	user       *user
+	name       string
+	subscribed bool
+	uidNext    uint32
+	messages   []*storedMessage
+}
+
+// appendMessage stores a new message. The caller must hold the server lock.
+func (mbox *mailbox) appendMessage(body []byte, flags []string, date time.Time) {
+	mbox.messages = append(mbox.messages, &storedMessage{
+		uid:   mbox.uidNext,
+		date:  date,
+		flags: append([]string(nil), flags...),
+		body:  append([]byte(nil), body...),
+	})
+	mbox.uidNext++
+}
+
+func (mbox *mailbox) Name() string {
+	return mbox.name
+}
+
+func (mbox *mailbox) Info() (*imap.MailboxInfo, error) {
+	return &imap.MailboxInfo{
+		Delimiter: "/",
+		Name:      mbox.name,
+	}, nil
+}
+
+func (mbox *mailbox) Status(items []imap.StatusItem) (*imap.MailboxStatus, error) {
+	mbox.user.server.mu.Lock()
+	defer mbox.user.server.mu.Unlock()
+
+	status := imap.NewMailboxStatus(mbox.name, items)
+	status.Flags = []string{imap.SeenFlag, imap.AnsweredFlag, imap.FlaggedFlag, imap.DeletedFlag, imap.DraftFlag}
+	status.PermanentFlags = []string{"\\*"}
+
+	var unseen uint32
+	for i, msg := range mbox.messages {
+		if !msg.hasFlag(imap.SeenFlag) {
+			if status.UnseenSeqNum == 0 {
+				status.UnseenSeqNum = uint32(i + 1)
+			}
+			unseen++
+		}
+	}
+
+	for _, item := range items {
+		switch item {
+		case imap.StatusMessages:
+			status.Messages = uint32(len(mbox.messages))
+		case imap.StatusUidNext:
+			status.UidNext = mbox.uidNext
+		case imap.StatusUidValidity:
+			status.UidValidity = 1
+		case imap.StatusRecent:
+			status.Recent = 0
+		case imap.StatusUnseen:
+			status.Unseen = unseen
+		}
+	}
+
+	return status, nil
+}
+
+func (mbox *mailbox) SetSubscribed(subscribed bool) error {
+	mbox.user.server.mu.Lock()
+	defer mbox.user.server.mu.Unlock()
+
+	mbox.subscribed = subscribed
+	return nil
+}
+
+func (mbox *mailbox) Check() error {
+	return nil
+}
+
+func (mbox *mailbox) ListMessages(uid bool, seqSet *imap.SeqSet, items []imap.FetchItem, ch chan<- *imap.Message) error {
+	defer close(ch)
+
+	// Fetch under the lock, send without it so a slow client never blocks deliveries
+	mbox.user.server.mu.Lock()
+	var fetched []*imap.Message
+	for i, msg := range mbox.messages {
+		seqNum := uint32(i + 1)
+		if !seqSet.Contains(msg.id(uid, seqNum)) {
+			continue
+		}
+
+		m, err := msg.fetch(seqNum, items)
+		if err != nil {
+			continue
+		}
+		fetched = append(fetched, m)
+
+		// Fetching a body section without PEEK marks the message as seen
+		for _, item := range items {
+			section, err := imap.ParseBodySectionName(item)
+			if err == nil && !section.Peek && !msg.hasFlag(imap.SeenFlag) {
+				msg.flags = append(msg.flags, imap.SeenFlag)
+			}
+		}
+	}
+	mbox.user.server.mu.Unlock()
+
+	for _, m := range fetched {
+		ch <- m
+	}
+
+	return nil
+}
+
+func (mbox *mailbox) SearchMessages(uid bool, criteria *imap.SearchCriteria) ([]uint32, error) {
+	mbox.user.server.mu.Lock()
+	defer mbox.user.server.mu.Unlock()
+
+	var ids []uint32
+	for i, msg := range mbox.messages {
+		seqNum := uint32(i + 1)
+
+		e, err := message.Read(bytes.NewReader(msg.body))
+		if err != nil && !message.IsUnknownCharset(err) {
+			continue
+		}
+
+		ok, err := backendutil.Match(e, seqNum, msg.uid, msg.date, msg.flags, criteria)
+		if err != nil || !ok {
+			continue
+		}
+
+		ids = append(ids, msg.id(uid, seqNum))
+	}
+
+	return ids, nil
+}
+
+func (mbox *mailbox) CreateMessage(flags []string, date time.Time, body imap.Literal) error {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	mbox.user.server.mu.Lock()
+	defer mbox.user.server.mu.Unlock()
+
+	mbox.appendMessage(b, flags, date)
+	mbox.user.server.notifyInbox(mbox.user)
+	return nil
+}
+
+func (mbox *mailbox) UpdateMessagesFlags(uid bool, seqSet *imap.SeqSet, op imap.FlagsOp, flags []string) error {
+	mbox.user.server.mu.Lock()
+	defer mbox.user.server.mu.Unlock()
+
+	for i, msg := range mbox.messages {
+		if !seqSet.Contains(msg.id(uid, uint32(i+1))) {
+			continue
+		}
+
+		msg.flags = backendutil.UpdateFlags(msg.flags, op, flags)
+	}
+
+	return nil
+}
+
+func (mbox *mailbox) CopyMessages(uid bool, seqSet *imap.SeqSet, destName string) error {
+	mbox.user.server.mu.Lock()
+	defer mbox.user.server.mu.Unlock()
+
+	dest, ok := mbox.user.mailboxes[destName]
+	if !ok {
+		return backend.ErrNoSuchMailbox
+	}
+
+	for i, msg := range mbox.messages {
+		if !seqSet.Contains(msg.id(uid, uint32(i+1))) {
+			continue
+		}
+
+		dest.appendMessage(msg.body, msg.flags, msg.date)
+	}
+
+	return nil
+}
+
+// MoveMessages moves messages to another mailbox, as Gmail does when a label replaces the INBOX one.
+func (mbox *mailbox) MoveMessages(uid bool, seqSet *imap.SeqSet, destName string) error {
+	mbox.user.server.mu.Lock()
+	defer mbox.user.server.mu.Unlock()
+
+	dest, ok := mbox.user.mailboxes[destName]
+	if !ok {
+		return backend.ErrNoSuchMailbox
+	}
+
+	var kept []*storedMessage
+	for i, msg := range mbox.messages {
+		if !seqSet.Contains(msg.id(uid, uint32(i+1))) {
+			kept = append(kept, msg)
+			continue
+		}
+
+		dest.appendMessage(msg.body, msg.flags, msg.date)
+	}
+	mbox.messages = kept
+
+	return nil
+}
+
+func (mbox *mailbox) Expunge() error {
+	mbox.user.server.mu.Lock()
+	defer mbox.user.server.mu.Unlock()
+
+	var kept []*storedMessage
+	for _, msg := range mbox.messages {
+		if !msg.hasFlag(imap.DeletedFlag) {
+			kept = append(kept, msg)
+		}
+	}
+	mbox.messages = kept
+
+	return nil
+}
+
+// storedMessage is a raw message with its IMAP metadata.
+type storedMessage struct {
+	uid   uint32
+	date  time.Time
+	flags []string
+	body  []byte
+}
+
+// id returns the UID or the sequence number, depending on the command.
+func (m *storedMessage) id(uid bool, seqNum uint32) uint32 {
+	if uid {
+		return m.uid
+	}
+	return seqNum
+}
+
+func (m *storedMessage) hasFlag(flag string) bool {
+	for _, f := range m.flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *storedMessage) headerAndBody() (textproto.Header, io.Reader, error) {
+	body := bufio.NewReader(bytes.NewReader(m.body))
+	hdr, err := textproto.ReadHeader(body)
+	return hdr, body, err
+}
+
+// fetch builds the IMAP representation of the requested items.
+func (m *storedMessage) fetch(seqNum uint32, items []imap.FetchItem) (*imap.Message, error) {
+	fetched := imap.NewMessage(seqNum, items)
+	for _, item := range items {
+		switch item {
+		case imap.FetchEnvelope:
+			hdr, _, err := m.headerAndBody()
+			if err != nil {
+				return nil, err
+			}
+			fetched.Envelope, _ = backendutil.FetchEnvelope(hdr)
+		case imap.FetchBody, imap.FetchBodyStructure:
+			hdr, body, err := m.headerAndBody()
+			if err != nil {
+				return nil, err
+			}
+			fetched.BodyStructure, _ = backendutil.FetchBodyStructure(hdr, body, item == imap.FetchBodyStructure)
+		case imap.FetchFlags:
+			fetched.Flags = append([]string(nil), m.flags...)
+		case imap.FetchInternalDate:
+			fetched.InternalDate = m.date
+		case imap.FetchRFC822Size:
+			fetched.Size = uint32(len(m.body))
+		case imap.FetchUid:
+			fetched.Uid = m.uid
+		default:
+			section, err := imap.ParseBodySectionName(item)
+			if err != nil {
+				break
+			}
+
+			hdr, body, err := m.headerAndBody()
+			if err != nil {
+				return nil, err
+			}
+
+			l, _ := backendutil.FetchBodySection(hdr, body, section)
+			fetched.Body[section] = l
+		}
+	}
+
+	return fetched, nil
+}
@@ -0,0 +1,270 @@
+package fakemail
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"job_sender/types"
+	constants "job_sender/utils/constants"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/backend"
+	imapserver "github.com/emersion/go-imap/server"
+	"github.com/emersion/go-sasl"
+	"github.com/emersion/go-smtp"
+)
+
+// Server is an in-process SMTP and IMAP server that keeps every mailbox in memory.
+// It speaks enough of both protocols for the EmailService to send, search, fetch and archive emails.
+type Server struct {
+	mu    sync.Mutex
+	users map[string]*user
+
+	// updates tells the IMAP clients idling on a mailbox about new messages.
+	updates chan backend.Update
+
+	smtpServer   *smtp.Server
+	imapServer   *imapserver.Server
+	smtpListener net.Listener
+	imapListener net.Listener
+
+	// Logger, when set, receives a line for every delivered message.
+	Logger *log.Logger
+}
+
+// Message is a delivered message as stored in a mailbox.
+type Message struct {
+	Uid   uint32
+	Date  time.Time
+	Flags []string
+	Body  []byte
+}
+
+// NewServer creates a new Server without any users.
+func NewServer() *Server {
+	s := &Server{
+		users:   make(map[string]*user),
+		updates: make(chan backend.Update),
+	}
+
+	smtpBackend := &smtpBackend{server: s}
+	s.smtpServer = smtp.NewServer(smtpBackend)
+	s.smtpServer.EnableAuth(sasl.Login, smtpBackend.newLoginServer)
+	s.smtpServer.Domain = "localhost"
+	s.smtpServer.AllowInsecureAuth = true
+	s.smtpServer.ReadTimeout = 30 * time.Second
+	s.smtpServer.WriteTimeout = 30 * time.Second
+	s.smtpServer.ErrorLog = log.New(io.Discard, "", 0)
+
+	s.imapServer = imapserver.New(&imapBackend{server: s})
+	s.imapServer.AllowInsecureAuth = true
+	s.imapServer.ErrorLog = log.New(io.Discard, "", 0)
+
+	return s
+}
+
+// AddUser creates a mailbox owner that can log in to both servers with the given password.
+func (s *Server) AddUser(address string, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.getOrCreateUser(address)
+	u.password = password
+}
+
+// Start starts listening on the given addresses, e.g. "127.0.0.1:0" for a random port.
+func (s *Server) Start(smtpAddr string, imapAddr string) error {
+	smtpListener, err := net.Listen("tcp", smtpAddr)
+	if err != nil {
+		return fmt.Errorf("could not listen for SMTP: %w", err)
+	}
+
+	imapListener, err := net.Listen("tcp", imapAddr)
+	if err != nil {
+		smtpListener.Close()
+		return fmt.Errorf("could not listen for IMAP: %w", err)
+	}
+
+	s.smtpListener = smtpListener
+	s.imapListener = imapListener
+
+	go s.smtpServer.Serve(smtpListener)
+	go s.imapServer.Serve(imapListener)
+
+	return nil
+}
+
+// Close stops both servers.
+func (s *Server) Close() error {
+	smtpErr := s.smtpServer.Close()
+	imapErr := s.imapServer.Close()
+	if smtpErr != nil {
+		return smtpErr
+	}
+	return imapErr
+}
+
+// SmtpAddr returns the address the SMTP server listens on.
+func (s *Server) SmtpAddr() string {
+	return s.smtpListener.Addr().String()
+}
+
+// ImapAddr returns the address the IMAP server listens on.
+func (s *Server) ImapAddr() string {
+	return s.imapListener.Addr().String()
+}
+
+// MailTransport returns the transport settings that point an EmailService at this server.
+func (s *Server) MailTransport() *types.MailTransport {
+	smtpHost, smtpPort := splitHostPort(s.SmtpAddr())
+	imapHost, imapPort := splitHostPort(s.ImapAddr())
+
+	return &types.MailTransport{
+		SmtpHost:    smtpHost,
+		SmtpPort:    smtpPort,
+		SmtpTLSMode: constants.NoTLS,
+
+		ImapHost:    imapHost,
+		ImapPort:    imapPort,
+		ImapTLSMode: constants.NoTLS,
+
+		AuthMechanism: constants.PlainAuth,
+	}
+}
+
+// Deliver puts a raw message into the INBOX of every recipient, as if it was received over SMTP.
+func (s *Server) Deliver(from string, to []string, raw []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rcpt := range to {
+		u := s.getOrCreateUser(mailboxAddress(rcpt))
+		u.inbox().appendMessage(raw, nil, time.Now())
+		s.notifyInbox(u)
+	}
+
+	if s.Logger != nil {
+		s.Logger.Printf("fakemail: delivered %q from %s to %s (%d bytes)", subjectOf(raw), from, strings.Join(to, ", "), len(raw))
+	}
+}
+
+// notifyInbox sends the new message count of the user's INBOX to the clients idling on it. The caller must hold s.mu.
+func (s *Server) notifyInbox(u *user) {
+	status := imap.NewMailboxStatus(inboxName, []imap.StatusItem{imap.StatusMessages})
+	status.Messages = uint32(len(u.inbox().messages))
+
+	update := &backend.MailboxUpdate{
+		Update:        backend.NewUpdate(u.username, inboxName),
+		MailboxStatus: status,
+	}
+
+	// The IMAP server broadcasts updates one at a time, and not at all before it is started
+	go func() {
+		s.updates <- update
+	}()
+}
+
+// Messages returns a copy of the messages in the INBOX of the given address.
+func (s *Server) Messages(address string) []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[normalizeAddress(address)]
+	if !ok {
+		return nil
+	}
+
+	var messages []Message
+	for _, msg := range u.inbox().messages {
+		messages = append(messages, Message{
+			Uid:   msg.uid,
+			Date:  msg.date,
+			Flags: append([]string(nil), msg.flags...),
+			Body:  append([]byte(nil), msg.body...),
+		})
+	}
+
+	return messages
+}
+
+// getOrCreateUser returns the user of the address, creating one without a password if needed.
+// The caller must hold s.mu.
+func (s *Server) getOrCreateUser(address string) *user {
+	address = normalizeAddress(address)
+
+	u, ok := s.users[address]
+	if !ok {
+		u = newUser(s, address)
+		s.users[address] = u
+	}
+
+	return u
+}
+
+// authenticate checks the credentials of a user.
+func (s *Server) authenticate(username string, password string) (*user, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[normalizeAddress(username)]
+	if !ok || u.password == "" || u.password != password {
+		return nil, fmt.Errorf("invalid credentials")
+	}
+
+	return u, nil
+}
+
+// subjectOf returns the decoded subject of a raw message, or an empty string if it has none.
+func subjectOf(raw []byte) string {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return ""
+	}
+
+	subject := msg.Header.Get("Subject")
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(subject); err == nil {
+		subject = decoded
+	}
+
+	return subject
+}
+
+// normalizeAddress strips the angle brackets and lower-cases an email address.
+func normalizeAddress(address string) string {
+	return strings.ToLower(strings.Trim(strings.TrimSpace(address), "<>"))
+}
+
+// mailboxAddress strips the tag of a plus address, so "box+tag@host" is delivered to "box@host" as Gmail does.
+func mailboxAddress(address string) string {
+	local, domain, ok := strings.Cut(address, "@")
+	if !ok {
+		return address
+	}
+
+	local, _, _ = strings.Cut(local, "+")
+	return local + "@" + domain
+}
+
+// splitHostPort splits a listener address into a host and a numeric port.
+func splitHostPort(addr string) (string, int) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr, 0
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return host, 0
+	}
+
+	return host, port
+}
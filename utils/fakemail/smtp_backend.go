@@ -0,0 +1,80 @@
+package fakemail
+
+import (
+	"io"
+
+	"github.com/emersion/go-sasl"
+	"github.com/emersion/go-smtp"
+)
+
+// smtpBackend accepts messages over SMTP and delivers them to the in-memory mailboxes.
+type smtpBackend struct {
+	server *Server
+}
+
+// Login authenticates a user, anonymous submissions are accepted as well.
+func (b *smtpBackend) Login(state *smtp.ConnectionState, username string, password string) (smtp.Session, error) {
+	_, err := b.server.authenticate(username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return &smtpSession{server: b.server}, nil
+}
+
+// AnonymousLogin lets clients such as curl or swaks drop a reply into a mailbox without credentials.
+func (b *smtpBackend) AnonymousLogin(state *smtp.ConnectionState) (smtp.Session, error) {
+	return &smtpSession{server: b.server}, nil
+}
+
+// smtpSession collects the envelope of a single message.
+type smtpSession struct {
+	server *Server
+
+	from string
+	to   []string
+}
+
+func (s *smtpSession) Reset() {
+	s.from = ""
+	s.to = nil
+}
+
+func (s *smtpSession) Logout() error {
+	return nil
+}
+
+func (s *smtpSession) Mail(from string, opts smtp.MailOptions) error {
+	s.from = from
+	return nil
+}
+
+func (s *smtpSession) Rcpt(to string) error {
+	s.to = append(s.to, to)
+	return nil
+}
+
+func (s *smtpSession) Data(r io.Reader) error {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	s.server.Deliver(s.from, s.to, raw)
+
+	return nil
+}
+
+// newLoginServer provides the SASL LOGIN mechanism next to the PLAIN one built into go-smtp.
+func (b *smtpBackend) newLoginServer(conn *smtp.Conn) sasl.Server {
+	return sasl.NewLoginServer(func(username string, password string) error {
+		state := conn.State()
+		session, err := b.Login(&state, username, password)
+		if err != nil {
+			return err
+		}
+
+		conn.SetSession(session)
+		return nil
+	})
+}